@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/agent"
+	"github.com/N0tT1m/claude-code-go/internal/repl"
+)
+
+const modelListTTL = 5 * time.Minute
+
+// newCompleter wires REPL completion for slash commands and their arguments.
+func newCompleter(s *session, workingDir string) *repl.Completer {
+	files := &repl.FileProvider{Root: workingDir}
+
+	models := repl.Cached(modelListTTL, func() []repl.Candidate {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		names, err := s.agent.GetAvailableModels(ctx)
+		if err != nil {
+			return nil
+		}
+
+		var candidates []repl.Candidate
+		for _, name := range names {
+			description := ""
+			if name == s.agent.Model() {
+				description = "current"
+			}
+			candidates = append(candidates, repl.Candidate{Value: name, Description: description})
+		}
+		return candidates
+	})
+
+	jobArgs := repl.ProviderFunc(func(args []string, prefix string) []repl.Candidate {
+		switch len(args) {
+		case 0:
+			return repl.Static(0, "output", "follow", "cancel").Complete(args, prefix)
+		case 1:
+			var candidates []repl.Candidate
+			for _, job := range s.jobs.List() {
+				candidates = append(candidates, repl.Candidate{
+					Value:       strconv.Itoa(job.ID),
+					Description: string(job.Status()) + "  " + job.Name,
+				})
+			}
+			return candidates
+		}
+		return nil
+	})
+
+	reviewArgs := repl.ProviderFunc(func(args []string, prefix string) []repl.Candidate {
+		if len(args) != 0 {
+			return nil
+		}
+
+		candidates := []repl.Candidate{{Value: "--staged", Description: "review the index"}}
+
+		// Complete the ref after ".." as well as the first one
+		base := ""
+		if i := strings.Index(prefix, ".."); i != -1 {
+			base = prefix[:i+2]
+		}
+		for _, ref := range gitRefs() {
+			candidates = append(candidates, repl.Candidate{Value: base + ref})
+		}
+		return candidates
+	})
+
+	scopeArgs := repl.ProviderFunc(func(args []string, prefix string) []repl.Candidate {
+		if len(args) != 0 {
+			return nil
+		}
+		ws, _ := s.agent.Workspace()
+		if ws == nil {
+			return nil
+		}
+		candidates := []repl.Candidate{
+			{Value: "all", Description: "the whole workspace"},
+			{Value: "reset", Description: "the starting package"},
+		}
+		for _, member := range ws.Members {
+			candidates = append(candidates, repl.Candidate{Value: member})
+		}
+		return candidates
+	})
+
+	c := repl.NewCompleter()
+	c.Register("help", "Show help or ask a question", nil)
+	c.Register("commit", "Create a git commit", repl.Static(0, "--amend", "--split"))
+	c.Register("clear", "Start a new conversation", nil)
+	c.Register("config", "Show current configuration", nil)
+	c.Register("models", "List available models", nil)
+	c.Register("model", "Show or switch the model", models)
+	c.Register("open", "Open a file in $EDITOR", files)
+	c.Register("style", "Show or switch the response style", repl.Static(0, agent.OutputStyles()...))
+	c.Register("think", "Control reasoning models", repl.Static(0, "on", "off", "auto", "show", "hide"))
+	c.Register("bg", "Run a task as a background job", nil)
+	c.Register("jobs", "List, follow, or cancel background jobs", jobArgs)
+	c.Register("explain", "Explain an error or stack trace", nil)
+	c.Register("trace", "Show the output behind a footnote", nil)
+	c.Register("attach", "Attach files to the next message", repl.ProviderFunc(func(args []string, prefix string) []repl.Candidate {
+		candidates := files.Complete(args, prefix)
+		if len(args) == 0 {
+			candidates = append(candidates, repl.Candidate{Value: "clear", Description: "drop pending attachments"})
+		}
+		return candidates
+	}))
+	c.Register("review", "Review a diff", reviewArgs)
+	c.Register("branch", "Suggest a branch name for a task and switch to it", nil)
+	c.Register("whatchanged", "Summarize changes made this session", nil)
+	c.Register("why", "Explain why a line exists", files)
+	c.Register("scope", "Show or widen the monorepo packages in the context", scopeArgs)
+	c.Register("add-dir", "Add a directory outside the project to the context", nil)
+	c.Register("pin", "Keep a file in every prompt", files)
+	c.Register("unpin", "Stop keeping a file in every prompt", files)
+	c.Register("mcp", "Show the MCP servers", nil)
+	return c
+}
+
+func gitRefs() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/tags", "refs/remotes").Output()
+	if err != nil {
+		return nil
+	}
+	return append([]string{"HEAD"}, strings.Fields(string(output))...)
+}
@@ -3,74 +3,181 @@ package agent
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
+	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/N0tT1m/claude-code-go/internal/audit"
 	"github.com/N0tT1m/claude-code-go/internal/config"
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/history"
 	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+	"github.com/N0tT1m/claude-code-go/internal/permissions"
 	"github.com/N0tT1m/claude-code-go/internal/tools"
+	"github.com/N0tT1m/claude-code-go/internal/workspace"
 )
 
 type Agent struct {
-	llmClient *llm.Client
-	config    *config.Config
-	tools     *tools.Registry
+	llmClient    *llm.Client
+	config       *config.Config
+	tools        *tools.Registry
+	workspace    *workspace.Coordinator
+	filter       *projectctx.ContentFilter
+	context      *projectctx.ContextManager
+	permissions  *permissions.Policy
+	audit        *audit.Log
+	attachments  []Attachment
+	baseline     *Snapshot
+	toolResults  *toolResultStore
+	history      *history.Session
+	mcp          *mcp.Manager
+	conversation conversation
 }
 
 type GitStatus struct {
-	Changes []GitChange
-	Branch  string
+	Changes  []GitChange
+	Branch   string // "(detached)" when HEAD is not on a branch
+	Upstream string
+	Ahead    int
+	Behind   int
 }
 
 type GitChange struct {
-	Type string
-	File string
+	Type     string // modified, added, deleted, renamed, copied, typechange, unmerged or untracked
+	File     string
+	OrigFile string // Source path of renames and copies
+	Staged   bool   // The index differs from HEAD
+	Unstaged bool   // The working tree differs from the index
 }
 
 func New(client *llm.Client, cfg *config.Config) *Agent {
-	return &Agent{
-		llmClient: client,
-		config:    cfg,
-		tools:     tools.NewRegistry(),
+	if err := client.SetThinking(cfg.Agent.Thinking, cfg.Agent.ThinkingBudget); err != nil {
+		client.SetThinking(llm.ThinkingAuto, 0)
 	}
+
+	workingDir, _ := os.Getwd()
+	a := &Agent{
+		llmClient:   client,
+		config:      cfg,
+		tools:       newToolRegistry(cfg),
+		filter:      projectctx.NewContentFilter(cfg.Context),
+		context:     newContextManager(workingDir, cfg, client),
+		permissions: newPolicy(cfg),
+		toolResults: newToolResultStore(),
+	}
+	a.tools.Register(&recallTool{store: a.toolResults})
+	registerSymbolTools(a.tools, a.context.SymbolIndex)
+	registerScopeTool(a.tools, a.context)
+	a.tools.OnEdit(a.context.Invalidate)
+	recordAccesses(a.tools, a.context)
+
+	return a
 }
 
-func (a *Agent) GetGitStatus(ctx context.Context) (*GitStatus, error) {
-	// Implementation would use git commands to get status
-	// This is a simplified version
-	return &GitStatus{
-		Changes: []GitChange{
-			{Type: "modified", File: "main.go"},
-			{Type: "added", File: "config.go"},
-		},
-		Branch: "main",
-	}, nil
+// newPolicy builds the configured edit policy.
+func newPolicy(cfg *config.Config) *permissions.Policy {
+	policy, err := permissions.NewPolicy(cfg.Permissions)
+	if err != nil {
+		// An unrecognized auto_accept mode must not silently allow edits
+		policy, _ = permissions.NewPolicy(config.PermissionsConfig{
+			AutoAccept:   permissions.AutoAcceptNone,
+			TestPatterns: cfg.Permissions.TestPatterns,
+		})
+	}
+	return policy
 }
 
-func (a *Agent) GenerateCommitMessage(ctx context.Context, status *GitStatus) (string, error) {
-	var changes []string
-	for _, change := range status.Changes {
-		changes = append(changes, fmt.Sprintf("%s: %s", change.Type, change.File))
+// newToolRegistry builds the tool registry, routing command execution to the
+// configured remote host if there is one.
+func newToolRegistry(cfg *config.Config) *tools.Registry {
+	registry := tools.NewRegistry()
+	if !cfg.Remote.Enabled {
+		return registry
 	}
 
-	prompt := fmt.Sprintf(`Generate a concise git commit message for these changes:
-%s
+	workingDir, _ := os.Getwd()
+	executor, err := tools.NewSSHExecutor(cfg.Remote, workingDir)
+	if err != nil {
+		registry.SetExecutor(tools.UnavailableExecutor(fmt.Errorf("remote execution is misconfigured: %w", err)))
+		return registry
+	}
+
+	registry.SetExecutor(executor)
+	return registry
+}
+
+// Close shuts down the language servers started for diagnostics and the
+// connections to MCP servers.
+func (a *Agent) Close() {
+	a.context.CloseLanguageServers()
+	if a.mcp != nil {
+		a.mcp.Close()
+	}
+}
+
+// WatchProject keeps the project context current from file system events
+// instead of walking the project for every request. Call the returned
+// function to stop watching.
+func (a *Agent) WatchProject() (func(), error) {
+	return a.context.Watch()
+}
+
+// Executor describes where shell, build and test commands run.
+func (a *Agent) Executor() string {
+	return a.tools.Executor().Describe()
+}
+
+// AttachWorkspace routes the agent's file mutations through the workspace
+// coordinator so concurrent sessions on the same directory don't collide.
+func (a *Agent) AttachWorkspace(c *workspace.Coordinator) {
+	a.workspace = c
+	a.tools.SetGuard(c)
+}
+
+// SetAuditLog records every tool execution in l and makes answers cite the
+// entries they rely on.
+func (a *Agent) SetAuditLog(l *audit.Log) {
+	a.audit = l
+}
+
+func (a *Agent) AuditLog() *audit.Log {
+	return a.audit
+}
+
+func (a *Agent) GetGitStatus(ctx context.Context) (*GitStatus, error) {
+	output, err := runGit(ctx, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitStatus(output)
+}
 
-Follow conventional commit format and be specific about what was changed.`, strings.Join(changes, "\n"))
+func (a *Agent) GenerateCommitMessage(ctx context.Context, status *GitStatus) (string, error) {
+	changes := a.CommitChanges(status)
+
+	system, prompt, err := a.commitPrompt(changes)
+	if err != nil {
+		return "", err
+	}
 
 	messages := []llm.Message{
-		{Role: "system", Content: "You are a git commit message generator. Create clear, concise commit messages following conventional commit format."},
+		{Role: "system", Content: system},
 		{Role: "user", Content: prompt},
 	}
 
 	req := llm.ChatRequest{
 		Model:       a.config.LMStudio.Model,
 		Messages:    messages,
-		MaxTokens:   100,
+		MaxTokens:   200,
 		Temperature: 0.3,
 	}
 
@@ -83,30 +190,203 @@ Follow conventional commit format and be specific about what was changed.`, stri
 		return "", fmt.Errorf("no commit message generated")
 	}
 
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	return strings.Trim(strings.TrimSpace(resp.Choices[0].Message.Content), "`"), nil
 }
 
-func (a *Agent) CreateCommit(ctx context.Context, message string) error {
-	// Implementation would execute git commands
-	fmt.Printf("Would execute: git commit -m \"%s\"\n", message)
-	return nil
+// CommitChanges returns the changes a commit would include: everything when
+// git.auto_stage is set, otherwise only what is staged.
+func (a *Agent) CommitChanges(status *GitStatus) []GitChange {
+	if a.config.Git.AutoStage {
+		return status.Changes
+	}
+
+	var staged []GitChange
+	for _, change := range status.Changes {
+		if change.Staged {
+			staged = append(staged, change)
+		}
+	}
+	return staged
+}
+
+// CommitOptions adjusts a single CreateCommit call.
+type CommitOptions struct {
+	Amend      bool // Replace the last commit instead of creating a new one
+	KeepIndex  bool // Commit exactly what is staged, even with git.auto_stage
+	SkipReview bool // Commit even if the pre-commit review has findings
+}
+
+// CreateCommit stages changes when git.auto_stage is set, runs the
+// pre-commit review (failing with a *PreCommitError on findings), commits
+// them with message honoring the sign-off and signing settings, and returns
+// the hash of the new commit. A commit that does not happen leaves the
+// index as it found it.
+func (a *Agent) CreateCommit(ctx context.Context, message string, opts CommitOptions) (string, error) {
+	committed := false
+	if a.config.Git.AutoStage && !opts.KeepIndex {
+		restore, err := saveIndex(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			if committed {
+				return
+			}
+			if err := restore(); err != nil {
+				log.Printf("Warning: failed to restore the git index: %v", err)
+			}
+		}()
+
+		if _, err := runGit(ctx, "add", "--all"); err != nil {
+			return "", err
+		}
+	}
+
+	if !opts.SkipReview {
+		findings, err := a.PreCommitReview(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(findings) > 0 {
+			return "", &PreCommitError{Findings: findings}
+		}
+	}
+
+	args := []string{"commit", "--file", "-"}
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	if a.config.Git.SignOff {
+		args = append(args, "--signoff")
+	}
+	if a.config.Git.GPGSign {
+		args = append(args, "--gpg-sign"+keyArg(a.config.Git.SigningKey))
+	}
+
+	if _, err := runGitInput(ctx, message, args...); err != nil {
+		return "", err
+	}
+
+	committed = true
+
+	hash, err := runGit(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(hash), nil
+}
+
+func keyArg(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "=" + key
 }
 
 func (a *Agent) GetAvailableModels(ctx context.Context) ([]string, error) {
 	return a.llmClient.GetModels(ctx)
 }
 
+// Model returns the model requests are sent to.
+func (a *Agent) Model() string {
+	return a.config.LMStudio.Model
+}
+
+// SetModel switches the model for the rest of the session.
+func (a *Agent) SetModel(name string) {
+	a.config.LMStudio.Model = name
+}
+
+// SetThinking switches how reasoning models think for the rest of the
+// session; see config.AgentConfig.Thinking.
+func (a *Agent) SetThinking(mode string, budgetTokens int) error {
+	if err := a.llmClient.SetThinking(mode, budgetTokens); err != nil {
+		return err
+	}
+
+	a.config.Agent.Thinking = mode
+	a.config.Agent.ThinkingBudget = budgetTokens
+	return nil
+}
+
+// Thinking returns the thinking mode and budget in effect.
+func (a *Agent) Thinking() (string, int) {
+	mode := a.config.Agent.Thinking
+	if mode == "" {
+		mode = llm.ThinkingAuto
+	}
+	return mode, a.config.Agent.ThinkingBudget
+}
+
 func (a *Agent) ProcessInput(ctx context.Context, input string) (string, error) {
+	return a.ProcessInputWithEvents(ctx, input, nil)
+}
+
+// ProcessInputWithEvents is ProcessInput with a callback that observes each
+// tool call and result as the agent works towards its answer. Requests
+// continue the session's conversation, which carries each file once until
+// it changes.
+func (a *Agent) ProcessInputWithEvents(ctx context.Context, input string, onEvent func(Event)) (string, error) {
+	conv := a.conversationFor(ctx)
+	conv.messages = conv.sent.trimHistory(conv.messages, max(planBudget(a.config.Agent).History-estimateTokens(input), 0))
+
+	systemPrompt, files, err := a.buildSystemPrompt(ctx, input, &conv.sent)
+	if err != nil {
+		conv.sent.unsend()
+		return "", err
+	}
+
+	request := llm.Message{Role: "user", Content: a.userMessage(input) + files}
+	messages := append([]llm.Message{{Role: "system", Content: systemPrompt}}, conv.messages...)
+	messages = append(messages, request)
+
+	response, err := a.runConversation(ctx, messages, onEvent)
+	if err != nil {
+		conv.sent.unsend()
+		return response, err
+	}
+
+	conv.messages = append(conv.messages, request, llm.Message{Role: "assistant", Content: response})
+	conv.sent.turns++
+	a.recordExchange(ctx, input, response)
+	return response, nil
+}
+
+// buildSystemPrompt describes the project for a request, and renders the
+// files for its message apart, leaving out those the conversation sent
+// tracks still holds. query, when not empty, selects the code to include
+// if semantic retrieval is on.
+func (a *Agent) buildSystemPrompt(ctx context.Context, query string, sent *sentFiles) (string, string, error) {
 	// Get current working directory
 	workingDir, err := os.Getwd()
 	if err != nil {
-		return "", fmt.Errorf("failed to get working directory: %w", err)
+		return "", "", fmt.Errorf("failed to get working directory: %w", err)
 	}
 
-	// Read relevant files in the project
-	projectContext, err := a.getProjectContext(workingDir)
+	budget := planBudget(a.config.Agent)
+
+	notes := workspaceSection(a.context, workingDir)
+	notes += dependenciesSection(a.context.Root())
+	notes += diagnosticsSection(ctx, a.context)
+	symbols := a.mentionedSymbols(query)
+	notes += symbolsSection(symbols)
+	notes += projectMemorySection(workingDir)
+	if a.audit != nil {
+		notes += citationInstructions
+	}
+	notes += styleSection(a.config.Agent.OutputStyle)
+
+	gitStatus := truncateTokens(a.getGitStatusString(ctx), budget.Git)
+
+	// File contents get their own share plus whatever the instructions,
+	// notes and git status leave of theirs, or less when they overrun it
+	fileTokens := budget.Files + budget.System + budget.Git - estimateTokens(a.config.Agent.SystemPrompt+notes+gitStatus)
+
+	// Read relevant files in the project, from the workspace root in a
+	// monorepo
+	structure, files, err := a.getProjectContext(ctx, a.context.Root(), query, symbols, budget.Structure, max(fileTokens, budget.Files/4), sent)
 	if err != nil {
-		return "", fmt.Errorf("failed to get project context: %w", err)
+		return "", "", fmt.Errorf("failed to get project context: %w", err)
 	}
 
 	// Build enhanced system prompt with project context
@@ -119,45 +399,28 @@ func (a *Agent) ProcessInput(ctx context.Context, input string) (string, error)
 ### Project Files:
 %s
 
+The contents of the project's files are in the conversation: each message carries the files that changed since they were last sent.
+
 ### Recent Git Status:
 %s
 
 Use this context to provide accurate assistance with the codebase.`,
 		a.config.Agent.SystemPrompt,
 		workingDir,
-		projectContext,
-		a.getGitStatusString(ctx))
-
-	messages := []llm.Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: input},
-	}
-
-	req := llm.ChatRequest{
-		Model:       a.config.LMStudio.Model,
-		Messages:    messages,
-		Tools:       a.tools.GetAvailable(),
-		MaxTokens:   a.config.Agent.MaxTokens,
-		Temperature: a.config.Agent.Temperature,
-	}
+		structure,
+		gitStatus)
 
-	resp, err := a.llmClient.Chat(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("LLM request failed: %w", err)
+	if files != "" {
+		files = "\n\n## Project Files\n" + files
 	}
-
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
-	}
-
-	return resp.Choices[0].Message.Content, nil
+	return systemPrompt + notes, files, nil
 }
 
 func (a *Agent) isSourceFile(path string) bool {
 	sourceExts := []string{
 		".go", ".py", ".js", ".ts", ".jsx", ".tsx", ".java", ".c", ".cpp", ".h",
 		".cs", ".php", ".rb", ".rs", ".swift", ".kt", ".scala", ".clj",
-		".yaml", ".yml", ".json", ".toml", ".md", ".txt", ".sql",
+		".yaml", ".yml", ".json", ".toml", ".md", ".txt", ".sql", ".ipynb",
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
@@ -187,11 +450,14 @@ func (a *Agent) getGitStatusString(ctx context.Context) string {
 
 	var statusStr strings.Builder
 	statusStr.WriteString(fmt.Sprintf("Branch: %s\n", status.Branch))
+	if status.Upstream != "" {
+		statusStr.WriteString(fmt.Sprintf("Upstream: %s (ahead %d, behind %d)\n", status.Upstream, status.Ahead, status.Behind))
+	}
 
 	if len(status.Changes) > 0 {
 		statusStr.WriteString("Changes:\n")
 		for _, change := range status.Changes {
-			statusStr.WriteString(fmt.Sprintf("  %s: %s\n", change.Type, change.File))
+			statusStr.WriteString(fmt.Sprintf("  %s\n", change))
 		}
 	} else {
 		statusStr.WriteString("No changes")
@@ -200,48 +466,160 @@ func (a *Agent) getGitStatusString(ctx context.Context) string {
 	return statusStr.String()
 }
 
-func (a *Agent) getProjectContext(workingDir string) (string, error) {
-	var context strings.Builder
+// getProjectContext renders the project tree within structureTokens and,
+// separately, the files relevant to query, which names symbols, within
+// maxTokens, plus what the tree leaves unused. Files whose version the
+// conversation sent tracks still holds are only named.
+func (a *Agent) getProjectContext(ctx context.Context, workingDir, query string, symbols []projectctx.SymbolMention, structureTokens, maxTokens int, sent *sentFiles) (string, string, error) {
+	var context, files strings.Builder
 	var totalTokens int
-	const maxTokens = 2000 // Reserve tokens for context
+
+	// The project summary, when there is one, stands in for the tree
+	structure, err := a.context.ProjectSummary(ctx)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if structure != "" {
+		context.WriteString("## Project Summary:\n")
+	} else {
+		context.WriteString("## Project Structure:\n")
+		structure, _ = a.getProjectStructure(workingDir)
+	}
+	structure = truncateTokens(structure, structureTokens)
+	context.WriteString(structure)
+	maxTokens += max(structureTokens-estimateTokens(structure), 0)
+	structure = context.String()
+
+	// Pinned files are always included, in full unless one alone is over
+	// the budget; the rest share what they leave
+	pinned := make(map[string]bool)
+	if pins := a.context.PinnedFiles(); len(pins) > 0 {
+		files.WriteString("\n## Pinned Files:\n")
+		for _, file := range pins {
+			pinned[file.Path] = true
+			relPath, _ := filepath.Rel(workingDir, file.Path)
+			content, err := file.ReadContent()
+			if err != nil {
+				continue
+			}
+			if tokens := estimateTokens(content); tokens > maxTokens && file.Outline() != "" {
+				files.WriteString(sent.entry(file.Path, fmt.Sprintf("\n--- %s (outline, too large to pin in full) ---\n", relPath), file.Outline()))
+				maxTokens -= estimateTokens(file.Outline())
+			} else {
+				files.WriteString(sent.entry(file.Path, fmt.Sprintf("\n--- %s ---\n", relPath), content+"\n"))
+				maxTokens -= tokens
+			}
+		}
+		maxTokens = max(maxTokens, 0)
+	}
+
+	// Directories added to the context share half the files budget
+	rootTokens := 0
+	if len(a.context.Roots()) > 0 {
+		rootTokens = maxTokens / 2
+		maxTokens -= rootTokens
+	}
+
+	// With semantic retrieval, the chunks closest to the request replace
+	// the fixed selection of files
+	if query != "" && a.context.RetrievalEnabled() {
+		chunks, err := a.context.RelevantChunks(ctx, query, a.config.Context.RetrievalTopK)
+		if err == nil && len(chunks) > 0 {
+			files.WriteString("\n## Relevant Code:\n")
+			for i, chunk := range chunks {
+				if pinned[filepath.Join(a.context.Root(), filepath.FromSlash(chunk.File))] {
+					continue // Already in full
+				}
+				tokens := estimateTokens(chunk.Text)
+				if i > 0 && totalTokens+tokens > maxTokens {
+					break
+				}
+				files.WriteString(fmt.Sprintf("\n--- %s:%d-%d ---\n%s\n", chunk.File, chunk.StartLine, chunk.EndLine, chunk.Text))
+				totalTokens += tokens
+			}
+			return structure, files.String() + a.rootsContext(rootTokens, sent), nil
+		}
+		if err != nil {
+			log.Printf("Warning: semantic retrieval failed, using the default file selection: %v", err)
+		}
+	}
 
 	// Get list of relevant files, prioritizing by importance
-	files, err := a.getRelevantFiles(workingDir)
+	relevant, err := a.getRelevantFiles(workingDir, query, symbols)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	context.WriteString("## Project Structure:\n")
-	structure, _ := a.getProjectStructure(workingDir)
-	context.WriteString(structure)
-	context.WriteString("\n## Key Files:\n")
+	files.WriteString("\n## Key Files:\n")
 
-	for _, fileInfo := range files {
+	included := make(map[[md5.Size]byte]string)
+	for i, fileInfo := range relevant {
+		if pinned[fileInfo.Path] {
+			continue
+		}
 		if totalTokens > maxTokens {
-			context.WriteString(fmt.Sprintf("\n... and %d more files (truncated due to context limit)\n", len(files)-len(context.String())))
+			files.WriteString(fmt.Sprintf("\n... and %d more files (truncated due to context limit)\n", len(relevant)-i))
 			break
 		}
 
-		content, err := os.ReadFile(fileInfo.Path)
+		// Notebooks are included as their cells, without outputs
+		content, err := projectctx.ReadSource(fileInfo.Path)
 		if err != nil {
 			continue
 		}
 
-		// Estimate tokens (rough: 4 chars per token)
-		estimatedTokens := len(content) / 4
-		if totalTokens+estimatedTokens > maxTokens {
-			// Include just the file header/imports for context
+		if !projectctx.IsText(content) {
+			files.WriteString(fmt.Sprintf("\n--- %s (binary or not UTF-8, %d bytes, omitted) ---\n", fileInfo.RelPath, len(content)))
+			continue
+		}
+
+		// Point at the earlier copy of a duplicated file instead of
+		// repeating it
+		sum := md5.Sum(content)
+		if original, ok := included[sum]; ok && len(content) > 0 {
+			files.WriteString(fmt.Sprintf("\n--- %s (identical to %s) ---\n", fileInfo.RelPath, original))
+			continue
+		}
+		included[sum] = fileInfo.RelPath
+
+		// Estimate tokens (rough: 4 chars per token); a file may take up to
+		// its share of the budget, or what is left when that is less
+		estimatedTokens := estimateTokens(string(content))
+		fileTokens := maxTokens / maxFileShare
+		if totalTokens+estimatedTokens > maxTokens || estimatedTokens > fileTokens {
+			// Include the parts of the file relevant to the request, else
+			// an outline of what it imports and exports, or just its
+			// header when neither was found
+			budget := min(max(maxTokens-totalTokens, maxTokens/4), fileTokens)
+			var chunks []projectctx.FileChunk
+			if len(content) <= maxContextFileSize {
+				chunks = projectctx.SelectChunks(fileInfo.Path, string(content), query, budget)
+			}
+			if len(chunks) > 0 {
+				files.WriteString(fmt.Sprintf("\n--- %s (excerpts) ---\n", fileInfo.RelPath))
+				for _, chunk := range chunks {
+					files.WriteString(fmt.Sprintf("[%s]\n%s\n", chunk.Heading(), chunk.Text))
+					totalTokens += estimateTokens(chunk.Text)
+				}
+				continue
+			}
+			if outline := projectctx.FileOutline(fileInfo.Path, string(content)); outline != "" {
+				lines := strings.Count(string(content), "\n") + 1
+				files.WriteString(sent.entry(fileInfo.Path, fmt.Sprintf("\n--- %s (outline of %d lines, too large to include) ---\n", fileInfo.RelPath, lines), outline))
+				totalTokens += estimateTokens(outline)
+				continue
+			}
 			lines := strings.Split(string(content), "\n")
 			preview := strings.Join(lines[:min(10, len(lines))], "\n")
-			context.WriteString(fmt.Sprintf("\n--- %s (preview) ---\n%s\n... (truncated)\n", fileInfo.RelPath, preview))
-			totalTokens += len(preview) / 4
+			files.WriteString(fmt.Sprintf("\n--- %s (preview) ---\n%s\n... (truncated)\n", fileInfo.RelPath, preview))
+			totalTokens += estimateTokens(preview)
 		} else {
-			context.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", fileInfo.RelPath, string(content)))
+			files.WriteString(sent.entry(fileInfo.Path, fmt.Sprintf("\n--- %s ---\n", fileInfo.RelPath), string(content)+"\n"))
 			totalTokens += estimatedTokens
 		}
 	}
 
-	return context.String(), nil
+	return structure, files.String() + a.rootsContext(rootTokens, sent), nil
 }
 
 type FileInfo struct {
@@ -250,70 +628,230 @@ type FileInfo struct {
 	Size     int64
 	ModTime  time.Time
 	Priority int // Higher = more important
+
+	// Relevance combines lexical relevance to the request with how much
+	// and how recently the file changed and how often it was worked on in
+	// sessions, between 0 and 1. It is 0 for all files when the request
+	// matched none, there is no git history and no file was worked on.
+	Relevance float64
 }
 
-func (a *Agent) getRelevantFiles(workingDir string) ([]FileInfo, error) {
+const (
+	// maxContextFileSize is the largest file excerpted for the context;
+	// larger ones are outlined, and files over maxOutlineFileSize are left
+	// out
+	maxContextFileSize = 512 * 1024
+	maxOutlineFileSize = 8 * 1024 * 1024
+
+	// maxFileShare divides the files budget into the most a single file
+	// may take before it is excerpted or outlined
+	maxFileShare = 2
+
+	// lexicalWeight is the share of Relevance from BM25, churnWeight the
+	// share from how often the file changed in recent commits and
+	// frecencyWeight the share from how often and how recently it was
+	// read or edited in sessions; the rest comes from how recently the
+	// file was modified, which a formatter run or a checkout also does
+	lexicalWeight   = 0.6
+	churnWeight     = 0.1
+	frecencyWeight  = 0.2
+	recencyHalfLife = 7 * 24 * time.Hour
+
+	// maxCoChangedFiles is how many files that historically changed along
+	// with the files a request names are promoted
+	maxCoChangedFiles = 10
+)
+
+// getRelevantFiles orders the files to show for a request: files the query
+// names or that declare the symbols it names, the project files they
+// import, the files calling those symbols and the files usually changed
+// with them come first, then the rest by relevance to the query and git churn,
+// falling back to priority when neither says anything.
+// getProjectContext takes them in order until the budget runs out.
+func (a *Agent) getRelevantFiles(workingDir, query string, symbols []projectctx.SymbolMention) ([]FileInfo, error) {
 	var files []FileInfo
 
-	err := filepath.Walk(workingDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	entries, err := a.context.Entries()
+	if err != nil {
+		return nil, err
+	}
 
-		// Skip hidden and build directories
-		if strings.HasPrefix(filepath.Base(path), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+	history := a.gitHistory()
+	targeted := a.targetedFiles(query, symbols, history)
+	scores, maxScore := a.lexicalScores(query)
+
+	var churn map[string]float64
+	var maxChurn float64
+	if history != nil {
+		churn = history.Churn()
+		for _, c := range churn {
+			maxChurn = max(maxChurn, c)
 		}
+	}
 
-		if info.IsDir() {
-			skipDirs := []string{"node_modules", "vendor", "target", "build", "dist", ".git"}
-			for _, skip := range skipDirs {
-				if info.Name() == skip {
-					return filepath.SkipDir
-				}
-			}
-			return nil
+	frecency := a.context.Frecency()
+	var maxFrecency float64
+	for _, f := range frecency {
+		maxFrecency = max(maxFrecency, f)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(workingDir, entry.RelPath)
+		if entry.IsDir || !a.isSourceFile(path) || entry.Size > maxOutlineFileSize && !projectctx.IsNotebook(path) {
+			continue
 		}
 
-		if !a.isSourceFile(path) || info.Size() > 20000 {
-			return nil
+		if a.filter.Excluded(path, entry.RelPath, entry.Size) != "" {
+			continue
+		}
+		priority, ok := targeted[entry.RelPath]
+		if !ok {
+			priority = a.getFilePriority(entry.RelPath)
 		}
 
-		relPath, _ := filepath.Rel(workingDir, path)
-		priority := a.getFilePriority(relPath)
+		var relevance float64
+		if maxScore > 0 || maxChurn > 0 || maxFrecency > 0 {
+			recency := math.Exp2(-float64(time.Since(entry.ModTime)) / float64(recencyHalfLife))
+			relevance = (1 - lexicalWeight - churnWeight - frecencyWeight) * recency
+			if maxScore > 0 {
+				relevance += lexicalWeight * scores[entry.RelPath] / maxScore
+			}
+			if maxChurn > 0 {
+				relevance += churnWeight * churn[filepath.ToSlash(entry.RelPath)] / maxChurn
+			}
+			if maxFrecency > 0 {
+				relevance += frecencyWeight * frecency[entry.RelPath] / maxFrecency
+			}
+		}
 
 		files = append(files, FileInfo{
-			Path:     path,
-			RelPath:  relPath,
-			Size:     info.Size(),
-			ModTime:  info.ModTime(),
-			Priority: priority,
+			Path:      path,
+			RelPath:   entry.RelPath,
+			Size:      entry.Size,
+			ModTime:   entry.ModTime,
+			Priority:  priority,
+			Relevance: relevance,
 		})
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
-	// Sort by priority (high to low), then by modification time (recent first)
+	// Sort files the request targets first, then by relevance, priority
+	// (high to low) and modification time (recent first)
 	sort.Slice(files, func(i, j int) bool {
+		_, iTargeted := targeted[files[i].RelPath]
+		_, jTargeted := targeted[files[j].RelPath]
+		if iTargeted != jTargeted {
+			return iTargeted
+		}
+		if !iTargeted && files[i].Relevance != files[j].Relevance {
+			return files[i].Relevance > files[j].Relevance
+		}
 		if files[i].Priority != files[j].Priority {
 			return files[i].Priority > files[j].Priority
 		}
 		return files[i].ModTime.After(files[j].ModTime)
 	})
 
-	// Limit to most important files
-	if len(files) > 10 {
-		files = files[:10]
+	return files, nil
+}
+
+// lexicalScores ranks project files by BM25 against query, returning the
+// scores by relative path and the highest of them.
+func (a *Agent) lexicalScores(query string) (map[string]float64, float64) {
+	if query == "" {
+		return nil, 0
+	}
+	scores, err := a.context.LexicalScores(query)
+	if err != nil {
+		log.Printf("Warning: failed to rank files by relevance to the request: %v", err)
+		return nil, 0
 	}
 
-	return files, nil
+	var maxScore float64
+	for _, score := range scores {
+		maxScore = max(maxScore, score)
+	}
+	return scores, maxScore
+}
+
+// targetedFiles returns priorities for the files query mentions or that
+// declare the symbols it names, their dependencies, the call sites of the
+// symbols and the files that usually change along with them, above those
+// of getFilePriority; nearer dependencies and more frequent companions
+// rank higher.
+func (a *Agent) targetedFiles(query string, symbols []projectctx.SymbolMention, history *projectctx.GitHistory) map[string]int {
+	targeted := make(map[string]int)
+	if query == "" {
+		return targeted
+	}
+
+	targets, _ := a.context.MentionedFiles(query)
+	for _, symbol := range symbols {
+		for _, def := range symbol.Definitions {
+			if relPath := filepath.FromSlash(def.File); !slices.Contains(targets, relPath) {
+				targets = append(targets, relPath)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return targeted
+	}
+	for _, target := range targets {
+		targeted[target] = 300
+	}
+
+	related, err := a.context.RelatedFiles(targets)
+	if err != nil {
+		log.Printf("Warning: failed to resolve the dependencies of %s: %v", strings.Join(targets, ", "), err)
+	}
+	for i, dep := range related {
+		targeted[filepath.FromSlash(dep)] = 200 - i
+	}
+
+	// Callers rank between imports and companions
+	callers := 0
+	for _, symbol := range symbols {
+		for _, site := range symbol.CallSites {
+			relPath := filepath.FromSlash(site.File)
+			if _, ok := targeted[relPath]; !ok {
+				targeted[relPath] = 150 - callers
+				callers++
+			}
+		}
+	}
+
+	if history == nil {
+		return targeted
+	}
+	slashTargets := make([]string, len(targets))
+	for i, target := range targets {
+		slashTargets[i] = filepath.ToSlash(target)
+	}
+	// Files deleted since they were committed are skipped
+	promoted := 0
+	for _, file := range history.CoChanged(slashTargets, 3*maxCoChangedFiles) {
+		relPath := filepath.FromSlash(file)
+		if _, ok := targeted[relPath]; ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(a.context.Root(), relPath)); err != nil {
+			continue
+		}
+		targeted[relPath] = 100 - promoted
+		if promoted++; promoted == maxCoChangedFiles {
+			break
+		}
+	}
+	return targeted
+}
+
+// gitHistory returns what the git log says about the project's files, or
+// nil when the project is not a git repository or the log can't be read.
+func (a *Agent) gitHistory() *projectctx.GitHistory {
+	history, err := a.context.GitHistory()
+	if err != nil {
+		log.Printf("Warning: failed to read the git history: %v", err)
+	}
+	return history
 }
 
 func (a *Agent) getFilePriority(relPath string) int {
@@ -336,45 +874,28 @@ func (a *Agent) getFilePriority(relPath string) int {
 
 func (a *Agent) getProjectStructure(workingDir string) (string, error) {
 	var structure strings.Builder
+	structure.WriteString(filepath.Base(workingDir) + "/\n")
 
-	err := filepath.Walk(workingDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if strings.HasPrefix(filepath.Base(path), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if info.IsDir() {
-			skipDirs := []string{"node_modules", "vendor", "target", "build", "dist", ".git"}
-			for _, skip := range skipDirs {
-				if info.Name() == skip {
-					return filepath.SkipDir
-				}
-			}
-		}
-
-		relPath, _ := filepath.Rel(workingDir, path)
-		depth := strings.Count(relPath, string(filepath.Separator))
+	entries, err := a.context.Entries()
+	for _, entry := range entries {
+		depth := strings.Count(entry.RelPath, string(filepath.Separator))
 
 		// Limit depth to avoid too much structure
 		if depth > 3 {
-			return nil
+			continue
 		}
 
 		indent := strings.Repeat("  ", depth)
-		if info.IsDir() {
-			structure.WriteString(fmt.Sprintf("%s%s/\n", indent, info.Name()))
-		} else if a.isSourceFile(path) {
-			structure.WriteString(fmt.Sprintf("%s%s\n", indent, info.Name()))
+		name := filepath.Base(entry.RelPath)
+		if entry.IsDir {
+			structure.WriteString(fmt.Sprintf("%s%s/\n", indent, name))
+		} else if a.isSourceFile(name) {
+			structure.WriteString(fmt.Sprintf("%s%s\n", indent, name))
 		}
-
-		return nil
-	})
+	}
+	if a.context.Sampled() {
+		structure.WriteString(projectctx.SampledNote)
+	}
 
 	return structure.String(), err
 }
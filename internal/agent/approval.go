@@ -0,0 +1,90 @@
+// Package: internal/agent/approval.go
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/permissions"
+)
+
+// ApprovalRequest describes a file edit, a command or a web request the
+// policy does not auto-accept, or an MCP server's request to have the
+// model generate text.
+type ApprovalRequest struct {
+	Tool      string
+	Arguments string
+	Paths     []permissions.PathClass
+	Commands  []string // Set for a command instead of Paths
+	URLs      []string // Set for a web request instead of Paths
+
+	// Set for a sampling request: the server asking, the model it gets and
+	// the last message it sent
+	Server string
+	Model  string
+	Prompt string
+}
+
+// Approver asks the user whether an edit, a command, a web request or a
+// sampling request may run.
+type Approver func(ApprovalRequest) bool
+
+type approverKey struct{}
+
+// WithApprover attaches an interactive approver to ctx. Runs without one
+// (headless mode) deny edits, commands and web requests that need
+// approval.
+func WithApprover(ctx context.Context, approve Approver) context.Context {
+	return context.WithValue(ctx, approverKey{}, approve)
+}
+
+// Permissions returns the edit policy in effect.
+func (a *Agent) Permissions() *permissions.Policy {
+	return a.permissions
+}
+
+// authorize checks a tool call against the edit policy, asking the
+// approver for commands, web requests and edits that are not
+// auto-accepted. The commands are approved first: telling which files an
+// edit changes can take running one.
+func (a *Agent) authorize(ctx context.Context, call llm.ToolCall, args map[string]interface{}) error {
+	approve, _ := ctx.Value(approverKey{}).(Approver)
+
+	if commands := a.tools.Commands(call.Function.Name, args); len(commands) > 0 && a.permissions.CommandNeedsApproval() {
+		if approve == nil {
+			return fmt.Errorf("running `%s` requires approval (permissions.auto_accept is %q) and no one is available to approve it", commands[0], a.permissions.AutoAccept())
+		}
+		if !approve(ApprovalRequest{Tool: call.Function.Name, Arguments: call.Function.Arguments, Commands: commands}) {
+			return fmt.Errorf("the user denied running `%s`", commands[0])
+		}
+	}
+
+	if urls := a.tools.URLs(call.Function.Name, args); len(urls) > 0 && a.permissions.FetchNeedsApproval() {
+		if approve == nil {
+			return fmt.Errorf("fetching %s requires approval (permissions.auto_accept is %q) and no one is available to approve it", urls[0], a.permissions.AutoAccept())
+		}
+		if !approve(ApprovalRequest{Tool: call.Function.Name, Arguments: call.Function.Arguments, URLs: urls}) {
+			return fmt.Errorf("the user denied fetching %s", urls[0])
+		}
+	}
+
+	paths := a.tools.MutatedPaths(call.Function.Name, args)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	decision := a.permissions.Evaluate(paths)
+	if !decision.NeedsApproval {
+		return nil
+	}
+
+	if approve == nil {
+		return fmt.Errorf("editing %s requires approval (permissions.auto_accept is %q) and no one is available to approve it", paths[0], a.permissions.AutoAccept())
+	}
+
+	if !approve(ApprovalRequest{Tool: call.Function.Name, Arguments: call.Function.Arguments, Paths: decision.Paths}) {
+		return fmt.Errorf("the user denied the edit to %s", paths[0])
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package: internal/agent/ask_agent.go
+package agent
+
+import (
+	builtinContext "context"
+	"fmt"
+	"io"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+// askAgentToolName is the tool the MCP server offers hosts to delegate a
+// task to the agent. The agent's own model is not offered it.
+const askAgentToolName = "ask_agent"
+
+// askAgentTool has the agent answer a prompt as it answers the user, with
+// the project's context and its tools.
+type askAgentTool struct {
+	agent *EnhancedAgent
+}
+
+func (t *askAgentTool) Name() string { return askAgentToolName }
+
+func (t *askAgentTool) Description() string {
+	return "Delegate a coding task or question about this project to claude-go's agent, which reads the project, runs its tools (editing files, running commands) and returns its answer"
+}
+
+func (t *askAgentTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "The task or question, with whatever the agent needs to know",
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+func (t *askAgentTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(builtinContext.Background(), args, nil)
+}
+
+// ExecuteContext answers the prompt, writing the tools the agent runs and
+// their output to stream as it goes.
+func (t *askAgentTool) ExecuteContext(ctx builtinContext.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	prompt, _ := args["prompt"].(string)
+	if prompt == "" {
+		return "", fmt.Errorf("prompt is required")
+	}
+	return t.agent.Ask(ctx, prompt, stream)
+}
+
+// Ask answers prompt in a conversation of its own, apart from the
+// session's: with the project context a turn of the session gets, running
+// the tools the model calls until it answers. The tools run through the
+// agent's tool loop, so the edit policy, audit log and result elision
+// apply; no one is there to approve, so edits that need approval are
+// denied. The tools it runs and their output are written to stream, when
+// given.
+func (a *EnhancedAgent) Ask(ctx builtinContext.Context, prompt string, stream io.Writer) (string, error) {
+	projectCtx, err := a.contextManager.GetProjectContext()
+	if err != nil {
+		return "", fmt.Errorf("failed to get project context: %w", err)
+	}
+
+	var sent sentFiles
+	messages := []llm.Message{
+		{Role: "system", Content: a.buildEnhancedSystemPrompt(projectCtx)},
+		{Role: "user", Content: prompt + a.filesMessage(projectCtx, &sent)},
+	}
+
+	// An approver the host's context might carry is not the user's
+	ctx = builtinContext.WithValue(ctx, approverKey{}, Approver(nil))
+	return a.loop.runConversation(ctx, messages, func(e Event) {
+		if stream == nil {
+			return
+		}
+		switch e.Type {
+		case "tool_call":
+			fmt.Fprintf(stream, "Running %s\n", e.Tool)
+		case "tool_output":
+			io.WriteString(stream, e.Content)
+		}
+	})
+}
+
+// modelTools returns the tools offered to the model: all but ask_agent,
+// which is for MCP hosts.
+func (a *Agent) modelTools() []llm.Tool {
+	available := a.tools.GetAvailable()
+	offered := available[:0]
+	for _, tool := range available {
+		if tool.Function.Name != askAgentToolName {
+			offered = append(offered, tool)
+		}
+	}
+	return offered
+}
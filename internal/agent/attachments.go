@@ -0,0 +1,198 @@
+// Package: internal/agent/attachments.go
+package agent
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+)
+
+const (
+	maxAttachmentBytes = 256 * 1024
+	maxAttachmentChars = 24000
+	csvPreviewRows     = 25
+)
+
+// Attachment is a file the user explicitly handed to the model, as opposed
+// to files retrieved as project context.
+type Attachment struct {
+	Path    string
+	Kind    string // csv, json or text
+	Summary string
+	Content string
+}
+
+// LoadAttachment reads and renders a file for inclusion in a message.
+func LoadAttachment(path string) (Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxAttachmentBytes {
+		return Attachment{}, fmt.Errorf("%s is too large to attach (%d KB, limit %d KB)", path, info.Size()/1024, maxAttachmentBytes/1024)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	if !projectctx.IsText(data) {
+		return Attachment{}, fmt.Errorf("%s looks like a binary file", path)
+	}
+
+	att := Attachment{Path: path}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv":
+		att.Kind = "csv"
+		att.Summary, att.Content, err = renderCSV(data, strings.EqualFold(filepath.Ext(path), ".tsv"))
+	case ".json":
+		att.Kind = "json"
+		att.Summary, att.Content, err = renderJSON(data)
+	default:
+		att.Kind = "text"
+		att.Summary = fmt.Sprintf("%d lines", strings.Count(string(data), "\n")+1)
+		att.Content = string(data)
+	}
+
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if len(att.Content) > maxAttachmentChars {
+		att.Content = att.Content[:maxAttachmentChars] + "\n... (truncated)"
+	}
+
+	return att, nil
+}
+
+// Attach queues a file to be sent with the next message.
+func (a *Agent) Attach(path string) (Attachment, error) {
+	att, err := LoadAttachment(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	a.attachments = append(a.attachments, att)
+	a.context.RecordAccess(projectctx.AccessRead, path)
+	return att, nil
+}
+
+func (a *Agent) PendingAttachments() []Attachment {
+	return a.attachments
+}
+
+func (a *Agent) ClearAttachments() {
+	a.attachments = nil
+}
+
+// userMessage combines the input with any pending attachments, which are
+// consumed by the call.
+func (a *Agent) userMessage(input string) string {
+	if len(a.attachments) == 0 {
+		return input
+	}
+
+	var msg strings.Builder
+	msg.WriteString(input)
+	msg.WriteString("\n\n## Attached Files\n\nThe user explicitly attached these files to this message:\n")
+
+	for _, att := range a.attachments {
+		lang := att.Kind
+		if att.Kind == "csv" {
+			lang = "markdown"
+		}
+		msg.WriteString(fmt.Sprintf("\n### %s (%s, %s)\n```%s\n%s\n```\n", att.Path, att.Kind, att.Summary, lang, strings.TrimRight(att.Content, "\n")))
+	}
+
+	a.attachments = nil
+	return msg.String()
+}
+
+// renderCSV turns the header and first rows into a markdown table.
+func renderCSV(data []byte, tabs bool) (string, string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	if tabs {
+		reader.Comma = '\t'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", "", err
+	}
+	if len(records) == 0 {
+		return "empty", "", nil
+	}
+
+	header := records[0]
+	rows := records[1:]
+
+	var table strings.Builder
+	table.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	table.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+
+	for i, row := range rows {
+		if i >= csvPreviewRows {
+			table.WriteString(fmt.Sprintf("\n... %d more rows\n", len(rows)-csvPreviewRows))
+			break
+		}
+		table.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	summary := fmt.Sprintf("%d rows, columns: %s", len(rows), strings.Join(header, ", "))
+	return summary, table.String(), nil
+}
+
+// renderJSON pretty-prints the document and describes its top-level shape.
+func renderJSON(data []byte) (string, string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", "", err
+	}
+
+	pretty, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+
+	var summary string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		summary = "object with keys: " + strings.Join(keys, ", ")
+	case []interface{}:
+		summary = fmt.Sprintf("array of %d items", len(v))
+	default:
+		summary = typeName(value)
+	}
+
+	return summary, string(pretty), nil
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,136 @@
+// Package: internal/agent/branch.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const (
+	defaultBranchPattern = "{type}/{ticket}-{slug}"
+	defaultTicketPattern = `\b[A-Z][A-Z0-9]+-\d+\b`
+	maxBranchSlugWords   = 6
+)
+
+var (
+	branchSlugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+	branchSeparators  = regexp.MustCompile(`([-_])[-_]+`)
+	branchTypes       = []interface{}{"feat", "fix", "docs", "refactor", "perf", "test", "chore"}
+)
+
+var branchSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"type":    map[string]interface{}{"type": "string", "enum": branchTypes},
+		"summary": map[string]interface{}{"type": "string", "minLength": float64(1)},
+	},
+	"required": []interface{}{"type", "summary"},
+}
+
+// SuggestBranchName turns a one-line task description into a branch name
+// following git.branch_pattern. The model picks the change type and a
+// short summary; the ticket comes from the description itself.
+func (a *Agent) SuggestBranchName(ctx context.Context, description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return "", fmt.Errorf("describe the task the branch is for")
+	}
+
+	git := a.config.Git
+	ticketPattern := git.TicketPattern
+	if ticketPattern == "" {
+		ticketPattern = defaultTicketPattern
+	}
+	ticketRe, err := regexp.Compile(ticketPattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid git.ticket_pattern: %w", err)
+	}
+	ticket := ticketRe.FindString(description)
+
+	instructions, err := schemaInstructions(branchSchema)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: `You name git branches. Classify the task as one of the given types and summarize it in two to five lowercase words (no ticket IDs, no filler words like "the" or "a").` + instructions},
+		{Role: "user", Content: "Task: " + description},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, branchSchema, 0.2)
+	if err != nil {
+		return "", fmt.Errorf("failed to suggest a branch name: %w", err)
+	}
+
+	var suggestion struct {
+		Type    string `json:"type"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal(raw, &suggestion); err != nil {
+		return "", fmt.Errorf("failed to decode branch name: %w", err)
+	}
+
+	summary := strings.ToLower(suggestion.Summary)
+	if ticket != "" {
+		summary = strings.ReplaceAll(summary, strings.ToLower(ticket), "")
+	}
+
+	return formatBranchName(ctx, git.BranchPattern, git.BranchCase, suggestion.Type, ticket, summary)
+}
+
+// formatBranchName fills in the branch pattern and cleans up separators
+// left behind by empty placeholders, e.g. a missing ticket.
+func formatBranchName(ctx context.Context, pattern, casing, kind, ticket, summary string) (string, error) {
+	if pattern == "" {
+		pattern = defaultBranchPattern
+	}
+
+	separator := "-"
+	switch casing {
+	case "", "kebab":
+	case "snake":
+		separator = "_"
+	default:
+		return "", fmt.Errorf("unknown git.branch_case %q (available: kebab, snake)", casing)
+	}
+
+	words := strings.Fields(branchSlugInvalid.ReplaceAllString(strings.ToLower(summary), " "))
+	if len(words) > maxBranchSlugWords {
+		words = words[:maxBranchSlugWords]
+	}
+	slug := strings.Join(words, separator)
+	if slug == "" {
+		return "", fmt.Errorf("could not derive a branch name from %q", summary)
+	}
+
+	name := strings.NewReplacer("{type}", kind, "{ticket}", ticket, "{slug}", slug).Replace(pattern)
+
+	// Drop separators next to empty placeholders: "feat/-x" -> "feat/x"
+	parts := strings.Split(name, "/")
+	kept := parts[:0]
+	for _, part := range parts {
+		part = strings.Trim(part, "-_.")
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	name = branchSeparators.ReplaceAllString(strings.Join(kept, "/"), "$1")
+
+	if _, err := runGit(ctx, "check-ref-format", "--branch", name); err != nil {
+		return "", fmt.Errorf("%q is not a valid branch name; check git.branch_pattern", name)
+	}
+	return name, nil
+}
+
+// CreateBranch creates the branch from HEAD and switches to it, carrying
+// uncommitted changes along.
+func (a *Agent) CreateBranch(ctx context.Context, name string) error {
+	_, err := runGit(ctx, "switch", "--create", name)
+	return err
+}
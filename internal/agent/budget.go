@@ -0,0 +1,97 @@
+// Package: internal/agent/budget.go
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	defaultContextWindow = 16384
+
+	// minPromptTokens keeps a usable prompt when max_tokens claims most of
+	// a small context window
+	minPromptTokens = 2048
+)
+
+// promptBudget is how many tokens each section of a request may use.
+type promptBudget struct {
+	System    int
+	Structure int
+	Files     int
+	Git       int
+	History   int
+}
+
+// planBudget splits the context window, less the tokens reserved for the
+// response, between the sections of the prompt by the configured ratios.
+func planBudget(cfg config.AgentConfig) promptBudget {
+	window := cfg.ContextWindow
+	if window <= 0 {
+		window = defaultContextWindow
+	}
+	available := max(window-max(cfg.MaxTokens, 0), min(window, minPromptTokens))
+
+	ratios := cfg.Budget
+	total := max(ratios.System, 0) + max(ratios.Structure, 0) + max(ratios.Files, 0) + max(ratios.Git, 0) + max(ratios.History, 0)
+	if total <= 0 {
+		ratios = config.DefaultBudget()
+		total = ratios.System + ratios.Structure + ratios.Files + ratios.Git + ratios.History
+	}
+
+	share := func(ratio float64) int {
+		return int(float64(available) * max(ratio, 0) / total)
+	}
+	return promptBudget{
+		System:    share(ratios.System),
+		Structure: share(ratios.Structure),
+		Files:     share(ratios.Files),
+		Git:       share(ratios.Git),
+		History:   share(ratios.History),
+	}
+}
+
+// estimateTokens approximates the token count of text at 4 characters per
+// token.
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// truncateTokens cuts text at the last line that fits in maxTokens, noting
+// how many lines were left out.
+func truncateTokens(text string, maxTokens int) string {
+	limit := maxTokens * 4
+	if len(text) <= limit {
+		return text
+	}
+
+	cut := strings.LastIndex(text[:limit], "\n") + 1
+	omitted := strings.Count(text[cut:], "\n")
+	if !strings.HasSuffix(text, "\n") {
+		omitted++
+	}
+	return fmt.Sprintf("%s... (%d more lines left out to fit the context budget)\n", text[:cut], omitted)
+}
+
+// trimHistory drops the oldest messages until the rest fit in maxTokens,
+// always keeping the latest one. A reply left without the message it
+// answered is dropped as well.
+func trimHistory(messages []llm.Message, maxTokens int) []llm.Message {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += estimateTokens(msg.Content)
+	}
+
+	start := 0
+	for start < len(messages)-1 && tokens > maxTokens {
+		tokens -= estimateTokens(messages[start].Content)
+		start++
+	}
+	for start < len(messages)-1 && messages[start].Role != "user" {
+		start++
+	}
+	return messages[start:]
+}
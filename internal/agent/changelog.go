@@ -0,0 +1,294 @@
+// Package: internal/agent/changelog.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+// Changelog sections in the order they are written. Types missing here
+// (and commits that are not conventional) land in "other".
+var changelogGroups = []struct {
+	Type  string
+	Title string
+}{
+	{"breaking", "Breaking Changes"},
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"maintenance", "Maintenance"},
+	{"other", "Other Changes"},
+}
+
+var changelogTypeAliases = map[string]string{
+	"feature": "feat", "bugfix": "fix", "doc": "docs",
+	"build": "maintenance", "chore": "maintenance", "ci": "maintenance",
+	"style": "maintenance", "test": "maintenance", "tests": "maintenance",
+}
+
+var (
+	conventionalSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	changelogHeading    = regexp.MustCompile(`(?m)^## `)
+)
+
+type ChangelogCommit struct {
+	Hash    string `json:"hash"`
+	Type    string `json:"type"`
+	Scope   string `json:"scope,omitempty"`
+	Subject string `json:"subject"`
+}
+
+type ChangelogSection struct {
+	Title   string   `json:"title"`
+	Entries []string `json:"entries"`
+}
+
+type Changelog struct {
+	Version  string             `json:"version"`
+	Date     string             `json:"date"`
+	Sections []ChangelogSection `json:"sections"`
+}
+
+var changelogSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"sections": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"type":    map[string]interface{}{"type": "string"},
+					"entries": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				},
+				"required": []interface{}{"type", "entries"},
+			},
+		},
+	},
+	"required": []interface{}{"sections"},
+}
+
+// GenerateChangelog groups the commits in rng (e.g. "v1.2.0..HEAD") by
+// conventional-commit type and has the model rewrite them as entries for
+// readers of the changelog. An empty version is taken from the end of the
+// range: its tag, or "Unreleased".
+func (a *Agent) GenerateChangelog(ctx context.Context, rng, version string) (*Changelog, error) {
+	if !strings.Contains(rng, "..") {
+		rng += "..HEAD"
+	}
+	end := rng[strings.LastIndex(rng, "..")+2:]
+	if end == "" {
+		end = "HEAD"
+	}
+
+	commits, err := changelogCommits(ctx, rng)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits in %s", rng)
+	}
+
+	changelog := &Changelog{Version: version}
+	if changelog.Version == "" {
+		changelog.Version = "Unreleased"
+		if tag, err := runGit(ctx, "describe", "--tags", "--exact-match", end); err == nil {
+			changelog.Version = strings.TrimSpace(tag)
+		}
+	}
+	date, _ := runGit(ctx, "log", "-1", "--format=%as", end)
+	changelog.Date = strings.TrimSpace(date)
+
+	grouped := make(map[string][]ChangelogCommit)
+	var prompt strings.Builder
+	prompt.WriteString("Commits by type:\n")
+	for _, group := range changelogGroups {
+		for _, commit := range commits {
+			if commit.Type == group.Type {
+				grouped[group.Type] = append(grouped[group.Type], commit)
+			}
+		}
+		if len(grouped[group.Type]) == 0 {
+			continue
+		}
+		prompt.WriteString(fmt.Sprintf("\n[%s]\n", group.Type))
+		for _, commit := range grouped[group.Type] {
+			if commit.Scope != "" {
+				prompt.WriteString(fmt.Sprintf("- (%s) %s\n", commit.Scope, commit.Subject))
+			} else {
+				prompt.WriteString(fmt.Sprintf("- %s\n", commit.Subject))
+			}
+		}
+	}
+
+	instructions, err := schemaInstructions(changelogSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := `You write release notes. For each commit type given, rewrite its commits as changelog entries for users of the project: plain language, past tense or imperative consistently, one line each, prefixed with "**scope:** " when a scope is given. Merge commits that describe the same change, drop pure noise (typo fixes, merge artifacts), and never invent changes. Keep the type names exactly as given.` + instructions
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, changelogSchema, 0.3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write changelog entries: %w", err)
+	}
+
+	var written struct {
+		Sections []struct {
+			Type    string   `json:"type"`
+			Entries []string `json:"entries"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(raw, &written); err != nil {
+		return nil, fmt.Errorf("failed to decode changelog entries: %w", err)
+	}
+
+	entries := make(map[string][]string)
+	for _, section := range written.Sections {
+		entries[section.Type] = append(entries[section.Type], section.Entries...)
+	}
+
+	for _, group := range changelogGroups {
+		if len(grouped[group.Type]) == 0 {
+			continue
+		}
+		lines := entries[group.Type]
+		if len(lines) == 0 {
+			// The model skipped the group; keep the commits as they are
+			for _, commit := range grouped[group.Type] {
+				lines = append(lines, commit.Subject)
+			}
+		}
+		changelog.Sections = append(changelog.Sections, ChangelogSection{Title: group.Title, Entries: lines})
+	}
+
+	return changelog, nil
+}
+
+// changelogCommits lists the non-merge commits in rng, oldest first.
+func changelogCommits(ctx context.Context, rng string) ([]ChangelogCommit, error) {
+	output, err := runGit(ctx, "log", "--no-merges", "--reverse", "--format=%h%x1f%s%x1f%b%x1e", rng)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []ChangelogCommit
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+
+		commit := ChangelogCommit{Hash: fields[0], Type: "other", Subject: fields[1]}
+		if m := conventionalSubject.FindStringSubmatch(fields[1]); m != nil {
+			commit.Type = strings.ToLower(m[1])
+			commit.Scope = m[2]
+			commit.Subject = m[4]
+			if alias, ok := changelogTypeAliases[commit.Type]; ok {
+				commit.Type = alias
+			}
+			if m[3] == "!" {
+				commit.Type = "breaking"
+			}
+		}
+		if len(fields) == 3 && strings.Contains(fields[2], "BREAKING CHANGE") {
+			commit.Type = "breaking"
+		}
+		if !isChangelogGroup(commit.Type) {
+			commit.Type = "other"
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+func isChangelogGroup(kind string) bool {
+	for _, group := range changelogGroups {
+		if group.Type == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders the release as a CHANGELOG.md section.
+func (c *Changelog) Format() string {
+	var out strings.Builder
+	out.WriteString("## [" + c.Version + "]")
+	if c.Date != "" && c.Version != "Unreleased" {
+		out.WriteString(" - " + c.Date)
+	}
+	out.WriteString("\n")
+
+	for _, section := range c.Sections {
+		out.WriteString("\n### " + section.Title + "\n\n")
+		for _, entry := range section.Entries {
+			out.WriteString("- " + strings.TrimSpace(strings.TrimPrefix(entry, "- ")) + "\n")
+		}
+	}
+
+	return out.String()
+}
+
+// UpdateChangelog adds the release to the changelog at path, replacing a
+// section for the same version and otherwise inserting it above the newest
+// one. The file is written through the file tool, so the edit policy and
+// workspace coordination apply as they do to the model's edits.
+func (a *Agent) UpdateChangelog(ctx context.Context, path string, changelog *Changelog) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	args, _ := json.Marshal(map[string]interface{}{
+		"operation": "write",
+		"path":      path,
+		"content":   mergeChangelog(string(existing), changelog.Format()),
+	})
+	call := llm.ToolCall{Type: "function", Function: llm.FunctionCall{Name: "file_operations", Arguments: string(args)}}
+
+	_, _, err = a.executeToolCall(ctx, call, nil)
+	return err
+}
+
+func mergeChangelog(existing, section string) string {
+	if strings.TrimSpace(existing) == "" {
+		return "# Changelog\n\n" + section
+	}
+
+	heading := section[:strings.Index(section, "\n")]
+	version := heading[:strings.Index(heading, "]")+1]
+
+	starts := changelogHeading.FindAllStringIndex(existing, -1)
+	for i, loc := range starts {
+		if !strings.HasPrefix(existing[loc[0]:], version) {
+			continue
+		}
+		end := len(existing)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		return existing[:loc[0]] + section + "\n" + existing[end:]
+	}
+
+	if len(starts) == 0 {
+		return strings.TrimRight(existing, "\n") + "\n\n" + section
+	}
+	at := starts[0][0]
+	return existing[:at] + section + "\n" + existing[at:]
+}
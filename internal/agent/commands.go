@@ -0,0 +1,70 @@
+// Package: internal/agent/commands.go
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+)
+
+// registerProjectPrompts offers the project's custom slash-command
+// templates as MCP prompts. A template may start with a front matter block
+// giving its description and argument-hint; otherwise its first line
+// describes it. $ARGUMENTS in it takes the text given after the command.
+func (a *EnhancedAgent) registerProjectPrompts() error {
+	paths, err := filepath.Glob(filepath.Join(config.CommandsDir(a.workingDir), "*.md"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".md")
+		prompt, template := parseCommandTemplate(name, string(content))
+		a.mcpServer.RegisterPrompt(prompt, template)
+	}
+	return nil
+}
+
+func parseCommandTemplate(name, content string) (mcp.Prompt, string) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	prompt := mcp.Prompt{Name: name}
+	template := content
+	argumentHint := ""
+
+	if rest, ok := strings.CutPrefix(content, "---\n"); ok {
+		if frontMatter, body, ok := strings.Cut(rest, "\n---\n"); ok {
+			template = body
+			for _, line := range strings.Split(frontMatter, "\n") {
+				key, value, _ := strings.Cut(line, ":")
+				value = strings.Trim(strings.TrimSpace(value), `"'`)
+				switch strings.TrimSpace(key) {
+				case "description":
+					prompt.Description = value
+				case "argument-hint":
+					argumentHint = value
+				}
+			}
+		}
+	}
+	template = strings.TrimSpace(template)
+
+	if prompt.Description == "" {
+		first, _, _ := strings.Cut(template, "\n")
+		prompt.Description = strings.TrimSpace(strings.TrimLeft(first, "# "))
+	}
+	if strings.Contains(template, "$ARGUMENTS") {
+		description := argumentHint
+		if description == "" {
+			description = "Text that replaces $ARGUMENTS in the template"
+		}
+		prompt.Arguments = []mcp.PromptArgument{{Name: "arguments", Description: description}}
+	}
+	return prompt, template
+}
@@ -0,0 +1,134 @@
+// Package: internal/agent/commit_style.go
+package agent
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+var commitStyleRules = map[string]string{
+	"conventional": `Use the Conventional Commits format: "<type>(<scope>): <summary>", where type is one of feat, fix, docs, style, refactor, perf, test, build, ci or chore. Omit "(<scope>)" when the changes span unrelated areas.`,
+	"gitmoji":      `Use the gitmoji format: "<emoji> <summary>", starting with the single gitmoji that fits best (✨ new feature, 🐛 bug fix, ♻️ refactor, 📝 docs, ✅ tests, 🔧 configuration, ⚡️ performance, 🔥 removal, 🎨 formatting). A scope may follow the emoji as "<emoji> <scope>: <summary>".`,
+	"plain":        `Use a plain subject line in the imperative mood ("Add", "Fix", "Remove"), capitalized, with no type prefix and no trailing period.`,
+}
+
+// CommitStyles lists the built-in commit message styles.
+func CommitStyles() []string {
+	styles := []string{"template"}
+	for name := range commitStyleRules {
+		styles = append(styles, name)
+	}
+	sort.Strings(styles)
+	return styles
+}
+
+// commitPrompt builds the system and user prompts for a commit message in
+// the configured style.
+func (a *Agent) commitPrompt(changes []GitChange) (string, string, error) {
+	rule, err := a.commitStyleRule()
+	if err != nil {
+		return "", "", err
+	}
+
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+
+	prompt := fmt.Sprintf("Generate a concise git commit message for these changes:\n%s\n", strings.Join(lines, "\n"))
+	if scopes := a.commitScopes(changes); len(scopes) > 0 {
+		prompt += fmt.Sprintf("\nScopes of the changed paths (use these names, most relevant first): %s\n", strings.Join(scopes, ", "))
+	}
+	prompt += "\nBe specific about what was changed. Keep the subject under 72 characters; add a short body only if the subject cannot explain the change. Reply with the commit message only."
+
+	system := "You are a git commit message generator. " + rule
+	return system, prompt, nil
+}
+
+// commitStyleRule describes the configured commit message style to the
+// model.
+func (a *Agent) commitStyleRule() (string, error) {
+	git := a.config.Git
+
+	style := git.CommitStyle
+	if style == "" {
+		style = "conventional"
+	}
+
+	switch {
+	case style == "template":
+		if git.CommitTemplate == "" {
+			return "", fmt.Errorf("git.commit_style is \"template\" but git.commit_template is empty")
+		}
+		return fmt.Sprintf("Follow this template exactly, replacing the placeholders ({type}, {scope}, {emoji}, {summary}, {body}) and keeping all other text:\n%s", git.CommitTemplate), nil
+	case commitStyleRules[style] != "":
+		return commitStyleRules[style], nil
+	default:
+		return "", fmt.Errorf("unknown git.commit_style %q (available: %s)", style, strings.Join(CommitStyles(), ", "))
+	}
+}
+
+// commitScopes derives scopes for the changed paths from the git.scopes
+// rules, falling back to each path's top-level directory, ordered by how
+// many changes they cover.
+func (a *Agent) commitScopes(changes []GitChange) []string {
+	counts := make(map[string]int)
+
+	for _, change := range changes {
+		scope := scopeForPath(change.File, a.config.Git.Scopes)
+		if scope != "" {
+			counts[scope]++
+		}
+	}
+
+	scopes := make([]string, 0, len(counts))
+	for scope := range counts {
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if counts[scopes[i]] != counts[scopes[j]] {
+			return counts[scopes[i]] > counts[scopes[j]]
+		}
+		return scopes[i] < scopes[j]
+	})
+	return scopes
+}
+
+func scopeForPath(file string, rules map[string]string) string {
+	// Longest pattern first so specific rules beat general ones
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+
+	for _, pattern := range patterns {
+		if matchScopeGlob(pattern, file) {
+			return rules[pattern]
+		}
+	}
+
+	dir := path.Dir(file)
+	if dir == "." {
+		return ""
+	}
+
+	parts := strings.Split(dir, "/")
+	// Container directories like internal/ or src/ say little; use the next level
+	if len(parts) > 1 && (parts[0] == "internal" || parts[0] == "pkg" || parts[0] == "src" || parts[0] == "lib" || parts[0] == "cmd") {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// matchScopeGlob matches a path against a glob where a trailing "/**"
+// covers everything below a directory.
+func matchScopeGlob(pattern, file string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return file == prefix || strings.HasPrefix(file, prefix+"/")
+	}
+	ok, _ := path.Match(pattern, file)
+	return ok
+}
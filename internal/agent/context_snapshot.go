@@ -0,0 +1,15 @@
+// Package: internal/agent/context_snapshot.go
+package agent
+
+// ExportContext writes what is known about the project (file outlines, the
+// symbol index, embedded chunks, the project summary) to path, so another
+// checkout can start from it with ImportContext.
+func (a *Agent) ExportContext(path string) error {
+	return a.context.ExportSnapshot(path)
+}
+
+// ImportContext loads a snapshot written by ExportContext, returning how
+// many of the project's files it covered unchanged.
+func (a *Agent) ImportContext(path string) (int, error) {
+	return a.context.ImportSnapshot(path)
+}
@@ -0,0 +1,172 @@
+// Package: internal/agent/delta.go
+package agent
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+// sentFiles tracks which version of each project file the conversation
+// already holds, so each turn only carries the files that changed since.
+type sentFiles struct {
+	files   map[string]sentFile // By path
+	turns   int                 // User messages added so far
+	dropped int                 // User messages trimmed from the history so far
+}
+
+// sentFile is the version of a file sent in a turn: its content hash and
+// whether it went in full or as its outline.
+type sentFile struct {
+	version string
+	turn    int
+}
+
+// holds reports whether a message still in the history carries version of
+// path.
+func (s *sentFiles) holds(path, version string) bool {
+	sent, ok := s.files[path]
+	return ok && sent.version == version && sent.turn > s.dropped
+}
+
+// send records that the next turn carries version of path.
+func (s *sentFiles) send(path, version string) {
+	if s.files == nil {
+		s.files = make(map[string]sentFile)
+	}
+	s.files[path] = sentFile{version: version, turn: s.turns + 1}
+}
+
+// entry renders a file for the next message as header and body, or as the
+// header alone, marked unchanged, when a message still in the history
+// carries the same body.
+func (s *sentFiles) entry(path, header, body string) string {
+	sum := md5.Sum([]byte(body))
+	version := hex.EncodeToString(sum[:])
+	if s.holds(path, version) {
+		return strings.TrimSuffix(header, " ---\n") + " (unchanged since it was sent earlier in this conversation) ---\n"
+	}
+	s.send(path, version)
+	return header + body
+}
+
+// unsend forgets the files recorded for a turn that never joined the
+// history, so the next turn sends them again.
+func (s *sentFiles) unsend() {
+	for path, sent := range s.files {
+		if sent.turn > s.turns {
+			delete(s.files, path)
+		}
+	}
+}
+
+// trimHistory drops the oldest messages over maxTokens like trimHistory,
+// noting which turns left the history so the files sent in them are sent
+// again.
+func (s *sentFiles) trimHistory(messages []llm.Message, maxTokens int) []llm.Message {
+	trimmed := trimHistory(messages, maxTokens)
+	for _, msg := range messages[:len(messages)-len(trimmed)] {
+		if msg.Role == "user" {
+			s.dropped++
+		}
+	}
+	return trimmed
+}
+
+// conversation is a session's earlier turns, each request with the files
+// sent along and its answer, kept to the history budget.
+type conversation struct {
+	messages []llm.Message
+	sent     sentFiles
+}
+
+type ownConversationKey struct{}
+
+// WithOwnConversation runs requests under ctx in a conversation of their
+// own instead of the session's, as background jobs running beside it do.
+func WithOwnConversation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ownConversationKey{}, true)
+}
+
+// conversationFor returns the conversation a request under ctx continues.
+func (a *Agent) conversationFor(ctx context.Context) *conversation {
+	if own, _ := ctx.Value(ownConversationKey{}).(bool); own {
+		return &conversation{}
+	}
+	return &a.conversation
+}
+
+// ClearConversation starts a new conversation: the next request carries
+// the project's files afresh and none of the earlier turns.
+func (a *Agent) ClearConversation() {
+	a.conversation = conversation{}
+}
+
+// filesMessage renders the pinned files in full and outlines of the most
+// recently modified files that fit the files budget, for the next user
+// message of the conversation whose files sent tracks. Files whose current
+// version an earlier message still in the history carries are only named.
+func (a *EnhancedAgent) filesMessage(projectCtx *projectctx.ProjectContext, sent *sentFiles) string {
+	budget := planBudget(a.config.Agent)
+
+	var files, pinned, key strings.Builder
+	var unchanged []string
+	tokens := 0
+
+	for _, file := range projectCtx.Pinned {
+		content, err := file.ReadContent()
+		if err != nil {
+			continue
+		}
+		entry := fmt.Sprintf("--- %s ---\n%s\n", file.Path, content)
+		tokens += estimateTokens(entry)
+		if version := file.Hash + " full"; sent.holds(file.Path, version) {
+			unchanged = append(unchanged, file.Path)
+		} else {
+			pinned.WriteString(entry)
+			sent.send(file.Path, version)
+		}
+	}
+
+	for i, file := range projectCtx.Files {
+		note := ""
+		if file.OutlineOnly {
+			note = ", over the context budget"
+		}
+		label := ""
+		if file.Root != "" {
+			label = "[" + file.Root + "] "
+		}
+		entry := fmt.Sprintf("- %s%s (%s, %d tokens%s)\n", label, file.Path, file.Language, file.TokenCount, note) + file.Outline()
+		if i > 0 && tokens+estimateTokens(entry) > budget.Files {
+			key.WriteString(fmt.Sprintf("... and %d more files\n", len(projectCtx.Files)-i))
+			break
+		}
+		tokens += estimateTokens(entry)
+		if version := file.Hash + " outline"; sent.holds(file.Path, version) {
+			unchanged = append(unchanged, file.Path)
+		} else {
+			key.WriteString(entry)
+			sent.send(file.Path, version)
+		}
+	}
+
+	if pinned.Len() > 0 {
+		files.WriteString("### Pinned Files:\n" + pinned.String() + "\n")
+	}
+	if key.Len() > 0 {
+		files.WriteString("### Key Files (recently modified):\n" + key.String() + "\n")
+	}
+	if len(unchanged) > 0 {
+		files.WriteString("Unchanged since they were sent earlier in this conversation: " + strings.Join(unchanged, ", ") + "\n")
+	}
+	if files.Len() == 0 {
+		return ""
+	}
+	return "\n\n## Project Files\n\n" + files.String()
+}
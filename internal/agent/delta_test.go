@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+// turn renders path with body as the next request of the conversation
+// would, and adds the request and its answer to the history.
+func (c *conversation) turn(path, body string) string {
+	entry := c.sent.entry(path, "\n--- "+path+" ---\n", body)
+	c.messages = append(c.messages,
+		llm.Message{Role: "user", Content: entry},
+		llm.Message{Role: "assistant", Content: "ok"})
+	c.sent.turns++
+	return entry
+}
+
+func TestConversationSendsFilesOnce(t *testing.T) {
+	var c conversation
+	if got := c.turn("main.go", "package main\n"); !strings.Contains(got, "package main") {
+		t.Fatalf("first turn = %q, want the file's content", got)
+	}
+	if got := c.turn("main.go", "package main\n"); strings.Contains(got, "package main") || !strings.Contains(got, "unchanged") {
+		t.Errorf("second turn = %q, want the file only named as unchanged", got)
+	}
+	if got := c.turn("main.go", "package main\n\nfunc main() {}\n"); !strings.Contains(got, "func main") {
+		t.Errorf("after a change = %q, want the new content", got)
+	}
+
+	// The turn that carried the file falls out of the history
+	c.messages = c.sent.trimHistory(c.messages, estimateTokens("ok"))
+	if got := c.turn("main.go", "package main\n\nfunc main() {}\n"); !strings.Contains(got, "func main") {
+		t.Errorf("after trimming = %q, want the content sent again", got)
+	}
+}
+
+func TestFailedTurnSendsFilesAgain(t *testing.T) {
+	var c conversation
+	c.sent.entry("main.go", "\n--- main.go ---\n", "package main\n")
+	c.sent.unsend() // The request failed, so no message holds the file
+
+	if got := c.turn("main.go", "package main\n"); !strings.Contains(got, "package main") {
+		t.Errorf("turn after a failed one = %q, want the file's content", got)
+	}
+}
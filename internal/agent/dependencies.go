@@ -0,0 +1,40 @@
+// Package: internal/agent/dependencies.go
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+)
+
+const maxListedDependencies = 60
+
+// dependenciesSection lists the libraries the project's manifests declare,
+// with their versions, so suggestions stick to what is available.
+func dependenciesSection(workingDir string) string {
+	deps, _ := projectctx.ParseManifests(workingDir)
+	if len(deps) == 0 {
+		return ""
+	}
+
+	var manifests []string
+	byManifest := make(map[string][]string)
+	for _, dep := range deps {
+		if _, ok := byManifest[dep.Manifest]; !ok {
+			manifests = append(manifests, dep.Manifest)
+		}
+		byManifest[dep.Manifest] = append(byManifest[dep.Manifest], dep.Requirement())
+	}
+
+	var section strings.Builder
+	section.WriteString("\n\n## Dependencies\n\nPrefer these libraries, at these versions, over adding new ones:\n")
+	for _, manifest := range manifests {
+		entries := byManifest[manifest]
+		if len(entries) > maxListedDependencies {
+			entries = append(entries[:maxListedDependencies], fmt.Sprintf("... and %d more", len(entries)-maxListedDependencies))
+		}
+		section.WriteString(fmt.Sprintf("\n%s: %s\n", manifest, strings.Join(entries, ", ")))
+	}
+	return section.String()
+}
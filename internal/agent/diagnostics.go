@@ -0,0 +1,26 @@
+// Package: internal/agent/diagnostics.go
+package agent
+
+import (
+	"context"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+)
+
+// diagnosticsSection lists the compile errors and warnings the configured
+// language servers report, so questions about why the project doesn't
+// build are answered from what the compiler says.
+func diagnosticsSection(ctx context.Context, cm *projectctx.ContextManager) string {
+	diagnostics := cm.Diagnostics(ctx)
+	if len(diagnostics) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString("\n\n## Diagnostics\n\nThe language server currently reports:\n")
+	for _, d := range diagnostics {
+		section.WriteString("- " + d.String() + "\n")
+	}
+	return section.String()
+}
@@ -0,0 +1,164 @@
+// Package: internal/agent/elision.go
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	defaultKeepToolResults = 3
+	minElidedResultLength  = 300
+	maxStoredToolResults   = 500
+)
+
+// toolResultStore keeps full tool results so elided ones can be recalled.
+type toolResultStore struct {
+	mu      sync.Mutex
+	results map[int]string
+	order   []int
+	last    int
+}
+
+func newToolResultStore() *toolResultStore {
+	return &toolResultStore{results: make(map[int]string)}
+}
+
+// put stores a result under id, or under a fresh id when id is 0, and
+// returns the id used.
+func (s *toolResultStore) put(id int, result string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == 0 {
+		id = s.last + 1
+	}
+	if id > s.last {
+		s.last = id
+	}
+
+	if _, exists := s.results[id]; !exists {
+		s.order = append(s.order, id)
+	}
+	s.results[id] = result
+
+	if len(s.order) > maxStoredToolResults {
+		delete(s.results, s.order[0])
+		s.order = s.order[1:]
+	}
+	return id
+}
+
+func (s *toolResultStore) get(id int) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.results[id]
+	return result, ok
+}
+
+// toolRef identifies the stored result behind a tool message.
+type toolRef struct {
+	id   int
+	tool string
+	args string
+}
+
+// elideToolResults returns the history to send to the model: all but the
+// last keep tool results are replaced with one-line summaries pointing at
+// recall_tool_result, and so are the kept ones, oldest first, while the
+// history is over maxTokens. The latest result is always sent in full.
+// messages itself is left untouched.
+func elideToolResults(messages []llm.Message, refs map[string]toolRef, keep, maxTokens int) []llm.Message {
+	var toolIndexes []int
+	tokens := 0
+	for i, msg := range messages {
+		if msg.Role == "tool" {
+			toolIndexes = append(toolIndexes, i)
+		}
+		if msg.Role != "system" {
+			tokens += estimateTokens(msg.Content)
+		}
+	}
+	overBudget := func() bool {
+		return maxTokens > 0 && tokens > maxTokens
+	}
+	if len(toolIndexes) <= keep && !overBudget() {
+		return messages
+	}
+
+	elided := make([]llm.Message, len(messages))
+	copy(elided, messages)
+
+	for n, i := range toolIndexes {
+		recent := n >= len(toolIndexes)-keep
+		if recent && (!overBudget() || n == len(toolIndexes)-1) {
+			continue
+		}
+
+		msg := elided[i]
+		ref, ok := refs[msg.ToolCallID]
+		if !ok || len(msg.Content) < minElidedResultLength {
+			continue
+		}
+
+		lines := strings.Count(msg.Content, "\n") + 1
+		tokens -= estimateTokens(msg.Content)
+		msg.Content = fmt.Sprintf("[Earlier result of %s elided: %s — %d lines. Call recall_tool_result with id %d if you need it again.]",
+			describeToolCall(ref.tool, ref.args), firstLine(stripRefTag(msg.Content)), lines, ref.id)
+		tokens += estimateTokens(msg.Content)
+		elided[i] = msg
+	}
+
+	return elided
+}
+
+func stripRefTag(content string) string {
+	if strings.HasPrefix(content, "[ref ") {
+		if _, rest, found := strings.Cut(content, "\n"); found {
+			return rest
+		}
+	}
+	return content
+}
+
+// recallTool lets the model fetch a tool result that was elided from the
+// conversation history.
+type recallTool struct {
+	store *toolResultStore
+}
+
+func (t *recallTool) Name() string { return "recall_tool_result" }
+
+func (t *recallTool) Description() string {
+	return "Return the full output of an earlier tool call whose result was elided from the conversation"
+}
+
+func (t *recallTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "integer",
+				"description": "The id given in the elided result",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *recallTool) Execute(args map[string]interface{}) (string, error) {
+	id, ok := args["id"].(float64)
+	if !ok {
+		return "", fmt.Errorf("id is required")
+	}
+
+	result, ok := t.store.get(int(id))
+	if !ok {
+		return "", fmt.Errorf("no stored tool result with id %d", int(id))
+	}
+	return result, nil
+}
@@ -4,7 +4,9 @@ package agent
 import (
 	builtinContext "context"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/N0tT1m/claude-code-go/internal/llm"
 	"github.com/N0tT1m/claude-code-go/internal/mcp"
 	"github.com/N0tT1m/claude-code-go/internal/tools"
+	"github.com/N0tT1m/claude-code-go/internal/workspace"
 )
 
 type EnhancedAgent struct {
@@ -22,36 +25,173 @@ type EnhancedAgent struct {
 	contextManager *context.ContextManager
 	mcpClient      *mcp.Client
 	mcpServer      *mcp.Server
+	stopWatching   func()
+	workspace      *workspace.Coordinator
 	sessionMemory  []llm.Message
+	sent           sentFiles
 	workingDir     string
+
+	// loop runs Ask's tool calls, sharing the client, tools and context
+	loop *Agent
 }
 
 func NewEnhanced(client *llm.Client, cfg *config.Config) *EnhancedAgent {
 	workingDir, _ := os.Getwd()
 
-	return &EnhancedAgent{
+	if err := client.SetThinking(cfg.Agent.Thinking, cfg.Agent.ThinkingBudget); err != nil {
+		client.SetThinking(llm.ThinkingAuto, 0)
+	}
+
+	a := &EnhancedAgent{
 		llmClient:      client,
 		config:         cfg,
-		tools:          tools.NewRegistry(),
-		contextManager: context.NewContextManager(workingDir, cfg.Agent.MaxTokens),
+		tools:          newToolRegistry(cfg),
+		contextManager: newContextManager(workingDir, cfg, client),
 		sessionMemory:  []llm.Message{},
 		workingDir:     workingDir,
 	}
+	a.loop = &Agent{
+		llmClient:   client,
+		config:      cfg,
+		tools:       a.tools,
+		filter:      context.NewContentFilter(cfg.Context),
+		context:     a.contextManager,
+		permissions: newPolicy(cfg),
+		toolResults: newToolResultStore(),
+	}
+	a.tools.Register(&recallTool{store: a.loop.toolResults})
+	registerSymbolTools(a.tools, func() (*context.SymbolIndex, error) {
+		return a.contextManager.SymbolIndex()
+	})
+	registerScopeTool(a.tools, a.contextManager)
+	a.tools.OnEdit(a.contextManager.Invalidate)
+	recordAccesses(a.tools, a.contextManager)
+	return a
+}
+
+// newContextManager builds the project context manager. In a monorepo it
+// is rooted at the workspace and scoped to the member holding workingDir.
+func newContextManager(workingDir string, cfg *config.Config, client *llm.Client) *context.ContextManager {
+	ws, err := context.DetectWorkspace(workingDir)
+	if err != nil {
+		log.Printf("Warning: failed to detect a monorepo workspace: %v", err)
+	}
+
+	root := workingDir
+	if ws != nil {
+		root = ws.Root
+	}
+	cm := context.NewContextManager(root, planBudget(cfg.Agent).Files)
+	cm.SetContentFilter(context.NewContentFilter(cfg.Context))
+	if ttl := cfg.Context.RefreshTTL; ttl != 0 {
+		cm.SetRefreshTTL(time.Duration(max(ttl, 0)) * time.Second)
+	}
+	if cfg.Context.MaxFiles != 0 || cfg.Context.MaxProjectMB != 0 {
+		maxFiles, maxBytes := context.DefaultMaxFiles, int64(context.DefaultMaxProjectBytes)
+		if n := cfg.Context.MaxFiles; n != 0 {
+			maxFiles = max(n, 0)
+		}
+		if mb := cfg.Context.MaxProjectMB; mb != 0 {
+			maxBytes = int64(max(mb, 0)) << 20
+		}
+		cm.SetProjectLimits(maxFiles, maxBytes)
+	}
+	if policy := cfg.Context.Symlinks; policy != "" {
+		if err := cm.SetSymlinks(policy); err != nil {
+			log.Printf("Warning: %v; links to files are read, linked directories left out", err)
+		}
+	}
+	if ws != nil {
+		cm.SetWorkspace(ws, ws.MemberFor(workingDir))
+	}
+	cm.SetLanguageServers(cfg.Context.LanguageServers)
+	if cfg.Context.ProjectSummary {
+		cm.SetSummarizer(projectSummarizer(client, cfg))
+	}
+	addConfiguredRoots(cm, cfg.Context.AdditionalDirs, workingDir)
+	pinConfigured(cm, cfg.Context.Pinned, workingDir)
+	if model := cfg.Context.EmbeddingModel; model != "" {
+		cm.SetEmbedder(model, func(ctx builtinContext.Context, texts []string) ([][]float32, error) {
+			return client.Embed(ctx, model, texts)
+		})
+	}
+	return cm
 }
 
+// StartMCPServer registers the session as the workspace's server session
+// and starts serving the project over MCP, on the Unix socket at
+// socketPath unless it is empty; MCPServer adds other transports.
 func (a *EnhancedAgent) StartMCPServer(socketPath string) error {
+	coordinator, err := workspace.Open(a.workingDir, "server")
+	if err != nil {
+		return fmt.Errorf("failed to register server session: %w", err)
+	}
+	a.workspace = coordinator
+	a.tools.SetGuard(coordinator)
+
+	if others, err := coordinator.Others(); err == nil {
+		for _, other := range others {
+			log.Printf("Warning: %s session %s (pid %d) is also working in %s; edits will be coordinated",
+				other.Kind, other.ID, other.PID, a.workingDir)
+		}
+	}
+
+	// Hosts can also delegate whole tasks to the agent
+	a.tools.Register(&askAgentTool{agent: a})
 	a.mcpServer = mcp.NewMCPServer("claude-go", "0.1.0", a.tools)
+	if err := a.mcpServer.SetFileRoot(a.workingDir); err != nil {
+		return err
+	}
 
 	// Register project files as MCP resources
 	if err := a.registerProjectResources(); err != nil {
 		return fmt.Errorf("failed to register resources: %w", err)
 	}
+	if err := a.registerProjectPrompts(); err != nil {
+		return fmt.Errorf("failed to register prompts: %w", err)
+	}
+
+	// Hosts subscribed to a file hear when it changes
+	if stop, err := a.contextManager.Watch(); err == nil {
+		a.stopWatching = stop
+		a.contextManager.OnChange(func(path string) {
+			a.mcpServer.ResourceChanged(mcp.FileURI(path))
+		})
+	} else {
+		log.Printf("Warning: MCP hosts will not be told when files change: %v", err)
+	}
 
+	if socketPath == "" {
+		return nil
+	}
 	return a.mcpServer.Start(socketPath)
 }
 
+// MCPServer returns the server StartMCPServer started, or nil.
+func (a *EnhancedAgent) MCPServer() *mcp.Server {
+	return a.mcpServer
+}
+
+// Close stops the MCP server, if running, with its file watcher, and the
+// language servers, and
+// unregisters the session.
+func (a *EnhancedAgent) Close() error {
+	a.contextManager.CloseLanguageServers()
+	if a.mcpServer != nil {
+		a.mcpServer.Stop()
+	}
+	if a.stopWatching != nil {
+		a.stopWatching()
+	}
+	if a.workspace != nil {
+		return a.workspace.Close()
+	}
+	return nil
+}
+
 func (a *EnhancedAgent) ConnectToMCPServer(socketPath string) error {
 	a.mcpClient = mcp.NewMCPClient()
+	a.mcpClient.SetReconnect(true)
 	if err := a.mcpClient.ConnectUnix(socketPath); err != nil {
 		return err
 	}
@@ -60,12 +200,6 @@ func (a *EnhancedAgent) ConnectToMCPServer(socketPath string) error {
 }
 
 func (a *EnhancedAgent) ProcessInputStreaming(ctx builtinContext.Context, input string, callback func(string) error) error {
-	// Add input to session memory
-	a.sessionMemory = append(a.sessionMemory, llm.Message{
-		Role:    "user",
-		Content: input,
-	})
-
 	// Get project context
 	projectCtx, err := a.contextManager.GetProjectContext()
 	if err != nil {
@@ -79,26 +213,33 @@ func (a *EnhancedAgent) ProcessInputStreaming(ctx builtinContext.Context, input
 		{Role: "system", Content: systemPrompt},
 	}
 
-	// Add recent session memory (keep last 10 exchanges)
-	if len(a.sessionMemory) > 20 {
-		a.sessionMemory = a.sessionMemory[len(a.sessionMemory)-20:]
-	}
+	// Keep as much recent session memory as the history budget allows,
+	// then add the input with the files the remaining history doesn't
+	// already hold in their current version
+	a.sessionMemory = a.sent.trimHistory(a.sessionMemory, max(planBudget(a.config.Agent).History-estimateTokens(input), 0))
+	a.sessionMemory = append(a.sessionMemory, llm.Message{
+		Role:    "user",
+		Content: input + a.filesMessage(projectCtx, &a.sent),
+	})
+	a.sent.turns++
 	messages = append(messages, a.sessionMemory...)
 
 	req := llm.ChatRequest{
 		Model:       a.config.LMStudio.Model,
 		Messages:    messages,
-		Tools:       a.tools.GetAvailable(),
+		Tools:       a.loop.modelTools(),
 		MaxTokens:   a.config.Agent.MaxTokens,
 		Temperature: a.config.Agent.Temperature,
 		Stream:      true,
 	}
 
 	var fullResponse strings.Builder
+	var reasoning llm.ReasoningFilter
 
 	err = a.llmClient.ChatStream(ctx, req, func(response llm.StreamResponse) error {
 		if len(response.Choices) > 0 {
-			delta := response.Choices[0].Delta.Content
+			// Reasoning is neither shown nor kept in session memory
+			delta := reasoning.Write(response.Choices[0].Delta.Content)
 			if delta != "" {
 				fullResponse.WriteString(delta)
 				return callback(delta)
@@ -111,6 +252,13 @@ func (a *EnhancedAgent) ProcessInputStreaming(ctx builtinContext.Context, input
 		return fmt.Errorf("streaming request failed: %w", err)
 	}
 
+	if rest := reasoning.Flush(); rest != "" {
+		fullResponse.WriteString(rest)
+		if err := callback(rest); err != nil {
+			return err
+		}
+	}
+
 	// Add response to session memory
 	a.sessionMemory = append(a.sessionMemory, llm.Message{
 		Role:    "assistant",
@@ -122,26 +270,35 @@ func (a *EnhancedAgent) ProcessInputStreaming(ctx builtinContext.Context, input
 
 func (a *EnhancedAgent) buildEnhancedSystemPrompt(projectCtx *context.ProjectContext) string {
 	var prompt strings.Builder
+	budget := planBudget(a.config.Agent)
 
 	prompt.WriteString(a.config.Agent.SystemPrompt)
 	prompt.WriteString("\n\n## Current Project Context\n\n")
 
-	// Add project structure
-	prompt.WriteString("### Project Structure:\n```\n")
-	prompt.WriteString(projectCtx.Structure)
-	prompt.WriteString("\n```\n\n")
+	// Add the project summary, or the structure without one
+	if projectCtx.Summary != "" {
+		prompt.WriteString("### Project Summary:\n")
+		prompt.WriteString(truncateTokens(projectCtx.Summary, budget.Structure))
+		prompt.WriteString("\n\n")
+	} else {
+		prompt.WriteString("### Project Structure:\n```\n")
+		prompt.WriteString(truncateTokens(projectCtx.Structure, budget.Structure))
+		prompt.WriteString("\n```\n\n")
+	}
 
 	// Add git information
 	if projectCtx.GitInfo.Branch != "" {
-		prompt.WriteString(fmt.Sprintf("### Git Information:\n"))
-		prompt.WriteString(fmt.Sprintf("- Current branch: %s\n", projectCtx.GitInfo.Branch))
-		prompt.WriteString(fmt.Sprintf("- Status: %s\n", projectCtx.GitInfo.Status))
+		var git strings.Builder
+		git.WriteString(fmt.Sprintf("### Git Information:\n"))
+		git.WriteString(fmt.Sprintf("- Current branch: %s\n", projectCtx.GitInfo.Branch))
+		git.WriteString(fmt.Sprintf("- Status: %s\n", projectCtx.GitInfo.Status))
 		if len(projectCtx.GitInfo.RecentCommits) > 0 {
-			prompt.WriteString("- Recent commits:\n")
+			git.WriteString("- Recent commits:\n")
 			for _, commit := range projectCtx.GitInfo.RecentCommits {
-				prompt.WriteString(fmt.Sprintf("  - %s\n", commit))
+				git.WriteString(fmt.Sprintf("  - %s\n", commit))
 			}
 		}
+		prompt.WriteString(truncateTokens(git.String(), budget.Git))
 		prompt.WriteString("\n")
 	}
 
@@ -154,36 +311,50 @@ func (a *EnhancedAgent) buildEnhancedSystemPrompt(projectCtx *context.ProjectCon
 		prompt.WriteString("\n")
 	}
 
-	// Add relevant files (sample of recent files)
-	if len(projectCtx.Files) > 0 {
-		prompt.WriteString("### Key Files (recently modified):\n")
-		for i, file := range projectCtx.Files {
-			if i >= 5 { // Limit to first 5 files to save tokens
-				break
-			}
-			prompt.WriteString(fmt.Sprintf("- %s (%s, %d tokens)\n", file.Path, file.Language, file.TokenCount))
-		}
-
-		if len(projectCtx.Files) > 5 {
-			prompt.WriteString(fmt.Sprintf("... and %d more files\n", len(projectCtx.Files)-5))
+	// Add what the language servers report, so build questions are answered
+	// from real errors
+	if len(projectCtx.Diagnostics) > 0 {
+		prompt.WriteString("### Diagnostics:\n")
+		for _, d := range projectCtx.Diagnostics {
+			prompt.WriteString(fmt.Sprintf("- %s\n", d))
 		}
 		prompt.WriteString("\n")
 	}
 
 	prompt.WriteString(fmt.Sprintf("Total project context: %d tokens\n\n", projectCtx.TotalTokens))
 
+	prompt.WriteString("The project's files are in the conversation: each message carries the files that changed since they were last sent. ")
 	prompt.WriteString("Use this context to provide more accurate and relevant assistance. ")
 	prompt.WriteString("When referencing files or making changes, consider the project structure and existing code patterns.")
+	prompt.WriteString(projectMemorySection(a.workingDir))
+	prompt.WriteString(styleSection(a.config.Agent.OutputStyle))
 
 	return prompt.String()
 }
 
+// summaryURI is the resource of the project's summary.
+const summaryURI = "claude-go://summary"
+
+// registerProjectResources offers the project's files as file:// resources
+// named by their paths in the project, and its summary at summaryURI.
 func (a *EnhancedAgent) registerProjectResources() error {
 	projectCtx, err := a.contextManager.GetProjectContext()
 	if err != nil {
 		return err
 	}
 
+	a.mcpServer.HandleScheme("claude-go", func(uri string) ([]mcp.ResourceContents, error) {
+		if uri != summaryURI {
+			return nil, fmt.Errorf("unknown resource %s", uri)
+		}
+		summary, err := a.GetProjectSummary(builtinContext.Background())
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{{URI: uri, MimeType: "text/markdown", Text: summary}}, nil
+	})
+	a.mcpServer.RegisterResource(summaryURI, "Project summary", "The project's languages, dependencies and git state", "text/markdown", nil)
+
 	for _, file := range projectCtx.Files {
 		mimeType := "text/plain"
 		if strings.Contains(file.Language, "json") {
@@ -197,10 +368,14 @@ func (a *EnhancedAgent) registerProjectResources() error {
 			"token_count":   fmt.Sprintf("%d", file.TokenCount),
 		}
 
+		name := file.Path
+		if relPath, err := filepath.Rel(a.workingDir, file.Path); err == nil {
+			name = filepath.ToSlash(relPath)
+		}
 		a.mcpServer.RegisterResource(
-			file.Path,
-			file.Path,
-			fmt.Sprintf("%s file (%s)", file.Language, file.Path),
+			mcp.FileURI(file.Path),
+			name,
+			fmt.Sprintf("%s file (%s)", file.Language, name),
 			mimeType,
 			metadata,
 		)
@@ -260,7 +435,8 @@ func (a *EnhancedAgent) ExecuteCommand(ctx builtinContext.Context, command strin
 	case "context":
 		return a.showCurrentContext(ctx)
 	case "refresh":
-		a.contextManager = context.NewContextManager(a.workingDir, a.config.Agent.MaxTokens)
+		a.contextManager.CloseLanguageServers()
+		a.contextManager = newContextManager(a.workingDir, a.config, a.llmClient)
 		return "Context refreshed", nil
 	default:
 		// Delegate to regular tool execution
@@ -0,0 +1,251 @@
+// Package: internal/agent/explain.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	maxTraceReferences = 8
+	snippetRadius      = 8
+)
+
+// Matches "path/to/file.ext:123" as printed by compilers and stack traces,
+// plus Python's `File "x.py", line 12` form.
+var (
+	traceRefPattern   = regexp.MustCompile(`([\w./\\@-]+\.[A-Za-z]{1,5}):(\d+)`)
+	pythonRefPattern  = regexp.MustCompile(`File "([^"]+)", line (\d+)`)
+	explainSkipDirs   = []string{"node_modules", "vendor", "target", "build", "dist", ".git"}
+	explainSystemText = `You are an expert debugger. Given an error or stack trace and the source code it points to, identify the most likely root cause and propose a fix.
+
+Respond in this format:
+## Root Cause
+A short explanation of what is going wrong and why.
+
+## Proposed Patch
+A unified diff (` + "```diff" + `) against the files shown, or "No patch" if the fix is outside the code provided.`
+)
+
+type traceReference struct {
+	Path string
+	Line int
+}
+
+// ExplainError correlates an error or stack trace with project sources and
+// asks the model for the likely root cause and a patch.
+func (a *Agent) ExplainError(ctx context.Context, trace string) (string, error) {
+	trace = strings.TrimSpace(trace)
+	if trace == "" {
+		return "", fmt.Errorf("no error text provided")
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("## Error\n```\n")
+	prompt.WriteString(trace)
+	prompt.WriteString("\n```\n")
+
+	refs := parseTraceReferences(trace)
+	var index map[string][]string
+	found := 0
+
+	for _, ref := range refs {
+		path := resolveTracePath(workingDir, ref.Path)
+		if path == "" {
+			if index == nil {
+				index = indexProjectFiles(workingDir)
+			}
+			path = lookupIndexedFile(index, ref.Path)
+		}
+		if path == "" || !withinDir(workingDir, path) {
+			continue
+		}
+
+		snippet, err := sourceSnippet(path, ref.Line, snippetRadius)
+		if err != nil {
+			continue
+		}
+
+		relPath, _ := filepath.Rel(workingDir, path)
+		if found == 0 {
+			prompt.WriteString("\n## Referenced Source\n")
+		}
+		prompt.WriteString(fmt.Sprintf("\n--- %s:%d ---\n%s\n", relPath, ref.Line, snippet))
+		found++
+	}
+
+	if found == 0 {
+		prompt.WriteString("\nNo project files could be matched to this error; reason from the error text alone.\n")
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: explainSystemText},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	req := llm.ChatRequest{
+		Model:       a.config.LMStudio.Model,
+		Messages:    messages,
+		MaxTokens:   a.config.Agent.MaxTokens,
+		Temperature: 0.2,
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("LLM request failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no explanation generated")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+func parseTraceReferences(trace string) []traceReference {
+	var refs []traceReference
+	seen := make(map[string]bool)
+
+	add := func(path, line string) {
+		n, err := strconv.Atoi(line)
+		if err != nil || n <= 0 {
+			return
+		}
+		key := path + ":" + line
+		if seen[key] || len(refs) >= maxTraceReferences {
+			return
+		}
+		seen[key] = true
+		refs = append(refs, traceReference{Path: path, Line: n})
+	}
+
+	for _, m := range pythonRefPattern.FindAllStringSubmatch(trace, -1) {
+		add(m[1], m[2])
+	}
+	for _, m := range traceRefPattern.FindAllStringSubmatch(trace, -1) {
+		add(m[1], m[2])
+	}
+
+	return refs
+}
+
+// resolveTracePath finds the file a trace names, as it is or relative to
+// workingDir, if it lies inside workingDir.
+func resolveTracePath(workingDir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() || !withinDir(workingDir, path) {
+		return ""
+	}
+	return path
+}
+
+// withinDir reports whether path is inside dir once symlinks are followed.
+// A pasted trace can name any file, and only project files may be read
+// into the prompt.
+func withinDir(dir, path string) bool {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// indexProjectFiles maps base names to the project files carrying them so
+// paths from other machines (CI, containers) can be matched by suffix.
+func indexProjectFiles(workingDir string) map[string][]string {
+	index := make(map[string][]string)
+
+	filepath.WalkDir(workingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != workingDir && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			for _, skip := range explainSkipDirs {
+				if name == skip {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+
+		index[d.Name()] = append(index[d.Name()], path)
+		return nil
+	})
+
+	return index
+}
+
+func lookupIndexedFile(index map[string][]string, ref string) string {
+	ref = filepath.ToSlash(ref)
+	best, bestLen := "", 0
+
+	for _, candidate := range index[filepath.Base(ref)] {
+		// Prefer the candidate sharing the longest path suffix with ref
+		common := commonSuffixSegments(filepath.ToSlash(candidate), ref)
+		if common > bestLen {
+			best, bestLen = candidate, common
+		}
+	}
+
+	return best
+}
+
+func commonSuffixSegments(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(as) && n < len(bs) && as[len(as)-1-n] == bs[len(bs)-1-n] {
+		n++
+	}
+	return n
+}
+
+func sourceSnippet(path string, line, radius int) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return "", fmt.Errorf("line %d out of range", line)
+	}
+
+	start := max(1, line-radius)
+	end := min(len(lines), line+radius)
+
+	var snippet strings.Builder
+	for i := start; i <= end; i++ {
+		marker := "  "
+		if i == line {
+			marker = "> "
+		}
+		snippet.WriteString(fmt.Sprintf("%s%4d | %s\n", marker, i, lines[i-1]))
+	}
+
+	return strings.TrimRight(snippet.String(), "\n"), nil
+}
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveTracePathStaysInProject(t *testing.T) {
+	project := t.TempDir()
+	outside := t.TempDir()
+	for _, path := range []string{filepath.Join(project, "main.go"), filepath.Join(outside, "secret")} {
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(project, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "main.go", want: filepath.Join(project, "main.go")},
+		{path: filepath.Join(project, "main.go"), want: filepath.Join(project, "main.go")},
+		{path: filepath.Join(outside, "secret")},
+		{path: filepath.Join("..", filepath.Base(outside), "secret")},
+		{path: "link"},
+		{path: "missing.go"},
+		{path: "."},
+	}
+	for _, tt := range tests {
+		if got := resolveTracePath(project, tt.path); got != tt.want {
+			t.Errorf("resolveTracePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package: internal/agent/footnotes.go
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/audit"
+)
+
+const citationInstructions = `
+
+## Citing Tool Results
+
+Every tool result you receive starts with a reference like [ref 3]. When a statement in your answer relies on a tool result (for example "the tests pass" or "the function is defined in x.go:42"), cite it with a footnote marker such as [^3].`
+
+var citationPattern = regexp.MustCompile(`\[\^(\d+)\]`)
+
+// attachFootnotes appends a footnote per cited audit entry so the user can
+// verify each claim against the recorded command output. If the model cited
+// nothing, every executed tool is listed instead.
+func (a *Agent) attachFootnotes(answer string, executed []audit.Entry) string {
+	if a.audit == nil || len(executed) == 0 {
+		return answer
+	}
+
+	byID := make(map[int]audit.Entry, len(executed))
+	for _, entry := range executed {
+		byID[entry.ID] = entry
+	}
+
+	var cited []audit.Entry
+	seen := make(map[int]bool)
+	for _, m := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		id, _ := strconv.Atoi(m[1])
+		if entry, ok := byID[id]; ok && !seen[id] {
+			seen[id] = true
+			cited = append(cited, entry)
+		}
+	}
+
+	var notes strings.Builder
+	notes.WriteString("\n\n---\n")
+
+	if len(cited) == 0 {
+		notes.WriteString("Commands run for this answer:\n")
+		cited = executed
+	}
+
+	for _, entry := range cited {
+		status := "ok"
+		if entry.Error != "" {
+			status = "failed: " + entry.Error
+		}
+		notes.WriteString(fmt.Sprintf("[^%d]: %s (%s) — %s\n",
+			entry.ID, describeToolCall(entry.Tool, entry.Arguments), status, a.audit.Link(entry.ID)))
+	}
+
+	return strings.TrimRight(answer, "\n") + strings.TrimRight(notes.String(), "\n")
+}
+
+// describeToolCall renders a short human-readable form of a tool call.
+func describeToolCall(tool, arguments string) string {
+	var args map[string]interface{}
+	json.Unmarshal([]byte(arguments), &args)
+
+	switch tool {
+	case "shell_execute":
+		if command, ok := args["command"].(string); ok {
+			return fmt.Sprintf("`%s`", command)
+		}
+	case "git_operations":
+		if command, ok := args["command"].(string); ok {
+			parts := []string{"git", command}
+			if extra, ok := args["args"].([]interface{}); ok {
+				for _, arg := range extra {
+					parts = append(parts, fmt.Sprint(arg))
+				}
+			}
+			return fmt.Sprintf("`%s`", strings.Join(parts, " "))
+		}
+	case "file_operations":
+		operation, _ := args["operation"].(string)
+		path, _ := args["path"].(string)
+		return fmt.Sprintf("%s %s", operation, path)
+	case "edit_file":
+		path, _ := args["path"].(string)
+		return fmt.Sprintf("edit %s", path)
+	case "multi_edit":
+		edits, _ := args["edits"].([]interface{})
+		return fmt.Sprintf("%d edits", len(edits))
+	case "build":
+		if command, ok := args["command"].(string); ok && command != "" {
+			return fmt.Sprintf("`%s`", command)
+		}
+		return "build"
+	case "lint_format":
+		if fix, _ := args["fix"].(bool); fix {
+			return "format files"
+		}
+		return "lint and check formatting"
+	case "run_tests":
+		parts := []string{"run tests"}
+		for _, key := range []string{"target", "name"} {
+			if value, _ := args[key].(string); value != "" {
+				parts = append(parts, value)
+			}
+		}
+		return strings.Join(parts, " ")
+	case "web_fetch":
+		url, _ := args["url"].(string)
+		return fmt.Sprintf("fetch %s", url)
+	}
+
+	summary := arguments
+	if len(summary) > 80 {
+		summary = summary[:77] + "..."
+	}
+	return fmt.Sprintf("%s %s", tool, summary)
+}
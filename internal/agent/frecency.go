@@ -0,0 +1,24 @@
+// Package: internal/agent/frecency.go
+package agent
+
+import (
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+// recordAccesses counts the files tools read and edit towards their
+// frecency.
+func recordAccesses(registry *tools.Registry, cm *projectctx.ContextManager) {
+	registry.OnRead(func(paths ...string) {
+		cm.RecordAccess(projectctx.AccessRead, paths...)
+	})
+	registry.OnEdit(func(paths ...string) {
+		cm.RecordAccess(projectctx.AccessEdit, paths...)
+	})
+}
+
+// Opened records that the user opened paths in their editor, which counts
+// as working on them.
+func (a *Agent) Opened(paths ...string) {
+	a.context.RecordAccess(projectctx.AccessEdit, paths...)
+}
@@ -0,0 +1,231 @@
+// Package: internal/agent/git.go
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runGit executes git in the current directory and returns its stdout.
+func runGit(ctx context.Context, args ...string) (string, error) {
+	return runGitInput(ctx, "", args...)
+}
+
+// runGitInput is runGit with stdin set to input.
+func runGitInput(ctx context.Context, input string, args ...string) (string, error) {
+	return runGitEnv(ctx, nil, input, args...)
+}
+
+// runGitEnv is runGitInput with extra environment variables.
+func runGitEnv(ctx context.Context, env []string, input string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = strings.NewReader(input)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+
+	return stdout.String(), nil
+}
+
+var gitStatusCodes = map[byte]string{
+	'M': "modified",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'T': "typechange",
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch -z` output.
+func parseGitStatus(output string) (*GitStatus, error) {
+	status := &GitStatus{}
+	fields := strings.Split(output, "\x00")
+
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if entry == "" {
+			continue
+		}
+
+		switch entry[0] {
+		case '#':
+			parseBranchHeader(status, entry)
+
+		case '1', '2':
+			// 1 XY sub mH mI mW hH hI path
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by the original path
+			n := 9
+			if entry[0] == '2' {
+				n = 10
+			}
+			parts := strings.SplitN(entry, " ", n)
+			if len(parts) < n {
+				return nil, fmt.Errorf("malformed git status entry: %q", entry)
+			}
+
+			change := newGitChange(parts[1], parts[n-1])
+			if entry[0] == '2' && i+1 < len(fields) {
+				i++
+				change.OrigFile = fields[i]
+			}
+			status.Changes = append(status.Changes, change)
+
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			parts := strings.SplitN(entry, " ", 11)
+			if len(parts) < 11 {
+				return nil, fmt.Errorf("malformed git status entry: %q", entry)
+			}
+			status.Changes = append(status.Changes, GitChange{Type: "unmerged", File: parts[10], Unstaged: true})
+
+		case '?':
+			status.Changes = append(status.Changes, GitChange{Type: "untracked", File: strings.TrimPrefix(entry, "? "), Unstaged: true})
+		}
+	}
+
+	return status, nil
+}
+
+func parseBranchHeader(status *GitStatus, header string) {
+	key, value, _ := strings.Cut(strings.TrimPrefix(header, "# "), " ")
+
+	switch key {
+	case "branch.head":
+		status.Branch = value
+	case "branch.upstream":
+		status.Upstream = value
+	case "branch.ab":
+		fmt.Sscanf(value, "+%d -%d", &status.Ahead, &status.Behind)
+	}
+}
+
+func newGitChange(xy, path string) GitChange {
+	change := GitChange{File: path}
+	if len(xy) != 2 {
+		return change
+	}
+
+	change.Staged = xy[0] != '.'
+	change.Unstaged = xy[1] != '.'
+
+	// The staged status describes the change better (a staged rename shows
+	// as R. even if the file was modified again afterwards)
+	code := xy[1]
+	if change.Staged {
+		code = xy[0]
+	}
+	change.Type = gitStatusCodes[code]
+	if change.Type == "" {
+		change.Type = "modified"
+	}
+
+	return change
+}
+
+func (c GitChange) String() string {
+	var state string
+	switch {
+	case c.Type == "untracked" || c.Type == "unmerged":
+	case c.Staged && c.Unstaged:
+		state = " (partially staged)"
+	case c.Staged:
+		state = " (staged)"
+	default:
+		state = " (unstaged)"
+	}
+
+	if c.OrigFile != "" {
+		return fmt.Sprintf("%s%s: %s -> %s", c.Type, state, c.OrigFile, c.File)
+	}
+	return fmt.Sprintf("%s%s: %s", c.Type, state, c.File)
+}
+
+// LastCommitChanges lists the files changed by HEAD, for describing an
+// amended commit; --root lists the files of a root commit too.
+func (a *Agent) LastCommitChanges(ctx context.Context) ([]GitChange, error) {
+	output, err := runGit(ctx, "diff-tree", "--no-commit-id", "--name-status", "-z", "-r", "-M", "--root", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return parseNameStatus(output), nil
+}
+
+// parseNameStatus parses `--name-status -z` output: each status is
+// followed by its path, or by the original path and then the new one for
+// renames and copies.
+func parseNameStatus(output string) []GitChange {
+	var changes []GitChange
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		code := fields[i]
+		if code == "" {
+			continue
+		}
+
+		change := GitChange{Type: gitStatusCodes[code[0]], File: fields[i+1], Staged: true}
+		if change.Type == "" {
+			change.Type = "modified"
+		}
+		if (code[0] == 'R' || code[0] == 'C') && i+2 < len(fields) {
+			change.OrigFile = change.File
+			change.File = fields[i+2]
+			i++
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// saveIndex copies the git index aside and returns a function putting it
+// back, to undo staging for a commit that does not happen. The copy is
+// written back under git's own index.lock, as git writes the index.
+func saveIndex(ctx context.Context) (func() error, error) {
+	output, err := runGit(ctx, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSpace(output)
+
+	saved, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Nothing was ever staged in a new repository
+		return func() error { return os.Remove(path) }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save the git index: %w", err)
+	}
+
+	return func() error {
+		lock := path + ".lock"
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(saved); err != nil {
+			f.Close()
+			os.Remove(lock)
+			return err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(lock)
+			return err
+		}
+		return os.Rename(lock, path)
+	}, nil
+}
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string // Joined with NULs, as -z prints them
+		want    *GitStatus
+		wantErr bool
+	}{
+		{
+			name:    "clean branch with upstream",
+			entries: []string{"# branch.oid 1234abcd", "# branch.head main", "# branch.upstream origin/main", "# branch.ab +2 -1"},
+			want:    &GitStatus{Branch: "main", Upstream: "origin/main", Ahead: 2, Behind: 1},
+		},
+		{
+			name:    "detached head",
+			entries: []string{"# branch.oid 1234abcd", "# branch.head (detached)"},
+			want:    &GitStatus{Branch: "(detached)"},
+		},
+		{
+			name: "ordinary changes",
+			entries: []string{
+				"# branch.head main",
+				"1 .M N... 100644 100644 100644 aaaa aaaa internal/a.go",
+				"1 M. N... 100644 100644 100644 aaaa bbbb staged.go",
+				"1 MM N... 100644 100644 100644 aaaa bbbb both.go",
+				"1 A. N... 000000 100644 100644 0000 bbbb new.go",
+				"1 D. N... 100644 000000 000000 aaaa 0000 gone.go",
+				"1 .T N... 100644 100644 120000 aaaa aaaa link",
+			},
+			want: &GitStatus{Branch: "main", Changes: []GitChange{
+				{Type: "modified", File: "internal/a.go", Unstaged: true},
+				{Type: "modified", File: "staged.go", Staged: true},
+				{Type: "modified", File: "both.go", Staged: true, Unstaged: true},
+				{Type: "added", File: "new.go", Staged: true},
+				{Type: "deleted", File: "gone.go", Staged: true},
+				{Type: "typechange", File: "link", Unstaged: true},
+			}},
+		},
+		{
+			name:    "path with spaces",
+			entries: []string{"1 .M N... 100644 100644 100644 aaaa aaaa docs/read me.md"},
+			want: &GitStatus{Changes: []GitChange{
+				{Type: "modified", File: "docs/read me.md", Unstaged: true},
+			}},
+		},
+		{
+			name: "rename and copy with their original paths",
+			entries: []string{
+				"2 R. N... 100644 100644 100644 aaaa aaaa R100 new name.go", "old name.go",
+				"2 RM N... 100644 100644 100644 aaaa bbbb R87 moved.go", "orig.go",
+				"2 C. N... 100644 100644 100644 aaaa aaaa C100 copy.go", "source.go",
+			},
+			want: &GitStatus{Changes: []GitChange{
+				{Type: "renamed", File: "new name.go", OrigFile: "old name.go", Staged: true},
+				{Type: "renamed", File: "moved.go", OrigFile: "orig.go", Staged: true, Unstaged: true},
+				{Type: "copied", File: "copy.go", OrigFile: "source.go", Staged: true},
+			}},
+		},
+		{
+			name: "unmerged and untracked",
+			entries: []string{
+				"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.go",
+				"? notes/to do.txt",
+			},
+			want: &GitStatus{Changes: []GitChange{
+				{Type: "unmerged", File: "conflict.go", Unstaged: true},
+				{Type: "untracked", File: "notes/to do.txt", Unstaged: true},
+			}},
+		},
+		{
+			name:    "ignored entries are skipped",
+			entries: []string{"! build/"},
+			want:    &GitStatus{},
+		},
+		{
+			name:    "empty output",
+			entries: nil,
+			want:    &GitStatus{},
+		},
+		{
+			name:    "truncated ordinary entry",
+			entries: []string{"1 .M N... 100644 a.go"},
+			wantErr: true,
+		},
+		{
+			name:    "truncated unmerged entry",
+			entries: []string{"u UU N... 100644 a.go"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := strings.Join(tt.entries, "\x00")
+			if output != "" {
+				output += "\x00"
+			}
+			got, err := parseGitStatus(output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseGitStatus() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGitStatus() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGitStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitChangeString(t *testing.T) {
+	tests := []struct {
+		change GitChange
+		want   string
+	}{
+		{GitChange{Type: "modified", File: "a.go", Unstaged: true}, "modified (unstaged): a.go"},
+		{GitChange{Type: "modified", File: "a.go", Staged: true}, "modified (staged): a.go"},
+		{GitChange{Type: "modified", File: "a.go", Staged: true, Unstaged: true}, "modified (partially staged): a.go"},
+		{GitChange{Type: "renamed", File: "b.go", OrigFile: "a.go", Staged: true}, "renamed (staged): a.go -> b.go"},
+		{GitChange{Type: "untracked", File: "c.go", Unstaged: true}, "untracked: c.go"},
+		{GitChange{Type: "unmerged", File: "d.go", Unstaged: true}, "unmerged: d.go"},
+	}
+	for _, tt := range tests {
+		if got := tt.change.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.change, got, tt.want)
+		}
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string // Joined with NULs, as -z prints them
+		want    []GitChange
+	}{
+		{
+			name:    "modified, added and deleted",
+			entries: []string{"M", "a.go", "A", "new.go", "D", "gone.go"},
+			want: []GitChange{
+				{Type: "modified", File: "a.go", Staged: true},
+				{Type: "added", File: "new.go", Staged: true},
+				{Type: "deleted", File: "gone.go", Staged: true},
+			},
+		},
+		{
+			name:    "paths with tabs, quotes and newlines",
+			entries: []string{"M", "a\tb.go", "M", "\"quoted\".go", "A", "two\nlines.go"},
+			want: []GitChange{
+				{Type: "modified", File: "a\tb.go", Staged: true},
+				{Type: "modified", File: "\"quoted\".go", Staged: true},
+				{Type: "added", File: "two\nlines.go", Staged: true},
+			},
+		},
+		{
+			name:    "rename and copy with their original paths",
+			entries: []string{"R100", "old name.go", "new name.go", "C75", "source.go", "copy.go", "M", "x"},
+			want: []GitChange{
+				{Type: "renamed", File: "new name.go", OrigFile: "old name.go", Staged: true},
+				{Type: "copied", File: "copy.go", OrigFile: "source.go", Staged: true},
+				{Type: "modified", File: "x", Staged: true},
+			},
+		},
+		{
+			name: "empty commit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := strings.Join(tt.entries, "\x00")
+			if output != "" {
+				output += "\x00"
+			}
+			if got := parseNameStatus(output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNameStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedCommitRestoresIndex(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) string {
+		t.Helper()
+		output, err := runGit(t.Context(), append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output
+	}
+	git("init", "--quiet")
+	if err := os.WriteFile("staged.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "staged.go")
+	if err := os.WriteFile("unstaged.go", []byte("package main\n\n// TODO: finish\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Git: config.GitConfig{AutoStage: true, PreCommitReview: "scan"}}
+	a := &Agent{config: cfg}
+	_, err := a.CreateCommit(t.Context(), "Add main", CommitOptions{})
+	var blocked *PreCommitError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("CreateCommit() error = %v, want a *PreCommitError", err)
+	}
+
+	if staged := git("diff", "--cached", "--name-only"); staged != "staged.go\n" {
+		t.Errorf("staged after the blocked commit = %q, want only staged.go", staged)
+	}
+}
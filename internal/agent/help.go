@@ -0,0 +1,47 @@
+// Package: internal/agent/help.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/help"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const helpTopicLimit = 4
+
+const helpSystemText = `You answer questions about claude-go, a local command-line coding assistant. Answer ONLY from the documentation excerpts provided; if they do not cover the question, say so and point to the closest related command. Be brief and include the exact command, flag, or config key the user needs.`
+
+// AnswerHelp answers a question about claude-go itself from the bundled help
+// corpus. If the model cannot be reached, the matching topics are returned
+// as-is.
+func (a *Agent) AnswerHelp(ctx context.Context, question string) (string, error) {
+	topics := help.Search(question, helpTopicLimit)
+	if len(topics) == 0 {
+		return "", fmt.Errorf("no help topics match %q; try /help for the list of commands", question)
+	}
+
+	var docs strings.Builder
+	for _, topic := range topics {
+		docs.WriteString(fmt.Sprintf("### %s\n%s\n\n", topic.Title, topic.Body))
+	}
+
+	req := llm.ChatRequest{
+		Model: a.config.LMStudio.Model,
+		Messages: []llm.Message{
+			{Role: "system", Content: helpSystemText},
+			{Role: "user", Content: fmt.Sprintf("Documentation:\n\n%s\nQuestion: %s", docs.String(), question)},
+		},
+		MaxTokens:   a.config.Agent.MaxTokens,
+		Temperature: 0.1,
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil || len(resp.Choices) == 0 {
+		return strings.TrimSpace(docs.String()), nil
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,68 @@
+// Package: internal/agent/mcp.go
+package agent
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+)
+
+// StartMCPServers connects in the background to the enabled MCP servers
+// of the config and of the project's .claude-go/config.json, whose servers
+// replace those of the same name, and offers the model the tools of those
+// connected. Connections that fail are retried until Close. The servers
+// may have the model generate text, as their sampling setting allows.
+func (a *Agent) StartMCPServers() {
+	servers := make(map[string]config.MCPServerConfig)
+	for name, server := range a.config.MCPServers {
+		servers[name] = server
+	}
+
+	workingDir, _ := os.Getwd()
+	if project, err := config.LoadProject(workingDir); err != nil {
+		log.Printf("Warning: ignoring the project's MCP servers: %v", err)
+	} else {
+		for name, server := range project.MCPServers {
+			servers[name] = server
+		}
+	}
+
+	if len(servers) == 0 {
+		return
+	}
+	a.mcp = mcp.NewManager("claude-go", "0.1.0")
+	a.mcp.SetSampler(func(ctx context.Context, server string, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+		return a.sample(ctx, server, servers[server], params)
+	})
+	a.mcp.SetRoots(a.mcpRoots())
+	if a.config.MCPDebugLog != "" {
+		if frames, err := mcp.OpenFrameLog(a.config.MCPDebugLog); err == nil {
+			a.mcp.SetFrameLog(frames)
+		} else {
+			log.Printf("Warning: MCP traffic will not be logged: %v", err)
+		}
+	}
+	a.mcp.Start(servers)
+	a.tools.AddSource(a.mcp)
+}
+
+// WaitForMCPServers waits until each MCP server was tried once, or timeout
+// passed.
+func (a *Agent) WaitForMCPServers(timeout time.Duration) {
+	if a.mcp != nil {
+		a.mcp.Wait(timeout)
+	}
+}
+
+// MCPServers returns where the connection to each configured MCP server
+// stands, by name.
+func (a *Agent) MCPServers() []mcp.ServerStatus {
+	if a.mcp == nil {
+		return nil
+	}
+	return a.mcp.Status()
+}
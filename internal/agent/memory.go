@@ -0,0 +1,25 @@
+// Package: internal/agent/memory.go
+package agent
+
+import (
+	"os"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+// projectMemorySection renders the project's memory file, if any, for the
+// system prompt.
+func projectMemorySection(workingDir string) string {
+	data, err := os.ReadFile(config.MemoryPath(workingDir))
+	if err != nil {
+		return ""
+	}
+
+	memory := strings.TrimSpace(string(data))
+	if memory == "" {
+		return ""
+	}
+
+	return "\n\n## Project Memory\n\nFollow these project-specific instructions:\n\n" + memory
+}
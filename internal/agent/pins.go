@@ -0,0 +1,36 @@
+// Package: internal/agent/pins.go
+package agent
+
+import (
+	"log"
+	"os"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+)
+
+// pinConfigured pins the files in context.pinned, relative to workingDir.
+func pinConfigured(cm *projectctx.ContextManager, paths []string, workingDir string) {
+	for _, path := range paths {
+		if _, err := cm.Pin(resolvePath(path, workingDir)); err != nil {
+			log.Printf("Warning: failed to pin %s: %v", path, err)
+		}
+	}
+}
+
+// Pin keeps a project file in every prompt, in full, for the rest of the
+// session. It returns the path relative to the project root.
+func (a *Agent) Pin(path string) (string, error) {
+	workingDir, _ := os.Getwd()
+	return a.context.Pin(resolvePath(path, workingDir))
+}
+
+// Unpin stops keeping a file in every prompt.
+func (a *Agent) Unpin(path string) (string, error) {
+	workingDir, _ := os.Getwd()
+	return a.context.Unpin(resolvePath(path, workingDir))
+}
+
+// Pinned lists the pinned files relative to the project root.
+func (a *Agent) Pinned() []string {
+	return a.context.Pinned()
+}
@@ -0,0 +1,173 @@
+// Package: internal/agent/postmortem.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/audit"
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	postMortemTimeout      = 60 * time.Second
+	postMortemResultPrefix = 1500
+)
+
+// runTrace records the steps of an autonomous run for post-mortems.
+type runTrace struct {
+	task           string
+	steps          []runStep
+	budgetExceeded bool
+}
+
+type runStep struct {
+	Tool      string
+	Arguments string
+	Result    string
+	Failed    bool
+	AuditID   int
+}
+
+func (t *runTrace) record(call llm.ToolCall, result string, entry *audit.Entry, err error) {
+	step := runStep{
+		Tool:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+		Result:    result,
+		Failed:    err != nil,
+	}
+	if entry != nil {
+		step.AuditID = entry.ID
+	}
+	t.steps = append(t.steps, step)
+}
+
+// RunFailure is returned when an autonomous run is aborted. It carries the
+// post-mortem that was generated and saved for it.
+type RunFailure struct {
+	Err        error
+	PostMortem string
+	Path       string
+}
+
+func (f *RunFailure) Error() string {
+	if f.Path != "" {
+		return fmt.Sprintf("%v (post-mortem saved to %s)", f.Err, f.Path)
+	}
+	return f.Err.Error()
+}
+
+func (f *RunFailure) Unwrap() error { return f.Err }
+
+// failRun turns a run error into a RunFailure with a post-mortem, unless the
+// run never got as far as executing a tool.
+func (a *Agent) failRun(ctx context.Context, run *runTrace, err error) error {
+	if len(run.steps) == 0 && !run.budgetExceeded {
+		return err
+	}
+
+	report := a.generatePostMortem(ctx, run, err)
+
+	path, saveErr := a.savePostMortem(report)
+	if saveErr != nil {
+		path = ""
+	}
+
+	return &RunFailure{Err: err, PostMortem: report, Path: path}
+}
+
+func (a *Agent) generatePostMortem(ctx context.Context, run *runTrace, runErr error) string {
+	var trace strings.Builder
+	for i, step := range run.steps {
+		status := "ok"
+		if step.Failed {
+			status = "FAILED"
+		}
+		result := step.Result
+		if len(result) > postMortemResultPrefix {
+			result = result[:postMortemResultPrefix] + "\n... (truncated)"
+		}
+		trace.WriteString(fmt.Sprintf("\nStep %d [%s]: %s\n", i+1, status, describeToolCall(step.Tool, step.Arguments)))
+		trace.WriteString(result + "\n")
+	}
+
+	reason := runErr.Error()
+	if run.budgetExceeded {
+		reason = fmt.Sprintf("the run exceeded its budget of tool iterations (%v)", runErr)
+	}
+
+	header := fmt.Sprintf("# Post-mortem: %s\n\n**Task:** %s\n**Failure:** %s\n**Steps executed:** %d\n",
+		time.Now().Format(time.RFC1123), firstLine(run.task), reason, len(run.steps))
+	if a.audit != nil {
+		header += fmt.Sprintf("**Audit log:** %s\n", a.audit.Path())
+	}
+
+	// The run's own context may be the reason it stopped
+	analysisCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), postMortemTimeout)
+	defer cancel()
+
+	prompt := fmt.Sprintf(`An autonomous coding run failed. Write a short post-mortem with these sections:
+## What Was Attempted
+## Where It Failed
+## Relevant Output
+## Suggested Next Action
+(a concrete manual step the user should take)
+
+Task: %s
+Failure: %s
+
+Steps:
+%s`, run.task, reason, trace.String())
+
+	req := llm.ChatRequest{
+		Model: a.config.LMStudio.Model,
+		Messages: []llm.Message{
+			{Role: "system", Content: "You write concise, factual post-mortems of failed automated coding runs."},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:   1024,
+		Temperature: 0.2,
+	}
+
+	if resp, err := a.llmClient.Chat(analysisCtx, req); err == nil && len(resp.Choices) > 0 {
+		return header + "\n" + strings.TrimSpace(resp.Choices[0].Message.Content) + "\n"
+	}
+
+	// The model is unavailable; fall back to the raw trace
+	return header + "\n## Steps\n" + trace.String() + "\n## Suggested Next Action\nInspect the last failing step above and re-run the task once it is fixed.\n"
+}
+
+// savePostMortem stores the report next to the session's audit log, or in
+// ~/.claude-go/postmortems when no audit log is attached.
+func (a *Agent) savePostMortem(report string) (string, error) {
+	stamp := time.Now().Format("20060102-150405")
+
+	var path string
+	if a.audit != nil {
+		path = strings.TrimSuffix(a.audit.Path(), ".jsonl") + "-postmortem-" + stamp + ".md"
+	} else {
+		dir, err := config.Dir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, "postmortems", stamp+".md")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(report), 0644)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if len(line) > 200 {
+		line = line[:197] + "..."
+	}
+	return line
+}
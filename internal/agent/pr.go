@@ -0,0 +1,167 @@
+// Package: internal/agent/pr.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const maxPullRequestDiffChars = 60000
+
+type PullRequest struct {
+	Base       string   `json:"base"`
+	BaseBranch string   `json:"base_branch"` // Base as a branch name on the remote
+	Title      string   `json:"title"`
+	Summary    string   `json:"summary"`
+	Changes    []string `json:"changes"`
+	TestPlan   []string `json:"test_plan"`
+}
+
+var pullRequestSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"title":     map[string]interface{}{"type": "string", "minLength": float64(1)},
+		"summary":   map[string]interface{}{"type": "string"},
+		"changes":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"test_plan": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+	},
+	"required": []interface{}{"title", "summary", "changes", "test_plan"},
+}
+
+// DescribePullRequest summarizes the commits on the current branch that are
+// not on base into a pull request title and body. An empty base uses the
+// branch's upstream, or the remote's default branch when the upstream is the
+// branch's own remote copy.
+func (a *Agent) DescribePullRequest(ctx context.Context, base string) (*PullRequest, error) {
+	if base == "" {
+		var err error
+		if base, err = pullRequestBase(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	log, err := runGit(ctx, "log", "--no-merges", "--reverse", "--format=- %s%n%b", base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(log) == "" {
+		return nil, fmt.Errorf("no commits on the current branch that are not on %s", base)
+	}
+
+	stat, err := runGit(ctx, "diff", "--no-color", "--stat", base+"...HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := runGit(ctx, "diff", "--no-color", "--unified=3", base+"...HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	truncated := false
+	if len(diff) > maxPullRequestDiffChars {
+		diff = diff[:maxPullRequestDiffChars]
+		truncated = true
+	}
+
+	instructions, err := schemaInstructions(pullRequestSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := `You write pull request descriptions. From the commits and diff, produce a short imperative title (under 72 characters), a summary of one or two sentences saying what the change does and why, a list of the notable changes, and a test plan: concrete steps a reviewer can follow to verify the change, naming the tests added or changed in the diff. Describe only what the diff shows.` + instructions
+
+	userPrompt := fmt.Sprintf("Commits:\n%s\nFiles changed:\n%s\n```diff\n%s\n```", log, stat, diff)
+	if truncated {
+		userPrompt += "\n\nThe diff was truncated; rely on the commits and file list for the rest."
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, pullRequestSchema, 0.3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe pull request: %w", err)
+	}
+
+	var pr PullRequest
+	if err := json.Unmarshal(raw, &pr); err != nil {
+		return nil, fmt.Errorf("failed to decode pull request: %w", err)
+	}
+	pr.Base = base
+	pr.BaseBranch = remoteBranchName(ctx, base)
+	pr.Title = strings.TrimSpace(pr.Title)
+
+	return &pr, nil
+}
+
+// pullRequestBase picks the ref a pull request for the current branch
+// would merge into.
+func pullRequestBase(ctx context.Context) (string, error) {
+	branch, _ := runGit(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	branch = strings.TrimSpace(branch)
+
+	upstream, err := runGit(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	upstream = strings.TrimSpace(upstream)
+	if err == nil && upstream != "" && !strings.HasSuffix(upstream, "/"+branch) {
+		return upstream, nil
+	}
+
+	if head, err := runGit(ctx, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimSpace(head), nil
+	}
+
+	for _, candidate := range []string{"origin/main", "origin/master", "main", "master"} {
+		if candidate == branch {
+			continue
+		}
+		if _, err := runGit(ctx, "rev-parse", "--verify", "--quiet", candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot tell which branch %s merges into; pass --base", branch)
+}
+
+// remoteBranchName strips the remote from a remote-tracking ref such as
+// origin/main, as `gh pr create --base` expects a plain branch name.
+func remoteBranchName(ctx context.Context, ref string) string {
+	remotes, _ := runGit(ctx, "remote")
+	for _, remote := range strings.Fields(remotes) {
+		if strings.HasPrefix(ref, remote+"/") {
+			return strings.TrimPrefix(ref, remote+"/")
+		}
+	}
+	return ref
+}
+
+// Body renders the pull request body as Markdown.
+func (pr *PullRequest) Body() string {
+	var out strings.Builder
+	out.WriteString("## Summary\n\n")
+	out.WriteString(strings.TrimSpace(pr.Summary))
+	out.WriteString("\n")
+
+	if len(pr.Changes) > 0 {
+		out.WriteString("\n## Changes\n\n")
+		for _, change := range pr.Changes {
+			out.WriteString("- " + strings.TrimSpace(change) + "\n")
+		}
+	}
+
+	out.WriteString("\n## Test plan\n\n")
+	if len(pr.TestPlan) == 0 {
+		out.WriteString("- No test steps provided\n")
+	}
+	for _, step := range pr.TestPlan {
+		out.WriteString("- " + strings.TrimSpace(step) + "\n")
+	}
+
+	return out.String()
+}
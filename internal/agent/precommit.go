@@ -0,0 +1,197 @@
+// Package: internal/agent/precommit.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+const maxPreCommitDiffChars = 40000
+
+var (
+	hunkNewStart = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+	todoMarker   = regexp.MustCompile(`\b(TODO|FIXME|XXX|HACK)\b`)
+	// Conflict markers left behind by a merge or rebase
+	conflictMarker = regexp.MustCompile(`^(<{7}|>{7})( |$)|^={7}$`)
+)
+
+// debugStatements are statements that rarely belong in a commit, by file
+// extension.
+var debugStatements = map[string]*regexp.Regexp{
+	".go":  regexp.MustCompile(`^\s*(print|println)\(|\bspew\.(Dump|Printf)|\bruntime\.Breakpoint\(`),
+	".js":  regexp.MustCompile(`\bconsole\.(log|debug|trace)\(|\bdebugger\b`),
+	".jsx": regexp.MustCompile(`\bconsole\.(log|debug|trace)\(|\bdebugger\b`),
+	".ts":  regexp.MustCompile(`\bconsole\.(log|debug|trace)\(|\bdebugger\b`),
+	".tsx": regexp.MustCompile(`\bconsole\.(log|debug|trace)\(|\bdebugger\b`),
+	".py":  regexp.MustCompile(`\bbreakpoint\(\)|\b(i?pdb)\.set_trace\(`),
+	".rb":  regexp.MustCompile(`\bbinding\.pry\b|\bbyebug\b`),
+	".rs":  regexp.MustCompile(`\bdbg!\(`),
+	".php": regexp.MustCompile(`\b(var_dump|dd|dump)\(`),
+}
+
+// PreCommitFinding is one reason the pre-commit review blocks a commit.
+type PreCommitFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Kind    string `json:"kind"` // secret, debug, todo, conflict or broken
+	Message string `json:"message"`
+}
+
+func (f PreCommitFinding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("[%s] %s: %s", f.Kind, location, f.Message)
+}
+
+// PreCommitError blocks a commit. Retrying with CommitOptions.SkipReview
+// overrides it.
+type PreCommitError struct {
+	Findings []PreCommitFinding
+}
+
+func (e *PreCommitError) Error() string {
+	return fmt.Sprintf("pre-commit review found %d problem(s) in the staged changes", len(e.Findings))
+}
+
+var preCommitSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"findings": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file":    map[string]interface{}{"type": "string"},
+					"line":    map[string]interface{}{"type": "integer", "minimum": float64(0)},
+					"message": map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"file", "message"},
+			},
+		},
+	},
+	"required": []interface{}{"findings"},
+}
+
+// PreCommitReview checks the staged changes as configured by
+// git.pre_commit_review and returns what should block the commit.
+func (a *Agent) PreCommitReview(ctx context.Context) ([]PreCommitFinding, error) {
+	mode := a.config.Git.PreCommitReview
+	switch mode {
+	case "", "off":
+		return nil, nil
+	case "scan", "full":
+	default:
+		return nil, fmt.Errorf("unknown git.pre_commit_review %q (available: off, scan, full)", mode)
+	}
+
+	diff, err := runGit(ctx, "diff", "--cached", "--no-color", "--no-ext-diff", "--unified=0")
+	if err != nil {
+		return nil, err
+	}
+
+	findings := scanAddedLines(diff)
+
+	if mode == "full" && strings.TrimSpace(diff) != "" {
+		broken, err := a.findBrokenCode(ctx, diff)
+		if err != nil {
+			return nil, fmt.Errorf("pre-commit review failed: %w", err)
+		}
+		findings = append(findings, broken...)
+	}
+
+	return findings, nil
+}
+
+// scanAddedLines runs the pattern checks over the lines a diff adds.
+func scanAddedLines(diff string) []PreCommitFinding {
+	var findings []PreCommitFinding
+	file := ""
+	lineNum := 0
+	inHeader := false
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHeader = true
+			continue
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" {
+				file = ""
+			}
+			continue
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+			if m := hunkNewStart.FindStringSubmatch(line); m != nil {
+				lineNum, _ = strconv.Atoi(m[1])
+			}
+			continue
+		case inHeader || !strings.HasPrefix(line, "+") || file == "":
+			continue
+		}
+
+		added := line[1:]
+		ext := strings.ToLower(filepath.Ext(file))
+
+		for _, finding := range tools.ScanLine(file, added, lineNum, "secrets") {
+			findings = append(findings, PreCommitFinding{File: file, Line: lineNum, Kind: "secret", Message: finding.Rule + ": " + finding.Snippet})
+		}
+		if conflictMarker.MatchString(added) {
+			findings = append(findings, PreCommitFinding{File: file, Line: lineNum, Kind: "conflict", Message: "merge conflict marker"})
+		}
+		if pattern := debugStatements[ext]; pattern != nil && pattern.MatchString(added) {
+			findings = append(findings, PreCommitFinding{File: file, Line: lineNum, Kind: "debug", Message: strings.TrimSpace(added)})
+		}
+		if m := todoMarker.FindString(added); m != "" {
+			findings = append(findings, PreCommitFinding{File: file, Line: lineNum, Kind: "todo", Message: strings.TrimSpace(added)})
+		}
+
+		lineNum++
+	}
+
+	return findings
+}
+
+// findBrokenCode asks the model for changes that clearly cannot work.
+func (a *Agent) findBrokenCode(ctx context.Context, diff string) ([]PreCommitFinding, error) {
+	if len(diff) > maxPreCommitDiffChars {
+		diff = diff[:maxPreCommitDiffChars]
+	}
+
+	instructions, err := schemaInstructions(preCommitSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: `You are a pre-commit check. Report only code in the added lines that is obviously broken: syntax errors, unbalanced brackets, references to names that plainly do not exist, unreachable or half-finished code, and leftover placeholder text. Do not report style, design or anything you are unsure about; return an empty list when nothing is clearly broken. Use the line number in the new version of the file.` + instructions},
+		{Role: "user", Content: fmt.Sprintf("```diff\n%s\n```", diff)},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, preCommitSchema, 0.1)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Findings []PreCommitFinding `json:"findings"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode findings: %w", err)
+	}
+	for i := range result.Findings {
+		result.Findings[i].Kind = "broken"
+	}
+	return result.Findings, nil
+}
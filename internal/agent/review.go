@@ -0,0 +1,145 @@
+// Package: internal/agent/review.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const maxReviewDiffChars = 60000
+
+var severityRank = map[string]int{"critical": 0, "major": 1, "minor": 2, "nit": 3}
+
+type ReviewOptions struct {
+	Range  string // e.g. "main..HEAD"; empty reviews uncommitted changes
+	Staged bool
+}
+
+type Review struct {
+	Summary  string          `json:"summary"`
+	Findings []ReviewFinding `json:"findings"`
+}
+
+type ReviewFinding struct {
+	Severity   string `json:"severity"`
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+var reviewSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{"type": "string"},
+		"findings": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"severity":   map[string]interface{}{"type": "string", "enum": []interface{}{"critical", "major", "minor", "nit"}},
+					"file":       map[string]interface{}{"type": "string"},
+					"line":       map[string]interface{}{"type": "integer", "minimum": float64(0)},
+					"message":    map[string]interface{}{"type": "string"},
+					"suggestion": map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"severity", "file", "message"},
+			},
+		},
+	},
+	"required": []interface{}{"summary", "findings"},
+}
+
+// ReviewDiff reviews the git diff selected by opts and returns structured
+// findings ordered by severity.
+func (a *Agent) ReviewDiff(ctx context.Context, opts ReviewOptions) (*Review, error) {
+	args := []string{"diff", "--no-color", "--unified=5"}
+	switch {
+	case opts.Staged:
+		args = append(args, "--staged")
+	case opts.Range != "":
+		args = append(args, opts.Range)
+	default:
+		args = append(args, "HEAD")
+	}
+
+	diff, err := runGit(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(diff) == "" {
+		return &Review{Summary: "No changes to review"}, nil
+	}
+
+	truncated := false
+	if len(diff) > maxReviewDiffChars {
+		diff = diff[:maxReviewDiffChars]
+		truncated = true
+	}
+
+	instructions, err := schemaInstructions(reviewSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := `You are a meticulous senior engineer performing code review. Review only the changes in the diff. Report real problems: bugs, security issues, race conditions, error handling gaps, missing tests, and unclear code. Use "line" for the line number in the new version of the file. Do not report issues in unchanged code, and return an empty findings list if the change looks good.` + instructions
+
+	userPrompt := fmt.Sprintf("Review this diff:\n\n```diff\n%s\n```", diff)
+	if truncated {
+		userPrompt += "\n\nThe diff was truncated; review only what is shown."
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, reviewSchema, 0.2)
+	if err != nil {
+		return nil, fmt.Errorf("review failed: %w", err)
+	}
+
+	var review Review
+	if err := json.Unmarshal(raw, &review); err != nil {
+		return nil, fmt.Errorf("failed to decode review: %w", err)
+	}
+
+	sort.SliceStable(review.Findings, func(i, j int) bool {
+		return severityRank[review.Findings[i].Severity] < severityRank[review.Findings[j].Severity]
+	})
+
+	return &review, nil
+}
+
+// Format renders the review for the terminal.
+func (r *Review) Format() string {
+	var out strings.Builder
+	out.WriteString(r.Summary)
+	out.WriteString("\n")
+
+	if len(r.Findings) == 0 {
+		out.WriteString("\nNo findings.\n")
+		return out.String()
+	}
+
+	out.WriteString(fmt.Sprintf("\n%d finding(s):\n", len(r.Findings)))
+	for _, f := range r.Findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		out.WriteString(fmt.Sprintf("\n[%s] %s\n  %s\n", strings.ToUpper(f.Severity), location, f.Message))
+		if f.Suggestion != "" {
+			out.WriteString(fmt.Sprintf("  Suggestion: %s\n", f.Suggestion))
+		}
+	}
+
+	return out.String()
+}
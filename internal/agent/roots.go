@@ -0,0 +1,97 @@
+// Package: internal/agent/roots.go
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+)
+
+// addConfiguredRoots adds the directories in context.additional_dirs,
+// relative to workingDir or the home directory ("~/"), to the context.
+func addConfiguredRoots(cm *projectctx.ContextManager, dirs []string, workingDir string) {
+	for _, dir := range dirs {
+		if _, err := cm.AddRoot(resolvePath(dir, workingDir)); err != nil {
+			log.Printf("Warning: failed to add %s to the context: %v", dir, err)
+		}
+	}
+}
+
+// resolvePath resolves a configured or typed path: relative to workingDir,
+// or to the home directory with "~/".
+func resolvePath(path, workingDir string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(workingDir, path)
+	}
+	return path
+}
+
+// AddRoot adds a directory, such as a shared library or a sibling service,
+// to the context for the rest of the session.
+func (a *Agent) AddRoot(dir string) (projectctx.ContextRoot, error) {
+	workingDir, _ := os.Getwd()
+	root, err := a.context.AddRoot(resolvePath(dir, workingDir))
+	if err == nil && a.mcp != nil {
+		a.mcp.SetRoots(a.mcpRoots())
+	}
+	return root, err
+}
+
+// Roots lists the directories added to the context besides the project.
+func (a *Agent) Roots() []projectctx.ContextRoot {
+	return a.context.Roots()
+}
+
+// mcpRoots are the roots offered to MCP servers: the working directory and
+// the added directories.
+func (a *Agent) mcpRoots() []mcp.Root {
+	workingDir, _ := os.Getwd()
+	roots := []mcp.Root{{URI: mcp.FileURI(workingDir), Name: filepath.Base(workingDir)}}
+	for _, root := range a.Roots() {
+		roots = append(roots, mcp.Root{URI: mcp.FileURI(root.Path), Name: root.Label})
+	}
+	return roots
+}
+
+// rootsContext renders the added directories within maxTokens: a quarter
+// for their structure, the rest for their most recently modified files,
+// named only when sent has them in the conversation.
+func (a *Agent) rootsContext(maxTokens int, sent *sentFiles) string {
+	roots := a.context.RootContexts(maxTokens * 3 / 4)
+	if len(roots) == 0 {
+		return ""
+	}
+
+	var context strings.Builder
+	context.WriteString("\n## Additional Roots:\n")
+	for _, root := range roots {
+		context.WriteString(fmt.Sprintf("\n### [%s] %s\n", root.Label, root.Path))
+		context.WriteString(truncateTokens(root.Structure, maxTokens/4/len(roots)))
+		for _, file := range root.Files {
+			relPath, err := filepath.Rel(root.Path, file.Path)
+			if err != nil {
+				continue
+			}
+			if file.OutlineOnly {
+				context.WriteString(sent.entry(file.Path, fmt.Sprintf("\n--- [%s] %s (outline) ---\n", root.Label, relPath), file.Outline()))
+				continue
+			}
+			content, err := file.ReadContent()
+			if err != nil {
+				continue
+			}
+			context.WriteString(sent.entry(file.Path, fmt.Sprintf("\n--- [%s] %s ---\n", root.Label, relPath), content+"\n"))
+		}
+	}
+	return context.String()
+}
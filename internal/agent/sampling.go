@@ -0,0 +1,131 @@
+// Package: internal/agent/sampling.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+)
+
+// modelSize finds the parameter count in a model name, such as the 14b of
+// qwen2.5-coder:14b.
+var modelSize = regexp.MustCompile(`(?i)(?:^|[^a-z0-9.])(\d+(?:\.\d+)?)b(?:$|[^a-z0-9])`)
+
+// sample answers an MCP server's request to have the model generate a
+// message. Unless the server's sampling is "allow", the user approves it
+// through the approver of the turn whose tool call caused it; without one,
+// as outside a turn or in a headless run, it is rejected.
+func (a *Agent) sample(ctx context.Context, server string, cfg config.MCPServerConfig, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	var messages []llm.Message
+	if params.SystemPrompt != "" {
+		messages = append(messages, llm.Message{Role: "system", Content: params.SystemPrompt})
+	}
+	for _, msg := range params.Messages {
+		if msg.Content.Type != "text" {
+			return nil, fmt.Errorf("only text messages can be sampled, not %s", msg.Content.Type)
+		}
+		messages = append(messages, llm.Message{Role: msg.Role, Content: msg.Content.Text})
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages to sample from")
+	}
+
+	model := a.samplingModel(ctx, params.ModelPreferences)
+	if cfg.Sampling != mcp.SamplingAllow {
+		approve, _ := ctx.Value(approverKey{}).(Approver)
+		if approve == nil {
+			return nil, fmt.Errorf("%w: it needs approval and no one is available to give it", mcp.ErrSamplingRejected)
+		}
+		if !approve(ApprovalRequest{Tool: "sampling", Server: server, Model: model, Prompt: messages[len(messages)-1].Content}) {
+			return nil, mcp.ErrSamplingRejected
+		}
+	}
+
+	req := llm.ChatRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   params.MaxTokens,
+		Temperature: a.config.Agent.Temperature,
+		Stop:        params.StopSequences,
+	}
+	if req.MaxTokens <= 0 || req.MaxTokens > a.config.Agent.MaxTokens {
+		req.MaxTokens = a.config.Agent.MaxTokens
+	}
+	if params.Temperature != nil {
+		req.Temperature = *params.Temperature
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("LLM request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from LLM")
+	}
+
+	stopReason := "endTurn"
+	if resp.Choices[0].Finish == "length" {
+		stopReason = "maxTokens"
+	}
+	return &mcp.CreateMessageResult{
+		Role:       "assistant",
+		Content:    mcp.SamplingContent{Type: "text", Text: resp.Choices[0].Message.Content},
+		Model:      model,
+		StopReason: stopReason,
+	}, nil
+}
+
+// samplingModel maps a server's model preferences onto the models LM
+// Studio has: the first whose name contains a hint; else, when the server
+// puts speed or cost ahead of intelligence, the smallest by the parameter
+// count in its name, and the largest the other way round; else the
+// configured model.
+func (a *Agent) samplingModel(ctx context.Context, prefs *mcp.ModelPreferences) string {
+	if prefs == nil {
+		return a.config.LMStudio.Model
+	}
+	available, err := a.llmClient.GetModels(ctx)
+	if err != nil {
+		return a.config.LMStudio.Model
+	}
+	var models []string
+	for _, model := range available {
+		if !strings.Contains(strings.ToLower(model), "embed") {
+			models = append(models, model)
+		}
+	}
+
+	for _, hint := range prefs.Hints {
+		for _, model := range models {
+			if hint.Name != "" && strings.Contains(strings.ToLower(model), strings.ToLower(hint.Name)) {
+				return model
+			}
+		}
+	}
+
+	economy := max(prefs.SpeedPriority, prefs.CostPriority)
+	if economy == prefs.IntelligencePriority {
+		return a.config.LMStudio.Model
+	}
+	best, bestSize := "", 0.0
+	for _, model := range models {
+		match := modelSize.FindStringSubmatch(model)
+		if match == nil {
+			continue
+		}
+		size, _ := strconv.ParseFloat(match[1], 64)
+		if best == "" || (economy > prefs.IntelligencePriority) == (size < bestSize) {
+			best, bestSize = model, size
+		}
+	}
+	if best == "" {
+		return a.config.LMStudio.Model
+	}
+	return best
+}
@@ -0,0 +1,118 @@
+// Package: internal/agent/scope.go
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+const maxScopeListing = 50
+
+// registerScopeTool adds widen_context when the project is a monorepo.
+func registerScopeTool(registry *tools.Registry, cm *projectctx.ContextManager) {
+	if cm.Workspace() == nil {
+		return
+	}
+	registry.Register(&widenContextTool{context: cm})
+}
+
+// widenContextTool adds another workspace member to the project context.
+type widenContextTool struct {
+	context *projectctx.ContextManager
+}
+
+func (t *widenContextTool) Name() string { return "widen_context" }
+
+func (t *widenContextTool) Description() string {
+	return "Add another package of this monorepo workspace to the project context, when the user asks about code outside the current package. Returns the package's files; later requests include its code"
+}
+
+func (t *widenContextTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"package": map[string]interface{}{
+				"type":        "string",
+				"description": "The member's path relative to the workspace root, its directory name, or \"all\" for the whole workspace",
+			},
+		},
+		"required": []string{"package"},
+	}
+}
+
+func (t *widenContextTool) Execute(args map[string]interface{}) (string, error) {
+	name, _ := args["package"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("package is required")
+	}
+
+	member, err := t.context.WidenScope(name)
+	if err != nil {
+		return "", err
+	}
+	if member == "" {
+		return "The project context now covers the whole workspace.", nil
+	}
+
+	entries, err := t.context.Entries()
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("Added %s to the project context. Its files (relative to %s):\n", member, t.context.Root()))
+	listed := 0
+	for _, entry := range entries {
+		rel := filepath.ToSlash(entry.RelPath)
+		if entry.IsDir || !strings.HasPrefix(rel, member+"/") {
+			continue
+		}
+		if listed == maxScopeListing {
+			out.WriteString("...\n")
+			break
+		}
+		out.WriteString(rel + "\n")
+		listed++
+	}
+	return out.String(), nil
+}
+
+// workspaceSection tells the model about the monorepo and which of its
+// members the context covers.
+func workspaceSection(cm *projectctx.ContextManager, workingDir string) string {
+	ws := cm.Workspace()
+	if ws == nil {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf("\n\n## Workspace\n\nThis is a %s workspace rooted at %s; paths in the project context are relative to it.", ws.Kind, ws.Root))
+	if rel, err := filepath.Rel(ws.Root, workingDir); err == nil && rel != "." {
+		section.WriteString(fmt.Sprintf(" The working directory is %s.", filepath.ToSlash(rel)))
+	}
+	section.WriteString("\nMembers: " + strings.Join(ws.Members, ", ") + "\n")
+	if scope := cm.Scope(); len(scope) > 0 {
+		section.WriteString(fmt.Sprintf("The context covers %s only; call widen_context to add another member when the user asks about it.\n", strings.Join(scope, ", ")))
+	}
+	return section.String()
+}
+
+// Workspace returns the monorepo the project belongs to, or nil, and the
+// members the context is limited to (nil for all of them).
+func (a *Agent) Workspace() (*projectctx.Workspace, []string) {
+	return a.context.Workspace(), a.context.Scope()
+}
+
+// WidenScope adds a workspace member, or "all", to the project context.
+func (a *Agent) WidenScope(name string) (string, error) {
+	return a.context.WidenScope(name)
+}
+
+// ResetScope limits the project context to the starting member again.
+func (a *Agent) ResetScope() {
+	a.context.ResetScope()
+}
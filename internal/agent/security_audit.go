@@ -0,0 +1,46 @@
+// Package: internal/agent/security_audit.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const securityAuditPrompt = `You are an application security engineer auditing a codebase. You are given the output of a pattern-based scanner; many of its matches may be false positives. Use the file and search tools to inspect the referenced code where needed, then write a prioritized vulnerability report:
+
+## Summary
+One paragraph on the overall security posture.
+
+## Findings
+Ordered from most to least severe. For each: severity (critical/high/medium/low), title, file:line references, why it is exploitable, and a concrete remediation. Drop scanner matches you confirm are false positives and list them briefly under "Dismissed".
+
+## Recommendations
+Project-wide hardening steps.`
+
+// SecurityAudit scans the project for secrets and injection-prone code and
+// has the model turn the raw matches into a prioritized report.
+func (a *Agent) SecurityAudit(ctx context.Context, onEvent func(Event)) (string, error) {
+	scan, err := a.tools.Execute("security_scan", map[string]interface{}{"path": "."})
+	if err != nil {
+		return "", fmt.Errorf("security scan failed: %w", err)
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	structure, _ := a.getProjectStructure(workingDir)
+
+	userPrompt := fmt.Sprintf("## Project Structure\n```\n%s```\n\n## Scanner Output\n```\n%s\n```\n\nAudit this project and write the report.", structure, scan)
+
+	messages := []llm.Message{
+		{Role: "system", Content: securityAuditPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+
+	return a.runConversation(ctx, messages, onEvent)
+}
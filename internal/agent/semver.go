@@ -0,0 +1,288 @@
+// Package: internal/agent/semver.go
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const maxBumpCommits = 200
+
+var semverTag = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)`)
+
+// VersionBump is a recommended release version with the evidence for it.
+type VersionBump struct {
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Bump       string     `json:"bump"` // major, minor or patch
+	Reasoning  string     `json:"reasoning"`
+	Commits    int        `json:"commits"`
+	API        APIChanges `json:"api"`
+	TagCommand string     `json:"tag_command"`
+}
+
+// APIChanges lists exported Go identifiers, as "pkg/path.Name", whose
+// declarations were removed, changed or added.
+type APIChanges struct {
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+}
+
+var versionBumpSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"bump":      map[string]interface{}{"type": "string", "enum": []interface{}{"major", "minor", "patch"}},
+		"reasoning": map[string]interface{}{"type": "string", "minLength": float64(1)},
+	},
+	"required": []interface{}{"bump", "reasoning"},
+}
+
+// RecommendVersionBump looks at the commits and the exported Go API since
+// the last tag (or since) and recommends the next semantic version.
+func (a *Agent) RecommendVersionBump(ctx context.Context, since string) (*VersionBump, error) {
+	if since == "" {
+		tag, err := runGit(ctx, "describe", "--tags", "--abbrev=0")
+		if err != nil {
+			return nil, fmt.Errorf("no tags to compare against; pass the last release with --since")
+		}
+		since = strings.TrimSpace(tag)
+	}
+
+	m := semverTag.FindStringSubmatch(since)
+	if m == nil {
+		return nil, fmt.Errorf("%s is not a semantic version tag (vMAJOR.MINOR.PATCH)", since)
+	}
+	prefix := m[1]
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	patch, _ := strconv.Atoi(m[4])
+
+	commits, err := changelogCommits(ctx, since+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits since %s", since)
+	}
+
+	api, err := goAPIChanges(ctx, since, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	bump := &VersionBump{From: since, Commits: len(commits), API: *api}
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Last release: %s\n\nCommits since (type: subject):\n", since))
+	for i, commit := range commits {
+		if i == maxBumpCommits {
+			prompt.WriteString(fmt.Sprintf("... and %d more\n", len(commits)-i))
+			break
+		}
+		prompt.WriteString(fmt.Sprintf("- %s: %s\n", commit.Type, commit.Subject))
+	}
+	prompt.WriteString("\nExported Go API changes (internal packages and main excluded):\n")
+	if len(api.Removed)+len(api.Changed)+len(api.Added) == 0 {
+		prompt.WriteString("none\n")
+	}
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"Removed", api.Removed}, {"Changed signature", api.Changed}, {"Added", api.Added}} {
+		if len(group.names) > 0 {
+			prompt.WriteString(fmt.Sprintf("%s: %s\n", group.label, strings.Join(group.names, ", ")))
+		}
+	}
+	if major == 0 {
+		prompt.WriteString("\nThe project is before 1.0.0.\n")
+	}
+
+	instructions, err := schemaInstructions(versionBumpSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: `You are a release manager applying Semantic Versioning 2.0.0. Recommend "major" when the release breaks users (removed or changed exported API, breaking-change commits, changed behavior people rely on), "minor" when it adds backwards-compatible functionality, and "patch" for backwards-compatible fixes only. Before 1.0.0, breaking changes may ship as a minor bump; say so if you choose that. Explain the decision in two to four sentences citing the specific commits or API changes that decided it.` + instructions},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, versionBumpSchema, 0.2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recommend a version: %w", err)
+	}
+	if err := json.Unmarshal(raw, bump); err != nil {
+		return nil, fmt.Errorf("failed to decode recommendation: %w", err)
+	}
+
+	switch bump.Bump {
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	default:
+		patch++
+	}
+	bump.To = fmt.Sprintf("%s%d.%d.%d", prefix, major, minor, patch)
+	bump.TagCommand = fmt.Sprintf("git tag -a %s -m %q", bump.To, "Release "+bump.To)
+
+	return bump, nil
+}
+
+// goAPIChanges compares the exported declarations of the public Go
+// packages between two revisions.
+func goAPIChanges(ctx context.Context, from, to string) (*APIChanges, error) {
+	output, err := runGit(ctx, "diff", "--name-only", "--no-renames", from, to, "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	before := make(map[string]string)
+	after := make(map[string]string)
+	for _, file := range strings.Fields(output) {
+		if !isPublicGoFile(file) {
+			continue
+		}
+		oldSource, _ := runGit(ctx, "show", from+":"+file)
+		newSource, _ := runGit(ctx, "show", to+":"+file)
+		collectGoAPI(path.Dir(file), oldSource, before)
+		collectGoAPI(path.Dir(file), newSource, after)
+	}
+
+	changes := &APIChanges{}
+	for name, signature := range before {
+		current, ok := after[name]
+		switch {
+		case !ok:
+			changes.Removed = append(changes.Removed, name)
+		case current != signature:
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changes.Added = append(changes.Added, name)
+		}
+	}
+
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Added)
+	return changes, nil
+}
+
+func isPublicGoFile(file string) bool {
+	if strings.HasSuffix(file, "_test.go") {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "internal" || dir == "testdata" || dir == "vendor" {
+			return false
+		}
+	}
+	return true
+}
+
+// collectGoAPI records the exported declarations in source, keyed by
+// "dir.Name" (methods and fields as "dir.Type.Name"), with their
+// signatures.
+func collectGoAPI(dir, source string, api map[string]string) {
+	if source == "" {
+		return
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.SkipObjectResolution)
+	if err != nil || file.Name.Name == "main" {
+		return
+	}
+
+	render := func(node interface{}) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, node)
+		return buf.String()
+	}
+	key := func(names ...string) string {
+		return dir + "." + strings.Join(names, ".")
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil {
+				api[key(d.Name.Name)] = render(d.Type)
+				continue
+			}
+			recv := receiverName(d.Recv.List[0].Type)
+			if ast.IsExported(recv) {
+				api[key(recv, d.Name.Name)] = render(d.Type)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if !s.Name.IsExported() {
+						continue
+					}
+					structType, ok := s.Type.(*ast.StructType)
+					if !ok {
+						api[key(s.Name.Name)] = render(s.Type)
+						continue
+					}
+					api[key(s.Name.Name)] = "struct"
+					for _, field := range structType.Fields.List {
+						for _, name := range field.Names {
+							if name.IsExported() {
+								api[key(s.Name.Name, name.Name)] = render(field.Type)
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if !name.IsExported() {
+							continue
+						}
+						signature := d.Tok.String()
+						if s.Type != nil {
+							signature += " " + render(s.Type)
+						}
+						api[key(name.Name)] = signature
+					}
+				}
+			}
+		}
+	}
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+// Package: internal/agent/sessions.go
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/history"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const maxSessionTitleChars = 60
+
+// AttachHistory persists every exchange to s, titling the session after
+// its first one.
+func (a *Agent) AttachHistory(s *history.Session) {
+	a.history = s
+}
+
+// SearchSessions finds past sessions matching a free-text description,
+// best match first.
+func (a *Agent) SearchSessions(query string, limit int) ([]history.Match, error) {
+	return history.Search(query, limit)
+}
+
+// recordExchange saves an answered request to the session history. Saving
+// is best effort; a full disk must not fail the answer.
+func (a *Agent) recordExchange(ctx context.Context, input, response string) {
+	if a.history == nil {
+		return
+	}
+
+	untitled, err := a.history.Record(input, response)
+	if err != nil || !untitled {
+		return
+	}
+	a.history.SetTitle(a.sessionTitle(ctx, input, response))
+}
+
+// sessionTitle names a session from its first exchange, falling back to
+// the request itself when the model gives nothing usable.
+func (a *Agent) sessionTitle(ctx context.Context, input, response string) string {
+	fallback := firstLine(input)
+	if len(fallback) > maxSessionTitleChars {
+		fallback = fallback[:maxSessionTitleChars-3] + "..."
+	}
+
+	if len(response) > 2000 {
+		response = response[:2000]
+	}
+
+	req := llm.ChatRequest{
+		Model: a.config.LMStudio.Model,
+		Messages: []llm.Message{
+			{Role: "system", Content: "Title this coding session in at most six words, naming the concrete task (e.g. \"Fix websocket reconnect backoff\"). Reply with the title only, no quotes or punctuation at the end."},
+			{Role: "user", Content: "Request: " + input + "\n\nAnswer: " + response},
+		},
+		MaxTokens:   24,
+		Temperature: 0.3,
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil || len(resp.Choices) == 0 {
+		return fallback
+	}
+
+	title := strings.Trim(firstLine(resp.Choices[0].Message.Content), "\"'`#*. ")
+	if title == "" || len(title) > maxSessionTitleChars {
+		return fallback
+	}
+	return title
+}
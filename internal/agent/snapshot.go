@@ -0,0 +1,167 @@
+// Package: internal/agent/snapshot.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const maxWhatChangedDiff = 24 * 1024
+
+// Snapshot is the state of the working tree (tracked and untracked,
+// non-ignored files) stored as a git tree object. Taking one leaves the
+// user's index and working tree untouched.
+type Snapshot struct {
+	Tree  string
+	Taken time.Time
+}
+
+// TakeSnapshot records the working tree as a git tree object by staging it
+// into a throwaway copy of the index.
+func TakeSnapshot(ctx context.Context) (*Snapshot, error) {
+	indexPath, err := runGit(ctx, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return nil, fmt.Errorf("snapshots need a git repository: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "claude-go-index-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	// Starting from the real index lets git reuse its cached file hashes
+	if data, err := os.ReadFile(strings.TrimSpace(indexPath)); err == nil {
+		if err := os.WriteFile(tmp.Name(), data, 0600); err != nil {
+			return nil, err
+		}
+	} else {
+		os.Remove(tmp.Name())
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + tmp.Name()}
+	root, err := runGit(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGitEnv(ctx, env, "", "-C", strings.TrimSpace(root), "add", "--all"); err != nil {
+		return nil, err
+	}
+
+	tree, err := runGitEnv(ctx, env, "", "write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Tree: strings.TrimSpace(tree), Taken: time.Now()}, nil
+}
+
+// SetBaseline records the snapshot /whatchanged compares against.
+func (a *Agent) SetBaseline(s *Snapshot) {
+	a.baseline = s
+}
+
+// WhatChanged compares the working tree with the session's baseline and
+// has the model narrate the changes, grouped by intent and attributed to the
+// agent, other claude-go sessions, or the user.
+func (a *Agent) WhatChanged(ctx context.Context) (string, error) {
+	if a.baseline == nil {
+		return "", fmt.Errorf("no session-start snapshot was taken (is this a git repository?)")
+	}
+
+	current, err := TakeSnapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if current.Tree == a.baseline.Tree {
+		return fmt.Sprintf("Nothing has changed since the session started at %s.", a.baseline.Taken.Format(time.Kitchen)), nil
+	}
+
+	names, err := runGit(ctx, "diff", "--name-status", "-M", a.baseline.Tree, current.Tree)
+	if err != nil {
+		return "", err
+	}
+	stat, _ := runGit(ctx, "diff", "--stat", "-M", a.baseline.Tree, current.Tree)
+	diff, _ := runGit(ctx, "diff", "-M", a.baseline.Tree, current.Tree)
+	if len(diff) > maxWhatChangedDiff {
+		diff = diff[:maxWhatChangedDiff] + "\n... (diff truncated)"
+	}
+
+	var changes strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(names), "\n") {
+		fields := strings.Split(line, "\t")
+		path := fields[len(fields)-1]
+		changes.WriteString(fmt.Sprintf("%s [%s]\n", line, a.editAuthor(ctx, path)))
+	}
+
+	prompt := fmt.Sprintf(`Summarize everything that changed in this workspace since the session started at %s.
+
+Group the changes by intent (e.g. "fixed the config loader", "added tests for X") rather than by file, and write a short narrative: one heading per group, a sentence or two on what was done and why it appears to have been done, and the files involved. Say who made each group of changes using the author tags (agent = this assistant, other session = another claude-go session, user = edits made outside claude-go). End with anything that looks unfinished.
+
+## Changed Files (status, path, author)
+%s
+## Stat
+%s
+## Diff
+%s`, a.baseline.Taken.Format(time.Kitchen), changes.String(), stat, diff)
+
+	req := llm.ChatRequest{
+		Model: a.config.LMStudio.Model,
+		Messages: []llm.Message{
+			{Role: "system", Content: "You write concise, accurate summaries of code changes made during a work session."},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:   a.config.Agent.MaxTokens,
+		Temperature: 0.2,
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("LLM request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no summary generated")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// editAuthor attributes a changed path (relative to the repository root)
+// using the workspace edit journal.
+func (a *Agent) editAuthor(ctx context.Context, path string) string {
+	if a.workspace == nil {
+		return "unknown"
+	}
+
+	root, err := runGit(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "unknown"
+	}
+	abs := filepath.Join(strings.TrimSpace(root), filepath.FromSlash(path))
+
+	if own, err := a.workspace.OwnEdits(); err == nil {
+		for _, edit := range own {
+			if edit.Path == abs && edit.Time.After(a.baseline.Taken) {
+				return "agent"
+			}
+		}
+	}
+
+	if foreign, err := a.workspace.ForeignEdits(a.baseline.Taken); err == nil {
+		for _, edit := range foreign {
+			if edit.Path == abs {
+				return "other session"
+			}
+		}
+	}
+
+	return "user"
+}
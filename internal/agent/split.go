@@ -0,0 +1,315 @@
+// Package: internal/agent/split.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const maxSplitPromptChars = 60000
+
+// diffFile is one file of a unified diff: its header lines and hunks.
+type diffFile struct {
+	Path   string
+	Header string
+	Hunks  []string
+	// Atomic files (new, deleted, renamed, binary or mode-only changes)
+	// can only be applied whole, so their hunks stay together.
+	Atomic bool
+}
+
+// CommitHunk is a unit of change that can be committed on its own: one
+// hunk, or a whole file when it cannot be split.
+type CommitHunk struct {
+	ID     string `json:"id"`
+	File   string `json:"file"`
+	Header string `json:"header"` // "@@ ... @@" line, or a description for whole files
+	order  int
+	file   *diffFile
+	hunks  []string
+}
+
+// CommitGroup is one proposed commit.
+type CommitGroup struct {
+	Message string       `json:"message"`
+	Hunks   []CommitHunk `json:"hunks"`
+}
+
+// CommitSplit is a proposal to commit the staged changes as several
+// commits.
+type CommitSplit struct {
+	Groups []CommitGroup `json:"groups"`
+}
+
+var commitSplitSchema = schema.Schema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"commits": map[string]interface{}{
+			"type":     "array",
+			"minItems": float64(1),
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"message": map[string]interface{}{"type": "string", "minLength": float64(1)},
+					"hunks":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "minItems": float64(1)},
+				},
+				"required": []interface{}{"message", "hunks"},
+			},
+		},
+	},
+	"required": []interface{}{"commits"},
+}
+
+// ProposeCommitSplit clusters the staged hunks into logical groups, each
+// with a commit message in the configured style. With git.auto_stage set,
+// all changes are staged first.
+func (a *Agent) ProposeCommitSplit(ctx context.Context) (*CommitSplit, error) {
+	if a.config.Git.AutoStage {
+		if _, err := runGit(ctx, "add", "--all"); err != nil {
+			return nil, err
+		}
+	}
+
+	diff, err := runGit(ctx, "diff", "--cached", "--no-color", "--no-ext-diff", "--binary", "--find-renames")
+	if err != nil {
+		return nil, err
+	}
+
+	units := commitHunks(parseUnifiedDiff(diff))
+	if len(units) == 0 {
+		return nil, fmt.Errorf("no staged changes to split")
+	}
+
+	rule, err := a.commitStyleRule()
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := schemaInstructions(commitSplitSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Staged changes, one entry per hunk:\n")
+	for _, unit := range units {
+		body := strings.Join(unit.hunks, "")
+		if unit.file.Atomic && len(unit.hunks) == 0 {
+			body = unit.file.Header
+		}
+		entry := fmt.Sprintf("\n### %s %s %s\n%s", unit.ID, unit.File, unit.Header, body)
+		if prompt.Len()+len(entry) > maxSplitPromptChars {
+			entry = fmt.Sprintf("\n### %s %s %s\n(content omitted)\n", unit.ID, unit.File, unit.Header)
+		}
+		prompt.WriteString(entry)
+	}
+
+	systemPrompt := `You split a mixed set of staged changes into separate, logical git commits. Group hunks that belong to the same concern (a feature, a fix, a refactor, formatting, docs); keep hunks that depend on each other in the same commit, and order commits so each one builds on the earlier ones. Use as few commits as make sense; one is fine when everything is one change. Assign every hunk ID to exactly one commit. Commit messages: ` + rule + instructions
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	raw, err := a.chatStructured(ctx, messages, commitSplitSchema, 0.2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan commits: %w", err)
+	}
+
+	var plan struct {
+		Commits []struct {
+			Message string   `json:"message"`
+			Hunks   []string `json:"hunks"`
+		} `json:"commits"`
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return nil, fmt.Errorf("failed to decode commit plan: %w", err)
+	}
+
+	byID := make(map[string]CommitHunk, len(units))
+	for _, unit := range units {
+		byID[unit.ID] = unit
+	}
+
+	split := &CommitSplit{}
+	assigned := make(map[string]bool)
+	for _, commit := range plan.Commits {
+		group := CommitGroup{Message: strings.Trim(strings.TrimSpace(commit.Message), "`")}
+		for _, id := range commit.Hunks {
+			unit, ok := byID[strings.TrimSpace(id)]
+			if !ok || assigned[unit.ID] {
+				continue
+			}
+			assigned[unit.ID] = true
+			group.Hunks = append(group.Hunks, unit)
+		}
+		if len(group.Hunks) > 0 {
+			split.Groups = append(split.Groups, group)
+		}
+	}
+	if len(split.Groups) == 0 {
+		return nil, fmt.Errorf("the model did not assign any hunks to commits")
+	}
+
+	// Hunks the model left out go with the last commit rather than being
+	// dropped from history
+	last := &split.Groups[len(split.Groups)-1]
+	for _, unit := range units {
+		if !assigned[unit.ID] {
+			last.Hunks = append(last.Hunks, unit)
+		}
+	}
+
+	return split, nil
+}
+
+// ApplyCommitSplit commits each group in order by unstaging everything and
+// staging the group's hunks with `git apply --cached`. The working tree is
+// never touched, so on failure the remaining changes are still there to be
+// committed by hand. opts applies to every commit. It returns the hashes of
+// the commits it created.
+func (a *Agent) ApplyCommitSplit(ctx context.Context, split *CommitSplit, opts CommitOptions) ([]string, error) {
+	opts.KeepIndex = true
+
+	if _, err := runGit(ctx, "reset", "--quiet"); err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	for i, group := range split.Groups {
+		if _, err := runGitInput(ctx, group.patch(), "apply", "--cached", "--whitespace=nowarn", "-"); err != nil {
+			return hashes, fmt.Errorf("staging commit %d (%s): %w", i+1, firstLine(group.Message), err)
+		}
+
+		hash, err := a.CreateCommit(ctx, group.Message, opts)
+		if err != nil {
+			return hashes, fmt.Errorf("creating commit %d (%s): %w", i+1, firstLine(group.Message), err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, nil
+}
+
+// patch rebuilds a unified diff containing only the group's hunks, in
+// their original order so each file's header appears once.
+func (g CommitGroup) patch() string {
+	units := append([]CommitHunk(nil), g.Hunks...)
+	sort.Slice(units, func(i, j int) bool { return units[i].order < units[j].order })
+
+	var out strings.Builder
+	var current *diffFile
+	for _, unit := range units {
+		if unit.file != current {
+			current = unit.file
+			out.WriteString(current.Header)
+		}
+		for _, hunk := range unit.hunks {
+			out.WriteString(hunk)
+		}
+	}
+	return out.String()
+}
+
+// parseUnifiedDiff splits `git diff` output into files and hunks, keeping
+// every line (with its newline) so the pieces can be reassembled into a
+// patch.
+func parseUnifiedDiff(diff string) []*diffFile {
+	var files []*diffFile
+	var current *diffFile
+	var header, hunk strings.Builder
+	inHunks := false
+
+	flushHunk := func() {
+		if current != nil && hunk.Len() > 0 {
+			current.Hunks = append(current.Hunks, hunk.String())
+			hunk.Reset()
+		}
+	}
+	flushFile := func() {
+		flushHunk()
+		if current != nil {
+			if !inHunks {
+				current.Header = header.String()
+			}
+			files = append(files, current)
+		}
+		header.Reset()
+		inHunks = false
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushFile()
+			current = &diffFile{Path: diffPath(line)}
+			header.WriteString(line)
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "@@"):
+			if !inHunks {
+				current.Header = header.String()
+				inHunks = true
+			}
+			flushHunk()
+			hunk.WriteString(line)
+		case inHunks:
+			hunk.WriteString(line)
+		default:
+			header.WriteString(line)
+			for _, marker := range []string{"new file mode", "deleted file mode", "rename from", "copy from", "old mode", "GIT binary patch", "Binary files"} {
+				if strings.HasPrefix(line, marker) {
+					current.Atomic = true
+				}
+			}
+		}
+	}
+	flushFile()
+
+	return files
+}
+
+// diffPath takes the new path from a "diff --git a/x b/x" line.
+func diffPath(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if i := strings.LastIndex(line, " b/"); i != -1 {
+		return line[i+3:]
+	}
+	return line
+}
+
+// commitHunks numbers the units that can be committed independently.
+func commitHunks(files []*diffFile) []CommitHunk {
+	var units []CommitHunk
+	add := func(file *diffFile, header string, hunks []string) {
+		units = append(units, CommitHunk{
+			ID:     fmt.Sprintf("H%d", len(units)+1),
+			order:  len(units),
+			File:   file.Path,
+			Header: header,
+			file:   file,
+			hunks:  hunks,
+		})
+	}
+
+	for _, file := range files {
+		if file.Atomic || len(file.Hunks) == 0 {
+			add(file, "(whole file)", file.Hunks)
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			header, _, _ := strings.Cut(hunk, "\n")
+			add(file, header, []string{hunk})
+		}
+	}
+	return units
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const splitDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++
+ import "fmt"
+@@ -10,2 +11,3 @@ func main() {
+ 	fmt.Println("a")
++	fmt.Println("b")
+ }
+diff --git a/new.go b/new.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+diff --git a/old name.go b/new name.go
+similarity index 100%
+rename from old name.go
+rename to new name.go
+diff --git a/logo.png b/logo.png
+index 4444444..5555555 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/script.sh b/script.sh
+old mode 100644
+new mode 100755
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files := parseUnifiedDiff(splitDiff)
+
+	type fileSummary struct {
+		Path        string
+		Atomic      bool
+		Hunks       int
+		HunkHeaders []string
+	}
+	var got []fileSummary
+	for _, f := range files {
+		summary := fileSummary{Path: f.Path, Atomic: f.Atomic, Hunks: len(f.Hunks)}
+		for _, hunk := range f.Hunks {
+			header, _, _ := strings.Cut(hunk, "\n")
+			summary.HunkHeaders = append(summary.HunkHeaders, header)
+		}
+		got = append(got, summary)
+	}
+
+	want := []fileSummary{
+		{Path: "main.go", Hunks: 2, HunkHeaders: []string{"@@ -1,3 +1,4 @@", "@@ -10,2 +11,3 @@ func main() {"}},
+		{Path: "new.go", Atomic: true, Hunks: 1, HunkHeaders: []string{"@@ -0,0 +1 @@"}},
+		{Path: "new name.go", Atomic: true},
+		{Path: "logo.png", Atomic: true},
+		{Path: "script.sh", Atomic: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseUnifiedDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if header := files[0].Header; !strings.HasPrefix(header, "diff --git a/main.go") || !strings.HasSuffix(header, "+++ b/main.go\n") {
+		t.Errorf("main.go header = %q", header)
+	}
+	if hunk := files[0].Hunks[1]; hunk != "@@ -10,2 +11,3 @@ func main() {\n \tfmt.Println(\"a\")\n+\tfmt.Println(\"b\")\n }\n" {
+		t.Errorf("second main.go hunk = %q", hunk)
+	}
+	if header := files[2].Header; !strings.HasSuffix(header, "rename to new name.go\n") {
+		t.Errorf("rename header = %q", header)
+	}
+}
+
+func TestParseUnifiedDiffEdgeCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		files int
+	}{
+		{name: "empty", diff: "", files: 0},
+		{name: "preamble before the first file is dropped", diff: "warning: CRLF\ndiff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -1 +1 @@\n-x\n+y\n", files: 1},
+		{name: "no trailing newline", diff: "diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -1 +1 @@\n-x\n+y", files: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUnifiedDiff(tt.diff); len(got) != tt.files {
+				t.Errorf("parseUnifiedDiff() found %d files, want %d", len(got), tt.files)
+			}
+		})
+	}
+}
+
+func TestCommitHunks(t *testing.T) {
+	units := commitHunks(parseUnifiedDiff(splitDiff))
+
+	var got []string
+	for _, unit := range units {
+		got = append(got, unit.ID+" "+unit.File+" "+unit.Header)
+	}
+	want := []string{
+		"H1 main.go @@ -1,3 +1,4 @@",
+		"H2 main.go @@ -10,2 +11,3 @@ func main() {",
+		"H3 new.go (whole file)",
+		"H4 new name.go (whole file)",
+		"H5 logo.png (whole file)",
+		"H6 script.sh (whole file)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("commitHunks() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Every unit, in any order, rebuilds the original diff
+	reversed := make([]CommitHunk, len(units))
+	for i, unit := range units {
+		reversed[len(units)-1-i] = unit
+	}
+	if patch := (CommitGroup{Hunks: reversed}).patch(); patch != splitDiff {
+		t.Errorf("patch of every hunk =\n%s\nwant the original diff", patch)
+	}
+
+	// One hunk of a file brings the file's header once
+	second := CommitGroup{Hunks: []CommitHunk{units[1]}}.patch()
+	if strings.Count(second, "diff --git") != 1 || !strings.Contains(second, "+\tfmt.Println(\"b\")") || strings.Contains(second, "@@ -1,3") {
+		t.Errorf("patch of H2 =\n%s", second)
+	}
+}
+
+func TestDiffPath(t *testing.T) {
+	tests := map[string]string{
+		"diff --git a/main.go b/main.go\n":             "main.go",
+		"diff --git a/old.go b/dir/new.go\n":           "dir/new.go",
+		"diff --git a/with space.go b/with space.go\n": "with space.go",
+	}
+	for line, want := range tests {
+		if got := diffPath(line); got != want {
+			t.Errorf("diffPath(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
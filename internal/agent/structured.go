@@ -0,0 +1,147 @@
+// Package: internal/agent/structured.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+const maxSchemaAttempts = 3
+
+// ProcessStructured answers input with a JSON document that validates against
+// the given schema, re-prompting the model with the validation errors when
+// its answer does not conform.
+func (a *Agent) ProcessStructured(ctx context.Context, input string, s schema.Schema) (json.RawMessage, error) {
+	systemPrompt, files, err := a.buildSystemPrompt(ctx, input, &sentFiles{})
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := schemaInstructions(s)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt += instructions
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: a.userMessage(input) + files},
+	}
+
+	return a.chatStructured(ctx, messages, s, a.config.Agent.Temperature)
+}
+
+// chatStructured runs messages until the model's answer validates against s,
+// feeding validation errors back for up to maxSchemaAttempts attempts.
+func (a *Agent) chatStructured(ctx context.Context, messages []llm.Message, s schema.Schema, temperature float64) (json.RawMessage, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxSchemaAttempts; attempt++ {
+		req := llm.ChatRequest{
+			Model:       a.config.LMStudio.Model,
+			Messages:    messages,
+			MaxTokens:   a.config.Agent.MaxTokens,
+			Temperature: temperature,
+		}
+
+		resp, err := a.llmClient.Chat(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("LLM request failed: %w", err)
+		}
+
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("no response from LLM")
+		}
+
+		content := resp.Choices[0].Message.Content
+		result, feedback := parseStructured(content, s)
+		if feedback == "" {
+			return result, nil
+		}
+
+		lastErr = fmt.Errorf("%s", feedback)
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: content},
+			llm.Message{Role: "user", Content: fmt.Sprintf("Your answer was invalid: %s\nRespond again with only a corrected JSON document.", feedback)},
+		)
+	}
+
+	return nil, fmt.Errorf("no valid response after %d attempts: %w", maxSchemaAttempts, lastErr)
+}
+
+func schemaInstructions(s schema.Schema) (string, error) {
+	schemaJSON, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	return fmt.Sprintf(`
+
+## Output Format
+
+Respond with a single JSON document that validates against this JSON Schema:
+%s
+
+Output only the JSON document, with no explanation or markdown.`, string(schemaJSON)), nil
+}
+
+// parseStructured extracts the JSON document from a model answer and
+// validates it, returning a description of the problem on failure.
+func parseStructured(content string, s schema.Schema) (json.RawMessage, string) {
+	raw := extractJSON(content)
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, fmt.Sprintf("response is not valid JSON (%v)", err)
+	}
+
+	if errs := schema.Validate(s, value); len(errs) > 0 {
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return nil, "schema validation failed:\n- " + strings.Join(msgs, "\n- ")
+	}
+
+	compact, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode result (%v)", err)
+	}
+
+	return compact, ""
+}
+
+// extractJSON strips markdown fences and surrounding prose from a response.
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+
+	if start := strings.Index(content, "```"); start != -1 {
+		rest := content[start+3:]
+		if nl := strings.Index(rest, "\n"); nl != -1 {
+			rest = rest[nl+1:]
+		}
+		if end := strings.Index(rest, "```"); end != -1 {
+			return strings.TrimSpace(rest[:end])
+		}
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start == -1 {
+		return content
+	}
+
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+
+	if end := strings.LastIndex(content, closing); end > start {
+		return content[start : end+1]
+	}
+
+	return content[start:]
+}
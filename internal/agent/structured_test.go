@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+func TestParseStructured(t *testing.T) {
+	s := schema.Schema{
+		"type":       "object",
+		"required":   []interface{}{"status"},
+		"properties": map[string]interface{}{"status": map[string]interface{}{"enum": []interface{}{"ok", "failed"}}},
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		want         string
+		wantFeedback string
+	}{
+		{name: "bare document", content: `{"status": "ok"}`, want: `{"status":"ok"}`},
+		{name: "in a fence", content: "Here it is:\n```json\n{\"status\": \"failed\"}\n```\n", want: `{"status":"failed"}`},
+		{name: "surrounded by prose", content: `The result is {"status": "ok"} as requested.`, want: `{"status":"ok"}`},
+		{name: "not JSON", content: "I could not run the tests.", wantFeedback: "response is not valid JSON"},
+		{name: "truncated", content: `{"status": "ok"`, wantFeedback: "response is not valid JSON"},
+		{name: "missing property", content: `{}`, wantFeedback: `schema validation failed:` + "\n" + `- $: missing required property "status"`},
+		{name: "value outside enum", content: `{"status": "pending"}`, wantFeedback: "is not one of"},
+		{name: "wrong type", content: `["ok"]`, wantFeedback: "$: expected object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, feedback := parseStructured(tt.content, s)
+			if tt.wantFeedback != "" {
+				if got != nil || !strings.Contains(feedback, tt.wantFeedback) {
+					t.Fatalf("parseStructured() = %s, %q, want feedback %q", got, feedback, tt.wantFeedback)
+				}
+				return
+			}
+			if feedback != "" || string(got) != tt.want {
+				t.Errorf("parseStructured() = %s, %q, want %s", got, feedback, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Package: internal/agent/style.go
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var outputStyles = map[string]string{
+	"default":     "",
+	"concise":     `Be terse. Lead with the answer or the code change, prefer diffs over full files, and skip background explanations unless asked. Use short bullet points instead of paragraphs.`,
+	"explanatory": `Explain your reasoning. Describe what the relevant code does, why you chose a particular approach, and which trade-offs or alternatives exist before showing the change.`,
+	"teaching":    `Act as a patient mentor for someone learning this codebase. Introduce the concepts involved, walk through the code step by step, point out idioms and patterns used in the project, and end with a short suggestion of what to explore next.`,
+}
+
+// OutputStyles returns the names of the available response styles.
+func OutputStyles() []string {
+	names := make([]string, 0, len(outputStyles))
+	for name := range outputStyles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Agent) OutputStyle() string {
+	if a.config.Agent.OutputStyle == "" {
+		return "default"
+	}
+	return a.config.Agent.OutputStyle
+}
+
+// SetOutputStyle switches the response style for the rest of the session.
+func (a *Agent) SetOutputStyle(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := outputStyles[name]; !ok {
+		return fmt.Errorf("unknown output style %q (available: %s)", name, strings.Join(OutputStyles(), ", "))
+	}
+
+	a.config.Agent.OutputStyle = name
+	return nil
+}
+
+// styleSection renders the response style instructions for the system prompt.
+func styleSection(name string) string {
+	instructions := outputStyles[strings.ToLower(name)]
+	if instructions == "" {
+		return ""
+	}
+	return "\n\n## Response Style\n\n" + instructions
+}
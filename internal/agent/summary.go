@@ -0,0 +1,37 @@
+// Package: internal/agent/summary.go
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const summaryPrompt = `Summarize this software project in one page for a coding assistant that will work on it: what it is and does, its main components and where they live, its languages, frameworks and key dependencies, and how it is built and tested, where that is apparent. Use short paragraphs or bullets and at most 300 words. Reply with the summary only.`
+
+// projectSummarizer writes the project summary with the configured model.
+func projectSummarizer(client *llm.Client, cfg *config.Config) projectctx.Summarizer {
+	return func(ctx context.Context, material string) (string, error) {
+		req := llm.ChatRequest{
+			Model: cfg.LMStudio.Model,
+			Messages: []llm.Message{
+				{Role: "system", Content: summaryPrompt},
+				{Role: "user", Content: material},
+			},
+			MaxTokens:   600,
+			Temperature: 0.2,
+		}
+
+		resp, err := client.Chat(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("no summary generated")
+		}
+		return resp.Choices[0].Message.Content, nil
+	}
+}
@@ -0,0 +1,155 @@
+// Package: internal/agent/symbols.go
+package agent
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+const maxDefinitionResults = 20
+
+// mentionedSymbols resolves the functions, methods and types query names
+// in the symbol index.
+func (a *Agent) mentionedSymbols(query string) []projectctx.SymbolMention {
+	if query == "" {
+		return nil
+	}
+	symbols, err := a.context.MentionedSymbols(query)
+	if err != nil {
+		log.Printf("Warning: failed to look up the symbols the request names: %v", err)
+	}
+	return symbols
+}
+
+// symbolsSection maps the symbols a request names to their declarations
+// and call sites, whose files are put first in the project context.
+func symbolsSection(symbols []projectctx.SymbolMention) string {
+	if len(symbols) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString("\n\n## Mentioned Symbols\n")
+	for _, symbol := range symbols {
+		section.WriteString(fmt.Sprintf("\n%s is declared at:\n", symbol.Name))
+		for _, def := range symbol.Definitions {
+			section.WriteString("  " + def.String() + "\n")
+		}
+		if len(symbol.CallSites) > 0 {
+			section.WriteString("and used at:\n")
+			for _, site := range symbol.CallSites {
+				section.WriteString("  " + site.String() + "\n")
+			}
+		}
+	}
+	return section.String()
+}
+
+// registerSymbolTools adds find_definition and find_references, backed by
+// the symbol index of whichever context manager index returns.
+func registerSymbolTools(registry *tools.Registry, index func() (*projectctx.SymbolIndex, error)) {
+	registry.Register(&definitionTool{index: index})
+	registry.Register(&referencesTool{index: index})
+}
+
+func symbolParameters(description string) interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"symbol": map[string]interface{}{
+				"type":        "string",
+				"description": description,
+			},
+		},
+		"required": []string{"symbol"},
+	}
+}
+
+// definitionTool looks up where a symbol is declared.
+type definitionTool struct {
+	index func() (*projectctx.SymbolIndex, error)
+}
+
+func (t *definitionTool) Name() string { return "find_definition" }
+
+func (t *definitionTool) Description() string {
+	return "Find where a function, method, type or class is declared in the project, with its signature. Faster and more precise than searching the code"
+}
+
+func (t *definitionTool) Parameters() interface{} {
+	return symbolParameters("The symbol name, or Type.method to pick a method of one type")
+}
+
+func (t *definitionTool) Execute(args map[string]interface{}) (string, error) {
+	name, _ := args["symbol"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("symbol is required")
+	}
+
+	idx, err := t.index()
+	if err != nil {
+		return "", err
+	}
+
+	defs := idx.Definitions(name)
+	if len(defs) == 0 {
+		return fmt.Sprintf("No definition of %s found in the symbol index. It may be defined in a dependency; try code_search.", name), nil
+	}
+
+	var out strings.Builder
+	for i, def := range defs {
+		if i == maxDefinitionResults {
+			out.WriteString(fmt.Sprintf("... and %d more\n", len(defs)-i))
+			break
+		}
+		out.WriteString(def.String() + "\n")
+	}
+	return out.String(), nil
+}
+
+// referencesTool lists the lines that use a symbol.
+type referencesTool struct {
+	index func() (*projectctx.SymbolIndex, error)
+}
+
+func (t *referencesTool) Name() string { return "find_references" }
+
+func (t *referencesTool) Description() string {
+	return "List the lines in the project that mention a symbol as a whole word, excluding its declarations"
+}
+
+func (t *referencesTool) Parameters() interface{} {
+	return symbolParameters("The symbol name")
+}
+
+func (t *referencesTool) Execute(args map[string]interface{}) (string, error) {
+	name, _ := args["symbol"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("symbol is required")
+	}
+
+	idx, err := t.index()
+	if err != nil {
+		return "", err
+	}
+
+	refs, err := idx.References(name)
+	if err != nil {
+		return "", err
+	}
+	if len(refs) == 0 {
+		return fmt.Sprintf("No references to %s found.", name), nil
+	}
+
+	var out strings.Builder
+	for _, ref := range refs {
+		out.WriteString(ref.String() + "\n")
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,270 @@
+// Package: internal/agent/testgen.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	maxTestGenAttempts    = 3
+	maxTestGenSourceBytes = 48 * 1024
+	maxTestStyleExamples  = 2
+)
+
+const testGenSystemText = `You write Go unit tests. Prefer table-driven tests with t.Run subtests, use only the standard library unless the existing tests use something else, and follow the style of the example tests you are shown. Test exported and unexported behaviour through the package's own API; do not modify the code under test.
+
+Reply with ONLY the complete contents of the test file in a single ` + "```go" + ` block.`
+
+// TestGenResult describes the outcome of GenerateTests.
+type TestGenResult struct {
+	Path     string
+	Command  string
+	Output   string
+	Passed   bool
+	Attempts int
+}
+
+// GenerateTests writes table-driven tests for a Go file or package directory
+// and runs them, feeding compiler and test failures back to the model until
+// they pass or the attempts run out.
+func (a *Agent) GenerateTests(ctx context.Context, target string, onEvent func(Event)) (*TestGenResult, error) {
+	emit := func(e Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+	}
+
+	sources, dir, err := testGenSources(target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TestGenResult{
+		Path:    testGenPath(target, dir),
+		Command: "go test -count=1 ./" + filepath.ToSlash(dir),
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Write tests into %s.\n\n## Code Under Test\n", result.Path))
+	for _, path := range sortedKeys(sources) {
+		prompt.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", path, sources[path]))
+	}
+
+	examples := testStyleExamples(dir)
+	if len(examples) > 0 {
+		prompt.WriteString("\n## Existing Tests (match their style)\n")
+		for _, path := range sortedKeys(examples) {
+			prompt.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", path, examples[path]))
+		}
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: testGenSystemText},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	for attempt := 1; attempt <= maxTestGenAttempts; attempt++ {
+		result.Attempts = attempt
+
+		req := llm.ChatRequest{
+			Model:       a.config.LMStudio.Model,
+			Messages:    messages,
+			MaxTokens:   a.config.Agent.MaxTokens,
+			Temperature: 0.2,
+		}
+
+		resp, err := a.llmClient.Chat(ctx, req)
+		if err != nil {
+			return result, fmt.Errorf("LLM request failed: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return result, fmt.Errorf("no response from LLM")
+		}
+
+		reply := resp.Choices[0].Message.Content
+		code := extractCodeBlock(reply)
+		if code == "" {
+			return result, fmt.Errorf("model did not return a test file")
+		}
+
+		emit(Event{Type: "tool_call", Tool: "file_operations", Content: "write " + result.Path})
+		if _, err := a.tools.Execute("file_operations", map[string]interface{}{
+			"operation": "write",
+			"path":      result.Path,
+			"content":   code,
+		}); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", result.Path, err)
+		}
+
+		emit(Event{Type: "tool_call", Tool: "shell_execute", Content: result.Command})
+		output, testErr := a.tools.RunCommand(ctx, result.Command, "", nil)
+		result.Output = output
+		emit(Event{Type: "tool_result", Tool: "shell_execute", Content: result.Output})
+
+		if testErr == nil {
+			result.Passed = true
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		failure := result.Output
+		if len(failure) > 6000 {
+			failure = failure[len(failure)-6000:]
+		}
+
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: reply},
+			llm.Message{Role: "user", Content: fmt.Sprintf("`%s` failed:\n```\n%s\n```\nFix the tests (not the code under test) and reply with the complete corrected file.", result.Command, failure)},
+		)
+	}
+
+	return result, nil
+}
+
+// testGenSources reads the Go sources of target, which is either a file or a
+// package directory, and returns them with the package directory.
+func testGenSources(target string) (map[string]string, string, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var paths []string
+	dir := filepath.Clean(target)
+	if info.IsDir() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				paths = append(paths, filepath.Join(dir, name))
+			}
+		}
+	} else {
+		if filepath.Ext(target) != ".go" || strings.HasSuffix(target, "_test.go") {
+			return nil, "", fmt.Errorf("%s is not a Go source file; testgen supports Go files and packages", target)
+		}
+		dir = filepath.Dir(dir)
+		paths = []string{filepath.Clean(target)}
+	}
+
+	if len(paths) == 0 {
+		return nil, "", fmt.Errorf("no Go source files in %s", target)
+	}
+
+	sources := make(map[string]string)
+	total := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		if total+len(data) > maxTestGenSourceBytes {
+			break
+		}
+		total += len(data)
+		sources[path] = string(data)
+	}
+
+	return sources, dir, nil
+}
+
+// testGenPath picks a test file name that does not overwrite existing tests.
+func testGenPath(target, dir string) string {
+	base := filepath.Base(dir)
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		base = strings.TrimSuffix(filepath.Base(target), ".go")
+	}
+	if base == "." || base == string(filepath.Separator) {
+		base = "main"
+	}
+
+	path := filepath.Join(dir, base+"_test.go")
+	if _, err := os.Stat(path); err == nil {
+		path = filepath.Join(dir, base+"_gen_test.go")
+	}
+	return path
+}
+
+// testStyleExamples returns existing tests to imitate, preferring those in
+// dir and falling back to elsewhere in the project.
+func testStyleExamples(dir string) map[string]string {
+	examples := make(map[string]string)
+
+	add := func(path string) bool {
+		data, err := os.ReadFile(path)
+		if err == nil && len(data) < maxTestGenSourceBytes/4 {
+			examples[path] = string(data)
+		}
+		return len(examples) >= maxTestStyleExamples
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*_test.go")); len(matches) > 0 {
+		for _, path := range matches {
+			if add(path) {
+				return examples
+			}
+		}
+		return examples
+	}
+
+	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, "_test.go") && add(path) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+
+	return examples
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// extractCodeBlock returns the contents of the first fenced code block, or
+// the whole reply when it has no fence.
+func extractCodeBlock(content string) string {
+	content = strings.TrimSpace(content)
+
+	start := strings.Index(content, "```")
+	if start == -1 {
+		return content
+	}
+
+	rest := content[start+3:]
+	if nl := strings.Index(rest, "\n"); nl != -1 {
+		rest = rest[nl+1:]
+	}
+	if end := strings.Index(rest, "```"); end != -1 {
+		rest = rest[:end]
+	}
+
+	return strings.TrimSpace(rest) + "\n"
+}
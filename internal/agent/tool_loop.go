@@ -0,0 +1,179 @@
+// Package: internal/agent/tool_loop.go
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/audit"
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const defaultMaxToolIterations = 10
+
+// Event reports progress while the agent works on a request.
+type Event struct {
+	Type    string // "reasoning", "tool_call", "tool_output", "tool_result" or "response"
+	Tool    string
+	Content string
+}
+
+// runConversation sends messages to the model, executing any requested tool
+// calls and feeding their results back until the model gives a final answer.
+func (a *Agent) runConversation(ctx context.Context, messages []llm.Message, onEvent func(Event)) (string, error) {
+	emit := func(e Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+	}
+
+	var executed []audit.Entry
+
+	maxIterations := a.config.Agent.MaxToolIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxToolIterations
+	}
+
+	keep := a.config.Agent.KeepToolResults
+	if keep <= 0 {
+		keep = defaultKeepToolResults
+	}
+	refs := make(map[string]toolRef)
+	historyTokens := planBudget(a.config.Agent).History
+
+	run := &runTrace{task: lastUserMessage(messages)}
+	fail := func(err error) (string, error) {
+		return "", a.failRun(ctx, run, err)
+	}
+
+	for i := 0; i < maxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+
+		req := llm.ChatRequest{
+			Model:       a.config.LMStudio.Model,
+			Messages:    elideToolResults(messages, refs, keep, historyTokens),
+			Tools:       a.modelTools(),
+			MaxTokens:   a.config.Agent.MaxTokens,
+			Temperature: a.config.Agent.Temperature,
+		}
+
+		resp, err := a.llmClient.Chat(ctx, req)
+		if err != nil {
+			return fail(fmt.Errorf("LLM request failed: %w", err))
+		}
+
+		if len(resp.Choices) == 0 {
+			return fail(fmt.Errorf("no response from LLM"))
+		}
+
+		msg := resp.Choices[0].Message
+		if msg.Reasoning != "" {
+			emit(Event{Type: "reasoning", Content: msg.Reasoning})
+		}
+
+		if len(msg.ToolCalls) == 0 {
+			answer := a.attachFootnotes(msg.Content, executed)
+			emit(Event{Type: "response", Content: answer})
+			return answer, nil
+		}
+
+		messages = append(messages, msg)
+		for _, call := range msg.ToolCalls {
+			emit(Event{Type: "tool_call", Tool: call.Function.Name, Content: call.Function.Arguments})
+
+			output := eventWriter{emit: emit, tool: call.Function.Name}
+			result, entry, execErr := a.executeToolCall(ctx, call, output)
+			emit(Event{Type: "tool_result", Tool: call.Function.Name, Content: result})
+			run.record(call, result, entry, execErr)
+
+			storeID := 0
+			if entry != nil {
+				executed = append(executed, *entry)
+				storeID = entry.ID
+			}
+			storeID = a.toolResults.put(storeID, result)
+			refs[call.ID] = toolRef{id: storeID, tool: call.Function.Name, args: call.Function.Arguments}
+
+			if entry != nil {
+				result = fmt.Sprintf("[ref %d]\n%s", entry.ID, result)
+			}
+
+			messages = append(messages, llm.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    result,
+			})
+		}
+	}
+
+	run.budgetExceeded = true
+	return fail(fmt.Errorf("no final answer after %d tool iterations", maxIterations))
+}
+
+// executeToolCall runs a requested tool once the edit policy allows the
+// commands it would run and the edits it makes and, when an audit log is
+// attached, records the execution so the answer can cite it.
+func (a *Agent) executeToolCall(ctx context.Context, call llm.ToolCall, stream io.Writer) (string, *audit.Entry, error) {
+	if call.Function.Name == askAgentToolName {
+		err := fmt.Errorf("the agent cannot delegate to itself")
+		return fmt.Sprintf("Error: %v", err), nil, err
+	}
+
+	var args map[string]interface{}
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return fmt.Sprintf("Error: invalid tool arguments: %v", err), nil, err
+		}
+	}
+
+	if err := a.authorize(ctx, call, args); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil, err
+	}
+
+	start := time.Now()
+	output, err := a.tools.ExecuteContext(ctx, call.Function.Name, args, stream)
+	duration := time.Since(start)
+
+	result := output
+	if err != nil {
+		result = fmt.Sprintf("Error: %v", err)
+		if output != "" {
+			result = fmt.Sprintf("%s\nError: %v", output, err)
+		}
+	}
+
+	if a.audit == nil {
+		return result, nil, err
+	}
+
+	entry, auditErr := a.audit.Record(call.Function.Name, call.Function.Arguments, output, err, duration)
+	if auditErr != nil {
+		return result, nil, err
+	}
+	return result, &entry, err
+}
+
+// eventWriter forwards output of long-running tools as "tool_output" events.
+type eventWriter struct {
+	emit func(Event)
+	tool string
+}
+
+func (w eventWriter) Write(p []byte) (int, error) {
+	w.emit(Event{Type: "tool_output", Tool: w.tool, Content: string(p)})
+	return len(p), nil
+}
+
+func lastUserMessage(messages []llm.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
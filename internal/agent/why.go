@@ -0,0 +1,139 @@
+// Package: internal/agent/why.go
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/llm"
+)
+
+const (
+	maxWhyDiffChars = 30000
+	whySystemText   = `You are a senior engineer explaining a codebase's history. Given a line of code, the commit that introduced it and that commit's diff, explain why the code exists: the problem the commit solved and the role this line plays in the solution. Then list what could break if the line were changed or removed. Say so when the commit does not reveal the intent instead of guessing.
+
+Respond in this format:
+## Why it exists
+A short explanation.
+
+## What might break
+A bulleted list.`
+)
+
+// BlameInfo describes the commit that last changed a line.
+type BlameInfo struct {
+	Commit  string
+	Author  string
+	Date    string
+	Summary string
+}
+
+// ExplainLine blames a line of a file, pulls the commit that introduced it
+// with its diff, and asks the model why the code is there and what depends
+// on it.
+func (a *Agent) ExplainLine(ctx context.Context, path string, line int) (*BlameInfo, string, error) {
+	snippet, err := sourceSnippet(path, line, snippetRadius)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// -w -M -C look past whitespace changes and moved or copied lines to
+	// the commit that actually wrote the code
+	porcelain, err := runGit(ctx, "blame", "-w", "-M", "-C", "--porcelain", "-L", fmt.Sprintf("%d,%d", line, line), "--", path)
+	if err != nil {
+		return nil, "", err
+	}
+	blame := parseBlamePorcelain(porcelain)
+	if blame.Commit == "" {
+		return nil, "", fmt.Errorf("could not blame %s:%d", path, line)
+	}
+	if strings.Trim(blame.Commit, "0") == "" {
+		return nil, "", fmt.Errorf("%s:%d has not been committed yet", path, line)
+	}
+
+	commit, err := runGit(ctx, "show", "--no-color", "--stat", "--format=commit %H%nAuthor: %an%nDate: %as%n%n%B", blame.Commit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The diff of the blamed file first, then the rest of the commit as
+	// space allows
+	diff, err := runGit(ctx, "show", "--no-color", "--format=", "-M", blame.Commit, "--", path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(diff) < maxWhyDiffChars {
+		if rest, err := runGit(ctx, "show", "--no-color", "--format=", "-M", blame.Commit, "--", ".", ":(exclude)"+path); err == nil {
+			diff += rest
+		}
+	}
+	truncated := false
+	if len(diff) > maxWhyDiffChars {
+		diff = diff[:maxWhyDiffChars]
+		truncated = true
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("## Line in question\n%s:%d\n```\n%s\n```\n", path, line, snippet))
+	prompt.WriteString(fmt.Sprintf("\n## Originating commit\n```\n%s\n```\n", strings.TrimSpace(commit)))
+	prompt.WriteString(fmt.Sprintf("\n## Commit diff\n```diff\n%s\n```\n", diff))
+	if truncated {
+		prompt.WriteString("\nThe diff was truncated.\n")
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: whySystemText},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	req := llm.ChatRequest{
+		Model:       a.config.LMStudio.Model,
+		Messages:    messages,
+		MaxTokens:   a.config.Agent.MaxTokens,
+		Temperature: 0.2,
+	}
+
+	resp, err := a.llmClient.Chat(ctx, req)
+	if err != nil {
+		return blame, "", fmt.Errorf("LLM request failed: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return blame, "", fmt.Errorf("no explanation generated")
+	}
+
+	return blame, resp.Choices[0].Message.Content, nil
+}
+
+// parseBlamePorcelain reads the header of `git blame --porcelain` output
+// for a single line.
+func parseBlamePorcelain(output string) *BlameInfo {
+	info := &BlameInfo{}
+	for i, line := range strings.Split(output, "\n") {
+		if i == 0 {
+			if fields := strings.Fields(line); len(fields) > 0 {
+				info.Commit = fields[0]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			break
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			info.Author = value
+		case "author-time":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.Date = time.Unix(seconds, 0).Format("2006-01-02")
+			}
+		case "summary":
+			info.Summary = value
+		}
+	}
+	return info
+}
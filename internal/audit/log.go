@@ -0,0 +1,134 @@
+// Package: internal/audit/log.go
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const maxStoredOutput = 64 * 1024
+
+// Entry is one tool execution as recorded in the audit log.
+type Entry struct {
+	ID        int           `json:"id"`
+	Time      time.Time     `json:"time"`
+	Tool      string        `json:"tool"`
+	Arguments string        `json:"arguments"`
+	Output    string        `json:"output"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Log is an append-only JSONL record of every tool the agent executed in a
+// session, so claims in answers can be checked against real output.
+type Log struct {
+	path    string
+	mu      sync.Mutex
+	nextID  int
+	size    int64         // Bytes written to the file so far
+	offsets map[int]int64 // Where each entry starts in the file, by ID
+}
+
+// Open creates a new audit log for this session under ~/.claude-go/audit.
+func Open() (*Log, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir = filepath.Join(dir, "audit")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%d.jsonl", time.Now().Format("20060102-150405"), os.Getpid())
+	return &Log{
+		path:    filepath.Join(dir, name),
+		nextID:  1,
+		offsets: make(map[int]int64),
+	}, nil
+}
+
+func (l *Log) Path() string { return l.path }
+
+func (l *Log) Record(tool, arguments, output string, execErr error, duration time.Duration) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(output) > maxStoredOutput {
+		output = output[:maxStoredOutput] + "\n... (truncated)"
+	}
+
+	entry := Entry{
+		ID:        l.nextID,
+		Time:      time.Now(),
+		Tool:      tool,
+		Arguments: arguments,
+		Output:    output,
+		Duration:  duration,
+	}
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+
+	// Tool outputs can be large, so entries are read back from the file
+	// when asked for rather than kept in memory
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return entry, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	offset := l.size
+	n, err := f.Write(append(data, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		return entry, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.offsets[entry.ID] = offset
+	l.nextID++
+	return entry, nil
+}
+
+// Get reads back the entry recorded with id.
+func (l *Log) Get(id int) (Entry, bool) {
+	l.mu.Lock()
+	offset, ok := l.offsets[id]
+	l.mu.Unlock()
+	if !ok {
+		return Entry{}, false
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return Entry{}, false
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.NewDecoder(f).Decode(&entry); err != nil || entry.ID != id {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Link returns the reference printed in footnotes for an entry.
+func (l *Log) Link(id int) string {
+	return fmt.Sprintf("%s#%d", l.path, id)
+}
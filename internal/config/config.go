@@ -11,32 +11,251 @@ type Config struct {
 	LMStudio LMStudioConfig `json:"lm_studio"`
 	Agent    AgentConfig    `json:"agent"`
 	Git      GitConfig      `json:"git"`
+	Context  ContextConfig  `json:"context"`
+
+	Permissions PermissionsConfig `json:"permissions"`
+	Remote      RemoteConfig      `json:"remote"`
+
+	// MCPServers are the MCP servers, by name, whose tools every session
+	// can use; a project's own mcp_servers add to them and replace those of
+	// the same name.
+	MCPServers map[string]MCPServerConfig `json:"mcp_servers,omitempty"`
+
+	// MCPDebugLog, when set, is a file every JSON-RPC message exchanged
+	// with the MCP servers is logged to, for `claude-go mcp inspect`.
+	MCPDebugLog string `json:"mcp_debug_log,omitempty"`
 }
 
 type LMStudioConfig struct {
 	BaseURL string `json:"base_url"`
 	Model   string `json:"model"`
 	Timeout int    `json:"timeout"`
+
+	// Provider is "lmstudio" (default) or "mock". The mock answers from
+	// MockScript, or replays Cassette when no script is set.
+	Provider   string `json:"provider,omitempty"`
+	MockScript string `json:"mock_script,omitempty"`
+
+	// Cassette records every exchange with the real server to a file when
+	// set with the lmstudio provider, and is replayed by the mock provider.
+	Cassette string `json:"cassette,omitempty"`
 }
 
 type AgentConfig struct {
 	MaxTokens    int     `json:"max_tokens"`
 	Temperature  float64 `json:"temperature"`
 	SystemPrompt string  `json:"system_prompt"`
+	OutputStyle  string  `json:"output_style"`
+
+	// MaxToolIterations bounds how many tool-calling rounds an autonomous
+	// run may take before it is aborted with a post-mortem.
+	MaxToolIterations int `json:"max_tool_iterations"`
+
+	// KeepToolResults is how many of the most recent tool results are
+	// re-sent verbatim; older ones are replaced by one-line summaries the
+	// model can expand with recall_tool_result.
+	KeepToolResults int `json:"keep_tool_results"`
+
+	// ContextWindow is the model's context length in tokens (default
+	// 16384). What MaxTokens leaves of it for the prompt is split between
+	// its sections by Budget.
+	ContextWindow int          `json:"context_window,omitempty"`
+	Budget        BudgetConfig `json:"budget,omitempty"`
+
+	// Thinking controls reasoning models: "auto", "on" or "off".
+	// ThinkingBudget caps reasoning tokens when thinking is "on", and
+	// ShowThinking prints the reasoning instead of a one-line summary.
+	Thinking       string `json:"thinking"`
+	ThinkingBudget int    `json:"thinking_budget"`
+	ShowThinking   bool   `json:"show_thinking"`
+}
+
+// BudgetConfig gives each section of the prompt its share of the context
+// window. The ratios are relative to each other and need not add up to 1;
+// leaving them all at 0 uses DefaultBudget.
+type BudgetConfig struct {
+	System    float64 `json:"system"`    // Instructions, project memory and notes
+	Structure float64 `json:"structure"` // The project tree
+	Files     float64 `json:"files"`     // File contents and retrieved code
+	Git       float64 `json:"git"`       // Branch and working tree status
+	History   float64 `json:"history"`   // Earlier messages and tool results
 }
 
 type GitConfig struct {
 	AutoStage bool `json:"auto_stage"`
 	SignOff   bool `json:"sign_off"`
+
+	// GPGSign signs commits, with SigningKey if set or git's user.signingkey
+	// otherwise. When false, git's own commit.gpgsign setting still applies.
+	GPGSign    bool   `json:"gpg_sign,omitempty"`
+	SigningKey string `json:"signing_key,omitempty"`
+
+	// PreCommitReview checks the staged diff before every commit and blocks
+	// it on findings: "off" (default), "scan" for secrets, debug
+	// statements, TODO markers and conflict markers, or "full" to also have
+	// the model look for obviously broken code.
+	PreCommitReview string `json:"pre_commit_review,omitempty"`
+
+	// CommitStyle is "conventional" (default), "gitmoji", "plain" or
+	// "template", which follows CommitTemplate. Its placeholders {type},
+	// {scope}, {emoji}, {summary} and {body} are filled in by the model.
+	CommitStyle    string `json:"commit_style,omitempty"`
+	CommitTemplate string `json:"commit_template,omitempty"`
+
+	// Scopes maps path globs to commit scopes ("internal/llm/**": "llm").
+	// Paths matching no rule get a scope from their top-level directory.
+	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// BranchPattern shapes suggested branch names, default
+	// "{type}/{ticket}-{slug}". TicketPattern finds the ticket ID in the
+	// task description (default: JIRA-style "ABC-123"), and BranchCase is
+	// "kebab" (default) or "snake" for the slug.
+	BranchPattern string `json:"branch_pattern,omitempty"`
+	TicketPattern string `json:"ticket_pattern,omitempty"`
+	BranchCase    string `json:"branch_case,omitempty"`
 }
 
-func Load() (*Config, error) {
+type ContextConfig struct {
+	// ExcludeCategories maps a content category (test_fixtures, snapshots,
+	// lockfiles, generated_code, data_files) to its exclusion rule.
+	ExcludeCategories map[string]ExclusionRule `json:"exclude_categories"`
+
+	// Include and Exclude are .gitignore-style globs ("**" for any number
+	// of directories, a trailing "/" for directories). When Include is set,
+	// only matching files are read; Exclude always wins, like a
+	// .claudeignore at the project root.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// EmbeddingModel turns on semantic retrieval: project files are split
+	// into chunks, embedded with this model through the server's embeddings
+	// endpoint, and the RetrievalTopK chunks (default 8) closest to each
+	// request are put in the prompt instead of a fixed selection of files.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	RetrievalTopK  int    `json:"retrieval_top_k,omitempty"`
+
+	// ProjectSummary has the model write a one-page summary of the project
+	// once, cached until its layout, dependencies or README change, and
+	// sends that instead of the file tree with every request.
+	ProjectSummary bool `json:"project_summary,omitempty"`
+
+	// RefreshTTL is how many seconds a watched project tree is trusted
+	// before it is walked again in case the file watcher missed changes;
+	// 0 means the default (300) and a negative value never rewalks.
+	RefreshTTL int `json:"refresh_ttl,omitempty"`
+
+	// MaxFiles and MaxProjectMB bound the project walk (defaults 20000
+	// files and 2048 MB, negative for no limit). Larger projects are
+	// sampled breadth-first; Include keeps the context to the part that
+	// matters instead.
+	MaxFiles     int `json:"max_files,omitempty"`
+	MaxProjectMB int `json:"max_project_mb,omitempty"`
+
+	// Symlinks decides what the project walk does with symbolic links and
+	// Windows junctions: "files" (the default) reads links to files and
+	// leaves linked directories out, "follow" walks linked directories
+	// too, once each and never back into the project, and "skip" leaves
+	// all links out.
+	Symlinks string `json:"symlinks,omitempty"`
+
+	// AdditionalDirs are read into the context next to the project, such
+	// as shared libraries or sibling services; relative paths are taken
+	// from the working directory and "~/" from the home directory.
+	AdditionalDirs []string `json:"additional_dirs,omitempty"`
+
+	// Pinned files are in every prompt in full, ahead of the files picked
+	// by recency or relevance: API schemas, style guides, core types.
+	// Relative paths are taken from the working directory.
+	Pinned []string `json:"pinned,omitempty"`
+
+	// LanguageServers are started on demand to put the project's current
+	// compile errors and warnings in the context, e.g. gopls for .go files.
+	LanguageServers []LanguageServerConfig `json:"language_servers,omitempty"`
+}
+
+type LanguageServerConfig struct {
+	Command    []string `json:"command"`               // e.g. ["gopls"] or ["typescript-language-server", "--stdio"]
+	Extensions []string `json:"extensions"`            // Files opened in the server, e.g. [".go"]
+	LanguageID string   `json:"language_id,omitempty"` // Defaults to the extension without its dot
+}
+
+type ExclusionRule struct {
+	Enabled bool     `json:"enabled"`
+	Keep    []string `json:"keep,omitempty"`   // Globs that stay included despite matching
+	MaxKB   int      `json:"max_kb,omitempty"` // Size threshold for data_files
+}
+
+type PermissionsConfig struct {
+	// AutoAccept selects which file edits run without approval: "all",
+	// "tests" (only files matching TestPatterns; the default) or "none".
+	// Commands count as edits to production code.
+	AutoAccept string `json:"auto_accept"`
+
+	// TestPatterns classify paths as test code; everything else is
+	// production code. "**" matches any number of directories.
+	TestPatterns []string `json:"test_patterns"`
+}
+
+// RemoteConfig runs shell, build and test commands on another machine over
+// SSH while the model and context stay local.
+type RemoteConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Host       string `json:"host"` // user@host or an ~/.ssh/config alias
+	Port       int    `json:"port,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`
+	LocalPath  string `json:"local_path,omitempty"` // Project root; defaults to the working directory
+	RemotePath string `json:"remote_path"`          // Where the project root lives on the host
+
+	// Sync copies the project to RemotePath with rsync before each
+	// command; leave it off when the path is shared (NFS, sshfs...).
+	Sync        bool     `json:"sync,omitempty"`
+	SyncExclude []string `json:"sync_exclude,omitempty"`
+
+	// SyncDelete also removes files under RemotePath that are not in the
+	// project. It is refused for a RemotePath near the root, such as / or
+	// a home directory.
+	SyncDelete bool `json:"delete,omitempty"`
+}
+
+func DefaultTestPatterns() []string {
+	return []string{
+		"*_test.go", "test_*.py", "*_test.py", "conftest.py",
+		"*.test.*", "*.spec.*", "*Test.java", "*Tests.java", "*_spec.rb",
+		"**/test/**", "**/tests/**", "**/__tests__/**", "**/testdata/**", "**/spec/**",
+	}
+}
+
+func DefaultBudget() BudgetConfig {
+	return BudgetConfig{System: 0.15, Structure: 0.1, Files: 0.4, Git: 0.05, History: 0.3}
+}
+
+func DefaultExcludeCategories() map[string]ExclusionRule {
+	return map[string]ExclusionRule{
+		"test_fixtures":  {Enabled: true},
+		"snapshots":      {Enabled: true},
+		"lockfiles":      {Enabled: true},
+		"generated_code": {Enabled: true},
+		"data_files":     {Enabled: true, MaxKB: 64},
+	}
+}
+
+// Dir returns the directory holding claude-go's config and shared state.
+func Dir() (string, error) {
 	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".claude-go"), nil
+}
+
+func Load() (*Config, error) {
+	dir, err := Dir()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(home, ".claude-go", "config.json")
+	configPath := filepath.Join(dir, "config.json")
 
 	// Create default config if it doesn't exist
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -47,14 +266,27 @@ func Load() (*Config, error) {
 				Timeout: 30,
 			},
 			Agent: AgentConfig{
-				MaxTokens:    4096,
-				Temperature:  0.7,
-				SystemPrompt: defaultSystemPrompt(),
+				MaxTokens:         4096,
+				Temperature:       0.7,
+				SystemPrompt:      defaultSystemPrompt(),
+				OutputStyle:       "default",
+				MaxToolIterations: 10,
+				KeepToolResults:   3,
+				ContextWindow:     16384,
+				Budget:            DefaultBudget(),
+				Thinking:          "auto",
 			},
 			Git: GitConfig{
 				AutoStage: true,
 				SignOff:   false,
 			},
+			Context: ContextConfig{
+				ExcludeCategories: DefaultExcludeCategories(),
+			},
+			Permissions: PermissionsConfig{
+				AutoAccept:   "tests",
+				TestPatterns: DefaultTestPatterns(),
+			},
 		}
 
 		err := os.MkdirAll(filepath.Dir(configPath), 0755)
@@ -72,6 +304,18 @@ func Load() (*Config, error) {
 
 	var cfg Config
 	err = json.Unmarshal(data, &cfg)
+
+	// Configs written before exclusion categories existed get the defaults
+	if cfg.Context.ExcludeCategories == nil {
+		cfg.Context.ExcludeCategories = DefaultExcludeCategories()
+	}
+	if cfg.Permissions.AutoAccept == "" {
+		cfg.Permissions.AutoAccept = "tests"
+	}
+	if cfg.Permissions.TestPatterns == nil {
+		cfg.Permissions.TestPatterns = DefaultTestPatterns()
+	}
+
 	return &cfg, err
 }
 
@@ -95,3 +339,76 @@ func defaultSystemPrompt() string {
 
 Always be precise and helpful. When making changes to code, explain what you're doing and why. Ask for clarification if the request is ambiguous.`
 }
+
+// ProjectDirName is the per-project directory holding project config and
+// memory, kept next to the sources.
+const ProjectDirName = ".claude-go"
+
+type ProjectConfig struct {
+	MCPServers map[string]MCPServerConfig `json:"mcp_servers,omitempty"`
+}
+
+// MCPServerConfig is an MCP server to connect to: a command to launch for
+// stdio, or the URL of a remote server.
+type MCPServerConfig struct {
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"` // Added to the command's environment
+	URL       string            `json:"url,omitempty"`
+	Transport string            `json:"transport,omitempty"` // stdio, sse, http or websocket; by default stdio with a command, websocket with a ws:// or wss:// URL, sse with a URL ending in /sse, http with another
+	Sampling  string            `json:"sampling,omitempty"`  // Whether the server may have the model generate text: ask (default), allow or deny
+	Enabled   bool              `json:"enabled"`
+
+	// Timeouts are how many seconds to wait for the response to a request,
+	// by method (e.g. "tools/call"), or "*" for those not listed (default
+	// 30); 0 waits until the request is cancelled.
+	Timeouts map[string]int `json:"timeouts,omitempty"`
+}
+
+func ProjectConfigPath(root string) string {
+	return filepath.Join(root, ProjectDirName, "config.json")
+}
+
+// MemoryPath is the project memory file whose contents are included in
+// every system prompt.
+func MemoryPath(root string) string {
+	return filepath.Join(root, ProjectDirName, "memory.md")
+}
+
+// CommandsDir holds the project's custom slash-command templates, one
+// Markdown file per command, which the MCP server offers hosts as prompts.
+func CommandsDir(root string) string {
+	return filepath.Join(root, ProjectDirName, "commands")
+}
+
+// LoadProject reads the project config, returning an empty one if the
+// project has none.
+func LoadProject(root string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(ProjectConfigPath(root))
+	if os.IsNotExist(err) {
+		return &ProjectConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ProjectConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func SaveProject(root string, cfg *ProjectConfig) error {
+	path := ProjectConfigPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,336 @@
+// Package: internal/context/cache.go
+package context
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+// contextCacheVersion is bumped whenever what is cached per file changes,
+// so older caches are rebuilt rather than misread.
+const contextCacheVersion = 4
+
+// The ContextManager's file cache is kept on disk as a directory with a
+// file per project file, so a save writes only the entries that changed
+// and removes those of files that are gone, rather than rewriting the
+// whole project's. Each entry is everything but the contents.
+
+// contextCacheMeta is the cache directory's meta.json, telling which
+// project and format its entries are for.
+type contextCacheMeta struct {
+	Version int    `json:"version"`
+	Root    string `json:"root"`
+}
+
+// contextCacheEntry is the file of one project file's entry.
+type contextCacheEntry struct {
+	Path string       `json:"path"` // Slash-separated, relative to the project root
+	File *FileContext `json:"file"`
+}
+
+// contextCachePath is where the project's cached state is kept, other
+// files of which are named after it: the file cache is the directory
+// without its .json.
+func contextCachePath(root string) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache", fmt.Sprintf("%x.json", md5.Sum([]byte(root)))), nil
+}
+
+func contextCacheDir(root string) (string, error) {
+	path, err := contextCachePath(root)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json"), nil
+}
+
+// cacheEntryName is the name of the file holding the entry of relPath.
+func cacheEntryName(relPath string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(relPath)))
+	return hex.EncodeToString(sum[:12]) + ".json"
+}
+
+// markDirty records that the entry of the file at path, an absolute path,
+// changed or went away since the cache was saved. The caller holds
+// cacheMu.
+func (cm *ContextManager) markDirty(path string) {
+	if cm.cacheDirty == nil {
+		cm.cacheDirty = make(map[string]bool)
+	}
+	cm.cacheDirty[path] = true
+}
+
+// loadCache fills the in-memory cache from disk on first use. A missing or
+// unreadable cache just means every file is read again. The caller holds
+// cacheMu.
+func (cm *ContextManager) loadCache() {
+	if cm.cacheLoaded {
+		return
+	}
+	cm.cacheLoaded = true
+
+	dir, err := contextCacheDir(cm.projectRoot)
+	if err != nil {
+		return
+	}
+	// The cache of older versions was this one file
+	os.Remove(dir + ".json")
+
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return
+	}
+	var meta contextCacheMeta
+	if json.Unmarshal(data, &meta) != nil || meta.Version != contextCacheVersion || meta.Root != cm.projectRoot {
+		cm.cacheReset = true
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, dirEntry := range entries {
+		if dirEntry.Name() == "meta.json" || filepath.Ext(dirEntry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, dirEntry.Name()))
+		if err != nil {
+			continue
+		}
+		var entry contextCacheEntry
+		if json.Unmarshal(data, &entry) != nil || entry.File == nil {
+			continue
+		}
+		entry.File.Path = filepath.Join(cm.projectRoot, filepath.FromSlash(entry.Path))
+		if _, exists := cm.cache[entry.File.Path]; !exists {
+			cm.cache[entry.File.Path] = entry.File
+		}
+	}
+}
+
+// saveCache drops the entries of files that are neither in seen (absolute
+// paths) nor pinned, then writes to disk the entries that changed since
+// the last save and removes those that went away. Failing to save only
+// costs the next run some time.
+func (cm *ContextManager) saveCache(seen map[string]bool) {
+	cm.pinsMu.Lock()
+	pinned := make(map[string]bool, len(cm.pins))
+	for _, abs := range cm.pins {
+		pinned[abs] = true
+	}
+	cm.pinsMu.Unlock()
+
+	// One save at a time, so an older one cannot write its entries over a
+	// newer one's
+	cm.saveMu.Lock()
+	defer cm.saveMu.Unlock()
+
+	cm.cacheMu.Lock()
+	for path := range cm.cache {
+		if !seen[path] && !pinned[path] {
+			delete(cm.cache, path)
+			cm.markDirty(path)
+		}
+	}
+	reset := cm.cacheReset
+	written := make(map[string]*FileContext)
+	var removed []string
+	for path := range cm.cacheDirty {
+		if fileCtx, ok := cm.cache[path]; ok {
+			written[path] = fileCtx
+		} else {
+			removed = append(removed, path)
+		}
+	}
+	cm.cacheDirty, cm.cacheReset = nil, false
+	cm.cacheMu.Unlock()
+
+	if !reset && len(written) == 0 && len(removed) == 0 {
+		return
+	}
+	if err := cm.writeCache(reset, written, removed); err != nil {
+		// Try again on the next save
+		cm.cacheMu.Lock()
+		cm.cacheReset = cm.cacheReset || reset
+		for path := range written {
+			cm.markDirty(path)
+		}
+		for _, path := range removed {
+			cm.markDirty(path)
+		}
+		cm.cacheMu.Unlock()
+	}
+}
+
+// writeCache writes the entries of written and removes those of removed,
+// after removing every entry if reset.
+func (cm *ContextManager) writeCache(reset bool, written map[string]*FileContext, removed []string) error {
+	dir, err := contextCacheDir(cm.projectRoot)
+	if err != nil {
+		return err
+	}
+	if reset {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(contextCacheMeta{Version: contextCacheVersion, Root: cm.projectRoot})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "meta.json"), meta); err != nil {
+		return err
+	}
+
+	for _, path := range removed {
+		relPath, err := filepath.Rel(cm.projectRoot, path)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, cacheEntryName(relPath))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for path, fileCtx := range written {
+		relPath, err := filepath.Rel(cm.projectRoot, path)
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(contextCacheEntry{Path: filepath.ToSlash(relPath), File: fileCtx})
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(dir, cacheEntryName(relPath)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to path through a temporary file, so a
+// reader never sees it half written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Invalidate forgets what is known about paths (absolute, or relative to
+// the working directory), so the next request sees them as they are now
+// even if the file watcher has not caught up. Tools call it after editing
+// files.
+func (cm *ContextManager) Invalidate(paths ...string) {
+	var relPaths []string
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		relPath, err := filepath.Rel(cm.projectRoot, absPath)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		relPaths = append(relPaths, relPath)
+
+		cm.cacheMu.Lock()
+		if _, ok := cm.cache[absPath]; ok {
+			delete(cm.cache, absPath)
+			cm.markDirty(absPath)
+		}
+		cm.cacheMu.Unlock()
+	}
+
+	cm.filterMu.Lock()
+	for _, relPath := range relPaths {
+		delete(cm.excluded, relPath)
+	}
+	cm.filterMu.Unlock()
+
+	cm.treeMu.Lock()
+	for _, relPath := range relPaths {
+		cm.updateTree(relPath, false, true)
+	}
+	cm.treeMu.Unlock()
+
+	cm.forgetIndexed(relPaths)
+}
+
+// InvalidateAll forgets everything cached about the project: the file
+// cache, content filter results, the git history, the watched tree and
+// the files of the symbol, lexical and vector indexes, which are rebuilt
+// on the next request.
+func (cm *ContextManager) InvalidateAll() {
+	cm.cacheMu.Lock()
+	cm.cacheLoaded = true // Don't bring back the on-disk cache either
+	cm.cache = make(map[string]*FileContext)
+	cm.cacheDirty = nil
+	cm.cacheReset = true // Remove the entries saved on disk
+	cm.cacheMu.Unlock()
+
+	cm.filterMu.Lock()
+	cm.excluded = nil
+	cm.filterMu.Unlock()
+
+	cm.historyMu.Lock()
+	cm.history = nil
+	cm.historyMu.Unlock()
+
+	cm.resetTree()
+	cm.forgetIndexed(nil)
+}
+
+// forgetIndexed drops files from the indexes so they are read again; nil
+// drops every file.
+func (cm *ContextManager) forgetIndexed(relPaths []string) {
+	if idx := cm.index; idx != nil {
+		idx.mu.Lock()
+		if relPaths == nil {
+			idx.Files = make(map[string]indexedFile)
+		}
+		for _, relPath := range relPaths {
+			delete(idx.Files, filepath.ToSlash(relPath))
+		}
+		idx.mu.Unlock()
+	}
+
+	if idx := cm.vectors; idx != nil {
+		idx.mu.Lock()
+		if relPaths == nil {
+			idx.Files = make(map[string]vectorFile)
+		}
+		for _, relPath := range relPaths {
+			delete(idx.Files, filepath.ToSlash(relPath))
+		}
+		idx.mu.Unlock()
+	}
+
+	if idx := cm.lexical; idx != nil {
+		idx.mu.Lock()
+		if relPaths == nil {
+			idx.docs = make(map[string]lexicalDoc)
+			idx.postings = make(map[string]map[string]int)
+			idx.totalLen = 0
+		}
+		for _, relPath := range relPaths {
+			idx.remove(relPath)
+		}
+		idx.mu.Unlock()
+	}
+}
@@ -0,0 +1,145 @@
+// Package: internal/context/chunks.go
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxChunkLines = 60
+
+// FileChunk is a range of lines of a file, cut at declarations.
+type FileChunk struct {
+	StartLine int
+	EndLine   int
+	// Header is the signature of the first declaration in the chunk, or
+	// empty when it starts with none
+	Header string
+	Text   string
+}
+
+// Heading describes the chunk for a prompt: its lines and what it
+// declares.
+func (c FileChunk) Heading() string {
+	if c.Header == "" {
+		return fmt.Sprintf("lines %d-%d", c.StartLine, c.EndLine)
+	}
+	return fmt.Sprintf("lines %d-%d: %s", c.StartLine, c.EndLine, c.Header)
+}
+
+// SplitChunks splits a file into function- and class-level chunks of at
+// most maxChunkLines lines.
+func SplitChunks(path, content string) []FileChunk {
+	lines := strings.Split(content, "\n")
+	symbols := ExtractSymbols(path, content)
+
+	var chunks []FileChunk
+	next := 0
+	for _, r := range symbolRanges(len(lines), symbols) {
+		chunk := FileChunk{StartLine: r.start, EndLine: r.end, Text: strings.Join(lines[r.start-1:r.end], "\n")}
+		for next < len(symbols) && symbols[next].Line <= r.end {
+			if chunk.Header == "" && symbols[next].Line >= r.start {
+				chunk.Header = symbols[next].Signature
+			}
+			next++
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// SelectChunks picks the chunks of a large file that are most relevant to
+// query by BM25, as many as fit in maxTokens, and returns them in file
+// order. It returns nil when no chunk mentions any of the query's terms.
+func SelectChunks(path, content, query string, maxTokens int) []FileChunk {
+	queryTerms := uniqueTerms(tokenize(query))
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	chunks := SplitChunks(path, content)
+	idx := &lexicalIndex{docs: make(map[string]lexicalDoc), postings: make(map[string]map[string]int)}
+	for i, chunk := range chunks {
+		idx.add(strconv.Itoa(i), ProjectEntry{}, "", tokenize(chunk.Text))
+	}
+	scores := idx.score(queryTerms)
+	if len(scores) == 0 {
+		return nil
+	}
+
+	order := make([]int, 0, len(scores))
+	for i := range chunks {
+		if scores[strconv.Itoa(i)] > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[strconv.Itoa(order[a])] > scores[strconv.Itoa(order[b])] })
+
+	selected := make([]bool, len(chunks))
+	tokens := 0
+	for _, i := range order {
+		chunkTokens := len(chunks[i].Text) / 4
+		if tokens+chunkTokens > maxTokens {
+			continue
+		}
+		selected[i] = true
+		tokens += chunkTokens
+	}
+
+	var picked []FileChunk
+	for i, chunk := range chunks {
+		if selected[i] {
+			picked = append(picked, chunk)
+		}
+	}
+	return picked
+}
+
+type lineRange struct {
+	start, end int // 1-based, inclusive
+}
+
+// chunkRanges splits a file into ranges of at most maxChunkLines lines,
+// starting new ranges at declarations and merging small declarations.
+func chunkRanges(path, content string) []lineRange {
+	return symbolRanges(strings.Count(content, "\n")+1, ExtractSymbols(path, content))
+}
+
+// symbolRanges is chunkRanges for a file of total lines declaring symbols.
+func symbolRanges(total int, symbols []Symbol) []lineRange {
+	starts := []int{1}
+	for _, symbol := range symbols {
+		if symbol.Line > starts[len(starts)-1] && symbol.Line <= total {
+			starts = append(starts, symbol.Line)
+		}
+	}
+
+	var ranges []lineRange
+	var current *lineRange
+	for i, start := range starts {
+		end := total
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+
+		if current != nil && end-current.start+1 > maxChunkLines {
+			ranges = append(ranges, *current)
+			current = nil
+		}
+		if current == nil {
+			current = &lineRange{start: start}
+		}
+		current.end = end
+
+		for current.end-current.start+1 > maxChunkLines {
+			ranges = append(ranges, lineRange{start: current.start, end: current.start + maxChunkLines - 1})
+			current.start += maxChunkLines
+		}
+	}
+	if current != nil {
+		ranges = append(ranges, *current)
+	}
+	return ranges
+}
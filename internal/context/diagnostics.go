@@ -0,0 +1,208 @@
+// Package: internal/context/diagnostics.go
+package context
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/lsp"
+)
+
+const (
+	// maxDiagnosticFiles is how many of the most recently modified files
+	// are opened in each language server
+	maxDiagnosticFiles = 100
+
+	// maxDiagnostics bounds what goes in the context, errors first
+	maxDiagnostics = 50
+
+	// A server is done checking once it has published nothing for
+	// diagnosticsQuiet, and is not waited on for more than diagnosticsTimeout
+	diagnosticsQuiet   = 500 * time.Millisecond
+	diagnosticsTimeout = 10 * time.Second
+)
+
+// Diagnostic is a compile error or warning reported by a language server.
+type Diagnostic struct {
+	File     string // Relative to the project root
+	Line     int    // 1-based
+	Column   int    // 1-based
+	Severity string // "error" or "warning"
+	Source   string // The checker that reported it, e.g. "compiler"
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	source := ""
+	if d.Source != "" {
+		source = " (" + d.Source + ")"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s%s", filepath.ToSlash(d.File), d.Line, d.Column, d.Severity, d.Message, source)
+}
+
+type languageServer struct {
+	config config.LanguageServerConfig
+	client *lsp.Client
+	failed bool              // Not started again after failing once
+	sent   map[string]string // Content hash last sent, by absolute path
+}
+
+// SetLanguageServers configures the servers Diagnostics asks. They are
+// started on first use.
+func (cm *ContextManager) SetLanguageServers(servers []config.LanguageServerConfig) {
+	cm.lspMu.Lock()
+	defer cm.lspMu.Unlock()
+	for _, server := range servers {
+		if len(server.Command) > 0 && len(server.Extensions) > 0 {
+			cm.servers = append(cm.servers, &languageServer{config: server, sent: make(map[string]string)})
+		}
+	}
+}
+
+// Diagnostics returns the errors and warnings the configured language
+// servers currently report for the project, errors first. The most
+// recently modified matching files are sent to the servers, again whenever
+// their content changed. It returns nil without servers.
+func (cm *ContextManager) Diagnostics(ctx context.Context) []Diagnostic {
+	cm.lspMu.Lock()
+	defer cm.lspMu.Unlock()
+	if len(cm.servers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsTimeout)
+	defer cancel()
+
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return nil
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].entry.ModTime.After(sources[j].entry.ModTime)
+	})
+
+	var diagnostics []Diagnostic
+	for _, server := range cm.servers {
+		if server.failed {
+			continue
+		}
+		if server.client == nil {
+			client, err := lsp.Start(ctx, cm.projectRoot, server.config.Command)
+			if err != nil {
+				log.Printf("Warning: diagnostics from %s are unavailable: %v", server.config.Command[0], err)
+				server.failed = true
+				continue
+			}
+			server.client = client
+		}
+
+		if err := server.open(sources); err != nil {
+			log.Printf("Warning: %s stopped: %v", server.config.Command[0], err)
+			server.client.Close()
+			server.client = nil
+			server.failed = true
+			continue
+		}
+		for path, diags := range server.client.Diagnostics(ctx, diagnosticsQuiet) {
+			relPath, err := filepath.Rel(cm.projectRoot, path)
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				continue
+			}
+			for _, d := range diags {
+				severity := "error" // The protocol leaves a missing severity to the client
+				switch d.Severity {
+				case lsp.SeverityWarning:
+					severity = "warning"
+				case lsp.SeverityInformation, lsp.SeverityHint:
+					continue
+				}
+				diagnostics = append(diagnostics, Diagnostic{
+					File:     relPath,
+					Line:     d.Range.Start.Line + 1,
+					Column:   d.Range.Start.Character + 1,
+					Severity: severity,
+					Source:   d.Source,
+					Message:  strings.TrimSpace(d.Message),
+				})
+			}
+		}
+	}
+
+	sort.Slice(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Severity != b.Severity {
+			return a.Severity == "error"
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	if len(diagnostics) > maxDiagnostics {
+		diagnostics = diagnostics[:maxDiagnostics]
+	}
+	return diagnostics
+}
+
+// open sends the server the files it handles that changed since they were
+// last sent, up to maxDiagnosticFiles.
+func (s *languageServer) open(sources []sourceFile) error {
+	opened := 0
+	for _, file := range sources {
+		ext := strings.ToLower(filepath.Ext(file.path))
+		if !s.handles(ext) {
+			continue
+		}
+		if opened++; opened > maxDiagnosticFiles {
+			break
+		}
+
+		content, err := os.ReadFile(file.path)
+		if err != nil {
+			continue
+		}
+		hash := hashContent(content)
+		if s.sent[file.path] == hash {
+			continue
+		}
+
+		languageID := s.config.LanguageID
+		if languageID == "" {
+			languageID = strings.TrimPrefix(ext, ".")
+		}
+		if err := s.client.Open(file.path, languageID, string(content)); err != nil {
+			return err
+		}
+		s.sent[file.path] = hash
+	}
+	return nil
+}
+
+func (s *languageServer) handles(ext string) bool {
+	for _, handled := range s.config.Extensions {
+		if strings.EqualFold(handled, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseLanguageServers shuts down the language servers that were started.
+func (cm *ContextManager) CloseLanguageServers() {
+	cm.lspMu.Lock()
+	defer cm.lspMu.Unlock()
+	for _, server := range cm.servers {
+		if server.client != nil {
+			server.client.Close()
+			server.client = nil
+			server.sent = make(map[string]string)
+		}
+	}
+}
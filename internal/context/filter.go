@@ -0,0 +1,206 @@
+// Package: internal/context/filter.go
+package context
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const headerSniffBytes = 1024
+
+// ContentFilter excludes whole categories of files (lockfiles, generated
+// code, fixtures...) from the prompt, independent of their extension, and
+// applies the context.include and context.exclude globs.
+type ContentFilter struct {
+	rules   map[string]config.ExclusionRule
+	include []ignoreRule
+	exclude []string
+}
+
+func NewContentFilter(cfg config.ContextConfig) *ContentFilter {
+	rules := cfg.ExcludeCategories
+	if rules == nil {
+		rules = config.DefaultExcludeCategories()
+	}
+	return &ContentFilter{
+		rules:   rules,
+		include: parseIgnoreLines(cfg.Include),
+		exclude: cfg.Exclude,
+	}
+}
+
+// ExcludePatterns returns the context.exclude globs, which walkers pass to
+// NewIgnoreMatcher so excluded directories are pruned whole.
+func (f *ContentFilter) ExcludePatterns() []string {
+	if f == nil {
+		return nil
+	}
+	return f.exclude
+}
+
+var lockfiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"cargo.lock":          true,
+	"poetry.lock":         true,
+	"pipfile.lock":        true,
+	"uv.lock":             true,
+	"composer.lock":       true,
+	"gemfile.lock":        true,
+	"mix.lock":            true,
+}
+
+var fixtureDirs = map[string]bool{
+	"testdata":     true,
+	"fixtures":     true,
+	"fixture":      true,
+	"__fixtures__": true,
+	"__mocks__":    true,
+}
+
+var generatedSuffixes = []string{
+	".pb.go", "_grpc.pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", "_pb2.pyi",
+	".pb.cc", ".pb.h", "_pb.js", "_pb.d.ts", "_grpc_pb.js", ".pb.ts",
+	"_generated.go", ".gen.go", ".g.dart", ".freezed.dart", ".designer.cs", ".g.cs",
+	".generated.ts", ".generated.js", ".min.js", ".min.mjs", ".min.css", ".bundle.js", ".js.map", ".css.map",
+}
+
+// minifiableExts are the files checked for minification by line length.
+var minifiableExts = map[string]bool{".js": true, ".mjs": true, ".cjs": true, ".css": true}
+
+var dataExts = map[string]bool{
+	".csv": true, ".tsv": true, ".json": true, ".jsonl": true, ".ndjson": true,
+	".xml": true, ".sql": true, ".txt": true, ".yaml": true, ".yml": true,
+}
+
+// Excluded reports the category that excludes the file, or "" if the file
+// should be kept. relPath is relative to the project root.
+func (f *ContentFilter) Excluded(path, relPath string, size int64) string {
+	base := strings.ToLower(filepath.Base(relPath))
+	ext := strings.ToLower(filepath.Ext(relPath))
+	slashPath := filepath.ToSlash(relPath)
+
+	if len(f.include) > 0 && !included(f.include, slashPath) {
+		return "not_included"
+	}
+
+	checks := []struct {
+		category string
+		match    func() bool
+	}{
+		{"lockfiles", func() bool { return lockfiles[base] }},
+		{"snapshots", func() bool {
+			return ext == ".snap" || ext == ".golden" || hasDir(slashPath, "__snapshots__")
+		}},
+		{"test_fixtures", func() bool {
+			for dir := range fixtureDirs {
+				if hasDir(slashPath, dir) {
+					return true
+				}
+			}
+			return false
+		}},
+		{"data_files", func() bool {
+			maxKB := f.rules["data_files"].MaxKB
+			return dataExts[ext] && maxKB > 0 && size > int64(maxKB)*1024
+		}},
+		{"generated_code", func() bool {
+			for _, suffix := range generatedSuffixes {
+				if strings.HasSuffix(base, suffix) {
+					return true
+				}
+			}
+			return strings.HasPrefix(base, "zz_generated") || looksGenerated(path, minifiableExts[ext] && size > headerSniffBytes)
+		}},
+	}
+
+	for _, check := range checks {
+		rule, ok := f.rules[check.category]
+		if !ok || !rule.Enabled || !check.match() {
+			continue
+		}
+		if keep(rule.Keep, slashPath) {
+			continue
+		}
+		return check.category
+	}
+
+	return ""
+}
+
+func hasDir(slashPath, dir string) bool {
+	parts := strings.Split(slashPath, "/")
+	for _, part := range parts[:len(parts)-1] {
+		if strings.EqualFold(part, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether a context.include glob matches the file or one
+// of its directories.
+func included(rules []ignoreRule, slashPath string) bool {
+	isDir := false
+	for p := slashPath; p != "."; p = path.Dir(p) {
+		for _, rule := range rules {
+			if !rule.negate && rule.matches(p, isDir) {
+				return true
+			}
+		}
+		isDir = true
+	}
+	return false
+}
+
+func keep(globs []string, slashPath string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, slashPath); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, filepath.Base(slashPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// looksGenerated looks for the conventional generator markers
+// ("Code generated ... DO NOT EDIT", "@generated", "auto-generated") near
+// the top of a file. With checkMinified it also reports scripts and
+// stylesheets whose first line runs past the sniffed header, which is how
+// minified bundles look.
+func looksGenerated(path string, checkMinified bool) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, headerSniffBytes)
+	n, _ := file.Read(head)
+	head = head[:n]
+
+	if checkMinified && n == headerSniffBytes && !bytes.Contains(head, []byte("\n")) {
+		return true
+	}
+	if bytes.Contains(head, []byte("@generated")) {
+		return true
+	}
+	lower := bytes.ToLower(head)
+	if bytes.Contains(lower, []byte("auto-generated")) || bytes.Contains(lower, []byte("autogenerated")) ||
+		bytes.Contains(lower, []byte("automatically generated")) {
+		return bytes.Contains(lower, []byte("do not edit")) || bytes.Contains(lower, []byte("do not modify")) ||
+			bytes.Contains(lower, []byte("changes to this file"))
+	}
+	return bytes.Contains(head, []byte("Code generated")) && bytes.Contains(head, []byte("DO NOT EDIT"))
+}
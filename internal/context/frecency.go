@@ -0,0 +1,147 @@
+// Package: internal/context/frecency.go
+package context
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Accesses weigh in frecency by kind: an edit says more about what the
+// user works on than a read.
+const (
+	AccessRead = 1.0
+	AccessEdit = 2.0
+
+	// frecencyHalfLife is how long it takes an access to count half
+	frecencyHalfLife = 14 * 24 * time.Hour
+
+	// Scores that decayed below minFrecency are dropped from the log
+	minFrecency = 0.01
+)
+
+// accessLog is how often and how recently the project's files were read
+// and edited in sessions, kept as a score that halves every
+// frecencyHalfLife and grows by the weight of each access.
+type accessLog struct {
+	Root  string                  `json:"root"`
+	Files map[string]accessRecord `json:"files"` // Keyed by slash-separated path relative to Root
+}
+
+type accessRecord struct {
+	Score   float64   `json:"score"`
+	Updated time.Time `json:"updated"`
+}
+
+// decayed is the record's score at now.
+func (r accessRecord) decayed(now time.Time) float64 {
+	return r.Score * math.Exp2(-float64(now.Sub(r.Updated))/float64(frecencyHalfLife))
+}
+
+// RecordAccess notes that the user or the model read or edited paths
+// (absolute, or relative to the working directory), with weight
+// AccessRead or AccessEdit. Paths that are not files of the project are
+// ignored. The log is kept in ~/.claude-go/cache, so it carries over
+// between sessions.
+func (cm *ContextManager) RecordAccess(weight float64, paths ...string) {
+	cm.accessMu.Lock()
+	defer cm.accessMu.Unlock()
+	cm.loadAccessLog()
+
+	now := time.Now()
+	changed := false
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		relPath, err := filepath.Rel(cm.projectRoot, abs)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		if info, err := os.Stat(abs); err != nil || info.IsDir() {
+			continue
+		}
+		key := filepath.ToSlash(relPath)
+		record := cm.access.Files[key]
+		cm.access.Files[key] = accessRecord{Score: record.decayed(now) + weight, Updated: now}
+		changed = true
+	}
+	if changed {
+		cm.access.save(now)
+	}
+}
+
+// Frecency returns the decayed access score of each file read or edited in
+// past sessions, by path relative to the project root.
+func (cm *ContextManager) Frecency() map[string]float64 {
+	cm.accessMu.Lock()
+	defer cm.accessMu.Unlock()
+	cm.loadAccessLog()
+
+	now := time.Now()
+	scores := make(map[string]float64, len(cm.access.Files))
+	for key, record := range cm.access.Files {
+		scores[filepath.FromSlash(key)] = record.decayed(now)
+	}
+	return scores
+}
+
+func accessLogPath(root string) (string, error) {
+	path, err := contextCachePath(root)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json") + ".access.json", nil
+}
+
+// loadAccessLog reads the access log on first use; a missing or unreadable
+// one starts empty. The caller holds accessMu.
+func (cm *ContextManager) loadAccessLog() {
+	if cm.access != nil {
+		return
+	}
+	cm.access = &accessLog{Root: cm.projectRoot, Files: make(map[string]accessRecord)}
+
+	path, err := accessLogPath(cm.projectRoot)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var saved accessLog
+	if json.Unmarshal(data, &saved) == nil && saved.Root == cm.projectRoot && saved.Files != nil {
+		cm.access.Files = saved.Files
+	}
+}
+
+// save writes the log, dropping files whose score decayed to nothing;
+// failing to only loses this session's accesses.
+func (l *accessLog) save(now time.Time) {
+	for key, record := range l.Files {
+		if record.decayed(now) < minFrecency {
+			delete(l.Files, key)
+		}
+	}
+
+	path, err := accessLogPath(l.Root)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0755) != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, path)
+	}
+}
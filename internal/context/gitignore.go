@@ -0,0 +1,202 @@
+// Package: internal/context/gitignore.go
+package context
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read in every directory, in order, so .claudeignore can
+// add to (or "!"-negate) what git ignores without touching .gitignore.
+var ignoreFiles = []string{".gitignore", ".claudeignore"}
+
+// IgnoreMatcher applies the project's .gitignore and .claudeignore files, at
+// every level, and .git/info/exclude the way git does: deeper files override
+// shallower ones, the last matching pattern wins, and "!" re-includes.
+type IgnoreMatcher struct {
+	root  string
+	files map[string][]ignoreRule // Keyed by directory relative to root ("" for the root)
+}
+
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	base    bool // No slash in the pattern: match the name at any depth
+}
+
+// NewIgnoreMatcher reads the ignore files under root. exclude holds extra
+// patterns in the same syntax (context.exclude) applied after the root's
+// files.
+func NewIgnoreMatcher(root string, exclude []string) *IgnoreMatcher {
+	m := &IgnoreMatcher{root: root, files: make(map[string][]ignoreRule)}
+	rules := loadIgnoreFile(filepath.Join(root, ".git", "info", "exclude"))
+	rules = append(rules, m.rules("")...)
+	m.files[""] = append(rules, parseIgnoreLines(exclude)...)
+	return m
+}
+
+// Ignored reports whether the path (relative to the root, with either
+// separator) is ignored, including by an ignored parent directory, which
+// git never looks inside.
+func (m *IgnoreMatcher) Ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." || relPath == "" {
+		return false
+	}
+	if parent := path.Dir(relPath); parent != "." && m.Ignored(parent, true) {
+		return true
+	}
+
+	ignored := false
+	dir := ""
+	for {
+		rel := relPath
+		if dir != "" {
+			rel = strings.TrimPrefix(relPath, dir+"/")
+		}
+		for _, rule := range m.rules(dir) {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+
+		next, _, found := strings.Cut(rel, "/")
+		if !found {
+			break
+		}
+		if dir == "" {
+			dir = next
+		} else {
+			dir = dir + "/" + next
+		}
+	}
+
+	return ignored
+}
+
+func (m *IgnoreMatcher) rules(dir string) []ignoreRule {
+	rules, ok := m.files[dir]
+	if !ok {
+		for _, name := range ignoreFiles {
+			rules = append(rules, loadIgnoreFile(filepath.Join(m.root, filepath.FromSlash(dir), name))...)
+		}
+		m.files[dir] = rules
+	}
+	return rules
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base {
+		return r.pattern.MatchString(path.Base(rel))
+	}
+	return r.pattern.MatchString(rel)
+}
+
+func loadIgnoreFile(filename string) []ignoreRule {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return parseIgnoreLines(lines)
+}
+
+func parseIgnoreLines(lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		if rule, ok := parseIgnoreLine(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.base = !strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	pattern, err := regexp.Compile("^" + ignorePatternRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// ignorePatternRegexp translates a gitignore glob into a regular
+// expression over slash-separated paths.
+func ignorePatternRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
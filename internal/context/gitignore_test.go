@@ -0,0 +1,192 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	type query struct {
+		path  string
+		isDir bool
+		want  bool
+	}
+	tests := []struct {
+		name    string
+		files   map[string]string // Ignore files, relative to the root
+		exclude []string
+		queries []query
+	}{
+		{
+			name:  "negation",
+			files: map[string]string{".gitignore": "*.log\n!keep.log\n"},
+			queries: []query{
+				{"debug.log", false, true},
+				{"sub/debug.log", false, true},
+				{"keep.log", false, false},
+				{"sub/keep.log", false, false},
+				{"main.go", false, false},
+			},
+		},
+		{
+			name:  "last matching pattern wins",
+			files: map[string]string{".gitignore": "!keep.log\n*.log\n"},
+			queries: []query{
+				{"keep.log", false, true},
+			},
+		},
+		{
+			name:  "leading double star",
+			files: map[string]string{".gitignore": "**/build\n"},
+			queries: []query{
+				{"build", true, true},
+				{"a/b/build", true, true},
+				{"a/build/out.o", false, true},
+				{"builder", true, false},
+			},
+		},
+		{
+			name:  "trailing double star",
+			files: map[string]string{".gitignore": "logs/**\n"},
+			queries: []query{
+				{"logs", true, false},
+				{"logs/today.txt", false, true},
+				{"logs/2024/jan.txt", false, true},
+				{"sub/logs/today.txt", false, false},
+			},
+		},
+		{
+			name:  "inner double star",
+			files: map[string]string{".gitignore": "a/**/z\n"},
+			queries: []query{
+				{"a/z", false, true},
+				{"a/b/z", false, true},
+				{"a/b/c/z", false, true},
+				{"b/a/z", false, false},
+			},
+		},
+		{
+			name:  "anchoring",
+			files: map[string]string{".gitignore": "/todo\ndoc/*.txt\nname\n"},
+			queries: []query{
+				{"todo", false, true},
+				{"sub/todo", false, false},
+				{"doc/notes.txt", false, true},
+				{"doc/sub/notes.txt", false, false},
+				{"x/doc/notes.txt", false, false},
+				{"name", false, true},
+				{"deep/in/name", false, true},
+			},
+		},
+		{
+			name:  "directory only",
+			files: map[string]string{".gitignore": "tmp/\n"},
+			queries: []query{
+				{"tmp", true, true},
+				{"tmp/a.txt", false, true},
+				{"sub/tmp", true, true},
+				{"tmp", false, false},
+			},
+		},
+		{
+			name:  "no re-including under an excluded directory",
+			files: map[string]string{".gitignore": "vendor/\n!vendor/keep.go\n"},
+			queries: []query{
+				{"vendor", true, true},
+				{"vendor/keep.go", false, true},
+			},
+		},
+		{
+			name:  "re-including under a directory whose contents are excluded",
+			files: map[string]string{".gitignore": "vendor/*\n!vendor/keep.go\n"},
+			queries: []query{
+				{"vendor", true, false},
+				{"vendor/drop.go", false, true},
+				{"vendor/keep.go", false, false},
+			},
+		},
+		{
+			name: "deeper files override shallower ones",
+			files: map[string]string{
+				".gitignore":     "*.log\n",
+				"sub/.gitignore": "!*.log\n/local\n",
+			},
+			queries: []query{
+				{"a.log", false, true},
+				{"sub/a.log", false, false},
+				{"sub/deeper/a.log", false, false},
+				{"sub/local", false, true},
+				{"local", false, false},
+				{"sub/deeper/local", false, false},
+			},
+		},
+		{
+			name: "claudeignore and info/exclude",
+			files: map[string]string{
+				".git/info/exclude": "secret.txt\n",
+				".gitignore":        "*.tmp\n",
+				".claudeignore":     "fixtures/\n!important.tmp\n",
+			},
+			queries: []query{
+				{"secret.txt", false, true},
+				{"a.tmp", false, true},
+				{"important.tmp", false, false},
+				{"fixtures", true, true},
+			},
+		},
+		{
+			name:    "configured excludes",
+			files:   map[string]string{},
+			exclude: []string{"*.min.js", "!app.min.js"},
+			queries: []query{
+				{"lib.min.js", false, true},
+				{"app.min.js", false, false},
+			},
+		},
+		{
+			name:  "comments, escapes and character classes",
+			files: map[string]string{".gitignore": "# comment\n\\#hash\n\\!bang\nfile[0-9].txt\nnot[!a].md\nspace \n"},
+			queries: []query{
+				{"# comment", false, false},
+				{"#hash", false, true},
+				{"!bang", false, true},
+				{"file3.txt", false, true},
+				{"filex.txt", false, false},
+				{"notb.md", false, true},
+				{"nota.md", false, false},
+				{"space", false, true},
+			},
+		},
+		{
+			name:  "question mark stays within a segment",
+			files: map[string]string{".gitignore": "a?c\n"},
+			queries: []query{
+				{"abc", false, true},
+				{"a/c", false, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m := NewIgnoreMatcher(root, tt.exclude)
+			for _, q := range tt.queries {
+				if got := m.Ignored(q.path, q.isDir); got != q.want {
+					t.Errorf("Ignored(%q, dir %v) = %v, want %v", q.path, q.isDir, got, q.want)
+				}
+			}
+		})
+	}
+}
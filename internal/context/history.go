@@ -0,0 +1,162 @@
+// Package: internal/context/history.go
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// historyCommits is how many of the latest commits are read
+	historyCommits = 500
+
+	// churnHalfLife weighs a change by its age: one this old counts half
+	churnHalfLife = 30 * 24 * time.Hour
+
+	// Commits touching more files than maxCoChangeFiles (mass renames,
+	// reformatting) say nothing about which files belong together, and
+	// files must have changed together minCoChanges times to count
+	maxCoChangeFiles = 20
+	minCoChanges     = 2
+)
+
+// GitHistory is what the project's recent git log says about its files:
+// how often each changed lately, and which change together.
+type GitHistory struct {
+	Head    string
+	commits []historyCommit // Newest first
+	churn   map[string]float64
+}
+
+type historyCommit struct {
+	time  time.Time
+	files []string // Slash-separated, relative to the project root
+}
+
+// GitHistory reads the latest commits touching the project, once per HEAD.
+// It returns nil without an error when the project is not in a git
+// repository or has no commits.
+func (cm *ContextManager) GitHistory() (*GitHistory, error) {
+	head, err := cm.git("rev-parse", "HEAD")
+	if err != nil {
+		return nil, nil
+	}
+	head = strings.TrimSpace(head)
+
+	cm.historyMu.Lock()
+	defer cm.historyMu.Unlock()
+	if cm.history != nil && cm.history.Head == head {
+		return cm.history, nil
+	}
+
+	// --relative limits the log to the project directory and gives paths
+	// relative to it, which matters when the project is a subdirectory of
+	// the repository
+	out, err := cm.git("-c", "core.quotePath=false", "log", "-n", strconv.Itoa(historyCommits),
+		"--no-merges", "--relative", "--name-only", "--format=%x1e%ct")
+	if err != nil {
+		return nil, err
+	}
+
+	history := &GitHistory{Head: head, churn: make(map[string]float64)}
+	now := time.Now()
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		seconds, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		commit := historyCommit{time: time.Unix(seconds, 0)}
+		weight := math.Exp2(-float64(now.Sub(commit.time)) / float64(churnHalfLife))
+		for _, file := range lines[1:] {
+			if file = strings.TrimSpace(file); file != "" {
+				commit.files = append(commit.files, file)
+				history.churn[file] += weight
+			}
+		}
+		if len(commit.files) > 0 {
+			history.commits = append(history.commits, commit)
+		}
+	}
+
+	cm.history = history
+	return history, nil
+}
+
+// git runs git in the project root and returns its stdout.
+func (cm *ContextManager) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", cm.projectRoot}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.String(), nil
+}
+
+// Churn scores how much each file (slash-separated, relative to the
+// project root) changed recently: every commit counts, recent ones more.
+func (h *GitHistory) Churn() map[string]float64 {
+	return h.churn
+}
+
+// CoChanged returns the files that historically changed in the same
+// commits as any of targets, most often first, up to limit. Files changed
+// together fewer than minCoChanges times are left out.
+func (h *GitHistory) CoChanged(targets []string, limit int) []string {
+	isTarget := make(map[string]bool)
+	for _, target := range targets {
+		isTarget[target] = true
+	}
+
+	counts := make(map[string]int)
+	for _, commit := range h.commits {
+		if len(commit.files) > maxCoChangeFiles {
+			continue
+		}
+		touched := false
+		for _, file := range commit.files {
+			if isTarget[file] {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			continue
+		}
+		for _, file := range commit.files {
+			if !isTarget[file] {
+				counts[file]++
+			}
+		}
+	}
+
+	var files []string
+	for file, n := range counts {
+		if n >= minCoChanges {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if counts[files[i]] != counts[files[j]] {
+			return counts[files[i]] > counts[files[j]]
+		}
+		return files[i] < files[j]
+	})
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files
+}
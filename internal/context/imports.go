@@ -0,0 +1,257 @@
+// Package: internal/context/imports.go
+package context
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxRelatedFiles = 20
+
+var (
+	scriptImport = regexp.MustCompile(`(?m)(?:^\s*(?:import|export)\b[^'"\n]*?(?:from\s*)?|\brequire\s*\(\s*|\bimport\s*\(\s*)['"]([^'"\n]+)['"]`)
+	pythonImport = regexp.MustCompile(`(?m)^\s*import\s+([\w., ]+)`)
+	pythonFrom   = regexp.MustCompile(`(?m)^\s*from\s+(\.*[\w.]*)\s+import\s+\(?\s*([\w, ]+)`)
+
+	scriptExtensions = []string{"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", "/index.ts", "/index.tsx", "/index.js", "/index.jsx"}
+)
+
+// ExtractImports lists what a file imports, as written: Go import paths,
+// JavaScript/TypeScript module specifiers, and Python modules, with a
+// leading "." for each level of a relative Python import.
+func ExtractImports(path, content string) []string {
+	switch sourceExt(path) {
+	case ".go":
+		file, err := parser.ParseFile(token.NewFileSet(), path, content, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		var imports []string
+		for _, spec := range file.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports = append(imports, importPath)
+			}
+		}
+		return imports
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs":
+		var imports []string
+		for _, m := range scriptImport.FindAllStringSubmatch(content, -1) {
+			imports = append(imports, m[1])
+		}
+		return imports
+	case ".py":
+		var imports []string
+		for _, m := range pythonImport.FindAllStringSubmatch(content, -1) {
+			for _, module := range strings.Split(m[1], ",") {
+				if fields := strings.Fields(module); len(fields) > 0 {
+					imports = append(imports, fields[0])
+				}
+			}
+		}
+		for _, m := range pythonFrom.FindAllStringSubmatch(content, -1) {
+			imports = append(imports, m[1])
+			// "from pkg import mod" may name a submodule rather than a
+			// member; resolution drops the ones that are not files
+			for _, name := range strings.Split(m[2], ",") {
+				if fields := strings.Fields(name); len(fields) > 0 {
+					imports = append(imports, strings.TrimSuffix(m[1], ".")+"."+fields[0])
+				}
+			}
+		}
+		return imports
+	}
+	return nil
+}
+
+// Dependencies returns the project files that file (slash-separated,
+// relative to the root) imports directly. Imports of other modules and
+// packages outside the project are left out.
+func (idx *SymbolIndex) Dependencies(file string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.dependencies(file, idx.goModule())
+}
+
+// dependencies resolves file's imports. The caller holds idx.mu.
+func (idx *SymbolIndex) dependencies(file, module string) []string {
+	indexed, ok := idx.Files[file]
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]bool{file: true}
+	var deps []string
+	add := func(candidate string) bool {
+		if _, ok := idx.Files[candidate]; !ok {
+			return false
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			deps = append(deps, candidate)
+		}
+		return true
+	}
+
+	dir := path.Dir(file)
+	for _, imported := range indexed.Imports {
+		switch strings.ToLower(path.Ext(file)) {
+		case ".go":
+			// A Go import is a package: every non-test file in its directory
+			rel, ok := strings.CutPrefix(imported, module+"/")
+			if module == "" || !ok {
+				continue
+			}
+			for candidate := range idx.Files {
+				if path.Dir(candidate) == rel && strings.HasSuffix(candidate, ".go") && !strings.HasSuffix(candidate, "_test.go") {
+					add(candidate)
+				}
+			}
+		case ".py":
+			for _, candidate := range pythonModuleFiles(dir, imported) {
+				if add(candidate) {
+					break
+				}
+			}
+		default:
+			if !strings.HasPrefix(imported, ".") {
+				continue // A package from node_modules
+			}
+			base := path.Join(dir, imported)
+			for _, ext := range scriptExtensions {
+				if add(base + ext) {
+					break
+				}
+			}
+		}
+	}
+	return deps
+}
+
+// pythonModuleFiles lists the files a module name could refer to, most
+// likely first. Relative modules are resolved from dir; absolute ones
+// from the project root, then from dir for scripts importing siblings.
+func pythonModuleFiles(dir, module string) []string {
+	level := len(module) - len(strings.TrimLeft(module, "."))
+	name := strings.ReplaceAll(strings.TrimLeft(module, "."), ".", "/")
+
+	var bases []string
+	if level > 0 {
+		base := dir
+		for i := 1; i < level; i++ {
+			base = path.Dir(base)
+		}
+		bases = append(bases, base)
+	} else {
+		bases = append(bases, ".", dir)
+	}
+
+	var files []string
+	for _, base := range bases {
+		if name == "" {
+			files = append(files, path.Join(base, "__init__.py"))
+			continue
+		}
+		files = append(files, path.Join(base, name+".py"), path.Join(base, name, "__init__.py"))
+	}
+	return files
+}
+
+// goModule returns the module path declared in the project's go.mod.
+func (idx *SymbolIndex) goModule() string {
+	data, err := os.ReadFile(filepath.Join(idx.Root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+	return ""
+}
+
+// RelatedFiles returns the project files that the target files depend on,
+// directly or through other project files, nearest first. targets are
+// paths relative to the project root.
+func (cm *ContextManager) RelatedFiles(targets []string) ([]string, error) {
+	idx, err := cm.SymbolIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	module := idx.goModule()
+
+	seen := make(map[string]bool)
+	var queue []string
+	for _, target := range targets {
+		target = filepath.ToSlash(target)
+		seen[target] = true
+		queue = append(queue, target)
+	}
+
+	var related []string
+	for len(queue) > 0 && len(related) < maxRelatedFiles {
+		file := queue[0]
+		queue = queue[1:]
+		for _, dep := range idx.dependencies(file, module) {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			related = append(related, dep)
+			queue = append(queue, dep)
+		}
+	}
+	if len(related) > maxRelatedFiles {
+		related = related[:maxRelatedFiles]
+	}
+	return related, nil
+}
+
+// MentionedFiles returns the project source files that text names, by
+// their path relative to the root or by their file name.
+func (cm *ContextManager) MentionedFiles(text string) ([]string, error) {
+	entries, err := cm.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var mentioned []string
+	for _, entry := range entries {
+		if entry.IsDir || !cm.isSourceFile(entry.RelPath) {
+			continue
+		}
+		if mentions(text, filepath.ToSlash(entry.RelPath)) || mentions(text, filepath.Base(entry.RelPath)) {
+			mentioned = append(mentioned, entry.RelPath)
+		}
+	}
+	return mentioned, nil
+}
+
+// mentions reports whether text contains name not as part of a longer
+// path or word.
+func mentions(text, name string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], name)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(name)
+		if (start == 0 || !isPathChar(text[start-1])) && (end == len(text) || !isPathChar(text[end]) || text[end] == '.' && (end+1 == len(text) || !isPathChar(text[end+1]))) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isPathChar(c byte) bool {
+	return c == '/' || c == '.' || c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,298 @@
+// Package: internal/context/index.go
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const (
+	maxReferences = 200
+
+	// symbolIndexVersion is bumped when indexedFile changes, so indexes
+	// saved by older versions are rebuilt
+	symbolIndexVersion = 4
+)
+
+// SymbolIndex maps the project's symbols to where they are declared, and
+// records what each file imports. It is saved under ~/.claude-go/index, one
+// file per project, and refreshed incrementally: only files whose size or
+// modification time changed are parsed again.
+type SymbolIndex struct {
+	Version int                    `json:"version"`
+	Root    string                 `json:"root"`
+	Files   map[string]indexedFile `json:"files"` // Keyed by slash-separated path relative to Root
+
+	mu   sync.Mutex
+	path string
+}
+
+type indexedFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+	Symbols []Symbol  `json:"symbols"`
+	Imports []string  `json:"imports,omitempty"`
+}
+
+// Definition is a symbol and the file declaring it.
+type Definition struct {
+	File string
+	Symbol
+}
+
+func (d Definition) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Signature)
+}
+
+// Reference is a line mentioning a symbol.
+type Reference struct {
+	File string
+	Line int
+	Text string
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Text)
+}
+
+func indexPath(root string) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "index", fmt.Sprintf("%x.json", md5.Sum([]byte(root)))), nil
+}
+
+// loadSymbolIndex reads the saved index for root, starting an empty one if
+// there is none or it cannot be read.
+func loadSymbolIndex(root string) *SymbolIndex {
+	idx := &SymbolIndex{Version: symbolIndexVersion, Root: root, Files: make(map[string]indexedFile)}
+	path, err := indexPath(root)
+	if err != nil {
+		return idx
+	}
+	idx.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return idx
+	}
+	var saved SymbolIndex
+	if json.Unmarshal(data, &saved) == nil && saved.Version == symbolIndexVersion && saved.Root == root && saved.Files != nil {
+		idx.Files = saved.Files
+	}
+	return idx
+}
+
+// SymbolIndex returns the project's symbol index, brought up to date with
+// the files on disk.
+func (cm *ContextManager) SymbolIndex() (*SymbolIndex, error) {
+	cm.indexOnce.Do(func() {
+		cm.index = loadSymbolIndex(cm.projectRoot)
+	})
+	idx := cm.index
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []sourceFile
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		key := filepath.ToSlash(source.relPath)
+		seen[key] = true
+		if cached, ok := idx.Files[key]; !ok || cached.Size != source.entry.Size || !cached.ModTime.Equal(source.entry.ModTime) {
+			stale = append(stale, source)
+		}
+	}
+
+	// Parse the changed files on a pool of workers; files whose content
+	// hashes as before only get their new modification time
+	parsed := make([]*indexedFile, len(stale))
+	forEachParallel(len(stale), func(i int) {
+		source := stale[i]
+		content, err := ReadSource(source.path)
+		if err != nil {
+			return
+		}
+
+		file := indexedFile{Size: source.entry.Size, ModTime: source.entry.ModTime, Hash: hashContent(content)}
+		if cached, ok := idx.Files[filepath.ToSlash(source.relPath)]; ok && cached.Hash == file.Hash {
+			file.Symbols, file.Imports = cached.Symbols, cached.Imports
+		} else if IsText(content) {
+			file.Symbols = ExtractSymbols(source.path, string(content))
+			file.Imports = ExtractImports(source.path, string(content))
+		}
+		parsed[i] = &file
+	})
+
+	changed := false
+	for i, file := range parsed {
+		if file != nil {
+			idx.Files[filepath.ToSlash(stale[i].relPath)] = *file
+			changed = true
+		}
+	}
+
+	for key := range idx.Files {
+		if !seen[key] {
+			delete(idx.Files, key)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := idx.save(); err != nil {
+			return nil, fmt.Errorf("failed to save symbol index: %w", err)
+		}
+	}
+	return idx, nil
+}
+
+// save writes the index through a temporary file. The caller holds idx.mu.
+func (idx *SymbolIndex) save() error {
+	if idx.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// Definitions finds where name is declared. "Type.Method" narrows a method
+// to its receiver or enclosing type. Exact matches are returned when there
+// are any, case-insensitive ones otherwise.
+func (idx *SymbolIndex) Definitions(name string) []Definition {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	owner, member, qualified := strings.Cut(name, ".")
+	if !qualified {
+		member = name
+	}
+
+	var exact, folded []Definition
+	for file, indexed := range idx.Files {
+		for _, symbol := range indexed.Symbols {
+			if qualified && !memberOf(indexed.Symbols, symbol, owner) {
+				continue
+			}
+			switch {
+			case symbol.Name == member:
+				exact = append(exact, Definition{File: file, Symbol: symbol})
+			case strings.EqualFold(symbol.Name, member):
+				folded = append(folded, Definition{File: file, Symbol: symbol})
+			}
+		}
+	}
+
+	defs := exact
+	if len(defs) == 0 {
+		defs = folded
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].File != defs[j].File {
+			return defs[i].File < defs[j].File
+		}
+		return defs[i].Line < defs[j].Line
+	})
+	return defs
+}
+
+// memberOf reports whether symbol belongs to the type owner: by its
+// receiver for Go methods, otherwise by the nearest class-like symbol
+// declared before it.
+func memberOf(symbols []Symbol, symbol Symbol, owner string) bool {
+	if receiver, ok := strings.CutPrefix(symbol.Signature, "func ("); ok {
+		receiver, _, _ = strings.Cut(receiver, ")")
+		return regexp.MustCompile(`\b` + regexp.QuoteMeta(owner) + `\b`).MatchString(receiver)
+	}
+
+	enclosing := ""
+	for _, s := range symbols {
+		if s.Line >= symbol.Line {
+			break
+		}
+		switch s.Kind {
+		case "class", "interface", "trait", "impl", "module", "type":
+			enclosing = s.Name
+		}
+	}
+	return enclosing == owner || strings.HasSuffix(enclosing, " "+owner) || strings.HasPrefix(enclosing, owner+"<")
+}
+
+// References finds the lines in indexed files that mention name as a whole
+// word, leaving out the declarations themselves.
+func (idx *SymbolIndex) References(name string) ([]Reference, error) {
+	if _, member, qualified := strings.Cut(name, "."); qualified {
+		name = member
+	}
+	word, err := regexp.Compile(`\b` + regexp.QuoteMeta(name) + `\b`)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.Lock()
+	files := make([]string, 0, len(idx.Files))
+	declared := make(map[string]bool)
+	for file, indexed := range idx.Files {
+		files = append(files, file)
+		for _, symbol := range indexed.Symbols {
+			if symbol.Name == name {
+				declared[fmt.Sprintf("%s:%d", file, symbol.Line)] = true
+			}
+		}
+	}
+	idx.mu.Unlock()
+	sort.Strings(files)
+
+	var refs []Reference
+	for _, file := range files {
+		content, err := ReadSource(filepath.Join(idx.Root, filepath.FromSlash(file)))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := scanner.Text()
+			if !word.MatchString(line) || declared[fmt.Sprintf("%s:%d", file, lineNum)] {
+				continue
+			}
+			refs = append(refs, Reference{File: file, Line: lineNum, Text: strings.TrimSpace(line)})
+			if len(refs) == maxReferences {
+				return refs, nil
+			}
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,258 @@
+// Package: internal/context/lexical.go
+package context
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	maxLexicalFileSize = 512 * 1024
+)
+
+// stopWords are too common in code and questions to say which file a
+// question is about.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "this": true, "that": true, "with": true, "from": true,
+	"what": true, "how": true, "why": true, "does": true, "can": true, "are": true, "is": true,
+	"in": true, "of": true, "to": true, "it": true, "on": true, "be": true, "an": true, "or": true,
+	"if": true, "do": true, "we": true, "my": true, "me": true, "you": true, "please": true,
+	"return": true, "func": true, "var": true, "const": true, "import": true, "package": true,
+	"def": true, "let": true, "nil": true, "null": true, "true": true, "false": true, "err": true,
+}
+
+// lexicalIndex is an inverted index of the terms in the project's source
+// files, kept in memory and updated for changed files before each query.
+type lexicalIndex struct {
+	mu       sync.Mutex
+	docs     map[string]lexicalDoc     // Keyed by relative path
+	postings map[string]map[string]int // Term -> relative path -> term frequency
+	totalLen int
+}
+
+type lexicalDoc struct {
+	size    int64
+	modTime time.Time
+	hash    string
+	length  int
+	terms   []string
+}
+
+// LexicalScores ranks the project's source files against query with BM25.
+// Files without any of the query's terms are left out, so the result is
+// empty when nothing matches.
+func (cm *ContextManager) LexicalScores(query string) (map[string]float64, error) {
+	queryTerms := uniqueTerms(tokenize(query))
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+
+	cm.lexicalOnce.Do(func() {
+		cm.lexical = &lexicalIndex{docs: make(map[string]lexicalDoc), postings: make(map[string]map[string]int)}
+	})
+	idx := cm.lexical
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []sourceFile
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		seen[source.relPath] = true
+		if doc, ok := idx.docs[source.relPath]; !ok || doc.size != source.entry.Size || !doc.modTime.Equal(source.entry.ModTime) {
+			stale = append(stale, source)
+		}
+	}
+
+	// Read and tokenize changed files on a pool of workers, then update
+	// the index from this goroutine
+	type update struct {
+		read  bool
+		hash  string
+		terms []string
+	}
+	updates := make([]update, len(stale))
+	forEachParallel(len(stale), func(i int) {
+		source := stale[i]
+		// A notebook is mostly outputs, so its size says little about
+		// its cells'
+		if source.entry.Size > maxLexicalFileSize && !IsNotebook(source.path) {
+			return
+		}
+		content, err := ReadSource(source.path)
+		if err != nil || len(content) > maxLexicalFileSize {
+			return
+		}
+		u := update{read: true, hash: hashContent(content)}
+		if doc, ok := idx.docs[source.relPath]; ok && doc.hash == u.hash {
+			updates[i] = u // Unchanged content keeps its terms
+			return
+		}
+		if !IsText(content) {
+			content = nil // Match binary files by their path only
+		}
+		u.terms = tokenize(filepath.ToSlash(source.relPath) + "\n" + string(content))
+		updates[i] = u
+	})
+
+	for i, u := range updates {
+		source := stale[i]
+		doc, ok := idx.docs[source.relPath]
+		switch {
+		case !u.read:
+			if source.entry.Size > maxLexicalFileSize {
+				idx.remove(source.relPath)
+			}
+		case ok && doc.hash == u.hash:
+			doc.size, doc.modTime = source.entry.Size, source.entry.ModTime
+			idx.docs[source.relPath] = doc
+		default:
+			idx.remove(source.relPath)
+			idx.add(source.relPath, source.entry, u.hash, u.terms)
+		}
+	}
+	for relPath := range idx.docs {
+		if !seen[relPath] {
+			idx.remove(relPath)
+		}
+	}
+
+	return idx.score(queryTerms), nil
+}
+
+// add indexes a file's terms. The caller holds idx.mu.
+func (idx *lexicalIndex) add(relPath string, entry ProjectEntry, hash string, terms []string) {
+	freq := make(map[string]int)
+	for _, term := range terms {
+		freq[term]++
+	}
+
+	doc := lexicalDoc{size: entry.Size, modTime: entry.ModTime, hash: hash, length: len(terms)}
+	for term, n := range freq {
+		postings := idx.postings[term]
+		if postings == nil {
+			postings = make(map[string]int)
+			idx.postings[term] = postings
+		}
+		postings[relPath] = n
+		doc.terms = append(doc.terms, term)
+	}
+	idx.docs[relPath] = doc
+	idx.totalLen += doc.length
+}
+
+// remove drops a file from the index. The caller holds idx.mu.
+func (idx *lexicalIndex) remove(relPath string) {
+	doc, ok := idx.docs[relPath]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(idx.postings[term], relPath)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= doc.length
+	delete(idx.docs, relPath)
+}
+
+// score computes BM25 for every file containing a query term. The caller
+// holds idx.mu.
+func (idx *lexicalIndex) score(queryTerms []string) map[string]float64 {
+	scores := make(map[string]float64)
+	n := float64(len(idx.docs))
+	if n == 0 {
+		return scores
+	}
+	avgLen := float64(idx.totalLen) / n
+
+	for _, term := range queryTerms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for relPath, tf := range postings {
+			length := float64(idx.docs[relPath].length)
+			f := float64(tf)
+			scores[relPath] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*length/avgLen))
+		}
+	}
+	return scores
+}
+
+// tokenize splits text into lowercase terms. Identifiers are indexed whole
+// and by their camelCase and snake_case parts, so "getUserName" matches
+// questions about a "user name".
+func tokenize(text string) []string {
+	var terms []string
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			terms = appendTerm(terms, word)
+		}
+		for _, part := range parts {
+			terms = appendTerm(terms, part)
+		}
+	}
+	return terms
+}
+
+func appendTerm(terms []string, term string) []string {
+	term = strings.ToLower(term)
+	if len(term) < 2 || stopWords[term] {
+		return terms
+	}
+	return append(terms, term)
+}
+
+// splitIdentifier splits on underscores and lower-to-upper case changes,
+// keeping acronyms together: "parseHTTPRequest" is parse, HTTP, Request.
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
@@ -2,39 +2,166 @@
 package context
 
 import (
-	"crypto/md5"
+	"bytes"
+	"context"
 	"fmt"
-	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
 )
 
+// textSniffBytes is how much of a file IsText checks.
+const textSniffBytes = 8000
+
 type ContextManager struct {
 	projectRoot string
 	maxTokens   int
-	cache       map[string]*FileContext
-	lastRefresh time.Time
-	refreshTTL  time.Duration
+	cacheMu     sync.Mutex
+	cache       map[string]*FileContext // Keyed by absolute path
+	cacheLoaded bool
+	cacheDirty  map[string]bool // Entries to write or remove on the next save
+	cacheReset  bool            // Remove every entry saved first
+	saveMu      sync.Mutex
+	filter      *ContentFilter
+
+	index     *SymbolIndex
+	indexOnce sync.Once
+
+	embedModel  string
+	embed       Embedder
+	vectors     *VectorIndex
+	vectorsOnce sync.Once
+
+	lexical     *lexicalIndex
+	lexicalOnce sync.Once
+
+	historyMu sync.Mutex
+	history   *GitHistory
+
+	lspMu   sync.Mutex
+	servers []*languageServer
+
+	summarize Summarizer
+	summaryMu sync.Mutex
+	summary   *projectSummary
+
+	rootsMu sync.Mutex
+	roots   []*extraRoot
+
+	pinsMu sync.Mutex
+	pins   []string // Absolute paths, in the order they were pinned
+
+	accessMu sync.Mutex
+	access   *accessLog
+
+	// tree is kept current by watcher while the project is watched, and
+	// rebuilt once it is older than refreshTTL
+	treeMu     sync.Mutex
+	tree       map[string]ProjectEntry
+	treeBuilt  time.Time
+	refreshTTL time.Duration
+	ignore     *IgnoreMatcher
+	watcher    *fsnotify.Watcher
+
+	changeMu sync.Mutex
+	onChange []func(path string) // Called with each file the watcher sees change
+
+	symlinks string // SymlinksSkip, SymlinksFiles or SymlinksFollow; empty means SymlinksFiles
+
+	// Walks over maxFiles files or maxBytes bytes fall back to a sample
+	maxFiles      int
+	maxBytes      int64
+	sampled       bool
+	sampleWarning sync.Once
+
+	filterMu sync.Mutex
+	excluded map[string]exclusion // Content filter results by relative path
+
+	// In a monorepo the context can be limited to some member packages
+	scopeMu   sync.Mutex
+	workspace *Workspace
+	home      string   // The member the session started in
+	scope     []string // Nil for the whole project
+}
+
+// exclusion is a cached content filter result, valid while the file's size
+// and modification time are unchanged.
+type exclusion struct {
+	size     int64
+	modTime  time.Time
+	category string
 }
 
 type FileContext struct {
-	Path         string
-	Content      string
-	Size         int
-	LastModified time.Time
-	Hash         string
-	Language     string
-	TokenCount   int
+	Path string `json:"-"`
+	// Content is only set when the file was read in this run; entries
+	// restored from the on-disk cache leave it empty. Use ReadContent.
+	Content      string    `json:"-"`
+	Size         int       `json:"size"`
+	LastModified time.Time `json:"mod_time"`
+	Hash         string    `json:"hash"`
+	Language     string    `json:"language"`
+	TokenCount   int       `json:"token_count"`
+
+	// Symbols are the file's declarations, for prompts that include an
+	// outline instead of the whole file.
+	Symbols []Symbol `json:"symbols,omitempty"`
+
+	// Binary is set for files that are not UTF-8 text; they are left out
+	// of the context.
+	Binary bool `json:"binary,omitempty"`
+
+	// OutlineOnly is set on files in a ProjectContext that are represented
+	// by their outline because their content did not fit the token budget.
+	OutlineOnly bool `json:"-"`
+
+	// Root labels files of a directory added to the context with AddRoot;
+	// it is empty for the project's own files.
+	Root string `json:"-"`
+}
+
+// ReadContent returns the file's content, reading it if the entry came
+// from the on-disk cache.
+func (fc *FileContext) ReadContent() (string, error) {
+	if fc.Content != "" || fc.Size == 0 {
+		return fc.Content, nil
+	}
+	content, err := ReadSource(fc.Path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Outline renders the file's declarations one per line.
+func (fc *FileContext) Outline() string {
+	return Outline(fc.Symbols)
+}
+
+// tokens is what the file takes up in the context: its content, or its
+// outline when only that is included.
+func (fc *FileContext) tokens() int {
+	if fc.OutlineOnly {
+		return len(fc.Outline()) / 4
+	}
+	return fc.TokenCount
 }
 
 type ProjectContext struct {
+	Pinned       []FileContext // Always included in full, before Files
 	Files        []FileContext
 	Structure    string
-	Dependencies []string
+	Summary      string // What the project is, when a summarizer is set
+	Dependencies []Dependency
 	GitInfo      GitContext
+	Diagnostics  []Diagnostic // From the configured language servers
 	TotalTokens  int
 }
 
@@ -45,128 +172,243 @@ type GitContext struct {
 	RecentCommits []string
 }
 
+// DefaultRefreshTTL is how long a watched project tree is trusted before it
+// is walked again.
+const DefaultRefreshTTL = 5 * time.Minute
+
 func NewContextManager(projectRoot string, maxTokens int) *ContextManager {
 	return &ContextManager{
 		projectRoot: projectRoot,
 		maxTokens:   maxTokens,
 		cache:       make(map[string]*FileContext),
-		refreshTTL:  5 * time.Minute,
+		refreshTTL:  DefaultRefreshTTL,
+		maxFiles:    DefaultMaxFiles,
+		maxBytes:    DefaultMaxProjectBytes,
 	}
 }
 
+// SetProjectLimits sets how many files and bytes of them a project walk
+// may take in before it falls back to sampling; 0 means no limit.
+func (cm *ContextManager) SetProjectLimits(maxFiles int, maxBytes int64) {
+	cm.treeMu.Lock()
+	defer cm.treeMu.Unlock()
+	cm.maxFiles, cm.maxBytes = maxFiles, maxBytes
+}
+
+// SetRefreshTTL sets how long a watched project tree is trusted before it
+// is walked again; 0 trusts it until the watcher reports an error.
+func (cm *ContextManager) SetRefreshTTL(ttl time.Duration) {
+	cm.treeMu.Lock()
+	defer cm.treeMu.Unlock()
+	cm.refreshTTL = ttl
+}
+
+// SetContentFilter excludes files matching the filter's categories from
+// subsequent context builds.
+func (cm *ContextManager) SetContentFilter(filter *ContentFilter) {
+	cm.filter = filter
+}
+
+// GetProjectContext gathers the project's files, structure, dependencies
+// and diagnostics. Pinned files are always included and the rest share
+// what they leave of the budget. Directories added with AddRoot share half
+// the files budget; their files are labelled with their root and their structure
+// follows the project's.
 func (cm *ContextManager) GetProjectContext() (*ProjectContext, error) {
-	if time.Since(cm.lastRefresh) > cm.refreshTTL {
-		cm.refreshCache()
+	// Pinned files come out of the budget first
+	pinned := cm.PinnedFiles()
+	budget := cm.maxTokens - cm.calculateTotalTokens(pinned)
+	fileTokens := budget
+	if len(cm.Roots()) > 0 {
+		fileTokens /= 2
 	}
-
-	files, err := cm.getRelevantFiles()
+	relevant, err := cm.getRelevantFiles(max(fileTokens, 0))
 	if err != nil {
 		return nil, err
 	}
+	var files []FileContext
+	for _, file := range relevant {
+		if !cm.isPinned(file.Path) {
+			files = append(files, file)
+		}
+	}
 
 	structure, err := cm.generateProjectStructure()
 	if err != nil {
 		return nil, err
 	}
 
+	for _, root := range cm.RootContexts(max(budget-fileTokens, 0)) {
+		files = append(files, root.Files...)
+		structure += fmt.Sprintf("\n[%s] %s:\n%s", root.Label, root.Path, root.Structure)
+	}
+
 	gitInfo, err := cm.getGitContext()
 	if err != nil {
 		// Git context is optional
 		gitInfo = GitContext{}
 	}
 
-	deps, err := cm.getDependencies()
+	// Dependencies are optional; keep those from the manifests that parsed
+	deps, _ := cm.getDependencies()
+
+	diagnostics := cm.Diagnostics(context.Background())
+
+	// The summary is optional too; the structure stands in for it
+	summary, err := cm.ProjectSummary(context.Background())
 	if err != nil {
-		deps = []string{} // Dependencies are optional
+		log.Printf("Warning: %v", err)
 	}
 
-	totalTokens := cm.calculateTotalTokens(files)
+	totalTokens := cm.calculateTotalTokens(pinned) + cm.calculateTotalTokens(files)
 
 	return &ProjectContext{
+		Pinned:       pinned,
 		Files:        files,
 		Structure:    structure,
+		Summary:      summary,
 		Dependencies: deps,
 		GitInfo:      gitInfo,
+		Diagnostics:  diagnostics,
 		TotalTokens:  totalTokens,
 	}, nil
 }
 
-func (cm *ContextManager) refreshCache() {
-	cm.cache = make(map[string]*FileContext)
-	cm.lastRefresh = time.Now()
-}
+// getRelevantFiles picks the project files that fit maxTokens, most
+// recently modified first, keeping only the outline of those that don't.
+func (cm *ContextManager) getRelevantFiles(maxTokens int) ([]FileContext, error) {
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read and hash changed files in parallel, then pick them in walk
+	// order so the selection doesn't depend on which read finished first
+	contexts := make([]*FileContext, len(sources))
+	forEachParallel(len(sources), func(i int) {
+		contexts[i], _ = cm.getFileContext(sources[i].path, sources[i].entry)
+	})
 
-func (cm *ContextManager) getRelevantFiles() ([]FileContext, error) {
 	var files []FileContext
 	tokenCount := 0
 
-	err := filepath.WalkDir(cm.projectRoot, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	seen := make(map[string]bool)
+	hashes := make(map[string]bool)
+	for i, fileCtx := range contexts {
+		seen[sources[i].path] = true
+		if fileCtx == nil || fileCtx.Binary {
+			continue // Skip files we can't read as text
 		}
 
-		// Skip hidden files and directories
-		if strings.HasPrefix(d.Name(), ".") && d.Name() != ".env" {
-			if d.IsDir() {
-				return filepath.SkipDir
+		// Copies of a file already included add nothing
+		if fileCtx.Size > 0 && hashes[fileCtx.Hash] {
+			continue
+		}
+		hashes[fileCtx.Hash] = true
+
+		// Respect token limit, keeping the outline of files that don't fit
+		file := *fileCtx
+		if tokenCount+file.TokenCount > maxTokens {
+			file.OutlineOnly = true
+			if file.Outline() == "" || tokenCount+file.tokens() > maxTokens {
+				continue
 			}
-			return nil
 		}
 
-		// Skip common non-source directories
-		if d.IsDir() {
-			skipDirs := []string{"node_modules", "vendor", "target", "build", "dist", ".git"}
-			for _, skip := range skipDirs {
-				if d.Name() == skip {
-					return filepath.SkipDir
-				}
-			}
-			return nil
+		files = append(files, file)
+		tokenCount += file.tokens()
+	}
+
+	cm.saveCache(seen)
+
+	// Sort by relevance (recently modified first)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].LastModified.After(files[j].LastModified)
+	})
+
+	return files, nil
+}
+
+// walkSourceFiles calls fn for every source file that belongs in the
+// context: not hidden, ignored, in a dependency or build directory, or
+// excluded by the content filter. The content filter runs on a pool of
+// workers; fn is called from the calling goroutine in walk order.
+func (cm *ContextManager) walkSourceFiles(fn func(path, relPath string, entry ProjectEntry)) error {
+	entries, err := cm.Entries()
+	if err != nil {
+		return err
+	}
+
+	var candidates []sourceFile
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
 		}
 
+		path := filepath.Join(cm.projectRoot, entry.RelPath)
+
 		// Only include source files
 		if !cm.isSourceFile(path) {
-			return nil
+			continue
 		}
+		candidates = append(candidates, sourceFile{path: path, relPath: entry.RelPath, entry: entry})
+	}
 
-		fileCtx, err := cm.getFileContext(path)
-		if err != nil {
-			return nil // Skip files we can't read
-		}
+	excluded := make([]bool, len(candidates))
+	forEachParallel(len(candidates), func(i int) {
+		excluded[i] = cm.excludedByFilter(candidates[i].path, candidates[i].entry)
+	})
 
-		// Respect token limit
-		if tokenCount+fileCtx.TokenCount > cm.maxTokens {
-			return nil
+	for i, file := range candidates {
+		if !excluded[i] {
+			fn(file.path, file.relPath, file.entry)
 		}
+	}
+	return nil
+}
 
-		files = append(files, *fileCtx)
-		tokenCount += fileCtx.TokenCount
-
-		return nil
-	})
+// excludedByFilter applies the content filter, which may read the start of
+// the file, once per version of the file.
+func (cm *ContextManager) excludedByFilter(path string, entry ProjectEntry) bool {
+	if cm.filter == nil {
+		return false
+	}
 
-	if err != nil {
-		return nil, err
+	cm.filterMu.Lock()
+	cached, ok := cm.excluded[entry.RelPath]
+	cm.filterMu.Unlock()
+	if ok && cached.size == entry.Size && cached.modTime.Equal(entry.ModTime) {
+		return cached.category != ""
 	}
 
-	// Sort by relevance (recently modified first)
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].LastModified.After(files[j].LastModified)
-	})
+	category := cm.filter.Excluded(path, entry.RelPath, entry.Size)
 
-	return files, nil
+	cm.filterMu.Lock()
+	defer cm.filterMu.Unlock()
+	if cm.excluded == nil {
+		cm.excluded = make(map[string]exclusion)
+	}
+	cm.excluded[entry.RelPath] = exclusion{size: entry.Size, modTime: entry.ModTime, category: category}
+	return category != ""
 }
 
-func (cm *ContextManager) getFileContext(path string) (*FileContext, error) {
-	// Check cache first
-	if cached, exists := cm.cache[path]; exists {
-		stat, err := os.Stat(path)
-		if err == nil && stat.ModTime().Equal(cached.LastModified) {
-			return cached, nil
-		}
+// getFileContext returns the context for a file, reading it only when the
+// entry's size or modification time differ from the cached ones. A file
+// whose content hashes the same as the cached version keeps what was
+// extracted from it. It is safe to call from several goroutines.
+func (cm *ContextManager) getFileContext(path string, entry ProjectEntry) (*FileContext, error) {
+	cm.cacheMu.Lock()
+	cm.loadCache()
+	cached := cm.cache[path]
+	cm.cacheMu.Unlock()
+
+	if cached != nil && entry.ModTime.Equal(cached.LastModified) && int64(cached.Size) == entry.Size {
+		return cached, nil
 	}
 
-	content, err := os.ReadFile(path)
+	// Notebooks are read as their cells, so re-running one without
+	// changing its code doesn't count as a change
+	content, err := ReadSource(path)
 	if err != nil {
 		return nil, err
 	}
@@ -176,22 +418,60 @@ func (cm *ContextManager) getFileContext(path string) (*FileContext, error) {
 		return nil, err
 	}
 
-	hash := fmt.Sprintf("%x", md5.Sum(content))
-
-	fileCtx := &FileContext{
-		Path:         path,
-		Content:      string(content),
-		Size:         len(content),
-		LastModified: stat.ModTime(),
-		Hash:         hash,
-		Language:     cm.detectLanguage(path),
-		TokenCount:   cm.estimateTokens(string(content)),
+	hash := hashContent(content)
+
+	var fileCtx *FileContext
+	switch {
+	case cached != nil && cached.Hash == hash:
+		// Touched but unchanged, as after a checkout or a build step
+		unchanged := *cached
+		unchanged.LastModified = stat.ModTime()
+		unchanged.Size = int(stat.Size()) // A notebook's outputs may have changed
+		if !unchanged.Binary {
+			unchanged.Content = string(content)
+		}
+		fileCtx = &unchanged
+	case !IsText(content):
+		fileCtx = &FileContext{Path: path, Size: int(stat.Size()), LastModified: stat.ModTime(), Hash: hash, Binary: true}
+	default:
+		fileCtx = &FileContext{
+			Path:         path,
+			Content:      string(content),
+			Size:         int(stat.Size()), // Of the file on disk, which the cache is checked against
+			LastModified: stat.ModTime(),
+			Hash:         hash,
+			Language:     cm.detectLanguage(path),
+			TokenCount:   cm.estimateTokens(string(content)),
+			Symbols:      ExtractSymbols(path, string(content)),
+		}
 	}
 
+	cm.cacheMu.Lock()
 	cm.cache[path] = fileCtx
+	cm.markDirty(path)
+	cm.cacheMu.Unlock()
 	return fileCtx, nil
 }
 
+// IsText reports whether content is UTF-8 text without NUL bytes, judging
+// by its first textSniffBytes bytes.
+func IsText(content []byte) bool {
+	sample := content
+	if len(sample) > textSniffBytes {
+		sample = sample[:textSniffBytes]
+		// Don't count a character cut off by the sample as invalid
+		for i := 1; i < utf8.UTFMax; i++ {
+			if utf8.RuneStart(sample[len(sample)-i]) {
+				if !utf8.FullRune(sample[len(sample)-i:]) {
+					sample = sample[:len(sample)-i]
+				}
+				break
+			}
+		}
+	}
+	return bytes.IndexByte(sample, 0) == -1 && utf8.Valid(sample)
+}
+
 func (cm *ContextManager) isSourceFile(path string) bool {
 	sourceExts := []string{
 		".go", ".py", ".js", ".ts", ".jsx", ".tsx", ".java", ".c", ".cpp", ".h", ".hpp",
@@ -199,7 +479,7 @@ func (cm *ContextManager) isSourceFile(path string) bool {
 		".R", ".jl", ".dart", ".lua", ".sh", ".bash", ".zsh", ".fish", ".ps1",
 		".sql", ".html", ".css", ".scss", ".sass", ".less", ".vue", ".svelte",
 		".yaml", ".yml", ".json", ".toml", ".ini", ".cfg", ".conf", ".env",
-		".md", ".rst", ".txt", ".dockerfile", "Dockerfile", "Makefile",
+		".md", ".rst", ".txt", ".dockerfile", "Dockerfile", "Makefile", ".ipynb",
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
@@ -221,6 +501,7 @@ func (cm *ContextManager) detectLanguage(path string) string {
 	langMap := map[string]string{
 		".go":        "go",
 		".py":        "python",
+		".ipynb":     "jupyter",
 		".js":        "javascript",
 		".ts":        "typescript",
 		".jsx":       "javascript",
@@ -290,46 +571,22 @@ func (cm *ContextManager) estimateTokens(content string) int {
 
 func (cm *ContextManager) generateProjectStructure() (string, error) {
 	var structure strings.Builder
+	structure.WriteString(filepath.Base(cm.projectRoot) + "/\n")
 
-	err := filepath.WalkDir(cm.projectRoot, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip hidden directories except .env
-		if strings.HasPrefix(d.Name(), ".") && d.Name() != ".env" {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Skip common non-source directories
-		if d.IsDir() {
-			skipDirs := []string{"node_modules", "vendor", "target", "build", "dist", ".git"}
-			for _, skip := range skipDirs {
-				if d.Name() == skip {
-					return filepath.SkipDir
-				}
-			}
-		}
-
-		relPath, err := filepath.Rel(cm.projectRoot, path)
-		if err != nil {
-			relPath = path
-		}
-
-		depth := strings.Count(relPath, string(filepath.Separator))
+	entries, err := cm.Entries()
+	for _, entry := range entries {
+		depth := strings.Count(entry.RelPath, string(filepath.Separator))
 		indent := strings.Repeat("  ", depth)
 
-		if d.IsDir() {
-			structure.WriteString(fmt.Sprintf("%s%s/\n", indent, d.Name()))
+		if entry.IsDir {
+			structure.WriteString(fmt.Sprintf("%s%s/\n", indent, filepath.Base(entry.RelPath)))
 		} else {
-			structure.WriteString(fmt.Sprintf("%s%s\n", indent, d.Name()))
+			structure.WriteString(fmt.Sprintf("%s%s\n", indent, filepath.Base(entry.RelPath)))
 		}
-
-		return nil
-	})
+	}
+	if cm.Sampled() {
+		structure.WriteString(SampledNote)
+	}
 
 	return structure.String(), err
 }
@@ -345,40 +602,14 @@ func (cm *ContextManager) getGitContext() (GitContext, error) {
 	}, nil
 }
 
-func (cm *ContextManager) getDependencies() ([]string, error) {
-	var deps []string
-
-	// Check for Go dependencies
-	if goMod := filepath.Join(cm.projectRoot, "go.mod"); cm.fileExists(goMod) {
-		deps = append(deps, "Go project (go.mod found)")
-	}
-
-	// Check for Node.js dependencies
-	if packageJSON := filepath.Join(cm.projectRoot, "package.json"); cm.fileExists(packageJSON) {
-		deps = append(deps, "Node.js project (package.json found)")
-	}
-
-	// Check for Python dependencies
-	if requirements := filepath.Join(cm.projectRoot, "requirements.txt"); cm.fileExists(requirements) {
-		deps = append(deps, "Python project (requirements.txt found)")
-	}
-
-	if pyproject := filepath.Join(cm.projectRoot, "pyproject.toml"); cm.fileExists(pyproject) {
-		deps = append(deps, "Python project (pyproject.toml found)")
-	}
-
-	return deps, nil
-}
-
-func (cm *ContextManager) fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+func (cm *ContextManager) getDependencies() ([]Dependency, error) {
+	return ParseManifests(cm.projectRoot)
 }
 
 func (cm *ContextManager) calculateTotalTokens(files []FileContext) int {
 	total := 0
 	for _, file := range files {
-		total += file.TokenCount
+		total += file.tokens()
 	}
 	return total
 }
@@ -0,0 +1,331 @@
+// Package: internal/context/manifests.go
+package context
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dependency is a library declared in one of the project's manifests.
+type Dependency struct {
+	Name     string
+	Version  string // As written in the manifest: a version, range or specifier
+	Manifest string // go.mod, package.json, requirements.txt, pyproject.toml or Cargo.toml
+	Dev      bool   // Only needed for development, tests or builds
+}
+
+func (d Dependency) String() string {
+	return d.Requirement() + " [" + d.Manifest + "]"
+}
+
+// Requirement renders the name and version, marking development
+// dependencies.
+func (d Dependency) Requirement() string {
+	s := d.Name
+	if d.Version != "" {
+		s += " " + d.Version
+	}
+	if d.Dev {
+		s += " (dev)"
+	}
+	return s
+}
+
+var (
+	requirementName = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*(.*)$`)
+	tomlSection     = regexp.MustCompile(`^\[\[?\s*([^\]]+?)\s*\]\]?`)
+	tomlKeyValue    = regexp.MustCompile(`^([A-Za-z0-9_.-]+|"[^"]+")\s*=\s*(.*)$`)
+	tomlVersion     = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+	tomlString      = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// ParseManifests reads the dependency manifests in the project root:
+// go.mod, package.json, requirements.txt, pyproject.toml and Cargo.toml.
+// Missing manifests are skipped; the first unreadable one is returned as
+// an error along with the dependencies found in the others.
+func ParseManifests(root string) ([]Dependency, error) {
+	parsers := []struct {
+		name  string
+		parse func(data []byte) ([]Dependency, error)
+	}{
+		{"go.mod", parseGoMod},
+		{"package.json", parsePackageJSON},
+		{"requirements.txt", parseRequirements},
+		{"pyproject.toml", parsePyproject},
+		{"Cargo.toml", parseCargoToml},
+	}
+
+	var deps []Dependency
+	var firstErr error
+	for _, p := range parsers {
+		data, err := os.ReadFile(filepath.Join(root, p.name))
+		if err != nil {
+			if !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		parsed, err := p.parse(data)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		for i := range parsed {
+			parsed[i].Manifest = p.name
+		}
+		deps = append(deps, parsed...)
+	}
+	return deps, firstErr
+}
+
+// parseGoMod lists the direct requirements of a go.mod, leaving out those
+// marked // indirect.
+func parseGoMod(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	inRequire := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		indirect := strings.Contains(line, "// indirect")
+		line, _, _ = strings.Cut(line, "//")
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case line == "require (":
+			inRequire = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case strings.HasPrefix(line, "go "):
+			deps = append(deps, Dependency{Name: "go", Version: strings.TrimSpace(strings.TrimPrefix(line, "go "))})
+			continue
+		case !inRequire:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || indirect {
+			continue
+		}
+		deps = append(deps, Dependency{Name: fields[0], Version: fields[1]})
+	}
+	return deps, scanner.Err()
+}
+
+func parsePackageJSON(data []byte) ([]Dependency, error) {
+	var pkg struct {
+		Dependencies     map[string]string `json:"dependencies"`
+		PeerDependencies map[string]string `json:"peerDependencies"`
+		DevDependencies  map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, group := range []struct {
+		versions map[string]string
+		dev      bool
+	}{{pkg.Dependencies, false}, {pkg.PeerDependencies, false}, {pkg.DevDependencies, true}} {
+		names := make([]string, 0, len(group.versions))
+		for name := range group.versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, Dependency{Name: name, Version: group.versions[name], Dev: group.dev})
+		}
+	}
+	return deps, nil
+}
+
+// parseRequirements reads a pip requirements file, skipping options,
+// includes and editable or URL requirements.
+func parseRequirements(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		if dep, ok := parseRequirement(line); ok {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, scanner.Err()
+}
+
+// parseRequirement splits a PEP 508 requirement such as
+// "requests[socks]>=2.31; python_version > '3.8'" into name and version
+// specifier.
+func parseRequirement(requirement string) (Dependency, bool) {
+	requirement, _, _ = strings.Cut(requirement, ";")
+	m := requirementName.FindStringSubmatch(strings.TrimSpace(requirement))
+	if m == nil {
+		return Dependency{}, false
+	}
+	return Dependency{Name: m[1], Version: strings.Join(strings.Fields(m[3]), "")}, true
+}
+
+// parsePyproject reads PEP 621 [project] dependencies and optional
+// dependencies, and Poetry's dependency tables.
+func parsePyproject(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	forEachTomlValue(data, func(section, key, value string) {
+		switch {
+		case section == "project" && key == "dependencies":
+			for _, requirement := range tomlStrings(value) {
+				if dep, ok := parseRequirement(requirement); ok {
+					deps = append(deps, dep)
+				}
+			}
+		case section == "project.optional-dependencies":
+			for _, requirement := range tomlStrings(value) {
+				if dep, ok := parseRequirement(requirement); ok {
+					dep.Dev = true
+					deps = append(deps, dep)
+				}
+			}
+		case section == "tool.poetry.dependencies" || section == "tool.poetry.dev-dependencies" ||
+			strings.HasPrefix(section, "tool.poetry.group.") && strings.HasSuffix(section, ".dependencies"):
+			if key == "python" {
+				return
+			}
+			deps = append(deps, Dependency{Name: key, Version: tomlDependencyVersion(value), Dev: section != "tool.poetry.dependencies"})
+		}
+	})
+	return deps, nil
+}
+
+// parseCargoToml reads [dependencies], [dev-dependencies] and
+// [build-dependencies], including target-specific and per-crate tables.
+func parseCargoToml(data []byte) ([]Dependency, error) {
+	var deps []Dependency
+	tables := make(map[string]int) // [dependencies.name] tables by crate, indexing deps
+	forEachTomlValue(data, func(section, key, value string) {
+		kind := section
+		if i := strings.LastIndex(section, "."); strings.HasPrefix(section, "target.") && i >= 0 {
+			kind = section[i+1:]
+		}
+		switch kind {
+		case "dependencies", "dev-dependencies", "build-dependencies":
+			deps = append(deps, Dependency{Name: key, Version: tomlDependencyVersion(value), Dev: kind != "dependencies"})
+			return
+		}
+
+		for _, kind := range []string{"dependencies", "dev-dependencies", "build-dependencies"} {
+			name, ok := strings.CutPrefix(section, kind+".")
+			if !ok || strings.Contains(name, ".") {
+				continue
+			}
+			i, seen := tables[section]
+			if !seen {
+				i = len(deps)
+				tables[section] = i
+				deps = append(deps, Dependency{Name: name, Dev: kind != "dependencies"})
+			}
+			if key == "version" {
+				deps[i].Version = tomlDependencyVersion(value)
+			}
+		}
+	})
+	return deps, nil
+}
+
+// forEachTomlValue calls fn for every key in a TOML document with the
+// table it is in. Arrays spanning several lines are joined into one
+// value. This covers the shape of dependency manifests, not all of TOML.
+func forEachTomlValue(data []byte, fn func(section, key, value string)) {
+	section := ""
+	var pendingKey, pendingValue string
+	depth := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripTomlComment(scanner.Text()))
+		if depth > 0 {
+			pendingValue += " " + line
+			depth += strings.Count(line, "[") - strings.Count(line, "]")
+			if depth <= 0 {
+				fn(section, pendingKey, pendingValue)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if m := tomlSection.FindStringSubmatch(line); m != nil {
+			section = strings.ReplaceAll(m[1], `"`, "")
+			continue
+		}
+		m := tomlKeyValue.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key, value := strings.Trim(m[1], `"`), strings.TrimSpace(m[2])
+		if depth = strings.Count(value, "[") - strings.Count(value, "]"); depth > 0 {
+			pendingKey, pendingValue = key, value
+			continue
+		}
+		fn(section, key, value)
+	}
+}
+
+// stripTomlComment drops a trailing comment, leaving # inside strings.
+func stripTomlComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlStrings returns the strings in a TOML value, such as an array.
+func tomlStrings(value string) []string {
+	var out []string
+	for _, m := range tomlString.FindAllStringSubmatch(value, -1) {
+		out = append(out, m[1]+m[2])
+	}
+	return out
+}
+
+// tomlDependencyVersion reads "1.0" or { version = "1.0", ... }, describing
+// path and git dependencies that have no version.
+func tomlDependencyVersion(value string) string {
+	if strings.HasPrefix(value, "{") {
+		if m := tomlVersion.FindStringSubmatch(value); m != nil {
+			return m[1]
+		}
+		switch {
+		case strings.Contains(value, "path"):
+			return "(path)"
+		case strings.Contains(value, "git"):
+			return "(git)"
+		case strings.Contains(value, "workspace"):
+			return "(workspace)"
+		}
+		return ""
+	}
+	if values := tomlStrings(value); len(values) > 0 {
+		return values[0]
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+// Package: internal/context/mentions.go
+package context
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxMentionedSymbols = 5
+
+	// A name declared in more places than maxMentionDefinitions (String,
+	// New, Close) is too ambiguous to say which one the request means
+	maxMentionDefinitions = 3
+
+	maxCallSites = 5
+)
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)?`)
+
+// SymbolMention is a project symbol a request names, with where it is
+// declared and the lines that use it, calls first.
+type SymbolMention struct {
+	Name        string
+	Definitions []Definition
+	CallSites   []Reference
+}
+
+// MentionedSymbols finds the functions, methods and types of the project
+// that text names. Words only count when they look like code: in
+// backticks, followed by "(", qualified as Type.method, in camelCase or
+// snake_case, or capitalized mid-sentence.
+func (cm *ContextManager) MentionedSymbols(text string) ([]SymbolMention, error) {
+	candidates := codeIdentifiers(text)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	idx, err := cm.SymbolIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []SymbolMention
+	for _, name := range candidates {
+		member := name
+		if _, after, qualified := strings.Cut(name, "."); qualified {
+			member = after
+		}
+
+		var defs []Definition
+		for _, def := range idx.Definitions(name) {
+			if def.Name == member {
+				defs = append(defs, def)
+			}
+		}
+		if len(defs) == 0 || len(defs) > maxMentionDefinitions {
+			continue
+		}
+
+		refs, err := idx.References(name)
+		if err != nil {
+			return nil, err
+		}
+		// Comments mentioning the symbol, its own doc comment among them,
+		// are not uses
+		var uses []Reference
+		for _, ref := range refs {
+			if !isCommentLine(ref.Text) {
+				uses = append(uses, ref)
+			}
+		}
+		call := regexp.MustCompile(`\b` + regexp.QuoteMeta(member) + `\s*\(`)
+		sort.SliceStable(uses, func(i, j int) bool {
+			return call.MatchString(uses[i].Text) && !call.MatchString(uses[j].Text)
+		})
+
+		mentions = append(mentions, SymbolMention{Name: name, Definitions: defs, CallSites: uses[:min(len(uses), maxCallSites)]})
+		if len(mentions) == maxMentionedSymbols {
+			break
+		}
+	}
+	return mentions, nil
+}
+
+// codeIdentifiers returns the words of text that look like code, in order
+// and without repeats.
+func codeIdentifiers(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, loc := range identifierPattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		name := text[start:end]
+		if seen[name] || start > 0 && (text[start-1] == '/' || text[start-1] == '.') || end < len(text) && text[end] == '/' {
+			continue // Part of a path
+		}
+
+		marked := start > 0 && text[start-1] == '`' || end < len(text) && (text[end] == '`' || text[end] == '(')
+		if marked || looksLikeIdentifier(name) || capitalizedMidSentence(text, start, name) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// looksLikeIdentifier reports whether name could hardly be an English word:
+// qualified, snake_case or with a capital after its first letter.
+func looksLikeIdentifier(name string) bool {
+	if strings.Contains(name, ".") || strings.Contains(strings.Trim(name, "_"), "_") {
+		return true
+	}
+	for _, r := range name[1:] {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// capitalizedMidSentence reports whether name starts with a capital letter
+// without starting a sentence, like a type name.
+func capitalizedMidSentence(text string, start int, name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsUpper(r) || len(name) < 3 {
+		return false
+	}
+	before := strings.TrimRight(text[:start], " \t\"'(")
+	return before != "" && !strings.ContainsAny(before[len(before)-1:], ".!?:\n")
+}
+
+// isCommentLine reports whether a trimmed line starts with a comment
+// marker of one of the indexed languages.
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", ";"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
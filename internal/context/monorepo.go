@@ -0,0 +1,370 @@
+// Package: internal/context/monorepo.go
+package context
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Workspace is a monorepo: a root directory whose manifest lists member
+// packages that are developed together.
+type Workspace struct {
+	Kind    string   // go.work, pnpm, npm or cargo
+	Root    string   // Absolute path of the directory holding the manifest
+	Members []string // Member directories, slash-separated and relative to Root
+}
+
+// DetectWorkspace looks for a go.work, pnpm-workspace.yaml, package.json
+// with "workspaces" or Cargo.toml with [workspace] in dir and its parents,
+// up to the root of the git repository. It returns nil when dir is not in
+// a workspace.
+func DetectWorkspace(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ws, err := readWorkspace(dir)
+		if err != nil {
+			return nil, err
+		}
+		if ws != nil {
+			return ws, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func readWorkspace(dir string) (*Workspace, error) {
+	readers := []struct {
+		kind     string
+		manifest string
+		patterns func(data []byte) (include, exclude []string, err error)
+	}{
+		{"go.work", "go.work", goWorkPatterns},
+		{"pnpm", "pnpm-workspace.yaml", pnpmPatterns},
+		{"npm", "package.json", npmPatterns},
+		{"cargo", "Cargo.toml", cargoPatterns},
+	}
+
+	for _, r := range readers {
+		data, err := os.ReadFile(filepath.Join(dir, r.manifest))
+		if err != nil {
+			continue
+		}
+		include, exclude, err := r.patterns(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, r.manifest), err)
+		}
+		if len(include) == 0 {
+			continue
+		}
+		return &Workspace{Kind: r.kind, Root: dir, Members: expandMembers(dir, include, exclude)}, nil
+	}
+	return nil, nil
+}
+
+// expandMembers resolves member globs to the directories they match.
+func expandMembers(root string, include, exclude []string) []string {
+	excluded := make(map[string]bool)
+	for _, pattern := range exclude {
+		for _, dir := range globDirs(root, pattern) {
+			excluded[dir] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var members []string
+	for _, pattern := range include {
+		for _, dir := range globDirs(root, pattern) {
+			if dir != "." && !excluded[dir] && !seen[dir] {
+				seen[dir] = true
+				members = append(members, dir)
+			}
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+func globDirs(root, pattern string) []string {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+	// "packages/**" is treated as "packages/*": members are rarely nested
+	pattern = strings.ReplaceAll(pattern, "**", "*")
+	matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+
+	var dirs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || !info.IsDir() {
+			continue
+		}
+		if rel, err := filepath.Rel(root, match); err == nil {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+	}
+	return dirs
+}
+
+// goWorkPatterns reads the use directives of a go.work file.
+func goWorkPatterns(data []byte) ([]string, []string, error) {
+	var uses []string
+	inUse := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "use (":
+			inUse = true
+		case inUse && line == ")":
+			inUse = false
+		case inUse && line != "":
+			uses = append(uses, strings.Trim(line, `"`))
+		case strings.HasPrefix(line, "use "):
+			uses = append(uses, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	return uses, nil, scanner.Err()
+}
+
+// pnpmPatterns reads the packages list of pnpm-workspace.yaml, where
+// patterns starting with ! exclude directories.
+func pnpmPatterns(data []byte) ([]string, []string, error) {
+	var include, exclude []string
+	inPackages := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.HasPrefix(trimmed, "packages:")
+			continue
+		}
+		item, ok := strings.CutPrefix(trimmed, "- ")
+		if !inPackages || !ok {
+			continue
+		}
+		item, _, _ = strings.Cut(item, " #")
+		item = strings.Trim(strings.TrimSpace(item), `"'`)
+		if pattern, negated := strings.CutPrefix(item, "!"); negated {
+			exclude = append(exclude, pattern)
+		} else {
+			include = append(include, item)
+		}
+	}
+	return include, exclude, scanner.Err()
+}
+
+// npmPatterns reads "workspaces" from package.json, either a list of
+// patterns or Yarn's {"packages": [...]} form.
+func npmPatterns(data []byte) ([]string, []string, error) {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, nil, err
+	}
+	if len(pkg.Workspaces) == 0 {
+		return nil, nil, nil
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(pkg.Workspaces, &patterns); err == nil {
+		return patterns, nil, nil
+	}
+	var yarn struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(pkg.Workspaces, &yarn); err != nil {
+		return nil, nil, err
+	}
+	return yarn.Packages, nil, nil
+}
+
+// cargoPatterns reads members and exclude from Cargo.toml's [workspace].
+func cargoPatterns(data []byte) ([]string, []string, error) {
+	var include, exclude []string
+	forEachTomlValue(data, func(section, key, value string) {
+		if section != "workspace" {
+			return
+		}
+		switch key {
+		case "members":
+			include = tomlStrings(value)
+		case "exclude":
+			exclude = tomlStrings(value)
+		}
+	})
+	return include, exclude, nil
+}
+
+// MemberFor returns the member containing dir, or "" when dir is the
+// workspace root or outside every member.
+func (ws *Workspace) MemberFor(dir string) string {
+	rel, err := filepath.Rel(ws.Root, dir)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	best := ""
+	for _, member := range ws.Members {
+		if (rel == member || strings.HasPrefix(rel, member+"/")) && len(member) > len(best) {
+			best = member
+		}
+	}
+	return best
+}
+
+// FindMember resolves a name the user or model gave for a member: its
+// path, or the last element of its path when that is unambiguous.
+func (ws *Workspace) FindMember(name string) (string, error) {
+	name = strings.Trim(filepath.ToSlash(strings.TrimSpace(name)), "/")
+	name = strings.TrimPrefix(name, "./")
+
+	var matches []string
+	for _, member := range ws.Members {
+		if member == name {
+			return member, nil
+		}
+		if strings.HasSuffix("/"+member, "/"+name) {
+			matches = append(matches, member)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no workspace member named %q (members: %s)", name, strings.Join(ws.Members, ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q matches several workspace members: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// Root returns the directory the context is built from: the workspace
+// root in a monorepo, the project directory otherwise.
+func (cm *ContextManager) Root() string {
+	return cm.projectRoot
+}
+
+// SetWorkspace records the monorepo the project root belongs to and
+// limits the context to member, or to the whole workspace when member is
+// empty. The manager must have been created with ws.Root as its root.
+func (cm *ContextManager) SetWorkspace(ws *Workspace, member string) {
+	cm.scopeMu.Lock()
+	cm.workspace = ws
+	cm.home = member
+	cm.scope = nil
+	if member != "" {
+		cm.scope = []string{member}
+	}
+	cm.scopeMu.Unlock()
+
+	cm.resetTree()
+}
+
+// Workspace returns the monorepo the project belongs to, or nil.
+func (cm *ContextManager) Workspace() *Workspace {
+	cm.scopeMu.Lock()
+	defer cm.scopeMu.Unlock()
+	return cm.workspace
+}
+
+// Scope returns the member directories the context is limited to; nil
+// means the whole project.
+func (cm *ContextManager) Scope() []string {
+	cm.scopeMu.Lock()
+	defer cm.scopeMu.Unlock()
+	return append([]string(nil), cm.scope...)
+}
+
+// WidenScope adds a workspace member to the context, named by its path or
+// unambiguous last path element, and returns the member. "all" drops the
+// limit altogether.
+func (cm *ContextManager) WidenScope(name string) (string, error) {
+	member, err := cm.widenScope(name)
+	if err != nil {
+		return "", err
+	}
+	cm.resetTree()
+	return member, nil
+}
+
+func (cm *ContextManager) widenScope(name string) (string, error) {
+	cm.scopeMu.Lock()
+	defer cm.scopeMu.Unlock()
+
+	if cm.workspace == nil {
+		return "", fmt.Errorf("the project is not a workspace with member packages")
+	}
+	if name == "all" {
+		cm.scope = nil
+		return "", nil
+	}
+
+	member, err := cm.workspace.FindMember(name)
+	if err != nil {
+		return "", err
+	}
+	if cm.scope != nil && !slices.Contains(cm.scope, member) {
+		cm.scope = append(cm.scope, member)
+	}
+	return member, nil
+}
+
+// ResetScope limits the context to the member it started with again.
+func (cm *ContextManager) ResetScope() {
+	cm.scopeMu.Lock()
+	cm.scope = nil
+	if cm.home != "" {
+		cm.scope = []string{cm.home}
+	}
+	cm.scopeMu.Unlock()
+
+	cm.resetTree()
+}
+
+// resetTree makes the next request walk the project again, for directories
+// that were left out of the previous scope.
+func (cm *ContextManager) resetTree() {
+	cm.treeMu.Lock()
+	cm.tree = nil
+	cm.treeMu.Unlock()
+}
+
+// inScope reports whether an entry belongs in the scoped context: it is
+// inside a scoped member, a directory leading to one, or a file at the
+// root such as a shared configuration.
+func (cm *ContextManager) inScope(relPath string, isDir bool) bool {
+	cm.scopeMu.Lock()
+	defer cm.scopeMu.Unlock()
+
+	if len(cm.scope) == 0 {
+		return true
+	}
+	rel := filepath.ToSlash(relPath)
+	if !isDir && !strings.Contains(rel, "/") {
+		return true
+	}
+	for _, dir := range cm.scope {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") || isDir && strings.HasPrefix(dir, rel+"/") {
+			return true
+		}
+	}
+	return false
+}
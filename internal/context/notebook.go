@@ -0,0 +1,92 @@
+// Package: internal/context/notebook.go
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// notebook is the part of a Jupyter notebook that is read: cell sources,
+// not their outputs, which hold rendered tables and base64 images.
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"`
+	} `json:"cells"`
+}
+
+// IsNotebook reports whether path is a Jupyter notebook.
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// ReadSource reads a file as it goes into the context: notebooks as their
+// cells, everything else as is.
+func ReadSource(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil || !IsNotebook(path) {
+		return content, err
+	}
+
+	source, err := NotebookSource(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return []byte(source), nil
+}
+
+// NotebookSource renders a notebook's code and markdown cells in the
+// percent format ("# %%" before each cell, markdown as comments), which
+// reads as a Python script, leaving out outputs and attachments.
+func NotebookSource(content []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return "", fmt.Errorf("invalid notebook: %w", err)
+	}
+
+	var out strings.Builder
+	for _, cell := range nb.Cells {
+		source := cellSource(cell.Source)
+		if cell.CellType != "code" && cell.CellType != "markdown" || strings.TrimSpace(source) == "" {
+			continue // Raw cells are passed through to exports, not run
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+
+		switch cell.CellType {
+		case "code":
+			out.WriteString("# %%\n" + strings.TrimRight(source, "\n") + "\n")
+		case "markdown":
+			out.WriteString("# %% [markdown]\n")
+			for _, line := range strings.Split(strings.TrimRight(source, "\n"), "\n") {
+				out.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// sourceExt is the extension that decides how a file is parsed: notebooks
+// are parsed as the Python their cells render to.
+func sourceExt(path string) string {
+	if IsNotebook(path) {
+		return ".py"
+	}
+	return strings.ToLower(filepath.Ext(path))
+}
+
+// cellSource joins a cell's source, which nbformat allows as a string or
+// a list of lines.
+func cellSource(raw json.RawMessage) string {
+	var lines []string
+	if json.Unmarshal(raw, &lines) == nil {
+		return strings.Join(lines, "")
+	}
+	var source string
+	json.Unmarshal(raw, &source)
+	return source
+}
@@ -0,0 +1,85 @@
+// Package: internal/context/outline.go
+package context
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxOutlineImports = 20
+
+// FileOutline summarizes a file that is too large to include: what it
+// imports, and the symbols it exports with the first sentence of their doc
+// comments. Files without a notion of exports list all their symbols. It
+// returns "" when neither imports nor symbols were found.
+func FileOutline(path, content string) string {
+	var out strings.Builder
+
+	imports := ExtractImports(path, content)
+	if len(imports) > 0 {
+		shown := imports[:min(len(imports), maxOutlineImports)]
+		out.WriteString("  imports: " + strings.Join(shown, ", "))
+		if len(imports) > len(shown) {
+			out.WriteString(fmt.Sprintf(" and %d more", len(imports)-len(shown)))
+		}
+		out.WriteString("\n")
+	}
+
+	symbols := ExtractSymbols(path, content)
+	exported := exportedSymbols(path, symbols)
+	out.WriteString(Outline(exported))
+	if hidden := len(symbols) - len(exported); hidden > 0 {
+		out.WriteString(fmt.Sprintf("  (%d unexported declarations left out)\n", hidden))
+	}
+
+	if len(imports) == 0 && len(symbols) == 0 {
+		return ""
+	}
+	return out.String()
+}
+
+// exportedSymbols guesses which symbols are part of a file's public API
+// from the conventions of its language. Methods go with the type or class
+// declared before them.
+func exportedSymbols(path string, symbols []Symbol) []Symbol {
+	ext := sourceExt(path)
+	script := ext == ".js" || ext == ".jsx" || ext == ".ts" || ext == ".tsx" || ext == ".mjs"
+
+	var exported []Symbol
+	ownerExported := true
+	for _, symbol := range symbols {
+		var ok bool
+		switch {
+		case ext == ".go":
+			r, _ := utf8.DecodeRuneInString(symbol.Name)
+			ok = unicode.IsUpper(r)
+		case ext == ".py":
+			ok = !strings.HasPrefix(symbol.Name, "_") || strings.HasPrefix(symbol.Name, "__") && strings.HasSuffix(symbol.Name, "__")
+		case script:
+			ok = strings.HasPrefix(symbol.Signature, "export ")
+		case ext == ".rs":
+			ok = strings.HasPrefix(symbol.Signature, "pub")
+		case ext == ".java" || ext == ".cs" || ext == ".scala":
+			ok = !strings.Contains(symbol.Signature, "private ")
+		default:
+			ok = true
+		}
+
+		switch {
+		case symbol.Kind == "impl":
+			ok = true
+		case symbol.Kind == "method" && script:
+			ok = ownerExported // Class members carry no export of their own
+		case symbol.Kind == "method" && ext != ".go":
+			ok = ok && ownerExported
+		case symbol.Kind != "method":
+			ownerExported = ok
+		}
+		if ok {
+			exported = append(exported, symbol)
+		}
+	}
+	return exported
+}
@@ -0,0 +1,118 @@
+// Package: internal/context/pins.go
+package context
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Pin keeps a project file, such as an API schema, a style guide or the
+// core types, in every context in full, whatever its recency or relevance.
+// Pinned files are read even when the include globs or the content filter
+// would leave them out. It returns the path relative to the project root.
+func (cm *ContextManager) Pin(path string) (string, error) {
+	abs, relPath, err := cm.projectFile(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; pin the files in it", path)
+	}
+	content, err := ReadSource(abs)
+	if err != nil {
+		return "", err
+	}
+	if !IsText(content) {
+		return "", fmt.Errorf("%s looks like a binary file", path)
+	}
+
+	cm.pinsMu.Lock()
+	defer cm.pinsMu.Unlock()
+	if !slices.Contains(cm.pins, abs) {
+		cm.pins = append(cm.pins, abs)
+	}
+	return relPath, nil
+}
+
+// Unpin stops keeping a file in every context. It returns the path
+// relative to the project root, or an error when the file was not pinned.
+func (cm *ContextManager) Unpin(path string) (string, error) {
+	abs, relPath, err := cm.projectFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	cm.pinsMu.Lock()
+	defer cm.pinsMu.Unlock()
+	i := slices.Index(cm.pins, abs)
+	if i == -1 {
+		return "", fmt.Errorf("%s is not pinned", relPath)
+	}
+	cm.pins = slices.Delete(cm.pins, i, i+1)
+	return relPath, nil
+}
+
+// Pinned lists the pinned files relative to the project root, in the
+// order they were pinned.
+func (cm *ContextManager) Pinned() []string {
+	cm.pinsMu.Lock()
+	defer cm.pinsMu.Unlock()
+
+	var pinned []string
+	for _, abs := range cm.pins {
+		relPath, _ := filepath.Rel(cm.projectRoot, abs)
+		pinned = append(pinned, relPath)
+	}
+	return pinned
+}
+
+// PinnedFiles reads the pinned files. Files that were deleted or can no
+// longer be read are left out until they are back.
+func (cm *ContextManager) PinnedFiles() []FileContext {
+	cm.pinsMu.Lock()
+	pins := slices.Clone(cm.pins)
+	cm.pinsMu.Unlock()
+
+	var files []FileContext
+	for _, abs := range pins {
+		info, err := os.Stat(abs)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		relPath, _ := filepath.Rel(cm.projectRoot, abs)
+		fileCtx, err := cm.getFileContext(abs, ProjectEntry{RelPath: relPath, Size: info.Size(), ModTime: info.ModTime()})
+		if err != nil || fileCtx.Binary {
+			continue
+		}
+		files = append(files, *fileCtx)
+	}
+	return files
+}
+
+// isPinned reports whether the file at the absolute path abs is pinned.
+func (cm *ContextManager) isPinned(abs string) bool {
+	cm.pinsMu.Lock()
+	defer cm.pinsMu.Unlock()
+	return slices.Contains(cm.pins, abs)
+}
+
+// projectFile resolves path (absolute, or relative to the working
+// directory) to a file of the project.
+func (cm *ContextManager) projectFile(path string) (abs, relPath string, err error) {
+	abs, err = filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	relPath, err = filepath.Rel(cm.projectRoot, abs)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) || relPath == ".." {
+		return "", "", fmt.Errorf("%s is not in the project at %s", path, cm.projectRoot)
+	}
+	return abs, relPath, nil
+}
@@ -0,0 +1,119 @@
+// Package: internal/context/roots.go
+package context
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ContextRoot is a directory added to the context next to the project,
+// such as a shared library or a sibling service.
+type ContextRoot struct {
+	Label string // Names the root in the context: its directory name
+	Path  string // Absolute
+}
+
+// RootContext is what an added root contributes to the context.
+type RootContext struct {
+	ContextRoot
+	Structure string
+	Files     []FileContext
+}
+
+type extraRoot struct {
+	ContextRoot
+	cm *ContextManager
+}
+
+// AddRoot adds a directory to the context. Its files are read with the
+// project's content filter, limits and symlink policy but kept apart,
+// labelled with the directory's name. Directories overlapping the project
+// or a root already added are refused.
+func (cm *ContextManager) AddRoot(path string) (ContextRoot, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ContextRoot{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return ContextRoot{}, err
+	}
+	if !info.IsDir() {
+		return ContextRoot{}, fmt.Errorf("%s is not a directory", path)
+	}
+
+	cm.rootsMu.Lock()
+	defer cm.rootsMu.Unlock()
+
+	if overlaps(abs, cm.projectRoot) {
+		return ContextRoot{}, fmt.Errorf("%s overlaps the project at %s", abs, cm.projectRoot)
+	}
+	labels := make(map[string]bool)
+	for _, root := range cm.roots {
+		if overlaps(abs, root.Path) {
+			return ContextRoot{}, fmt.Errorf("%s overlaps %s, already in the context as %s", abs, root.Path, root.Label)
+		}
+		labels[root.Label] = true
+	}
+
+	label := filepath.Base(abs)
+	for n := 2; labels[label]; n++ {
+		label = fmt.Sprintf("%s-%d", filepath.Base(abs), n)
+	}
+
+	child := NewContextManager(abs, cm.maxTokens)
+	child.filter = cm.filter
+	cm.treeMu.Lock()
+	child.refreshTTL, child.maxFiles, child.maxBytes, child.symlinks = cm.refreshTTL, cm.maxFiles, cm.maxBytes, cm.symlinks
+	cm.treeMu.Unlock()
+
+	root := ContextRoot{Label: label, Path: abs}
+	cm.roots = append(cm.roots, &extraRoot{ContextRoot: root, cm: child})
+	return root, nil
+}
+
+// overlaps reports whether either directory contains the other.
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+string(filepath.Separator)) || strings.HasPrefix(b, a+string(filepath.Separator))
+}
+
+// Roots lists the directories added to the context, in the order they
+// were added.
+func (cm *ContextManager) Roots() []ContextRoot {
+	cm.rootsMu.Lock()
+	defer cm.rootsMu.Unlock()
+
+	roots := make([]ContextRoot, len(cm.roots))
+	for i, root := range cm.roots {
+		roots[i] = root.ContextRoot
+	}
+	return roots
+}
+
+// RootContexts reads the added roots, sharing maxTokens between them
+// equally. Files are labelled with their root; roots that can't be read
+// are left out.
+func (cm *ContextManager) RootContexts(maxTokens int) []RootContext {
+	cm.rootsMu.Lock()
+	roots := append([]*extraRoot(nil), cm.roots...)
+	cm.rootsMu.Unlock()
+	if len(roots) == 0 {
+		return nil
+	}
+
+	var contexts []RootContext
+	for _, root := range roots {
+		files, err := root.cm.getRelevantFiles(maxTokens / len(roots))
+		if err != nil {
+			continue
+		}
+		for i := range files {
+			files[i].Root = root.Label
+		}
+		structure, _ := root.cm.generateProjectStructure()
+		contexts = append(contexts, RootContext{ContextRoot: root.ContextRoot, Structure: structure, Files: files})
+	}
+	return contexts
+}
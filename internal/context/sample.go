@@ -0,0 +1,142 @@
+// Package: internal/context/sample.go
+package context
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultMaxFiles and DefaultMaxProjectBytes bound a project walk; past
+	// either, the project is sampled
+	DefaultMaxFiles        = 20000
+	DefaultMaxProjectBytes = 2048 << 20
+
+	// maxSampledDirFiles keeps one huge directory (datasets, fixtures,
+	// generated code) from taking the whole sample
+	maxSampledDirFiles = 100
+)
+
+// SampledNote ends the structure of a sampled project.
+const SampledNote = "(The project is too large to list in full; this is a breadth-first sample.)\n"
+
+// errProjectTooLarge stops a walk that went over the project limits.
+var errProjectTooLarge = errors.New("project too large")
+
+func (cm *ContextManager) overLimits(files int, bytes int64) bool {
+	return cm.maxFiles > 0 && files > cm.maxFiles || cm.maxBytes > 0 && bytes > cm.maxBytes
+}
+
+// Sampled reports whether the project was too large to walk in full, so
+// its entries are a breadth-first sample.
+func (cm *ContextManager) Sampled() bool {
+	cm.treeMu.Lock()
+	defer cm.treeMu.Unlock()
+	return cm.sampled
+}
+
+// sampleProject lists the project breadth-first, so the directories near
+// the root are all represented, taking at most maxSampledDirFiles files
+// from each directory, until the limits are reached. Directories are
+// listed even when none of their files could be taken. The caller holds
+// treeMu.
+func (cm *ContextManager) sampleProject(ignore *IgnoreMatcher, addDir func(path string) error) ([]ProjectEntry, error) {
+	var entries []ProjectEntry
+	var files int
+	var bytes int64
+
+	visited := cm.visitedRoots()
+	queue := []string{"."}
+	for len(queue) > 0 && !cm.overLimits(files+1, bytes) {
+		dir := queue[0]
+		queue = queue[1:]
+
+		path := filepath.Join(cm.projectRoot, dir)
+		if addDir != nil {
+			if err := addDir(path); err != nil {
+				return nil, err
+			}
+		}
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			if dir == "." {
+				return nil, err
+			}
+			continue
+		}
+
+		taken := 0
+		for _, d := range dirEntries {
+			relPath := filepath.Join(dir, d.Name())
+			isDir := d.IsDir()
+			var info fs.FileInfo
+			if isLink(filepath.Join(path, d.Name()), d) {
+				var ok bool
+				if info, _, ok = cm.followLink(filepath.Join(path, d.Name()), visited); !ok {
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if skipEntry(d.Name(), relPath, isDir, ignore) || isDir && !cm.inScope(relPath, true) {
+				continue
+			}
+			if info == nil {
+				if info, err = d.Info(); err != nil {
+					continue
+				}
+			}
+
+			if isDir {
+				queue = append(queue, relPath)
+			} else {
+				if taken == maxSampledDirFiles || cm.overLimits(files+1, bytes+info.Size()) {
+					continue
+				}
+				taken++
+				files++
+				bytes += info.Size()
+			}
+			entries = append(entries, ProjectEntry{RelPath: relPath, IsDir: isDir, Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return walkOrder(entries[i].RelPath, entries[j].RelPath) })
+	cm.sampled = true
+	cm.sampleWarning.Do(func() { cm.warnSampled(entries, files) })
+	return entries, nil
+}
+
+// warnSampled tells the user the context is incomplete and how to point it
+// at the part of the project that matters.
+func (cm *ContextManager) warnSampled(entries []ProjectEntry, files int) {
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir && !strings.ContainsRune(entry.RelPath, filepath.Separator) {
+			dirs = append(dirs, fmt.Sprintf("%q", filepath.ToSlash(entry.RelPath)+"/"))
+			if len(dirs) == 2 {
+				break
+			}
+		}
+	}
+	example := `"src/"`
+	if len(dirs) > 0 {
+		example = strings.Join(dirs, ", ")
+	}
+
+	var limits []string
+	if cm.maxFiles > 0 {
+		limits = append(limits, fmt.Sprintf("%d files (context.max_files)", cm.maxFiles))
+	}
+	if cm.maxBytes > 0 {
+		limits = append(limits, fmt.Sprintf("%d MB (context.max_project_mb)", cm.maxBytes>>20))
+	}
+
+	log.Printf("Warning: %s is larger than %s; the context covers a breadth-first sample of %d files. Set context.include to the directories you work in, e.g. \"include\": [%s]",
+		cm.projectRoot, strings.Join(limits, " or "), files, example)
+}
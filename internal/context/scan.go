@@ -0,0 +1,74 @@
+// Package: internal/context/scan.go
+package context
+
+import (
+	"crypto/md5"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+const (
+	// Reading and parsing files is mostly waiting on the disk, so the pool
+	// is larger than the number of CPUs but bounded to keep the number of
+	// open files and contents in memory down
+	minScanWorkers = 4
+	maxScanWorkers = 16
+)
+
+// scanWorkers is how many files are read at once.
+func scanWorkers() int {
+	return min(max(runtime.NumCPU()*2, minScanWorkers), maxScanWorkers)
+}
+
+// forEachParallel calls fn for 0 through n-1 on a bounded pool of workers,
+// returning once every call is done. fn must only write to state of its
+// own index or guard what it shares.
+func forEachParallel(n int, fn func(i int)) {
+	workers := min(scanWorkers(), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// sourceFile is a file found by walkSourceFiles.
+type sourceFile struct {
+	path    string
+	relPath string
+	entry   ProjectEntry
+}
+
+// sourceFiles lists the files walkSourceFiles visits, in the same order.
+func (cm *ContextManager) sourceFiles() ([]sourceFile, error) {
+	var files []sourceFile
+	err := cm.walkSourceFiles(func(path, relPath string, entry ProjectEntry) {
+		files = append(files, sourceFile{path: path, relPath: relPath, entry: entry})
+	})
+	return files, err
+}
+
+// hashContent identifies a file's content, so a file whose modification
+// time changed but whose content did not is not processed again.
+func hashContent(content []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(content))
+}
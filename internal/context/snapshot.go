@@ -0,0 +1,205 @@
+// Package: internal/context/snapshot.go
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is bumped when the snapshot format changes.
+const snapshotVersion = 1
+
+// snapshot is a portable copy of what is known about a project: the
+// file cache (outlines, languages, token counts), the symbol index, the
+// embedded chunks and the project summary. Paths are relative to the
+// project root, so it can be loaded from another checkout.
+type snapshot struct {
+	Version      int                     `json:"version"`
+	CacheVersion int                     `json:"cache_version"`
+	IndexVersion int                     `json:"index_version"`
+	Project      string                  `json:"project"` // The project directory's name
+	Created      time.Time               `json:"created"`
+	Structure    string                  `json:"structure"`
+	Summary      string                  `json:"summary,omitempty"`
+	SummaryHash  string                  `json:"summary_hash,omitempty"`
+	Files        map[string]*FileContext `json:"files"`
+	Index        map[string]indexedFile  `json:"index"`
+	Model        string                  `json:"embedding_model,omitempty"`
+	Vectors      map[string]vectorFile   `json:"vectors,omitempty"`
+}
+
+// ExportSnapshot brings the file cache and symbol index up to date and
+// writes them to path with the project structure, the cached project
+// summary and the embedded chunks, for ImportSnapshot to load elsewhere.
+func (cm *ContextManager) ExportSnapshot(path string) error {
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return err
+	}
+	contexts := make([]*FileContext, len(sources))
+	forEachParallel(len(sources), func(i int) {
+		contexts[i], _ = cm.getFileContext(sources[i].path, sources[i].entry)
+	})
+
+	snap := snapshot{
+		Version:      snapshotVersion,
+		CacheVersion: contextCacheVersion,
+		IndexVersion: symbolIndexVersion,
+		Project:      filepath.Base(cm.projectRoot),
+		Created:      time.Now(),
+		Files:        make(map[string]*FileContext),
+	}
+	seen := make(map[string]bool)
+	for i, fileCtx := range contexts {
+		seen[sources[i].path] = true
+		if fileCtx != nil {
+			snap.Files[filepath.ToSlash(sources[i].relPath)] = fileCtx
+		}
+	}
+	cm.saveCache(seen)
+
+	if snap.Structure, err = cm.generateProjectStructure(); err != nil {
+		return err
+	}
+
+	// The summary and vectors are exported as cached; writing them would
+	// take the model
+	cm.summaryMu.Lock()
+	if cm.summary == nil {
+		cm.summary = loadProjectSummary(cm.projectRoot)
+	}
+	if cm.summary != nil {
+		snap.Summary, snap.SummaryHash = cm.summary.Summary, cm.summary.Hash
+	}
+	cm.summaryMu.Unlock()
+
+	if cm.embedModel != "" {
+		vectors := cm.vectorIndex()
+		vectors.mu.Lock()
+		defer vectors.mu.Unlock()
+		snap.Model, snap.Vectors = cm.embedModel, vectors.Files
+	}
+
+	idx, err := cm.SymbolIndex()
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	snap.Index = idx.Files
+	data, err := json.Marshal(snap)
+	idx.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ImportSnapshot loads a snapshot written by ExportSnapshot into the
+// caches. Each file's entries are only taken when the file's content
+// hashes the same as when it was exported, so files changed since are
+// parsed and embedded as usual. It returns how many of the project's
+// files the snapshot covered.
+func (cm *ContextManager) ImportSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion || snap.CacheVersion != contextCacheVersion || snap.IndexVersion != symbolIndexVersion {
+		return 0, fmt.Errorf("the snapshot was written by a different version of claude-go; export it again")
+	}
+
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	// Hash the project's files against the snapshot on a pool of workers
+	matched := make([]bool, len(sources))
+	forEachParallel(len(sources), func(i int) {
+		saved := snap.Files[filepath.ToSlash(sources[i].relPath)]
+		if saved == nil {
+			return
+		}
+		content, err := ReadSource(sources[i].path)
+		matched[i] = err == nil && hashContent(content) == saved.Hash
+	})
+
+	cm.indexOnce.Do(func() {
+		cm.index = loadSymbolIndex(cm.projectRoot)
+	})
+	idx := cm.index
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var vectors *VectorIndex
+	if cm.embedModel != "" && snap.Model == cm.embedModel {
+		vectors = cm.vectorIndex()
+		vectors.mu.Lock()
+		defer vectors.mu.Unlock()
+	}
+
+	cm.cacheMu.Lock()
+	cm.loadCache()
+	imported := 0
+	for i, source := range sources {
+		if !matched[i] {
+			continue
+		}
+		key := filepath.ToSlash(source.relPath)
+		fileCtx := *snap.Files[key]
+		fileCtx.Path = source.path
+		fileCtx.Size, fileCtx.LastModified = int(source.entry.Size), source.entry.ModTime
+		cm.cache[source.path] = &fileCtx
+		cm.markDirty(source.path)
+		imported++
+
+		if file, ok := snap.Index[key]; ok && file.Hash == fileCtx.Hash {
+			file.Size, file.ModTime = source.entry.Size, source.entry.ModTime
+			idx.Files[key] = file
+		}
+		if file, ok := snap.Vectors[key]; ok && vectors != nil {
+			file.Size, file.ModTime = source.entry.Size, source.entry.ModTime
+			vectors.Files[key] = file
+		}
+	}
+	cm.cacheMu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		seen[source.path] = true
+	}
+	cm.saveCache(seen)
+
+	if imported > 0 {
+		if err := idx.save(); err != nil {
+			return imported, fmt.Errorf("failed to save symbol index: %w", err)
+		}
+		if vectors != nil {
+			if err := vectors.save(); err != nil {
+				return imported, fmt.Errorf("failed to save vector index: %w", err)
+			}
+		}
+	}
+
+	// The summary keeps its hash, so it is only used while the material
+	// it was written from is unchanged
+	if snap.Summary != "" {
+		cm.summaryMu.Lock()
+		cm.summary = &projectSummary{Root: cm.projectRoot, Hash: snap.SummaryHash, Summary: snap.Summary}
+		cm.summary.save()
+		cm.summaryMu.Unlock()
+	}
+	return imported, nil
+}
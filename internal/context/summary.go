@@ -0,0 +1,183 @@
+// Package: internal/context/summary.go
+package context
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Only directories this deep describe the layout; files come and go
+	// too often to regenerate the summary for each
+	summaryDirDepth = 3
+
+	maxSummaryReadme = 4000
+	maxSummaryDeps   = 40
+)
+
+// Summarizer writes a short description of a project from material about
+// it: its layout, languages, dependencies and README.
+type Summarizer func(ctx context.Context, material string) (string, error)
+
+type projectSummary struct {
+	Root    string `json:"root"`
+	Hash    string `json:"hash"` // Of the material the summary was written from
+	Summary string `json:"summary"`
+}
+
+// SetSummarizer turns on the project summary.
+func (cm *ContextManager) SetSummarizer(summarize Summarizer) {
+	cm.summarize = summarize
+}
+
+// ProjectSummary returns a one-page description of what the project is.
+// It is written once by the summarizer and kept in ~/.claude-go/cache until
+// the project's directories, languages, dependencies or README change. It
+// returns "" without a summarizer.
+func (cm *ContextManager) ProjectSummary(ctx context.Context) (string, error) {
+	if cm.summarize == nil {
+		return "", nil
+	}
+
+	material, err := cm.summaryMaterial()
+	if err != nil {
+		return "", err
+	}
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(material)))
+
+	cm.summaryMu.Lock()
+	defer cm.summaryMu.Unlock()
+
+	if cm.summary == nil {
+		cm.summary = loadProjectSummary(cm.projectRoot)
+	}
+	if cm.summary != nil && cm.summary.Hash == hash {
+		return cm.summary.Summary, nil
+	}
+
+	text, err := cm.summarize(ctx, material)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize the project: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("failed to summarize the project: empty summary")
+	}
+
+	cm.summary = &projectSummary{Root: cm.projectRoot, Hash: hash, Summary: text}
+	cm.summary.save()
+	return text, nil
+}
+
+// summaryMaterial describes the project for the summarizer from what
+// rarely changes.
+func (cm *ContextManager) summaryMaterial() (string, error) {
+	entries, err := cm.Entries()
+	if err != nil {
+		return "", err
+	}
+
+	var material strings.Builder
+	material.WriteString("Project: " + filepath.Base(cm.projectRoot) + "\n\nDirectories:\n")
+	languages := make(map[string]int)
+	for _, entry := range entries {
+		if !entry.IsDir {
+			if cm.isSourceFile(entry.RelPath) {
+				languages[cm.detectLanguage(entry.RelPath)]++
+			}
+			continue
+		}
+		if depth := strings.Count(entry.RelPath, string(filepath.Separator)); depth < summaryDirDepth {
+			material.WriteString(strings.Repeat("  ", depth) + filepath.Base(entry.RelPath) + "/\n")
+		}
+	}
+
+	// Languages by how many files use them, without the counts
+	var names []string
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if languages[names[i]] != languages[names[j]] {
+			return languages[names[i]] > languages[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	material.WriteString("\nLanguages: " + strings.Join(names, ", ") + "\n")
+
+	if deps, _ := ParseManifests(cm.projectRoot); len(deps) > 0 {
+		material.WriteString("\nDependencies:\n")
+		for i, dep := range deps {
+			if i == maxSummaryDeps {
+				material.WriteString(fmt.Sprintf("... and %d more\n", len(deps)-i))
+				break
+			}
+			material.WriteString(dep.String() + "\n")
+		}
+	}
+
+	for _, name := range []string{"README.md", "README", "README.rst", "README.txt"} {
+		data, err := os.ReadFile(filepath.Join(cm.projectRoot, name))
+		if err != nil {
+			continue
+		}
+		readme := string(data)
+		if len(readme) > maxSummaryReadme {
+			readme = readme[:maxSummaryReadme] + "\n..."
+		}
+		material.WriteString("\n" + name + ":\n" + readme + "\n")
+		break
+	}
+	return material.String(), nil
+}
+
+func projectSummaryPath(root string) (string, error) {
+	path, err := contextCachePath(root)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json") + ".summary.json", nil
+}
+
+func loadProjectSummary(root string) *projectSummary {
+	path, err := projectSummaryPath(root)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var saved projectSummary
+	if json.Unmarshal(data, &saved) != nil || saved.Root != root {
+		return nil
+	}
+	return &saved
+}
+
+// save writes the summary to the cache; failing to only costs regenerating
+// it next run.
+func (s *projectSummary) save() {
+	path, err := projectSummaryPath(s.Root)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0755) != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, path)
+	}
+}
@@ -0,0 +1,336 @@
+// Package: internal/context/symbols.go
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxSignatureChars = 160
+	maxDocChars       = 120
+	maxOutlineSymbols = 40
+)
+
+// Symbol is a declaration found in a file: a function, method or type.
+type Symbol struct {
+	Kind      string `json:"kind"` // func, method, type, class, interface, enum, trait, impl, module
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Line      int    `json:"line"`
+	Doc       string `json:"doc,omitempty"` // First sentence of the doc comment
+}
+
+// symbolPattern matches a declaration line; the "name" group is the symbol.
+type symbolPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+func patterns(specs ...string) []symbolPattern {
+	var out []symbolPattern
+	for i := 0; i+1 < len(specs); i += 2 {
+		out = append(out, symbolPattern{kind: specs[i], re: regexp.MustCompile(specs[i+1])})
+	}
+	return out
+}
+
+var (
+	pythonSymbols = patterns(
+		"class", `^\s*class\s+(?P<name>\w+)`,
+		"func", `^\s*(?:async\s+)?def\s+(?P<name>\w+)\s*\(`,
+	)
+	scriptSymbols = patterns(
+		"class", `^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(?P<name>\w+)`,
+		"interface", `^\s*(?:export\s+)?interface\s+(?P<name>\w+)`,
+		"type", `^\s*(?:export\s+)?type\s+(?P<name>\w+)\s*(?:<[^=]*>)?\s*=`,
+		"enum", `^\s*(?:export\s+)?(?:const\s+)?enum\s+(?P<name>\w+)`,
+		"func", `^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(?P<name>\w+)\s*[(<]`,
+		"func", `^\s*(?:export\s+)?(?:const|let|var)\s+(?P<name>\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:\([^)]*\)|\w+)\s*(?::[^=]+)?=>`,
+		"method", `^\s+(?:(?:public|private|protected|static|async|readonly|override|get|set)\s+)*(?P<name>[A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\([^)]*\)\s*(?::\s*[^{;]+)?\{\s*$`,
+	)
+	rustSymbols = patterns(
+		"func", `^\s*(?:pub(?:\([\w:]+\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"\w+"\s+)?fn\s+(?P<name>\w+)`,
+		"type", `^\s*(?:pub(?:\([\w:]+\))?\s+)?(?:struct|union|type)\s+(?P<name>\w+)`,
+		"enum", `^\s*(?:pub(?:\([\w:]+\))?\s+)?enum\s+(?P<name>\w+)`,
+		"trait", `^\s*(?:pub(?:\([\w:]+\))?\s+)?(?:unsafe\s+)?trait\s+(?P<name>\w+)`,
+		"impl", `^\s*(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?P<name>[\w:<>, ]+?)\s*(?:\{|where|$)`,
+	)
+	javaSymbols = patterns(
+		"class", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|sealed|open|data|partial)\s+)*(?:class|record|object)\s+(?P<name>\w+)`,
+		"interface", `^\s*(?:(?:public|private|protected|internal|static|sealed)\s+)*interface\s+(?P<name>\w+)`,
+		"enum", `^\s*(?:(?:public|private|protected|internal|static)\s+)*enum\s+(?:class\s+)?(?P<name>\w+)`,
+		"func", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|suspend|async|virtual)\s+)*fun\s+(?:<[^>]*>\s*)?(?:\w+\.)?(?P<name>\w+)\s*\(`,
+		"method", `^\s*(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|async|virtual)\s+)+[\w<>\[\],.? ]+\s+(?P<name>\w+)\s*\([^;]*$`,
+	)
+	cSymbols = patterns(
+		"type", `^(?:typedef\s+)?(?:struct|union|enum|class)\s+(?P<name>\w+)\s*(?:[:{]|$)`,
+		"func", `^(?:[\w*&:<>,]+\s+)+\**(?P<name>[\w:~]+)\s*\([^;]*$`,
+	)
+	rubySymbols = patterns(
+		"module", `^\s*module\s+(?P<name>[\w:]+)`,
+		"class", `^\s*class\s+(?P<name>[\w:]+)`,
+		"func", `^\s*def\s+(?P<name>[\w.?!=]+)`,
+	)
+	phpSymbols = patterns(
+		"class", `^\s*(?:(?:abstract|final)\s+)?class\s+(?P<name>\w+)`,
+		"interface", `^\s*interface\s+(?P<name>\w+)`,
+		"trait", `^\s*trait\s+(?P<name>\w+)`,
+		"func", `^\s*(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+&?(?P<name>\w+)\s*\(`,
+	)
+	shellSymbols = patterns(
+		"func", `^\s*(?:function\s+)?(?P<name>[\w-]+)\s*\(\)\s*\{?`,
+	)
+)
+
+var symbolPatterns = map[string][]symbolPattern{
+	".py":    pythonSymbols,
+	".js":    scriptSymbols,
+	".jsx":   scriptSymbols,
+	".ts":    scriptSymbols,
+	".tsx":   scriptSymbols,
+	".mjs":   scriptSymbols,
+	".rs":    rustSymbols,
+	".java":  javaSymbols,
+	".kt":    javaSymbols,
+	".cs":    javaSymbols,
+	".scala": javaSymbols,
+	".c":     cSymbols,
+	".h":     cSymbols,
+	".cpp":   cSymbols,
+	".hpp":   cSymbols,
+	".cc":    cSymbols,
+	".rb":    rubySymbols,
+	".php":   phpSymbols,
+	".sh":    shellSymbols,
+	".bash":  shellSymbols,
+}
+
+var hashComments = map[string]bool{".py": true, ".rb": true, ".sh": true, ".bash": true, ".php": true}
+
+// controlKeywords start lines the C and method patterns would otherwise
+// take for declarations.
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "return": true, "else": true,
+	"catch": true, "sizeof": true, "function": true, "new": true, "throw": true,
+}
+
+// ExtractSymbols lists the functions, methods and types declared in a file,
+// in source order. Go, Python and Java files are parsed with tree-sitter
+// (without cgo, Go with go/parser); other languages are matched line by
+// line against declaration patterns, which is enough for an outline.
+func ExtractSymbols(path, content string) []Symbol {
+	ext := sourceExt(path)
+	if symbols, ok := syntaxSymbols(ext, content); ok {
+		return symbols
+	}
+	if ext == ".go" {
+		return goSymbols(path, content)
+	}
+
+	pats := symbolPatterns[ext]
+	if pats == nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	var comment []string // The comment block above the current line
+	inBlockComment := false
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inBlockComment {
+			inBlockComment = !strings.Contains(trimmed, "*/")
+			comment = append(comment, commentText(trimmed))
+			continue
+		}
+		if strings.HasPrefix(trimmed, "/*") && !strings.Contains(trimmed, "*/") {
+			inBlockComment = true
+			comment = []string{commentText(trimmed)}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*") ||
+			hashComments[ext] && strings.HasPrefix(trimmed, "#") {
+			comment = append(comment, commentText(trimmed))
+			continue
+		}
+		if trimmed == "" {
+			comment = nil
+			continue
+		}
+		// Decorators and attributes sit between a declaration and its doc
+		if strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "#[") {
+			continue
+		}
+
+		doc := docSentence(strings.Join(comment, " "))
+		comment = nil
+		for _, pat := range pats {
+			m := pat.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := strings.TrimSpace(m[pat.re.SubexpIndex("name")])
+			if controlKeywords[name] {
+				continue
+			}
+
+			kind := pat.kind
+			if ext == ".py" {
+				if kind == "func" && line != strings.TrimLeft(line, " \t") {
+					kind = "method"
+				}
+				if docstring := pythonDocstring(lines[i+1:]); docstring != "" {
+					doc = docstring
+				}
+			}
+			symbols = append(symbols, Symbol{Kind: kind, Name: name, Signature: signatureLine(trimmed), Line: i + 1, Doc: doc})
+			break
+		}
+	}
+	return symbols
+}
+
+// commentText strips the comment markers from a line of a comment.
+func commentText(line string) string {
+	line = strings.TrimSuffix(line, "*/")
+	for _, marker := range []string{"///", "//!", "//", "/**", "/*", "*", "#"} {
+		if rest, ok := strings.CutPrefix(line, marker); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// pythonDocstring returns the first sentence of the docstring opening the
+// body that follows a def or class line.
+func pythonDocstring(body []string) string {
+	for i, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		var quote string
+		for _, q := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(trimmed, q) {
+				quote = q
+			}
+		}
+		if quote == "" {
+			return ""
+		}
+
+		text := strings.TrimPrefix(trimmed, quote)
+		for _, next := range body[i+1:] {
+			if strings.Contains(text, quote) {
+				break
+			}
+			text += " " + strings.TrimSpace(next)
+		}
+		text, _, _ = strings.Cut(text, quote)
+		return docSentence(text)
+	}
+	return ""
+}
+
+// docSentence shortens a doc comment to its first sentence.
+func docSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	if len(text) > maxDocChars {
+		text = text[:maxDocChars-3] + "..."
+	}
+	return text
+}
+
+// signatureLine trims a declaration line down to its signature.
+func signatureLine(line string) string {
+	line = strings.TrimRight(line, " \t{:")
+	if len(line) > maxSignatureChars {
+		line = line[:maxSignatureChars-3] + "..."
+	}
+	return line
+}
+
+func goSymbols(path, content string) []Symbol {
+	fset := token.NewFileSet()
+	// A file with syntax errors still yields the declarations parsed so far
+	file, _ := parser.ParseFile(fset, path, content, parser.SkipObjectResolution|parser.ParseComments)
+	if file == nil {
+		return nil
+	}
+
+	render := func(node interface{}) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			return ""
+		}
+		return signatureLine(strings.Join(strings.Fields(buf.String()), " "))
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind := "func"
+			if d.Recv != nil {
+				kind = "method"
+			}
+			sig := *d
+			sig.Body = nil
+			sig.Doc = nil
+			symbols = append(symbols, Symbol{Kind: kind, Name: d.Name.Name, Signature: render(&sig), Line: fset.Position(d.Pos()).Line, Doc: docSentence(d.Doc.Text())})
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				kind, sig := "type", ""
+				switch ts.Type.(type) {
+				case *ast.StructType:
+					sig = fmt.Sprintf("type %s struct", ts.Name.Name)
+				case *ast.InterfaceType:
+					kind = "interface"
+					sig = fmt.Sprintf("type %s interface", ts.Name.Name)
+				default:
+					bare := *ts
+					bare.Doc, bare.Comment = nil, nil
+					sig = "type " + render(&bare)
+				}
+				doc := ts.Doc
+				if doc == nil && len(d.Specs) == 1 {
+					doc = d.Doc
+				}
+				symbols = append(symbols, Symbol{Kind: kind, Name: ts.Name.Name, Signature: sig, Line: fset.Position(ts.Pos()).Line, Doc: docSentence(doc.Text())})
+			}
+		}
+	}
+	return symbols
+}
+
+// Outline renders symbols one per line, for prompts that need a file's
+// shape rather than its contents.
+func Outline(symbols []Symbol) string {
+	var out strings.Builder
+	for i, symbol := range symbols {
+		if i == maxOutlineSymbols {
+			out.WriteString(fmt.Sprintf("  ... and %d more\n", len(symbols)-i))
+			break
+		}
+		out.WriteString(fmt.Sprintf("  %d: %s\n", symbol.Line, symbol.Signature))
+		if symbol.Doc != "" {
+			out.WriteString(fmt.Sprintf("      %s\n", symbol.Doc))
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,241 @@
+// Package: internal/context/symbols_cgo.go
+
+//go:build cgo
+
+package context
+
+import (
+	"regexp"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// syntaxGrammars are the languages whose symbols are read from a
+// tree-sitter syntax tree; the others are matched line by line.
+var syntaxGrammars = map[string]func() *sitter.Language{
+	".go":   func() *sitter.Language { return sitter.NewLanguage(tree_sitter_go.Language()) },
+	".py":   func() *sitter.Language { return sitter.NewLanguage(tree_sitter_python.Language()) },
+	".java": func() *sitter.Language { return sitter.NewLanguage(tree_sitter_java.Language()) },
+}
+
+// syntaxSymbols lists the symbols of a Go, Python or Java file from its
+// syntax tree, reporting false for other languages. A file with syntax
+// errors still yields the declarations tree-sitter could make out.
+func syntaxSymbols(ext, content string) ([]Symbol, bool) {
+	grammar, ok := syntaxGrammars[ext]
+	if !ok {
+		return nil, false
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(grammar()); err != nil {
+		return nil, false
+	}
+	source := []byte(content)
+	tree := parser.Parse(source, nil)
+	if tree == nil {
+		return nil, false
+	}
+	defer tree.Close()
+
+	s := &syntaxOutline{source: source}
+	root := tree.RootNode()
+	switch ext {
+	case ".go":
+		s.goDecls(root)
+	case ".py":
+		s.pythonDefs(root, false)
+	case ".java":
+		s.javaDecls(root)
+	}
+	return s.symbols, true
+}
+
+// syntaxOutline collects the symbols of one syntax tree.
+type syntaxOutline struct {
+	source  []byte
+	symbols []Symbol
+}
+
+func (s *syntaxOutline) add(kind string, name *sitter.Node, signature string, at *sitter.Node, doc string) {
+	if name == nil {
+		return
+	}
+	s.symbols = append(s.symbols, Symbol{
+		Kind:      kind,
+		Name:      name.Utf8Text(s.source),
+		Signature: signatureLine(strings.Join(strings.Fields(signature), " ")),
+		Line:      int(at.StartPosition().Row) + 1,
+		Doc:       doc,
+	})
+}
+
+// header is the source of node up to its body, which is the declaration's
+// signature.
+func (s *syntaxOutline) header(node *sitter.Node) string {
+	end := node.EndByte()
+	if body := node.ChildByFieldName("body"); body != nil {
+		end = body.StartByte()
+	}
+	return string(s.source[node.StartByte():end])
+}
+
+// leadingComment returns the first sentence of the comments directly
+// above node, with no blank line between them.
+func (s *syntaxOutline) leadingComment(node *sitter.Node) string {
+	var lines []string
+	row := node.StartPosition().Row
+	for prev := node.PrevNamedSibling(); prev != nil; prev = prev.PrevNamedSibling() {
+		if !strings.HasSuffix(prev.Kind(), "comment") || prev.EndPosition().Row+1 < row {
+			break
+		}
+		var text []string
+		for _, line := range strings.Split(prev.Utf8Text(s.source), "\n") {
+			text = append(text, commentText(strings.TrimSpace(line)))
+		}
+		lines = append(text, lines...)
+		row = prev.StartPosition().Row
+	}
+	return docSentence(strings.Join(lines, " "))
+}
+
+func (s *syntaxOutline) goDecls(root *sitter.Node) {
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		decl := root.NamedChild(i)
+		switch decl.Kind() {
+		case "function_declaration", "method_declaration":
+			kind := "func"
+			if decl.Kind() == "method_declaration" {
+				kind = "method"
+			}
+			s.add(kind, decl.ChildByFieldName("name"), s.header(decl), decl, s.leadingComment(decl))
+		case "type_declaration":
+			specs := 0
+			for j := uint(0); j < decl.NamedChildCount(); j++ {
+				if kind := decl.NamedChild(j).Kind(); kind == "type_spec" || kind == "type_alias" {
+					specs++
+				}
+			}
+			for j := uint(0); j < decl.NamedChildCount(); j++ {
+				spec := decl.NamedChild(j)
+				if spec.Kind() != "type_spec" && spec.Kind() != "type_alias" {
+					continue
+				}
+				name, typ := spec.ChildByFieldName("name"), spec.ChildByFieldName("type")
+				if name == nil || typ == nil {
+					continue
+				}
+				kind, signature := "type", "type "+spec.Utf8Text(s.source)
+				switch typ.Kind() {
+				case "struct_type":
+					signature = "type " + name.Utf8Text(s.source) + " struct"
+				case "interface_type":
+					kind, signature = "interface", "type "+name.Utf8Text(s.source)+" interface"
+				}
+				doc := s.leadingComment(spec)
+				if doc == "" && specs == 1 {
+					doc = s.leadingComment(decl)
+				}
+				s.add(kind, name, signature, spec, doc)
+			}
+		}
+	}
+}
+
+// pythonDefs adds the classes and functions declared in block, and the
+// methods of the classes; inClass is whether block is a class body.
+func (s *syntaxOutline) pythonDefs(block *sitter.Node, inClass bool) {
+	for i := uint(0); i < block.NamedChildCount(); i++ {
+		def := block.NamedChild(i)
+		if def.Kind() == "decorated_definition" {
+			def = def.ChildByFieldName("definition")
+			if def == nil {
+				continue
+			}
+		}
+
+		switch def.Kind() {
+		case "class_definition":
+			s.add("class", def.ChildByFieldName("name"), s.header(def), def, s.pythonDocstring(def))
+			if body := def.ChildByFieldName("body"); body != nil {
+				s.pythonDefs(body, true)
+			}
+		case "function_definition":
+			kind := "func"
+			if inClass {
+				kind = "method"
+			}
+			s.add(kind, def.ChildByFieldName("name"), s.header(def), def, s.pythonDocstring(def))
+		}
+	}
+}
+
+// pythonDocstring returns the first sentence of the docstring opening the
+// body of a def or class.
+func (s *syntaxOutline) pythonDocstring(def *sitter.Node) string {
+	body := def.ChildByFieldName("body")
+	if body == nil || body.NamedChildCount() == 0 {
+		return ""
+	}
+	first := body.NamedChild(0)
+	if first.Kind() != "expression_statement" || first.NamedChildCount() == 0 || first.NamedChild(0).Kind() != "string" {
+		return ""
+	}
+	str := first.NamedChild(0)
+	var text strings.Builder
+	for i := uint(0); i < str.NamedChildCount(); i++ {
+		if part := str.NamedChild(i); part.Kind() == "string_content" {
+			text.WriteString(part.Utf8Text(s.source))
+		}
+	}
+	return docSentence(text.String())
+}
+
+// javaAnnotations are the annotations a Java declaration starts with,
+// which its outline leaves out.
+var javaAnnotations = regexp.MustCompile(`^(?:@[\w.]+(?:\([^)]*\))?\s*)+`)
+
+var javaKinds = map[string]string{
+	"class_declaration":           "class",
+	"record_declaration":          "class",
+	"interface_declaration":       "interface",
+	"annotation_type_declaration": "interface",
+	"enum_declaration":            "enum",
+	"method_declaration":          "method",
+	"constructor_declaration":     "method",
+}
+
+// javaDecls adds the types declared in node and their methods and nested
+// types.
+func (s *syntaxOutline) javaDecls(node *sitter.Node) {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		decl := node.NamedChild(i)
+		kind, ok := javaKinds[decl.Kind()]
+		if !ok {
+			continue
+		}
+		// Its line is the name's, below the annotations, and an abstract
+		// method's header ends in a semicolon
+		name := decl.ChildByFieldName("name")
+		if name == nil {
+			continue
+		}
+		signature := javaAnnotations.ReplaceAllString(strings.TrimSpace(s.header(decl)), "")
+		s.add(kind, name, strings.TrimSuffix(signature, ";"), name, s.leadingComment(decl))
+
+		if body := decl.ChildByFieldName("body"); body != nil && kind != "method" {
+			s.javaDecls(body)
+			// An enum's methods follow its constants
+			for j := uint(0); j < body.NamedChildCount(); j++ {
+				if declarations := body.NamedChild(j); declarations.Kind() == "enum_body_declarations" {
+					s.javaDecls(declarations)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package: internal/context/symbols_nocgo.go
+
+//go:build !cgo
+
+package context
+
+// syntaxSymbols reports false without cgo, which tree-sitter needs: every
+// language is then matched line by line, and Go parsed with go/parser.
+func syntaxSymbols(ext, content string) ([]Symbol, bool) {
+	return nil, false
+}
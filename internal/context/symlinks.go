@@ -0,0 +1,97 @@
+// Package: internal/context/symlinks.go
+package context
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Symlink policies: what a project walk does with symbolic links (and
+// directory junctions on Windows).
+const (
+	// SymlinksSkip leaves links out of the context
+	SymlinksSkip = "skip"
+	// SymlinksFiles reads links to files as the files they point to and
+	// leaves links to directories out; it is the default
+	SymlinksFiles = "files"
+	// SymlinksFollow walks linked directories as well, each real directory
+	// once, leaving out links back into the project or to a directory
+	// already walked
+	SymlinksFollow = "follow"
+)
+
+// SetSymlinks sets how symbolic links are walked: SymlinksSkip,
+// SymlinksFiles or SymlinksFollow.
+func (cm *ContextManager) SetSymlinks(policy string) error {
+	switch policy {
+	case SymlinksSkip, SymlinksFiles, SymlinksFollow:
+	default:
+		return fmt.Errorf("unknown symlink policy %q (want %s, %s or %s)", policy, SymlinksSkip, SymlinksFiles, SymlinksFollow)
+	}
+
+	cm.treeMu.Lock()
+	defer cm.treeMu.Unlock()
+	cm.symlinks = policy
+	cm.tree = nil
+	return nil
+}
+
+// isLink reports whether the entry at path is a symbolic link or, on
+// Windows, a junction, which the walk would otherwise enter like any
+// directory.
+func isLink(path string, d fs.DirEntry) bool {
+	if d.Type()&fs.ModeSymlink != 0 {
+		return true
+	}
+	if runtime.GOOS == "windows" && d.IsDir() {
+		_, err := os.Readlink(path)
+		return err == nil
+	}
+	return false
+}
+
+// followLink applies the symlink policy to the link at path. It returns
+// what the link points to, and for a directory to walk its real path,
+// which is added to visited; ok is false when the link is left out. The
+// caller holds treeMu.
+func (cm *ContextManager) followLink(path string, visited map[string]bool) (info fs.FileInfo, dir string, ok bool) {
+	if cm.symlinks == SymlinksSkip {
+		return nil, "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", false // Dangling
+	}
+	if !info.IsDir() {
+		return info, "", info.Mode().IsRegular()
+	}
+	if cm.symlinks != SymlinksFollow {
+		return nil, "", false
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, "", false
+	}
+	for walked := range visited {
+		if target == walked || strings.HasPrefix(target, walked+string(filepath.Separator)) {
+			return nil, "", false // Inside a directory already walked
+		}
+	}
+	visited[target] = true
+	return info, target, true
+}
+
+// visitedRoots starts the set of real directories a walk has entered with
+// the project itself, so links back into it are not walked twice.
+func (cm *ContextManager) visitedRoots() map[string]bool {
+	root := cm.projectRoot
+	if target, err := filepath.EvalSymlinks(root); err == nil {
+		root = target
+	}
+	return map[string]bool{root: true}
+}
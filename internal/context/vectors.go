@@ -0,0 +1,318 @@
+// Package: internal/context/vectors.go
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxEmbedChars     = 4000
+	embedBatchSize    = 32
+	DefaultRetrievalK = 8
+)
+
+// Embedder returns one embedding vector per text, in order.
+type Embedder func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Chunk is an embedded range of lines in a project file. Chunks start at
+// declarations where the file has any, so a function is rarely split from
+// its body.
+type Chunk struct {
+	File      string    `json:"file"`
+	StartLine int       `json:"start"`
+	EndLine   int       `json:"end"`
+	Hash      string    `json:"hash"`
+	Vector    []float32 `json:"vector"`
+}
+
+// ChunkMatch is a chunk retrieved for a query, with its current text.
+type ChunkMatch struct {
+	Chunk
+	Score float64
+	Text  string
+}
+
+// VectorIndex holds the embedded chunks of the project. Like the symbol
+// index it lives under ~/.claude-go/index and is refreshed incrementally;
+// chunks whose text did not change keep their vectors.
+type VectorIndex struct {
+	Root  string                `json:"root"`
+	Model string                `json:"model"`
+	Files map[string]vectorFile `json:"files"`
+
+	mu   sync.Mutex
+	path string
+}
+
+type vectorFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+// SetEmbedder turns on semantic retrieval with the given embedding model.
+func (cm *ContextManager) SetEmbedder(model string, embed Embedder) {
+	cm.embedModel = model
+	cm.embed = embed
+}
+
+// RetrievalEnabled reports whether an embedder is set.
+func (cm *ContextManager) RetrievalEnabled() bool {
+	return cm.embed != nil
+}
+
+func loadVectorIndex(root, model string) *VectorIndex {
+	idx := &VectorIndex{Root: root, Model: model, Files: make(map[string]vectorFile)}
+	path, err := indexPath(root)
+	if err != nil {
+		return idx
+	}
+	idx.path = strings.TrimSuffix(path, ".json") + ".vectors.json"
+
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		return idx
+	}
+	var saved VectorIndex
+	if json.Unmarshal(data, &saved) == nil && saved.Root == root && saved.Model == model && saved.Files != nil {
+		idx.Files = saved.Files
+	}
+	return idx
+}
+
+func (cm *ContextManager) vectorIndex() *VectorIndex {
+	cm.vectorsOnce.Do(func() {
+		cm.vectors = loadVectorIndex(cm.projectRoot, cm.embedModel)
+	})
+	return cm.vectors
+}
+
+// RelevantChunks returns the k chunks of the project closest to query,
+// embedding new and changed files first.
+func (cm *ContextManager) RelevantChunks(ctx context.Context, query string, k int) ([]ChunkMatch, error) {
+	if cm.embed == nil {
+		return nil, fmt.Errorf("semantic retrieval is not configured (set context.embedding_model)")
+	}
+	if k <= 0 {
+		k = DefaultRetrievalK
+	}
+
+	idx := cm.vectorIndex()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := cm.refreshVectors(ctx, idx); err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := cm.embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	queryVector := queryVectors[0]
+
+	var candidates []ChunkMatch
+	for _, file := range idx.Files {
+		for _, chunk := range file.Chunks {
+			candidates = append(candidates, ChunkMatch{Chunk: chunk, Score: cosine(queryVector, chunk.Vector)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].File < candidates[j].File
+	})
+
+	// Duplicated files have chunks with the same text; keep one of each
+	var matches []ChunkMatch
+	texts := make(map[string]bool)
+	for _, match := range candidates {
+		if len(matches) == k {
+			break
+		}
+		content, err := ReadSource(filepath.Join(cm.projectRoot, filepath.FromSlash(match.File)))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+		start, end := match.StartLine-1, min(match.EndLine, len(lines))
+		if start >= end {
+			continue
+		}
+		match.Text = strings.Join(lines[start:end], "\n")
+		if texts[match.Text] {
+			continue
+		}
+		texts[match.Text] = true
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// pendingChunk is a chunk waiting for its vector.
+type pendingChunk struct {
+	file  string
+	index int
+	text  string
+}
+
+// refreshVectors re-chunks files changed since the index was saved and
+// embeds the chunks it has no vector for. The caller holds idx.mu.
+func (cm *ContextManager) refreshVectors(ctx context.Context, idx *VectorIndex) error {
+	known := make(map[string][]float32)
+	for _, file := range idx.Files {
+		for _, chunk := range file.Chunks {
+			known[chunk.Hash] = chunk.Vector
+		}
+	}
+
+	sources, err := cm.sourceFiles()
+	if err != nil {
+		return err
+	}
+
+	var stale []sourceFile
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		key := filepath.ToSlash(source.relPath)
+		seen[key] = true
+		if cached, ok := idx.Files[key]; !ok || cached.Size != source.entry.Size || !cached.ModTime.Equal(source.entry.ModTime) {
+			stale = append(stale, source)
+		}
+	}
+
+	// Read and chunk changed files on a pool of workers
+	chunked := make([]*vectorFile, len(stale))
+	texts := make([][]string, len(stale)) // Text of each chunk still to embed, "" for known ones
+	forEachParallel(len(stale), func(i int) {
+		source := stale[i]
+		key := filepath.ToSlash(source.relPath)
+		content, err := ReadSource(source.path)
+		if err != nil {
+			return
+		}
+
+		file := vectorFile{Size: source.entry.Size, ModTime: source.entry.ModTime}
+		chunked[i] = &file
+		if !IsText(content) {
+			return
+		}
+		lines := strings.Split(string(content), "\n")
+		for _, r := range chunkRanges(source.path, string(content)) {
+			text := strings.Join(lines[r.start-1:r.end], "\n")
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			text = key + "\n" + text
+			if len(text) > maxEmbedChars {
+				text = text[:maxEmbedChars]
+			}
+			chunk := Chunk{File: key, StartLine: r.start, EndLine: r.end, Hash: hashContent([]byte(text))}
+			if vector, ok := known[chunk.Hash]; ok {
+				chunk.Vector = vector
+				text = ""
+			}
+			file.Chunks = append(file.Chunks, chunk)
+			texts[i] = append(texts[i], text)
+		}
+	})
+
+	changed := false
+	var pending []pendingChunk
+	for i, file := range chunked {
+		if file == nil {
+			continue
+		}
+		key := filepath.ToSlash(stale[i].relPath)
+		for j, text := range texts[i] {
+			if text != "" {
+				pending = append(pending, pendingChunk{file: key, index: j, text: text})
+			}
+		}
+		idx.Files[key] = *file
+		changed = true
+	}
+
+	for key := range idx.Files {
+		if !seen[key] {
+			delete(idx.Files, key)
+			changed = true
+		}
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		batch := pending[start:min(start+embedBatchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, p := range batch {
+			texts[i] = p.text
+		}
+
+		vectors, err := cm.embed(ctx, texts)
+		if err != nil {
+			// Files whose chunks are not all embedded are retried next time
+			for _, p := range pending[start:] {
+				delete(idx.Files, p.file)
+			}
+			idx.save()
+			return fmt.Errorf("failed to embed project files: %w", err)
+		}
+		for i, p := range batch {
+			idx.Files[p.file].Chunks[p.index].Vector = vectors[i]
+		}
+	}
+
+	if changed {
+		if err := idx.save(); err != nil {
+			return fmt.Errorf("failed to save vector index: %w", err)
+		}
+	}
+	return nil
+}
+
+// save writes the index through a temporary file. The caller holds idx.mu.
+func (idx *VectorIndex) save() error {
+	if idx.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
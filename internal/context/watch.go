@@ -0,0 +1,382 @@
+// Package: internal/context/watch.go
+package context
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// skipDirs are dependency and build directories never read into the
+// context.
+var skipDirs = map[string]bool{"node_modules": true, "vendor": true, "target": true, "build": true, "dist": true, ".git": true}
+
+// ProjectEntry is a file or directory that belongs in the project context:
+// not hidden (except .env), in a dependency or build directory, or ignored.
+type ProjectEntry struct {
+	RelPath string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// Entries lists the project's entries in walk order, limited to the
+// current scope. While the project is watched they come from the tree the
+// watcher keeps up to date; otherwise the project is walked.
+func (cm *ContextManager) Entries() ([]ProjectEntry, error) {
+	entries, err := cm.allEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := entries[:0]
+	for _, entry := range entries {
+		if cm.inScope(entry.RelPath, entry.IsDir) {
+			scoped = append(scoped, entry)
+		}
+	}
+	return scoped, nil
+}
+
+func (cm *ContextManager) allEntries() ([]ProjectEntry, error) {
+	cm.treeMu.Lock()
+	defer cm.treeMu.Unlock()
+
+	if cm.watcher == nil {
+		return cm.walkProject(".", NewIgnoreMatcher(cm.projectRoot, cm.filter.ExcludePatterns()), nil)
+	}
+
+	// The tree is walked again from time to time in case the watcher
+	// missed something
+	if cm.tree == nil || cm.refreshTTL > 0 && time.Since(cm.treeBuilt) > cm.refreshTTL {
+		if err := cm.rebuildTree(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]ProjectEntry, 0, len(cm.tree))
+	for _, entry := range cm.tree {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return walkOrder(entries[i].RelPath, entries[j].RelPath) })
+	return entries, nil
+}
+
+// walkProject walks the directory dir (relative to the root), calling
+// addDir for every directory it enters, dir included. Symbolic links are
+// walked according to the symlink policy. A walk of the whole project that
+// goes over its limits is replaced by a sample. The caller holds treeMu.
+func (cm *ContextManager) walkProject(dir string, ignore *IgnoreMatcher, addDir func(path string) error) ([]ProjectEntry, error) {
+	var entries []ProjectEntry
+	var files int
+	var bytes int64
+	limited := dir == "."
+	visited := cm.visitedRoots()
+
+	// walk walks the real directory root, which the project sees as
+	// relRoot; a followed link walks its target the same way
+	var walk func(root, relRoot string) error
+	walk = func(root, relRoot string) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if relRoot != dir {
+					return nil // Unreadable parts of a linked directory are left out
+				}
+				return err
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+			relPath := filepath.Join(relRoot, rel)
+			projectPath := filepath.Join(cm.projectRoot, relPath)
+			if rel == "." && relRoot != dir {
+				// A followed link, already listed and checked under its own name
+				if addDir != nil {
+					return addDir(projectPath)
+				}
+				return nil
+			}
+
+			isDir, target := d.IsDir(), ""
+			var info fs.FileInfo
+			if relPath != dir && isLink(path, d) {
+				var ok bool
+				if info, target, ok = cm.followLink(path, visited); !ok {
+					if d.IsDir() {
+						return filepath.SkipDir // A junction
+					}
+					return nil
+				}
+				isDir = info.IsDir()
+			}
+
+			if relPath != "." && (skipEntry(d.Name(), relPath, isDir, ignore) || isDir && !cm.inScope(relPath, true)) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if d.IsDir() && target == "" && addDir != nil {
+				if err := addDir(projectPath); err != nil {
+					return err
+				}
+			}
+			if relPath == "." {
+				return nil
+			}
+
+			if info == nil {
+				if info, err = d.Info(); err != nil {
+					return nil
+				}
+			}
+			if !isDir && limited {
+				files++
+				bytes += info.Size()
+				if cm.overLimits(files, bytes) {
+					return errProjectTooLarge
+				}
+			}
+			entries = append(entries, ProjectEntry{RelPath: relPath, IsDir: isDir, Size: info.Size(), ModTime: info.ModTime()})
+
+			if target != "" {
+				if err := walk(target, relPath); err != nil {
+					return err
+				}
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		})
+	}
+
+	// Start from the real directory, so a walk of a linked directory
+	// (from the watcher) enters it
+	root := filepath.Join(cm.projectRoot, dir)
+	if target, err := filepath.EvalSymlinks(root); err == nil {
+		root = target
+	}
+	err := walk(root, dir)
+	if errors.Is(err, errProjectTooLarge) {
+		return cm.sampleProject(ignore, addDir)
+	}
+	if limited {
+		cm.sampled = false
+	}
+	return entries, err
+}
+
+func skipEntry(name, relPath string, isDir bool, ignore *IgnoreMatcher) bool {
+	// Skip hidden files and directories
+	if strings.HasPrefix(name, ".") && (isDir || name != ".env") {
+		return true
+	}
+	// Skip common non-source directories
+	if isDir && skipDirs[name] {
+		return true
+	}
+	return ignore.Ignored(relPath, isDir)
+}
+
+// walkOrder sorts paths the way filepath.WalkDir visits them: directory by
+// directory, each directory's entries by name.
+func walkOrder(a, b string) bool {
+	as := strings.Split(a, string(filepath.Separator))
+	bs := strings.Split(b, string(filepath.Separator))
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// Watch keeps the project's entries current from file system events, so
+// long sessions see changes without walking the project for every request.
+// Call the returned function to stop watching.
+func (cm *ContextManager) Watch() (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start file watcher: %w", err)
+	}
+
+	cm.treeMu.Lock()
+	cm.watcher = watcher
+	err = cm.rebuildTree()
+	if err != nil {
+		cm.watcher = nil
+	}
+	cm.treeMu.Unlock()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go cm.watchEvents(watcher, done)
+
+	return func() {
+		cm.treeMu.Lock()
+		if cm.watcher == watcher {
+			cm.watcher, cm.tree, cm.ignore = nil, nil, nil
+		}
+		cm.treeMu.Unlock()
+		watcher.Close()
+		<-done
+	}, nil
+}
+
+func (cm *ContextManager) watchEvents(watcher *fsnotify.Watcher, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			cm.handleEvent(event)
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events were lost (typically a queue overflow); walk again on
+			// the next request
+			cm.treeMu.Lock()
+			cm.tree = nil
+			cm.treeMu.Unlock()
+		}
+	}
+}
+
+// rebuildTree walks the project and watches every directory in it. The
+// caller holds treeMu.
+func (cm *ContextManager) rebuildTree() error {
+	cm.ignore = NewIgnoreMatcher(cm.projectRoot, cm.filter.ExcludePatterns())
+	entries, err := cm.walkProject(".", cm.ignore, cm.watcher.Add)
+	if err != nil {
+		return fmt.Errorf("failed to watch project: %w", err)
+	}
+
+	cm.tree = make(map[string]ProjectEntry, len(entries))
+	for _, entry := range entries {
+		cm.tree[entry.RelPath] = entry
+	}
+	cm.treeBuilt = time.Now()
+	return nil
+}
+
+// stopWatching falls back to walking the project. The caller holds treeMu.
+func (cm *ContextManager) stopWatching() {
+	cm.watcher.Close()
+	cm.watcher = nil
+	cm.tree = nil
+	cm.ignore = nil
+}
+
+// handleEvent applies one file system event to the tree. Files are only
+// re-read for the context when their size or modification time changed,
+// so the tree is all the watcher has to maintain.
+func (cm *ContextManager) handleEvent(event fsnotify.Event) {
+	relPath, err := filepath.Rel(cm.projectRoot, event.Name)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return
+	}
+
+	cm.treeMu.Lock()
+	cm.updateTree(relPath, event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename), event.Has(fsnotify.Create))
+	cm.treeMu.Unlock()
+
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	cm.changeMu.Lock()
+	listeners := cm.onChange
+	cm.changeMu.Unlock()
+	for _, fn := range listeners {
+		fn(event.Name)
+	}
+}
+
+// OnChange registers fn to be called with the absolute path of each file
+// or directory that is written, created, removed or renamed while the
+// project is watched.
+func (cm *ContextManager) OnChange(fn func(path string)) {
+	cm.changeMu.Lock()
+	defer cm.changeMu.Unlock()
+	cm.onChange = append(cm.onChange, fn)
+}
+
+// updateTree brings the tree's entry for relPath up to date, walking it if
+// it is a new directory. The caller holds treeMu.
+func (cm *ContextManager) updateTree(relPath string, removed, created bool) {
+	if cm.tree == nil || cm.watcher == nil {
+		return
+	}
+
+	// Changed ignore rules can include or exclude anything; start over
+	name := filepath.Base(relPath)
+	if name == ".gitignore" || name == ".claudeignore" {
+		cm.tree = nil
+		return
+	}
+
+	if removed {
+		cm.removeFromTree(relPath)
+		return
+	}
+
+	path := filepath.Join(cm.projectRoot, relPath)
+	info, err := os.Lstat(path)
+	if err != nil {
+		cm.removeFromTree(relPath)
+		return
+	}
+	if isLink(path, fs.FileInfoToDirEntry(info)) {
+		var ok bool
+		if info, _, ok = cm.followLink(path, cm.visitedRoots()); !ok {
+			cm.removeFromTree(relPath)
+			return
+		}
+	}
+	if skipEntry(name, relPath, info.IsDir(), cm.ignore) {
+		return
+	}
+
+	if info.IsDir() && created {
+		entries, err := cm.walkProject(relPath, cm.ignore, cm.watcher.Add)
+		if err != nil {
+			// Most likely out of inotify watches; walking still works
+			cm.stopWatching()
+			return
+		}
+		for _, entry := range entries {
+			cm.tree[entry.RelPath] = entry
+		}
+		return
+	}
+
+	cm.tree[relPath] = ProjectEntry{RelPath: relPath, IsDir: info.IsDir(), Size: info.Size(), ModTime: info.ModTime()}
+}
+
+// removeFromTree drops an entry and everything below it. The caller holds
+// treeMu.
+func (cm *ContextManager) removeFromTree(relPath string) {
+	delete(cm.tree, relPath)
+	prefix := relPath + string(filepath.Separator)
+	for path := range cm.tree {
+		if strings.HasPrefix(path, prefix) {
+			delete(cm.tree, path)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package: internal/help/help.go
+package help
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed corpus.json
+var corpusData []byte
+
+// Topic is one entry of the bundled help corpus.
+type Topic struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Keywords []string `json:"keywords"`
+	Body     string   `json:"body"`
+}
+
+var corpus []Topic
+
+func init() {
+	if err := json.Unmarshal(corpusData, &corpus); err != nil {
+		panic("help: invalid corpus: " + err.Error())
+	}
+}
+
+// Topics returns every topic in the corpus.
+func Topics() []Topic {
+	return corpus
+}
+
+// Search ranks topics against a free-form question and returns at most
+// limit matches. Keyword hits weigh more than words found in the body.
+func Search(question string, limit int) []Topic {
+	words := tokenize(question)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		topic Topic
+		score int
+	}
+	var results []scored
+
+	for _, topic := range corpus {
+		score := 0
+		title := strings.ToLower(topic.Title)
+		body := strings.ToLower(topic.Body)
+
+		for _, word := range words {
+			for _, keyword := range topic.Keywords {
+				keyword = strings.ToLower(keyword)
+				if keyword == word || strings.TrimLeft(keyword, "-/.~") == word {
+					score += 5
+				} else if len(word) > 3 && strings.Contains(keyword, word) {
+					score += 2
+				}
+			}
+			if strings.Contains(title, word) {
+				score += 3
+			}
+			if len(word) > 3 && strings.Contains(body, word) {
+				score++
+			}
+		}
+
+		if score > 0 {
+			results = append(results, scored{topic, score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	var topics []Topic
+	for i := 0; i < len(results) && i < limit; i++ {
+		topics = append(topics, results[i].topic)
+	}
+	return topics
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "how": true, "do": true, "i": true, "to": true,
+	"is": true, "what": true, "can": true, "of": true, "in": true, "for": true, "it": true,
+	"does": true, "my": true, "and": true, "or": true, "with": true, "use": true,
+}
+
+func tokenize(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.'
+	})
+
+	var words []string
+	for _, field := range fields {
+		field = strings.Trim(field, "-.")
+		if field != "" && !stopWords[field] {
+			words = append(words, field)
+		}
+	}
+	return words
+}
@@ -0,0 +1,283 @@
+// Package: internal/history/history.go
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const maxSnippetChars = 160
+
+// Exchange is one request and the answer to it.
+type Exchange struct {
+	Time     time.Time `json:"time"`
+	Input    string    `json:"input"`
+	Response string    `json:"response"`
+}
+
+// Session is the persisted record of an interactive session, stored as one
+// JSON file under ~/.claude-go/sessions.
+type Session struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title,omitempty"`
+	Dir       string     `json:"dir"`
+	Started   time.Time  `json:"started"`
+	Updated   time.Time  `json:"updated"`
+	Exchanges []Exchange `json:"exchanges"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// Match is a session found by Search.
+type Match struct {
+	Session *Session
+	Score   float64
+	Snippet string
+}
+
+func sessionsDir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+// New starts a session for the project in dir. Nothing is written until the
+// first exchange is recorded.
+func New(dir string) (*Session, error) {
+	root, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%s-%d", now.Format("20060102-150405"), os.Getpid())
+	return &Session{
+		ID:      id,
+		Dir:     dir,
+		Started: now,
+		path:    filepath.Join(root, id+".json"),
+	}, nil
+}
+
+// Record appends an exchange and saves the session. untitled reports
+// whether the session still needs a title.
+func (s *Session) Record(input, response string) (untitled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Updated = time.Now()
+	s.Exchanges = append(s.Exchanges, Exchange{Time: s.Updated, Input: input, Response: response})
+	return s.Title == "" && len(s.Exchanges) == 1, s.save()
+}
+
+// SetTitle names the session and saves it.
+func (s *Session) SetTitle(title string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Title = title
+	return s.save()
+}
+
+// save writes the session through a temporary file so a crash never
+// leaves a truncated record. The caller holds s.mu.
+func (s *Session) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Load reads a saved session by ID.
+func Load(id string) (*Session, error) {
+	root, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	return load(filepath.Join(root, id+".json"))
+}
+
+func load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", filepath.Base(path), err)
+	}
+	s.path = path
+	return &s, nil
+}
+
+// List returns the saved sessions, most recently updated first.
+func List() ([]*Session, error) {
+	root, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(root, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, path := range paths {
+		s, err := load(path)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// Search ranks the saved sessions against a free-text query. Each query
+// term counts once per occurrence in the exchanges and three times in the
+// title; sessions matching more of the terms rank higher, and sessions
+// matching none are left out.
+func Search(query string, limit int) ([]Match, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("nothing to search for in %q", query)
+	}
+
+	sessions, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, s := range sessions {
+		counts := make(map[string]int)
+		countTerms(counts, terms, s.Title, 3)
+		for _, exchange := range s.Exchanges {
+			countTerms(counts, terms, exchange.Input, 1)
+			countTerms(counts, terms, exchange.Response, 1)
+		}
+		if len(counts) == 0 {
+			continue
+		}
+
+		score := 0.0
+		for _, count := range counts {
+			score += 1 + float64(count)/float64(count+2)
+		}
+		score *= float64(len(counts)) / float64(len(terms))
+
+		matches = append(matches, Match{Session: s, Score: score, Snippet: snippet(s, terms)})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true, "of": true, "to": true,
+	"in": true, "on": true, "for": true, "with": true, "where": true, "we": true, "i": true,
+	"session": true, "it": true, "that": true, "this": true, "is": true, "was": true,
+	"find": true, "when": true, "what": true, "how": true, "my": true, "our": true,
+}
+
+func searchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range words(query) {
+		if stopWords[word] {
+			continue
+		}
+		term := stem(word)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func countTerms(counts map[string]int, terms []string, text string, weight int) {
+	if text == "" {
+		return
+	}
+	for _, word := range words(text) {
+		word = stem(word)
+		for _, term := range terms {
+			if word == term {
+				counts[term] += weight
+			}
+		}
+	}
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// stem strips common English suffixes so "fixed", "fixes" and "fixing"
+// match "fix".
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+2 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// snippet returns the line of the session mentioning the most terms.
+func snippet(s *Session, terms []string) string {
+	best, bestCount := "", 0
+	consider := func(text string) {
+		for _, line := range strings.Split(text, "\n") {
+			counts := make(map[string]int)
+			countTerms(counts, terms, line, 1)
+			if len(counts) > bestCount {
+				best, bestCount = strings.TrimSpace(line), len(counts)
+			}
+		}
+	}
+	for _, exchange := range s.Exchanges {
+		consider(exchange.Input)
+		consider(exchange.Response)
+	}
+
+	if len(best) > maxSnippetChars {
+		best = best[:maxSnippetChars-3] + "..."
+	}
+	return best
+}
@@ -0,0 +1,225 @@
+// Package: internal/jobs/manager.go
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Func is the body of a background job. Progress written to out is kept in
+// the job's output buffer and delivered to followers.
+type Func func(ctx context.Context, out *Output) (string, error)
+
+type Job struct {
+	ID       int
+	Name     string
+	Started  time.Time
+	Finished time.Time
+
+	mu       sync.Mutex
+	status   Status
+	result   string
+	err      error
+	output   *Output
+	cancel   context.CancelFunc
+	done     chan struct{}
+	notified bool
+}
+
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Result returns the job's final answer and error once it has finished.
+func (j *Job) Result() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result, j.err
+}
+
+func (j *Job) Output() *Output { return j.output }
+
+func (j *Job) Done() <-chan struct{} { return j.done }
+
+// Output is a concurrency-safe, append-only log that can be followed.
+type Output struct {
+	mu        sync.Mutex
+	buf       strings.Builder
+	followers []chan string
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf.Write(p)
+	for _, f := range o.followers {
+		select {
+		case f <- string(p):
+		default: // Slow follower; it can re-read String()
+		}
+	}
+	return len(p), nil
+}
+
+func (o *Output) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(o, format, args...)
+}
+
+func (o *Output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// Follow returns the output so far and a channel receiving later writes.
+// The returned function stops following.
+func (o *Output) Follow() (string, <-chan string, func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ch := make(chan string, 64)
+	o.followers = append(o.followers, ch)
+
+	stop := func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		for i, f := range o.followers {
+			if f == ch {
+				o.followers = append(o.followers[:i], o.followers[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return o.buf.String(), ch, stop
+}
+
+type Manager struct {
+	mu     sync.Mutex
+	jobs   map[int]*Job
+	nextID int
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		jobs:   make(map[int]*Job),
+		nextID: 1,
+	}
+}
+
+// Start runs fn in the background and returns immediately.
+func (m *Manager) Start(name string, fn Func) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	job := &Job{
+		ID:      m.nextID,
+		Name:    name,
+		Started: time.Now(),
+		status:  StatusRunning,
+		output:  &Output{},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	m.jobs[job.ID] = job
+	m.nextID++
+	m.mu.Unlock()
+
+	go func() {
+		defer close(job.done)
+		defer cancel()
+
+		result, err := fn(ctx, job.output)
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+
+		job.Finished = time.Now()
+		job.result = result
+		job.err = err
+		switch {
+		case ctx.Err() == context.Canceled:
+			job.status = StatusCancelled
+		case err != nil:
+			job.status = StatusFailed
+		default:
+			job.status = StatusSucceeded
+		}
+	}()
+
+	return job
+}
+
+func (m *Manager) Get(id int) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// List returns all jobs ordered by ID.
+func (m *Manager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+func (m *Manager) Cancel(id int) error {
+	job, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("job %d not found", id)
+	}
+
+	if job.Status() != StatusRunning {
+		return fmt.Errorf("job %d is not running", id)
+	}
+
+	job.cancel()
+	return nil
+}
+
+// Finished returns jobs that completed since the last call, so callers can
+// notify the user once per job.
+func (m *Manager) Finished() []*Job {
+	var finished []*Job
+	for _, job := range m.List() {
+		job.mu.Lock()
+		if job.status != StatusRunning && !job.notified {
+			job.notified = true
+			finished = append(finished, job)
+		}
+		job.mu.Unlock()
+	}
+	return finished
+}
+
+// CancelAll stops every running job, e.g. when the session ends.
+func (m *Manager) CancelAll() {
+	for _, job := range m.List() {
+		if job.Status() == StatusRunning {
+			job.cancel()
+		}
+	}
+}
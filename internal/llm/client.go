@@ -16,11 +16,31 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+
+	thinkingMode   string
+	thinkingBudget int
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	// Reasoning holds the model's thinking, split out of Content by Chat.
+	// It is never sent back to the model.
+	Reasoning string `json:"reasoning_content,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type Tool struct {
@@ -40,6 +60,7 @@ type ChatRequest struct {
 	Tools       []Tool    `json:"tools,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
 }
 
@@ -74,7 +95,7 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 		return nil, fmt.Errorf("use ChatStream for streaming requests")
 	}
 
-	reqBody, err := json.Marshal(req)
+	reqBody, err := json.Marshal(c.prepareRequest(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -102,6 +123,15 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	for i := range chatResp.Choices {
+		msg := &chatResp.Choices[i].Message
+		reasoning, answer := SplitReasoning(msg.Content)
+		if reasoning != "" {
+			msg.Reasoning = strings.TrimSpace(msg.Reasoning + "\n" + reasoning)
+		}
+		msg.Content = answer
+	}
+
 	return &chatResp, nil
 }
 
@@ -125,6 +155,7 @@ type StreamResponse struct {
 }
 
 func (c *Client) ChatStream(ctx context.Context, req ChatRequest, callback func(StreamResponse) error) error {
+	req = c.prepareRequest(req)
 	req.Stream = true
 
 	reqBody, err := json.Marshal(req)
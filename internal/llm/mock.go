@@ -0,0 +1,369 @@
+// Package: internal/llm/mock.go
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const mockEmbeddingDims = 256
+
+// NewClient builds the client for the configured provider.
+func NewClient(cfg config.LMStudioConfig) (*Client, error) {
+	switch cfg.Provider {
+	case "", "lmstudio":
+		client := NewLMStudioClient(cfg.BaseURL)
+		if cfg.Cassette != "" {
+			recorder, err := newCassetteRecorder(cfg.Cassette, http.DefaultTransport)
+			if err != nil {
+				return nil, err
+			}
+			client.httpClient.Transport = recorder
+		}
+		return client, nil
+
+	case "mock":
+		var transport http.RoundTripper
+		var err error
+		switch {
+		case cfg.MockScript != "":
+			transport, err = loadMockScript(cfg.MockScript)
+		case cfg.Cassette != "":
+			transport, err = loadCassette(cfg.Cassette)
+		default:
+			transport = &mockScript{}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		client := NewLMStudioClient("http://mock/v1")
+		client.httpClient.Transport = transport
+		return client, nil
+	}
+
+	return nil, fmt.Errorf("unknown provider %q (want lmstudio or mock)", cfg.Provider)
+}
+
+// MockResponse is one scripted model reply. Replies with a Match pattern
+// answer every request whose last message matches it; the others are used
+// once each, in order.
+type MockResponse struct {
+	Match     string         `json:"match,omitempty"`
+	Content   string         `json:"content"`
+	ToolCalls []MockToolCall `json:"tool_calls,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+type MockToolCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type mockScriptFile struct {
+	Models    []string       `json:"models"`
+	Responses []MockResponse `json:"responses"`
+}
+
+// mockScript serves scripted replies in place of the chat server.
+type mockScript struct {
+	mu        sync.Mutex
+	models    []string
+	responses []MockResponse
+	next      int
+	calls     int
+}
+
+func loadMockScript(path string) (*mockScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+
+	var file mockScriptFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid mock script %s: %w", path, err)
+	}
+
+	for i := range file.Responses {
+		if file.Responses[i].Match == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(file.Responses[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock script response %d: invalid match: %w", i, err)
+		}
+		file.Responses[i].pattern = pattern
+	}
+
+	return &mockScript{models: file.Models, responses: file.Responses}, nil
+}
+
+func (m *mockScript) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/models") {
+		models := m.models
+		if len(models) == 0 {
+			models = []string{"mock"}
+		}
+		var data []map[string]string
+		for _, model := range models {
+			data = append(data, map[string]string{"id": model})
+		}
+		return jsonResponse(req, map[string]interface{}{"data": data})
+	}
+
+	if strings.HasSuffix(req.URL.Path, "/embeddings") {
+		var embReq EmbeddingRequest
+		if err := json.NewDecoder(req.Body).Decode(&embReq); err != nil {
+			return nil, fmt.Errorf("mock: invalid request: %w", err)
+		}
+		var data []map[string]interface{}
+		for i, input := range embReq.Input {
+			data = append(data, map[string]interface{}{"index": i, "embedding": mockEmbedding(input)})
+		}
+		return jsonResponse(req, map[string]interface{}{"data": data, "model": embReq.Model})
+	}
+
+	var chatReq ChatRequest
+	if err := json.NewDecoder(req.Body).Decode(&chatReq); err != nil {
+		return nil, fmt.Errorf("mock: invalid request: %w", err)
+	}
+
+	m.mu.Lock()
+	reply, err := m.reply(lastMessage(chatReq.Messages))
+	m.calls++
+	call := m.calls
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := Message{Role: "assistant", Content: reply.Content}
+	for i, tc := range reply.ToolCalls {
+		args, _ := json.Marshal(tc.Arguments)
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			ID:       fmt.Sprintf("mock-%d-%d", call, i),
+			Type:     "function",
+			Function: FunctionCall{Name: tc.Name, Arguments: string(args)},
+		})
+	}
+
+	if chatReq.Stream {
+		return streamResponse(req, msg.Content)
+	}
+
+	return jsonResponse(req, map[string]interface{}{
+		"id":      fmt.Sprintf("mock-%d", call),
+		"object":  "chat.completion",
+		"model":   chatReq.Model,
+		"choices": []map[string]interface{}{{"index": 0, "message": msg, "finish_reason": "stop"}},
+	})
+}
+
+func (m *mockScript) reply(last string) (MockResponse, error) {
+	for _, response := range m.responses {
+		if response.pattern != nil && response.pattern.MatchString(last) {
+			return response, nil
+		}
+	}
+
+	for m.next < len(m.responses) {
+		response := m.responses[m.next]
+		m.next++
+		if response.pattern == nil {
+			return response, nil
+		}
+	}
+
+	if len(m.responses) == 0 {
+		return MockResponse{Content: "This is a mock response."}, nil
+	}
+	return MockResponse{}, fmt.Errorf("mock: script has no response left for %q", firstLineOf(last))
+}
+
+// mockEmbedding hashes the words of text into a fixed-size vector, so
+// texts sharing words are close without a real embedding model.
+func mockEmbedding(text string) []float32 {
+	vector := make([]float32, mockEmbeddingDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vector[h.Sum32()%mockEmbeddingDims]++
+	}
+	return vector
+}
+
+func lastMessage(messages []Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1].Content
+}
+
+func firstLineOf(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	if len(line) > 80 {
+		line = line[:77] + "..."
+	}
+	return line
+}
+
+// cassetteEntry is one recorded exchange, stored as a line of JSON.
+type cassetteEntry struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response string          `json:"response"`
+}
+
+// cassetteRecorder passes requests to the real server and appends each
+// exchange to a cassette file.
+type cassetteRecorder struct {
+	mu        sync.Mutex
+	file      *os.File
+	transport http.RoundTripper
+}
+
+func newCassetteRecorder(path string, transport http.RoundTripper) (*cassetteRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	return &cassetteRecorder{file: file, transport: transport}, nil
+}
+
+func (r *cassetteRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	entry := cassetteEntry{Method: req.Method, Path: req.URL.Path, Status: resp.StatusCode, Response: string(data)}
+	if json.Valid(body) {
+		entry.Request = body
+	}
+	line, _ := json.Marshal(entry)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to record cassette: %w", err)
+	}
+
+	return resp, nil
+}
+
+// cassettePlayer replays recorded exchanges in order, separately for each
+// endpoint so model listings don't shift the chat replies.
+type cassettePlayer struct {
+	mu      sync.Mutex
+	entries map[string][]cassetteEntry
+}
+
+func loadCassette(path string) (*cassettePlayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	player := &cassettePlayer{entries: make(map[string][]cassetteEntry)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		key := entry.Method + " " + entry.Path
+		player.entries[key] = append(player.entries[key], entry)
+	}
+
+	return player, scanner.Err()
+}
+
+func (p *cassettePlayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.Path
+
+	p.mu.Lock()
+	queue := p.entries[key]
+	if len(queue) == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("cassette has no more recorded responses for %s", key)
+	}
+	entry := queue[0]
+	// Model listings are idempotent; keep replaying the last one
+	if len(queue) > 1 || !strings.HasSuffix(req.URL.Path, "/models") {
+		p.entries[key] = queue[1:]
+	}
+	p.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: entry.Status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(entry.Response)),
+		Request:    req,
+	}, nil
+}
+
+func jsonResponse(req *http.Request, v interface{}) (*http.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}, nil
+}
+
+func streamResponse(req *http.Request, content string) (*http.Response, error) {
+	var body bytes.Buffer
+	for _, word := range strings.SplitAfter(content, " ") {
+		chunk, _ := json.Marshal(StreamResponse{Choices: []StreamChoice{{Delta: StreamDelta{Content: word}}}})
+		fmt.Fprintf(&body, "data: %s\n\n", chunk)
+	}
+	body.WriteString("data: [DONE]\n\n")
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(&body),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,170 @@
+// Package: internal/llm/reasoning.go
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// Thinking modes for reasoning models.
+const (
+	ThinkingAuto = "auto" // Leave the model's default behaviour alone
+	ThinkingOn   = "on"   // Let the model reason, within the budget if one is set
+	ThinkingOff  = "off"  // Ask the model to answer without reasoning
+)
+
+// SetThinking controls how requests ask reasoning models (QwQ, DeepSeek-R1,
+// Qwen3) to think. A positive budget is passed to the model as a limit and
+// reserved on top of max_tokens so reasoning does not eat into the answer.
+func (c *Client) SetThinking(mode string, budgetTokens int) error {
+	switch mode {
+	case "":
+		mode = ThinkingAuto
+	case ThinkingAuto, ThinkingOn, ThinkingOff:
+	default:
+		return fmt.Errorf("unknown thinking mode %q (want auto, on or off)", mode)
+	}
+
+	c.thinkingMode = mode
+	c.thinkingBudget = budgetTokens
+	return nil
+}
+
+// prepareRequest applies the thinking settings and drops reasoning from the
+// history so it is never sent back to the model.
+func (c *Client) prepareRequest(req ChatRequest) ChatRequest {
+	messages := make([]Message, len(req.Messages))
+	copy(messages, req.Messages)
+	for i := range messages {
+		messages[i].Reasoning = ""
+	}
+	req.Messages = messages
+
+	var directive string
+	switch c.thinkingMode {
+	case ThinkingOff:
+		// Qwen3's soft switch; models without one still get their reasoning stripped
+		directive = "/no_think"
+	case ThinkingOn:
+		if c.thinkingBudget > 0 {
+			directive = fmt.Sprintf("Keep your reasoning brief: think for at most about %d tokens before answering.", c.thinkingBudget)
+			if req.MaxTokens > 0 {
+				req.MaxTokens += c.thinkingBudget
+			}
+		}
+	}
+
+	if directive != "" {
+		if len(messages) > 0 && messages[0].Role == "system" {
+			messages[0].Content += "\n\n" + directive
+		} else {
+			req.Messages = append([]Message{{Role: "system", Content: directive}}, messages...)
+		}
+	}
+
+	return req
+}
+
+// SplitReasoning separates <think>...</think> blocks from the answer. An
+// unterminated block (the model ran out of tokens while thinking) is all
+// reasoning; a closing tag without an opening one, as emitted by chat
+// templates that pre-fill <think>, ends a reasoning prefix.
+func SplitReasoning(content string) (reasoning, answer string) {
+	if !strings.Contains(content, thinkOpen) {
+		if before, after, found := strings.Cut(content, thinkClose); found {
+			return strings.TrimSpace(before), strings.TrimSpace(after)
+		}
+		return "", content
+	}
+
+	var thoughts, visible strings.Builder
+	rest := content
+	for {
+		before, after, found := strings.Cut(rest, thinkOpen)
+		visible.WriteString(before)
+		if !found {
+			break
+		}
+
+		thought, remaining, closed := strings.Cut(after, thinkClose)
+		if thoughts.Len() > 0 {
+			thoughts.WriteString("\n")
+		}
+		thoughts.WriteString(strings.TrimSpace(thought))
+		if !closed {
+			break
+		}
+		rest = remaining
+	}
+
+	return thoughts.String(), strings.TrimSpace(visible.String())
+}
+
+// ReasoningFilter removes <think> blocks from streamed content as it
+// arrives, holding back partial tags split across chunks.
+type ReasoningFilter struct {
+	thinking  bool
+	pending   string
+	reasoning strings.Builder
+}
+
+// Write consumes a chunk and returns the part of it that should be shown.
+func (f *ReasoningFilter) Write(chunk string) string {
+	text := f.pending + chunk
+	f.pending = ""
+
+	var visible strings.Builder
+	for text != "" {
+		tag := thinkOpen
+		if f.thinking {
+			tag = thinkClose
+		}
+
+		if i := strings.Index(text, tag); i != -1 {
+			f.emit(&visible, text[:i])
+			text = text[i+len(tag):]
+			f.thinking = !f.thinking
+			continue
+		}
+
+		// Hold back a suffix that could be the start of the tag
+		keep := 0
+		for n := len(tag) - 1; n > 0; n-- {
+			if strings.HasSuffix(text, tag[:n]) {
+				keep = n
+				break
+			}
+		}
+		f.emit(&visible, text[:len(text)-keep])
+		f.pending = text[len(text)-keep:]
+		break
+	}
+
+	return visible.String()
+}
+
+// Flush returns any held-back text once the stream has ended.
+func (f *ReasoningFilter) Flush() string {
+	var visible strings.Builder
+	f.emit(&visible, f.pending)
+	f.pending = ""
+	return visible.String()
+}
+
+// Reasoning returns the reasoning seen so far.
+func (f *ReasoningFilter) Reasoning() string {
+	return strings.TrimSpace(f.reasoning.String())
+}
+
+func (f *ReasoningFilter) emit(visible *strings.Builder, text string) {
+	if f.thinking {
+		f.reasoning.WriteString(text)
+	} else {
+		visible.WriteString(text)
+	}
+}
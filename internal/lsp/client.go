@@ -0,0 +1,418 @@
+// Package: internal/lsp/client.go
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	requestTimeout = 30 * time.Second
+
+	// startupQuiet is how long the server may stay silent after starting
+	// before it is taken to have nothing to report; loading a workspace
+	// takes longer than rechecking a changed file
+	startupQuiet = 3 * time.Second
+)
+
+// Severity of a diagnostic, as numbered by the protocol.
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// Diagnostic is a problem the server reported in a file.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Position is zero-based, with Character counted in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Client talks to a language server over its stdin and stdout. It opens
+// files and collects the diagnostics the server publishes for them; it does
+// not implement editing features. The server also exits when its stdin is
+// closed, should Close not be called.
+type Client struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	writeM sync.Mutex
+	nextID int64
+
+	mu          sync.Mutex
+	pending     map[int64]chan response
+	diagnostics map[string][]Diagnostic // By absolute path
+	versions    map[string]int          // Open documents by absolute path
+	activity    time.Time               // Last publish, open or change
+	published   bool
+	publishes   chan struct{} // Signaled on each publish
+	done        chan struct{}
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    *responseError
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Start runs the server command and initializes it for the workspace at
+// root.
+func Start(ctx context.Context, root string, command []string) (*Client, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no language server command")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	c := &Client{
+		cmd:         cmd,
+		stdin:       stdin,
+		pending:     make(map[int64]chan response),
+		diagnostics: make(map[string][]Diagnostic),
+		versions:    make(map[string]int),
+		activity:    time.Now(),
+		publishes:   make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	go c.readMessages(bufio.NewReader(stdout))
+
+	rootURI := fileURI(root)
+	params := map[string]interface{}{
+		"processId": nil,
+		"rootUri":   rootURI,
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"publishDiagnostics": map[string]interface{}{},
+				"synchronization":    map[string]interface{}{"didSave": false},
+			},
+			"workspace": map[string]interface{}{"configuration": true, "workspaceFolders": true},
+		},
+		"workspaceFolders": []map[string]string{{"uri": rootURI, "name": filepath.Base(root)}},
+	}
+	if _, err := c.call(ctx, "initialize", params); err != nil {
+		c.kill()
+		return nil, fmt.Errorf("failed to initialize %s: %w", command[0], err)
+	}
+	if err := c.notify("initialized", map[string]interface{}{}); err != nil {
+		c.kill()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Open sends a file's current content to the server, opening it the first
+// time and replacing its content after that.
+func (c *Client) Open(path, languageID, text string) error {
+	c.mu.Lock()
+	version, open := c.versions[path]
+	c.versions[path] = version + 1
+	c.activity = time.Now()
+	c.mu.Unlock()
+
+	uri := fileURI(path)
+	if !open {
+		return c.notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": languageID, "version": 1, "text": text},
+		})
+	}
+	return c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": version + 1},
+		"contentChanges": []map[string]string{{"text": text}},
+	})
+}
+
+// Diagnostics waits until the server has been quiet for quiet (longer
+// while it has not published anything yet) or ctx is done, and returns the
+// diagnostics it currently reports, by absolute path.
+func (c *Client) Diagnostics(ctx context.Context, quiet time.Duration) map[string][]Diagnostic {
+	for {
+		c.mu.Lock()
+		wait := quiet
+		if !c.published {
+			wait = max(quiet, startupQuiet)
+		}
+		remaining := time.Until(c.activity.Add(wait))
+		c.mu.Unlock()
+
+		if remaining <= 0 {
+			break
+		}
+		select {
+		case <-time.After(remaining):
+		case <-c.publishes:
+		case <-ctx.Done():
+			return c.snapshot()
+		case <-c.done:
+			return c.snapshot()
+		}
+	}
+	return c.snapshot()
+}
+
+func (c *Client) snapshot() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string][]Diagnostic, len(c.diagnostics))
+	for path, diags := range c.diagnostics {
+		out[path] = append([]Diagnostic(nil), diags...)
+	}
+	return out
+}
+
+// Close asks the server to shut down and exit, killing it if it doesn't.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := c.call(ctx, "shutdown", nil); err == nil {
+		c.notify("exit", nil)
+	}
+	c.stdin.Close()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.kill()
+	}
+	return c.cmd.Wait()
+}
+
+func (c *Client) kill() {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+}
+
+// call sends a request and waits for its response.
+func (c *Client) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id := atomic.AddInt64(&c.nextID, 1)
+	ch := make(chan response, 1)
+
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.write(message{ID: &rawID, Method: method, Params: marshalParams(params)}); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	select {
+	case resp := <-ch:
+		if resp.err != nil {
+			return nil, fmt.Errorf("%s failed: %s", method, resp.err.Message)
+		}
+		return resp.result, nil
+	case <-c.done:
+		return nil, fmt.Errorf("language server exited")
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+func (c *Client) notify(method string, params interface{}) error {
+	return c.write(message{Method: method, Params: marshalParams(params)})
+}
+
+// write frames a message with its Content-Length header.
+func (c *Client) write(msg message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeM.Lock()
+	defer c.writeM.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return fmt.Errorf("failed to write to language server: %w", err)
+	}
+	return nil
+}
+
+// readMessages handles everything the server sends until it exits.
+func (c *Client) readMessages(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		body, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		var msg message
+		if json.Unmarshal(body, &msg) != nil {
+			continue
+		}
+
+		switch {
+		case msg.Method == "textDocument/publishDiagnostics":
+			c.storeDiagnostics(msg.Params)
+		case msg.Method != "" && msg.ID != nil:
+			c.answer(msg)
+		case msg.Method == "" && msg.ID != nil:
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- response{result: msg.Result, err: msg.Error}
+			}
+		}
+	}
+}
+
+// answer replies to requests from the server, which wait for a response:
+// configuration gets defaults, everything else an empty result.
+func (c *Client) answer(msg message) {
+	var result interface{}
+	if msg.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		result = make([]interface{}, len(params.Items))
+	}
+	data, _ := json.Marshal(result)
+	c.write(message{ID: msg.ID, Result: data})
+}
+
+func (c *Client) storeDiagnostics(raw json.RawMessage) {
+	var params struct {
+		URI         string       `json:"uri"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	if json.Unmarshal(raw, &params) != nil {
+		return
+	}
+	path, err := uriPath(params.URI)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(params.Diagnostics) == 0 {
+		delete(c.diagnostics, path)
+	} else {
+		c.diagnostics[path] = params.Diagnostics
+	}
+	c.activity = time.Now()
+	c.published = true
+	select {
+	case c.publishes <- struct{}{}:
+	default:
+	}
+}
+
+// readFrame reads one message body, skipping headers other than
+// Content-Length.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func marshalParams(params interface{}) json.RawMessage {
+	if params == nil {
+		return nil
+	}
+	data, _ := json.Marshal(params)
+	return data
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func uriPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
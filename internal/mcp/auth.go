@@ -0,0 +1,143 @@
+// Package: internal/mcp/auth.go
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Anyone who reaches the server can run its tools, shell_execute among
+// them, so the network transports take a static bearer token: over HTTP in
+// the Authorization header of every request, over TCP in the _meta of
+// initialize, the first message of a connection. The Unix socket is
+// guarded by its file permissions instead. Clients may be given tokens of
+// their own, which tell them apart for the tool policies.
+
+// unauthorized is the error code of the response to a TCP connection's
+// first message when it does not carry the token.
+const unauthorized = -32001
+
+var errNoAuthToken = fmt.Errorf("serving MCP over the network needs an auth token; set one with SetAuthToken or AddClientToken")
+
+// identityKey is the key of the identity of an HTTP request's token in its
+// context.
+type identityKey struct{}
+
+// NewAuthToken returns a random token for SetAuthToken.
+func NewAuthToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create auth token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SetAuthToken sets the bearer token clients must present over TCP and
+// HTTP. It must be set before those transports are started.
+func (s *Server) SetAuthToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authToken = token
+}
+
+// AddClientToken adds a token that identifies a client as identity, which
+// SetToolPolicy can name; it is accepted like the one of SetAuthToken.
+func (s *Server) AddClientToken(identity, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clientTokens == nil {
+		s.clientTokens = make(map[string]string)
+	}
+	s.clientTokens[identity] = token
+}
+
+func (s *Server) hasAuthToken() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.authToken != "" || len(s.clientTokens) > 0
+}
+
+// identify reports whether an Authorization value ("Bearer <token>")
+// carries one of the server's tokens, and the identity of a client's own;
+// the shared token has none.
+func (s *Server) identify(authorization string) (string, bool) {
+	scheme, presented, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	presented = strings.TrimSpace(presented)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Every token is compared, so the time taken does not tell which matched
+	identity, found := "", false
+	if s.authToken != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(s.authToken)) == 1 {
+		found = true
+	}
+	for name, token := range s.clientTokens {
+		if token != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			identity, found = name, true
+		}
+	}
+	return identity, found
+}
+
+// requireAuth refuses HTTP requests without a token, and passes the
+// identity of the token on in the request's context.
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := s.identify(r.Header.Get("Authorization"))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+	})
+}
+
+// requestIdentity returns the identity of an HTTP request's token.
+func requestIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value(identityKey{}).(string)
+	return identity
+}
+
+// authorizedInitialize reports whether req is an initialize carrying a
+// token, which a TCP connection has to start with, and its identity.
+func (s *Server) authorizedInitialize(req MCPRequest) (string, bool) {
+	if req.Method != "initialize" {
+		return "", false
+	}
+	var params InitializeParams
+	if paramsData, ok := req.Params.(map[string]interface{}); ok {
+		paramsJSON, _ := json.Marshal(paramsData)
+		json.Unmarshal(paramsJSON, &params)
+	}
+	if params.Meta == nil {
+		return "", false
+	}
+	return s.identify(params.Meta.Authorization)
+}
+
+// SetAuthToken sets the bearer token the client presents: in the
+// Authorization header over HTTP, and with initialize over TCP.
+func (c *Client) SetAuthToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authToken = token
+}
+
+func (c *Client) authorization() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.authToken == "" {
+		return ""
+	}
+	return "Bearer " + c.authToken
+}
@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+// newAuthServer returns a server with the shared test token and a token of
+// the ci client's own.
+func newAuthServer() *Server {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	s.SetAuthToken(testToken)
+	s.AddClientToken("ci", "ci-token")
+	return s
+}
+
+func TestIdentify(t *testing.T) {
+	s := newAuthServer()
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantIdentity  string
+		wantOK        bool
+	}{
+		{name: "shared token", authorization: "Bearer " + testToken, wantOK: true},
+		{name: "client token", authorization: "Bearer ci-token", wantIdentity: "ci", wantOK: true},
+		{name: "scheme in any case", authorization: "bearer " + testToken, wantOK: true},
+		{name: "surrounding spaces", authorization: "Bearer  " + testToken + " ", wantOK: true},
+		{name: "empty", authorization: ""},
+		{name: "no scheme", authorization: testToken},
+		{name: "other scheme", authorization: "Basic " + testToken},
+		{name: "wrong token", authorization: "Bearer wrong-token"},
+		{name: "prefix of the token", authorization: "Bearer " + testToken[:4]},
+		{name: "empty token", authorization: "Bearer "},
+		{name: "identity instead of its token", authorization: "Bearer ci"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, ok := s.identify(tt.authorization)
+			if identity != tt.wantIdentity || ok != tt.wantOK {
+				t.Errorf("identify(%q) = %q, %v, want %q, %v", tt.authorization, identity, ok, tt.wantIdentity, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEmptyClientTokenIsNotAccepted(t *testing.T) {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	s.AddClientToken("ci", "")
+	if _, ok := s.identify("Bearer "); ok {
+		t.Error("an empty client token was accepted")
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	s := newAuthServer()
+	var identity string
+	handler := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = requestIdentity(r)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantIdentity  string
+	}{
+		{name: "shared token", authorization: "Bearer " + testToken, wantStatus: http.StatusOK},
+		{name: "client token", authorization: "Bearer ci-token", wantStatus: http.StatusOK, wantIdentity: "ci"},
+		{name: "no header", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer wrong-token", wantStatus: http.StatusUnauthorized},
+		{name: "token without scheme", authorization: testToken, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity = ""
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+			if identity != tt.wantIdentity {
+				t.Errorf("identity = %q, want %q", identity, tt.wantIdentity)
+			}
+		})
+	}
+}
+
+func TestAuthorizedInitialize(t *testing.T) {
+	s := newAuthServer()
+	withMeta := func(meta map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"clientInfo": map[string]interface{}{"name": "test-client", "version": "1.0"},
+			"_meta":      meta,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		req          MCPRequest
+		wantIdentity string
+		wantOK       bool
+	}{
+		{
+			name:   "initialize with the token",
+			req:    MCPRequest{Method: "initialize", Params: withMeta(map[string]interface{}{"authorization": "Bearer " + testToken})},
+			wantOK: true,
+		},
+		{
+			name:         "initialize with a client token",
+			req:          MCPRequest{Method: "initialize", Params: withMeta(map[string]interface{}{"authorization": "Bearer ci-token"})},
+			wantIdentity: "ci",
+			wantOK:       true,
+		},
+		{
+			name: "another method first",
+			req:  MCPRequest{Method: "tools/list", Params: withMeta(map[string]interface{}{"authorization": "Bearer " + testToken})},
+		},
+		{
+			name: "no params",
+			req:  MCPRequest{Method: "initialize"},
+		},
+		{
+			name: "no _meta",
+			req:  MCPRequest{Method: "initialize", Params: map[string]interface{}{"clientInfo": map[string]interface{}{"name": "test-client"}}},
+		},
+		{
+			name: "_meta without authorization",
+			req:  MCPRequest{Method: "initialize", Params: withMeta(map[string]interface{}{"progressToken": 1})},
+		},
+		{
+			name: "wrong token",
+			req:  MCPRequest{Method: "initialize", Params: withMeta(map[string]interface{}{"authorization": "Bearer wrong-token"})},
+		},
+		{
+			name: "authorization of the wrong type",
+			req:  MCPRequest{Method: "initialize", Params: withMeta(map[string]interface{}{"authorization": 42})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, ok := s.authorizedInitialize(tt.req)
+			if identity != tt.wantIdentity || ok != tt.wantOK {
+				t.Errorf("authorizedInitialize() = %q, %v, want %q, %v", identity, ok, tt.wantIdentity, tt.wantOK)
+			}
+		})
+	}
+}
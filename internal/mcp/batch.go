@@ -0,0 +1,276 @@
+// Package: internal/mcp/batch.go
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// invalidRequest is the error code of the response to a message that is
+// not a valid JSON-RPC request.
+const invalidRequest = -32600
+
+// BatchCall is a request of a batch.
+type BatchCall struct {
+	Method string
+	Params interface{}
+}
+
+// ResourceResult is the content of a resource ReadResources read, or why
+// it could not be read.
+type ResourceResult struct {
+	URI      string
+	Contents []ResourceContents
+	Err      error
+}
+
+// ResourceContents is the content of a resource as resources/read gives
+// it: text, or a base64 blob.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// isBatch reports whether a message is a JSON-RPC batch, an array of
+// messages.
+func isBatch(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+}
+
+// batchMessages returns the messages of a batch, or the message itself
+// when it is not one.
+func batchMessages(data []byte) []json.RawMessage {
+	data = bytes.TrimSpace(data)
+	if !isBatch(data) {
+		return []json.RawMessage{data}
+	}
+	var messages []json.RawMessage
+	json.Unmarshal(data, &messages)
+	return messages
+}
+
+// handleBatch runs the messages of a batch, its requests at once as a
+// connection's would, and returns what to answer it with: the responses
+// to its requests in order, nil when it had only notifications and
+// responses, or a lone error when it is empty. Initialize cannot be
+// batched.
+func (s *Server) handleBatch(p *peer, data []byte) interface{} {
+	messages := batchMessages(data)
+	if len(messages) == 0 {
+		return MCPResponse{
+			JSONRPC: "2.0",
+			Error: &MCPError{
+				Code:    invalidRequest,
+				Message: "Invalid Request: empty batch",
+			},
+		}
+	}
+
+	// Each request has its place among the responses before it runs
+	responses := make([]MCPResponse, 0, len(messages))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConnectionRequests)
+	for _, raw := range messages {
+		var req MCPRequest
+		if json.Unmarshal(raw, &req) != nil {
+			responses = append(responses, MCPResponse{
+				JSONRPC: "2.0",
+				Error: &MCPError{
+					Code:    invalidRequest,
+					Message: "Invalid Request",
+				},
+			})
+			continue
+		}
+		if req.ID == nil {
+			s.handleNotification(p, req)
+			continue
+		}
+		if req.Method == "" {
+			p.deliver(raw)
+			continue
+		}
+
+		responses = append(responses, MCPResponse{})
+		i := len(responses) - 1
+		if req.Method == "initialize" {
+			responses[i] = MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &MCPError{
+					Code:    invalidRequest,
+					Message: "Invalid Request: initialize cannot be batched",
+				},
+			}
+			continue
+		}
+		if !s.beginRequest() {
+			responses[i] = refuseRequest(req)
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(resp *MCPResponse, req MCPRequest) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer s.endRequest()
+			*resp = s.handleRequest(p, req)
+		}(&responses[i], req)
+	}
+	wg.Wait()
+
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// Batch sends calls to the server as one batch and waits for every
+// response, in the order of calls, so many requests cost one round trip.
+// It waits as long as the slowest of their methods may take (see
+// SetTimeout) or until ctx is done, cancelling those still running on the
+// server. The server must support batches, as claude-go's does.
+func (c *Client) Batch(ctx context.Context, calls []BatchCall) ([]MCPResponse, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	reqs := make([]MCPRequest, len(calls))
+	respChans := make([]chan MCPResponse, len(calls))
+	var wait time.Duration
+	for i, call := range calls {
+		reqs[i] = MCPRequest{
+			JSONRPC: "2.0",
+			ID:      c.nextRequestID(),
+			Method:  call.Method,
+			Params:  call.Params,
+		}
+		respChans[i] = make(chan MCPResponse, 1)
+		// A method without a timeout makes the batch wait for ctx alone
+		if d := c.requestTimeout(call.Method); d == 0 || wait < 0 {
+			wait = -1
+		} else {
+			wait = max(wait, d)
+		}
+	}
+
+	c.mu.Lock()
+	for i, req := range reqs {
+		c.responses[req.ID] = respChans[i]
+	}
+	done := c.done
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		for _, req := range reqs {
+			delete(c.responses, req.ID)
+		}
+		c.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// A lost connection fails the batch at once
+	select {
+	case <-done:
+		return nil, c.closedError()
+	default:
+	}
+	if err := c.write(reqs); err != nil {
+		select {
+		case <-done:
+			return nil, c.closedError()
+		default:
+		}
+		return nil, fmt.Errorf("failed to send batch: %w", err)
+	}
+
+	responses := make([]MCPResponse, len(reqs))
+	for i := range reqs {
+		select {
+		case responses[i] = <-respChans[i]:
+		case <-done:
+			select {
+			case responses[i] = <-respChans[i]:
+				continue
+			default:
+			}
+			return nil, c.closedError()
+		case <-ctx.Done():
+			c.cancelUnanswered(reqs[i:], respChans[i:], ctx.Err().Error())
+			return nil, ctx.Err()
+		case <-timeout:
+			c.cancelUnanswered(reqs[i:], respChans[i:], "request timeout")
+			return nil, fmt.Errorf("batch of %d requests timed out after %s", len(reqs), wait)
+		}
+	}
+	return responses, nil
+}
+
+// cancelUnanswered cancels on the server the requests of a batch that got
+// no response yet.
+func (c *Client) cancelUnanswered(reqs []MCPRequest, respChans []chan MCPResponse, reason string) {
+	for i, req := range reqs {
+		if len(respChans[i]) == 0 {
+			c.notify("notifications/cancelled", CancelledParams{RequestID: req.ID, Reason: reason})
+		}
+	}
+}
+
+// ReadResource reads a resource of the server.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	resp, err := c.sendRequestContext(ctx, MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "resources/read",
+		Params:  ReadResourceParams{URI: uri},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resourceContents(uri, resp)
+}
+
+// ReadResources reads many resources in one batch, in the order of uris;
+// a resource that could not be read has the error of it.
+func (c *Client) ReadResources(ctx context.Context, uris []string) ([]ResourceResult, error) {
+	calls := make([]BatchCall, len(uris))
+	for i, uri := range uris {
+		calls[i] = BatchCall{Method: "resources/read", Params: ReadResourceParams{URI: uri}}
+	}
+	responses, err := c.Batch(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ResourceResult, len(uris))
+	for i, resp := range responses {
+		results[i].URI = uris[i]
+		results[i].Contents, results[i].Err = resourceContents(uris[i], resp)
+	}
+	return results, nil
+}
+
+func resourceContents(uri string, resp MCPResponse) ([]ResourceContents, error) {
+	if resp.Error != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", uri, resp.Error.Message)
+	}
+	var result struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	resultJSON, _ := json.Marshal(resp.Result)
+	json.Unmarshal(resultJSON, &result)
+	return result.Contents, nil
+}
@@ -0,0 +1,273 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+func TestHandleBatch(t *testing.T) {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	p := s.addPeer(func([]byte) {})
+	defer s.removePeer(p)
+
+	tests := []struct {
+		name  string
+		batch string
+		want  []string // Each response as its ID and error code, 0 for none; nil for no answer
+	}{
+		{
+			name:  "requests answered in order",
+			batch: `[{"jsonrpc": "2.0", "id": 1, "method": "ping"}, {"jsonrpc": "2.0", "id": 2, "method": "tools/list"}]`,
+			want:  []string{"1 0", "2 0"},
+		},
+		{
+			name:  "unknown method among others",
+			batch: `[{"jsonrpc": "2.0", "id": 1, "method": "ping"}, {"jsonrpc": "2.0", "id": 2, "method": "nope"}, {"jsonrpc": "2.0", "id": 3, "method": "ping"}]`,
+			want:  []string{"1 0", "2 -32601", "3 0"},
+		},
+		{
+			name:  "notifications are not answered",
+			batch: `[{"jsonrpc": "2.0", "method": "notifications/initialized"}, {"jsonrpc": "2.0", "id": 1, "method": "ping"}]`,
+			want:  []string{"1 0"},
+		},
+		{
+			name:  "only notifications",
+			batch: `[{"jsonrpc": "2.0", "method": "notifications/initialized"}, {"jsonrpc": "2.0", "method": "notifications/cancelled", "params": {"requestId": 9}}]`,
+		},
+		{
+			name:  "responses are not answered",
+			batch: `[{"jsonrpc": "2.0", "id": "srv-1", "result": {}}]`,
+		},
+		{
+			name:  "empty batch",
+			batch: `[]`,
+			want:  []string{"<nil> -32600"},
+		},
+		{
+			name:  "message that is not a request",
+			batch: `[1, {"jsonrpc": "2.0", "id": 1, "method": "ping"}]`,
+			want:  []string{"<nil> -32600", "1 0"},
+		},
+		{
+			name:  "initialize cannot be batched",
+			batch: `[{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}, {"jsonrpc": "2.0", "id": 2, "method": "ping"}]`,
+			want:  []string{"1 -32600", "2 0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var responses []MCPResponse
+			switch answer := s.handleBatch(p, []byte(tt.batch)).(type) {
+			case nil:
+			case MCPResponse:
+				responses = []MCPResponse{answer}
+			case []MCPResponse:
+				responses = answer
+			default:
+				t.Fatalf("handleBatch() = %T", answer)
+			}
+
+			var got []string
+			for _, resp := range responses {
+				code := 0
+				if resp.Error != nil {
+					code = resp.Error.Code
+				}
+				got = append(got, fmt.Sprint(resp.ID, " ", code))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || (got == nil) != (tt.want == nil) {
+				t.Errorf("handleBatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBatchServer answers each batch on a pipe the client is connected to
+// with what respond returns for it, sending nothing when that is nil, and
+// passes on the notifications the client sends. The connection closes
+// after the first batch when hangUp is set.
+func fakeBatchServer(t *testing.T, hangUp bool, respond func([]MCPRequest) []MCPResponse) (*Client, <-chan MCPRequest) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() { serverConn.Close() })
+
+	notifications := make(chan MCPRequest, 16)
+	go func() {
+		decoder := json.NewDecoder(serverConn)
+		encoder := json.NewEncoder(serverConn)
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return
+			}
+			if !isBatch(raw) {
+				var notification MCPRequest
+				if json.Unmarshal(raw, &notification) == nil {
+					notifications <- notification
+				}
+				continue
+			}
+			var reqs []MCPRequest
+			if err := json.Unmarshal(raw, &reqs); err != nil {
+				return
+			}
+			if hangUp {
+				serverConn.Close()
+				return
+			}
+			if responses := respond(reqs); responses != nil {
+				if err := encoder.Encode(responses); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	c := NewMCPClient()
+	t.Cleanup(func() { c.Close() })
+	c.start(clientConn, clientConn, clientConn)
+	return c, notifications
+}
+
+func TestReadResources(t *testing.T) {
+	contents := func(req MCPRequest) MCPResponse {
+		uri := req.Params.(map[string]interface{})["uri"].(string)
+		if strings.HasSuffix(uri, "missing") {
+			return MCPResponse{JSONRPC: "2.0", ID: req.ID, Error: &MCPError{Code: -32602, Message: "Resource not found"}}
+		}
+		return MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+			"contents": []ResourceContents{{URI: uri, Text: "text of " + uri}},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		hangUp  bool
+		respond func([]MCPRequest) []MCPResponse
+		timeout time.Duration
+		want    []string // Each result as its text or error
+		wantErr string
+		// Requests cancelled on the server when the batch gave up
+		wantCancelled int
+	}{
+		{
+			name: "responses in order",
+			respond: func(reqs []MCPRequest) []MCPResponse {
+				var responses []MCPResponse
+				for _, req := range reqs {
+					responses = append(responses, contents(req))
+				}
+				return responses
+			},
+			want: []string{"text of a", "text of b"},
+		},
+		{
+			name: "responses out of order",
+			respond: func(reqs []MCPRequest) []MCPResponse {
+				var responses []MCPResponse
+				for i := len(reqs) - 1; i >= 0; i-- {
+					responses = append(responses, contents(reqs[i]))
+				}
+				return responses
+			},
+			want: []string{"text of a", "text of b"},
+		},
+		{
+			name: "one resource missing",
+			respond: func(reqs []MCPRequest) []MCPResponse {
+				reqs[1].Params = map[string]interface{}{"uri": "missing"}
+				return []MCPResponse{contents(reqs[0]), contents(reqs[1])}
+			},
+			want: []string{"text of a", "failed to read b: Resource not found"},
+		},
+		{
+			name:    "connection lost",
+			hangUp:  true,
+			wantErr: "closed the connection",
+		},
+		{
+			name: "no answer before the context is done",
+			respond: func(reqs []MCPRequest) []MCPResponse {
+				return nil
+			},
+			timeout:       50 * time.Millisecond,
+			wantErr:       context.DeadlineExceeded.Error(),
+			wantCancelled: 2,
+		},
+		{
+			name: "one response only",
+			respond: func(reqs []MCPRequest) []MCPResponse {
+				return []MCPResponse{contents(reqs[0])}
+			},
+			timeout:       50 * time.Millisecond,
+			wantErr:       context.DeadlineExceeded.Error(),
+			wantCancelled: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			c, notifications := fakeBatchServer(t, tt.hangUp, tt.respond)
+			results, err := c.ReadResources(ctx, []string{"a", "b"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadResources() error = %v, want %q", err, tt.wantErr)
+				}
+				for i := 0; i < tt.wantCancelled; i++ {
+					select {
+					case n := <-notifications:
+						if n.Method != "notifications/cancelled" {
+							t.Errorf("notification %q, want notifications/cancelled", n.Method)
+						}
+					case <-time.After(time.Second):
+						t.Fatalf("%d requests cancelled, want %d", i, tt.wantCancelled)
+					}
+				}
+				select {
+				case n := <-notifications:
+					t.Errorf("unexpected %s notification", n.Method)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, result := range results {
+				switch {
+				case result.Err != nil:
+					got = append(got, result.Err.Error())
+				case len(result.Contents) == 1:
+					got = append(got, result.Contents[0].Text)
+				default:
+					got = append(got, fmt.Sprintf("%d contents", len(result.Contents)))
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ReadResources() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyBatchIsNotSent(t *testing.T) {
+	c, _ := fakeBatchServer(t, true, nil)
+	if responses, err := c.Batch(context.Background(), nil); responses != nil || err != nil {
+		t.Errorf("Batch(nil) = %v, %v, want nothing sent", responses, err)
+	}
+}
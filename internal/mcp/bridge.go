@@ -0,0 +1,123 @@
+// Package: internal/mcp/bridge.go
+package mcp
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+// toolSeparator joins the name of a server to the names of its tools.
+const toolSeparator = "__"
+
+// remoteTool offers a tool of a connected MCP server as a registry tool,
+// named after the server as well (see namespacedTool) and called on it by
+// its own name.
+type remoteTool struct {
+	client *Client
+	server string
+	tool   MCPTool
+}
+
+func (t *remoteTool) Name() string { return namespacedTool(t.server, t.tool.Name) }
+
+// namespacedTool names the tool of a server in the registry: the server's
+// name, with what a tool name cannot hold replaced by "_", then "__" and
+// the tool's own name (e.g. github__search), so servers with tools of the
+// same name do not clash with each other or with the built-in tools.
+func namespacedTool(server, tool string) string {
+	prefix := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, server)
+	return prefix + toolSeparator + tool
+}
+
+func (t *remoteTool) Description() string {
+	if t.tool.Description == "" {
+		return "A tool of the " + t.server + " MCP server"
+	}
+	return t.tool.Description + " (from the " + t.server + " MCP server)"
+}
+
+func (t *remoteTool) Parameters() interface{} {
+	if t.tool.InputSchema == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.tool.InputSchema
+}
+
+func (t *remoteTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+// ExecuteContext calls the tool on its server, cancelling the call there
+// when ctx is cancelled.
+func (t *remoteTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return t.client.CallToolContext(ctx, t.tool.Name, args)
+}
+
+// ExecuteContent calls the tool like ExecuteContext, keeping the content
+// it returns as it is, so a server that serves it passes it on.
+func (t *remoteTool) ExecuteContent(ctx context.Context, args map[string]interface{}, stream io.Writer) (*tools.Result, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return t.client.CallToolResult(ctx, t.tool.Name, args)
+}
+
+// Tools returns the tools of the servers connected now, as registry tools
+// named after their servers. Should two servers' names come out the same
+// once namespaced, the server first by name provides the tool.
+func (m *Manager) Tools() []tools.Tool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var remote []tools.Tool
+	seen := make(map[string]bool)
+	for _, name := range names {
+		server := m.servers[name]
+		if server.status.State != StateConnected {
+			continue
+		}
+		for _, tool := range server.status.Tools {
+			namespaced := namespacedTool(name, tool.Name)
+			if !seen[namespaced] {
+				remote = append(remote, &remoteTool{client: server.client, server: name, tool: tool})
+				seen[namespaced] = true
+			}
+		}
+	}
+	return remote
+}
+
+// Wait waits until each enabled server has been tried once, or timeout
+// passed, so a session that runs a single request starts with their
+// tools.
+func (m *Manager) Wait(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		m.starting.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,107 @@
+// Package: internal/mcp/cancel.go
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// requestCancelled is the error code of the response to a cancelled
+// request, which the side that cancelled it ignores.
+const requestCancelled = -32800
+
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// inFlight is the cancellable requests of a peer that are running, by ID.
+type inFlight struct {
+	mu      sync.Mutex
+	cancels map[interface{}]context.CancelFunc
+}
+
+func newInFlight() *inFlight {
+	return &inFlight{cancels: make(map[interface{}]context.CancelFunc)}
+}
+
+// start returns the context of the request with ID id, cancelled by a
+// cancellation of it, and the function to call once it is done.
+func (f *inFlight) start(id interface{}) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.mu.Lock()
+	f.cancels[id] = cancel
+	f.mu.Unlock()
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.cancels, id)
+		f.mu.Unlock()
+		cancel()
+	}
+}
+
+func (f *inFlight) cancel(id interface{}) {
+	f.mu.Lock()
+	cancel := f.cancels[id]
+	f.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// cancelAll stops every request, when the peer has gone.
+func (f *inFlight) cancelAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+}
+
+// handleNotification acts on a notification from a client: a cancellation
+// stops the request it names, if it is still running, and once a client
+// that offers roots is initialized or says they changed, they are fetched.
+func (s *Server) handleNotification(p *peer, req MCPRequest) {
+	switch req.Method {
+	case "notifications/cancelled":
+	case "notifications/initialized", "notifications/roots/list_changed":
+		p.mu.Lock()
+		offersRoots := p.offersRoots
+		p.mu.Unlock()
+		if offersRoots {
+			go s.fetchRoots(p)
+		}
+		return
+	default:
+		return
+	}
+	var params CancelledParams
+	if paramsData, ok := req.Params.(map[string]interface{}); ok {
+		paramsJSON, _ := json.Marshal(paramsData)
+		json.Unmarshal(paramsJSON, &params)
+	}
+	if params.RequestID != nil {
+		p.calls.cancel(params.RequestID)
+	}
+}
+
+// Cancel tells the server to stop the request with ID id, which fails at
+// once with the reason; what the server answers it later is ignored.
+// Requests made with a context are cancelled when it is.
+func (c *Client) Cancel(id int64, reason string) error {
+	message := "request cancelled"
+	if reason != "" {
+		message += ": " + reason
+	}
+	c.mu.RLock()
+	if respChan, exists := c.responses[id]; exists {
+		select {
+		case respChan <- MCPResponse{JSONRPC: "2.0", ID: id, Error: &MCPError{Code: requestCancelled, Message: message}}:
+		default:
+		}
+	}
+	c.mu.RUnlock()
+
+	return c.notify("notifications/cancelled", CancelledParams{RequestID: id, Reason: reason})
+}
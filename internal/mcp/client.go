@@ -2,84 +2,234 @@
 package mcp
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
 )
 
+// stdioExitTimeout is how long Close waits for a stdio server to exit
+// after its stdin is closed before killing it.
+const stdioExitTimeout = 5 * time.Second
+
 type Client struct {
-	conn       net.Conn
+	conn       io.Closer // The socket, or the server's stdin for stdio
 	encoder    *json.Encoder
-	decoder    *json.Decoder
+	writeMu    sync.Mutex
+	done       chan struct{} // Closed when the connection stops delivering messages
 	requestID  int64
 	responses  map[interface{}]chan MCPResponse
 	mu         sync.RWMutex
 	serverInfo ServerInfo
+	authToken  string
+	tlsConfig  *tls.Config // Set by SetTLS
+
+	onToolsChanged func()                    // Called when the server says its tools changed
+	sampler        Sampler                   // Answers the server's sampling requests, when set
+	calls          map[int64]context.Context // Tool calls running, by request ID
+	timeouts       map[string]time.Duration  // By method, set by SetTimeout
+	roots          []Root                    // Offered to the server when set
+	rootsAnnounced bool                      // Whether Initialize offered the roots
+	frames         *FrameLog                 // Logs every message, when set
+	framesServer   string                    // The server's name in frames
+
+	// Set for a server spawned by ConnectStdio
+	process *stdioProcess
+
+	// Set by SetReconnect
+	reconnect    bool
+	redial       func() error  // Connects again the way the client last connected
+	reconnecting bool          // Whether a reconnect loop is running
+	closed       bool          // Set by Close, which stops reconnecting
+	closing      chan struct{} // Closed by Close
+	clientInfo   ClientInfo    // Given to Initialize, to initialize again
+}
+
+// stdioProcess is a server spawned by ConnectStdio.
+type stdioProcess struct {
+	cmd    *exec.Cmd
+	stdout *os.File
+	stderr *tailBuffer
+	exited chan struct{}
+}
+
+// MCPNotification is a JSON-RPC message that expects no response.
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 func NewMCPClient() *Client {
 	return &Client{
 		responses: make(map[interface{}]chan MCPResponse),
+		calls:     make(map[int64]context.Context),
+		closing:   make(chan struct{}),
 	}
 }
 
 func (c *Client) ConnectUnix(socketPath string) error {
+	c.setRedial(func() error { return c.ConnectUnix(socketPath) })
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		return fmt.Errorf("failed to connect to unix socket: %w", err)
 	}
 
-	c.conn = conn
-	c.encoder = json.NewEncoder(conn)
-	c.decoder = json.NewDecoder(conn)
-
-	go c.readResponses()
+	c.start(conn, conn, conn)
 	return nil
 }
 
 func (c *Client) ConnectTCP(host string, port int) error {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", host, port))
+	c.setRedial(func() error { return c.ConnectTCP(host, port) })
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		return fmt.Errorf("failed to connect to TCP: %w", err)
 	}
 
-	c.conn = conn
-	c.encoder = json.NewEncoder(conn)
-	c.decoder = json.NewDecoder(conn)
+	c.start(conn, conn, conn)
+	return nil
+}
+
+// ConnectStdio launches command with args as an MCP server and speaks
+// JSON-RPC over its stdin and stdout, one message per line. env is added
+// to the environment claude-go runs with. What the server writes to
+// stderr is kept for error messages.
+func (c *Client) ConnectStdio(command string, args []string, env map[string]string) error {
+	c.setRedial(func() error { return c.ConnectStdio(command, args, env) })
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	// Stdout is an os.Pipe rather than StdoutPipe, so the reader can keep
+	// reading while Wait runs
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	cmd.Stdout = stdoutWriter
+	process := &stdioProcess{cmd: cmd, stdout: stdout, stderr: &tailBuffer{}, exited: make(chan struct{})}
+	cmd.Stderr = process.stderr
+
+	err = cmd.Start()
+	stdoutWriter.Close()
+	if err != nil {
+		stdout.Close()
+		return fmt.Errorf("failed to start MCP server %s: %w", command, err)
+	}
 
-	go c.readResponses()
+	go func() {
+		cmd.Wait()
+		close(process.exited)
+	}()
+
+	c.mu.Lock()
+	c.process = process
+	c.mu.Unlock()
+	c.start(stdout, stdin, stdin)
 	return nil
 }
 
+// start begins speaking JSON-RPC over r and w; closing conn ends the
+// connection.
+func (c *Client) start(r io.Reader, w io.Writer, conn io.Closer) {
+	done := make(chan struct{})
+	c.writeMu.Lock()
+	c.encoder = json.NewEncoder(w)
+	c.writeMu.Unlock()
+	c.mu.Lock()
+	c.conn = conn
+	c.done = done
+	c.mu.Unlock()
+
+	go c.readResponses(json.NewDecoder(r), done)
+}
+
+// Close ends the connection, and stops reconnecting. A stdio server is
+// asked to exit by closing its stdin, and killed when it has not after
+// stdioExitTimeout.
 func (c *Client) Close() error {
-	if c.conn != nil {
-		return c.conn.Close()
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.closing)
+	}
+	c.mu.Unlock()
+	return c.closeConnection()
+}
+
+func (c *Client) closeConnection() error {
+	c.mu.RLock()
+	conn, process := c.conn, c.process
+	c.mu.RUnlock()
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	if process == nil {
+		return err
 	}
+
+	select {
+	case <-process.exited:
+	case <-time.After(stdioExitTimeout):
+		process.cmd.Process.Kill()
+		<-process.exited
+	}
+	process.stdout.Close()
 	return nil
 }
 
 func (c *Client) Initialize(clientName, clientVersion string) error {
+	return c.InitializeContext(context.Background(), clientName, clientVersion)
+}
+
+// InitializeContext initializes the connection like Initialize, giving up
+// when ctx is done.
+func (c *Client) InitializeContext(ctx context.Context, clientName, clientVersion string) error {
+	params := InitializeParams{
+		ProtocolVersion: "2024-11-05",
+		Capabilities:    ClientCapabilities{},
+		ClientInfo: ClientInfo{
+			Name:    clientName,
+			Version: clientVersion,
+		},
+	}
+	c.mu.RLock()
+	if c.sampler != nil {
+		params.Capabilities.Sampling = &struct{}{}
+	}
+	if c.roots != nil {
+		params.Capabilities.Roots = &RootsCapability{ListChanged: true}
+	}
+	c.mu.RUnlock()
+	if authorization := c.authorization(); authorization != "" {
+		params.Meta = &RequestMeta{Authorization: authorization}
+	}
 	req := MCPRequest{
 		JSONRPC: "2.0",
 		ID:      c.nextRequestID(),
 		Method:  "initialize",
-		Params: InitializeParams{
-			ProtocolVersion: "2024-11-05",
-			Capabilities: ClientCapabilities{
-				Roots:    true,
-				Sampling: false,
-			},
-			ClientInfo: ClientInfo{
-				Name:    clientName,
-				Version: clientVersion,
-			},
-		},
+		Params:  params,
 	}
 
-	resp, err := c.sendRequest(req)
+	resp, err := c.sendRequestContext(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -87,44 +237,97 @@ func (c *Client) Initialize(clientName, clientVersion string) error {
 	if resp.Error != nil {
 		return fmt.Errorf("initialize failed: %s", resp.Error.Message)
 	}
+	c.mu.Lock()
+	c.clientInfo = params.ClientInfo
+	c.mu.Unlock()
 
 	var result InitializeResult
 	resultJSON, _ := json.Marshal(resp.Result)
 	json.Unmarshal(resultJSON, &result)
 
+	c.mu.Lock()
 	c.serverInfo = result.ServerInfo
-	return nil
+	c.rootsAnnounced = params.Capabilities.Roots != nil
+	c.mu.Unlock()
+	return c.notify("notifications/initialized", nil)
 }
 
+// ListTools lists the server's tools, following its pages to the end.
 func (c *Client) ListTools() ([]MCPTool, error) {
-	req := MCPRequest{
-		JSONRPC: "2.0",
-		ID:      c.nextRequestID(),
-		Method:  "tools/list",
-	}
+	return c.ListToolsContext(context.Background())
+}
 
-	resp, err := c.sendRequest(req)
+func (c *Client) ListToolsContext(ctx context.Context) ([]MCPTool, error) {
+	var tools []MCPTool
+	err := c.listAll(ctx, "tools/list", func(resultJSON []byte) string {
+		var result struct {
+			Tools      []MCPTool `json:"tools"`
+			NextCursor string    `json:"nextCursor"`
+		}
+		json.Unmarshal(resultJSON, &result)
+		tools = append(tools, result.Tools...)
+		return result.NextCursor
+	})
 	if err != nil {
 		return nil, err
 	}
+	return tools, nil
+}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("list tools failed: %s", resp.Error.Message)
-	}
+// ListResources lists the server's resources, following its pages to the
+// end.
+func (c *Client) ListResources() ([]Resource, error) {
+	return c.ListResourcesContext(context.Background())
+}
 
-	var result struct {
-		Tools []MCPTool `json:"tools"`
+func (c *Client) ListResourcesContext(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	err := c.listAll(ctx, "resources/list", func(resultJSON []byte) string {
+		var result struct {
+			Resources  []Resource `json:"resources"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		json.Unmarshal(resultJSON, &result)
+		resources = append(resources, result.Resources...)
+		return result.NextCursor
+	})
+	if err != nil {
+		return nil, err
 	}
-	resultJSON, _ := json.Marshal(resp.Result)
-	json.Unmarshal(resultJSON, &result)
-
-	return result.Tools, nil
+	return resources, nil
 }
 
 func (c *Client) CallTool(name string, arguments map[string]interface{}) (string, error) {
+	return c.CallToolContext(context.Background(), name, arguments)
+}
+
+// CallToolContext calls a tool like CallTool; cancelling ctx cancels the
+// call on the server.
+func (c *Client) CallToolContext(ctx context.Context, name string, arguments map[string]interface{}) (string, error) {
+	result, err := c.CallToolResult(ctx, name, arguments)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// CallToolResult calls a tool like CallToolContext, returning its content
+// blocks as they are rather than as text.
+func (c *Client) CallToolResult(ctx context.Context, name string, arguments map[string]interface{}) (*tools.Result, error) {
+	id := c.nextRequestID()
+	// What the server asks of the client meanwhile is on the call's behalf
+	c.mu.Lock()
+	c.calls[id] = ctx
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+	}()
+
 	req := MCPRequest{
 		JSONRPC: "2.0",
-		ID:      c.nextRequestID(),
+		ID:      id,
 		Method:  "tools/call",
 		Params: CallToolParams{
 			Name:      name,
@@ -132,36 +335,59 @@ func (c *Client) CallTool(name string, arguments map[string]interface{}) (string
 		},
 	}
 
-	resp, err := c.sendRequest(req)
+	resp, err := c.sendRequestContext(ctx, req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if resp.Error != nil {
-		return "", fmt.Errorf("tool call failed: %s", resp.Error.Message)
+		return nil, fmt.Errorf("tool call failed: %s", resp.Error.Message)
 	}
 
-	var result struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
-	}
+	var result CallToolResult
 	resultJSON, _ := json.Marshal(resp.Result)
 	json.Unmarshal(resultJSON, &result)
 
-	if len(result.Content) > 0 && result.Content[0].Type == "text" {
-		return result.Content[0].Text, nil
+	// A tool that failed reports it in the result, for the model to see
+	if result.IsError {
+		text := (&tools.Result{Content: result.Content}).String()
+		if text == "" {
+			text = "the tool reported an error"
+		}
+		return nil, fmt.Errorf("%s", text)
 	}
+	return &tools.Result{Content: result.Content, Structured: result.StructuredContent}, nil
+}
 
-	return "", fmt.Errorf("unexpected response format")
+// Ping checks that the server still answers.
+func (c *Client) Ping() error {
+	return c.PingContext(context.Background())
+}
+
+func (c *Client) PingContext(ctx context.Context) error {
+	resp, err := c.sendRequestContext(ctx, MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "ping",
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("ping failed: %s", resp.Error.Message)
+	}
+	return nil
 }
 
-func (c *Client) sendRequest(req MCPRequest) (MCPResponse, error) {
+// sendRequestContext sends a request and waits for its response, until ctx
+// is done or the timeout of its method passes; then it is cancelled on the
+// server.
+func (c *Client) sendRequestContext(ctx context.Context, req MCPRequest) (MCPResponse, error) {
 	respChan := make(chan MCPResponse, 1)
 
 	c.mu.Lock()
 	c.responses[req.ID] = respChan
+	done := c.done
 	c.mu.Unlock()
 
 	defer func() {
@@ -170,36 +396,208 @@ func (c *Client) sendRequest(req MCPRequest) (MCPResponse, error) {
 		c.mu.Unlock()
 	}()
 
-	if err := c.encoder.Encode(req); err != nil {
+	// A method without a timeout waits for ctx alone
+	var timeout <-chan time.Time
+	if d := c.requestTimeout(req.Method); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// A lost connection fails requests at once
+	select {
+	case <-done:
+		return MCPResponse{}, c.closedError()
+	default:
+	}
+	if err := c.write(req); err != nil {
+		select {
+		case <-done:
+			return MCPResponse{}, c.closedError()
+		default:
+		}
 		return MCPResponse{}, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	select {
 	case resp := <-respChan:
 		return resp, nil
-	case <-time.After(30 * time.Second):
-		return MCPResponse{}, fmt.Errorf("request timeout")
+	case <-done:
+		select {
+		case resp := <-respChan:
+			return resp, nil
+		default:
+		}
+		return MCPResponse{}, c.closedError()
+	case <-ctx.Done():
+		c.notify("notifications/cancelled", CancelledParams{RequestID: req.ID, Reason: ctx.Err().Error()})
+		return MCPResponse{}, ctx.Err()
+	case <-timeout:
+		c.notify("notifications/cancelled", CancelledParams{RequestID: req.ID, Reason: "request timeout"})
+		return MCPResponse{}, fmt.Errorf("%s timed out after %s", req.Method, c.requestTimeout(req.Method))
+	}
+}
+
+func (c *Client) notify(method string, params interface{}) error {
+	if err := c.write(MCPNotification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
+
+// write sends one message, or a batch; requests and the replies to the server's own
+// requests are written from different goroutines.
+func (c *Client) write(msg interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.frames != nil {
+		if data, err := json.Marshal(msg); err == nil {
+			for _, message := range batchMessages(data) {
+				c.trace(FrameSent, message)
+			}
+		}
+	}
+	return c.encoder.Encode(msg)
+}
+
+// closedError explains why the connection stopped, with the last lines a
+// stdio server wrote to stderr.
+func (c *Client) closedError() error {
+	c.mu.RLock()
+	process, reconnect, closed := c.process, c.reconnect, c.closed
+	c.mu.RUnlock()
+
+	message := "MCP server closed the connection"
+	if process != nil {
+		if tail := strings.TrimSpace(process.stderr.String()); tail != "" {
+			message += ": " + tail
+		}
 	}
+	if reconnect && !closed {
+		message += " (reconnecting)"
+	}
+	return fmt.Errorf("%s", message)
 }
 
-func (c *Client) readResponses() {
+func (c *Client) readResponses(decoder *json.Decoder, done chan struct{}) {
+	defer func() {
+		close(done)
+		c.connectionLost()
+	}()
+
 	for {
-		var resp MCPResponse
-		if err := c.decoder.Decode(&resp); err != nil {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
 			return // Connection closed
 		}
+		// The responses to a batch come as one
+		for _, message := range batchMessages(raw) {
+			c.handleMessage(message)
+		}
+	}
+}
 
-		c.mu.RLock()
-		if respChan, exists := c.responses[resp.ID]; exists {
-			select {
-			case respChan <- resp:
-			default:
-			}
+// handleMessage hands a response to the request waiting for it, and
+// answers what the server sends of its own accord.
+func (c *Client) handleMessage(raw json.RawMessage) {
+	c.trace(FrameReceived, raw)
+
+	// Servers send requests and notifications of their own too
+	var msg struct {
+		ID     interface{}     `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(raw, &msg) != nil {
+		return
+	}
+	if msg.Method != "" {
+		c.handleServerRequest(msg.ID, msg.Method, msg.Params)
+		return
+	}
+
+	var resp MCPResponse
+	if json.Unmarshal(raw, &resp) != nil {
+		return
+	}
+	// Numeric IDs decode as float64; requests are sent with int64 IDs
+	id := resp.ID
+	if f, ok := id.(float64); ok && f == float64(int64(f)) {
+		id = int64(f)
+	}
+
+	c.mu.RLock()
+	if respChan, exists := c.responses[id]; exists {
+		select {
+		case respChan <- resp:
+		default:
 		}
-		c.mu.RUnlock()
 	}
+	c.mu.RUnlock()
+}
+
+// OnToolsChanged registers fn to be called, on its own goroutine, each time
+// the server says its tools changed, so they can be listed again.
+func (c *Client) OnToolsChanged(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onToolsChanged = fn
+}
+
+// handleServerRequest answers a request the server sent: ping, roots when
+// they are set, and sampling when a sampler is set, on its own goroutine as it takes a
+// while; anything else is not supported. Of the notifications (no ID),
+// only a changed tool list is acted on.
+func (c *Client) handleServerRequest(id interface{}, method string, params json.RawMessage) {
+	c.mu.RLock()
+	fn, sampler, roots := c.onToolsChanged, c.sampler, c.roots
+	c.mu.RUnlock()
+	if id == nil {
+		if method == "notifications/tools/list_changed" && fn != nil {
+			go fn()
+		}
+		return
+	}
+	if method == "sampling/createMessage" && sampler != nil {
+		go c.handleSampling(id, sampler, params)
+		return
+	}
+	resp := MCPResponse{JSONRPC: "2.0", ID: id}
+	switch {
+	case method == "ping":
+		resp.Result = struct{}{}
+	case method == "roots/list" && roots != nil:
+		resp.Result = map[string]interface{}{"roots": roots}
+	default:
+		resp.Error = &MCPError{Code: -32601, Message: "Method not found"}
+	}
+	c.write(resp)
 }
 
 func (c *Client) nextRequestID() int64 {
 	return atomic.AddInt64(&c.requestID, 1)
 }
+
+// tailBuffer keeps the last stderr output of a stdio server.
+type tailBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+const tailBufferSize = 4096
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > tailBufferSize {
+		b.data = b.data[len(b.data)-tailBufferSize:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
@@ -0,0 +1,112 @@
+// Package: internal/mcp/debug.go
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Directions of a frame
+const (
+	FrameSent     = "send"
+	FrameReceived = "recv"
+)
+
+// Frame is a JSON-RPC message a client exchanged with a server, as a line
+// of a FrameLog.
+type Frame struct {
+	Time      time.Time       `json:"time"`
+	Server    string          `json:"server"`
+	Direction string          `json:"direction"`            // FrameSent or FrameReceived
+	LatencyMS float64         `json:"latency_ms,omitempty"` // Of a response, since the request it answers
+	Message   json.RawMessage `json:"message"`
+}
+
+// FrameLog records every message of the clients given it to a file, one
+// Frame per line, to debug how they interoperate with a server.
+type FrameLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	sent    map[string]time.Time // When each request went out, by frameKey
+}
+
+// OpenFrameLog appends to the frame log at path, creating it.
+func OpenFrameLog(path string) (*FrameLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frame log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame log: %w", err)
+	}
+	return &FrameLog{file: file, encoder: json.NewEncoder(file), sent: make(map[string]time.Time)}, nil
+}
+
+func (l *FrameLog) Close() error {
+	return l.file.Close()
+}
+
+// record logs a message, timing a response against its request.
+func (l *FrameLog) record(server, direction string, data []byte) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	json.Unmarshal(data, &msg)
+
+	now := time.Now()
+	frame := Frame{Time: now, Server: server, Direction: direction, Message: json.RawMessage(data)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case msg.ID == nil || string(msg.ID) == "null":
+	case msg.Method != "":
+		l.sent[frameKey(server, direction, msg.ID)] = now
+	default:
+		// A response goes the other way to its request
+		requestDirection := FrameSent
+		if direction == FrameSent {
+			requestDirection = FrameReceived
+		}
+		key := frameKey(server, requestDirection, msg.ID)
+		if sent, ok := l.sent[key]; ok {
+			frame.LatencyMS = float64(now.Sub(sent).Microseconds()) / 1000
+			delete(l.sent, key)
+		}
+	}
+	l.encoder.Encode(frame)
+}
+
+func frameKey(server, direction string, id json.RawMessage) string {
+	return server + "\x00" + direction + "\x00" + string(id)
+}
+
+// SetFrameLog logs every message of the client to log, under the name of
+// its server. It must be set before connecting.
+func (c *Client) SetFrameLog(log *FrameLog, server string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames, c.framesServer = log, server
+}
+
+// trace logs a message the client sent or received, when it has a frame
+// log.
+func (c *Client) trace(direction string, data []byte) {
+	if c.frames != nil {
+		c.frames.record(c.framesServer, direction, data)
+	}
+}
+
+// SetFrameLog logs every message exchanged with the servers to log, which
+// Close closes. It must be set before Start.
+func (m *Manager) SetFrameLog(log *FrameLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.frames = log
+}
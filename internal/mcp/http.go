@@ -0,0 +1,603 @@
+// Package: internal/mcp/http.go
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The streamable HTTP transport: a single endpoint takes each message as a
+// POST and answers a request with its response. The server names a
+// session in the Mcp-Session-Id header of the initialize response, which
+// the client sends with every later message; a GET opens a stream for
+// messages the server sends of its own accord, whose event IDs let a
+// client that lost it resume with Last-Event-ID.
+
+const (
+	mcpSessionHeader = "Mcp-Session-Id"
+
+	// httpEventLog is how many events a session keeps for resuming
+	httpEventLog = 256
+
+	// httpSessionIdle is how long a session without an open stream is kept
+	// after its last message
+	httpSessionIdle = time.Hour
+)
+
+// httpSession is a streamable HTTP session of the server.
+type httpSession struct {
+	mu       sync.Mutex
+	events   []httpEvent // The last httpEventLog, for resuming
+	nextID   int
+	wake     chan struct{} // Closed when an event is added
+	streams  int
+	lastSeen time.Time
+	done     chan struct{} // Closed when the session ends
+	peer     *peer
+}
+
+type httpEvent struct {
+	id   int
+	data []byte
+}
+
+// newHTTPSession starts a session for the client whose token has the
+// identity; only that token can use it.
+func (s *Server) newHTTPSession(identity string) *httpSession {
+	session := &httpSession{wake: make(chan struct{}), lastSeen: time.Now(), done: make(chan struct{})}
+	session.peer = s.addPeer(session.send)
+	session.peer.mu.Lock()
+	session.peer.identity = identity
+	session.peer.mu.Unlock()
+	return session
+}
+
+// end closes the session. The caller holds s.mu.
+func (s *Server) endHTTPSession(id string, session *httpSession) {
+	delete(s.httpSessions, id)
+	delete(s.peers, session.peer)
+	session.peer.calls.cancelAll()
+	close(session.done)
+}
+
+// send queues a message for the session's stream.
+func (h *httpSession) send(data []byte) {
+	h.mu.Lock()
+	h.nextID++
+	h.events = append(h.events, httpEvent{id: h.nextID, data: data})
+	if len(h.events) > httpEventLog {
+		h.events = h.events[len(h.events)-httpEventLog:]
+	}
+	wake := h.wake
+	h.wake = make(chan struct{})
+	h.mu.Unlock()
+	close(wake)
+}
+
+// since returns the events after the event with ID cursor, and a channel
+// closed when there are more.
+func (h *httpSession) since(cursor int) ([]httpEvent, chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var events []httpEvent
+	for _, event := range h.events {
+		if event.id > cursor {
+			events = append(events, event)
+		}
+	}
+	return events, h.wake
+}
+
+func (h *httpSession) touch() {
+	h.mu.Lock()
+	h.lastSeen = time.Now()
+	h.mu.Unlock()
+}
+
+// idle reports whether the session has no stream and no message for
+// httpSessionIdle.
+func (h *httpSession) idle(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams == 0 && now.Sub(h.lastSeen) > httpSessionIdle
+}
+
+// StartHTTP serves MCP over streamable HTTP at /mcp on addr (host:port, or
+// :port for all interfaces). Requests must carry the auth token.
+func (s *Server) StartHTTP(addr string) error {
+	if !s.hasAuthToken() {
+		return errNoAuthToken
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", s.handleHTTP)
+	server := &http.Server{Handler: s.requireAuth(mux)}
+
+	s.mu.Lock()
+	if s.httpSessions == nil {
+		s.httpSessions = make(map[string]*httpSession)
+	}
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers send Origin; a page from another site must not reach a
+	// server listening on localhost
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handleHTTPMessage(w, r)
+	case http.MethodGet:
+		s.handleHTTPStream(w, r)
+	case http.MethodDelete:
+		session, id := s.httpSession(w, r)
+		if session == nil {
+			return
+		}
+		s.mu.Lock()
+		if s.httpSessions[id] == session {
+			s.endHTTPSession(id, session)
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// httpSession looks up the session named by the request, answering 400
+// when it names none and 404 when it is unknown or has ended.
+func (s *Server) httpSession(w http.ResponseWriter, r *http.Request) (*httpSession, string) {
+	id := r.Header.Get(mcpSessionHeader)
+	if id == "" {
+		http.Error(w, "missing "+mcpSessionHeader+" header", http.StatusBadRequest)
+		return nil, ""
+	}
+	s.mu.RLock()
+	session := s.httpSessions[id]
+	s.mu.RUnlock()
+	if session == nil || !session.peer.ownedBy(requestIdentity(r)) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return nil, ""
+	}
+	return session, id
+}
+
+func (s *Server) handleHTTPMessage(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	var req MCPRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10*1024*1024)).Decode(&raw); err != nil || (!isBatch(raw) && json.Unmarshal(raw, &req) != nil) {
+		http.Error(w, "invalid JSON-RPC message", http.StatusBadRequest)
+		return
+	}
+
+	// A batch is answered with the responses to its requests at once, on
+	// a session it cannot start
+	if isBatch(raw) {
+		session, _ := s.httpSession(w, r)
+		if session == nil {
+			return
+		}
+		session.touch()
+		reply := s.handleBatch(session.peer, raw)
+		if reply == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+
+	var session *httpSession
+	if req.Method == "initialize" {
+		id, err := newSessionID()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		session = s.newHTTPSession(requestIdentity(r))
+		s.mu.Lock()
+		for old, idle := range s.httpSessions {
+			if idle.idle(now) {
+				s.endHTTPSession(old, idle)
+			}
+		}
+		s.httpSessions[id] = session
+		s.mu.Unlock()
+		w.Header().Set(mcpSessionHeader, id)
+	} else {
+		if session, _ = s.httpSession(w, r); session == nil {
+			return
+		}
+		session.touch()
+	}
+
+	// Notifications and responses to the server's requests get no response
+	if req.ID == nil || req.Method == "" {
+		if req.Method != "" {
+			s.handleNotification(session.peer, req)
+		} else {
+			session.peer.deliver(raw)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if !s.beginRequest() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(refuseRequest(req))
+		return
+	}
+	defer s.endRequest()
+	if progressToken(req) != nil && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamHTTPResponse(w, session, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.handleRequest(session.peer, req))
+}
+
+// streamHTTPResponse answers a request that asked for progress with an
+// event stream, which carries the progress and then the response.
+func (s *Server) streamHTTPResponse(w http.ResponseWriter, session *httpSession, req MCPRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mu sync.Mutex
+	send := func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	// The stream's own peer gets the progress of this request alone, and
+	// shares the session's requests so it can be cancelled, its roots and
+	// who the client is
+	session.peer.mu.Lock()
+	stream := &peer{
+		send:          send,
+		calls:         session.peer.calls,
+		subscriptions: make(map[string]bool),
+		roots:         session.peer.roots,
+		identity:      session.peer.identity,
+	}
+	session.peer.mu.Unlock()
+	if data, err := json.Marshal(s.handleRequest(stream, req)); err == nil {
+		send(data)
+	}
+}
+
+func (s *Server) handleHTTPStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "the stream is text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	session, _ := s.httpSession(w, r)
+	if session == nil {
+		return
+	}
+
+	session.mu.Lock()
+	session.streams++
+	cursor := session.nextID
+	session.mu.Unlock()
+	defer func() {
+		session.mu.Lock()
+		session.streams--
+		session.lastSeen = time.Now()
+		session.mu.Unlock()
+	}()
+	// Resuming replays what was sent after the client's last event
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && lastID < cursor {
+		cursor = lastID
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		events, wake := session.since(cursor)
+		for _, event := range events {
+			fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", event.id, event.data)
+			cursor = event.id
+		}
+		flusher.Flush()
+
+		select {
+		case <-wake:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-session.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ConnectHTTP speaks to an MCP server over streamable HTTP at endpoint
+// (e.g. https://example.com/mcp). Nothing is sent until Initialize, which
+// starts the session.
+func (c *Client) ConnectHTTP(endpoint string) error {
+	c.setRedial(func() error { return c.ConnectHTTP(endpoint) })
+	if _, err := url.Parse(endpoint); err != nil {
+		return fmt.Errorf("invalid MCP server URL: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	transport := &httpTransport{ctx: ctx, client: c.httpClient(), endpoint: endpoint, authorization: c.authorization(), out: pw}
+	c.start(pr, transport, closerFunc(func() error {
+		transport.terminate()
+		cancel()
+		return pr.Close()
+	}))
+	return nil
+}
+
+// httpTransport posts each message written to it, which json.Encoder
+// writes in one call, and feeds what the server answers and streams to
+// out, one message per line.
+type httpTransport struct {
+	ctx           context.Context
+	client        *http.Client
+	endpoint      string
+	authorization string
+	out           *io.PipeWriter
+
+	mu        sync.Mutex
+	session   string
+	listening bool // Whether the stream for the server's own messages was opened
+}
+
+// Write posts a message. The server answers a POST once it has handled
+// the message, so the POST is made in the background, leaving the client
+// free to send others meanwhile, such as a cancellation.
+func (t *httpTransport) Write(data []byte) (int, error) {
+	msg := append([]byte(nil), data...)
+	go func() {
+		if err := t.post(msg); err != nil {
+			t.fail(msg, err)
+		}
+	}()
+	return len(data), nil
+}
+
+func (t *httpTransport) post(data []byte) error {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.authorize(req)
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session != "" {
+		req.Header.Set(mcpSessionHeader, session)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound && session != "" {
+		resp.Body.Close()
+		t.mu.Lock()
+		t.session, t.listening = "", false
+		t.mu.Unlock()
+		return fmt.Errorf("the MCP session expired; initialize again")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+
+	if id := resp.Header.Get(mcpSessionHeader); id != "" {
+		t.mu.Lock()
+		t.session = id
+		listen := !t.listening
+		t.listening = true
+		t.mu.Unlock()
+		if listen {
+			go t.listen()
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		go t.readStream(resp.Body)
+	case "application/json":
+		go func() {
+			defer resp.Body.Close()
+			if body, err := io.ReadAll(resp.Body); err == nil {
+				t.deliver(body)
+			}
+		}()
+	default:
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// fail answers a request that could not be posted, or each of a batch,
+// with an error, so the client does not wait for it.
+func (t *httpTransport) fail(data []byte, err error) {
+	if t.ctx.Err() != nil {
+		return
+	}
+	for _, message := range batchMessages(data) {
+		var msg struct {
+			ID     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}
+		if json.Unmarshal(message, &msg) != nil || msg.ID == nil || msg.Method == "" {
+			continue
+		}
+		resp, marshalErr := json.Marshal(MCPResponse{JSONRPC: "2.0", ID: msg.ID, Error: &MCPError{Code: -32603, Message: err.Error()}})
+		if marshalErr == nil {
+			t.deliver(resp)
+		}
+	}
+}
+
+// readStream feeds the messages of a response stream to out. A stream that
+// breaks off before the server closed it is resumed from its last event.
+func (t *httpTransport) readStream(body io.ReadCloser) {
+	lastID, err := t.pipeEvents(body, "")
+	for err != io.EOF && lastID != "" && t.ctx.Err() == nil {
+		if body, err = t.openStream(lastID); err != nil {
+			return
+		}
+		lastID, err = t.pipeEvents(body, lastID)
+	}
+}
+
+// listen keeps a stream open for the messages the server sends of its own
+// accord, reopening it where it left off when the server closes it. A
+// server without one answers 405, which ends it.
+func (t *httpTransport) listen() {
+	lastID := ""
+	for t.ctx.Err() == nil {
+		body, err := t.openStream(lastID)
+		if err != nil {
+			return
+		}
+		lastID, _ = t.pipeEvents(body, lastID)
+
+		select {
+		case <-t.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *httpTransport) openStream(lastID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.authorize(req)
+	t.mu.Lock()
+	if t.session != "" {
+		req.Header.Set(mcpSessionHeader, t.session)
+	}
+	t.mu.Unlock()
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("server answered %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// pipeEvents feeds the messages of an event stream to out until it ends,
+// returning the last event ID seen (lastID when it had none) and io.EOF
+// when the server closed the stream.
+func (t *httpTransport) pipeEvents(body io.ReadCloser, lastID string) (string, error) {
+	defer body.Close()
+	events := newSSEReader(body)
+	events.lastID = lastID
+	for {
+		event, data, err := events.next()
+		if err != nil {
+			return events.lastID, err
+		}
+		if event == "message" {
+			t.deliver([]byte(data))
+		}
+	}
+}
+
+// deliver feeds a message, or each message of a batch, to out.
+func (t *httpTransport) deliver(data []byte) {
+	for _, msg := range batchMessages(data) {
+		if _, err := t.out.Write(append(msg, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+func (t *httpTransport) authorize(req *http.Request) {
+	if t.authorization != "" {
+		req.Header.Set("Authorization", t.authorization)
+	}
+}
+
+// terminate ends the session on the server, which may not support it.
+func (t *httpTransport) terminate() {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.endpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set(mcpSessionHeader, session)
+	t.authorize(req)
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
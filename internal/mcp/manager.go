@@ -0,0 +1,360 @@
+// Package: internal/mcp/manager.go
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const (
+	// keepAliveInterval is how often a connected server is pinged
+	keepAliveInterval = 30 * time.Second
+
+	// Reconnecting waits minReconnectDelay after the first failure,
+	// doubling up to maxReconnectDelay
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Server connection states
+const (
+	StateDisabled   = "disabled"
+	StateConnecting = "connecting"
+	StateConnected  = "connected"
+	StateFailed     = "failed" // Retrying after a delay
+)
+
+// ServerStatus is where a configured server's connection stands.
+type ServerStatus struct {
+	Name      string
+	Transport string
+	State     string
+	Error     error // Why the last connection attempt failed or broke
+	Server    ServerInfo
+	Tools     []MCPTool // Those of the current connection
+	Since     time.Time // When the server entered State
+}
+
+// Manager keeps a connection to each enabled MCP server: it connects to
+// them in the background, pings them to notice a dead connection, and
+// reconnects with a backoff when one fails.
+type Manager struct {
+	clientName    string
+	clientVersion string
+
+	mu             sync.Mutex
+	servers        map[string]*managedServer
+	onToolsChanged []func()
+	sampler        func(ctx context.Context, server string, params CreateMessageParams) (*CreateMessageResult, error)
+	roots          []Root    // Offered to the servers, when set
+	frames         *FrameLog // Logs the messages of every server, when set
+	stop           chan struct{}
+	wg             sync.WaitGroup
+	starting       sync.WaitGroup // Servers not tried yet
+}
+
+type managedServer struct {
+	config config.MCPServerConfig
+	status ServerStatus
+	client *Client // The connection being made or in use
+}
+
+func NewManager(clientName, clientVersion string) *Manager {
+	return &Manager{
+		clientName:    clientName,
+		clientVersion: clientVersion,
+		servers:       make(map[string]*managedServer),
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start connects to each enabled server of servers in the background.
+func (m *Manager) Start(servers map[string]config.MCPServerConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, cfg := range servers {
+		server := &managedServer{
+			config: cfg,
+			status: ServerStatus{Name: name, Transport: Transport(cfg), State: StateDisabled, Since: time.Now()},
+		}
+		m.servers[name] = server
+		if cfg.Enabled {
+			server.status.State = StateConnecting
+			m.wg.Add(1)
+			m.starting.Add(1)
+			go m.run(server)
+		}
+	}
+}
+
+// Status returns the state of each configured server, by name.
+func (m *Manager) Status() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(m.servers))
+	for _, server := range m.servers {
+		statuses = append(statuses, server.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Close disconnects from the servers, stopping those it launched.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	select {
+	case <-m.stop:
+		m.mu.Unlock()
+		return
+	default:
+	}
+	close(m.stop)
+	// A connection still being made is closed so its requests fail now
+	for _, server := range m.servers {
+		if server.client != nil && server.status.State == StateConnecting {
+			server.client.Close()
+		}
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	if m.frames != nil {
+		m.frames.Close()
+	}
+}
+
+// run connects to server, and again each time the connection fails, until
+// the manager is closed.
+func (m *Manager) run(server *managedServer) {
+	defer m.wg.Done()
+
+	delay := minReconnectDelay
+	first := true
+	for {
+		client, err := m.connect(server)
+		if first {
+			m.starting.Done()
+			first = false
+		}
+		if err == nil {
+			delay = minReconnectDelay
+			err = m.keepAlive(client)
+		}
+		if client != nil {
+			client.Close()
+		}
+
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+		m.setState(server, StateFailed, err)
+
+		select {
+		case <-m.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+		m.setState(server, StateConnecting, err)
+	}
+}
+
+// connect opens a connection to server, initializes it and lists its
+// tools. The client is returned to be closed even when that fails.
+func (m *Manager) connect(server *managedServer) (*Client, error) {
+	client := NewMCPClient()
+	m.mu.Lock()
+	select {
+	case <-m.stop:
+		m.mu.Unlock()
+		return nil, fmt.Errorf("closed")
+	default:
+	}
+	server.client = client
+	if m.frames != nil {
+		client.SetFrameLog(m.frames, server.status.Name)
+	}
+	for method, seconds := range server.config.Timeouts {
+		client.SetTimeout(method, time.Duration(seconds)*time.Second)
+	}
+	m.mu.Unlock()
+
+	if err := client.Connect(server.config); err != nil {
+		return nil, err
+	}
+	// Close may have missed a connection it raced with
+	select {
+	case <-m.stop:
+		return client, fmt.Errorf("closed")
+	default:
+	}
+	client.OnToolsChanged(func() {
+		m.refreshTools(server, client)
+	})
+	m.mu.Lock()
+	sampler, roots := m.sampler, m.roots
+	m.mu.Unlock()
+	if roots != nil {
+		client.SetRoots(roots)
+	}
+	if sampler != nil && server.config.Sampling != SamplingDeny {
+		name := server.status.Name
+		client.SetSampler(func(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error) {
+			return sampler(ctx, name, params)
+		})
+	}
+	if err := client.Initialize(m.clientName, m.clientVersion); err != nil {
+		return client, err
+	}
+	tools, err := client.ListTools()
+	if err != nil {
+		return client, err
+	}
+
+	m.mu.Lock()
+	server.status.Server = client.serverInfo
+	server.status.Tools = tools
+	m.mu.Unlock()
+	m.setState(server, StateConnected, nil)
+	return client, nil
+}
+
+// keepAlive pings client until the connection fails or the manager is
+// closed.
+func (m *Manager) keepAlive(client *Client) error {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return nil
+		case <-client.done:
+			return client.closedError()
+		case <-ticker.C:
+			// Bounded whatever the configured timeouts, so a dead server is
+			// noticed
+			ctx, cancel := context.WithTimeout(context.Background(), keepAliveInterval)
+			err := client.PingContext(ctx)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("ping failed: %w", err)
+			}
+		}
+	}
+}
+
+func (m *Manager) setState(server *managedServer, state string, err error) {
+	m.mu.Lock()
+	// The server's tools come or go with the connection
+	changed := (server.status.State == StateConnected) != (state == StateConnected)
+	if server.status.State != state {
+		server.status.Since = time.Now()
+	}
+	server.status.State = state
+	server.status.Error = err
+	if state != StateConnected {
+		server.status.Tools = nil
+	}
+	m.mu.Unlock()
+
+	if changed {
+		m.toolsChanged()
+	}
+}
+
+// OnToolsChanged registers fn to be called when the tools of the connected
+// servers change: a server connects or disconnects, or says its tools
+// changed.
+func (m *Manager) OnToolsChanged(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onToolsChanged = append(m.onToolsChanged, fn)
+}
+
+func (m *Manager) toolsChanged() {
+	m.mu.Lock()
+	listeners := m.onToolsChanged
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// refreshTools lists the tools of server again after it said they
+// changed. A failure is left to the keep-alive to notice.
+func (m *Manager) refreshTools(server *managedServer, client *Client) {
+	tools, err := client.ListTools()
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	current := server.client == client && server.status.State == StateConnected
+	if current {
+		server.status.Tools = tools
+	}
+	m.mu.Unlock()
+
+	if current {
+		m.toolsChanged()
+	}
+}
+
+// Transport returns the transport cfg connects with: its own, or stdio for
+// a command, websocket for a ws:// or wss:// URL, sse for a URL ending in
+// /sse and http for another URL.
+func Transport(cfg config.MCPServerConfig) string {
+	switch {
+	case cfg.Transport != "":
+		return cfg.Transport
+	case cfg.Command != "":
+		return "stdio"
+	case strings.HasPrefix(cfg.URL, "ws://") || strings.HasPrefix(cfg.URL, "wss://"):
+		return "websocket"
+	case strings.HasSuffix(strings.TrimRight(cfg.URL, "/"), "/sse"):
+		return "sse"
+	default:
+		return "http"
+	}
+}
+
+// Connect opens a connection to the server cfg describes.
+func (c *Client) Connect(cfg config.MCPServerConfig) error {
+	switch transport := Transport(cfg); transport {
+	case "stdio":
+		if cfg.Command == "" {
+			return fmt.Errorf("no command to launch")
+		}
+		return c.ConnectStdio(cfg.Command, cfg.Args, cfg.Env)
+	case "sse":
+		if cfg.URL == "" {
+			return fmt.Errorf("no URL to connect to")
+		}
+		return c.ConnectSSE(cfg.URL)
+	case "http":
+		if cfg.URL == "" {
+			return fmt.Errorf("no URL to connect to")
+		}
+		return c.ConnectHTTP(cfg.URL)
+	case "websocket":
+		if cfg.URL == "" {
+			return fmt.Errorf("no URL to connect to")
+		}
+		return c.ConnectWebSocket(cfg.URL)
+	default:
+		return fmt.Errorf("unknown transport %q (want stdio, sse, http or websocket)", transport)
+	}
+}
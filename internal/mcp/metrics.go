@@ -0,0 +1,186 @@
+// Package: internal/mcp/metrics.go
+package mcp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the buckets of the
+// latency histograms.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// unknownMethod is what the requests for methods the server does not have
+// are counted under, so made-up names do not each get stats.
+const unknownMethod = "unknown"
+
+// serverMetrics counts the requests the server answered, by method.
+type serverMetrics struct {
+	mu      sync.Mutex
+	started time.Time
+	methods map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	requests int64
+	errors   int64
+	seconds  float64 // Spent answering them
+	buckets  []int64 // Requests by the first bucket they fit, the last for slower ones
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{started: time.Now(), methods: make(map[string]*methodMetrics)}
+}
+
+func (m *serverMetrics) record(method string, failed bool, latency time.Duration) {
+	seconds := latency.Seconds()
+	bucket := sort.SearchFloat64s(latencyBuckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.methods[method]
+	if stats == nil {
+		stats = &methodMetrics{buckets: make([]int64, len(latencyBuckets)+1)}
+		m.methods[method] = stats
+	}
+	stats.requests++
+	if failed {
+		stats.errors++
+	}
+	stats.seconds += seconds
+	stats.buckets[bucket]++
+}
+
+// ServerStats is what server/stats answers: how long the server has run
+// and the requests it answered, by method.
+type ServerStats struct {
+	UptimeSeconds float64                `json:"uptimeSeconds"`
+	Methods       map[string]MethodStats `json:"methods"`
+}
+
+// MethodStats is how many requests of a method were answered, how many
+// with an error, and how long they took.
+type MethodStats struct {
+	Requests  int64            `json:"requests"`
+	Errors    int64            `json:"errors"`
+	ErrorRate float64          `json:"errorRate"`
+	Latency   LatencyHistogram `json:"latency"`
+}
+
+// LatencyHistogram counts requests by how long they took: each bucket
+// those that took at most LE seconds, so the counts add up as they go,
+// Prometheus style. Those slower than the last bucket are counted in
+// Requests alone.
+type LatencyHistogram struct {
+	Buckets    []LatencyBucket `json:"buckets"`
+	SumSeconds float64         `json:"sumSeconds"`
+}
+
+type LatencyBucket struct {
+	LE    float64 `json:"le"`
+	Count int64   `json:"count"`
+}
+
+// Stats returns the requests the server answered so far, by method.
+func (s *Server) Stats() ServerStats {
+	m := s.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := ServerStats{
+		UptimeSeconds: time.Since(m.started).Seconds(),
+		Methods:       make(map[string]MethodStats, len(m.methods)),
+	}
+	for name, metrics := range m.methods {
+		method := MethodStats{
+			Requests: metrics.requests,
+			Errors:   metrics.errors,
+			Latency: LatencyHistogram{
+				Buckets:    make([]LatencyBucket, len(latencyBuckets)),
+				SumSeconds: metrics.seconds,
+			},
+		}
+		if metrics.requests > 0 {
+			method.ErrorRate = float64(metrics.errors) / float64(metrics.requests)
+		}
+		var count int64
+		for i, le := range latencyBuckets {
+			count += metrics.buckets[i]
+			method.Latency.Buckets[i] = LatencyBucket{LE: le, Count: count}
+		}
+		stats.Methods[name] = method
+	}
+	return stats
+}
+
+// StartMetrics serves the stats in Prometheus' text format at /metrics on
+// addr, for a server that runs for long. When the server has auth tokens,
+// scrapers must present one as the MCP transports do.
+func (s *Server) StartMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	var handler http.Handler = mux
+	if s.hasAuthToken() {
+		handler = s.requireAuth(mux)
+	}
+	server := &http.Server{Handler: handler}
+
+	s.mu.Lock()
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	stats := s.Stats()
+	methods := make([]string, 0, len(stats.Methods))
+	for method := range stats.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintf(w, "# HELP mcp_uptime_seconds How long the MCP server has run.\n")
+	fmt.Fprintf(w, "# TYPE mcp_uptime_seconds gauge\n")
+	fmt.Fprintf(w, "mcp_uptime_seconds %s\n", formatFloat(stats.UptimeSeconds))
+
+	fmt.Fprintf(w, "# HELP mcp_requests_total MCP requests answered, by method.\n")
+	fmt.Fprintf(w, "# TYPE mcp_requests_total counter\n")
+	for _, method := range methods {
+		fmt.Fprintf(w, "mcp_requests_total{method=%q} %d\n", method, stats.Methods[method].Requests)
+	}
+
+	fmt.Fprintf(w, "# HELP mcp_request_errors_total MCP requests answered with an error, by method.\n")
+	fmt.Fprintf(w, "# TYPE mcp_request_errors_total counter\n")
+	for _, method := range methods {
+		fmt.Fprintf(w, "mcp_request_errors_total{method=%q} %d\n", method, stats.Methods[method].Errors)
+	}
+
+	fmt.Fprintf(w, "# HELP mcp_request_duration_seconds How long MCP requests took to answer, by method.\n")
+	fmt.Fprintf(w, "# TYPE mcp_request_duration_seconds histogram\n")
+	for _, method := range methods {
+		stat := stats.Methods[method]
+		for _, bucket := range stat.Latency.Buckets {
+			fmt.Fprintf(w, "mcp_request_duration_seconds_bucket{method=%q,le=%q} %d\n", method, formatFloat(bucket.LE), bucket.Count)
+		}
+		fmt.Fprintf(w, "mcp_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, stat.Requests)
+		fmt.Fprintf(w, "mcp_request_duration_seconds_sum{method=%q} %s\n", method, formatFloat(stat.Latency.SumSeconds))
+		fmt.Fprintf(w, "mcp_request_duration_seconds_count{method=%q} %d\n", method, stat.Requests)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
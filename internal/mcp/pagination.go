@@ -0,0 +1,101 @@
+// Package: internal/mcp/pagination.go
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// listPageSize is how many items a page of tools/list, resources/list or
+// prompts/list holds.
+const listPageSize = 100
+
+// maxListPages bounds how many pages the client follows, against a server
+// whose cursors never end.
+const maxListPages = 1000
+
+// errInvalidCursor is returned for a cursor the server did not hand out.
+var errInvalidCursor = fmt.Errorf("invalid cursor")
+
+type PaginatedParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// listCursor returns the cursor of a list request, "" for the first page.
+func listCursor(req MCPRequest) string {
+	var params PaginatedParams
+	if paramsData, ok := req.Params.(map[string]interface{}); ok {
+		paramsJSON, _ := json.Marshal(paramsData)
+		json.Unmarshal(paramsJSON, &params)
+	}
+	return params.Cursor
+}
+
+// pageBounds returns where the page after cursor starts and ends among
+// keys, which are sorted and unique, and the cursor of the next page, ""
+// when it is the last. A cursor is the key the page before ended with, so
+// items added or removed meanwhile shift no page.
+func pageBounds(keys []string, cursor string) (start, end int, next string, err error) {
+	if cursor != "" {
+		last, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return 0, 0, "", errInvalidCursor
+		}
+		start = sort.Search(len(keys), func(i int) bool { return keys[i] > string(last) })
+	}
+	end = min(start+listPageSize, len(keys))
+	if end < len(keys) {
+		next = base64.RawURLEncoding.EncodeToString([]byte(keys[end-1]))
+	}
+	return start, end, next, nil
+}
+
+// invalidCursor answers a list request whose cursor is not valid.
+func invalidCursor(req MCPRequest) MCPResponse {
+	return MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error: &MCPError{
+			Code:    -32602,
+			Message: "Invalid cursor",
+		},
+	}
+}
+
+// listAll requests every page of a list, handing each result to add,
+// which takes its items and returns its next cursor.
+func (c *Client) listAll(ctx context.Context, method string, add func(resultJSON []byte) string) error {
+	cursor := ""
+	for pages := 0; pages < maxListPages; pages++ {
+		req := MCPRequest{
+			JSONRPC: "2.0",
+			ID:      c.nextRequestID(),
+			Method:  method,
+		}
+		if cursor != "" {
+			req.Params = PaginatedParams{Cursor: cursor}
+		}
+
+		resp, err := c.sendRequestContext(ctx, req)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s failed: %s", method, resp.Error.Message)
+		}
+
+		resultJSON, _ := json.Marshal(resp.Result)
+		next := add(resultJSON)
+		if next == "" {
+			return nil
+		}
+		if next == cursor {
+			return fmt.Errorf("%s returned the same cursor twice", method)
+		}
+		cursor = next
+	}
+	return fmt.Errorf("%s returned more than %d pages", method, maxListPages)
+}
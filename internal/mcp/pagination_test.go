@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+func TestPageBounds(t *testing.T) {
+	keys := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("key%03d", i)
+		}
+		return out
+	}
+	cursor := func(key string) string { return base64.RawURLEncoding.EncodeToString([]byte(key)) }
+
+	tests := []struct {
+		name      string
+		keys      []string
+		cursor    string
+		wantStart int
+		wantEnd   int
+		wantNext  string
+		wantErr   error
+	}{
+		{name: "empty list", keys: nil},
+		{name: "one short page", keys: keys(3), wantEnd: 3},
+		{name: "exactly one page", keys: keys(listPageSize), wantEnd: listPageSize},
+		{name: "first of two pages", keys: keys(listPageSize + 1), wantEnd: listPageSize, wantNext: cursor("key099")},
+		{name: "second page", keys: keys(listPageSize + 1), cursor: cursor("key099"), wantStart: listPageSize, wantEnd: listPageSize + 1},
+		{name: "cursor key removed meanwhile", keys: []string{"a", "c", "d"}, cursor: cursor("b"), wantStart: 1, wantEnd: 3},
+		{name: "cursor past the last key", keys: keys(3), cursor: cursor("zzz"), wantStart: 3, wantEnd: 3},
+		{name: "cursor not base64", keys: keys(3), cursor: "not base64!", wantErr: errInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, next, err := pageBounds(tt.keys, tt.cursor)
+			if err != tt.wantErr {
+				t.Fatalf("pageBounds() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if start != tt.wantStart || end != tt.wantEnd || next != tt.wantNext {
+				t.Errorf("pageBounds() = %d, %d, %q, want %d, %d, %q", start, end, next, tt.wantStart, tt.wantEnd, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestServerPagesResources(t *testing.T) {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	const total = 2*listPageSize + 50
+	for i := 0; i < total; i++ {
+		s.RegisterResource(fmt.Sprintf("claude-go://test/%03d", i), "Test", "", "text/plain", nil)
+	}
+	p := s.addPeer(func([]byte) {})
+	defer s.removePeer(p)
+
+	list := func(cursor string) MCPResponse {
+		req := MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/list"}
+		if cursor != "" {
+			req.Params = map[string]interface{}{"cursor": cursor}
+		}
+		return s.handleListResources(p, req)
+	}
+
+	seen := make(map[string]bool)
+	var sizes []int
+	cursor := ""
+	for {
+		resp := list(cursor)
+		if resp.Error != nil {
+			t.Fatalf("resources/list: %s", resp.Error.Message)
+		}
+		result := resp.Result.(map[string]interface{})
+		page := result["resources"].([]Resource)
+		sizes = append(sizes, len(page))
+		for _, resource := range page {
+			if seen[resource.URI] {
+				t.Fatalf("%s listed twice", resource.URI)
+			}
+			seen[resource.URI] = true
+		}
+		next, _ := result["nextCursor"].(string)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != total || fmt.Sprint(sizes) != fmt.Sprint([]int{listPageSize, listPageSize, 50}) {
+		t.Errorf("listed %d resources in pages of %v, want %d in 100, 100, 50", len(seen), sizes, total)
+	}
+
+	if resp := list("not base64!"); resp.Error == nil || resp.Error.Code != -32602 {
+		t.Errorf("invalid cursor: response = %+v, want error -32602", resp)
+	}
+}
+
+// fakeListServer answers tools/list on a pipe the client is connected to,
+// with the page respond returns for each cursor.
+func fakeListServer(t *testing.T, respond func(cursor string) MCPResponse) *Client {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() { serverConn.Close() })
+
+	go func() {
+		decoder := json.NewDecoder(serverConn)
+		encoder := json.NewEncoder(serverConn)
+		for {
+			var req MCPRequest
+			if err := decoder.Decode(&req); err != nil {
+				return
+			}
+			resp := respond(listCursor(req))
+			resp.JSONRPC, resp.ID = "2.0", req.ID
+			if err := encoder.Encode(resp); err != nil {
+				return
+			}
+		}
+	}()
+
+	c := NewMCPClient()
+	t.Cleanup(func() { c.Close() })
+	c.start(clientConn, clientConn, clientConn)
+	return c
+}
+
+func TestClientFollowsPages(t *testing.T) {
+	page := func(names []string, next string) MCPResponse {
+		tools := make([]MCPTool, len(names))
+		for i, name := range names {
+			tools[i] = MCPTool{Name: name}
+		}
+		return MCPResponse{Result: map[string]interface{}{"tools": tools, "nextCursor": next}}
+	}
+
+	tests := []struct {
+		name    string
+		respond func(cursor string) MCPResponse
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "pages to the end",
+			respond: func(cursor string) MCPResponse {
+				switch cursor {
+				case "":
+					return page([]string{"a", "b"}, "2")
+				case "2":
+					return page([]string{"c"}, "3")
+				default:
+					return page([]string{"d"}, "")
+				}
+			},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "error on a later page",
+			respond: func(cursor string) MCPResponse {
+				if cursor == "" {
+					return page([]string{"a"}, "2")
+				}
+				return MCPResponse{Error: &MCPError{Code: -32602, Message: "Invalid cursor"}}
+			},
+			wantErr: "tools/list failed: Invalid cursor",
+		},
+		{
+			name:    "same cursor twice",
+			respond: func(cursor string) MCPResponse { return page([]string{"a"}, "again") },
+			wantErr: "returned the same cursor twice",
+		},
+		{
+			name: "cursors that never end",
+			respond: func(cursor string) MCPResponse {
+				n := 0
+				fmt.Sscan(cursor, &n)
+				return page(nil, fmt.Sprint(n+1))
+			},
+			wantErr: fmt.Sprintf("more than %d pages", maxListPages),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools, err := fakeListServer(t, tt.respond).ListTools()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ListTools() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListTools() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
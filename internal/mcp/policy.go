@@ -0,0 +1,57 @@
+// Package: internal/mcp/policy.go
+package mcp
+
+import (
+	"fmt"
+	"path"
+)
+
+// defaultPolicy names the tool policy of the clients no other names.
+const defaultPolicy = "*"
+
+// SetToolPolicy limits the tools a client may list and call to those
+// matching patterns (glob patterns such as "git_*"). The client is named by
+// the identity of its token (AddClientToken); the name a client gives in
+// initialize is its own claim and never selects a policy. "*" names every
+// client without a policy of its own, including all of those without a
+// token of their own. Once any policy is set, a client with none of its own
+// and no "*" policy gets no tools; with none set, every client gets all of
+// them.
+func (s *Server) SetToolPolicy(client string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.toolPolicies == nil {
+		s.toolPolicies = make(map[string][]string)
+	}
+	s.toolPolicies[client] = append([]string{}, patterns...)
+	return nil
+}
+
+// toolAllowed reports whether the peer may list and call the tool.
+func (s *Server) toolAllowed(p *peer, tool string) bool {
+	p.mu.Lock()
+	client := p.identity
+	p.mu.Unlock()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.toolPolicies) == 0 {
+		return true
+	}
+	patterns, ok := s.toolPolicies[client]
+	if client == "" || !ok {
+		patterns = s.toolPolicies[defaultPolicy]
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, tool); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+func TestToolPolicyFollowsIdentity(t *testing.T) {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	if err := s.SetToolPolicy("ci", []string{"git_*", "shell_*"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetToolPolicy(defaultPolicy, []string{"code_*"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		identity string // Of the client's token
+		claims   string // The name it gives in initialize
+		tool     string
+		want     bool
+	}{
+		{name: "identity gets its policy", identity: "ci", claims: "ci", tool: "shell_execute", want: true},
+		{name: "identity limited to its policy", identity: "ci", claims: "ci", tool: "code_search", want: false},
+		{name: "no token claiming another's name", claims: "ci", tool: "shell_execute", want: false},
+		{name: "no token gets the default", claims: "ci", tool: "code_search", want: true},
+		{name: "token claiming another's name", identity: "editor", claims: "ci", tool: "shell_execute", want: false},
+		{name: "unknown identity gets the default", identity: "editor", claims: "ci", tool: "code_search", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := s.addPeer(func([]byte) {})
+			defer s.removePeer(p)
+			p.identity = tt.identity
+			s.handleInitialize(p, MCPRequest{ID: 1, Method: "initialize", Params: map[string]interface{}{
+				"clientInfo": map[string]interface{}{"name": tt.claims, "version": "1.0"},
+			}})
+
+			if got := s.toolAllowed(p, tt.tool); got != tt.want {
+				t.Errorf("toolAllowed(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
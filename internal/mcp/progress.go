@@ -0,0 +1,103 @@
+// Package: internal/mcp/progress.go
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often a tool call that asked for progress gets a
+// notification while it runs.
+const progressInterval = time.Second
+
+// RequestMeta is the _meta of a request's params.
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"` // String or number
+	Authorization string      `json:"authorization,omitempty"` // "Bearer <token>", with initialize over TCP
+}
+
+type ProgressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// progressToken returns the progress token of a request, or nil when it
+// asked for no progress.
+func progressToken(req MCPRequest) interface{} {
+	params, _ := req.Params.(map[string]interface{})
+	meta, _ := params["_meta"].(map[string]interface{})
+	return meta["progressToken"]
+}
+
+// progressReporter is the stream of a running tool. Every progressInterval
+// it sends the peer that called the tool notifications/progress with the
+// last line of output, or how long the tool has run when it wrote none; the
+// total is unknown, so progress counts the notifications.
+type progressReporter struct {
+	peer    *peer
+	token   interface{}
+	started time.Time
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu       sync.Mutex
+	line     []byte // The line being written
+	lastLine string // The last complete line
+	sent     int
+}
+
+func startProgress(p *peer, token interface{}) *progressReporter {
+	r := &progressReporter{peer: p, token: token, started: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	go r.run()
+	return r
+}
+
+func (r *progressReporter) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.line = append(r.line, data...)
+	if i := bytes.LastIndexByte(r.line, '\n'); i >= 0 {
+		lines := bytes.Split(bytes.TrimRight(r.line[:i], "\r\n"), []byte("\n"))
+		if last := bytes.TrimSpace(lines[len(lines)-1]); len(last) > 0 {
+			r.lastLine = string(last)
+		}
+		r.line = append([]byte(nil), r.line[i+1:]...)
+	}
+	return len(data), nil
+}
+
+func (r *progressReporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *progressReporter) report() {
+	r.mu.Lock()
+	r.sent++
+	params := ProgressParams{ProgressToken: r.token, Progress: float64(r.sent), Message: r.lastLine}
+	r.mu.Unlock()
+	if params.Message == "" {
+		params.Message = fmt.Sprintf("running for %s", time.Since(r.started).Round(time.Second))
+	}
+	r.peer.notify("notifications/progress", params)
+}
+
+// Stop ends the notifications; none is sent after it returns, so the
+// response follows the last of them.
+func (r *progressReporter) Stop() {
+	close(r.stop)
+	<-r.done
+}
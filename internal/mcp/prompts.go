@@ -0,0 +1,134 @@
+// Package: internal/mcp/prompts.go
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Prompt is a prompt template the server offers hosts, such as a custom
+// slash command.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// registeredPrompt is a prompt with the template it renders.
+type registeredPrompt struct {
+	Prompt
+	template string
+}
+
+// RegisterPrompt offers prompt to hosts. Getting it renders template with
+// each $NAME placeholder, for the prompt's argument NAME in upper case,
+// replaced by the argument's value.
+func (s *Server) RegisterPrompt(prompt Prompt, template string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prompts[prompt.Name] = registeredPrompt{Prompt: prompt, template: template}
+}
+
+// handleListPrompts lists a page of the prompts, by name.
+func (s *Server) handleListPrompts(req MCPRequest) MCPResponse {
+	s.mu.RLock()
+	prompts := make([]Prompt, 0, len(s.prompts))
+	for _, prompt := range s.prompts {
+		prompts = append(prompts, prompt.Prompt)
+	}
+	s.mu.RUnlock()
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].Name < prompts[j].Name
+	})
+
+	names := make([]string, len(prompts))
+	for i, prompt := range prompts {
+		names[i] = prompt.Name
+	}
+	start, end, next, err := pageBounds(names, listCursor(req))
+	if err != nil {
+		return invalidCursor(req)
+	}
+
+	result := map[string]interface{}{
+		"prompts": prompts[start:end],
+	}
+	if next != "" {
+		result["nextCursor"] = next
+	}
+	return MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+func (s *Server) handleGetPrompt(req MCPRequest) MCPResponse {
+	var params GetPromptParams
+	if paramsData, ok := req.Params.(map[string]interface{}); ok {
+		paramsJSON, _ := json.Marshal(paramsData)
+		json.Unmarshal(paramsJSON, &params)
+	}
+
+	s.mu.RLock()
+	prompt, exists := s.prompts[params.Name]
+	s.mu.RUnlock()
+
+	if !exists {
+		return MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: fmt.Sprintf("Prompt not found: %s", params.Name),
+			},
+		}
+	}
+
+	text := prompt.template
+	for _, arg := range prompt.Arguments {
+		value, ok := params.Arguments[arg.Name]
+		if !ok && arg.Required {
+			return MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Error: &MCPError{
+					Code:    -32602,
+					Message: fmt.Sprintf("Missing required argument: %s", arg.Name),
+				},
+			}
+		}
+		text = strings.ReplaceAll(text, "$"+strings.ToUpper(arg.Name), value)
+	}
+
+	return MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"description": prompt.Description,
+			"messages": []map[string]interface{}{
+				{
+					"role": "user",
+					"content": map[string]interface{}{
+						"type": "text",
+						"text": text,
+					},
+				},
+			},
+		},
+	}
+}
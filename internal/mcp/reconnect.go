@@ -0,0 +1,104 @@
+// Package: internal/mcp/reconnect.go
+package mcp
+
+import (
+	"log"
+	"time"
+)
+
+// SetReconnect has the client connect again, the way it last connected,
+// when the connection is lost, such as when the server restarts: it waits
+// minReconnectDelay, doubling up to maxReconnectDelay while it fails, and
+// initializes again once connected. Requests made meanwhile fail at once.
+// The Manager reconnects with clients of its own instead.
+func (c *Client) SetReconnect(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnect = enabled
+}
+
+func (c *Client) setRedial(redial func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redial = redial
+}
+
+// connectionLost starts reconnecting once a connection stops delivering
+// messages, unless the client was closed or is reconnecting already.
+func (c *Client) connectionLost() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.reconnect || c.closed || c.reconnecting || c.redial == nil {
+		return
+	}
+	c.reconnecting = true
+	go c.reconnectLoop()
+}
+
+func (c *Client) reconnectLoop() {
+	// The lost connection is closed first, so a stdio server is reaped
+	c.closeConnection()
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-c.closing:
+			c.mu.Lock()
+			c.reconnecting = false
+			c.mu.Unlock()
+			return
+		case <-time.After(delay):
+		}
+
+		err := c.reconnectOnce()
+		if err == nil {
+			break
+		}
+		log.Printf("Warning: failed to reconnect to the MCP server: %v", err)
+		delay = min(delay*2, maxReconnectDelay)
+	}
+
+	c.mu.Lock()
+	c.reconnecting = false
+	done, toolsChanged, closed := c.done, c.onToolsChanged, c.closed
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+	// The server's tools may differ after it restarted
+	if toolsChanged != nil {
+		go toolsChanged()
+	}
+	// A connection lost again meanwhile found the loop still running
+	select {
+	case <-done:
+		c.connectionLost()
+	default:
+	}
+}
+
+// reconnectOnce connects, and initializes again when the client had
+// initialized.
+func (c *Client) reconnectOnce() error {
+	c.mu.RLock()
+	redial, info := c.redial, c.clientInfo
+	c.mu.RUnlock()
+
+	if err := redial(); err != nil {
+		return err
+	}
+	// Close may have missed the connection it raced with
+	select {
+	case <-c.closing:
+		c.closeConnection()
+		return nil
+	default:
+	}
+	if info.Name == "" {
+		return nil
+	}
+	if err := c.Initialize(info.Name, info.Version); err != nil {
+		c.closeConnection()
+		return err
+	}
+	return nil
+}
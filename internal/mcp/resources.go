@@ -0,0 +1,112 @@
+// Package: internal/mcp/resources.go
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ResourceHandler reads the resources of a scheme other than file, such as
+// claude-go://summary.
+type ResourceHandler func(uri string) ([]ResourceContents, error)
+
+// HandleScheme has handler read the registered resources whose URIs are of
+// scheme.
+func (s *Server) HandleScheme(scheme string, handler ResourceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.schemes == nil {
+		s.schemes = make(map[string]ResourceHandler)
+	}
+	s.schemes[scheme] = handler
+}
+
+// SetFileRoot serves file resources only from inside dir, once symlinks
+// are followed, whatever was registered.
+func (s *Server) SetFileRoot(dir string) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid file root: %w", err)
+	}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fileRoot = root
+	return nil
+}
+
+// resourceURI returns the URI a resource is registered under: a URI as it
+// is, a path as its file:// URI.
+func resourceURI(uri string) string {
+	if strings.Contains(uri, "://") || strings.HasPrefix(uri, "file:") {
+		return uri
+	}
+	return FileURI(uri)
+}
+
+// withinRoots reports whether path is one of roots or inside one.
+func withinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readResource reads a registered resource: a file from disk, if it is
+// inside the server's file root and the peer's roots once symlinks are
+// followed, as text or, when it is not UTF-8, as a base64 blob; a
+// resource of another scheme with its scheme's handler.
+func (s *Server) readResource(p *peer, resource Resource) ([]ResourceContents, error) {
+	u, err := url.Parse(resource.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI %q", resource.URI)
+	}
+	if u.Scheme != "file" {
+		s.mu.RLock()
+		handler := s.schemes[u.Scheme]
+		s.mu.RUnlock()
+		if handler == nil {
+			return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+		return handler(resource.URI)
+	}
+
+	path, ok := filePath(resource.URI)
+	if !ok {
+		return nil, fmt.Errorf("not a local file URI: %s", resource.URI)
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	fileRoot := s.fileRoot
+	s.mu.RUnlock()
+	p.mu.Lock()
+	roots := p.roots
+	p.mu.Unlock()
+	if (fileRoot != "" && !withinRoots(real, []string{fileRoot})) || (roots != nil && !withinRoots(real, roots)) {
+		return nil, fmt.Errorf("%s is outside the roots", resource.URI)
+	}
+
+	content, err := os.ReadFile(real)
+	if err != nil {
+		return nil, err
+	}
+	contents := ResourceContents{URI: resource.URI, MimeType: resource.MimeType}
+	if utf8.Valid(content) {
+		contents.Text = string(content)
+	} else {
+		contents.Blob = base64.StdEncoding.EncodeToString(content)
+	}
+	return []ResourceContents{contents}, nil
+}
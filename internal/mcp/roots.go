@@ -0,0 +1,130 @@
+// Package: internal/mcp/roots.go
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Root is a directory a client works in, as a file:// URI. The server asks
+// a client that offers roots for them once it is initialized and each
+// time it says they changed, and from then on lists and reads only the
+// file resources inside them.
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// FileURI returns the file:// URI of a local path.
+func FileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // C:/dir on Windows
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// filePath returns the local path of a file:// URI.
+func filePath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return "", false
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.Clean(filepath.FromSlash(path)), true
+}
+
+// inRoots reports whether a resource is one the peer may see: any when it
+// named no roots (or they are not known yet), otherwise only file
+// resources inside them and resources that are not files.
+func (p *peer) inRoots(uri string) bool {
+	p.mu.Lock()
+	roots := p.roots
+	p.mu.Unlock()
+	if roots == nil {
+		return true
+	}
+	path, ok := filePath(uri)
+	if !ok {
+		return true
+	}
+	return withinRoots(path, roots)
+}
+
+// fetchRoots asks a client that offers roots for them.
+func (s *Server) fetchRoots(p *peer) {
+	resp, err := p.request("roots/list", nil)
+	if err == nil && resp.Error != nil {
+		err = fmt.Errorf("%s", resp.Error.Message)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to list the roots of an MCP client: %v", err)
+		return
+	}
+
+	var result struct {
+		Roots []Root `json:"roots"`
+	}
+	resultJSON, _ := json.Marshal(resp.Result)
+	json.Unmarshal(resultJSON, &result)
+
+	roots := []string{}
+	for _, root := range result.Roots {
+		if path, ok := filePath(root.URI); ok {
+			// Files are read once their symlinks are followed
+			if real, err := filepath.EvalSymlinks(path); err == nil && real != path {
+				roots = append(roots, real)
+			}
+			roots = append(roots, path)
+		}
+	}
+	p.mu.Lock()
+	p.roots = roots
+	p.mu.Unlock()
+}
+
+// SetRoots sets the directories the client offers servers as roots. Set
+// before Initialize, the client announces them; set again once
+// initialized, it tells the server they changed.
+func (c *Client) SetRoots(roots []Root) {
+	c.mu.Lock()
+	if roots == nil {
+		roots = []Root{}
+	}
+	c.roots = roots
+	announced := c.rootsAnnounced
+	c.mu.Unlock()
+
+	if announced {
+		c.notify("notifications/roots/list_changed", nil)
+	}
+}
+
+// SetRoots sets the roots offered to every server, now and on each later
+// connection.
+func (m *Manager) SetRoots(roots []Root) {
+	m.mu.Lock()
+	m.roots = roots
+	var clients []*Client
+	for _, server := range m.servers {
+		if server.client != nil && server.status.State == StateConnected {
+			clients = append(clients, server.client)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, client := range clients {
+		client.SetRoots(roots)
+	}
+}
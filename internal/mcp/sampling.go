@@ -0,0 +1,127 @@
+// Package: internal/mcp/sampling.go
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Sampling modes of a configured server
+const (
+	SamplingAsk   = "ask"   // The user approves each request; the default
+	SamplingAllow = "allow" // Requests are answered without asking
+	SamplingDeny  = "deny"  // The client does not offer sampling
+)
+
+// samplingRejected is the error code of the response to a sampling
+// request that was not approved.
+const samplingRejected = -1
+
+type SamplingMessage struct {
+	Role    string          `json:"role"` // user or assistant
+	Content SamplingContent `json:"content"`
+}
+
+type SamplingContent struct {
+	Type     string `json:"type"` // text, image or audio
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // Base64, for an image or audio
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// ModelPreferences are what a server would like of the model: hints are
+// substrings of model names, tried in order, and the priorities (0 to 1)
+// weigh cost, speed and intelligence against each other.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         float64     `json:"costPriority,omitempty"`
+	SpeedPriority        float64     `json:"speedPriority,omitempty"`
+	IntelligencePriority float64     `json:"intelligencePriority,omitempty"`
+}
+
+type ModelHint struct {
+	Name string `json:"name"`
+}
+
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	IncludeContext   string            `json:"includeContext,omitempty"` // none, thisServer or allServers
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+type CreateMessageResult struct {
+	Role       string          `json:"role"`
+	Content    SamplingContent `json:"content"`
+	Model      string          `json:"model"`
+	StopReason string          `json:"stopReason,omitempty"` // endTurn, stopSequence or maxTokens
+}
+
+// Sampler answers a server's request to have the model generate a
+// message. ctx is that of a tool call to the server running at the time,
+// so the request can be tied to the turn that caused it; with none
+// running, it is the background context.
+type Sampler func(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error)
+
+// ErrSamplingRejected is returned by a Sampler when the user did not
+// approve the request.
+var ErrSamplingRejected = fmt.Errorf("the user rejected the sampling request")
+
+// SetSampler offers servers sampling, answered by sampler. It must be set
+// before Initialize, which announces the capability.
+func (c *Client) SetSampler(sampler Sampler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sampler = sampler
+}
+
+// handleSampling answers a sampling/createMessage request of the server.
+func (c *Client) handleSampling(id interface{}, sampler Sampler, rawParams json.RawMessage) {
+	resp := MCPResponse{JSONRPC: "2.0", ID: id}
+	var params CreateMessageParams
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		resp.Error = &MCPError{Code: -32602, Message: "Invalid params: " + err.Error()}
+		c.write(resp)
+		return
+	}
+
+	result, err := sampler(c.callContext(), params)
+	switch {
+	case errors.Is(err, ErrSamplingRejected):
+		resp.Error = &MCPError{Code: samplingRejected, Message: err.Error()}
+	case err != nil:
+		resp.Error = &MCPError{Code: -32603, Message: err.Error()}
+	default:
+		resp.Result = result
+	}
+	c.write(resp)
+}
+
+// callContext returns the context of the most recent tool call that is
+// still running, or the background context.
+func (c *Client) callContext() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var latest int64
+	ctx := context.Background()
+	for id, callCtx := range c.calls {
+		if id > latest {
+			latest, ctx = id, callCtx
+		}
+	}
+	return ctx
+}
+
+// SetSampler answers the sampling requests of the servers with sampler,
+// given the name of the server asking. Servers configured with sampling
+// "deny" are not offered it. It must be set before Start.
+func (m *Manager) SetSampler(sampler func(ctx context.Context, server string, params CreateMessageParams) (*CreateMessageResult, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sampler = sampler
+}
@@ -5,27 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
-	"os"
+	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/N0tT1m/claude-code-go/internal/tools"
 )
 
+// maxConnectionRequests is how many requests of a socket or TCP
+// connection run at once.
+const maxConnectionRequests = 32
+
 // MCP (Model Context Protocol) implementation
 type Server struct {
 	name         string
 	version      string
 	tools        *tools.Registry
 	resources    map[string]Resource
+	prompts      map[string]registeredPrompt
 	listeners    []net.Listener
+	httpServers  []*http.Server
+	sessions     map[string]*sseSession     // Open HTTP+SSE streams by session ID
+	httpSessions map[string]*httpSession    // Streamable HTTP sessions by ID
+	peers        map[*peer]bool             // Connected clients, over any transport
+	conns        map[net.Conn]bool          // Open socket connections
+	authToken    string                     // Required over TCP and HTTP
+	clientTokens map[string]string          // Tokens of clients of their own, by identity
+	toolPolicies map[string][]string        // Tool name patterns each client may use, by identity
+	schemes      map[string]ResourceHandler // Read the resources of other schemes than file, by scheme
+	fileRoot     string                     // File resources are served from inside it, when set
+	metrics      *serverMetrics
+	active       int           // Requests being handled
+	draining     bool          // Set by Shutdown
+	drained      chan struct{} // Closed when no request is left, once Shutdown waits
 	mu           sync.RWMutex
 	capabilities ServerCapabilities
 }
 
+// ServerCapabilities are the features a server offers, each an object per
+// the spec; one left out is not offered.
 type ServerCapabilities struct {
-	Tools     bool `json:"tools"`
-	Resources bool `json:"resources"`
-	Prompts   bool `json:"prompts"`
+	Tools     *ToolsCapability     `json:"tools,omitempty"`
+	Resources *ResourcesCapability `json:"resources,omitempty"`
+	Prompts   *PromptsCapability   `json:"prompts,omitempty"`
+}
+
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type PromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
 }
 
 type Resource struct {
@@ -60,11 +96,18 @@ type InitializeParams struct {
 	ProtocolVersion string             `json:"protocolVersion"`
 	Capabilities    ClientCapabilities `json:"capabilities"`
 	ClientInfo      ClientInfo         `json:"clientInfo"`
+	Meta            *RequestMeta       `json:"_meta,omitempty"`
 }
 
+// ClientCapabilities are the optional features a client offers, each an
+// object per the spec; one left out is not supported.
 type ClientCapabilities struct {
-	Roots    bool `json:"roots"`
-	Sampling bool `json:"sampling"`
+	Roots    *RootsCapability `json:"roots,omitempty"`
+	Sampling *struct{}        `json:"sampling,omitempty"`
+}
+
+type RootsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
 }
 
 type ClientInfo struct {
@@ -84,22 +127,33 @@ type ServerInfo struct {
 }
 
 func NewMCPServer(name, version string, toolRegistry *tools.Registry) *Server {
-	return &Server{
+	s := &Server{
 		name:      name,
 		version:   version,
 		tools:     toolRegistry,
 		resources: make(map[string]Resource),
+		prompts:   make(map[string]registeredPrompt),
+		peers:     make(map[*peer]bool),
+		conns:     make(map[net.Conn]bool),
+		metrics:   newServerMetrics(),
 		capabilities: ServerCapabilities{
-			Tools:     true,
-			Resources: true,
-			Prompts:   false,
+			Tools:     &ToolsCapability{ListChanged: true},
+			Resources: &ResourcesCapability{Subscribe: true},
+			Prompts:   &PromptsCapability{},
 		},
 	}
+
+	// Hosts fetch the tool list again when it changes
+	toolRegistry.OnChange(func() {
+		s.Notify("notifications/tools/list_changed", nil)
+	})
+	return s
 }
 
 func (s *Server) Start(socketPath string) error {
-	// Clean up existing socket
-	os.Remove(socketPath)
+	if err := removeStaleSocket(socketPath); err != nil {
+		return err
+	}
 
 	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
@@ -110,11 +164,15 @@ func (s *Server) Start(socketPath string) error {
 	s.listeners = append(s.listeners, listener)
 	s.mu.Unlock()
 
-	go s.acceptConnections(listener)
+	go s.acceptConnections(listener, false)
 	return nil
 }
 
+// StartTCP serves MCP on port to clients that present the auth token.
 func (s *Server) StartTCP(port int) error {
+	if !s.hasAuthToken() {
+		return errNoAuthToken
+	}
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return fmt.Errorf("failed to create TCP listener: %w", err)
@@ -124,63 +182,169 @@ func (s *Server) StartTCP(port int) error {
 	s.listeners = append(s.listeners, listener)
 	s.mu.Unlock()
 
-	go s.acceptConnections(listener)
+	go s.acceptConnections(listener, true)
 	return nil
 }
 
-func (s *Server) Stop() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for _, listener := range s.listeners {
-		listener.Close()
-	}
-	s.listeners = nil
-	return nil
-}
-
-func (s *Server) acceptConnections(listener net.Listener) {
+func (s *Server) acceptConnections(listener net.Listener, requireAuth bool) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
 			return // Listener closed
 		}
 
-		go s.handleConnection(conn)
+		go s.handleConnection(conn, requireAuth, "")
 	}
 }
 
-func (s *Server) handleConnection(conn net.Conn) {
+// handleConnection serves a client; with requireAuth, one whose first
+// message is not an initialize carrying the auth token is turned away.
+// identity is that of a token the client already presented otherwise.
+func (s *Server) handleConnection(conn net.Conn, requireAuth bool, identity string) {
 	defer conn.Close()
 
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		return
+	}
+	s.conns[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
 
+	// Notifications are written between responses
+	var writeMu sync.Mutex
+	write := func(msg interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return encoder.Encode(msg)
+	}
+	p := s.addPeer(func(data []byte) {
+		write(json.RawMessage(data))
+	})
+	defer s.removePeer(p)
+	p.mu.Lock()
+	p.identity = identity
+	p.mu.Unlock()
+
+	// Past maxConnectionRequests running at once, the connection is not
+	// read until one finishes
+	slots := make(chan struct{}, maxConnectionRequests)
+
 	for {
-		var req MCPRequest
-		if err := decoder.Decode(&req); err != nil {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
 			return // Connection closed or malformed JSON
 		}
+		batch := isBatch(raw)
+		var req MCPRequest
+		if !batch && json.Unmarshal(raw, &req) != nil {
+			continue
+		}
 
-		resp := s.handleRequest(req)
-		if err := encoder.Encode(resp); err != nil {
-			return // Failed to send response
+		// A batch cannot be the initialize that authorizes the connection
+		if requireAuth {
+			identity, ok := s.authorizedInitialize(req)
+			if !ok {
+				write(MCPResponse{
+					JSONRPC: "2.0",
+					ID:      req.ID,
+					Error: &MCPError{
+						Code:    unauthorized,
+						Message: "Unauthorized: initialize with the server's auth token first",
+					},
+				})
+				return
+			}
+			p.mu.Lock()
+			p.identity = identity
+			p.mu.Unlock()
+			requireAuth = false
+		}
+
+		if batch {
+			slots <- struct{}{}
+			go func(raw json.RawMessage) {
+				defer func() { <-slots }()
+				if reply := s.handleBatch(p, raw); reply != nil {
+					if err := write(reply); err != nil {
+						conn.Close()
+					}
+				}
+			}(raw)
+			continue
+		}
+
+		// Notifications get no response, and responses are to the server's
+		// own requests
+		if req.ID == nil {
+			s.handleNotification(p, req)
+			continue
+		}
+		if req.Method == "" {
+			p.deliver(raw)
+			continue
 		}
+		// Each request runs on its own, so a slow tool call holds up nothing
+		// else and its cancellation can be read; responses go out in the
+		// order they are ready
+		if !s.beginRequest() {
+			write(refuseRequest(req))
+			continue
+		}
+		slots <- struct{}{}
+		go func(req MCPRequest) {
+			defer func() { <-slots }()
+			defer s.endRequest()
+			if err := write(s.handleRequest(p, req)); err != nil {
+				conn.Close() // Failed to send response
+			}
+		}(req)
 	}
 }
 
-func (s *Server) handleRequest(req MCPRequest) MCPResponse {
+// handleRequest answers a request, over any transport, counting it in the
+// stats by method.
+func (s *Server) handleRequest(p *peer, req MCPRequest) (resp MCPResponse) {
+	start := time.Now()
+	defer func() {
+		method := req.Method
+		if resp.Error != nil && resp.Error.Code == -32601 {
+			method = unknownMethod
+		}
+		s.metrics.record(method, resp.Error != nil, time.Since(start))
+	}()
+
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req)
+		return s.handleInitialize(p, req)
+	case "ping":
+		return MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: struct{}{}}
 	case "tools/list":
-		return s.handleListTools(req)
+		return s.handleListTools(p, req)
 	case "tools/call":
-		return s.handleCallTool(req)
+		return s.handleCallTool(p, req)
 	case "resources/list":
-		return s.handleListResources(req)
+		return s.handleListResources(p, req)
 	case "resources/read":
-		return s.handleReadResource(req)
+		return s.handleReadResource(p, req)
+	case "resources/subscribe":
+		return s.handleSubscribe(p, req, true)
+	case "resources/unsubscribe":
+		return s.handleSubscribe(p, req, false)
+	case "prompts/list":
+		return s.handleListPrompts(req)
+	case "prompts/get":
+		return s.handleGetPrompt(req)
+	case "server/stats":
+		return MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: s.Stats()}
 	default:
 		return MCPResponse{
 			JSONRPC: "2.0",
@@ -193,13 +357,17 @@ func (s *Server) handleRequest(req MCPRequest) MCPResponse {
 	}
 }
 
-func (s *Server) handleInitialize(req MCPRequest) MCPResponse {
+func (s *Server) handleInitialize(p *peer, req MCPRequest) MCPResponse {
 	var params InitializeParams
 	if paramsData, ok := req.Params.(map[string]interface{}); ok {
 		paramsJSON, _ := json.Marshal(paramsData)
 		json.Unmarshal(paramsJSON, &params)
 	}
 
+	p.mu.Lock()
+	p.offersRoots = params.Capabilities.Roots != nil
+	p.mu.Unlock()
+
 	result := InitializeResult{
 		ProtocolVersion: "2024-11-05",
 		Capabilities:    s.capabilities,
@@ -216,20 +384,39 @@ func (s *Server) handleInitialize(req MCPRequest) MCPResponse {
 	}
 }
 
-func (s *Server) handleListTools(req MCPRequest) MCPResponse {
+// handleListTools lists a page of the tools the client may use, by name.
+func (s *Server) handleListTools(p *peer, req MCPRequest) MCPResponse {
 	tools := s.tools.GetAvailable()
 
-	mcpTools := make([]MCPTool, len(tools))
-	for i, tool := range tools {
-		mcpTools[i] = MCPTool{
+	mcpTools := make([]MCPTool, 0, len(tools))
+	for _, tool := range tools {
+		if !s.toolAllowed(p, tool.Function.Name) {
+			continue
+		}
+		mcpTools = append(mcpTools, MCPTool{
 			Name:        tool.Function.Name,
 			Description: tool.Function.Description,
 			InputSchema: tool.Function.Parameters,
-		}
+		})
+	}
+	sort.Slice(mcpTools, func(i, j int) bool {
+		return mcpTools[i].Name < mcpTools[j].Name
+	})
+
+	names := make([]string, len(mcpTools))
+	for i, tool := range mcpTools {
+		names[i] = tool.Name
+	}
+	start, end, next, err := pageBounds(names, listCursor(req))
+	if err != nil {
+		return invalidCursor(req)
 	}
 
 	result := map[string]interface{}{
-		"tools": mcpTools,
+		"tools": mcpTools[start:end],
+	}
+	if next != "" {
+		result["nextCursor"] = next
 	}
 
 	return MCPResponse{
@@ -248,16 +435,52 @@ type MCPTool struct {
 type CallToolParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
 }
 
-func (s *Server) handleCallTool(req MCPRequest) MCPResponse {
+// handleCallTool runs a tool for the peer p, sending it progress while the
+// tool runs when the request carries a progress token. A cancellation of
+// the request stops tools that support it.
+func (s *Server) handleCallTool(p *peer, req MCPRequest) MCPResponse {
 	var params CallToolParams
 	if paramsData, ok := req.Params.(map[string]interface{}); ok {
 		paramsJSON, _ := json.Marshal(paramsData)
 		json.Unmarshal(paramsJSON, &params)
 	}
 
-	result, err := s.tools.Execute(params.Name, params.Arguments)
+	if !s.toolAllowed(p, params.Name) {
+		return MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: fmt.Sprintf("Tool %s is not allowed for this client", params.Name),
+			},
+		}
+	}
+
+	ctx, done := p.calls.start(req.ID)
+	defer done()
+
+	var result *tools.Result
+	var err error
+	if params.Meta != nil && params.Meta.ProgressToken != nil {
+		progress := startProgress(p, params.Meta.ProgressToken)
+		result, err = s.tools.ExecuteResult(ctx, params.Name, params.Arguments, progress)
+		progress.Stop()
+	} else {
+		result, err = s.tools.ExecuteResult(ctx, params.Name, params.Arguments, nil)
+	}
+	if ctx.Err() != nil {
+		return MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    requestCancelled,
+				Message: "Request cancelled",
+			},
+		}
+	}
 	if err != nil {
 		return MCPResponse{
 			JSONRPC: "2.0",
@@ -272,18 +495,24 @@ func (s *Server) handleCallTool(req MCPRequest) MCPResponse {
 	return MCPResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": result,
-				},
-			},
+		Result: CallToolResult{
+			Content:           result.Content,
+			StructuredContent: result.Structured,
 		},
 	}
 }
 
-func (s *Server) handleListResources(req MCPRequest) MCPResponse {
+// CallToolResult is the result of tools/call: the tool's content blocks,
+// and the same as a JSON object when the tool gives one.
+type CallToolResult struct {
+	Content           []tools.Content `json:"content"`
+	StructuredContent interface{}     `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// handleListResources lists a page of the resources, by URI; of the files
+// only those inside the client's roots.
+func (s *Server) handleListResources(p *peer, req MCPRequest) MCPResponse {
 	s.mu.RLock()
 	resources := make([]Resource, 0, len(s.resources))
 	for _, resource := range s.resources {
@@ -291,8 +520,31 @@ func (s *Server) handleListResources(req MCPRequest) MCPResponse {
 	}
 	s.mu.RUnlock()
 
+	scoped := resources[:0]
+	for _, resource := range resources {
+		if p.inRoots(resource.URI) {
+			scoped = append(scoped, resource)
+		}
+	}
+	resources = scoped
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].URI < resources[j].URI
+	})
+
+	uris := make([]string, len(resources))
+	for i, resource := range resources {
+		uris[i] = resource.URI
+	}
+	start, end, next, err := pageBounds(uris, listCursor(req))
+	if err != nil {
+		return invalidCursor(req)
+	}
+
 	result := map[string]interface{}{
-		"resources": resources,
+		"resources": resources[start:end],
+	}
+	if next != "" {
+		result["nextCursor"] = next
 	}
 
 	return MCPResponse{
@@ -306,16 +558,18 @@ type ReadResourceParams struct {
 	URI string `json:"uri"`
 }
 
-func (s *Server) handleReadResource(req MCPRequest) MCPResponse {
+func (s *Server) handleReadResource(p *peer, req MCPRequest) MCPResponse {
 	var params ReadResourceParams
 	if paramsData, ok := req.Params.(map[string]interface{}); ok {
 		paramsJSON, _ := json.Marshal(paramsData)
 		json.Unmarshal(paramsJSON, &params)
 	}
 
+	// Only what was registered is read, never a path a URI makes up
 	s.mu.RLock()
 	resource, exists := s.resources[params.URI]
 	s.mu.RUnlock()
+	exists = exists && p.inRoots(params.URI)
 
 	if !exists {
 		return MCPResponse{
@@ -328,48 +582,34 @@ func (s *Server) handleReadResource(req MCPRequest) MCPResponse {
 		}
 	}
 
-	// For file resources, read the content
-	if resource.MimeType == "text/plain" || resource.MimeType == "application/octet-stream" {
-		content, err := os.ReadFile(resource.URI)
-		if err != nil {
-			return MCPResponse{
-				JSONRPC: "2.0",
-				ID:      req.ID,
-				Error: &MCPError{
-					Code:    -32602,
-					Message: fmt.Sprintf("Failed to read resource: %s", err.Error()),
-				},
-			}
-		}
-
-		result := map[string]interface{}{
-			"contents": []map[string]interface{}{
-				{
-					"uri":      resource.URI,
-					"mimeType": resource.MimeType,
-					"text":     string(content),
-				},
-			},
-		}
-
+	contents, err := s.readResource(p, resource)
+	if err != nil {
 		return MCPResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
-			Result:  result,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: fmt.Sprintf("Failed to read resource: %s", err.Error()),
+			},
 		}
 	}
 
+	result := map[string]interface{}{
+		"contents": contents,
+	}
+
 	return MCPResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
-		Error: &MCPError{
-			Code:    -32602,
-			Message: "Unsupported resource type",
-		},
+		Result:  result,
 	}
 }
 
+// RegisterResource offers a resource at uri: a file:// URI (a path is
+// registered as its file:// URI), or a URI of a scheme with a handler
+// (see HandleScheme).
 func (s *Server) RegisterResource(uri, name, description, mimeType string, metadata map[string]string) {
+	uri = resourceURI(uri)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
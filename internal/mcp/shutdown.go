@@ -0,0 +1,129 @@
+// Package: internal/mcp/shutdown.go
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// Stop shuts the server down, giving the requests that are running
+// stopTimeout to finish.
+const stopTimeout = 10 * time.Second
+
+// cancelGrace is how long Shutdown waits for the requests it cancelled at
+// its deadline to answer before closing their connections.
+const cancelGrace = 2 * time.Second
+
+// shuttingDown is the error code of the response to a request that came in
+// while the server was shutting down.
+const shuttingDown = -32000
+
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown stops the server gracefully: it stops accepting connections
+// (closing a Unix socket removes its file) and refuses new requests, waits
+// for those running to be answered until ctx is done, then cancels the
+// rest and closes every connection. It returns ctx's error when requests
+// had to be cancelled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.listeners = nil
+	drained := s.drainedLocked()
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.mu.RLock()
+		for p := range s.peers {
+			p.calls.cancelAll()
+		}
+		s.mu.RUnlock()
+		select {
+		case <-drained:
+		case <-time.After(cancelGrace):
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.stopHTTP()
+	return err
+}
+
+// drainedLocked returns a channel closed once no request is running. The
+// caller holds s.mu.
+func (s *Server) drainedLocked() chan struct{} {
+	if s.drained == nil {
+		s.drained = make(chan struct{})
+		if s.active == 0 {
+			close(s.drained)
+		}
+	}
+	return s.drained
+}
+
+// beginRequest counts a request in until endRequest, so Shutdown waits for
+// its answer; it reports false, refusing it, once the server is shutting
+// down.
+func (s *Server) beginRequest() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.active++
+	return true
+}
+
+func (s *Server) endRequest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.active == 0 && s.drained != nil {
+		close(s.drained)
+	}
+}
+
+// refuseRequest answers a request the server will not run as it is
+// shutting down.
+func refuseRequest(req MCPRequest) MCPResponse {
+	return MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error: &MCPError{
+			Code:    shuttingDown,
+			Message: "Server shutting down",
+		},
+	}
+}
+
+// removeStaleSocket removes the socket file a server that crashed left at
+// socketPath, so it can be listened on again, but not one that a running
+// server still answers on.
+func removeStaleSocket(socketPath string) error {
+	if _, err := os.Stat(socketPath); err != nil {
+		return nil
+	}
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by a running server", socketPath)
+	}
+	return os.Remove(socketPath)
+}
@@ -0,0 +1,375 @@
+// Package: internal/mcp/sse.go
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The HTTP+SSE transport: a client opens an event stream with GET /sse,
+// whose first event names the endpoint to POST its messages to; the
+// server answers each POST with 202 Accepted and sends the responses on
+// the stream.
+
+const (
+	// sseKeepAlive is how often an idle stream gets a comment, so proxies
+	// do not time it out
+	sseKeepAlive = 30 * time.Second
+
+	// sseEndpointTimeout is how long ConnectSSE waits for the endpoint
+	sseEndpointTimeout = 30 * time.Second
+)
+
+// sseSession is an open event stream of the server.
+type sseSession struct {
+	events chan []byte
+	done   chan struct{}
+	peer   *peer
+}
+
+// StartSSE serves MCP over HTTP with server-sent events on addr (host:port,
+// or :port for all interfaces): GET /sse opens a session and POST
+// /messages?sessionId=... sends it a request. The endpoint is announced
+// relative to the stream's URL, so both work under a reverse proxy's path
+// prefix. Requests must carry the auth token.
+func (s *Server) StartSSE(addr string) error {
+	if !s.hasAuthToken() {
+		return errNoAuthToken
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", s.handleSSEStream)
+	mux.HandleFunc("POST /messages", s.handleSSEMessage)
+	server := &http.Server{Handler: s.requireAuth(mux)}
+
+	s.mu.Lock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*sseSession)
+	}
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+func (s *Server) handleSSEStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	session := &sseSession{events: make(chan []byte, 16), done: make(chan struct{})}
+	// A stream that is too far behind misses notifications
+	session.peer = s.addPeer(func(data []byte) {
+		select {
+		case session.events <- data:
+		default:
+		}
+	})
+	session.peer.mu.Lock()
+	session.peer.identity = requestIdentity(r)
+	session.peer.mu.Unlock()
+	s.mu.Lock()
+	s.sessions[id] = session
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.sessions, id)
+		s.mu.Unlock()
+		s.removePeer(session.peer)
+		close(session.done)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+
+	fmt.Fprintf(w, "event: endpoint\ndata: messages?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-session.events:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	session, ok := s.sessions[r.URL.Query().Get("sessionId")]
+	s.mu.RUnlock()
+	if !ok || !session.peer.ownedBy(requestIdentity(r)) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	var raw json.RawMessage
+	var req MCPRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10*1024*1024)).Decode(&raw); err != nil || (!isBatch(raw) && json.Unmarshal(raw, &req) != nil) {
+		http.Error(w, "invalid JSON-RPC message", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	// The responses to a batch go out on the stream as one message
+	if isBatch(raw) {
+		go func() {
+			reply := s.handleBatch(session.peer, raw)
+			if reply == nil {
+				return
+			}
+			data, err := json.Marshal(reply)
+			if err != nil {
+				return
+			}
+			select {
+			case session.events <- data:
+			case <-session.done:
+			}
+		}()
+		return
+	}
+
+	// Notifications get no response, and responses are to the server's
+	// own requests
+	if req.ID == nil {
+		s.handleNotification(session.peer, req)
+		return
+	}
+	if req.Method == "" {
+		session.peer.deliver(raw)
+		return
+	}
+	if !s.beginRequest() {
+		if data, err := json.Marshal(refuseRequest(req)); err == nil {
+			session.peer.send(data)
+		}
+		return
+	}
+	go func() {
+		defer s.endRequest()
+		data, err := json.Marshal(s.handleRequest(session.peer, req))
+		if err != nil {
+			return
+		}
+		select {
+		case session.events <- data:
+		case <-session.done:
+		}
+	}()
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to create session ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ConnectSSE opens the event stream of an MCP server's HTTP+SSE transport
+// at streamURL (e.g. https://example.com/mcp/sse) and posts requests to
+// the endpoint the server announces on it.
+func (c *Client) ConnectSSE(streamURL string) error {
+	c.setRedial(func() error { return c.ConnectSSE(streamURL) })
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		return fmt.Errorf("invalid MCP server URL: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("invalid MCP server URL: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	authorization := c.authorization()
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	// The stream stays open, so the client has no timeout; waiting for the
+	// endpoint has one of its own
+	httpClient := c.httpClient()
+	timer := time.AfterFunc(sseEndpointTimeout, cancel)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return fmt.Errorf("failed to connect to %s: %w", streamURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		timer.Stop()
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("failed to connect to %s: %s", streamURL, resp.Status)
+	}
+
+	events := newSSEReader(resp.Body)
+	var endpoint *url.URL
+	for endpoint == nil {
+		event, data, err := events.next()
+		if err != nil {
+			timer.Stop()
+			resp.Body.Close()
+			cancel()
+			return fmt.Errorf("no endpoint announced by %s: %w", streamURL, err)
+		}
+		if event == "endpoint" {
+			if endpoint, err = base.Parse(strings.TrimSpace(data)); err != nil {
+				timer.Stop()
+				resp.Body.Close()
+				cancel()
+				return fmt.Errorf("invalid endpoint announced by %s: %w", streamURL, err)
+			}
+		}
+	}
+	timer.Stop()
+
+	// Messages on the stream are fed to the decoder one per line
+	pr, pw := io.Pipe()
+	go func() {
+		defer resp.Body.Close()
+		for {
+			event, data, err := events.next()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if event == "message" {
+				if _, err := pw.Write([]byte(data + "\n")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	poster := &ssePoster{client: httpClient, endpoint: endpoint.String(), authorization: authorization}
+	c.start(pr, poster, closerFunc(func() error {
+		cancel()
+		return pr.Close()
+	}))
+	return nil
+}
+
+// ssePoster sends each message written to it, which json.Encoder writes in
+// one call, as a POST to the session's endpoint.
+type ssePoster struct {
+	client        *http.Client
+	endpoint      string
+	authorization string
+}
+
+func (p *ssePoster) Write(data []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("server answered %s", resp.Status)
+	}
+	return len(data), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// sseReader parses a text/event-stream into events.
+type sseReader struct {
+	r      *bufio.Reader
+	lastID string // The last event ID the stream set
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next event's type ("message" when unnamed) and data,
+// its data lines joined by newlines.
+func (s *sseReader) next() (event, data string, err error) {
+	var lines []string
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if lines == nil && event == "" {
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			return event, strings.Join(lines, "\n"), nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			lines = append(lines, value)
+		case "id":
+			s.lastID = value
+		}
+	}
+}
+
+// stopHTTP closes the HTTP servers and their open streams. The caller
+// holds s.mu.
+func (s *Server) stopHTTP() {
+	for _, server := range s.httpServers {
+		server.Close()
+	}
+	s.httpServers = nil
+}
@@ -0,0 +1,202 @@
+// Package: internal/mcp/subscriptions.go
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// peerRequestTimeout is how long the server waits for a client to answer
+// a request of its own.
+const peerRequestTimeout = 30 * time.Second
+
+// peer is a client connected to the server, over any transport, the
+// resources it subscribed to and its requests that are running.
+type peer struct {
+	send      func(data []byte) // Delivers a message the server sends of its own accord
+	calls     *inFlight
+	requestID int64
+
+	mu            sync.Mutex
+	subscriptions map[string]bool             // By URI
+	responses     map[string]chan MCPResponse // To the server's requests, by ID
+	offersRoots   bool                        // Whether the client said it has roots
+	identity      string                      // Of the client's own token, if it has one
+	roots         []string                    // Paths of the client's roots; nil when not known
+}
+
+// peerRequest is the key in calls of a request the server made, which a
+// client's own request IDs never equal.
+type peerRequest string
+
+type SubscribeParams struct {
+	URI string `json:"uri"`
+}
+
+func (s *Server) addPeer(send func(data []byte)) *peer {
+	p := &peer{send: send, calls: newInFlight(), subscriptions: make(map[string]bool), responses: make(map[string]chan MCPResponse)}
+	s.mu.Lock()
+	s.peers[p] = true
+	s.mu.Unlock()
+	return p
+}
+
+// removePeer forgets a client that went away, stopping its requests.
+func (s *Server) removePeer(p *peer) {
+	s.mu.Lock()
+	delete(s.peers, p)
+	s.mu.Unlock()
+	p.calls.cancelAll()
+}
+
+// request sends the client a request and waits for its answer, until the
+// client goes away or peerRequestTimeout passes.
+func (p *peer) request(method string, params interface{}) (MCPResponse, error) {
+	id := fmt.Sprintf("server-%d", atomic.AddInt64(&p.requestID, 1))
+	data, err := json.Marshal(MCPRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return MCPResponse{}, err
+	}
+
+	respChan := make(chan MCPResponse, 1)
+	ctx, done := p.calls.start(peerRequest(id))
+	defer done()
+	p.mu.Lock()
+	p.responses[id] = respChan
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.responses, id)
+		p.mu.Unlock()
+	}()
+
+	p.send(data)
+	select {
+	case resp := <-respChan:
+		return resp, nil
+	case <-ctx.Done():
+		return MCPResponse{}, fmt.Errorf("the client went away")
+	case <-time.After(peerRequestTimeout):
+		return MCPResponse{}, fmt.Errorf("%s timed out", method)
+	}
+}
+
+// deliver hands a response the client sent to the request of the
+// server's that waits for it.
+func (p *peer) deliver(data []byte) {
+	var resp MCPResponse
+	if json.Unmarshal(data, &resp) != nil {
+		return
+	}
+	id, _ := resp.ID.(string)
+	p.mu.Lock()
+	respChan := p.responses[id]
+	p.mu.Unlock()
+	if respChan != nil {
+		select {
+		case respChan <- resp:
+		default:
+		}
+	}
+}
+
+// notify sends a notification to the peer alone.
+func (p *peer) notify(method string, params interface{}) error {
+	data, err := json.Marshal(MCPNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	p.send(data)
+	return nil
+}
+
+// ownedBy reports whether the peer's session belongs to the token with the
+// identity, so another token cannot use it.
+func (p *peer) ownedBy(identity string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.identity == identity
+}
+
+func (p *peer) subscribed(uri string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.subscriptions[uri]
+}
+
+// Notify sends a notification to every connected client. Streamable HTTP
+// sessions keep it for a client that resumes its stream; an HTTP+SSE
+// stream that is too far behind misses it.
+func (s *Server) Notify(method string, params interface{}) error {
+	return s.notify(method, params, func(*peer) bool { return true })
+}
+
+// ResourceChanged tells the clients subscribed to the resource at uri that
+// it changed, so they read it again.
+func (s *Server) ResourceChanged(uri string) error {
+	return s.notify("notifications/resources/updated", SubscribeParams{URI: uri}, func(p *peer) bool {
+		return p.subscribed(uri)
+	})
+}
+
+func (s *Server) notify(method string, params interface{}, to func(*peer) bool) error {
+	data, err := json.Marshal(MCPNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	peers := make([]*peer, 0, len(s.peers))
+	for p := range s.peers {
+		if to(p) {
+			peers = append(peers, p)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, p := range peers {
+		p.send(data)
+	}
+	return nil
+}
+
+func (s *Server) handleSubscribe(p *peer, req MCPRequest, subscribe bool) MCPResponse {
+	var params SubscribeParams
+	if paramsData, ok := req.Params.(map[string]interface{}); ok {
+		paramsJSON, _ := json.Marshal(paramsData)
+		json.Unmarshal(paramsJSON, &params)
+	}
+
+	s.mu.RLock()
+	_, exists := s.resources[params.URI]
+	s.mu.RUnlock()
+	exists = exists && p.inRoots(params.URI)
+
+	if !exists && subscribe {
+		return MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Resource not found",
+			},
+		}
+	}
+
+	p.mu.Lock()
+	if subscribe {
+		p.subscriptions[params.URI] = true
+	} else {
+		delete(p.subscriptions, params.URI)
+	}
+	p.mu.Unlock()
+
+	return MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  struct{}{},
+	}
+}
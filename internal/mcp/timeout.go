@@ -0,0 +1,36 @@
+// Package: internal/mcp/timeout.go
+package mcp
+
+import "time"
+
+// defaultRequestTimeout is how long the client waits for the response to
+// a request whose method has no timeout of its own.
+const defaultRequestTimeout = 30 * time.Second
+
+// anyMethod names the timeout of the methods without one of their own.
+const anyMethod = "*"
+
+// SetTimeout sets how long the client waits for the response to requests
+// of method, such as "tools/call", or, with "*", of every method without a
+// timeout of its own (30 seconds unless set); 0 waits as long as the
+// request's context allows. A context's earlier deadline still applies.
+func (c *Client) SetTimeout(method string, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timeouts == nil {
+		c.timeouts = make(map[string]time.Duration)
+	}
+	c.timeouts[method] = timeout
+}
+
+func (c *Client) requestTimeout(method string) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if timeout, ok := c.timeouts[method]; ok {
+		return timeout
+	}
+	if timeout, ok := c.timeouts[anyMethod]; ok {
+		return timeout
+	}
+	return defaultRequestTimeout
+}
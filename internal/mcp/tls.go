@@ -0,0 +1,130 @@
+// Package: internal/mcp/tls.go
+package mcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StartTLS serves MCP on port over TLS with the certificate and key in
+// certFile and keyFile (PEM), to clients that present the auth token like
+// over TCP.
+func (s *Server) StartTLS(port int, certFile, keyFile string) error {
+	if !s.hasAuthToken() {
+		return errNoAuthToken
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	listener, err := tls.Listen("tcp", fmt.Sprintf(":%d", port), &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create TLS listener: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	go s.acceptConnections(listener, true)
+	return nil
+}
+
+// TLSOptions are how the client checks the server's certificate, over
+// ConnectTLS and https URLs. Left empty, it is verified against the
+// system's CAs.
+type TLSOptions struct {
+	CAFile     string // PEM certificates of the CAs to trust instead of the system's, such as a self-signed server certificate
+	ServerName string // The name the certificate must be for; the host connected to by default
+	PinSHA256  string // Hex SHA-256 of the server certificate's public key (SPKI), which must match; with no CAFile, the CAs are not checked
+}
+
+// SetTLS sets how the client checks the server's certificate. It must be
+// set before connecting.
+func (c *Client) SetTLS(opts TLSOptions) error {
+	config := &tls.Config{ServerName: opts.ServerName, MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		data, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.PinSHA256 != "" {
+		pin, err := hex.DecodeString(strings.ReplaceAll(opts.PinSHA256, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return fmt.Errorf("invalid pin %q: want the hex SHA-256 of the server's public key", opts.PinSHA256)
+		}
+		// The pin names the one key to accept, so it stands in for the CAs
+		// when none are given
+		config.InsecureSkipVerify = opts.CAFile == ""
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("the server sent no certificate")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+			if !bytes.Equal(sum[:], pin) {
+				return fmt.Errorf("the server's public key does not match the pinned one")
+			}
+			return nil
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tlsConfig = config
+	return nil
+}
+
+// ConnectTLS speaks to a server started with StartTLS, checking its
+// certificate as SetTLS set.
+func (c *Client) ConnectTLS(host string, port int) error {
+	c.setRedial(func() error { return c.ConnectTLS(host, port) })
+	c.mu.RLock()
+	config := c.tlsConfig
+	c.mu.RUnlock()
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	conn, err := tls.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)), config)
+	if err != nil {
+		return fmt.Errorf("failed to connect over TLS: %w", err)
+	}
+
+	c.start(conn, conn, conn)
+	return nil
+}
+
+// httpClient returns the client for the HTTP transports, which checks an
+// https server as SetTLS set.
+func (c *Client) httpClient() *http.Client {
+	c.mu.RLock()
+	config := c.tlsConfig
+	c.mu.RUnlock()
+	if config == nil {
+		return &http.Client{}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+// testCertificate writes a self-signed certificate for 127.0.0.1 and
+// localhost, and its key, returning their files and the hex SHA-256 of its
+// public key.
+func testCertificate(t *testing.T) (certFile, keyFile, pin string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return certFile, keyFile, hex.EncodeToString(sum[:])
+}
+
+func TestTLSVerification(t *testing.T) {
+	certFile, keyFile, pin := testCertificate(t)
+	otherCert, _, otherPin := testCertificate(t)
+
+	s := newTestServer(t)
+	_, port := freeAddr(t)
+	if err := s.StartTLS(port, certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Colon-separated, as openssl prints fingerprints
+	var colons []string
+	for i := 0; i < len(pin); i += 2 {
+		colons = append(colons, strings.ToUpper(pin[i:i+2]))
+	}
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr bool
+	}{
+		{name: "pinned key", opts: TLSOptions{PinSHA256: pin}},
+		{name: "pinned key with colons", opts: TLSOptions{PinSHA256: strings.Join(colons, ":")}},
+		{name: "CA file", opts: TLSOptions{CAFile: certFile}},
+		{name: "CA file and pinned key", opts: TLSOptions{CAFile: certFile, PinSHA256: pin}},
+		{name: "CA file with the name on the certificate", opts: TLSOptions{CAFile: certFile, ServerName: "localhost"}},
+		{name: "system CAs", opts: TLSOptions{}, wantErr: true},
+		{name: "another key pinned", opts: TLSOptions{PinSHA256: otherPin}, wantErr: true},
+		{name: "CA file and another key pinned", opts: TLSOptions{CAFile: certFile, PinSHA256: otherPin}, wantErr: true},
+		{name: "another CA", opts: TLSOptions{CAFile: otherCert}, wantErr: true},
+		{name: "CA file with a name not on the certificate", opts: TLSOptions{CAFile: certFile, ServerName: "example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewMCPClient()
+			defer c.Close()
+			c.SetAuthToken(testToken)
+			if err := c.SetTLS(tt.opts); err != nil {
+				t.Fatalf("SetTLS: %v", err)
+			}
+
+			err := c.ConnectTLS("127.0.0.1", port)
+			if err == nil {
+				err = c.Initialize("test-client", "1.0")
+			}
+			if tt.wantErr && err == nil {
+				t.Fatal("connected, want the certificate refused")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetTLSRefusesBadOptions(t *testing.T) {
+	certFile, _, pin := testCertificate(t)
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr string
+	}{
+		{name: "pin not hex", opts: TLSOptions{PinSHA256: strings.Repeat("zz", sha256.Size)}, wantErr: "invalid pin"},
+		{name: "pin too short", opts: TLSOptions{PinSHA256: pin[:32]}, wantErr: "invalid pin"},
+		{name: "pin too long", opts: TLSOptions{PinSHA256: pin + "00"}, wantErr: "invalid pin"},
+		{name: "missing CA file", opts: TLSOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: "failed to read CA file"},
+		{name: "CA file without certificates", opts: TLSOptions{CAFile: notPEM}, wantErr: "no certificates found"},
+		{name: "valid", opts: TLSOptions{CAFile: certFile, PinSHA256: pin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewMCPClient().SetTLS(tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SetTLS() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SetTLS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartTLSRefusesBadSetup(t *testing.T) {
+	certFile, keyFile, _ := testCertificate(t)
+	_, otherKey, _ := testCertificate(t)
+
+	tests := []struct {
+		name     string
+		token    string
+		certFile string
+		keyFile  string
+		wantErr  string
+	}{
+		{name: "no token", certFile: certFile, keyFile: keyFile, wantErr: "needs an auth token"},
+		{name: "missing certificate", token: testToken, certFile: filepath.Join(t.TempDir(), "missing.pem"), keyFile: keyFile, wantErr: "failed to load TLS certificate"},
+		{name: "key of another certificate", token: testToken, certFile: certFile, keyFile: otherKey, wantErr: "failed to load TLS certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMCPServer("test", "1.0", tools.NewRegistry())
+			defer s.Stop()
+			if tt.token != "" {
+				s.SetAuthToken(tt.token)
+			}
+			_, port := freeAddr(t)
+			err := s.StartTLS(port, tt.certFile, tt.keyFile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("StartTLS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
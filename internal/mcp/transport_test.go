@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+)
+
+const testToken = "secret-token"
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	return fmt.Sprintf("127.0.0.1:%d", port), port
+}
+
+// transport starts one of the server's transports and connects c to it.
+type transport struct {
+	name    string
+	network bool // Needs the auth token
+	start   func(t *testing.T, s *Server) (connect func(c *Client) error)
+}
+
+var transports = []transport{
+	{
+		name: "unix",
+		start: func(t *testing.T, s *Server) func(c *Client) error {
+			socketPath := filepath.Join(t.TempDir(), "mcp.sock")
+			if err := s.Start(socketPath); err != nil {
+				t.Fatal(err)
+			}
+			return func(c *Client) error { return c.ConnectUnix(socketPath) }
+		},
+	},
+	{
+		name:    "tcp",
+		network: true,
+		start: func(t *testing.T, s *Server) func(c *Client) error {
+			_, port := freeAddr(t)
+			if err := s.StartTCP(port); err != nil {
+				t.Fatal(err)
+			}
+			return func(c *Client) error { return c.ConnectTCP("127.0.0.1", port) }
+		},
+	},
+	{
+		name:    "streamable http",
+		network: true,
+		start: func(t *testing.T, s *Server) func(c *Client) error {
+			addr, _ := freeAddr(t)
+			if err := s.StartHTTP(addr); err != nil {
+				t.Fatal(err)
+			}
+			return func(c *Client) error { return c.ConnectHTTP("http://" + addr + "/mcp") }
+		},
+	},
+	{
+		name:    "sse",
+		network: true,
+		start: func(t *testing.T, s *Server) func(c *Client) error {
+			addr, _ := freeAddr(t)
+			if err := s.StartSSE(addr); err != nil {
+				t.Fatal(err)
+			}
+			return func(c *Client) error { return c.ConnectSSE("http://" + addr + "/sse") }
+		},
+	},
+	{
+		name:    "websocket",
+		network: true,
+		start: func(t *testing.T, s *Server) func(c *Client) error {
+			addr, _ := freeAddr(t)
+			if err := s.StartWebSocket(addr); err != nil {
+				t.Fatal(err)
+			}
+			return func(c *Client) error { return c.ConnectWebSocket("ws://" + addr + "/ws") }
+		},
+	},
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	s.SetAuthToken(testToken)
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+func TestTransports(t *testing.T) {
+	for _, tr := range transports {
+		t.Run(tr.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.RegisterResource("claude-go://test", "Test", "A test resource", "text/plain", nil)
+			connect := tr.start(t, s)
+
+			c := NewMCPClient()
+			defer c.Close()
+			c.SetAuthToken(testToken)
+			if err := connect(c); err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			if err := c.Initialize("test-client", "1.0"); err != nil {
+				t.Fatalf("initialize: %v", err)
+			}
+
+			tools, err := c.ListTools()
+			if err != nil {
+				t.Fatalf("tools/list: %v", err)
+			}
+			names := make([]string, len(tools))
+			for i, tool := range tools {
+				names[i] = tool.Name
+			}
+			if !slices.Contains(names, "code_search") {
+				t.Errorf("tools/list = %v, want code_search among them", names)
+			}
+
+			resources, err := c.ListResources()
+			if err != nil {
+				t.Fatalf("resources/list: %v", err)
+			}
+			if len(resources) != 1 || resources[0].URI != "claude-go://test" {
+				t.Errorf("resources/list = %+v, want the test resource", resources)
+			}
+
+			if err := c.Ping(); err != nil {
+				t.Errorf("ping: %v", err)
+			}
+		})
+	}
+}
+
+func TestTransportsRefuseWrongToken(t *testing.T) {
+	for _, tr := range transports {
+		if !tr.network {
+			continue
+		}
+		t.Run(tr.name, func(t *testing.T) {
+			connect := tr.start(t, newTestServer(t))
+
+			c := NewMCPClient()
+			defer c.Close()
+			c.SetAuthToken("wrong-token")
+			err := connect(c)
+			if err == nil {
+				err = c.Initialize("test-client", "1.0")
+			}
+			if err == nil {
+				t.Fatal("connected with the wrong token")
+			}
+		})
+	}
+}
+
+func TestNetworkTransportsNeedToken(t *testing.T) {
+	s := NewMCPServer("test", "1.0", tools.NewRegistry())
+	defer s.Stop()
+	addr, port := freeAddr(t)
+
+	starts := map[string]func() error{
+		"tcp":       func() error { return s.StartTCP(port) },
+		"http":      func() error { return s.StartHTTP(addr) },
+		"sse":       func() error { return s.StartSSE(addr) },
+		"websocket": func() error { return s.StartWebSocket(addr) },
+	}
+	for name, start := range starts {
+		if err := start(); err != errNoAuthToken {
+			t.Errorf("%s without a token: err = %v, want errNoAuthToken", name, err)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Package: internal/mcp/websocket.go
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// websocketGUID is what the handshake's accept key is derived with
+	// (RFC 6455).
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// maxWebSocketMessage bounds a message, so a peer cannot have one
+	// buffered without end.
+	maxWebSocketMessage = 10 * 1024 * 1024
+	// websocketHandshakeTimeout bounds connecting and the handshake.
+	websocketHandshakeTimeout = 30 * time.Second
+)
+
+// WebSocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// StartWebSocket serves MCP over WebSocket at /ws on addr (host:port, or
+// :port for all interfaces), one JSON-RPC message per text message. The
+// auth token goes in the Authorization header of the upgrade or, from
+// browsers, which cannot set it, in the initialize like over TCP.
+func (s *Server) StartWebSocket(addr string) error {
+	if !s.hasAuthToken() {
+		return errNoAuthToken
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create WebSocket listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+	server := &http.Server{Handler: mux}
+
+	s.mu.Lock()
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+// handleWebSocket upgrades a request and serves it as a socket
+// connection. Pages of any origin may connect, since none gets in without
+// the token.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return
+	}
+	identity, authorized := s.identify(r.Header.Get("Authorization"))
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n"
+	if headerHasToken(r.Header, "Sec-WebSocket-Protocol", "mcp") {
+		response += "Sec-WebSocket-Protocol: mcp\r\n"
+	}
+	rw.WriteString(response + "\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	s.handleConnection(&wsConn{Conn: conn, r: rw.Reader}, !authorized, identity)
+}
+
+// ConnectWebSocket speaks to an MCP server over WebSocket at wsURL, ws://
+// or wss:// (checked as SetTLS set), such as ws://localhost:8080/ws. The
+// auth token goes in the Authorization header of the upgrade.
+func (c *Client) ConnectWebSocket(wsURL string) error {
+	c.setRedial(func() error { return c.ConnectWebSocket(wsURL) })
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return fmt.Errorf("invalid WebSocket URL: %w", err)
+	}
+
+	dialer := &net.Dialer{Timeout: websocketHandshakeTimeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = dialer.Dial("tcp", hostPort(u, "80"))
+	case "wss":
+		c.mu.RLock()
+		config := c.tlsConfig
+		c.mu.RUnlock()
+		if config == nil {
+			config = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, "443"), config)
+	default:
+		return fmt.Errorf("invalid WebSocket URL %q: want ws:// or wss://", wsURL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	ws, err := c.websocketHandshake(conn, u)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("WebSocket handshake failed: %w", err)
+	}
+
+	c.start(ws, ws, ws)
+	return nil
+}
+
+func (c *Client) websocketHandshake(conn net.Conn, u *url.URL) (*wsConn, error) {
+	conn.SetDeadline(time.Now().Add(websocketHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":                {"websocket"},
+			"Connection":             {"Upgrade"},
+			"Sec-Websocket-Key":      {key},
+			"Sec-Websocket-Version":  {"13"},
+			"Sec-Websocket-Protocol": {"mcp"},
+		},
+	}
+	if authorization := c.authorization(); authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("server answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, fmt.Errorf("server answered with the wrong accept key")
+	}
+	return &wsConn{Conn: conn, r: r, client: true}, nil
+}
+
+// hostPort returns the host and port of u, the port defaulting to port.
+func hostPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header has token, in
+// any case.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsConn carries JSON-RPC over a WebSocket, so it is served and read as a
+// socket connection is: each Write, which the JSON encoder makes once per
+// message, is sent as a text message, and the messages received are read
+// one per line. Pings are answered as they come.
+type wsConn struct {
+	net.Conn
+	r       *bufio.Reader
+	client  bool // Masks the frames it sends, as a client must, and expects the server's not to be
+	writeMu sync.Mutex
+	pending []byte // What is left to read of the last message
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		message, err := c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = append(message, '\n')
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	if err := c.writeFrame(wsText, bytes.TrimRight(p, "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close says goodbye with a normal closure before closing the connection.
+func (c *wsConn) Close() error {
+	c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrame(wsClose, []byte{0x03, 0xe8})
+	return c.Conn.Close()
+}
+
+// readMessage returns the next data message, put together from its
+// frames. Control frames may come between those of a message, but not
+// another message's first frame.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			c.writeFrame(wsPong, payload)
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return nil, io.EOF
+		case wsText, wsBinary:
+			if started {
+				return nil, fmt.Errorf("websocket: new message before the last one ended")
+			}
+			started = true
+		case wsContinuation:
+			if !started {
+				return nil, fmt.Errorf("websocket: continuation frame without a message to continue")
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+
+		message = append(message, payload...)
+		if len(message) > maxWebSocketMessage {
+			return nil, fmt.Errorf("websocket: message larger than %d bytes", maxWebSocketMessage)
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.r, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	// No extension is negotiated that would give the reserved bits a
+	// meaning
+	if header[0]&0x70 != 0 {
+		err = fmt.Errorf("websocket: reserved bits set")
+		return
+	}
+	// Control frames (close, ping, pong) come whole and short
+	if opcode&0x8 != 0 && (!fin || length > 125) {
+		err = fmt.Errorf("websocket: fragmented or oversized control frame")
+		return
+	}
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.r, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.r, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxWebSocketMessage {
+		err = fmt.Errorf("websocket: frame larger than %d bytes", maxWebSocketMessage)
+		return
+	}
+	// Clients mask their frames and servers do not
+	if masked == c.client {
+		err = fmt.Errorf("websocket: wrongly masked frame")
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return fmt.Errorf("websocket: failed to make a mask: %w", err)
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.Conn.Write(frame)
+	return err
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// recordConn keeps what is written to it; reading goes through wsConn.r.
+type recordConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordConn) Write(p []byte) (int, error) { return c.written.Write(p) }
+
+// frame encodes a frame as a client sends it, masked; header0 sets the
+// FIN and reserved bits and the opcode directly.
+func frame(header0 byte, payload string) []byte {
+	out := []byte{header0}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, 0x80|byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x80|126)
+		out = binary.BigEndian.AppendUint16(out, uint16(n))
+	default:
+		out = append(out, 0x80|127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	out = append(out, mask[:]...)
+	for i := 0; i < len(payload); i++ {
+		out = append(out, payload[i]^mask[i%4])
+	}
+	return out
+}
+
+const fin = 0x80
+
+func frames(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestWebSocketReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "single frame",
+			input: frame(fin|wsText, `{"id":1}`),
+			want:  `{"id":1}`,
+		},
+		{
+			name:  "fragmented",
+			input: frames(frame(wsText, "hello "), frame(wsContinuation, "wide "), frame(fin|wsContinuation, "world")),
+			want:  "hello wide world",
+		},
+		{
+			name:  "ping between fragments",
+			input: frames(frame(wsText, "hello "), frame(fin|wsPing, "p"), frame(fin|wsContinuation, "world")),
+			want:  "hello world",
+		},
+		{
+			name:  "16-bit length",
+			input: frame(fin|wsText, strings.Repeat("a", 300)),
+			want:  strings.Repeat("a", 300),
+		},
+		{
+			name:  "64-bit length",
+			input: frame(fin|wsBinary, strings.Repeat("b", 70000)),
+			want:  strings.Repeat("b", 70000),
+		},
+		{
+			name:    "unmasked frame from a client",
+			input:   []byte{fin | wsText, 2, 'h', 'i'},
+			wantErr: "wrongly masked",
+		},
+		{
+			name:    "continuation without a message",
+			input:   frame(fin|wsContinuation, "orphan"),
+			wantErr: "continuation frame without a message",
+		},
+		{
+			name:    "new message before the last ended",
+			input:   frames(frame(wsText, "one"), frame(fin|wsText, "two")),
+			wantErr: "new message before the last one ended",
+		},
+		{
+			name:    "fragmented ping",
+			input:   frame(wsPing, "p"),
+			wantErr: "fragmented or oversized control frame",
+		},
+		{
+			name:    "fragmented close",
+			input:   frames(frame(wsText, "one"), frame(wsClose, "")),
+			wantErr: "fragmented or oversized control frame",
+		},
+		{
+			name:    "oversized control frame",
+			input:   frame(fin|wsPing, strings.Repeat("p", 126)),
+			wantErr: "fragmented or oversized control frame",
+		},
+		{
+			name:    "reserved bit",
+			input:   frame(fin|0x40|wsText, "x"),
+			wantErr: "reserved bits set",
+		},
+		{
+			name:    "unknown opcode",
+			input:   frame(fin|0x3, "x"),
+			wantErr: "unknown opcode 3",
+		},
+		{
+			name:    "frame over the message limit",
+			input:   []byte{fin | wsText, 0x80 | 127, 0, 0, 0, 0, 0x10, 0, 0, 0},
+			wantErr: "frame larger than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &wsConn{Conn: &recordConn{}, r: bufio.NewReader(bytes.NewReader(tt.input))}
+			got, err := c.readMessage()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readMessage() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readMessage() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketControlFrames(t *testing.T) {
+	conn := &recordConn{}
+	c := &wsConn{Conn: conn, r: bufio.NewReader(bytes.NewReader(frames(frame(fin|wsPing, "hi"), frame(fin|wsClose, "\x03\xe8"))))}
+	if _, err := c.readMessage(); !errors.Is(err, io.EOF) {
+		t.Fatalf("readMessage() error = %v, want io.EOF after a close", err)
+	}
+
+	// The server answers unmasked: a pong echoing the ping, then the close
+	want := []byte{fin | wsPong, 2, 'h', 'i', fin | wsClose, 2, 0x03, 0xe8}
+	if !bytes.Equal(conn.written.Bytes(), want) {
+		t.Errorf("written = %x, want %x", conn.written.Bytes(), want)
+	}
+}
+
+func TestWebSocketWriteFrame(t *testing.T) {
+	for _, size := range []int{0, 5, 125, 126, 300, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte{'x'}, size)
+		for _, client := range []bool{true, false} {
+			conn := &recordConn{}
+			writer := &wsConn{Conn: conn, client: client}
+			if err := writer.writeFrame(wsText, payload); err != nil {
+				t.Fatalf("writeFrame(%d bytes, client %v) error = %v", size, client, err)
+			}
+			if masked := conn.written.Bytes()[1]&0x80 != 0; masked != client {
+				t.Errorf("writeFrame(%d bytes, client %v) masked = %v", size, client, masked)
+			}
+
+			reader := &wsConn{Conn: &recordConn{}, r: bufio.NewReader(&conn.written), client: !client}
+			got, err := reader.readMessage()
+			if err != nil {
+				t.Fatalf("reading back %d bytes (client %v): %v", size, client, err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("read back %d bytes, want %d", len(got), size)
+			}
+		}
+	}
+}
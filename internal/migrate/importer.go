@@ -0,0 +1,287 @@
+// Package: internal/migrate/importer.go
+package migrate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+// Sources lists the assistants settings can be imported from.
+var Sources = []string{"claude-code", "aider", "cursor"}
+
+// MemorySection is an instructions document found in another tool's config.
+type MemorySection struct {
+	Source  string
+	Content string
+}
+
+// Result is what an import found, before it is written to the project.
+type Result struct {
+	Memory     []MemorySection
+	MCPServers map[string]config.MCPServerConfig
+	Notes      []string
+}
+
+// Scan collects the instructions and MCP servers another assistant has
+// configured for the project at root.
+func Scan(root, from string) (*Result, error) {
+	result := &Result{MCPServers: make(map[string]config.MCPServerConfig)}
+
+	switch from {
+	case "claude-code":
+		for _, name := range []string{"CLAUDE.md", filepath.Join(".claude", "CLAUDE.md"), "CLAUDE.local.md"} {
+			result.addMemoryFile(root, name)
+		}
+		if err := result.addMCPConfig(filepath.Join(root, ".mcp.json")); err != nil {
+			return nil, err
+		}
+
+	case "aider":
+		if err := result.scanAider(root); err != nil {
+			return nil, err
+		}
+
+	case "cursor":
+		result.addMemoryFile(root, ".cursorrules")
+
+		rules, _ := filepath.Glob(filepath.Join(root, ".cursor", "rules", "*.mdc"))
+		sort.Strings(rules)
+		for _, rule := range rules {
+			rel, _ := filepath.Rel(root, rule)
+			result.addMemoryFile(root, rel)
+		}
+
+		if err := result.addMCPConfig(filepath.Join(root, ".cursor", "mcp.json")); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown source %q (supported: %s)", from, strings.Join(Sources, ", "))
+	}
+
+	return result, nil
+}
+
+func (r *Result) Empty() bool {
+	return len(r.Memory) == 0 && len(r.MCPServers) == 0
+}
+
+// Apply writes the imported memory and MCP servers into claude-go's project
+// files. Existing memory sections and server names are left untouched.
+func Apply(root string, r *Result) ([]string, error) {
+	var changes []string
+
+	if len(r.Memory) > 0 {
+		path := config.MemoryPath(root)
+		existing, _ := os.ReadFile(path)
+
+		var memory strings.Builder
+		memory.Write(existing)
+
+		for _, section := range r.Memory {
+			header := fmt.Sprintf("## Imported from %s", section.Source)
+			if strings.Contains(string(existing), header) {
+				continue
+			}
+			if memory.Len() > 0 {
+				memory.WriteString("\n\n")
+			}
+			memory.WriteString(header + "\n\n" + strings.TrimSpace(section.Content) + "\n")
+			changes = append(changes, fmt.Sprintf("memory: imported %s", section.Source))
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(memory.String()), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write memory: %w", err)
+		}
+	}
+
+	if len(r.MCPServers) > 0 {
+		project, err := config.LoadProject(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load project config: %w", err)
+		}
+		if project.MCPServers == nil {
+			project.MCPServers = make(map[string]config.MCPServerConfig)
+		}
+
+		names := make([]string, 0, len(r.MCPServers))
+		for name := range r.MCPServers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if _, exists := project.MCPServers[name]; exists {
+				changes = append(changes, fmt.Sprintf("mcp server %s: already configured, skipped", name))
+				continue
+			}
+			project.MCPServers[name] = r.MCPServers[name]
+			changes = append(changes, fmt.Sprintf("mcp server %s: added", name))
+		}
+
+		if err := config.SaveProject(root, project); err != nil {
+			return nil, fmt.Errorf("failed to save project config: %w", err)
+		}
+	}
+
+	return changes, nil
+}
+
+func (r *Result) addMemoryFile(root, name string) {
+	content, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil || strings.TrimSpace(string(content)) == "" {
+		return
+	}
+
+	r.Memory = append(r.Memory, MemorySection{
+		Source:  filepath.ToSlash(name),
+		Content: stripFrontmatter(string(content)),
+	})
+}
+
+// addMCPConfig reads the "mcpServers" block shared by Claude Code's .mcp.json
+// and Cursor's mcp.json.
+func (r *Result) addMCPConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file struct {
+		MCPServers map[string]struct {
+			Type    string            `json:"type"`
+			Command string            `json:"command"`
+			Args    []string          `json:"args"`
+			Env     map[string]string `json:"env"`
+			URL     string            `json:"url"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for name, server := range file.MCPServers {
+		transport := server.Type
+		if transport == "" {
+			transport = "stdio"
+			if server.URL != "" {
+				transport = "sse"
+			}
+		}
+
+		r.MCPServers[name] = config.MCPServerConfig{
+			Command:   server.Command,
+			Args:      server.Args,
+			Env:       server.Env,
+			URL:       server.URL,
+			Transport: transport,
+			Enabled:   true,
+		}
+	}
+
+	return nil
+}
+
+// scanAider reads the conventions files listed under "read" in
+// .aider.conf.yml, falling back to the customary CONVENTIONS.md.
+func (r *Result) scanAider(root string) error {
+	confPath := filepath.Join(root, ".aider.conf.yml")
+
+	readFiles, model, err := parseAiderConfig(confPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", confPath, err)
+	}
+
+	if len(readFiles) == 0 {
+		readFiles = []string{"CONVENTIONS.md"}
+	}
+	for _, name := range readFiles {
+		r.addMemoryFile(root, name)
+	}
+
+	if model != "" {
+		r.Notes = append(r.Notes, fmt.Sprintf("aider used model %q; set lm_studio.model to a local equivalent", model))
+	}
+
+	return nil
+}
+
+// parseAiderConfig extracts the "read" and "model" keys from aider's YAML
+// config. Only the flat scalar/list forms aider documents are supported.
+func parseAiderConfig(path string) ([]string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var readFiles []string
+	var model string
+	inRead := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if inRead && strings.HasPrefix(trimmed, "- ") {
+			readFiles = append(readFiles, unquote(strings.TrimPrefix(trimmed, "- ")))
+			continue
+		}
+		inRead = false
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "read":
+			if value == "" {
+				inRead = true
+			} else if strings.HasPrefix(value, "[") {
+				for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+					if item = unquote(strings.TrimSpace(item)); item != "" {
+						readFiles = append(readFiles, item)
+					}
+				}
+			} else {
+				readFiles = append(readFiles, unquote(value))
+			}
+		case "model":
+			model = unquote(value)
+		}
+	}
+
+	return readFiles, model, scanner.Err()
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"'`)
+}
+
+// stripFrontmatter drops the YAML header used by Cursor's .mdc rule files.
+func stripFrontmatter(content string) string {
+	if !strings.HasPrefix(content, "---") {
+		return content
+	}
+	rest := content[3:]
+	if end := strings.Index(rest, "\n---"); end != -1 {
+		return strings.TrimLeft(rest[end+4:], "\n")
+	}
+	return content
+}
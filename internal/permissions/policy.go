@@ -0,0 +1,161 @@
+// Package: internal/permissions/policy.go
+package permissions
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+// Class is the kind of code a path holds.
+type Class string
+
+const (
+	ClassTest       Class = "test"
+	ClassProduction Class = "production"
+)
+
+// Auto-accept modes for config.PermissionsConfig.AutoAccept.
+const (
+	AutoAcceptAll   = "all"
+	AutoAcceptTests = "tests"
+	AutoAcceptNone  = "none"
+)
+
+// Policy decides which file edits, and which commands, may run without
+// approval.
+type Policy struct {
+	autoAccept string
+	patterns   []string
+}
+
+func NewPolicy(cfg config.PermissionsConfig) (*Policy, error) {
+	mode := cfg.AutoAccept
+	if mode == "" {
+		mode = AutoAcceptTests
+	}
+
+	switch mode {
+	case AutoAcceptAll, AutoAcceptTests, AutoAcceptNone:
+	default:
+		return nil, fmt.Errorf("invalid permissions.auto_accept %q (want all, tests or none)", mode)
+	}
+
+	patterns := cfg.TestPatterns
+	if patterns == nil {
+		patterns = config.DefaultTestPatterns()
+	}
+
+	return &Policy{autoAccept: mode, patterns: patterns}, nil
+}
+
+func (p *Policy) AutoAccept() string {
+	return p.autoAccept
+}
+
+// Classify reports whether path is test or production code.
+func (p *Policy) Classify(path string) Class {
+	slashPath := filepath.ToSlash(filepath.Clean(path))
+	slashPath = strings.TrimPrefix(slashPath, "./")
+
+	for _, pattern := range p.patterns {
+		if matchGlob(pattern, slashPath) {
+			return ClassTest
+		}
+	}
+	return ClassProduction
+}
+
+// PathClass pairs an edited path with its classification.
+type PathClass struct {
+	Path  string
+	Class Class
+}
+
+// Decision is the outcome of evaluating an edit against the policy.
+type Decision struct {
+	NeedsApproval bool
+	Paths         []PathClass
+}
+
+// Evaluate classifies the paths an edit touches and decides whether the edit
+// needs approval. An edit touching any production file is treated as a
+// production edit.
+func (p *Policy) Evaluate(paths []string) Decision {
+	decision := Decision{}
+	production := false
+
+	for _, path := range paths {
+		class := p.Classify(path)
+		if class == ClassProduction {
+			production = true
+		}
+		decision.Paths = append(decision.Paths, PathClass{Path: path, Class: class})
+	}
+
+	switch p.autoAccept {
+	case AutoAcceptNone:
+		decision.NeedsApproval = len(paths) > 0
+	case AutoAcceptTests:
+		decision.NeedsApproval = production
+	}
+
+	return decision
+}
+
+// CommandNeedsApproval reports whether running a command needs approval.
+// A command can change any file, so it is approved like an edit to
+// production code.
+func (p *Policy) CommandNeedsApproval() bool {
+	return p.autoAccept != AutoAcceptAll
+}
+
+// FetchNeedsApproval reports whether a request to another host needs
+// approval. Its URL can carry anything the model has read out of the
+// workspace, so it is approved like a command.
+func (p *Policy) FetchNeedsApproval() bool {
+	return p.autoAccept != AutoAcceptAll
+}
+
+// Badge labels a class for approval prompts.
+func Badge(class Class) string {
+	if class == ClassTest {
+		return "[TEST]"
+	}
+	return "[PROD]"
+}
+
+// matchGlob matches slash-separated paths against patterns where "*" stays
+// within one path segment and "**" spans any number of segments. Patterns
+// without a slash match the file name alone.
+func matchGlob(pattern, path string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(pattern, filepath.Base(path))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,217 @@
+// Package: internal/repl/complete.go
+package repl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Candidate is one completion offered for the word under the cursor.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// Provider completes the arguments of a slash command. args holds the
+// arguments before the word being completed and prefix the partial word.
+type Provider interface {
+	Complete(args []string, prefix string) []Candidate
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(args []string, prefix string) []Candidate
+
+func (f ProviderFunc) Complete(args []string, prefix string) []Candidate {
+	return f(args, prefix)
+}
+
+type command struct {
+	description string
+	provider    Provider
+}
+
+// Completer completes slash command names and delegates their arguments to
+// the provider registered for each command.
+type Completer struct {
+	commands map[string]command
+}
+
+func NewCompleter() *Completer {
+	return &Completer{commands: make(map[string]command)}
+}
+
+// Register adds a command. provider may be nil for commands without
+// completable arguments.
+func (c *Completer) Register(name, description string, provider Provider) {
+	c.commands[name] = command{description: description, provider: provider}
+}
+
+// Complete returns the byte offset in line where the word being completed
+// starts and the candidates for it.
+func (c *Completer) Complete(line string) (int, []Candidate) {
+	if !strings.HasPrefix(line, "/") {
+		return len(line), nil
+	}
+
+	fields := strings.Fields(line)
+	start := strings.LastIndexAny(line, " \t") + 1
+	prefix := line[start:]
+
+	// Still typing the command name
+	if start == 0 {
+		var candidates []Candidate
+		for name, cmd := range c.commands {
+			if strings.HasPrefix("/"+name, prefix) {
+				candidates = append(candidates, Candidate{Value: "/" + name, Description: cmd.description})
+			}
+		}
+		sortCandidates(candidates)
+		return 0, candidates
+	}
+
+	cmd, ok := c.commands[strings.TrimPrefix(fields[0], "/")]
+	if !ok || cmd.provider == nil {
+		return start, nil
+	}
+
+	args := fields[1:]
+	if prefix != "" && len(args) > 0 {
+		args = args[:len(args)-1]
+	}
+
+	var candidates []Candidate
+	for _, candidate := range cmd.provider.Complete(args, prefix) {
+		if strings.HasPrefix(candidate.Value, prefix) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sortCandidates(candidates)
+	return start, candidates
+}
+
+func sortCandidates(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Value < candidates[j].Value
+	})
+}
+
+// Static completes a fixed set of words at the given argument position.
+func Static(position int, values ...string) Provider {
+	return ProviderFunc(func(args []string, prefix string) []Candidate {
+		if len(args) != position {
+			return nil
+		}
+		candidates := make([]Candidate, len(values))
+		for i, value := range values {
+			candidates[i] = Candidate{Value: value}
+		}
+		return candidates
+	})
+}
+
+// Cached wraps a provider whose results are expensive to compute, such as
+// model lists fetched from the server, and reuses them for ttl.
+func Cached(ttl time.Duration, fn func() []Candidate) Provider {
+	var (
+		mu      sync.Mutex
+		values  []Candidate
+		fetched time.Time
+	)
+
+	return ProviderFunc(func(args []string, prefix string) []Candidate {
+		if len(args) != 0 {
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if values == nil || time.Since(fetched) > ttl {
+			values = fn()
+			fetched = time.Now()
+		}
+		return values
+	})
+}
+
+const fileIndexTTL = 30 * time.Second
+
+// FileProvider completes project file paths from the git index (tracked and
+// untracked-but-not-ignored files), falling back to walking the directory
+// outside of git repositories.
+type FileProvider struct {
+	Root string
+
+	mu      sync.Mutex
+	files   []string
+	indexed time.Time
+}
+
+func (p *FileProvider) Complete(args []string, prefix string) []Candidate {
+	files := p.index()
+
+	// Offer directories first so long paths can be completed step by step
+	seen := make(map[string]bool)
+	var candidates []Candidate
+	for _, file := range files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+
+		value := file
+		if slash := strings.Index(file[len(prefix):], "/"); slash != -1 {
+			value = file[:len(prefix)+slash+1]
+		}
+		if !seen[value] {
+			seen[value] = true
+			candidates = append(candidates, Candidate{Value: value})
+		}
+	}
+	return candidates
+}
+
+func (p *FileProvider) index() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.files != nil && time.Since(p.indexed) < fileIndexTTL {
+		return p.files
+	}
+
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = p.Root
+	if output, err := cmd.Output(); err == nil {
+		p.files = strings.Split(strings.TrimSpace(string(output)), "\n")
+	} else {
+		p.files = walkFiles(p.Root)
+	}
+	p.indexed = time.Now()
+
+	return p.files
+}
+
+func walkFiles(root string) []string {
+	files := []string{}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
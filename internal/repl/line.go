@@ -0,0 +1,166 @@
+// Package: internal/repl/line.go
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LineReader reads REPL input. On a terminal it switches the tty out of
+// canonical mode while a line is edited so Tab can trigger completion;
+// otherwise (pipes, files, terminals without stty) it reads plain lines.
+// Both paths share one buffered reader so no input is lost between them.
+type LineReader struct {
+	in        *os.File
+	out       io.Writer
+	reader    *bufio.Reader
+	completer *Completer
+	terminal  bool
+}
+
+func NewLineReader(in *os.File, out io.Writer, completer *Completer) *LineReader {
+	return &LineReader{
+		in:        in,
+		out:       out,
+		reader:    bufio.NewReader(in),
+		completer: completer,
+		terminal:  isTerminal(in),
+	}
+}
+
+// ReadLine prints prompt and returns the next line without its newline.
+func (l *LineReader) ReadLine(prompt string) (string, error) {
+	if l.terminal && l.completer != nil {
+		if saved, err := l.stty("-g"); err == nil {
+			if _, err := l.stty("-icanon", "-echo", "min", "1"); err == nil {
+				defer l.stty(strings.TrimSpace(saved))
+				return l.edit(prompt)
+			}
+		}
+	}
+
+	fmt.Fprint(l.out, prompt)
+	line, err := l.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (l *LineReader) edit(prompt string) (string, error) {
+	var buf []rune
+	fmt.Fprint(l.out, prompt)
+
+	redraw := func() {
+		fmt.Fprintf(l.out, "\r\033[K%s%s", prompt, string(buf))
+	}
+
+	for {
+		r, _, err := l.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(l.out, "\n")
+			return string(buf), nil
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(l.out, "\n")
+				return "", io.EOF
+			}
+		case 127, '\b':
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+				redraw()
+			}
+		case 21: // Ctrl-U
+			buf = buf[:0]
+			redraw()
+		case '\t':
+			buf = l.complete(buf, redraw)
+		case 27: // Escape sequences (arrow keys etc.) are not supported
+			l.skipEscape()
+		default:
+			if r >= ' ' {
+				buf = append(buf, r)
+				fmt.Fprint(l.out, string(r))
+			}
+		}
+	}
+}
+
+// complete applies Tab completion to buf: a single candidate is inserted,
+// several are narrowed to their common prefix or listed.
+func (l *LineReader) complete(buf []rune, redraw func()) []rune {
+	line := string(buf)
+	start, candidates := l.completer.Complete(line)
+	if len(candidates) == 0 {
+		return buf
+	}
+
+	word := line[start:]
+	if len(candidates) == 1 {
+		value := candidates[0].Value
+		if !strings.HasSuffix(value, "/") {
+			value += " "
+		}
+		buf = []rune(line[:start] + value)
+		redraw()
+		return buf
+	}
+
+	common := candidates[0].Value
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate.Value, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	if len(common) > len(word) {
+		buf = []rune(line[:start] + common)
+		redraw()
+		return buf
+	}
+
+	fmt.Fprint(l.out, "\n")
+	for _, candidate := range candidates {
+		if candidate.Description != "" {
+			fmt.Fprintf(l.out, "  %-24s %s\n", candidate.Value, candidate.Description)
+		} else {
+			fmt.Fprintf(l.out, "  %s\n", candidate.Value)
+		}
+	}
+	redraw()
+	return buf
+}
+
+func (l *LineReader) skipEscape() {
+	next, _, err := l.reader.ReadRune()
+	if err != nil || (next != '[' && next != 'O') {
+		return
+	}
+	for {
+		r, _, err := l.reader.ReadRune()
+		if err != nil || (r >= '@' && r <= '~') {
+			return
+		}
+	}
+}
+
+func (l *LineReader) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = l.in
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
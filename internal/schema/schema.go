@@ -0,0 +1,255 @@
+// Package: internal/schema/schema.go
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a decoded JSON Schema document. Only the subset of keywords
+// needed for tool parameters and structured output is supported.
+type Schema map[string]interface{}
+
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func Load(path string) (Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Normalize converts a schema built from Go literals (e.g. []string enums,
+// map[string]string items) into the generic form produced by encoding/json.
+func Normalize(v interface{}) (Schema, error) {
+	if s, ok := v.(Schema); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schema: %w", err)
+	}
+
+	return s, nil
+}
+
+// Validate checks value (as decoded by encoding/json) against the schema and
+// returns every violation found.
+func Validate(s Schema, value interface{}) []ValidationError {
+	var errs []ValidationError
+	validate(map[string]interface{}(s), value, "$", &errs)
+	return errs
+}
+
+func validate(s map[string]interface{}, value interface{}, path string, errs *[]ValidationError) {
+	addErr := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		addErr("expected %s, got %s", describeType(t), typeName(value))
+		return
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if equal(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addErr("value %v is not one of %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, exists := v[name]; !exists {
+					addErr("missing required property %q", name)
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if propSchema, ok := props[k].(map[string]interface{}); ok {
+				validate(propSchema, v[k], path+"."+k, errs)
+				continue
+			}
+
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					addErr("unexpected property %q", k)
+				}
+			case map[string]interface{}:
+				validate(additional, v[k], path+"."+k, errs)
+			}
+		}
+
+	case []interface{}:
+		if min, ok := number(s["minItems"]); ok && float64(len(v)) < min {
+			addErr("expected at least %v items, got %d", min, len(v))
+		}
+		if max, ok := number(s["maxItems"]); ok && float64(len(v)) > max {
+			addErr("expected at most %v items, got %d", max, len(v))
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := number(s["minLength"]); ok && length < min {
+			addErr("expected at least %v characters", min)
+		}
+		if max, ok := number(s["maxLength"]); ok && length > max {
+			addErr("expected at most %v characters", max)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				addErr("invalid pattern %q in schema", pattern)
+			} else if !re.MatchString(v) {
+				addErr("value %q does not match pattern %q", v, pattern)
+			}
+		}
+
+	case float64:
+		if min, ok := number(s["minimum"]); ok && v < min {
+			addErr("value %v is less than minimum %v", v, min)
+		}
+		if max, ok := number(s["maximum"]); ok && v > max {
+			addErr("value %v is greater than maximum %v", v, max)
+		}
+	}
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesSingleType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func number(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func equal(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
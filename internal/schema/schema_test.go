@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string // Each problem as Error() prints it
+	}{
+		{
+			name:   "valid object",
+			schema: `{"type": "object", "properties": {"name": {"type": "string"}, "count": {"type": "integer", "minimum": 0}}, "required": ["name"]}`,
+			value:  `{"name": "a", "count": 3}`,
+		},
+		{
+			name:   "wrong top-level type",
+			schema: `{"type": "object"}`,
+			value:  `[1, 2]`,
+			want:   []string{"$: expected object, got array"},
+		},
+		{
+			name:   "missing required properties",
+			schema: `{"type": "object", "required": ["name", "count"]}`,
+			value:  `{}`,
+			want:   []string{`$: missing required property "name"`, `$: missing required property "count"`},
+		},
+		{
+			name:   "wrong property types, in key order",
+			schema: `{"type": "object", "properties": {"b": {"type": "string"}, "a": {"type": "boolean"}}}`,
+			value:  `{"b": 1, "a": "yes"}`,
+			want:   []string{"$.a: expected boolean, got string", "$.b: expected string, got integer"},
+		},
+		{
+			name:   "integer given a fraction",
+			schema: `{"type": "integer"}`,
+			value:  `1.5`,
+			want:   []string{"$: expected integer, got number"},
+		},
+		{
+			name:   "union type",
+			schema: `{"type": ["string", "null"]}`,
+			value:  `3`,
+			want:   []string{"$: expected string or null, got integer"},
+		},
+		{
+			name:   "null for a union with null",
+			schema: `{"type": ["string", "null"]}`,
+			value:  `null`,
+		},
+		{
+			name:   "value outside enum",
+			schema: `{"enum": ["low", "high"]}`,
+			value:  `"medium"`,
+			want:   []string{"$: value medium is not one of [low high]"},
+		},
+		{
+			name:   "unexpected property",
+			schema: `{"type": "object", "properties": {"a": {}}, "additionalProperties": false}`,
+			value:  `{"a": 1, "b": 2}`,
+			want:   []string{`$: unexpected property "b"`},
+		},
+		{
+			name:   "additional properties against a schema",
+			schema: `{"type": "object", "additionalProperties": {"type": "number"}}`,
+			value:  `{"a": 1, "b": "x"}`,
+			want:   []string{"$.b: expected number, got string"},
+		},
+		{
+			name:   "too few and too many items",
+			schema: `{"type": "object", "properties": {"few": {"type": "array", "minItems": 2}, "many": {"type": "array", "maxItems": 1}}}`,
+			value:  `{"few": [1], "many": [1, 2]}`,
+			want:   []string{"$.few: expected at least 2 items, got 1", "$.many: expected at most 1 items, got 2"},
+		},
+		{
+			name:   "bad items, by index",
+			schema: `{"type": "array", "items": {"type": "object", "required": ["id"]}}`,
+			value:  `[{"id": 1}, {}, "x"]`,
+			want:   []string{`$[1]: missing required property "id"`, "$[2]: expected object, got string"},
+		},
+		{
+			name:   "string length counted in characters",
+			schema: `{"type": "string", "minLength": 2, "maxLength": 3}`,
+			value:  `"日本"`,
+		},
+		{
+			name:   "string too short and too long",
+			schema: `{"type": "object", "properties": {"short": {"minLength": 3}, "long": {"maxLength": 1}}}`,
+			value:  `{"short": "ab", "long": "ab"}`,
+			want:   []string{"$.long: expected at most 1 characters", "$.short: expected at least 3 characters"},
+		},
+		{
+			name:   "pattern mismatch",
+			schema: `{"type": "string", "pattern": "^v[0-9]+$"}`,
+			value:  `"version1"`,
+			want:   []string{`$: value "version1" does not match pattern "^v[0-9]+$"`},
+		},
+		{
+			name:   "invalid pattern in the schema",
+			schema: `{"type": "string", "pattern": "("}`,
+			value:  `"x"`,
+			want:   []string{`$: invalid pattern "(" in schema`},
+		},
+		{
+			name:   "below minimum and above maximum",
+			schema: `{"type": "array", "items": {"type": "number", "minimum": 1, "maximum": 10}}`,
+			value:  `[0, 11]`,
+			want:   []string{"$[0]: value 0 is less than minimum 1", "$[1]: value 11 is greater than maximum 10"},
+		},
+		{
+			name:   "unknown keywords and types are ignored",
+			schema: `{"type": "widget", "format": "email", "oneOf": [{"type": "string"}]}`,
+			value:  `42`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Schema
+			if err := json.Unmarshal([]byte(tt.schema), &s); err != nil {
+				t.Fatal(err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, problem := range Validate(s, value) {
+				got = append(got, problem.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "schema", path: write("ok.json", `{"type": "object"}`)},
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), wantErr: "failed to read schema"},
+		{name: "not JSON", path: write("bad.json", `{"type": `), wantErr: "failed to parse schema"},
+		{name: "not an object", path: write("array.json", `["type"]`), wantErr: "failed to parse schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeGoLiterals(t *testing.T) {
+	s, err := Normalize(map[string]interface{}{
+		"type":     "object",
+		"required": []string{"mode"},
+		"properties": map[string]interface{}{
+			"mode": map[string]interface{}{"type": "string", "enum": []string{"fast", "slow"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if problems := Validate(s, map[string]interface{}{"mode": "fast"}); len(problems) != 0 {
+		t.Errorf("valid value refused: %v", problems)
+	}
+	if problems := Validate(s, map[string]interface{}{"mode": "other"}); len(problems) != 1 {
+		t.Errorf("value outside the []string enum: problems = %v, want 1", problems)
+	}
+	if problems := Validate(s, map[string]interface{}{}); len(problems) != 1 {
+		t.Errorf("[]string required not enforced: problems = %v", problems)
+	}
+
+	if _, err := Normalize(map[string]interface{}{"bad": make(chan int)}); err == nil {
+		t.Error("Normalize() of a value JSON cannot hold succeeded")
+	}
+}
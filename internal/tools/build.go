@@ -0,0 +1,273 @@
+// Package: internal/tools/build.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDiagnostics bounds how many diagnostics a build reports.
+const maxDiagnostics = 100
+
+// BuildTool runs the project's build and reports what the compiler
+// complained about as diagnostics, each a file, line, column and message,
+// rather than the build's whole output.
+type BuildTool struct {
+	runner *commandRunner
+}
+
+func (t *BuildTool) Name() string { return "build" }
+
+func (t *BuildTool) Description() string {
+	return "Build the project (with its detected build command unless one is given) and get its errors and warnings as file:line:column diagnostics. Use it after editing code to check it compiles"
+}
+
+func (t *BuildTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Build command (detected from the project if omitted, e.g. go build ./... or cargo build)",
+			},
+		},
+	}
+}
+
+// BuildReport is the result of a build call.
+type BuildReport struct {
+	Command     string       `json:"command"`
+	Success     bool         `json:"success"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Truncated   bool         `json:"truncated,omitempty"` // More than maxDiagnostics
+}
+
+// Diagnostic is an error, warning or note of a compiler.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"` // error, warning or note
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := fmt.Sprintf("%s:%d", d.File, d.Line)
+	if d.Column > 0 {
+		location += fmt.Sprintf(":%d", d.Column)
+	}
+	severity := d.Severity
+	if d.Code != "" {
+		severity += " " + d.Code
+	}
+	return fmt.Sprintf("%s: %s: %s", location, severity, d.Message)
+}
+
+// buildCommand is the command a call gives as key, or else the project's
+// detected build command; "" if there is neither.
+func buildCommand(args map[string]interface{}, key string) string {
+	command, _ := args[key].(string)
+	if command == "" {
+		wd, _ := os.Getwd()
+		command = DetectBuildCommand(wd)
+	}
+	return command
+}
+
+func (t *BuildTool) Commands(args map[string]interface{}) []string {
+	if command := buildCommand(args, "command"); command != "" {
+		return []string{command}
+	}
+	return nil
+}
+
+func (t *BuildTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+func (t *BuildTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	result, err := t.ExecuteContent(ctx, args, stream)
+	return result.String(), err
+}
+
+// ExecuteContent runs the build; compile errors are part of the report,
+// not an error, which is for a build that failed without any.
+func (t *BuildTool) ExecuteContent(ctx context.Context, args map[string]interface{}, stream io.Writer) (*Result, error) {
+	command := buildCommand(args, "command")
+	if command == "" {
+		return nil, fmt.Errorf("no build command detected; pass command")
+	}
+
+	output, runErr := t.runner.run(ctx, command, "", stream)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &BuildReport{Command: command, Success: runErr == nil, Diagnostics: []Diagnostic{}}
+	diagnostics := parseDiagnostics(output)
+	if len(diagnostics) > maxDiagnostics {
+		diagnostics, report.Truncated = diagnostics[:maxDiagnostics], true
+	}
+	report.Diagnostics = append(report.Diagnostics, diagnostics...)
+	for _, d := range diagnostics {
+		switch d.Severity {
+		case "error":
+			report.Errors++
+		case "warning":
+			report.Warnings++
+		}
+	}
+	if runErr != nil && report.Errors == 0 {
+		return nil, fmt.Errorf("`%s` failed: %w\n%s", command, runErr, trimFailure(output))
+	}
+
+	result, err := JSONResult(report)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = []Content{TextContent(report.String())}
+	return result, nil
+}
+
+// String summarizes the report for the model: whether the build worked,
+// then its diagnostics.
+func (r *BuildReport) String() string {
+	var b strings.Builder
+	if r.Success {
+		fmt.Fprintf(&b, "`%s` succeeded", r.Command)
+	} else {
+		fmt.Fprintf(&b, "`%s` failed", r.Command)
+	}
+	fmt.Fprintf(&b, ": %d errors, %d warnings", r.Errors, r.Warnings)
+	for _, d := range r.Diagnostics {
+		b.WriteString("\n" + d.String())
+	}
+	if r.Truncated {
+		fmt.Fprintf(&b, "\n(only the first %d diagnostics are shown)", maxDiagnostics)
+	}
+	return b.String()
+}
+
+// Compiler output formats, each with the file, line, column, severity,
+// code and message it gives where it has them
+var (
+	// error[E0308]: mismatched types, then --> src/main.rs:1:21
+	rustHeader   = regexp.MustCompile(`^(error|warning)(?:\[(\w+)\])?: (.+)$`)
+	rustLocation = regexp.MustCompile(`^\s*--> (.+?):(\d+):(\d+)$`)
+	// src/a.ts(12,5): error TS2322: message, or src/a.ts:12:5 - error ...
+	tscDiagnostic = regexp.MustCompile(`^(.+?)(?:\((\d+),(\d+)\):|:(\d+):(\d+) -) (error|warning) (TS\d+): (.+)$`)
+	// [ERROR] /src/A.java:[12,5] message
+	mavenDiagnostic = regexp.MustCompile(`^\[(ERROR|WARNING)\] (.+?):\[(\d+),(\d+)\] (.+)$`)
+	// File "x.py", line 3, then eventually SyntaxError: message
+	pythonLocation = regexp.MustCompile(`^\s*File "(.+?)", line (\d+)`)
+	pythonError    = regexp.MustCompile(`^(\w+(?:Error|Exception)): (.+)$`)
+	// x.go:12:5: message (vet: x.go:... from go vet), a.c:12:5: error:
+	// message, A.java:12: error: message
+	lineDiagnostic = regexp.MustCompile(`^(?:vet: )?((?:[A-Za-z]:)?[^\s:][^:]*\.\w+):(\d+):(?:(\d+):)?\s*(?:(fatal error|error|warning|note):\s*)?(.+)$`)
+)
+
+// parseDiagnostics finds the diagnostics in a build's output, in the
+// formats of Go, gcc and clang, rustc, tsc, javac and Maven, and Python.
+func parseDiagnostics(output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	seen := make(map[string]bool)
+	add := func(d Diagnostic) bool {
+		d.File = relativePath(d.File)
+		d.Message = strings.TrimSpace(d.Message)
+		key := d.String()
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		diagnostics = append(diagnostics, d)
+		return true
+	}
+
+	var rust, python *Diagnostic // Waiting for their location or message
+	last := -1                   // The diagnostic continuation lines add to
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if m := rustHeader.FindStringSubmatch(line); m != nil {
+			rust = &Diagnostic{Severity: m[1], Code: m[2], Message: m[3]}
+			last = -1
+			continue
+		}
+		if m := rustLocation.FindStringSubmatch(line); m != nil && rust != nil {
+			rust.File, rust.Line, rust.Column = m[1], atoi(m[2]), atoi(m[3])
+			add(*rust)
+			rust, last = nil, -1
+			continue
+		}
+		if m := pythonLocation.FindStringSubmatch(line); m != nil {
+			python = &Diagnostic{File: m[1], Line: atoi(m[2]), Severity: "error"}
+			last = -1
+			continue
+		}
+		if m := pythonError.FindStringSubmatch(line); m != nil && python != nil {
+			python.Code, python.Message = m[1], m[2]
+			add(*python)
+			python, last = nil, -1
+			continue
+		}
+
+		var d *Diagnostic
+		if m := tscDiagnostic.FindStringSubmatch(line); m != nil {
+			d = &Diagnostic{File: m[1], Line: atoi(m[2] + m[4]), Column: atoi(m[3] + m[5]), Severity: m[6], Code: m[7], Message: m[8]}
+		} else if m := mavenDiagnostic.FindStringSubmatch(line); m != nil {
+			d = &Diagnostic{File: m[2], Line: atoi(m[3]), Column: atoi(m[4]), Severity: strings.ToLower(m[1]), Message: m[5]}
+		} else if m := lineDiagnostic.FindStringSubmatch(line); m != nil {
+			severity := m[4]
+			if severity == "" || severity == "fatal error" {
+				severity = "error"
+			}
+			d = &Diagnostic{File: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Severity: severity, Message: m[5]}
+		}
+		if d != nil {
+			last = -1
+			if add(*d) {
+				last = len(diagnostics) - 1
+			}
+			continue
+		}
+
+		// Go follows some errors with tab-indented lines, such as the have
+		// and want of a mismatched call
+		if last >= 0 && strings.HasPrefix(line, "\t") {
+			diagnostics[last].Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		last = -1
+	}
+	return diagnostics
+}
+
+// relativePath makes path relative to the working directory if it is in
+// it, as the other tools' paths are.
+func relativePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
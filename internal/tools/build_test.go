@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name:   "go build",
+			output: "# example.com/app\n./main.go:12:5: undefined: foo\ninternal/a.go:3:2: \"os\" imported and not used\n",
+			want: []Diagnostic{
+				{File: "./main.go", Line: 12, Column: 5, Severity: "error", Message: "undefined: foo"},
+				{File: "internal/a.go", Line: 3, Column: 2, Severity: "error", Message: `"os" imported and not used`},
+			},
+		},
+		{
+			name:   "go continuation lines",
+			output: "./main.go:9:6: not enough arguments in call to f\n\thave ()\n\twant (int)\nok\n\tstray\n",
+			want: []Diagnostic{
+				{File: "./main.go", Line: 9, Column: 6, Severity: "error", Message: "not enough arguments in call to f\nhave ()\nwant (int)"},
+			},
+		},
+		{
+			name:   "go vet",
+			output: "# example.com/app\nvet: ./main.go:7:2: unreachable code\n",
+			want: []Diagnostic{
+				{File: "./main.go", Line: 7, Column: 2, Severity: "error", Message: "unreachable code"},
+			},
+		},
+		{
+			name:   "gcc and clang",
+			output: "a.c:12:5: error: expected ';'\nb.h:1:10: fatal error: 'x.h' file not found\na.c:20:3: warning: unused variable 'n' [-Wunused-variable]\na.c:20:3: note: declared here\n",
+			want: []Diagnostic{
+				{File: "a.c", Line: 12, Column: 5, Severity: "error", Message: "expected ';'"},
+				{File: "b.h", Line: 1, Column: 10, Severity: "error", Message: "'x.h' file not found"},
+				{File: "a.c", Line: 20, Column: 3, Severity: "warning", Message: "unused variable 'n' [-Wunused-variable]"},
+				{File: "a.c", Line: 20, Column: 3, Severity: "note", Message: "declared here"},
+			},
+		},
+		{
+			name:   "rustc",
+			output: "error[E0308]: mismatched types\n --> src/main.rs:1:21\n  |\nwarning: unused variable: `x`\n  --> src/lib.rs:4:9\n",
+			want: []Diagnostic{
+				{File: "src/main.rs", Line: 1, Column: 21, Severity: "error", Code: "E0308", Message: "mismatched types"},
+				{File: "src/lib.rs", Line: 4, Column: 9, Severity: "warning", Message: "unused variable: `x`"},
+			},
+		},
+		{
+			name:   "tsc",
+			output: "src/a.ts(12,5): error TS2322: Type 'string' is not assignable to type 'number'.\nsrc/b.ts:3:1 - warning TS6133: 'x' is declared but never used.\n",
+			want: []Diagnostic{
+				{File: "src/a.ts", Line: 12, Column: 5, Severity: "error", Code: "TS2322", Message: "Type 'string' is not assignable to type 'number'."},
+				{File: "src/b.ts", Line: 3, Column: 1, Severity: "warning", Code: "TS6133", Message: "'x' is declared but never used."},
+			},
+		},
+		{
+			name:   "maven",
+			output: "[INFO] Compiling 3 source files\n[ERROR] /src/A.java:[12,5] cannot find symbol\n[WARNING] /src/B.java:[3,1] deprecated API\n",
+			want: []Diagnostic{
+				{File: "/src/A.java", Line: 12, Column: 5, Severity: "error", Message: "cannot find symbol"},
+				{File: "/src/B.java", Line: 3, Column: 1, Severity: "warning", Message: "deprecated API"},
+			},
+		},
+		{
+			name:   "javac",
+			output: "A.java:12: error: ';' expected\n        int x = 1\n                 ^\n1 error\n",
+			want: []Diagnostic{
+				{File: "A.java", Line: 12, Severity: "error", Message: "';' expected"},
+			},
+		},
+		{
+			name:   "python traceback",
+			output: "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\n    import missing\nModuleNotFoundError: No module named 'missing'\n",
+			want: []Diagnostic{
+				{File: "app.py", Line: 3, Severity: "error", Code: "ModuleNotFoundError", Message: "No module named 'missing'"},
+			},
+		},
+		{
+			name:   "python syntax error",
+			output: "  File \"x.py\", line 7\n    def f(\n         ^\nSyntaxError: '(' was never closed\n",
+			want: []Diagnostic{
+				{File: "x.py", Line: 7, Severity: "error", Code: "SyntaxError", Message: "'(' was never closed"},
+			},
+		},
+		{
+			name:   "windows line endings",
+			output: "main.go:1:1: expected 'package'\r\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 1, Column: 1, Severity: "error", Message: "expected 'package'"},
+			},
+		},
+		{
+			name:   "duplicates are dropped",
+			output: "a.go:1:1: bad\na.go:1:1: bad\na.go:2:1: bad\n",
+			want: []Diagnostic{
+				{File: "a.go", Line: 1, Column: 1, Severity: "error", Message: "bad"},
+				{File: "a.go", Line: 2, Column: 1, Severity: "error", Message: "bad"},
+			},
+		},
+		{
+			name:   "absolute paths in the working directory",
+			output: filepath.Join(wd, "pkg", "a.go") + ":4:2: undefined: x\n",
+			want: []Diagnostic{
+				{File: filepath.Join("pkg", "a.go"), Line: 4, Column: 2, Severity: "error", Message: "undefined: x"},
+			},
+		},
+		{
+			name:   "no diagnostics",
+			output: "ok  \texample.com/app\t0.01s\nBUILD SUCCESS\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDiagnostics(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDiagnostics() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package: internal/tools/content.go
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Content is a block of a tool's result, in the shape MCP serializes it:
+// text, an image or audio clip (Data in base64, with its MimeType), a link
+// to a resource, or a resource embedded whole.
+type Content struct {
+	Type     string            `json:"type"` // text, image, audio, resource_link or resource
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	MimeType string            `json:"mimeType,omitempty"`
+	URI      string            `json:"uri,omitempty"` // Of a resource_link
+	Name     string            `json:"name,omitempty"`
+	Resource *EmbeddedResource `json:"resource,omitempty"`
+}
+
+// EmbeddedResource is the content of a resource, as text or a base64 blob.
+type EmbeddedResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// Result is the result of a tool whose output is more than text.
+// Structured, when set, is the same result as a JSON object, for clients
+// that read it rather than the text.
+type Result struct {
+	Content    []Content
+	Structured interface{}
+}
+
+// ContentTool is implemented by tools that return typed content, such as
+// a screenshot or a coverage report; the model, which reads text, gets the
+// result's String.
+type ContentTool interface {
+	ExecuteContent(ctx context.Context, args map[string]interface{}, stream io.Writer) (*Result, error)
+}
+
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+func ImageContent(data []byte, mimeType string) Content {
+	return Content{Type: "image", Data: base64.StdEncoding.EncodeToString(data), MimeType: mimeType}
+}
+
+func ResourceLink(uri, name, mimeType string) Content {
+	return Content{Type: "resource_link", URI: uri, Name: name, MimeType: mimeType}
+}
+
+// JSONResult returns v as a structured result, with its JSON as the text.
+func JSONResult(v interface{}) (*Result, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return &Result{Content: []Content{TextContent(string(data))}, Structured: v}, nil
+}
+
+// textResult is the result of a tool that returns text.
+func textResult(text string) *Result {
+	return &Result{Content: []Content{TextContent(text)}}
+}
+
+// String renders the result as text: text and embedded text as they are,
+// anything else as a note of what it is.
+func (r *Result) String() string {
+	if r == nil {
+		return ""
+	}
+	if len(r.Content) == 0 && r.Structured != nil {
+		data, _ := json.MarshalIndent(r.Structured, "", "  ")
+		return string(data)
+	}
+
+	parts := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		switch {
+		case content.Type == "text":
+			parts = append(parts, content.Text)
+		case content.Type == "image" || content.Type == "audio":
+			parts = append(parts, fmt.Sprintf("[%s %s, %d bytes]", content.MimeType, content.Type, base64.StdEncoding.DecodedLen(len(content.Data))))
+		case content.Type == "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource %s: %s]", content.Name, content.URI))
+		case content.Type == "resource" && content.Resource != nil:
+			if content.Resource.Text != "" {
+				parts = append(parts, content.Resource.Text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s, %s]", content.Resource.URI, content.Resource.MimeType))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content]", content.Type))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
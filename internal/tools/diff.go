@@ -0,0 +1,179 @@
+// Package: internal/tools/diff.go
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is how many unchanged lines surround a change in a hunk.
+	diffContext = 3
+	// maxDiffCells bounds the table the changed lines are compared with;
+	// past it, they are shown as all removed and then all added.
+	maxDiffCells = 4_000_000
+)
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// unifiedDiff returns the changes from before to after as a unified diff
+// of path, empty when there are none. created marks a new file.
+func unifiedDiff(path string, before, after string, created bool) string {
+	lines := diffLines(splitLines(before), splitLines(after))
+
+	var changes []int
+	for i, line := range lines {
+		if line.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if created {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&b, "--- %s\n", path)
+	}
+	fmt.Fprintf(&b, "+++ %s\n", path)
+
+	// Hunks take the changes whose context touches
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-diffContext, 0)
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*diffContext+1 {
+			j++
+		}
+		end := min(changes[j]+diffContext+1, len(lines))
+		writeHunk(&b, lines, start, end)
+		i = j + 1
+	}
+	return b.String()
+}
+
+func writeHunk(b *strings.Builder, lines []diffLine, start, end int) {
+	// Line numbers are those of the first line of the hunk in each file
+	oldLine, newLine := 1, 1
+	for _, line := range lines[:start] {
+		if line.op != '+' {
+			oldLine++
+		}
+		if line.op != '-' {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, line := range lines[start:end] {
+		if line.op != '+' {
+			oldCount++
+		}
+		if line.op != '-' {
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, line := range lines[start:end] {
+		b.WriteByte(line.op)
+		b.WriteString(strings.TrimSuffix(line.text, "\n"))
+		b.WriteByte('\n')
+		if !strings.HasSuffix(line.text, "\n") {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines lines up a and b: the lines they share, found as the longest
+// common subsequence of what lies between their common start and end, and
+// those only one has.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+func diffMiddle(a, b []string) []diffLine {
+	var lines []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, text := range a {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range b {
+			lines = append(lines, diffLine{'+', text})
+		}
+		return lines
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
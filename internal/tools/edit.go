@@ -0,0 +1,341 @@
+// Package: internal/tools/edit.go
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EditTool changes part of a file, so the model does not have to write
+// the whole file out again: a string it quotes, or a range of lines.
+type EditTool struct{}
+
+func (t *EditTool) Name() string { return "edit_file" }
+
+func (t *EditTool) Description() string {
+	return "Edit part of a file: replace old_string, which must match exactly once unless replace_all is set, with new_string; or replace lines start_line to end_line with new_string. Prefer it to rewriting a whole file"
+}
+
+func (t *EditTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": editProperties(),
+		"required":   []string{"path", "new_string"},
+	}
+}
+
+// editProperties are the parameters of an edit, of edit_file and of each
+// of multi_edit's.
+func editProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "File to edit",
+		},
+		"old_string": map[string]interface{}{
+			"type":        "string",
+			"description": "Exact text to replace, with enough surrounding lines to match once. Empty to create a file that does not exist",
+		},
+		"new_string": map[string]interface{}{
+			"type":        "string",
+			"description": "Text to put in its place",
+		},
+		"replace_all": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Replace every occurrence of old_string instead of requiring one",
+		},
+		"start_line": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     1,
+			"description": "Without old_string: first line to replace (1-based)",
+		},
+		"end_line": map[string]interface{}{
+			"type":        "integer",
+			"minimum":     0,
+			"description": "Last line to replace, inclusive (start_line by default); start_line-1 inserts before start_line",
+		},
+	}
+}
+
+// fileEdit is an edit of edit_file or multi_edit.
+type fileEdit struct {
+	Path       string  `json:"path"`
+	OldString  *string `json:"old_string"`
+	NewString  string  `json:"new_string"`
+	ReplaceAll bool    `json:"replace_all"`
+	StartLine  *int    `json:"start_line"`
+	EndLine    *int    `json:"end_line"`
+}
+
+func parseFileEdit(args map[string]interface{}) (fileEdit, error) {
+	var edit fileEdit
+	data, err := json.Marshal(args)
+	if err != nil {
+		return edit, err
+	}
+	if err := json.Unmarshal(data, &edit); err != nil {
+		return edit, fmt.Errorf("invalid edit: %w", err)
+	}
+	if edit.Path == "" {
+		return edit, fmt.Errorf("path is required")
+	}
+	return edit, nil
+}
+
+// apply returns content, which exists is false for a file not there yet,
+// with the edit made, and what it did.
+func (e fileEdit) apply(content string, exists bool) (string, string, error) {
+	switch {
+	case e.OldString != nil && *e.OldString == "":
+		if exists {
+			return "", "", fmt.Errorf("%s already exists; old_string must not be empty", e.Path)
+		}
+		return e.NewString, fmt.Sprintf("Created %s (%d lines)", e.Path, lineCount(e.NewString)), nil
+	case !exists:
+		return "", "", fmt.Errorf("%s does not exist; create it with an empty old_string", e.Path)
+	case e.OldString != nil:
+		return replaceString(e.Path, content, *e.OldString, e.NewString, e.ReplaceAll)
+	case e.StartLine != nil:
+		end := *e.StartLine
+		if e.EndLine != nil {
+			end = *e.EndLine
+		}
+		return replaceLines(e.Path, content, *e.StartLine, end, e.NewString)
+	default:
+		return "", "", fmt.Errorf("%s: either old_string or start_line is required", e.Path)
+	}
+}
+
+func (t *EditTool) MutatedPaths(args map[string]interface{}) []string {
+	path, _ := args["path"].(string)
+	if path == "" {
+		return nil
+	}
+	return []string{path}
+}
+
+func (t *EditTool) Execute(args map[string]interface{}) (string, error) {
+	edit, err := parseFileEdit(args)
+	if err != nil {
+		return "", err
+	}
+
+	exists := true
+	mode := os.FileMode(0644)
+	info, err := os.Stat(edit.Path)
+	switch {
+	case os.IsNotExist(err):
+		exists = false
+	case err != nil:
+		return "", err
+	case info.IsDir():
+		return "", fmt.Errorf("%s is a directory", edit.Path)
+	default:
+		mode = info.Mode().Perm()
+	}
+	var content []byte
+	if exists {
+		if content, err = os.ReadFile(edit.Path); err != nil {
+			return "", err
+		}
+	}
+
+	updated, summary, err := edit.apply(string(content), exists)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(edit.Path), 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(edit.Path, []byte(updated), mode); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", edit.Path, err)
+	}
+	return summary, nil
+}
+
+// replaceString replaces oldString in content, which must occur once
+// unless replaceAll. A file with CRLF line endings matches a string quoted
+// with LF ones, and keeps its endings.
+func replaceString(path, content, oldString, newString string, replaceAll bool) (string, string, error) {
+	if oldString == newString {
+		return "", "", fmt.Errorf("%s: old_string and new_string are the same; nothing to change", path)
+	}
+
+	count := strings.Count(content, oldString)
+	if count == 0 && strings.Contains(content, "\r\n") && !strings.Contains(oldString, "\r\n") {
+		oldString = strings.ReplaceAll(oldString, "\n", "\r\n")
+		newString = strings.ReplaceAll(newString, "\n", "\r\n")
+		count = strings.Count(content, oldString)
+	}
+	switch {
+	case count == 0:
+		return "", "", fmt.Errorf("%s: old_string not found; read the file and quote it exactly, including whitespace", path)
+	case count > 1 && !replaceAll:
+		return "", "", fmt.Errorf("%s: old_string matches %d times (at lines %s); include more context to make it unique, or set replace_all", path, count, matchLines(content, oldString))
+	}
+
+	first := strings.Count(content[:strings.Index(content, oldString)], "\n") + 1
+	updated := strings.ReplaceAll(content, oldString, newString)
+	if count == 1 {
+		return updated, fmt.Sprintf("Replaced 1 occurrence in %s at line %d", path, first), nil
+	}
+	return updated, fmt.Sprintf("Replaced %d occurrences in %s, the first at line %d", count, path, first), nil
+}
+
+// replaceLines replaces lines start to end of content, 1-based and
+// inclusive, with text; an end of start-1 inserts text before start.
+func replaceLines(path, content string, start, end int, text string) (string, string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	switch {
+	case start < 1 || start > len(lines)+1:
+		return "", "", fmt.Errorf("%s: start_line %d is outside the file's %d lines", path, start, len(lines))
+	case end < start-1:
+		return "", "", fmt.Errorf("%s: end_line %d is before start_line %d", path, end, start)
+	case end > len(lines):
+		return "", "", fmt.Errorf("%s: end_line %d is past the file's %d lines", path, end, len(lines))
+	}
+
+	// The new lines end as the file's lines do, unless they end the file
+	// and the last line had no newline
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		last := end == len(lines) && !strings.HasSuffix(content, "\n")
+		if !last {
+			if strings.Contains(content, "\r\n") {
+				text += "\r\n"
+			} else {
+				text += "\n"
+			}
+		}
+	}
+
+	var updated strings.Builder
+	for _, line := range lines[:start-1] {
+		updated.WriteString(line)
+	}
+	updated.WriteString(text)
+	for _, line := range lines[end:] {
+		updated.WriteString(line)
+	}
+
+	if end < start {
+		return updated.String(), fmt.Sprintf("Inserted %d lines before line %d of %s", lineCount(text), start, path), nil
+	}
+	return updated.String(), fmt.Sprintf("Replaced lines %d-%d of %s with %d lines", start, end, path, lineCount(text)), nil
+}
+
+// matchLines lists the lines occurrences of s start at.
+func matchLines(content, s string) string {
+	var lines []string
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], s)
+		if i < 0 {
+			break
+		}
+		lines = append(lines, fmt.Sprint(strings.Count(content[:offset+i], "\n")+1))
+		offset += i + len(s)
+	}
+	return strings.Join(lines, ", ")
+}
+
+// lineCount is the number of lines in text, a last one without a newline
+// included.
+func lineCount(text string) int {
+	if text == "" {
+		return 0
+	}
+	n := strings.Count(text, "\n")
+	if !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+// MultiEditTool makes a list of edits, to one file or several, as one
+// transaction: every edit is checked against the files as the ones before
+// it left them, and nothing is written unless all of them can be made.
+type MultiEditTool struct{}
+
+func (t *MultiEditTool) Name() string { return "multi_edit" }
+
+func (t *MultiEditTool) Description() string {
+	return "Make several edits, to one file or across files, all or nothing: each edit is like edit_file's and applies to the result of those before it; if any fails, no file is changed. Returns a diff of the changes"
+}
+
+func (t *MultiEditTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Edits to make, in order",
+				"minItems":    1,
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": editProperties(),
+					"required":   []string{"path", "new_string"},
+				},
+			},
+		},
+		"required": []string{"edits"},
+	}
+}
+
+func parseFileEdits(args map[string]interface{}) ([]fileEdit, error) {
+	raw, _ := args["edits"].([]interface{})
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("edits must not be empty")
+	}
+	edits := make([]fileEdit, len(raw))
+	for i, item := range raw {
+		fields, _ := item.(map[string]interface{})
+		edit, err := parseFileEdit(fields)
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: %w", i+1, err)
+		}
+		edits[i] = edit
+	}
+	return edits, nil
+}
+
+func (t *MultiEditTool) MutatedPaths(args map[string]interface{}) []string {
+	edits, err := parseFileEdits(args)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, edit := range edits {
+		if !seen[edit.Path] {
+			paths = append(paths, edit.Path)
+			seen[edit.Path] = true
+		}
+	}
+	return paths
+}
+
+func (t *MultiEditTool) Execute(args map[string]interface{}) (string, error) {
+	edits, err := parseFileEdits(args)
+	if err != nil {
+		return "", err
+	}
+
+	tx := NewTransaction()
+	for i, edit := range edits {
+		if _, err := tx.StageEdit(edit); err != nil {
+			return "", fmt.Errorf("no files changed: edit %d: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Apply(); err != nil {
+		return "", fmt.Errorf("no files changed: %w", err)
+	}
+	return fmt.Sprintf("Made %d edits to %s\n\n%s", len(edits), strings.Join(tx.Paths(), ", "), tx.Diff()), nil
+}
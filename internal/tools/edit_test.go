@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileEditApply(t *testing.T) {
+	str := func(s string) *string { return &s }
+	line := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		edit    fileEdit
+		content string
+		missing bool // The file does not exist yet
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "unique string",
+			edit:    fileEdit{OldString: str("b"), NewString: "B"},
+			content: "a\nb\nc\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "every occurrence with replace_all",
+			edit:    fileEdit{OldString: str("x"), NewString: "y", ReplaceAll: true},
+			content: "x\nx\n",
+			want:    "y\ny\n",
+		},
+		{
+			name:    "LF string in a CRLF file",
+			edit:    fileEdit{OldString: str("a\nb"), NewString: "a\nB"},
+			content: "a\r\nb\r\n",
+			want:    "a\r\nB\r\n",
+		},
+		{
+			name:    "line range",
+			edit:    fileEdit{StartLine: line(2), EndLine: line(3), NewString: "X"},
+			content: "a\nb\nc\nd\n",
+			want:    "a\nX\nd\n",
+		},
+		{
+			name:    "insert before a line",
+			edit:    fileEdit{StartLine: line(2), EndLine: line(1), NewString: "X"},
+			content: "a\nb\n",
+			want:    "a\nX\nb\n",
+		},
+		{
+			name:    "create",
+			edit:    fileEdit{OldString: str(""), NewString: "package main\n"},
+			missing: true,
+			want:    "package main\n",
+		},
+		{
+			name:    "create over an existing file",
+			edit:    fileEdit{OldString: str(""), NewString: "x"},
+			content: "a\n",
+			wantErr: "already exists",
+		},
+		{
+			name:    "edit a missing file",
+			edit:    fileEdit{OldString: str("a"), NewString: "b"},
+			missing: true,
+			wantErr: "does not exist",
+		},
+		{
+			name:    "string not found",
+			edit:    fileEdit{OldString: str("z"), NewString: "y"},
+			content: "a\n",
+			wantErr: "old_string not found",
+		},
+		{
+			name:    "string matching twice",
+			edit:    fileEdit{OldString: str("x"), NewString: "y"},
+			content: "x\na\nx\n",
+			wantErr: "matches 2 times (at lines 1, 3)",
+		},
+		{
+			name:    "same old and new string",
+			edit:    fileEdit{OldString: str("a"), NewString: "a"},
+			content: "a\n",
+			wantErr: "nothing to change",
+		},
+		{
+			name:    "neither string nor lines",
+			edit:    fileEdit{NewString: "x"},
+			content: "a\n",
+			wantErr: "either old_string or start_line is required",
+		},
+		{
+			name:    "start line past the end",
+			edit:    fileEdit{StartLine: line(5), NewString: "x"},
+			content: "a\nb\n",
+			wantErr: "start_line 5 is outside",
+		},
+		{
+			name:    "start line zero",
+			edit:    fileEdit{StartLine: line(0), NewString: "x"},
+			content: "a\n",
+			wantErr: "start_line 0 is outside",
+		},
+		{
+			name:    "end line before start line",
+			edit:    fileEdit{StartLine: line(3), EndLine: line(1), NewString: "x"},
+			content: "a\nb\nc\n",
+			wantErr: "end_line 1 is before start_line 3",
+		},
+		{
+			name:    "end line past the end",
+			edit:    fileEdit{StartLine: line(1), EndLine: line(4), NewString: "x"},
+			content: "a\nb\n",
+			wantErr: "end_line 4 is past",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.edit.Path = "file.txt"
+			got, _, err := tt.edit.apply(tt.content, !tt.missing)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("apply() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditToolRefusesBadTargets(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{name: "no path", args: map[string]interface{}{"old_string": "a", "new_string": "b"}, wantErr: "path is required"},
+		{name: "directory", args: map[string]interface{}{"path": dir, "old_string": "a", "new_string": "b"}, wantErr: "is a directory"},
+		{name: "wrong argument type", args: map[string]interface{}{"path": filepath.Join(dir, "a.go"), "new_string": 42}, wantErr: "invalid edit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&EditTool{}).Execute(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEditToolLeavesFileOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("x\nx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&EditTool{}).Execute(map[string]interface{}{"path": path, "old_string": "x", "new_string": "y"}); err == nil {
+		t.Fatal("ambiguous edit succeeded")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "x\nx\n" {
+		t.Errorf("file = %q after a failed edit, want it unchanged", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Package: internal/tools/executor.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Executor runs the shell commands behind the shell, build and test tools,
+// either locally or on a remote host. Output is written to stream (when not
+// nil) as it arrives and also returned once the command exits.
+type Executor interface {
+	Run(ctx context.Context, command, dir string, stream io.Writer) (string, error)
+	Describe() string
+}
+
+// StreamingTool is implemented by tools that can report output while they
+// run.
+type StreamingTool interface {
+	ExecuteStream(args map[string]interface{}, stream io.Writer) (string, error)
+}
+
+// ContextTool is implemented by tools that stop when their context is
+// cancelled, such as those that run commands.
+type ContextTool interface {
+	ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error)
+}
+
+// LocalExecutor runs commands with sh on this machine.
+type LocalExecutor struct{}
+
+func (LocalExecutor) Run(ctx context.Context, command, dir string, stream io.Writer) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	killGroup(cmd)
+	return runStreaming(cmd, stream)
+}
+
+func (LocalExecutor) Describe() string { return "local" }
+
+// unavailableExecutor reports a configuration error on every run, so a
+// broken remote setup never silently falls back to running locally.
+type unavailableExecutor struct {
+	err error
+}
+
+func (e unavailableExecutor) Run(ctx context.Context, command, dir string, stream io.Writer) (string, error) {
+	return "", e.err
+}
+
+func (e unavailableExecutor) Describe() string { return "unavailable: " + e.err.Error() }
+
+// UnavailableExecutor returns an executor that fails every run with err.
+func UnavailableExecutor(err error) Executor {
+	return unavailableExecutor{err: err}
+}
+
+// commandRunner is shared by the tools that execute commands so the
+// registry can swap the executor for all of them at once.
+type commandRunner struct {
+	mu       sync.RWMutex
+	executor Executor
+}
+
+func (r *commandRunner) run(ctx context.Context, command, dir string, stream io.Writer) (string, error) {
+	return r.current().Run(ctx, command, dir, stream)
+}
+
+func (r *commandRunner) current() Executor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.executor
+}
+
+func (r *commandRunner) setExecutor(executor Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executor = executor
+}
+
+// commandWaitDelay is how long a killed command's output is still read:
+// what it started may hold it open after it is gone.
+const commandWaitDelay = 2 * time.Second
+
+func runStreaming(cmd *exec.Cmd, stream io.Writer) (string, error) {
+	var output bytes.Buffer
+	var w io.Writer = &output
+	if stream != nil {
+		w = io.MultiWriter(&output, stream)
+	}
+
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.WaitDelay = commandWaitDelay
+	err := cmd.Run()
+	return output.String(), err
+}
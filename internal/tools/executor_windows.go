@@ -0,0 +1,11 @@
+// Package: internal/tools/executor_windows.go
+
+//go:build windows
+
+package tools
+
+import "os/exec"
+
+// killGroup leaves cmd as it is: cancelling its context kills the shell,
+// and commandWaitDelay bounds the wait for anything it started.
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,358 @@
+// Package: internal/tools/lint.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LintFormatTool runs a language's formatter and linter over the project,
+// or some of it: gofmt or goimports and golangci-lint (go vet without it)
+// for Go, ruff for Python, prettier for JavaScript and TypeScript. With
+// fix, it formats the files that need it; which files those are is what
+// MutatedPaths reports, so the edit is approved like any other.
+type LintFormatTool struct {
+	runner *commandRunner
+}
+
+func (t *LintFormatTool) Name() string { return "lint_format" }
+
+func (t *LintFormatTool) Description() string {
+	return "Check formatting and lint (Go: gofmt/goimports and golangci-lint or go vet; Python: ruff; JavaScript/TypeScript: prettier), reporting unformatted files and lint diagnostics. With fix, format the unformatted files instead and return the diff"
+}
+
+func (t *LintFormatTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"language": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"go", "python", "javascript"},
+				"description": "Language to check (detected from the project if omitted); javascript covers TypeScript",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files or directories to check (the whole project by default)",
+			},
+			"fix": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Format the files that need it rather than report them",
+			},
+		},
+	}
+}
+
+// linter is the formatter and linter of a language, as shell commands.
+type linter struct {
+	// list prints the files among paths that formatting would change
+	list func(paths []string) string
+	// format prints the file at path formatted
+	format func(path string) string
+	// lint prints diagnostics parseDiagnostics reads; nil for none
+	lint func(paths []string) string
+}
+
+var linters = map[string]linter{
+	"go": {
+		list:   func(paths []string) string { return goFormatter + " -l " + quoteAll(paths) },
+		format: func(path string) string { return goFormatter + " " + shellQuote(path) },
+		lint: func(paths []string) string {
+			packages := quoteAll(goPackages(paths))
+			return "if command -v golangci-lint >/dev/null 2>&1; then golangci-lint run " + packages + "; else go vet " + packages + "; fi"
+		},
+	},
+	"python": {
+		list: func(paths []string) string { return "ruff format --check " + quoteAll(paths) },
+		format: func(path string) string {
+			return "ruff format --stdin-filename " + shellQuote(path) + " - < " + shellQuote(path)
+		},
+		lint: func(paths []string) string { return "ruff check --output-format concise " + quoteAll(paths) },
+	},
+	"javascript": {
+		list:   func(paths []string) string { return "npx prettier --list-different " + quoteAll(paths) },
+		format: func(path string) string { return "npx prettier " + shellQuote(path) },
+	},
+}
+
+// goFormatter is goimports where it is installed, which also fixes the
+// imports gofmt would leave, and gofmt otherwise.
+const goFormatter = "$(command -v goimports || echo gofmt)"
+
+func quoteAll(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// goPackages turns paths into the package patterns go vet and
+// golangci-lint take: a directory's packages and everything below, or a
+// file's package.
+func goPackages(paths []string) []string {
+	packages := make([]string, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			path = filepath.Dir(path)
+		}
+		path = filepath.ToSlash(filepath.Clean(path))
+		if path == "." {
+			packages[i] = "./..."
+		} else if filepath.IsAbs(path) || strings.HasPrefix(path, "../") {
+			packages[i] = path + "/..."
+		} else {
+			packages[i] = "./" + path + "/..."
+		}
+	}
+	return packages
+}
+
+// DetectLintLanguage guesses which of lint_format's languages the project
+// in dir is written in.
+func DetectLintLanguage(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("go.mod"):
+		return "go"
+	case exists("pyproject.toml") || exists("setup.py") || exists("setup.cfg") || exists("ruff.toml") || exists("requirements.txt"):
+		return "python"
+	case exists("package.json"):
+		return "javascript"
+	}
+	return ""
+}
+
+// LintReport is the result of a lint_format check.
+type LintReport struct {
+	Language    string       `json:"language"`
+	Unformatted []string     `json:"unformatted"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Errors      []string     `json:"errors,omitempty"` // Of a linter that failed without diagnostics
+}
+
+func (r *LintReport) String() string {
+	var b strings.Builder
+	if len(r.Unformatted) == 0 {
+		b.WriteString("All files are formatted")
+	} else {
+		fmt.Fprintf(&b, "%d files need formatting (call again with fix to format them):", len(r.Unformatted))
+		for _, path := range r.Unformatted {
+			b.WriteString("\n  " + path)
+		}
+	}
+	if len(r.Diagnostics) == 0 && len(r.Errors) == 0 {
+		b.WriteString("\nNo lint issues")
+	} else if len(r.Diagnostics) > 0 {
+		fmt.Fprintf(&b, "\n%d lint issues:", len(r.Diagnostics))
+		for _, d := range r.Diagnostics {
+			b.WriteString("\n" + d.String())
+		}
+	}
+	for _, errText := range r.Errors {
+		b.WriteString("\nThe linter failed:\n" + indent(errText))
+	}
+	return b.String()
+}
+
+// lintArgs are the language and paths of a call, filled in with their
+// defaults.
+func lintArgs(args map[string]interface{}) (string, linter, []string, error) {
+	language, _ := args["language"].(string)
+	if language == "" {
+		wd, _ := os.Getwd()
+		if language = DetectLintLanguage(wd); language == "" {
+			return "", linter{}, nil, fmt.Errorf("no language detected; set language to go, python or javascript")
+		}
+	}
+	l, ok := linters[language]
+	if !ok {
+		return "", linter{}, nil, fmt.Errorf("unsupported language %q", language)
+	}
+
+	var paths []string
+	if raw, ok := args["paths"].([]interface{}); ok {
+		for _, item := range raw {
+			if path, ok := item.(string); ok && path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	return language, l, paths, nil
+}
+
+// unformatted returns the files formatting would change.
+func (t *LintFormatTool) unformatted(ctx context.Context, l linter, paths []string) ([]string, error) {
+	output, err := t.runner.run(ctx, l.list(paths), "", nil)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	// Formatters list files among their complaints, which are not files
+	var files []string
+	var other []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "Would reformat:"))
+		if line == "" {
+			continue
+		}
+		if info, statErr := os.Stat(line); statErr == nil && !info.IsDir() {
+			files = append(files, line)
+		} else {
+			other = append(other, line)
+		}
+	}
+	// Listing files that need formatting is a failure to ruff and
+	// prettier; without any, it is a failure to run at all
+	if err != nil && len(files) == 0 && len(other) > 0 {
+		return nil, fmt.Errorf("`%s` failed: %w\n%s", l.list(paths), err, trimFailure(strings.Join(other, "\n")))
+	}
+	return files, nil
+}
+
+// Commands reports the commands that list the unformatted files and,
+// without fix, lint; with fix, formatting them is an edit MutatedPaths
+// reports.
+func (t *LintFormatTool) Commands(args map[string]interface{}) []string {
+	_, l, paths, err := lintArgs(args)
+	if err != nil {
+		return nil
+	}
+	commands := []string{l.list(paths)}
+	if fix, _ := args["fix"].(bool); !fix && l.lint != nil {
+		commands = append(commands, l.lint(paths))
+	}
+	return commands
+}
+
+// fixPlanKey is where a fix call's arguments keep its fixPlan once
+// MutatedPaths has made it.
+const fixPlanKey = "_lint_fix_plan"
+
+// fixPlan is the files a fix call formats: those found unformatted when
+// the edit was approved, or why they could not be found.
+type fixPlan struct {
+	files []string
+	err   error
+}
+
+// plan lists the unformatted files of a fix call once, keeping them in its
+// arguments, so the call formats the files that were approved and not
+// whatever a second listing finds.
+func (t *LintFormatTool) plan(ctx context.Context, args map[string]interface{}, l linter, paths []string) *fixPlan {
+	if plan, ok := args[fixPlanKey].(*fixPlan); ok {
+		return plan
+	}
+	files, err := t.unformatted(ctx, l, paths)
+	plan := &fixPlan{files: files, err: err}
+	args[fixPlanKey] = plan
+	return plan
+}
+
+// MutatedPaths reports, with fix, the files formatting would change, or
+// the paths asked for if that cannot be told, in which case the call
+// fails without changing any; without fix, none.
+func (t *LintFormatTool) MutatedPaths(args map[string]interface{}) []string {
+	if fix, _ := args["fix"].(bool); !fix {
+		return nil
+	}
+	_, l, paths, err := lintArgs(args)
+	if err != nil {
+		return nil
+	}
+	plan := t.plan(context.Background(), args, l, paths)
+	if plan.err != nil {
+		return paths
+	}
+	return plan.files
+}
+
+func (t *LintFormatTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+func (t *LintFormatTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	result, err := t.ExecuteContent(ctx, args, stream)
+	return result.String(), err
+}
+
+// ExecuteContent checks, or with fix formats; lint issues are part of the
+// report, not an error, which is for a formatter or linter that could not
+// be run.
+func (t *LintFormatTool) ExecuteContent(ctx context.Context, args map[string]interface{}, stream io.Writer) (*Result, error) {
+	language, l, paths, err := lintArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if fix, _ := args["fix"].(bool); fix {
+		plan := t.plan(ctx, args, l, paths)
+		if plan.err != nil {
+			return nil, plan.err
+		}
+		text, err := t.format(ctx, l, plan.files)
+		return textResult(text), err
+	}
+
+	files, err := t.unformatted(ctx, l, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{Language: language, Unformatted: files, Diagnostics: []Diagnostic{}}
+	if report.Unformatted == nil {
+		report.Unformatted = []string{}
+	}
+	if l.lint != nil {
+		command := l.lint(paths)
+		output, lintErr := t.runner.run(ctx, command, "", nil)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Diagnostics = append(report.Diagnostics, parseDiagnostics(output)...)
+		if lintErr != nil && len(report.Diagnostics) == 0 {
+			report.Errors = append(report.Errors, fmt.Sprintf("`%s`: %v\n%s", command, lintErr, trimFailure(output)))
+		}
+	}
+
+	result, err := JSONResult(report)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = []Content{TextContent(report.String())}
+	return result, nil
+}
+
+// format formats files as one transaction, so a file the formatter fails
+// on leaves every file as it was, and returns the diff.
+func (t *LintFormatTool) format(ctx context.Context, l linter, files []string) (string, error) {
+	if len(files) == 0 {
+		return "All files are formatted; nothing to change", nil
+	}
+
+	tx := NewTransaction()
+	for _, path := range files {
+		formatted, err := t.runner.run(ctx, l.format(path), "", nil)
+		if err != nil {
+			return "", fmt.Errorf("no files changed: formatting %s failed: %w\n%s", path, err, trimFailure(formatted))
+		}
+		if err := tx.Stage(path, []byte(formatted)); err != nil {
+			return "", fmt.Errorf("no files changed: %w", err)
+		}
+	}
+	if err := tx.Apply(); err != nil {
+		return "", fmt.Errorf("no files changed: %w", err)
+	}
+	return fmt.Sprintf("Formatted %s\n\n%s", strings.Join(tx.Paths(), ", "), tx.Diff()), nil
+}
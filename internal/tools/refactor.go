@@ -0,0 +1,198 @@
+// Package: internal/tools/refactor.go
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RefactorTool applies a multi-file change as one transaction, builds the
+// project, and rolls every file back if the build fails.
+type RefactorTool struct {
+	runner *commandRunner
+}
+
+func (t *RefactorTool) Name() string { return "refactor" }
+
+func (t *RefactorTool) Description() string {
+	return "Apply edits across multiple files atomically, run the build, and automatically roll back all edits if compilation fails"
+}
+
+func (t *RefactorTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"changes": map[string]interface{}{
+				"type":        "array",
+				"description": "Edits to apply. Each edit either replaces old_string with new_string as edit_file does (once unless replace_all is set), or sets the full content of the file",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path":        map[string]interface{}{"type": "string"},
+						"old_string":  map[string]interface{}{"type": "string"},
+						"new_string":  map[string]interface{}{"type": "string"},
+						"replace_all": map[string]interface{}{"type": "boolean"},
+						"content":     map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"path"},
+				},
+			},
+			"build_command": map[string]interface{}{
+				"type":        "string",
+				"description": "Command verifying the change compiles (detected from the project if omitted)",
+			},
+		},
+		"required": []string{"changes"},
+	}
+}
+
+type refactorChange struct {
+	Path       string  `json:"path"`
+	OldString  *string `json:"old_string"`
+	NewString  *string `json:"new_string"`
+	ReplaceAll bool    `json:"replace_all"`
+	Content    *string `json:"content"`
+}
+
+func parseRefactorChanges(args map[string]interface{}) ([]refactorChange, error) {
+	raw, ok := args["changes"]
+	if !ok {
+		return nil, fmt.Errorf("changes is required")
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []refactorChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, fmt.Errorf("invalid changes: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("changes must not be empty")
+	}
+
+	return changes, nil
+}
+
+func (t *RefactorTool) MutatedPaths(args map[string]interface{}) []string {
+	changes, err := parseRefactorChanges(args)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
+
+func (t *RefactorTool) Commands(args map[string]interface{}) []string {
+	if command := buildCommand(args, "build_command"); command != "" {
+		return []string{command}
+	}
+	return nil
+}
+
+func (t *RefactorTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteStream(args, nil)
+}
+
+func (t *RefactorTool) ExecuteStream(args map[string]interface{}, stream io.Writer) (string, error) {
+	return t.ExecuteContext(context.Background(), args, stream)
+}
+
+// ExecuteContext applies the changes; cancelling ctx stops the build, which
+// rolls them back.
+func (t *RefactorTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	changes, err := parseRefactorChanges(args)
+	if err != nil {
+		return "", err
+	}
+
+	tx := NewTransaction()
+	for i, change := range changes {
+		switch {
+		case change.Path == "":
+			return "", fmt.Errorf("change %d: path is required", i)
+		case change.OldString != nil:
+			newString := ""
+			if change.NewString != nil {
+				newString = *change.NewString
+			}
+			_, err = tx.StageEdit(fileEdit{Path: change.Path, OldString: change.OldString, NewString: newString, ReplaceAll: change.ReplaceAll})
+		case change.Content != nil:
+			err = tx.Stage(change.Path, []byte(*change.Content))
+		default:
+			err = fmt.Errorf("%s: either old_string/new_string or content is required", change.Path)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("no files changed: %w", err)
+		}
+	}
+
+	if err := tx.Apply(); err != nil {
+		return "", fmt.Errorf("no files changed: %w", err)
+	}
+
+	buildCommand := buildCommand(args, "build_command")
+	files := strings.Join(tx.Paths(), ", ")
+	if buildCommand == "" {
+		return fmt.Sprintf("Applied changes to %s (no build command detected, not verified)", files), nil
+	}
+
+	output, buildErr := t.runner.run(ctx, buildCommand, "", stream)
+	if buildErr != nil {
+		if err := tx.Rollback(); err != nil {
+			return output, fmt.Errorf("build failed and %w", err)
+		}
+		return output, fmt.Errorf("build failed (%s); all changes rolled back", buildCommand)
+	}
+
+	return fmt.Sprintf("Applied changes to %s; `%s` succeeded", files, buildCommand), nil
+}
+
+// DetectBuildCommand guesses the command that compiles the project in dir.
+func DetectBuildCommand(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("go.mod"):
+		return "go build ./..."
+	case exists("Cargo.toml"):
+		return "cargo build"
+	case exists("package.json"):
+		data, _ := os.ReadFile(filepath.Join(dir, "package.json"))
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		json.Unmarshal(data, &pkg)
+		if _, ok := pkg.Scripts["build"]; ok {
+			return "npm run build"
+		}
+		if exists("tsconfig.json") {
+			return "npx tsc --noEmit"
+		}
+	case exists("pom.xml"):
+		return "mvn -q compile"
+	case exists("build.gradle") || exists("build.gradle.kts"):
+		return "./gradlew compileJava"
+	case exists("pyproject.toml") || exists("setup.py"):
+		return "python -m compileall -q ."
+	case exists("Makefile"):
+		return "make"
+	}
+
+	return ""
+}
@@ -2,17 +2,32 @@
 package tools
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/N0tT1m/claude-code-go/internal/llm"
 )
 
+// Registry holds the tools the model can call. Tools are registered and
+// unregistered, and sources added, while MCP connections and the agent
+// call tools from their own goroutines, so mu guards all of it; the
+// callbacks run outside it.
 type Registry struct {
-	tools map[string]Tool
+	mu       sync.RWMutex
+	tools    map[string]Tool
+	sources  []ToolSource
+	onChange []func()
+	guard    Guard
+	runner   *commandRunner
+	onEdit   []func(paths ...string)
+	onRead   []func(paths ...string)
 }
 
 type Tool interface {
@@ -22,28 +37,187 @@ type Tool interface {
 	Execute(args map[string]interface{}) (string, error)
 }
 
+// Mutator is implemented by tools that modify the workspace. MutatedPaths
+// reports which paths a call with the given arguments would change.
+type Mutator interface {
+	MutatedPaths(args map[string]interface{}) []string
+}
+
+// Commander is implemented by tools that run commands, which can change
+// anything the user can. Commands reports the commands a call with the
+// given arguments would run.
+type Commander interface {
+	Commands(args map[string]interface{}) []string
+}
+
+// Fetcher is implemented by tools that send requests to other hosts,
+// which can carry anything the model has read. URLs reports the URLs a
+// call with the given arguments would request.
+type Fetcher interface {
+	URLs(args map[string]interface{}) []string
+}
+
+// Reader is implemented by tools that read files. ReadPaths reports which
+// files a call with the given arguments reads.
+type Reader interface {
+	ReadPaths(args map[string]interface{}) []string
+}
+
+// ToolSource provides tools that come and go while the registry is in use,
+// such as those of the MCP servers connected at the time.
+type ToolSource interface {
+	Tools() []Tool
+}
+
+// ChangingToolSource is implemented by tool sources that can say when the
+// tools they provide change.
+type ChangingToolSource interface {
+	ToolSource
+	OnToolsChanged(fn func())
+}
+
+// Guard coordinates workspace mutations with other claude-go sessions:
+// an edit locks the paths it changes, and a command, which can change
+// any, the whole workspace.
+type Guard interface {
+	Acquire(path string) (func(), error)
+	AcquireWorkspace() (func(), error)
+	RecordEdit(path, operation string) error
+	RecordCommand(command string) error
+}
+
 func NewRegistry() *Registry {
 	r := &Registry{
-		tools: make(map[string]Tool),
+		tools:  make(map[string]Tool),
+		runner: &commandRunner{executor: LocalExecutor{}},
 	}
 
 	// Register built-in tools
 	r.Register(&FileTool{})
+	r.Register(&EditTool{})
+	r.Register(&MultiEditTool{})
 	r.Register(&GitTool{})
-	r.Register(&ShellTool{})
+	r.Register(&ShellTool{runner: r.runner})
 	r.Register(&SearchTool{})
+	if structuralSearchAvailable {
+		r.Register(&StructuralSearchTool{})
+	}
+	r.Register(&RefactorTool{runner: r.runner})
+	r.Register(&BuildTool{runner: r.runner})
+	r.Register(&RunTestsTool{runner: r.runner})
+	r.Register(&LintFormatTool{runner: r.runner})
+	r.Register(&SecurityScanTool{})
+	r.Register(&WebFetchTool{})
 
 	return r
 }
 
 func (r *Registry) Register(tool Tool) {
+	r.mu.Lock()
 	r.tools[tool.Name()] = tool
+	r.mu.Unlock()
+	r.changed()
+}
+
+// Unregister removes the registered tool of that name, reporting whether
+// there was one. Calls already running it finish.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	_, exists := r.tools[name]
+	delete(r.tools, name)
+	r.mu.Unlock()
+	if exists {
+		r.changed()
+	}
+	return exists
+}
+
+// OnChange registers fn to be called when the available tools change: a
+// tool is registered or unregistered, or a source's tools change.
+func (r *Registry) OnChange(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+func (r *Registry) changed() {
+	r.mu.RLock()
+	listeners := r.onChange
+	r.mu.RUnlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// AddSource offers the tools source provides at each call along with the
+// registered ones, which take precedence over those of the same name.
+func (r *Registry) AddSource(source ToolSource) {
+	r.mu.Lock()
+	r.sources = append(r.sources, source)
+	r.mu.Unlock()
+	if changing, ok := source.(ChangingToolSource); ok {
+		changing.OnToolsChanged(r.changed)
+	}
+	r.changed()
+}
+
+// snapshot returns the registered tools and the sources as they are now;
+// the sources are asked for their tools without mu held.
+func (r *Registry) snapshot() (map[string]Tool, []ToolSource) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make(map[string]Tool, len(r.tools))
+	for name, tool := range r.tools {
+		tools[name] = tool
+	}
+	return tools, r.sources
+}
+
+// lookup finds a registered tool, or else one a source provides now.
+func (r *Registry) lookup(name string) (Tool, bool) {
+	r.mu.RLock()
+	tool, ok := r.tools[name]
+	sources := r.sources
+	r.mu.RUnlock()
+	if ok {
+		return tool, true
+	}
+	for _, source := range sources {
+		for _, tool := range source.Tools() {
+			if tool.Name() == name {
+				return tool, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// all returns the registered tools and those the sources provide now,
+// the first of each name.
+func (r *Registry) all() []Tool {
+	registered, sources := r.snapshot()
+	tools := make([]Tool, 0, len(registered))
+	seen := make(map[string]bool)
+	for name, tool := range registered {
+		tools = append(tools, tool)
+		seen[name] = true
+	}
+	for _, source := range sources {
+		for _, tool := range source.Tools() {
+			if !seen[tool.Name()] {
+				tools = append(tools, tool)
+				seen[tool.Name()] = true
+			}
+		}
+	}
+	return tools
 }
 
 func (r *Registry) GetAvailable() []llm.Tool {
-	tools := make([]llm.Tool, 0, len(r.tools))
+	all := r.all()
+	tools := make([]llm.Tool, 0, len(all))
 
-	for _, tool := range r.tools {
+	for _, tool := range all {
 		tools = append(tools, llm.Tool{
 			Type: "function",
 			Function: llm.ToolFunction{
@@ -57,13 +231,195 @@ func (r *Registry) GetAvailable() []llm.Tool {
 	return tools
 }
 
+// MutatedPaths reports the paths a call to the named tool would change, or
+// nil for tools that do not modify the workspace.
+func (r *Registry) MutatedPaths(name string, args map[string]interface{}) []string {
+	tool, _ := r.lookup(name)
+	if mutator, ok := tool.(Mutator); ok {
+		return mutator.MutatedPaths(args)
+	}
+	return nil
+}
+
+// Commands reports the commands a call to the named tool would run, or
+// nil if it runs none.
+func (r *Registry) Commands(name string, args map[string]interface{}) []string {
+	tool, _ := r.lookup(name)
+	if commander, ok := tool.(Commander); ok {
+		return commander.Commands(args)
+	}
+	return nil
+}
+
+// URLs reports the URLs a call to the named tool would request, or nil if
+// it requests none.
+func (r *Registry) URLs(name string, args map[string]interface{}) []string {
+	tool, _ := r.lookup(name)
+	if fetcher, ok := tool.(Fetcher); ok {
+		return fetcher.URLs(args)
+	}
+	return nil
+}
+
+func (r *Registry) SetGuard(guard Guard) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guard = guard
+}
+
+// OnEdit registers fn to be called with the paths a tool changed, after
+// each successful call to a Mutator.
+func (r *Registry) OnEdit(fn func(paths ...string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onEdit = append(r.onEdit, fn)
+}
+
+// OnRead registers fn to be called with the paths a tool read, after each
+// successful call to a Reader.
+func (r *Registry) OnRead(fn func(paths ...string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRead = append(r.onRead, fn)
+}
+
+// hooks returns the guard and the edit and read callbacks as they are now.
+func (r *Registry) hooks() (Guard, []func(paths ...string), []func(paths ...string)) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.guard, r.onEdit, r.onRead
+}
+
+// SetExecutor switches where shell, build and test commands run.
+func (r *Registry) SetExecutor(executor Executor) {
+	r.runner.setExecutor(executor)
+}
+
+func (r *Registry) Executor() Executor {
+	return r.runner.current()
+}
+
+// RunCommand runs a shell command through the configured executor.
+func (r *Registry) RunCommand(ctx context.Context, command, dir string, stream io.Writer) (string, error) {
+	return r.runner.run(ctx, command, dir, stream)
+}
+
 func (r *Registry) Execute(name string, args map[string]interface{}) (string, error) {
-	tool, exists := r.tools[name]
+	return r.ExecuteStream(name, args, nil)
+}
+
+// ExecuteStream runs a tool, passing stream to tools that can report output
+// while they run.
+func (r *Registry) ExecuteStream(name string, args map[string]interface{}, stream io.Writer) (string, error) {
+	return r.ExecuteContext(context.Background(), name, args, stream)
+}
+
+// ExecuteContext runs a tool like ExecuteStream; tools that support it stop
+// when ctx is cancelled, and none starts once it is.
+func (r *Registry) ExecuteContext(ctx context.Context, name string, args map[string]interface{}, stream io.Writer) (string, error) {
+	result, err := r.ExecuteResult(ctx, name, args, stream)
+	return result.String(), err
+}
+
+// ExecuteResult runs a tool like ExecuteContext, returning the typed
+// content of a ContentTool as it is; the result of any other is its text.
+// Arguments that do not match the tool's Parameters schema are refused
+// with an *ArgumentsError before it runs.
+func (r *Registry) ExecuteResult(ctx context.Context, name string, args map[string]interface{}, stream io.Writer) (*Result, error) {
+	tool, exists := r.lookup(name)
 	if !exists {
-		return "", fmt.Errorf("tool %s not found", name)
+		return nil, fmt.Errorf("tool %s not found", name)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateArgs(tool, args); err != nil {
+		return nil, err
+	}
+	guard, onEdit, onRead := r.hooks()
+
+	execute := func() (*Result, error) {
+		if typed, ok := tool.(ContentTool); ok {
+			return typed.ExecuteContent(ctx, args, stream)
+		}
+		var text string
+		var err error
+		if cancellable, ok := tool.(ContextTool); ok {
+			text, err = cancellable.ExecuteContext(ctx, args, stream)
+		} else if streaming, ok := tool.(StreamingTool); ok && stream != nil {
+			text, err = streaming.ExecuteStream(args, stream)
+		} else {
+			text, err = tool.Execute(args)
+		}
+		return textResult(text), err
+	}
+
+	if reader, ok := tool.(Reader); ok {
+		inner := execute
+		execute = func() (*Result, error) {
+			result, err := inner()
+			if paths := reader.ReadPaths(args); err == nil && len(paths) > 0 {
+				for _, fn := range onRead {
+					fn(paths...)
+				}
+			}
+			return result, err
+		}
 	}
 
-	return tool.Execute(args)
+	if commander, ok := tool.(Commander); ok && guard != nil {
+		if commands := commander.Commands(args); len(commands) > 0 {
+			release, err := guard.AcquireWorkspace()
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+
+			// A command that fails may still have changed files
+			inner := execute
+			execute = func() (*Result, error) {
+				result, err := inner()
+				for _, command := range commands {
+					guard.RecordCommand(command)
+				}
+				return result, err
+			}
+		}
+	}
+
+	mutator, ok := tool.(Mutator)
+	if !ok {
+		return execute()
+	}
+
+	paths := mutator.MutatedPaths(args)
+	if guard != nil {
+		for _, path := range paths {
+			release, err := guard.Acquire(path)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+	}
+
+	result, err := execute()
+	if err == nil {
+		if guard != nil {
+			operation, _ := args["operation"].(string)
+			if operation == "" {
+				operation = name
+			}
+			for _, path := range paths {
+				guard.RecordEdit(path, operation)
+			}
+		}
+		for _, fn := range onEdit {
+			fn(paths...)
+		}
+	}
+
+	return result, err
 }
 
 // FileTool - File operations
@@ -72,7 +428,7 @@ type FileTool struct{}
 func (t *FileTool) Name() string { return "file_operations" }
 
 func (t *FileTool) Description() string {
-	return "Read, write, and manage files in the codebase"
+	return "Read, write, and manage files in the codebase; to change part of a file, use edit_file rather than writing it all"
 }
 
 func (t *FileTool) Parameters() interface{} {
@@ -97,6 +453,24 @@ func (t *FileTool) Parameters() interface{} {
 	}
 }
 
+func (t *FileTool) MutatedPaths(args map[string]interface{}) []string {
+	operation, _ := args["operation"].(string)
+	path, _ := args["path"].(string)
+	if path == "" || (operation != "write" && operation != "delete") {
+		return nil
+	}
+	return []string{path}
+}
+
+func (t *FileTool) ReadPaths(args map[string]interface{}) []string {
+	operation, _ := args["operation"].(string)
+	path, _ := args["path"].(string)
+	if path == "" || operation != "read" {
+		return nil
+	}
+	return []string{path}
+}
+
 func (t *FileTool) Execute(args map[string]interface{}) (string, error) {
 	operation, ok := args["operation"].(string)
 	if !ok {
@@ -185,12 +559,9 @@ func (t *GitTool) Parameters() interface{} {
 	}
 }
 
-func (t *GitTool) Execute(args map[string]interface{}) (string, error) {
-	command, ok := args["command"].(string)
-	if !ok {
-		return "", fmt.Errorf("command is required")
-	}
-
+// gitArgs returns the git command line of a call.
+func gitArgs(args map[string]interface{}) []string {
+	command, _ := args["command"].(string)
 	gitArgs := []string{command}
 
 	if argsInterface, exists := args["args"]; exists {
@@ -202,7 +573,29 @@ func (t *GitTool) Execute(args map[string]interface{}) (string, error) {
 			}
 		}
 	}
+	return gitArgs
+}
+
+// Commands reports the git command of a call that can change the
+// repository or the files in it: all but status, diff and log, and those
+// too when they write their output to a file.
+func (t *GitTool) Commands(args map[string]interface{}) []string {
+	gitArgs := gitArgs(args)
+	switch gitArgs[0] {
+	case "status", "diff", "log":
+		if !slices.ContainsFunc(gitArgs[1:], func(arg string) bool { return strings.HasPrefix(arg, "--output") }) {
+			return nil
+		}
+	}
+	return []string{"git " + quoteAll(gitArgs)}
+}
+
+func (t *GitTool) Execute(args map[string]interface{}) (string, error) {
+	if _, ok := args["command"].(string); !ok {
+		return "", fmt.Errorf("command is required")
+	}
 
+	gitArgs := gitArgs(args)
 	cmd := exec.Command("git", gitArgs...)
 	output, err := cmd.CombinedOutput()
 
@@ -210,7 +603,9 @@ func (t *GitTool) Execute(args map[string]interface{}) (string, error) {
 }
 
 // ShellTool - Execute shell commands
-type ShellTool struct{}
+type ShellTool struct {
+	runner *commandRunner
+}
 
 func (t *ShellTool) Name() string { return "shell_execute" }
 
@@ -235,84 +630,29 @@ func (t *ShellTool) Parameters() interface{} {
 	}
 }
 
-func (t *ShellTool) Execute(args map[string]interface{}) (string, error) {
-	command, ok := args["command"].(string)
-	if !ok {
-		return "", fmt.Errorf("command is required")
-	}
-
-	cmd := exec.Command("sh", "-c", command)
-
-	if workingDir, exists := args["working_dir"]; exists {
-		if dir, ok := workingDir.(string); ok {
-			cmd.Dir = dir
-		}
+func (t *ShellTool) Commands(args map[string]interface{}) []string {
+	command, _ := args["command"].(string)
+	if command == "" {
+		return nil
 	}
-
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	return []string{command}
 }
 
-// SearchTool - Search through codebase
-type SearchTool struct{}
-
-func (t *SearchTool) Name() string { return "code_search" }
-
-func (t *SearchTool) Description() string {
-	return "Search for text patterns, function definitions, or file names in the codebase"
+func (t *ShellTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteStream(args, nil)
 }
 
-func (t *SearchTool) Parameters() interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"pattern": map[string]interface{}{
-				"type":        "string",
-				"description": "Search pattern or text to find",
-			},
-			"file_pattern": map[string]interface{}{
-				"type":        "string",
-				"description": "File pattern to limit search (e.g., '*.go', '*.py')",
-			},
-			"case_sensitive": map[string]interface{}{
-				"type":        "boolean",
-				"description": "Whether search should be case sensitive",
-			},
-		},
-		"required": []string{"pattern"},
-	}
+func (t *ShellTool) ExecuteStream(args map[string]interface{}, stream io.Writer) (string, error) {
+	return t.ExecuteContext(context.Background(), args, stream)
 }
 
-func (t *SearchTool) Execute(args map[string]interface{}) (string, error) {
-	pattern, ok := args["pattern"].(string)
+// ExecuteContext runs the command, killing it when ctx is cancelled.
+func (t *ShellTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	command, ok := args["command"].(string)
 	if !ok {
-		return "", fmt.Errorf("pattern is required")
-	}
-
-	grepArgs := []string{"-r", "-n"}
-
-	if caseSensitive, exists := args["case_sensitive"]; exists {
-		if !caseSensitive.(bool) {
-			grepArgs = append(grepArgs, "-i")
-		}
-	}
-
-	grepArgs = append(grepArgs, pattern)
-
-	if filePattern, exists := args["file_pattern"]; exists {
-		if pattern, ok := filePattern.(string); ok {
-			grepArgs = append(grepArgs, "--include="+pattern)
-		}
-	}
-
-	grepArgs = append(grepArgs, ".")
-
-	cmd := exec.Command("grep", grepArgs...)
-	output, err := cmd.CombinedOutput()
-
-	if err != nil && len(output) == 0 {
-		return "No matches found", nil
+		return "", fmt.Errorf("command is required")
 	}
 
-	return string(output), nil
+	dir, _ := args["working_dir"].(string)
+	return t.runner.run(ctx, command, dir, stream)
 }
@@ -0,0 +1,594 @@
+// Package: internal/tools/runtests.go
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxFailureLines bounds the output kept of each failure.
+const maxFailureLines = 40
+
+// RunTestsTool runs a project's tests, or some of them, with its test
+// framework, and reports which passed and which failed, with the output of
+// each failure rather than the whole run's.
+type RunTestsTool struct {
+	runner *commandRunner
+}
+
+func (t *RunTestsTool) Name() string { return "run_tests" }
+
+func (t *RunTestsTool) Description() string {
+	return "Run the project's tests with its framework (go test, pytest, jest or cargo test, detected if not given), optionally only a package or file and tests matching a name, and get pass/fail counts with each failure's output, trimmed to the frames in the project"
+}
+
+func (t *RunTestsTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"framework": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"go", "pytest", "jest", "cargo"},
+				"description": "Test framework (detected from the project if omitted)",
+			},
+			"target": map[string]interface{}{
+				"type":        "string",
+				"description": "What to test: a Go package pattern (./internal/tools, ./... by default), a pytest or jest file or directory, or a cargo package",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Only tests matching this: a regular expression for go test -run, an expression for pytest -k, a pattern for jest -t, a substring for cargo test",
+			},
+		},
+	}
+}
+
+// TestReport is the result of a run_tests call.
+type TestReport struct {
+	Framework string       `json:"framework"`
+	Command   string       `json:"command"`
+	Passed    int          `json:"passed"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Tests     []TestResult `json:"tests"`
+	Errors    []string     `json:"errors,omitempty"` // Output not of any test, such as build errors
+}
+
+// TestResult is a test of a TestReport.
+type TestResult struct {
+	Name     string  `json:"name"`
+	Package  string  `json:"package,omitempty"`  // Go package, test file or crate target
+	Status   string  `json:"status"`             // pass, fail or skip
+	Duration float64 `json:"duration,omitempty"` // In seconds
+	Output   string  `json:"output,omitempty"`   // Of a failure, trimmed
+}
+
+// testFramework runs and reads the results of one kind of tests.
+type testFramework struct {
+	command func(target, name string) string
+	parse   func(report *TestReport, output string)
+	// readable is whether the output is worth streaming as it comes,
+	// rather than JSON for parse
+	readable bool
+}
+
+var testFrameworks = map[string]testFramework{
+	"go": {
+		command: func(target, name string) string {
+			if target == "" {
+				target = "./..."
+			} else if strings.HasSuffix(target, ".go") {
+				target = "./" + filepath.ToSlash(filepath.Dir(target))
+			}
+			command := "go test -json " + shellQuote(target)
+			if name != "" {
+				command += " -run " + shellQuote(name)
+			}
+			return command
+		},
+		parse: parseGoTests,
+	},
+	"pytest": {
+		command: func(target, name string) string {
+			command := "python -m pytest -q -rA --tb=short"
+			if target != "" {
+				command += " " + shellQuote(target)
+			}
+			if name != "" {
+				command += " -k " + shellQuote(name)
+			}
+			return command
+		},
+		parse:    parsePytest,
+		readable: true,
+	},
+	"jest": {
+		command: func(target, name string) string {
+			command := "npx jest --ci --json"
+			if target != "" {
+				command += " " + shellQuote(target)
+			}
+			if name != "" {
+				command += " -t " + shellQuote(name)
+			}
+			return command
+		},
+		parse: parseJest,
+	},
+	"cargo": {
+		command: func(target, name string) string {
+			command := "cargo test"
+			if target != "" {
+				command += " -p " + shellQuote(target)
+			}
+			if name != "" {
+				command += " " + shellQuote(name)
+			}
+			return command + " -- --color never"
+		},
+		parse:    parseCargoTests,
+		readable: true,
+	},
+}
+
+// DetectTestFramework guesses the test framework of the project in dir.
+func DetectTestFramework(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("go.mod"):
+		return "go"
+	case exists("Cargo.toml"):
+		return "cargo"
+	case exists("package.json"):
+		data, _ := os.ReadFile(filepath.Join(dir, "package.json"))
+		var pkg struct {
+			Scripts         map[string]string `json:"scripts"`
+			Dependencies    map[string]string `json:"dependencies"`
+			DevDependencies map[string]string `json:"devDependencies"`
+		}
+		json.Unmarshal(data, &pkg)
+		_, dep := pkg.Dependencies["jest"]
+		_, devDep := pkg.DevDependencies["jest"]
+		if dep || devDep || strings.Contains(pkg.Scripts["test"], "jest") || exists("jest.config.js") || exists("jest.config.ts") {
+			return "jest"
+		}
+	case exists("pytest.ini") || exists("conftest.py") || exists("pyproject.toml") || exists("setup.py") || exists("setup.cfg") || exists("tox.ini"):
+		return "pytest"
+	}
+
+	return ""
+}
+
+// testCommand is the framework of a call, filled in with the detected one,
+// and the command running the tests it asks for.
+func testCommand(args map[string]interface{}) (string, testFramework, string, error) {
+	name, _ := args["framework"].(string)
+	if name == "" {
+		wd, _ := os.Getwd()
+		if name = DetectTestFramework(wd); name == "" {
+			return "", testFramework{}, "", fmt.Errorf("no test framework detected; set framework to go, pytest, jest or cargo")
+		}
+	}
+	framework, ok := testFrameworks[name]
+	if !ok {
+		return "", testFramework{}, "", fmt.Errorf("unsupported test framework %q", name)
+	}
+	target, _ := args["target"].(string)
+	filter, _ := args["name"].(string)
+	return name, framework, framework.command(target, filter), nil
+}
+
+func (t *RunTestsTool) Commands(args map[string]interface{}) []string {
+	if _, _, command, err := testCommand(args); err == nil {
+		return []string{command}
+	}
+	return nil
+}
+
+func (t *RunTestsTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+func (t *RunTestsTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	result, err := t.ExecuteContent(ctx, args, stream)
+	return result.String(), err
+}
+
+// ExecuteContent runs the tests; failing ones are part of the report, not
+// an error, which is for tests that could not be run at all.
+func (t *RunTestsTool) ExecuteContent(ctx context.Context, args map[string]interface{}, stream io.Writer) (*Result, error) {
+	name, framework, command, err := testCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TestReport{Framework: name, Command: command, Tests: []TestResult{}}
+	if !framework.readable {
+		stream = nil
+	}
+	output, runErr := t.runner.run(ctx, report.Command, "", stream)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	framework.parse(report, output)
+	for _, test := range report.Tests {
+		switch test.Status {
+		case "pass":
+			report.Passed++
+		case "fail":
+			report.Failed++
+		case "skip":
+			report.Skipped++
+		}
+	}
+	if runErr != nil && len(report.Tests) == 0 && len(report.Errors) == 0 {
+		return nil, fmt.Errorf("`%s` failed: %w\n%s", report.Command, runErr, trimFailure(output))
+	}
+
+	result, err := JSONResult(report)
+	if err != nil {
+		return nil, err
+	}
+	result.Content = []Content{TextContent(report.String())}
+	return result, nil
+}
+
+// String summarizes the report for the model: the counts, then each
+// failure with its output.
+func (r *TestReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s`: %d passed, %d failed, %d skipped", r.Command, r.Passed, r.Failed, r.Skipped)
+	if len(r.Tests) == 0 && len(r.Errors) == 0 {
+		b.WriteString(" (no tests ran)")
+	}
+	for _, errText := range r.Errors {
+		fmt.Fprintf(&b, "\n\nERROR\n%s", indent(errText))
+	}
+	for _, test := range r.Tests {
+		if test.Status != "fail" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\nFAIL %s", test.Name)
+		if test.Package != "" {
+			fmt.Fprintf(&b, " (%s)", test.Package)
+		}
+		if test.Output != "" {
+			b.WriteString("\n" + indent(test.Output))
+		}
+	}
+	return b.String()
+}
+
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(text, "\n", "\n    ")
+}
+
+// goTestEvent is a line of go test -json.
+type goTestEvent struct {
+	Action     string
+	Package    string
+	Test       string
+	Output     string
+	Elapsed    float64
+	ImportPath string // Of build-output
+}
+
+// goTestNoise matches the lines go test prints around a test's own output.
+var goTestNoise = regexp.MustCompile(`^(=== (RUN|PAUSE|CONT|NAME)|--- (PASS|FAIL|SKIP)|PASS$|FAIL$|FAIL\t|ok  \t|exit status \d+$|\?   \t)`)
+
+func parseGoTests(report *TestReport, output string) {
+	type key struct{ pkg, test string }
+	outputs := make(map[key]*strings.Builder)
+	buildOutput := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	var raw strings.Builder
+
+	for _, line := range strings.Split(output, "\n") {
+		var event goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil {
+			if strings.TrimSpace(line) != "" {
+				raw.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		k := key{event.Package, event.Test}
+		switch event.Action {
+		case "output":
+			if !goTestNoise.MatchString(strings.TrimLeft(event.Output, " ")) {
+				if outputs[k] == nil {
+					outputs[k] = &strings.Builder{}
+				}
+				outputs[k].WriteString(event.Output)
+			}
+		case "build-output":
+			pkg, _, _ := strings.Cut(event.ImportPath, " ")
+			if buildOutput[pkg] == nil {
+				buildOutput[pkg] = &strings.Builder{}
+			}
+			buildOutput[pkg].WriteString(event.Output)
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				// A package fails without a test failing when it does
+				// not build, or its tests panic outside of one
+				if event.Action == "fail" && !failedTests[event.Package] {
+					text := ""
+					if b := buildOutput[event.Package]; b != nil {
+						text = b.String()
+					}
+					if b := outputs[k]; b != nil {
+						text += b.String()
+					}
+					report.Errors = append(report.Errors, strings.TrimSpace(event.Package+"\n"+trimFailure(text)))
+				}
+				continue
+			}
+			test := TestResult{Name: event.Test, Package: event.Package, Status: event.Action, Duration: event.Elapsed}
+			if event.Action == "fail" {
+				failedTests[event.Package] = true
+				if b := outputs[k]; b != nil {
+					test.Output = trimFailure(b.String())
+				}
+			}
+			report.Tests = append(report.Tests, test)
+		}
+	}
+	if text := strings.TrimSpace(raw.String()); text != "" {
+		report.Errors = append(report.Errors, trimFailure(text))
+	}
+
+	// A test fails when one of its subtests does; the subtest is the
+	// failure worth reporting
+	failed := make(map[key]bool)
+	for _, test := range report.Tests {
+		if test.Status == "fail" {
+			failed[key{test.Package, test.Name}] = true
+		}
+	}
+	tests := report.Tests[:0]
+	for _, test := range report.Tests {
+		if test.Status == "fail" && test.Output == "" {
+			hasFailedSubtest := false
+			for k := range failed {
+				if k.pkg == test.Package && strings.HasPrefix(k.test, test.Name+"/") {
+					hasFailedSubtest = true
+					break
+				}
+			}
+			if hasFailedSubtest {
+				continue
+			}
+		}
+		tests = append(tests, test)
+	}
+	report.Tests = tests
+}
+
+var (
+	pytestSection   = regexp.MustCompile(`^=+ (.+?) =+$`)
+	pytestFailure   = regexp.MustCompile(`^_+ (.+?) _+$`)
+	pytestSummary   = regexp.MustCompile(`^(PASSED|FAILED|ERROR|SKIPPED|XFAIL|XPASS) (.+)$`)
+	pytestSkipCount = regexp.MustCompile(`^\[\d+\] `)
+)
+
+func parsePytest(report *TestReport, output string) {
+	section, failure := "", ""
+	failures := make(map[string]*strings.Builder)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := pytestSection.FindStringSubmatch(line); m != nil {
+			section, failure = m[1], ""
+			continue
+		}
+		switch section {
+		case "FAILURES", "ERRORS":
+			if m := pytestFailure.FindStringSubmatch(line); m != nil {
+				failure = m[1]
+				failures[failure] = &strings.Builder{}
+			} else if failure != "" {
+				failures[failure].WriteString(line + "\n")
+			}
+		case "short test summary info":
+			m := pytestSummary.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			id, _, _ := strings.Cut(m[2], " - ")
+			file, name, _ := strings.Cut(id, "::")
+			test := TestResult{Name: name, Package: file}
+			switch m[1] {
+			case "PASSED", "XFAIL":
+				test.Status = "pass"
+			case "SKIPPED":
+				// SKIPPED [1] file:line: reason
+				test.Status, test.Name, test.Package = "skip", pytestSkipCount.ReplaceAllString(m[2], ""), ""
+			default:
+				test.Status = "fail"
+				// Failures are headed by the test's name with its class,
+				// or what failed to be collected or set up
+				short := strings.ReplaceAll(name, "::", ".")
+				for _, header := range []string{short, "ERROR at setup of " + short, "ERROR at teardown of " + short, "ERROR collecting " + file} {
+					if b := failures[header]; b != nil {
+						test.Output = trimFailure(b.String())
+						break
+					}
+				}
+				if name == "" {
+					test.Name, test.Package = file, ""
+				}
+			}
+			report.Tests = append(report.Tests, test)
+		}
+	}
+}
+
+// jestReport is the part of jest --json's output run_tests reads.
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			Duration        float64  `json:"duration"` // In milliseconds
+			FailureMessages []string `json:"failureMessages"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+func parseJest(report *TestReport, output string) {
+	var results *jestReport
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, `{"num`) {
+			continue
+		}
+		var parsed jestReport
+		if json.Unmarshal([]byte(line), &parsed) == nil {
+			results = &parsed
+			break
+		}
+	}
+	if results == nil {
+		return
+	}
+
+	wd, _ := os.Getwd()
+	for _, file := range results.TestResults {
+		name := file.Name
+		if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		// A file that fails to run, failing to compile say, has no tests
+		if len(file.AssertionResults) == 0 && file.Status == "failed" {
+			report.Errors = append(report.Errors, name+"\n"+trimFailure(file.Message))
+			continue
+		}
+		for _, assertion := range file.AssertionResults {
+			test := TestResult{Name: assertion.FullName, Package: name, Duration: assertion.Duration / 1000}
+			switch assertion.Status {
+			case "passed":
+				test.Status = "pass"
+			case "failed":
+				test.Status = "fail"
+				test.Output = trimFailure(strings.Join(assertion.FailureMessages, "\n"))
+			default:
+				test.Status = "skip"
+			}
+			report.Tests = append(report.Tests, test)
+		}
+	}
+}
+
+var (
+	cargoRunning = regexp.MustCompile(`^\s*Running (?:unittests )?(\S+)`)
+	cargoTest    = regexp.MustCompile(`^test (.+?) \.\.\. (ok|FAILED|ignored)`)
+	cargoFailure = regexp.MustCompile(`^---- (.+?) stdout ----$`)
+)
+
+func parseCargoTests(report *TestReport, output string) {
+	target, failure := "", ""
+	failures := make(map[string]*strings.Builder)
+	var tests []TestResult
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := cargoRunning.FindStringSubmatch(line); m != nil {
+			target, failure = m[1], ""
+			continue
+		}
+		if m := cargoTest.FindStringSubmatch(line); m != nil {
+			status := map[string]string{"ok": "pass", "FAILED": "fail", "ignored": "skip"}[m[2]]
+			tests = append(tests, TestResult{Name: m[1], Package: target, Status: status})
+			continue
+		}
+		if m := cargoFailure.FindStringSubmatch(line); m != nil {
+			failure = target + "\x00" + m[1]
+			failures[failure] = &strings.Builder{}
+			continue
+		}
+		if line == "failures:" || strings.HasPrefix(line, "test result:") {
+			failure = ""
+		}
+		if failure != "" {
+			failures[failure].WriteString(line + "\n")
+		}
+	}
+
+	for _, test := range tests {
+		if b := failures[test.Package+"\x00"+test.Name]; b != nil {
+			test.Output = trimFailure(b.String())
+		}
+		report.Tests = append(report.Tests, test)
+	}
+}
+
+// libraryFrames match the stack frames of a language's runtime, test
+// framework and dependencies, which failures are trimmed of. A Go frame is
+// a function line and a location line under it.
+var (
+	goLibraryFrame   = regexp.MustCompile(`^(runtime|testing|panic|reflect|internal/|created by (runtime|testing))`)
+	goFrameLocation  = regexp.MustCompile(`^\t.*\.go:\d+`)
+	jsLibraryFrame   = regexp.MustCompile(`^\s+at .*(node_modules|node:internal|\(internal/|\(<anonymous>\))`)
+	rustLibraryFrame = regexp.MustCompile(`^\s+\d+: (std|core|alloc|test|__rust|rust_begin_unwind)`)
+	rustFrameSource  = regexp.MustCompile(`^\s+at /rustc/`)
+	pyLibraryFrame   = regexp.MustCompile(`(site-packages|dist-packages|/lib/python\d)`)
+)
+
+// trimFailure cuts a failure's output down to what concerns the project:
+// stack frames of runtimes, test frameworks and dependencies are dropped,
+// and what is left is capped at maxFailureLines.
+func trimFailure(output string) string {
+	lines := strings.Split(strings.Trim(output, "\n"), "\n")
+	// Go indents the output of subtests; what all of it shares is dropped
+	common := -1
+	for _, line := range lines {
+		if trimmed := strings.TrimLeft(line, " "); trimmed != "" && (common < 0 || len(line)-len(trimmed) < common) {
+			common = len(line) - len(trimmed)
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line[min(max(common, 0), len(line)-len(strings.TrimLeft(line, " "))):], " \r")
+	}
+	kept := make([]string, 0, len(lines))
+	dropped := 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case goLibraryFrame.MatchString(line) && i+1 < len(lines) && goFrameLocation.MatchString(lines[i+1]):
+			i++
+			dropped++
+		case jsLibraryFrame.MatchString(line), rustLibraryFrame.MatchString(line):
+			dropped++
+		case rustFrameSource.MatchString(line):
+		case strings.HasPrefix(strings.TrimSpace(line), "File \"") && pyLibraryFrame.MatchString(line):
+			// The source line under the frame goes with it
+			if i+1 < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "File \"") {
+				i++
+			}
+			dropped++
+		default:
+			kept = append(kept, line)
+		}
+	}
+	if dropped > 0 {
+		kept = append(kept, fmt.Sprintf("(%d library frames omitted)", dropped))
+	}
+	if len(kept) > maxFailureLines {
+		more := len(kept) - maxFailureLines
+		kept = append(kept[:maxFailureLines], fmt.Sprintf("(%d more lines)", more))
+	}
+	return strings.Join(kept, "\n")
+}
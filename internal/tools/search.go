@@ -0,0 +1,399 @@
+// Package: internal/tools/search.go
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	projectctx "github.com/N0tT1m/claude-code-go/internal/context"
+)
+
+const (
+	// defaultMaxMatches is how many matches a search returns unless told
+	// otherwise.
+	defaultMaxMatches = 200
+	// maxSearchFileSize skips files too large to be source.
+	maxSearchFileSize = 10 * 1024 * 1024
+	// binarySniffSize is how much of a file is checked for a NUL byte,
+	// which marks it binary.
+	binarySniffSize = 8000
+)
+
+// SearchTool searches file contents with a regular expression, in Go, so
+// it needs no grep: it walks the tree as git sees it, skipping what
+// .gitignore and .claudeignore ignore, hidden files and binary ones.
+type SearchTool struct{}
+
+func (t *SearchTool) Name() string { return "code_search" }
+
+func (t *SearchTool) Description() string {
+	return "Search file contents with a regular expression (Go RE2 syntax), skipping ignored, hidden and binary files. Returns file:line:text matches, with context lines if asked, or JSON"
+}
+
+func (t *SearchTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression to find (Go RE2 syntax), or text with literal set",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory or file to search (the working directory by default)",
+			},
+			"file_pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "File pattern to limit search (e.g., '*.go', '*.py', or 'internal/*/*.go' for paths)",
+			},
+			"case_sensitive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Whether search should be case sensitive (default true)",
+			},
+			"literal": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Treat pattern as plain text rather than a regular expression",
+			},
+			"context": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"description": "Lines of context to show before and after each match",
+			},
+			"multiline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Let matches span lines: the pattern runs over each whole file and . matches newlines",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"text", "json"},
+				"description": "text (file:line:text, the default) or json (a list of matches with file, line, column and context)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     1,
+				"description": fmt.Sprintf("Stop after this many matches (default %d)", defaultMaxMatches),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+// SearchMatch is a match of code_search, as its JSON lists them.
+type SearchMatch struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line"`
+	EndLine int      `json:"end_line,omitempty"` // Of a match spanning lines
+	Column  int      `json:"column"`             // In characters, from 1
+	Text    string   `json:"text"`               // The lines the match is on
+	Before  []string `json:"before,omitempty"`
+	After   []string `json:"after,omitempty"`
+}
+
+type searchOptions struct {
+	re          *regexp.Regexp
+	filePattern string
+	context     int
+	multiline   bool
+	maxMatches  int
+}
+
+func (t *SearchTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+// ExecuteContext searches until it has max_results matches, or ctx is
+// cancelled.
+func (t *SearchTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	if literal, _ := args["literal"].(bool); literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	opts := searchOptions{maxMatches: defaultMaxMatches}
+	opts.filePattern, _ = args["file_pattern"].(string)
+	opts.multiline, _ = args["multiline"].(bool)
+	if context, ok := args["context"].(float64); ok {
+		opts.context = int(context)
+	}
+	if maxResults, ok := args["max_results"].(float64); ok && maxResults > 0 {
+		opts.maxMatches = int(maxResults)
+	}
+
+	flags := ""
+	if caseSensitive, ok := args["case_sensitive"].(bool); ok && !caseSensitive {
+		flags += "i"
+	}
+	if opts.multiline {
+		flags += "ms"
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	opts.re = re
+
+	root, _ := args["path"].(string)
+	if root == "" {
+		root = "."
+	}
+	matches, truncated, err := search(ctx, root, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if format, _ := args["format"].(string); format == "json" {
+		data, err := json.MarshalIndent(struct {
+			Matches   []SearchMatch `json:"matches"`
+			Truncated bool          `json:"truncated"`
+		}{matches, truncated}, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	if len(matches) == 0 {
+		return "No matches found", nil
+	}
+	output := formatMatches(matches, opts.context)
+	if truncated {
+		output += fmt.Sprintf("\n(stopped after %d matches; narrow the search or raise max_results)", opts.maxMatches)
+	}
+	return output, nil
+}
+
+// search returns the matches in the files walkFiles finds under root.
+func search(ctx context.Context, root string, opts searchOptions) ([]SearchMatch, bool, error) {
+	var matches []SearchMatch
+	truncated := false
+	err := walkFiles(ctx, root, opts.filePattern, func(path string) bool {
+		found, err := searchFile(path, opts, opts.maxMatches-len(matches))
+		if err != nil {
+			return true
+		}
+		matches = append(matches, found...)
+		truncated = len(matches) >= opts.maxMatches
+		return !truncated
+	})
+	return matches, truncated, err
+}
+
+// walkFiles calls fn with root, when it is a file, or each file under it
+// matching filePattern, until fn returns false. It skips what the
+// project's ignore files ignore when root is inside the working
+// directory, or root's own otherwise, and hidden files.
+func walkFiles(ctx context.Context, root, filePattern string, fn func(path string) bool) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		fn(root)
+		return nil
+	}
+
+	ignoreRoot, err := searchIgnoreRoot(root)
+	if err != nil {
+		return err
+	}
+	ignore := projectctx.NewIgnoreMatcher(ignoreRoot, nil)
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		isDir := d.IsDir()
+		if strings.HasPrefix(d.Name(), ".") || ignored(ignore, ignoreRoot, path, isDir) {
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDir || !d.Type().IsRegular() || !matchesFilePattern(filePattern, root, path) {
+			return nil
+		}
+		if !fn(path) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// searchIgnoreRoot returns the directory whose ignore files apply to a
+// search of root: the working directory when root is inside it, else root.
+func searchIgnoreRoot(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs, nil
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return wd, nil
+	}
+	return abs, nil
+}
+
+func ignored(ignore *projectctx.IgnoreMatcher, ignoreRoot, path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(ignoreRoot, abs)
+	if err != nil {
+		return false
+	}
+	return ignore.Ignored(rel, isDir)
+}
+
+// matchesFilePattern reports whether path matches pattern: its name, or
+// its path under root for a pattern with a slash.
+func matchesFilePattern(pattern, root, path string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.Contains(pattern, "/") {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return false
+		}
+		matched, _ := filepath.Match(pattern, filepath.ToSlash(rel))
+		return matched
+	}
+	matched, _ := filepath.Match(pattern, filepath.Base(path))
+	return matched
+}
+
+// searchFile returns up to limit matches in the file at path, none for a
+// file too large or binary.
+func searchFile(path string, opts searchOptions, limit int) ([]SearchMatch, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxSearchFileSize {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		return nil, nil
+	}
+
+	content := string(data)
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	var matches []SearchMatch
+	add := func(start, end, column int) {
+		match := SearchMatch{
+			File:   path,
+			Line:   start + 1,
+			Column: column,
+			Text:   strings.Join(lines[start:end+1], "\n"),
+			Before: lines[max(start-opts.context, 0):start],
+			After:  lines[end+1 : min(end+1+opts.context, len(lines))],
+		}
+		if end > start {
+			match.EndLine = end + 1
+		}
+		matches = append(matches, match)
+	}
+
+	if opts.multiline {
+		for _, loc := range opts.re.FindAllStringIndex(content, limit) {
+			start := strings.Count(content[:loc[0]], "\n")
+			end := start + strings.Count(strings.TrimSuffix(content[loc[0]:loc[1]], "\n"), "\n")
+			lineStart := strings.LastIndex(content[:loc[0]], "\n") + 1
+			add(start, min(end, len(lines)-1), utf8.RuneCountInString(content[lineStart:loc[0]])+1)
+		}
+		return matches, nil
+	}
+
+	for i, line := range lines {
+		if len(matches) >= limit {
+			break
+		}
+		if loc := opts.re.FindStringIndex(line); loc != nil {
+			add(i, i, utf8.RuneCountInString(line[:loc[0]])+1)
+		}
+	}
+	return matches, nil
+}
+
+// formatMatches renders matches as grep does: file:line:text for the
+// lines matched, file-line-text for the context lines around them and,
+// with context, -- between lines that are not next to each other.
+func formatMatches(matches []SearchMatch, context int) string {
+	var b strings.Builder
+	for i := 0; i < len(matches); {
+		file := matches[i].File
+		j := i
+		for j < len(matches) && matches[j].File == file {
+			j++
+		}
+
+		// A line can be the context of one match and part of another
+		lines := make(map[int]string)
+		matched := make(map[int]bool)
+		for _, match := range matches[i:j] {
+			first := match.Line - len(match.Before)
+			for k, text := range match.Before {
+				lines[first+k] = text
+			}
+			for k, text := range strings.Split(match.Text, "\n") {
+				lines[match.Line+k] = text
+				matched[match.Line+k] = true
+			}
+			last := match.Line + strings.Count(match.Text, "\n")
+			for k, text := range match.After {
+				lines[last+1+k] = text
+			}
+		}
+		numbers := make([]int, 0, len(lines))
+		for n := range lines {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+
+		for k, n := range numbers {
+			if context > 0 && b.Len() > 0 && (k == 0 || n != numbers[k-1]+1) {
+				b.WriteString("--\n")
+			}
+			separator := "-"
+			if matched[n] {
+				separator = ":"
+			}
+			fmt.Fprintf(&b, "%s%s%d%s%s\n", file, separator, n, separator, lines[n])
+		}
+		i = j
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
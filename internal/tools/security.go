@@ -0,0 +1,245 @@
+// Package: internal/tools/security.go
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxScanFileSize = 1 << 20
+	maxScanFindings = 200
+)
+
+type securityRule struct {
+	ID       string
+	Category string // "secrets" or "injection"
+	Severity string
+	Pattern  *regexp.Regexp
+	Exts     []string       // Empty matches every file
+	Unless   *regexp.Regexp // Lines also matching this are not reported
+}
+
+var securityRules = []securityRule{
+	{"aws-access-key", "secrets", "critical", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), nil, nil},
+	{"private-key", "secrets", "critical", regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY`), nil, nil},
+	{"github-token", "secrets", "critical", regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b|github_pat_[A-Za-z0-9_]{40,}`), nil, nil},
+	{"slack-token", "secrets", "high", regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}`), nil, nil},
+	{"stripe-key", "secrets", "critical", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{20,}`), nil, nil},
+	{"hardcoded-credential", "secrets", "high", regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)\b["']?\s*[:=]\s*["'][^"'\s]{8,}["']`), nil, nil},
+	{"connection-string-password", "secrets", "high", regexp.MustCompile(`[a-z]+://[^/\s:@]+:[^/\s:@]{4,}@[^\s"']+`), nil, nil},
+
+	{"sql-string-building", "injection", "high", regexp.MustCompile(`(?i)(fmt\.Sprintf|\+|f")\s*\(?\s*["'][^"']*\b(SELECT|INSERT|UPDATE|DELETE)\b[^"']*\b(WHERE|VALUES|SET)\b[^"']*["']?\s*(\+|%|\{)`), nil, nil},
+	{"shell-command-concatenation", "injection", "high", regexp.MustCompile(`exec\.Command\(\s*"(sh|bash|cmd)"\s*,\s*"(-c|/c)"\s*,\s*[^)]*\+`), []string{".go"}, nil},
+	{"python-shell-true", "injection", "high", regexp.MustCompile(`subprocess\.\w+\([^)]*shell\s*=\s*True`), []string{".py"}, nil},
+	{"python-os-system", "injection", "medium", regexp.MustCompile(`os\.(system|popen)\(\s*(f["']|[^"')]*\+)`), []string{".py"}, nil},
+	{"eval", "injection", "high", regexp.MustCompile(`\beval\(`), []string{".js", ".ts", ".jsx", ".tsx", ".py", ".php", ".rb"}, nil},
+	{"unsafe-deserialization", "injection", "high", regexp.MustCompile(`pickle\.loads?\(|yaml\.load\(|Marshal\.load\(|unserialize\(`), nil, regexp.MustCompile(`SafeLoader|safe_load`)},
+	{"dom-xss", "injection", "medium", regexp.MustCompile(`\.innerHTML\s*=|dangerouslySetInnerHTML|document\.write\(`), []string{".js", ".ts", ".jsx", ".tsx", ".html", ".vue", ".svelte"}, nil},
+	{"template-unescaped", "injection", "medium", regexp.MustCompile(`template\.HTML\(`), []string{".go"}, nil},
+	{"tls-verification-disabled", "injection", "high", regexp.MustCompile(`InsecureSkipVerify:\s*true|verify\s*=\s*False|rejectUnauthorized:\s*false`), nil, nil},
+	{"path-traversal", "injection", "medium", regexp.MustCompile(`(os\.(Open|ReadFile)|open)\([^)]*(r\.URL|request\.|req\.(query|params|body))`), nil, nil},
+}
+
+var scanSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "build": true, "dist": true,
+	".git": true, "__pycache__": true, ".venv": true, "venv": true,
+}
+
+// SecurityScanTool - pattern-based secret and injection detection
+type SecurityScanTool struct{}
+
+func (t *SecurityScanTool) Name() string { return "security_scan" }
+
+func (t *SecurityScanTool) Description() string {
+	return "Scan the codebase for hard-coded secrets and injection-prone code patterns, returning file:line findings"
+}
+
+func (t *SecurityScanTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory or file to scan (defaults to the current directory)",
+			},
+			"category": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"all", "secrets", "injection"},
+				"description": "Which checks to run",
+			},
+		},
+	}
+}
+
+type SecurityFinding struct {
+	File     string
+	Line     int
+	Rule     string
+	Category string
+	Severity string
+	Snippet  string
+}
+
+func (t *SecurityScanTool) Execute(args map[string]interface{}) (string, error) {
+	root, _ := args["path"].(string)
+	if root == "" {
+		root = "."
+	}
+	category, _ := args["category"].(string)
+
+	findings, err := ScanSecurity(root, category)
+	if err != nil {
+		return "", err
+	}
+
+	if len(findings) == 0 {
+		return "No findings", nil
+	}
+
+	var out strings.Builder
+	for _, f := range findings {
+		out.WriteString(fmt.Sprintf("%s:%d [%s/%s/%s] %s\n", f.File, f.Line, f.Severity, f.Category, f.Rule, f.Snippet))
+	}
+	if len(findings) >= maxScanFindings {
+		out.WriteString(fmt.Sprintf("... stopped after %d findings\n", maxScanFindings))
+	}
+
+	return out.String(), nil
+}
+
+// ScanSecurity runs the pattern rules over every text file below root.
+func ScanSecurity(root, category string) ([]SecurityFinding, error) {
+	var findings []SecurityFinding
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			if path != root && (scanSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if len(findings) >= maxScanFindings {
+			return filepath.SkipAll
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() > maxScanFileSize {
+			return nil
+		}
+
+		findings = append(findings, scanFile(path, category)...)
+		return nil
+	})
+
+	if len(findings) > maxScanFindings {
+		findings = findings[:maxScanFindings]
+	}
+
+	severity := map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severity[findings[i].Severity] < severity[findings[j].Severity]
+	})
+
+	return findings, err
+}
+
+func scanFile(path, category string) []SecurityFinding {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	var findings []SecurityFinding
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanFileSize)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if lineNum == 1 && strings.ContainsRune(line, 0) {
+			return nil // Binary file
+		}
+
+		findings = append(findings, scanLine(path, ext, line, lineNum, category)...)
+	}
+
+	return findings
+}
+
+// ScanLine runs the pattern rules over a single line of the file at path,
+// e.g. a line added in a diff.
+func ScanLine(path, line string, lineNum int, category string) []SecurityFinding {
+	return scanLine(path, strings.ToLower(filepath.Ext(path)), line, lineNum, category)
+}
+
+func scanLine(path, ext, line string, lineNum int, category string) []SecurityFinding {
+	var findings []SecurityFinding
+
+	for _, rule := range securityRules {
+		if category != "" && category != "all" && rule.Category != category {
+			continue
+		}
+		if len(rule.Exts) > 0 && !containsString(rule.Exts, ext) {
+			continue
+		}
+		if !rule.Pattern.MatchString(line) || (rule.Unless != nil && rule.Unless.MatchString(line)) {
+			continue
+		}
+
+		snippet := strings.TrimSpace(line)
+		if rule.Category == "secrets" {
+			snippet = maskSecret(snippet, rule.Pattern)
+		}
+		if len(snippet) > 160 {
+			snippet = snippet[:157] + "..."
+		}
+
+		findings = append(findings, SecurityFinding{
+			File:     path,
+			Line:     lineNum,
+			Rule:     rule.ID,
+			Category: rule.Category,
+			Severity: rule.Severity,
+			Snippet:  snippet,
+		})
+	}
+
+	return findings
+}
+
+// maskSecret hides all but the first few characters of matched secrets so
+// scan output does not leak them into prompts or logs.
+func maskSecret(line string, pattern *regexp.Regexp) string {
+	return pattern.ReplaceAllStringFunc(line, func(match string) string {
+		if len(match) <= 8 {
+			return strings.Repeat("*", len(match))
+		}
+		return match[:6] + strings.Repeat("*", len(match)-6)
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
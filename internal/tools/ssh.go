@@ -0,0 +1,167 @@
+// Package: internal/tools/ssh.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+// SSHExecutor runs commands on a remote host with the system ssh client.
+// The local project root maps to RemotePath on the host; with Sync set the
+// project is copied there with rsync before each command.
+type SSHExecutor struct {
+	host       string
+	port       int
+	keyFile    string
+	localRoot  string
+	remoteRoot string
+	sync       bool
+	delete     bool
+	exclude    []string
+}
+
+func NewSSHExecutor(cfg config.RemoteConfig, localRoot string) (*SSHExecutor, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("remote.host is required")
+	}
+	if cfg.RemotePath == "" || !path.IsAbs(cfg.RemotePath) {
+		return nil, fmt.Errorf("remote.remote_path must be an absolute path on %s", cfg.Host)
+	}
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, fmt.Errorf("ssh client not found: %w", err)
+	}
+	if cfg.Sync {
+		if _, err := exec.LookPath("rsync"); err != nil {
+			return nil, fmt.Errorf("remote.sync needs rsync: %w", err)
+		}
+	}
+	if cfg.Sync && cfg.SyncDelete && shallowRemotePath(cfg.RemotePath) {
+		return nil, fmt.Errorf("remote.delete would remove everything under %s on %s but the project; set remote_path to the project's own directory", cfg.RemotePath, cfg.Host)
+	}
+
+	if cfg.LocalPath != "" {
+		localRoot = cfg.LocalPath
+	}
+	localRoot, err := filepath.Abs(localRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile := cfg.KeyFile
+	if rest, ok := strings.CutPrefix(keyFile, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		keyFile = filepath.Join(home, rest)
+	}
+
+	exclude := cfg.SyncExclude
+	if exclude == nil {
+		exclude = []string{".git", "node_modules", "target", ".claude-go"}
+	}
+
+	return &SSHExecutor{
+		host:       cfg.Host,
+		port:       cfg.Port,
+		keyFile:    keyFile,
+		localRoot:  localRoot,
+		remoteRoot: cfg.RemotePath,
+		sync:       cfg.Sync,
+		delete:     cfg.SyncDelete,
+		exclude:    exclude,
+	}, nil
+}
+
+func (e *SSHExecutor) Describe() string {
+	return fmt.Sprintf("ssh %s:%s", e.host, e.remoteRoot)
+}
+
+func (e *SSHExecutor) Run(ctx context.Context, command, dir string, stream io.Writer) (string, error) {
+	remoteDir, err := e.RemotePath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if e.sync {
+		if output, err := e.push(ctx); err != nil {
+			return output, fmt.Errorf("sync to %s failed: %w", e.host, err)
+		}
+	}
+
+	script := fmt.Sprintf("cd %s && sh -c %s", shellQuote(remoteDir), shellQuote(command))
+	args := append(e.sshArgs(), e.host, script)
+
+	return runStreaming(exec.CommandContext(ctx, "ssh", args...), stream)
+}
+
+// RemotePath maps a local directory (absolute, or relative to the project
+// root) to its location on the remote host.
+func (e *SSHExecutor) RemotePath(dir string) (string, error) {
+	if dir == "" {
+		return e.remoteRoot, nil
+	}
+
+	local := dir
+	if !filepath.IsAbs(local) {
+		local = filepath.Join(e.localRoot, local)
+	}
+
+	rel, err := filepath.Rel(e.localRoot, local)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s and has no remote mapping", dir, e.localRoot)
+	}
+
+	return path.Join(e.remoteRoot, filepath.ToSlash(rel)), nil
+}
+
+func (e *SSHExecutor) sshArgs() []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if e.keyFile != "" {
+		args = append(args, "-i", e.keyFile)
+	}
+	if e.port != 0 {
+		args = append(args, "-p", strconv.Itoa(e.port))
+	}
+	return args
+}
+
+// shallowRemotePath reports whether dir is the root, a directory just
+// below it or a home directory, which hold far more than a project.
+func shallowRemotePath(dir string) bool {
+	parts := strings.Split(strings.Trim(path.Clean(dir), "/"), "/")
+	if len(parts) < 2 || parts[0] == "" {
+		return true
+	}
+	return len(parts) == 2 && (parts[0] == "home" || parts[0] == "Users")
+}
+
+func (e *SSHExecutor) push(ctx context.Context) (string, error) {
+	// rsync splits the command at spaces outside quotes
+	ssh := "ssh " + quoteAll(e.sshArgs())
+
+	args := []string{"-az", "-e", ssh}
+	if e.delete {
+		args = append(args, "--delete")
+	}
+	for _, pattern := range e.exclude {
+		args = append(args, "--exclude", pattern)
+	}
+	args = append(args, e.localRoot+"/", e.host+":"+e.remoteRoot+"/")
+
+	output, err := exec.CommandContext(ctx, "rsync", args...).CombinedOutput()
+	return string(output), err
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
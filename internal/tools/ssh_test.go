@@ -0,0 +1,25 @@
+package tools
+
+import "testing"
+
+func TestShallowRemotePath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{dir: "/", want: true},
+		{dir: "/root", want: true},
+		{dir: "/srv/", want: true},
+		{dir: "/home/builder", want: true},
+		{dir: "/Users/builder/", want: true},
+		{dir: "/home/builder/src/..", want: true},
+		{dir: "/srv/app", want: false},
+		{dir: "/home/builder/src", want: false},
+		{dir: "/opt/builds/myproject", want: false},
+	}
+	for _, tt := range tests {
+		if got := shallowRemotePath(tt.dir); got != tt.want {
+			t.Errorf("shallowRemotePath(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package: internal/tools/structural.go
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// StructuralSearchTool searches code by its syntax tree rather than its
+// text, with tree-sitter queries: a template of the language's, or one of
+// the model's own. It needs claude-go built with cgo (see
+// structural_cgo.go); without, it is not registered.
+type StructuralSearchTool struct{}
+
+func (t *StructuralSearchTool) Name() string { return "structural_search" }
+
+func (t *StructuralSearchTool) Description() string {
+	return "Search code by syntax rather than text, e.g. every call of db.Query whose argument is a string concatenation, using tree-sitter. Use a template, filled in with name, or a tree-sitter query of your own whose @match capture is reported. Languages: " + strings.Join(structuralLanguageNames(), ", ")
+}
+
+func (t *StructuralSearchTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"language": map[string]interface{}{
+				"type":        "string",
+				"enum":        structuralLanguageNames(),
+				"description": "Language of the files to search",
+			},
+			"template": map[string]interface{}{
+				"type":        "string",
+				"enum":        structuralTemplateNames(),
+				"description": structuralTemplateHelp(),
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "What the template looks for: the function called (e.g. Query or db.Query), the function or type declared, text in the string or import. Optional except for calls",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "A tree-sitter query instead of a template, e.g. (call_expression function: (selector_expression) @fn (#eq? @fn \"db.Query\")) @match",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory or file to search (the working directory by default)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     1,
+				"description": fmt.Sprintf("Stop after this many matches (default %d)", defaultMaxMatches),
+			},
+		},
+		"required": []string{"language"},
+	}
+}
+
+// structuralMatch is a node a structural search found.
+type structuralMatch struct {
+	File   string
+	Line   int
+	Column int
+	Text   string
+}
+
+func (t *StructuralSearchTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+// ExecuteContext searches until it has max_results matches, or ctx is
+// cancelled.
+func (t *StructuralSearchTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	language, _ := args["language"].(string)
+	query, err := structuralQuery(language, args)
+	if err != nil {
+		return "", err
+	}
+	root, _ := args["path"].(string)
+	if root == "" {
+		root = "."
+	}
+	limit := defaultMaxMatches
+	if maxResults, ok := args["max_results"].(float64); ok && maxResults > 0 {
+		limit = int(maxResults)
+	}
+
+	matches, truncated, err := structuralSearch(ctx, language, query, root, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "No matches found", nil
+	}
+	var b strings.Builder
+	for _, match := range matches {
+		// A match is shown by its first line
+		text, rest, multiline := strings.Cut(match.Text, "\n")
+		text = strings.TrimRight(text, "\r")
+		if multiline && strings.TrimSpace(rest) != "" {
+			text += " …"
+		}
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", match.File, match.Line, match.Column, text)
+	}
+	if truncated {
+		fmt.Fprintf(&b, "(stopped after %d matches; narrow the search or raise max_results)\n", limit)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// structuralLanguage is a language structural_search can parse.
+type structuralLanguage struct {
+	extensions []string
+	templates  map[string]structuralTemplate
+}
+
+// structuralTemplate is a query for something commonly looked for. With
+// a name, the text of its capture must match pattern, in which %s is the
+// name as a regular expression.
+type structuralTemplate struct {
+	query     string
+	capture   string
+	pattern   string
+	lastPart  bool // The name's part after its last dot is what is matched
+	needsName bool
+}
+
+// Name patterns of the templates
+const (
+	calledName = `(^|\.)%s$`
+	exactName  = `^%s$`
+	nameWithin = `%s`
+)
+
+var structuralLanguages = map[string]structuralLanguage{
+	"go": {
+		extensions: []string{".go"},
+		templates: map[string]structuralTemplate{
+			"call":             {query: `(call_expression function: (_) @name) @match`, capture: "name", pattern: calledName, needsName: true},
+			"call_with_concat": {query: `(call_expression function: (_) @name arguments: (argument_list (binary_expression operator: "+"))) @match`, capture: "name", pattern: calledName, needsName: true},
+			"function":         {query: `[(function_declaration name: (identifier) @name) (method_declaration name: (field_identifier) @name)] @match`, capture: "name", pattern: exactName},
+			"type":             {query: `(type_spec name: (type_identifier) @name) @match`, capture: "name", pattern: exactName},
+			"string":           {query: `[(interpreted_string_literal) (raw_string_literal)] @match`, capture: "match", pattern: nameWithin},
+			"import":           {query: `(import_spec path: (_) @name) @match`, capture: "name", pattern: nameWithin},
+			"ignored_error":    {query: `[(assignment_statement left: (expression_list (identifier) @blank .) right: (expression_list (call_expression))) (short_var_declaration left: (expression_list (identifier) @blank .) right: (expression_list (call_expression)))] @match (#eq? @blank "_")`},
+			"defer_in_loop":    {query: `(for_statement body: (block (statement_list (defer_statement) @match)))`},
+		},
+	},
+	"python": {
+		extensions: []string{".py", ".pyi"},
+		templates: map[string]structuralTemplate{
+			"call":             {query: `(call function: (_) @name) @match`, capture: "name", pattern: calledName, needsName: true},
+			"call_with_concat": {query: `(call function: (_) @name arguments: (argument_list [(binary_operator) (string (interpolation))])) @match`, capture: "name", pattern: calledName, needsName: true},
+			"function":         {query: `(function_definition name: (identifier) @name) @match`, capture: "name", pattern: exactName},
+			"type":             {query: `(class_definition name: (identifier) @name) @match`, capture: "name", pattern: exactName},
+			"string":           {query: `(string) @match`, capture: "match", pattern: nameWithin},
+			"import":           {query: `[(import_statement) (import_from_statement)] @match`, capture: "match", pattern: nameWithin},
+		},
+	},
+	"java": {
+		extensions: []string{".java"},
+		templates: map[string]structuralTemplate{
+			"call":             {query: `(method_invocation name: (identifier) @name) @match`, capture: "name", pattern: exactName, lastPart: true, needsName: true},
+			"call_with_concat": {query: `(method_invocation name: (identifier) @name arguments: (argument_list (binary_expression operator: "+"))) @match`, capture: "name", pattern: exactName, lastPart: true, needsName: true},
+			"function":         {query: `[(method_declaration name: (identifier) @name) (constructor_declaration name: (identifier) @name)] @match`, capture: "name", pattern: exactName},
+			"type":             {query: `[(class_declaration name: (identifier) @name) (interface_declaration name: (identifier) @name) (enum_declaration name: (identifier) @name) (record_declaration name: (identifier) @name)] @match`, capture: "name", pattern: exactName},
+			"string":           {query: `(string_literal) @match`, capture: "match", pattern: nameWithin},
+			"import":           {query: `(import_declaration) @match`, capture: "match", pattern: nameWithin},
+		},
+	},
+}
+
+// structuralTemplateDescriptions say what each template finds.
+var structuralTemplateDescriptions = map[string]string{
+	"call":             "calls of the function or method name",
+	"call_with_concat": "calls of name with a string concatenation (or, in Python, an f-string) among the arguments",
+	"function":         "function and method declarations, those called name if given",
+	"type":             "type and class declarations, those called name if given",
+	"string":           "string literals, those containing name if given",
+	"import":           "imports, those containing name if given",
+	"ignored_error":    "(Go) calls whose last result, usually an error, is assigned to _",
+	"defer_in_loop":    "(Go) defer statements directly inside a for loop",
+}
+
+func structuralLanguageNames() []string {
+	names := make([]string, 0, len(structuralLanguages))
+	for name := range structuralLanguages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func structuralTemplateNames() []string {
+	names := make([]string, 0, len(structuralTemplateDescriptions))
+	for name := range structuralTemplateDescriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func structuralTemplateHelp() string {
+	var help []string
+	for _, name := range structuralTemplateNames() {
+		help = append(help, name+": "+structuralTemplateDescriptions[name])
+	}
+	return "A ready-made query: " + strings.Join(help, "; ")
+}
+
+// structuralQuery returns the tree-sitter query a call to
+// structural_search asks for.
+func structuralQuery(language string, args map[string]interface{}) (string, error) {
+	lang, ok := structuralLanguages[language]
+	if !ok {
+		return "", fmt.Errorf("unsupported language %q (want %s)", language, strings.Join(structuralLanguageNames(), ", "))
+	}
+	if query, _ := args["query"].(string); query != "" {
+		return query, nil
+	}
+
+	templateName, _ := args["template"].(string)
+	if templateName == "" {
+		return "", fmt.Errorf("either template or query is required")
+	}
+	template, ok := lang.templates[templateName]
+	if !ok {
+		names := make([]string, 0, len(lang.templates))
+		for name := range lang.templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("no %s template for %s (%s has %s)", templateName, language, language, strings.Join(names, ", "))
+	}
+
+	name, _ := args["name"].(string)
+	if name == "" || template.capture == "" {
+		if template.needsName {
+			return "", fmt.Errorf("the %s template needs a name", templateName)
+		}
+		return template.query, nil
+	}
+	if template.lastPart {
+		name = name[strings.LastIndex(name, ".")+1:]
+	}
+	pattern := fmt.Sprintf(template.pattern, regexp.QuoteMeta(name))
+	// The pattern is a string of the query, where \ and " are escaped
+	pattern = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(pattern)
+	return fmt.Sprintf(`(%s (#match? @%s "%s"))`, template.query, template.capture, pattern), nil
+}
@@ -0,0 +1,124 @@
+// Package: internal/tools/structural_cgo.go
+
+//go:build cgo
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// structuralSearchAvailable is whether structural_search can parse code,
+// which tree-sitter, in C, needs cgo for.
+const structuralSearchAvailable = true
+
+func structuralGrammar(language string) *sitter.Language {
+	switch language {
+	case "go":
+		return sitter.NewLanguage(tree_sitter_go.Language())
+	case "python":
+		return sitter.NewLanguage(tree_sitter_python.Language())
+	case "java":
+		return sitter.NewLanguage(tree_sitter_java.Language())
+	}
+	return nil
+}
+
+// structuralSearch returns up to limit matches of query in the files of
+// language under root, and whether it stopped at limit.
+func structuralSearch(ctx context.Context, language, query, root string, limit int) ([]structuralMatch, bool, error) {
+	grammar := structuralGrammar(language)
+	if grammar == nil {
+		return nil, false, fmt.Errorf("unsupported language %q", language)
+	}
+	q, qerr := sitter.NewQuery(grammar, query)
+	if qerr != nil {
+		return nil, false, fmt.Errorf("invalid query: %s", qerr.Error())
+	}
+	defer q.Close()
+	if len(q.CaptureNames()) == 0 {
+		return nil, false, fmt.Errorf("invalid query: it captures nothing; mark what to report with @match")
+	}
+	// What a query reports is its @match capture, else its first one
+	capture, ok := q.CaptureIndexForName("match")
+	if !ok {
+		capture = 0
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(grammar); err != nil {
+		return nil, false, err
+	}
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+
+	extensions := make(map[string]bool)
+	for _, ext := range structuralLanguages[language].extensions {
+		extensions[ext] = true
+	}
+
+	var matches []structuralMatch
+	truncated := false
+	err := walkFiles(ctx, root, "", func(path string) bool {
+		if !extensions[filepath.Ext(path)] {
+			return true
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxSearchFileSize {
+			return true
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return true
+		}
+		tree := parser.Parse(source, nil)
+		if tree == nil {
+			return true
+		}
+		defer tree.Close()
+
+		// A node can match more than one pattern of the query
+		seen := make(map[[2]uint]bool)
+		found := cursor.Matches(q, tree.RootNode(), source)
+		for match := found.Next(); match != nil; match = found.Next() {
+			for _, c := range match.Captures {
+				if uint(c.Index) != capture {
+					continue
+				}
+				span := [2]uint{c.Node.StartByte(), c.Node.EndByte()}
+				if seen[span] {
+					continue
+				}
+				seen[span] = true
+				start := c.Node.StartPosition()
+				matches = append(matches, structuralMatch{
+					File:   path,
+					Line:   int(start.Row) + 1,
+					Column: int(start.Column) + 1,
+					Text:   c.Node.Utf8Text(source),
+				})
+				if len(matches) >= limit {
+					truncated = true
+					return false
+				}
+			}
+			if ctx.Err() != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	return matches, truncated, err
+}
@@ -0,0 +1,17 @@
+// Package: internal/tools/structural_nocgo.go
+
+//go:build !cgo
+
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// structuralSearchAvailable is false without cgo, which tree-sitter needs.
+const structuralSearchAvailable = false
+
+func structuralSearch(ctx context.Context, language, query, root string, limit int) ([]structuralMatch, bool, error) {
+	return nil, false, fmt.Errorf("structural_search needs claude-go built with cgo")
+}
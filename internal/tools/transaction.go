@@ -0,0 +1,225 @@
+// Package: internal/tools/transaction.go
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transaction stages file changes in memory and applies them all-or-nothing.
+type Transaction struct {
+	files map[string]*stagedFile
+	order []string
+}
+
+type stagedFile struct {
+	path     string // Absolute
+	name     string // As first staged, for display
+	original []byte
+	existed  bool
+	mode     os.FileMode
+	content  []byte
+	applied  bool
+}
+
+func NewTransaction() *Transaction {
+	return &Transaction{files: make(map[string]*stagedFile)}
+}
+
+// Current returns the content path will have once the transaction applies,
+// taking earlier staged changes into account.
+func (t *Transaction) Current(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if staged, ok := t.files[abs]; ok {
+		return staged.content, nil
+	}
+	return os.ReadFile(abs)
+}
+
+// Stage sets the content path will have once the transaction applies.
+// Paths are resolved against the working directory, so a file staged as
+// a relative and an absolute path is still staged once.
+func (t *Transaction) Stage(path string, content []byte) error {
+	name := filepath.Clean(path)
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	if staged, ok := t.files[path]; ok {
+		staged.content = content
+		return nil
+	}
+
+	staged := &stagedFile{path: path, name: name, content: content, mode: 0644}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		staged.original = original
+		staged.existed = true
+		staged.mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	t.files[path] = staged
+	t.order = append(t.order, path)
+	return nil
+}
+
+// StageEdit stages an edit of edit_file's kind, made to the file as the
+// changes staged before it leave it, and returns what it did.
+func (t *Transaction) StageEdit(edit fileEdit) (string, error) {
+	exists := true
+	current, err := t.Current(edit.Path)
+	if os.IsNotExist(err) {
+		exists = false
+	} else if err != nil {
+		return "", err
+	}
+
+	updated, summary, err := edit.apply(string(current), exists)
+	if err != nil {
+		return "", err
+	}
+	return summary, t.Stage(edit.Path, []byte(updated))
+}
+
+// Diff returns the staged changes as a unified diff, file by file.
+func (t *Transaction) Diff() string {
+	var b strings.Builder
+	for _, path := range t.order {
+		staged := t.files[path]
+		b.WriteString(unifiedDiff(staged.name, string(staged.original), string(staged.content), !staged.existed))
+	}
+	return b.String()
+}
+
+// Paths returns the staged files as they were named when staged.
+func (t *Transaction) Paths() []string {
+	names := make([]string, len(t.order))
+	for i, path := range t.order {
+		names[i] = t.files[path].name
+	}
+	return names
+}
+
+// Apply writes every staged file. If any write fails, files already written
+// are restored before the error is returned.
+func (t *Transaction) Apply() error {
+	for _, path := range t.order {
+		staged := t.files[path]
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Rollback()
+			return err
+		}
+
+		if err := writeFileAtomic(path, staged.content, staged.mode); err != nil {
+			t.Rollback()
+			return fmt.Errorf("failed to write %s: %w", staged.name, err)
+		}
+		staged.applied = true
+	}
+
+	return nil
+}
+
+// Rollback restores every applied file to its original state.
+func (t *Transaction) Rollback() error {
+	var errs []string
+
+	for i := len(t.order) - 1; i >= 0; i-- {
+		staged := t.files[t.order[i]]
+		if !staged.applied {
+			continue
+		}
+
+		var err error
+		if staged.existed {
+			err = writeFileAtomic(staged.path, staged.original, staged.mode)
+		} else if target, linkErr := resolveLink(staged.path); linkErr != nil {
+			err = linkErr
+		} else {
+			err = os.Remove(target)
+		}
+
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		staged.applied = false
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("rollback incomplete: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the content of path through a temporary file
+// renamed over it. A symlink is followed, so the file it points to is
+// written and the link kept.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	path, err := resolveLink(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// maxLinkDepth bounds how many symlinks resolveLink follows, as the kernel
+// does, so a cycle of links ends in an error.
+const maxLinkDepth = 40
+
+// resolveLink returns the file path names once symlinks are followed, even
+// when the last one points to a file that does not exist yet.
+func resolveLink(path string) (string, error) {
+	for range maxLinkDepth {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("%s: too many levels of symbolic links", path)
+}
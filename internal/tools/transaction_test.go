@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStageNormalizesPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := NewTransaction()
+	if err := tx.Stage("main.go", []byte("package main\n\nfunc a() {}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Stage(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc b() {}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if paths := tx.Paths(); len(paths) != 1 || paths[0] != "main.go" {
+		t.Fatalf("Paths() = %v, want main.go staged once", paths)
+	}
+	if err := tx.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile("main.go"); string(got) != "package main\n\nfunc b() {}\n" {
+		t.Errorf("main.go = %q, want the last staged content", got)
+	}
+}
+
+func TestApplyWritesThroughSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "shared", "config.go")
+	link := filepath.Join(dir, "config.go")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("package config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("shared", "config.go"), link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tx := NewTransaction()
+	if err := tx.Stage(link, []byte("package config\n\nconst X = 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("config.go is no longer a symlink (%v)", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "package config\n\nconst X = 1\n" {
+		t.Errorf("link target = %q, want the staged content", got)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "package config\n" {
+		t.Errorf("link target after rollback = %q, want the original", got)
+	}
+}
+
+func TestMultiEditIsAllOrNothing(t *testing.T) {
+	edit := func(path, oldString, newString string) map[string]interface{} {
+		return map[string]interface{}{"path": path, "old_string": oldString, "new_string": newString}
+	}
+
+	tests := []struct {
+		name    string
+		edits   []interface{}
+		want    map[string]string // Contents afterwards, by file
+		wantErr string
+	}{
+		{
+			name:  "edits across files",
+			edits: []interface{}{edit("a.go", "a", "A"), edit("b.go", "b", "B")},
+			want:  map[string]string{"a.go": "A\n", "b.go": "B\n"},
+		},
+		{
+			name:  "later edit sees the earlier one",
+			edits: []interface{}{edit("a.go", "a", "A"), edit("a.go", "A", "AA")},
+			want:  map[string]string{"a.go": "AA\n", "b.go": "b\n"},
+		},
+		{
+			name:  "create, then edit what was created",
+			edits: []interface{}{edit("c.go", "", "c\n"), edit("c.go", "c", "C")},
+			want:  map[string]string{"a.go": "a\n", "b.go": "b\n", "c.go": "C\n"},
+		},
+		{
+			name:    "last edit fails",
+			edits:   []interface{}{edit("a.go", "a", "A"), edit("b.go", "missing", "x")},
+			want:    map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			wantErr: "edit 2: b.go: old_string not found",
+		},
+		{
+			name:    "edit depends on one that was not made",
+			edits:   []interface{}{edit("a.go", "a", "A"), edit("a.go", "a", "B")},
+			want:    map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			wantErr: "edit 2",
+		},
+		{
+			name:    "creating a file twice",
+			edits:   []interface{}{edit("c.go", "", "c\n"), edit("c.go", "", "d\n")},
+			want:    map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			wantErr: "already exists",
+		},
+		{
+			name:    "edit without a path",
+			edits:   []interface{}{edit("a.go", "a", "A"), map[string]interface{}{"new_string": "x"}},
+			want:    map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			wantErr: "edit 2: path is required",
+		},
+		{
+			name:    "no edits",
+			edits:   []interface{}{},
+			want:    map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			wantErr: "edits must not be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			for name, content := range map[string]string{"a.go": "a\n", "b.go": "b\n"} {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := (&MultiEditTool{}).Execute(map[string]interface{}{"edits": tt.edits})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+
+			for name, want := range tt.want {
+				if got, _ := os.ReadFile(name); string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if _, err := os.Stat("c.go"); tt.want["c.go"] == "" && err == nil {
+				t.Error("c.go was created")
+			}
+		})
+	}
+}
+
+func TestApplyRollsBackOnWriteFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.go", []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := NewTransaction()
+	for _, path := range []string{"a.go", "new.go", filepath.Join("pkg", "b.go")} {
+		if err := tx.Stage(path, []byte("package main\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file appears where the directory of the last change would go
+	if err := os.WriteFile("pkg", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Apply(); err == nil {
+		t.Fatal("Apply() succeeded, want the write under pkg to fail")
+	}
+	if got, _ := os.ReadFile("a.go"); string(got) != "a\n" {
+		t.Errorf("a.go = %q, want it restored", got)
+	}
+	if _, err := os.Stat("new.go"); !os.IsNotExist(err) {
+		t.Errorf("new.go left behind (%v), want it removed", err)
+	}
+}
+
+func TestStageRefusesDirectories(t *testing.T) {
+	if err := NewTransaction().Stage(t.TempDir(), []byte("x")); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("Stage(directory) error = %v, want it refused", err)
+	}
+}
+
+func TestStageEditMatchesLikeEditFile(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		edit    fileEdit
+		want    string
+		wantErr string
+	}{
+		{name: "LF string in a CRLF file", edit: fileEdit{OldString: str("a\nb"), NewString: "a\nB"}, want: "a\r\nB\r\nb\r\n"},
+		{name: "ambiguous", edit: fileEdit{OldString: str("b"), NewString: "B"}, wantErr: "matches 2 times"},
+		{name: "replace_all", edit: fileEdit{OldString: str("b"), NewString: "B", ReplaceAll: true}, want: "a\r\nB\r\nB\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "crlf.txt")
+			if err := os.WriteFile(path, []byte("a\r\nb\r\nb\r\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.edit.Path = path
+
+			tx := NewTransaction()
+			_, err := tx.StageEdit(tt.edit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("StageEdit() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := tx.Current(path); string(got) != tt.want {
+				t.Errorf("staged = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
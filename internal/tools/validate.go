@@ -0,0 +1,57 @@
+// Package: internal/tools/validate.go
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+)
+
+// ArgumentsError is why the arguments of a call do not match the tool's
+// Parameters schema: every problem, with the argument it is in, so the
+// model can correct the call.
+type ArgumentsError struct {
+	Tool     string
+	Problems []schema.ValidationError
+}
+
+func (e *ArgumentsError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.Error()
+	}
+	return fmt.Sprintf("invalid arguments for %s:\n- %s\nCall it again with arguments that match its parameters.", e.Tool, strings.Join(problems, "\n- "))
+}
+
+// validateArgs checks args against the tool's Parameters schema, as JSON
+// would carry them. A schema that cannot be read checks nothing, and
+// keywords the schema package does not know are ignored.
+func validateArgs(tool Tool, args map[string]interface{}) error {
+	params := tool.Parameters()
+	if params == nil {
+		return nil
+	}
+	s, err := schema.Normalize(params)
+	if err != nil {
+		return nil
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments for %s: %w", tool.Name(), err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid arguments for %s: %w", tool.Name(), err)
+	}
+
+	if problems := schema.Validate(s, value); len(problems) > 0 {
+		return &ArgumentsError{Tool: tool.Name(), Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingTool has edit_file-like parameters and counts its runs.
+type recordingTool struct {
+	params interface{}
+	runs   int
+}
+
+func (t *recordingTool) Name() string            { return "record" }
+func (t *recordingTool) Description() string     { return "Records its calls" }
+func (t *recordingTool) Parameters() interface{} { return t.params }
+func (t *recordingTool) Execute(args map[string]interface{}) (string, error) {
+	t.runs++
+	return "ok", nil
+}
+
+func TestArgumentsAreValidatedBeforeRunning(t *testing.T) {
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":       map[string]interface{}{"type": "string"},
+			"start_line": map[string]interface{}{"type": "integer", "minimum": 1},
+			"mode":       map[string]interface{}{"type": "string", "enum": []string{"read", "write"}},
+		},
+		"required": []string{"path"},
+	}
+
+	tests := []struct {
+		name    string
+		params  interface{}
+		args    map[string]interface{}
+		wantErr []string // Problems the error lists
+	}{
+		{name: "valid", params: params, args: map[string]interface{}{"path": "a.go", "start_line": 3, "mode": "read"}},
+		{name: "no schema", args: map[string]interface{}{"anything": true}},
+		{name: "unreadable schema checks nothing", params: map[string]interface{}{"bad": make(chan int)}, args: nil},
+		{name: "no arguments", params: params, args: nil, wantErr: []string{`$: missing required property "path"`}},
+		{name: "wrong type", params: params, args: map[string]interface{}{"path": 42}, wantErr: []string{"$.path: expected string, got integer"}},
+		{name: "Go int checked as JSON number", params: params, args: map[string]interface{}{"path": "a.go", "start_line": 0}, wantErr: []string{"$.start_line: value 0 is less than minimum 1"}},
+		{name: "fraction for an integer", params: params, args: map[string]interface{}{"path": "a.go", "start_line": 1.5}, wantErr: []string{"$.start_line: expected integer, got number"}},
+		{name: "outside enum", params: params, args: map[string]interface{}{"path": "a.go", "mode": "append"}, wantErr: []string{"$.mode: value append is not one of [read write]"}},
+		{
+			name:    "every problem at once",
+			params:  params,
+			args:    map[string]interface{}{"start_line": "3", "mode": 1},
+			wantErr: []string{`missing required property "path"`, "$.mode: expected string", "$.start_line: expected integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &recordingTool{params: tt.params}
+			r := NewRegistry()
+			r.Register(tool)
+
+			_, err := r.ExecuteResult(context.Background(), "record", tt.args, nil)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("ExecuteResult() error = %v", err)
+				}
+				if tool.runs != 1 {
+					t.Errorf("tool ran %d times, want once", tool.runs)
+				}
+				return
+			}
+
+			var argsErr *ArgumentsError
+			if !errors.As(err, &argsErr) {
+				t.Fatalf("ExecuteResult() error = %v, want an *ArgumentsError", err)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+			if len(argsErr.Problems) != len(tt.wantErr) {
+				t.Errorf("%d problems (%v), want %d", len(argsErr.Problems), argsErr.Problems, len(tt.wantErr))
+			}
+			if tool.runs != 0 {
+				t.Error("the tool ran with invalid arguments")
+			}
+		})
+	}
+}
+
+func TestArgumentsErrorTellsModelToRetry(t *testing.T) {
+	err := validateArgs(&recordingTool{params: map[string]interface{}{"type": "object", "required": []string{"path"}}}, nil)
+	want := "invalid arguments for record:\n- $: missing required property \"path\"\nCall it again with arguments that match its parameters."
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
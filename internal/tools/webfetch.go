@@ -0,0 +1,563 @@
+// Package: internal/tools/webfetch.go
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const (
+	// defaultFetchTokens is how much of a page web_fetch returns unless
+	// told otherwise, at 4 characters per token.
+	defaultFetchTokens = 8000
+	// maxFetchSize is how much of a response is read.
+	maxFetchSize = 5 * 1024 * 1024
+	// fetchTimeout bounds a request, redirects included.
+	fetchTimeout = 30 * time.Second
+	// webCacheTTL is how long a fetched page is answered from the cache.
+	webCacheTTL = time.Hour
+)
+
+// WebFetchTool downloads a web page for the model to read, such as the
+// docs of a library it is about to use: HTML is converted to markdown,
+// the result cut to a token budget, and pages are cached on disk for an
+// hour so reading on through a long one costs no more requests.
+type WebFetchTool struct {
+	client   *http.Client // publicClient unless set
+	cacheDir string       // ~/.claude-go/cache/web unless set
+}
+
+// publicClient fetches from public addresses only, so the model cannot
+// have web_fetch reach the machine itself, the local network or a cloud
+// metadata service (169.254.169.254). The address is checked as it is
+// dialed, after name resolution, so a redirect or a name that resolves
+// to a private address is refused too. It uses no proxy, which would
+// make the proxy's address the only one checked.
+var publicClient = &http.Client{
+	Timeout: fetchTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: fetchTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if !isPublicAddr(addrPort.Addr()) {
+					return fmt.Errorf("%s is not a public address", addrPort.Addr())
+				}
+				return nil
+			},
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598), which some clouds
+// serve their metadata from.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddr reports whether addr is reachable on the internet, rather
+// than loopback, private, link-local, multicast or unspecified.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+func (t *WebFetchTool) Name() string { return "web_fetch" }
+
+func (t *WebFetchTool) Description() string {
+	return "Fetch a web page (http or https) and return it as markdown, e.g. a library's documentation. Long pages are truncated to max_tokens; the result says which offset to pass to read on. Pages are cached for an hour"
+}
+
+func (t *WebFetchTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "URL to fetch",
+			},
+			"max_tokens": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     100,
+				"description": fmt.Sprintf("Most of the page to return, in tokens (default %d)", defaultFetchTokens),
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"description": "Where to start reading, as given by a truncated result",
+			},
+			"refresh": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Fetch the page again rather than use the cached copy",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// webPage is a fetched page as it is cached: its content converted to
+// markdown, or as it came for text that is not HTML.
+type webPage struct {
+	URL       string    `json:"url"` // After redirects
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// URLs reports the page a call fetches, unless it is answered from the
+// cache.
+func (t *WebFetchTool) URLs(args map[string]interface{}) []string {
+	rawURL, _ := args["url"].(string)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	if refresh, _ := args["refresh"].(bool); !refresh {
+		if _, cached := t.cached(u.String()); cached {
+			return nil
+		}
+	}
+	return []string{u.String()}
+}
+
+func (t *WebFetchTool) Execute(args map[string]interface{}) (string, error) {
+	return t.ExecuteContext(context.Background(), args, nil)
+}
+
+func (t *WebFetchTool) ExecuteContext(ctx context.Context, args map[string]interface{}, stream io.Writer) (string, error) {
+	rawURL, _ := args["url"].(string)
+	if rawURL == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid url %q: want an http or https URL", rawURL)
+	}
+	maxTokens := defaultFetchTokens
+	if n, ok := args["max_tokens"].(float64); ok && n > 0 {
+		maxTokens = int(n)
+	}
+	offset := 0
+	if n, ok := args["offset"].(float64); ok && n > 0 {
+		offset = int(n)
+	}
+	refresh, _ := args["refresh"].(bool)
+
+	var storeErr error
+	page, cached := t.cached(u.String())
+	if !cached || refresh {
+		if page, err = t.fetch(ctx, u); err != nil {
+			return "", err
+		}
+		storeErr = t.store(u.String(), page)
+	}
+
+	if offset > len(page.Content) {
+		return "", fmt.Errorf("offset %d is past the end of the page (%d)", offset, len(page.Content))
+	}
+	text, next := truncatePage(page.Content, offset, maxTokens)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "URL: %s\n", page.URL)
+	if page.Title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", page.Title)
+	}
+	if cached && !refresh {
+		fmt.Fprintf(&b, "Cached: fetched %s ago\n", time.Since(page.FetchedAt).Round(time.Second))
+	}
+	if storeErr != nil {
+		fmt.Fprintf(&b, "Not cached: %v\n", storeErr)
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+	if next < len(page.Content) {
+		fmt.Fprintf(&b, "\n\n(truncated at about %d of %d tokens; call again with offset %d to read on)", next/4, len(page.Content)/4, next)
+	}
+	return b.String(), nil
+}
+
+// truncatePage returns what of content, from offset, fits in maxTokens,
+// cut at the end of a line if there is one, and where it stopped.
+func truncatePage(content string, offset, maxTokens int) (string, int) {
+	end := offset + maxTokens*4
+	if end >= len(content) {
+		return content[offset:], len(content)
+	}
+	if i := strings.LastIndex(content[offset:end], "\n"); i > 0 {
+		end = offset + i + 1
+	}
+	for end > offset && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return strings.TrimRight(content[offset:end], "\n"), end
+}
+
+func (t *WebFetchTool) fetch(ctx context.Context, u *url.URL) (webPage, error) {
+	client := t.client
+	if client == nil {
+		client = publicClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return webPage{}, err
+	}
+	req.Header.Set("User-Agent", "claude-go")
+	req.Header.Set("Accept", "text/html, text/markdown, text/plain;q=0.9, */*;q=0.5")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return webPage{}, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webPage{}, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize))
+	if err != nil {
+		return webPage{}, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+
+	page := webPage{URL: resp.Request.URL.String(), FetchedAt: time.Now()}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title, page.Content = htmlToMarkdown(body, resp.Request.URL)
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"), mediaType == "application/javascript":
+		page.Content = strings.ToValidUTF8(string(body), "�")
+	default:
+		return webPage{}, fmt.Errorf("%s is %s, not a page that can be read as text", u, mediaType)
+	}
+	return page, nil
+}
+
+func (t *WebFetchTool) cachePath(rawURL string) string {
+	dir := t.cacheDir
+	if dir == "" {
+		configDir, err := config.Dir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(configDir, "cache", "web")
+	}
+	return filepath.Join(dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(rawURL))))
+}
+
+// cached returns the page at rawURL if it was fetched within webCacheTTL.
+func (t *WebFetchTool) cached(rawURL string) (webPage, bool) {
+	var page webPage
+	path := t.cachePath(rawURL)
+	if path == "" {
+		return page, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &page) != nil {
+		return page, false
+	}
+	return page, time.Since(page.FetchedAt) < webCacheTTL
+}
+
+// store caches page, readable by the user alone since a page can hold
+// whatever its URL gave access to.
+func (t *WebFetchTool) store(rawURL string, page webPage) error {
+	path := t.cachePath(rawURL)
+	if path == "" {
+		return fmt.Errorf("no cache directory")
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+var (
+	spaceRun     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLineRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown returns the title of an HTML page and its main content,
+// its <main> or <article> if it has one, as markdown. Links and images
+// are made absolute against base; scripts, styles and navigation are left
+// out.
+func htmlToMarkdown(body []byte, base *url.URL) (string, string) {
+	doc, err := html.Parse(strings.NewReader(strings.ToValidUTF8(string(body), "�")))
+	if err != nil {
+		return "", strings.ToValidUTF8(string(body), "�")
+	}
+
+	title := ""
+	if n := findElement(doc, atom.Title); n != nil {
+		title = strings.TrimSpace(spaceRun.ReplaceAllString(textContent(n), " "))
+	}
+	root := findElement(doc, atom.Main)
+	if root == nil {
+		root = findElement(doc, atom.Article)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	c := &markdownConverter{base: base}
+	markdown := c.children(root)
+
+	lines := strings.Split(markdown, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	markdown = blankLineRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return title, strings.TrimSpace(markdown)
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// markdownConverter renders HTML nodes as markdown.
+type markdownConverter struct {
+	base *url.URL
+}
+
+// skippedElements are left out of a page's markdown.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Nav: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Select: true, atom.Input: true,
+}
+
+// blockElements are set apart from what is around them by blank lines.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Figure: true, atom.Figcaption: true, atom.Dl: true, atom.Dt: true,
+	atom.Dd: true, atom.Details: true, atom.Summary: true, atom.Address: true, atom.Body: true,
+}
+
+func (c *markdownConverter) children(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.node(child))
+	}
+	return b.String()
+}
+
+func (c *markdownConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skippedElements[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(c.children(n))
+		if text == "" {
+			return ""
+		}
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.A:
+		text := strings.TrimSpace(c.children(n))
+		href := attr(n, "href")
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		return "[" + text + "](" + c.resolve(href) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + attr(n, "alt") + "](" + c.resolve(src) + ")"
+	case atom.Strong, atom.B:
+		return wrapInline(c.children(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(c.children(n), "_")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return wrapInline(spaceRun.ReplaceAllString(textContent(n), " "), "`")
+	case atom.Pre:
+		return "\n\n```" + codeLanguage(n) + "\n" + strings.Trim(textContent(n), "\n") + "\n```\n\n"
+	case atom.Ul, atom.Ol:
+		if n.Parent != nil && n.Parent.DataAtom == atom.Li {
+			return "\n" + c.list(n) + "\n"
+		}
+		return "\n\n" + c.list(n) + "\n\n"
+	case atom.Blockquote:
+		text := blankLineRun.ReplaceAllString(strings.TrimSpace(c.children(n)), "\n\n")
+		return "\n\n> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n\n"
+	case atom.Table:
+		return "\n\n" + c.table(n) + "\n\n"
+	}
+	if blockElements[n.DataAtom] {
+		return "\n\n" + strings.TrimSpace(c.children(n)) + "\n\n"
+	}
+	return c.children(n)
+}
+
+func (c *markdownConverter) resolve(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || c.base == nil {
+		return ref
+	}
+	return c.base.ResolveReference(u).String()
+}
+
+// wrapInline marks text, keeping the marks inside its surrounding spaces.
+func wrapInline(text, mark string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := text[:strings.Index(text, trimmed)]
+	end := text[len(start)+len(trimmed):]
+	return start + mark + trimmed + mark + end
+}
+
+// codeLanguage is the language a code block is marked with, as in
+// class="language-go" on it or its <code>.
+func codeLanguage(pre *html.Node) string {
+	for _, n := range []*html.Node{pre, pre.FirstChild} {
+		if n == nil || n.Type != html.ElementNode {
+			continue
+		}
+		for _, class := range strings.Fields(attr(n, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) {
+					return strings.TrimPrefix(class, prefix)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (c *markdownConverter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		text := blankLineRun.ReplaceAllString(strings.TrimSpace(c.children(child)), "\n\n")
+		// Lines after the first, nested lists among them, line up under it
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.ReplaceAll(text, "\n", "\n"+indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom != atom.Tr {
+				walk(child)
+				continue
+			}
+			var row []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					text := spaceRun.ReplaceAllString(c.children(cell), " ")
+					row = append(row, strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
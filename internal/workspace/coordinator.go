@@ -0,0 +1,457 @@
+// Package: internal/workspace/coordinator.go
+package workspace
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+)
+
+const (
+	heartbeatInterval = 15 * time.Second
+	staleAfter        = 4 * heartbeatInterval
+
+	// workspaceLock is the lock a session holds while it runs a command,
+	// which can change any file
+	workspaceLock = "workspace.lock"
+)
+
+// maxJournalSize is the size at which the edit journal is rotated to
+// journal.1.jsonl, replacing the one rotated before.
+var maxJournalSize int64 = 1 << 20
+
+// Session describes one claude-go process working in a workspace.
+type Session struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"` // "cli" or "server"
+	PID        int       `json:"pid"`
+	WorkingDir string    `json:"working_dir"`
+	Started    time.Time `json:"started"`
+	Heartbeat  time.Time `json:"heartbeat"`
+}
+
+func (s Session) alive() bool {
+	return time.Since(s.Heartbeat) < staleAfter
+}
+
+// JournalEntry records a workspace mutation made by a session.
+type JournalEntry struct {
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id"`
+	Kind      string    `json:"kind"`
+	Path      string    `json:"path"`
+	Operation string    `json:"operation"`
+}
+
+// Coordinator registers a session in the shared state directory for its
+// workspace and arbitrates edits with other sessions on the same workspace.
+type Coordinator struct {
+	dir  string
+	self Session
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+func Open(workingDir, kind string) (*Coordinator, error) {
+	absDir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stateDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(absDir))
+	dir := filepath.Join(stateDir, "workspaces", hex.EncodeToString(sum[:8]))
+
+	for _, sub := range []string{"sessions", "locks"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create workspace state: %w", err)
+		}
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &Coordinator{
+		dir: dir,
+		self: Session{
+			ID:         id,
+			Kind:       kind,
+			PID:        os.Getpid(),
+			WorkingDir: absDir,
+			Started:    now,
+			Heartbeat:  now,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if err := c.writeSession(); err != nil {
+		return nil, err
+	}
+
+	go c.heartbeat()
+	return c, nil
+}
+
+func (c *Coordinator) ID() string { return c.self.ID }
+
+func (c *Coordinator) Kind() string { return c.self.Kind }
+
+// Others returns the live sessions, other than this one, on the workspace.
+func (c *Coordinator) Others() ([]Session, error) {
+	entries, err := os.ReadDir(filepath.Join(c.dir, "sessions"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	for _, entry := range entries {
+		path := filepath.Join(c.dir, "sessions", entry.Name())
+
+		var s Session
+		data, err := os.ReadFile(path)
+		if err != nil || json.Unmarshal(data, &s) != nil {
+			continue
+		}
+
+		if !s.alive() {
+			os.Remove(path) // Crashed session
+			continue
+		}
+
+		if s.ID != c.self.ID {
+			sessions = append(sessions, s)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+
+	return sessions, nil
+}
+
+// Acquire takes the edit lock for path, failing if another live session
+// currently holds it or is running a command.
+func (c *Coordinator) Acquire(path string) (func(), error) {
+	release, err := c.acquireLock(c.lockPath(path), func(holder Session) error {
+		return fmt.Errorf("%s is being edited by %s session %s", path, holder.Kind, holder.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A command takes the workspace lock before it looks for edit locks,
+	// so of the two, at least one sees the other
+	if holder, ok := c.otherHolder(filepath.Join(c.dir, "locks", workspaceLock)); ok {
+		release()
+		return nil, fmt.Errorf("%s session %s is running a command in the workspace", holder.Kind, holder.ID)
+	}
+	return release, nil
+}
+
+// AcquireWorkspace takes the workspace lock for a command, which can
+// change any file, failing if another live session is editing a file or
+// running a command of its own.
+func (c *Coordinator) AcquireWorkspace() (func(), error) {
+	lockPath := filepath.Join(c.dir, "locks", workspaceLock)
+	release, err := c.acquireLock(lockPath, func(holder Session) error {
+		return fmt.Errorf("%s session %s is running a command in the workspace", holder.Kind, holder.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locks, err := os.ReadDir(filepath.Join(c.dir, "locks"))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	for _, lock := range locks {
+		if lock.Name() == workspaceLock || filepath.Ext(lock.Name()) != ".lock" {
+			continue
+		}
+		if holder, ok := c.otherHolder(filepath.Join(c.dir, "locks", lock.Name())); ok {
+			release()
+			return nil, fmt.Errorf("%s session %s is editing files in the workspace", holder.Kind, holder.ID)
+		}
+	}
+	return release, nil
+}
+
+// acquireLock takes the lock at lockPath, which this session may already
+// hold, breaking it if its holder is gone; held describes the error when
+// a live session holds it.
+func (c *Coordinator) acquireLock(lockPath string, held func(holder Session) error) (func(), error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		err := c.createLock(lockPath)
+		if err == nil {
+			return func() { c.removeLock(lockPath, c.self.ID) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to take lock: %w", err)
+		}
+
+		owner, err := os.ReadFile(lockPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Released meanwhile
+		}
+		if string(owner) == c.self.ID {
+			return func() {}, nil
+		}
+
+		if holder, ok := c.liveSession(string(owner)); ok {
+			return nil, held(holder)
+		}
+
+		// Holder is gone; break the stale lock, unless another session
+		// broke it and took the lock first, and retry once
+		c.removeLock(lockPath, string(owner))
+	}
+
+	return nil, fmt.Errorf("failed to take lock")
+}
+
+// otherHolder returns the live session other than this one that holds the
+// lock at lockPath, if any.
+func (c *Coordinator) otherHolder(lockPath string) (Session, bool) {
+	owner, err := os.ReadFile(lockPath)
+	if err != nil || string(owner) == c.self.ID {
+		return Session{}, false
+	}
+	return c.liveSession(string(owner))
+}
+
+// createLock creates the lock file at lockPath holding this session's ID,
+// failing with os.ErrExist if it exists. The file is written in full
+// before it is linked into place, so no one reads an empty owner.
+func (c *Coordinator) createLock(lockPath string) error {
+	suffix, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	tmp := lockPath + "." + suffix + ".tmp"
+	if err := os.WriteFile(tmp, []byte(c.self.ID), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Link(tmp, lockPath)
+}
+
+// removeLock removes the lock file at lockPath if owner holds it. Locks are
+// only removed holding the file lock on locks/guard, so that of two
+// sessions breaking the same stale lock, the second finds the lock the
+// first took in its place and leaves it.
+func (c *Coordinator) removeLock(lockPath, owner string) {
+	withFileLock(filepath.Join(c.dir, "locks", "guard"), func() error {
+		if held, err := os.ReadFile(lockPath); err != nil || string(held) != owner {
+			return nil
+		}
+		return os.Remove(lockPath)
+	})
+}
+
+// RecordEdit appends a mutation to the workspace's shared edit journal.
+func (c *Coordinator) RecordEdit(path, operation string) error {
+	entry := JournalEntry{
+		Time:      time.Now(),
+		SessionID: c.self.ID,
+		Kind:      c.self.Kind,
+		Path:      c.normalize(path),
+		Operation: operation,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Sessions rotate and append holding the file lock on journal.lock, so
+	// none appends to a journal another is rotating away
+	return withFileLock(filepath.Join(c.dir, "journal.lock"), func() error {
+		journalPath := filepath.Join(c.dir, "journal.jsonl")
+		if info, err := os.Stat(journalPath); err == nil && info.Size() >= maxJournalSize {
+			if err := os.Rename(journalPath, filepath.Join(c.dir, "journal.1.jsonl")); err != nil {
+				return fmt.Errorf("failed to rotate edit journal: %w", err)
+			}
+		}
+
+		f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open edit journal: %w", err)
+		}
+		defer f.Close()
+
+		_, err = f.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// RecordCommand appends a command the session ran to the edit journal,
+// under the working directory, since it may have changed any file.
+func (c *Coordinator) RecordCommand(command string) error {
+	return c.RecordEdit(c.self.WorkingDir, "ran `"+command+"` in")
+}
+
+// ForeignEdits returns journal entries written by other sessions since the
+// given time, so a session can tell the user what changed underneath it.
+func (c *Coordinator) ForeignEdits(since time.Time) ([]JournalEntry, error) {
+	return c.journal(since, func(entry JournalEntry) bool {
+		return entry.SessionID != c.self.ID && entry.Time.After(since)
+	})
+}
+
+// OwnEdits returns the journal entries this session has written, as far
+// back as the journal has been kept.
+func (c *Coordinator) OwnEdits() ([]JournalEntry, error) {
+	return c.journal(c.self.Started, func(entry JournalEntry) bool {
+		return entry.SessionID == c.self.ID
+	})
+}
+
+// journal returns the entries match accepts from the rotated journal and
+// the current one, skipping a file last written before since.
+func (c *Coordinator) journal(since time.Time, match func(JournalEntry) bool) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	for _, name := range []string{"journal.1.jsonl", "journal.jsonl"} {
+		f, err := os.Open(filepath.Join(c.dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if info, err := f.Stat(); err == nil && info.ModTime().Before(since) {
+			f.Close()
+			continue
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry JournalEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+				continue
+			}
+			if match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func (c *Coordinator) Close() error {
+	close(c.stop)
+	<-c.done
+
+	// Release any locks still held by this session
+	locks, _ := os.ReadDir(filepath.Join(c.dir, "locks"))
+	for _, lock := range locks {
+		if filepath.Ext(lock.Name()) != ".lock" {
+			continue
+		}
+		path := filepath.Join(c.dir, "locks", lock.Name())
+		if owner, err := os.ReadFile(path); err == nil && string(owner) == c.self.ID {
+			c.removeLock(path, c.self.ID)
+		}
+	}
+
+	return os.Remove(c.sessionPath())
+}
+
+func (c *Coordinator) heartbeat() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			c.self.Heartbeat = time.Now()
+			c.mu.Unlock()
+			c.writeSession()
+		}
+	}
+}
+
+func (c *Coordinator) writeSession() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.self)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := c.sessionPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to register session: %w", err)
+	}
+	return os.Rename(tmp, c.sessionPath())
+}
+
+func (c *Coordinator) liveSession(id string) (Session, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, "sessions", id+".json"))
+	if err != nil {
+		return Session{}, false
+	}
+
+	var s Session
+	if json.Unmarshal(data, &s) != nil || !s.alive() {
+		return Session{}, false
+	}
+	return s, true
+}
+
+func (c *Coordinator) sessionPath() string {
+	return filepath.Join(c.dir, "sessions", c.self.ID+".json")
+}
+
+func (c *Coordinator) lockPath(path string) string {
+	sum := sha256.Sum256([]byte(c.normalize(path)))
+	return filepath.Join(c.dir, "locks", hex.EncodeToString(sum[:12])+".lock")
+}
+
+func (c *Coordinator) normalize(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.self.WorkingDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,192 @@
+package workspace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// openSessions registers n sessions on the same workspace, with the
+// state directory under a temporary home.
+func openSessions(t *testing.T, n int) []*Coordinator {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	workingDir := t.TempDir()
+
+	sessions := make([]*Coordinator, n)
+	for i := range sessions {
+		c, err := Open(workingDir, "cli")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		sessions[i] = c
+	}
+	return sessions
+}
+
+func TestLocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   func(c *Coordinator) (func(), error) // Held by the first session
+		second  func(c *Coordinator) (func(), error) // Then tried by the second
+		wantErr string
+	}{
+		{
+			name:    "same file",
+			first:   func(c *Coordinator) (func(), error) { return c.Acquire("main.go") },
+			second:  func(c *Coordinator) (func(), error) { return c.Acquire("main.go") },
+			wantErr: "main.go is being edited by cli session",
+		},
+		{
+			name:   "different files",
+			first:  func(c *Coordinator) (func(), error) { return c.Acquire("main.go") },
+			second: func(c *Coordinator) (func(), error) { return c.Acquire("util.go") },
+		},
+		{
+			name:    "file during a command",
+			first:   func(c *Coordinator) (func(), error) { return c.AcquireWorkspace() },
+			second:  func(c *Coordinator) (func(), error) { return c.Acquire("main.go") },
+			wantErr: "is running a command in the workspace",
+		},
+		{
+			name:    "command during an edit",
+			first:   func(c *Coordinator) (func(), error) { return c.Acquire("main.go") },
+			second:  func(c *Coordinator) (func(), error) { return c.AcquireWorkspace() },
+			wantErr: "is editing files in the workspace",
+		},
+		{
+			name:    "command during a command",
+			first:   func(c *Coordinator) (func(), error) { return c.AcquireWorkspace() },
+			second:  func(c *Coordinator) (func(), error) { return c.AcquireWorkspace() },
+			wantErr: "is running a command in the workspace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := openSessions(t, 2)
+
+			release, err := tt.first(sessions[0])
+			if err != nil {
+				t.Fatalf("first session: %v", err)
+			}
+
+			second, err := tt.second(sessions[1])
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("second session: %v", err)
+				}
+				second()
+				release()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("second session: err = %v, want %q", err, tt.wantErr)
+			}
+
+			// Released, the lock is the second session's to take
+			release()
+			second, err = tt.second(sessions[1])
+			if err != nil {
+				t.Fatalf("second session after release: %v", err)
+			}
+			second()
+		})
+	}
+}
+
+func TestSessionReentersItsOwnLocks(t *testing.T) {
+	c := openSessions(t, 1)[0]
+
+	release, err := c.AcquireWorkspace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if _, err := c.Acquire("main.go"); err != nil {
+		t.Errorf("editing during its own command: %v", err)
+	}
+	if _, err := c.AcquireWorkspace(); err != nil {
+		t.Errorf("running a second command of its own: %v", err)
+	}
+}
+
+func TestStaleLockIsTakenOverOnce(t *testing.T) {
+	sessions := openSessions(t, 8)
+
+	// A lock held by a session that crashed without unregistering
+	lockPath := sessions[0].lockPath("main.go")
+	if err := os.WriteFile(lockPath, []byte("0123456789ab"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var holders []string
+	for _, c := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Acquire("main.go"); err == nil {
+				mu.Lock()
+				holders = append(holders, c.ID())
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(holders) != 1 {
+		t.Fatalf("%d sessions took the lock (%v), want 1", len(holders), holders)
+	}
+	if owner, _ := os.ReadFile(lockPath); string(owner) != holders[0] {
+		t.Errorf("lock holds %q, want %q", owner, holders[0])
+	}
+}
+
+func TestJournalRotationKeepsWholeEntries(t *testing.T) {
+	defer func(size int64) { maxJournalSize = size }(maxJournalSize)
+	maxJournalSize = 2048
+
+	sessions := openSessions(t, 4)
+	var wg sync.WaitGroup
+	for _, c := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := c.RecordEdit(fmt.Sprintf("file%d.go", i), "write"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := sessions[0].RecordCommand("go test ./..."); err != nil {
+		t.Fatal(err)
+	}
+
+	var last JournalEntry
+	for _, name := range []string{"journal.1.jsonl", "journal.jsonl"} {
+		f, err := os.Open(filepath.Join(sessions[0].dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if err := json.Unmarshal(scanner.Bytes(), &last); err != nil {
+				t.Errorf("%s: torn entry %q: %v", name, scanner.Text(), err)
+			}
+		}
+		f.Close()
+	}
+	if last.Operation != "ran `go test ./...` in" || last.SessionID != sessions[0].ID() {
+		t.Errorf("last entry = %+v, want the command", last)
+	}
+}
@@ -0,0 +1,20 @@
+// Package: internal/workspace/flock.go
+package workspace
+
+import "os"
+
+// withFileLock runs fn holding an exclusive lock on the file at path,
+// created if need be, waiting for other processes that hold it.
+func withFileLock(path string, fn func() error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+	return fn()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/agent"
+	"github.com/N0tT1m/claude-code-go/internal/jobs"
+)
+
+const jobOutputPreview = 500
+
+func startBackgroundJob(s *session, task string) {
+	if task == "" {
+		fmt.Println("Usage: /bg <task>")
+		return
+	}
+
+	job := s.jobs.Start(task, func(ctx context.Context, out *jobs.Output) (string, error) {
+		ctx = agent.WithApprover(agent.WithOwnConversation(ctx), func(req agent.ApprovalRequest) bool {
+			return s.approveForJob(ctx, task, req)
+		})
+		response, err := s.agent.ProcessInputWithEvents(ctx, task, func(e agent.Event) {
+			switch e.Type {
+			case "reasoning":
+				printReasoning(out, e.Content, false)
+			case "tool_output":
+				out.Printf("%s", e.Content)
+			case "tool_call":
+				out.Printf("→ %s %s\n", e.Tool, e.Content)
+			case "tool_result":
+				out.Printf("%s\n", truncateOutput(e.Content, jobOutputPreview))
+			case "response":
+				out.Printf("\n%s\n", e.Content)
+			}
+		})
+		if err != nil {
+			out.Printf("Error: %v\n", err)
+			printPostMortem(out, err)
+		}
+		return response, err
+	})
+
+	fmt.Printf("Started job %d: %s\n", job.ID, task)
+	fmt.Println("Use '/jobs' to check on it, '/jobs follow <id>' to stream its output")
+}
+
+// jobApproval is a background job's request to run a command or make an
+// edit, which the REPL asks the user about before its next prompt.
+type jobApproval struct {
+	task  string
+	req   agent.ApprovalRequest
+	reply chan bool
+}
+
+// approveForJob queues a job's approval request for the REPL and waits for
+// the answer, denying the request if the job is cancelled first.
+func (s *session) approveForJob(ctx context.Context, task string, req agent.ApprovalRequest) bool {
+	pending := jobApproval{task: task, req: req, reply: make(chan bool, 1)}
+	select {
+	case s.approvals <- pending:
+	case <-ctx.Done():
+		return false
+	}
+
+	fmt.Printf("\n[job %q is waiting for approval; press Enter to answer]\n", task)
+	select {
+	case approved := <-pending.reply:
+		return approved
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// answerJobApprovals asks the user about the requests background jobs
+// have queued.
+func (s *session) answerJobApprovals() {
+	for {
+		select {
+		case pending := <-s.approvals:
+			fmt.Printf("Background job %q:\n", pending.task)
+			pending.reply <- s.approve(pending.req)
+		default:
+			return
+		}
+	}
+}
+
+func handleJobs(s *session, args []string) {
+	if len(args) == 0 {
+		listJobs(s.jobs)
+		return
+	}
+
+	if len(args) < 2 {
+		fmt.Println("Usage: /jobs [output|follow|cancel] <id>")
+		return
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		fmt.Printf("Invalid job id: %s\n", args[1])
+		return
+	}
+
+	job, ok := s.jobs.Get(id)
+	if !ok {
+		fmt.Printf("Job %d not found\n", id)
+		return
+	}
+
+	switch args[0] {
+	case "output":
+		fmt.Print(job.Output().String())
+	case "follow":
+		followJob(job)
+	case "cancel":
+		if err := s.jobs.Cancel(id); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Cancelling job %d\n", id)
+	default:
+		fmt.Printf("Unknown jobs subcommand: %s\n", args[0])
+	}
+}
+
+func listJobs(m *jobs.Manager) {
+	list := m.List()
+	if len(list) == 0 {
+		fmt.Println("No background jobs")
+		return
+	}
+
+	for _, job := range list {
+		elapsed := time.Since(job.Started)
+		if job.Status() != jobs.StatusRunning {
+			elapsed = job.Finished.Sub(job.Started)
+		}
+		fmt.Printf("  [%d] %-9s %6s  %s\n", job.ID, job.Status(), elapsed.Round(time.Second), job.Name)
+	}
+}
+
+func followJob(job *jobs.Job) {
+	existing, updates, stop := job.Output().Follow()
+	defer stop()
+
+	fmt.Print(existing)
+	for {
+		select {
+		case chunk := <-updates:
+			fmt.Print(chunk)
+		case <-job.Done():
+			// Drain anything written just before completion
+			for {
+				select {
+				case chunk := <-updates:
+					fmt.Print(chunk)
+				default:
+					fmt.Printf("[job %d %s]\n", job.ID, job.Status())
+					return
+				}
+			}
+		}
+	}
+}
+
+func reportFinishedJobs(m *jobs.Manager) {
+	for _, job := range m.Finished() {
+		fmt.Printf("[job %d %s] %s\n", job.ID, job.Status(), job.Name)
+	}
+}
+
+func truncateOutput(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "\n... (truncated)"
+}
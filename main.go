@@ -4,15 +4,30 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/N0tT1m/claude-code-go/internal/agent"
+	"github.com/N0tT1m/claude-code-go/internal/audit"
 	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/history"
+	"github.com/N0tT1m/claude-code-go/internal/jobs"
 	"github.com/N0tT1m/claude-code-go/internal/llm"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+	"github.com/N0tT1m/claude-code-go/internal/migrate"
+	"github.com/N0tT1m/claude-code-go/internal/permissions"
+	"github.com/N0tT1m/claude-code-go/internal/repl"
+	"github.com/N0tT1m/claude-code-go/internal/schema"
+	"github.com/N0tT1m/claude-code-go/internal/tools"
+	"github.com/N0tT1m/claude-code-go/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -30,12 +45,32 @@ func main() {
 	rootCmd.PersistentFlags().BoolP("headless", "p", false, "Run in headless mode")
 	rootCmd.PersistentFlags().String("output-format", "text", "Output format (text, json)")
 	rootCmd.PersistentFlags().String("base-url", "", "LM Studio base URL (overrides config)")
+	rootCmd.PersistentFlags().String("provider", "", "Model provider: lmstudio or mock (overrides config)")
+	rootCmd.PersistentFlags().String("mock-script", "", "Answer from a scripted mock instead of a model")
+	rootCmd.PersistentFlags().String("cassette", "", "Record model exchanges to this file, or replay it with --provider mock")
+	rootCmd.PersistentFlags().String("schema", "", "JSON schema file the headless answer must validate against")
+	rootCmd.PersistentFlags().StringArray("attach", nil, "File to attach to the headless prompt (repeatable)")
+	rootCmd.PersistentFlags().String("mcp-debug", "", "Log every MCP message to this file (--mcp-debug=<file>), or to ~/.claude-go/mcp-debug.jsonl")
+	rootCmd.PersistentFlags().Lookup("mcp-debug").NoOptDefVal = defaultMCPDebugLog()
 
 	// Add subcommands
 	rootCmd.AddCommand(
 		newCommitCommand(),
 		newConfigCommand(),
 		newChatCommand(),
+		newExplainCommand(),
+		newReviewCommand(),
+		newImportCommand(),
+		newAuditCommand(),
+		newTestGenCommand(),
+		newPRCommand(),
+		newChangelogCommand(),
+		newBranchCommand(),
+		newBumpCommand(),
+		newSessionsCommand(),
+		newContextCommand(),
+		newMCPCommand(),
+		newServeCommand(),
 	)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -44,26 +79,22 @@ func main() {
 }
 
 func runInteractiveMode(cmd *cobra.Command, args []string) {
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
+	cfg := loadConfig(cmd)
 
-	// Override base URL if provided via flag
-	if baseURL, _ := cmd.Flags().GetString("base-url"); baseURL != "" {
-		cfg.LMStudio.BaseURL = baseURL
-	}
+	// Initialize the client with potentially overridden settings
+	client := newClient(cfg)
 
-	// Override model if provided via flag
-	if model, _ := cmd.Flags().GetString("model"); model != "" {
-		cfg.LMStudio.Model = model
+	if headless, _ := cmd.Flags().GetBool("headless"); headless {
+		runHeadless(cmd, args, agent.New(client, cfg))
+		return
 	}
 
-	// Initialize LM Studio client with potentially overridden URL
-	client := llm.NewLMStudioClient(cfg.LMStudio.BaseURL)
-
 	// Test connection
-	fmt.Printf("Connecting to LM Studio at %s...\n", cfg.LMStudio.BaseURL)
+	if cfg.LMStudio.Provider == "mock" {
+		fmt.Println("Using the mock provider (no model server)...")
+	} else {
+		fmt.Printf("Connecting to LM Studio at %s...\n", cfg.LMStudio.BaseURL)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -76,21 +107,52 @@ func runInteractiveMode(cmd *cobra.Command, args []string) {
 
 	// Initialize agent
 	a := agent.New(client, cfg)
+	defer a.Close()
+	attachAuditLog(a)
+
+	workingDir, _ := os.Getwd()
+	coordinator, err := workspace.Open(workingDir, "cli")
+	if err != nil {
+		log.Fatalf("Failed to register session: %v", err)
+	}
+	defer coordinator.Close()
+	a.AttachWorkspace(coordinator)
+	warnAboutOtherSessions(coordinator)
+	if snapshot, err := agent.TakeSnapshot(context.Background()); err == nil {
+		a.SetBaseline(snapshot)
+	}
+	if record, err := history.New(workingDir); err == nil {
+		a.AttachHistory(record)
+	}
+	if stop, err := a.WatchProject(); err == nil {
+		defer stop()
+	} else {
+		log.Printf("Warning: project files will be rescanned for every request: %v", err)
+	}
+	a.StartMCPServers()
+	lastJournalCheck := time.Now()
+
+	sess := &session{agent: a, jobs: jobs.NewManager(), showThinking: cfg.Agent.ShowThinking, approvals: make(chan jobApproval, 16)}
+	sess.input = repl.NewLineReader(os.Stdin, os.Stdout, newCompleter(sess, workingDir))
+	defer sess.jobs.CancelAll()
 
 	fmt.Println("Claude Go - AI Coding Assistant")
 	fmt.Printf("Using model: %s\n", cfg.LMStudio.Model)
+	if cfg.Remote.Enabled {
+		fmt.Printf("Running commands on: %s\n", a.Executor())
+	}
 	fmt.Println("Type 'exit' to quit, '/help' for commands")
 	fmt.Println()
 
-	scanner := bufio.NewScanner(os.Stdin)
-
 	for {
-		fmt.Print("claude> ")
-		if !scanner.Scan() {
+		sess.answerJobApprovals()
+
+		line, err := sess.input.ReadLine("claude> ")
+		if err != nil {
 			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 		if input == "" {
 			continue
 		}
@@ -99,16 +161,28 @@ func runInteractiveMode(cmd *cobra.Command, args []string) {
 			break
 		}
 
+		warnAboutForeignEdits(coordinator, lastJournalCheck)
+		lastJournalCheck = time.Now()
+		reportFinishedJobs(sess.jobs)
+
 		if strings.HasPrefix(input, "/") {
-			handleSlashCommand(input, a)
+			handleSlashCommand(input, sess)
 			continue
 		}
 
 		// Process natural language input
-		ctx := context.Background()
-		response, err := a.ProcessInput(ctx, input)
+		ctx := agent.WithApprover(context.Background(), sess.approve)
+		response, err := a.ProcessInputWithEvents(ctx, input, func(e agent.Event) {
+			switch e.Type {
+			case "reasoning":
+				printReasoning(os.Stdout, e.Content, sess.showThinking)
+			case "tool_output":
+				fmt.Print(e.Content)
+			}
+		})
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
+			printPostMortem(os.Stdout, err)
 			continue
 		}
 
@@ -117,19 +191,213 @@ func runInteractiveMode(cmd *cobra.Command, args []string) {
 	}
 }
 
-func handleSlashCommand(input string, a *agent.Agent) {
+func warnAboutOtherSessions(c *workspace.Coordinator) {
+	others, err := c.Others()
+	if err != nil {
+		return
+	}
+
+	for _, other := range others {
+		fmt.Printf("⚠️  Another %s session (%s, pid %d) is working in this directory since %s.\n",
+			other.Kind, other.ID, other.PID, other.Started.Format(time.Kitchen))
+		fmt.Println("   Edits are coordinated through file locks; changes it makes will be reported here.")
+	}
+}
+
+func warnAboutForeignEdits(c *workspace.Coordinator, since time.Time) {
+	edits, err := c.ForeignEdits(since)
+	if err != nil {
+		return
+	}
+
+	for _, edit := range edits {
+		fmt.Printf("⚠️  %s session %s: %s %s\n", edit.Kind, edit.SessionID, edit.Operation, edit.Path)
+	}
+}
+
+func runHeadless(cmd *cobra.Command, args []string, a *agent.Agent) {
+	defer a.Close()
+	attachAuditLog(a)
+	a.StartMCPServers()
+	// A single request has no later turn to pick up servers that are slow
+	// to start
+	a.WaitForMCPServers(15 * time.Second)
+
+	prompt := strings.Join(args, " ")
+	if prompt == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Failed to read prompt from stdin: %v", err)
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+
+	if prompt == "" {
+		log.Fatal("No prompt provided")
+	}
+
+	attachments, _ := cmd.Flags().GetStringArray("attach")
+	for _, path := range attachments {
+		if _, err := a.Attach(path); err != nil {
+			log.Fatalf("Failed to attach %s: %v", path, err)
+		}
+	}
+
+	ctx := context.Background()
+
+	if schemaPath, _ := cmd.Flags().GetString("schema"); schemaPath != "" {
+		s, err := schema.Load(schemaPath)
+		if err != nil {
+			log.Fatalf("Failed to load schema: %v", err)
+		}
+
+		result, err := a.ProcessStructured(ctx, prompt, s)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		fmt.Println(string(result))
+		return
+	}
+
+	response, err := a.ProcessInput(ctx, prompt)
+	if err != nil {
+		printPostMortem(os.Stderr, err)
+		log.Fatalf("Error: %v", err)
+	}
+
+	if format, _ := cmd.Flags().GetString("output-format"); format == "json" {
+		output, _ := json.Marshal(map[string]string{"response": response})
+		fmt.Println(string(output))
+		return
+	}
+
+	fmt.Println(response)
+}
+
+// session holds the state of an interactive REPL session.
+type session struct {
+	agent     *agent.Agent
+	jobs      *jobs.Manager
+	input     *repl.LineReader
+	approvals chan jobApproval // Background jobs' requests, asked at the prompt
+
+	showThinking bool
+}
+
+// approve asks the user about an edit the permission policy does not
+// auto-accept, labelling each path as test or production code, about a
+// command or web request it does not, or about an MCP server's sampling
+// request, showing what it asks.
+func (s *session) approve(req agent.ApprovalRequest) bool {
+	if req.Server != "" {
+		prompt := req.Prompt
+		if len(prompt) > 500 {
+			prompt = prompt[:500] + "..."
+		}
+		fmt.Printf("⚠️  The %s MCP server wants %s to answer:\n", req.Server, req.Model)
+		for _, line := range strings.Split(prompt, "\n") {
+			fmt.Printf("   %s\n", line)
+		}
+		answer, err := s.input.ReadLine("Approve? [y/N] ")
+		return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+	}
+
+	if len(req.Commands) > 0 {
+		fmt.Printf("⚠️  %s wants to run:\n", req.Tool)
+		for _, command := range req.Commands {
+			fmt.Printf("   $ %s\n", command)
+		}
+		answer, err := s.input.ReadLine("Approve? [y/N] ")
+		return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+	}
+
+	if len(req.URLs) > 0 {
+		fmt.Printf("⚠️  %s wants to fetch:\n", req.Tool)
+		for _, u := range req.URLs {
+			fmt.Printf("   %s\n", u)
+		}
+		answer, err := s.input.ReadLine("Approve? [y/N] ")
+		return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+	}
+
+	fmt.Printf("⚠️  %s wants to edit:\n", req.Tool)
+	for _, p := range req.Paths {
+		fmt.Printf("   %s %s\n", permissions.Badge(p.Class), p.Path)
+	}
+
+	answer, err := s.input.ReadLine("Approve? [y/N] ")
+	return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+}
+
+func handleSlashCommand(input string, s *session) {
+	a := s.agent
 	parts := strings.Fields(input)
 	command := parts[0][1:] // Remove the '/'
 
 	switch command {
 	case "help":
+		if len(parts) > 1 {
+			handleHelpQuestion(a, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+			return
+		}
 		showHelp()
 	case "commit":
-		handleCommit(a)
+		confirm := func(prompt string) bool {
+			answer, err := s.input.ReadLine(prompt)
+			return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+		}
+		if len(parts) > 1 && parts[1] == "--split" {
+			handleSplitCommit(a, confirm)
+			return
+		}
+		handleCommit(a, agent.CommitOptions{Amend: len(parts) > 1 && parts[1] == "--amend"}, confirm)
+	case "clear":
+		a.ClearConversation()
+		fmt.Println("Conversation cleared")
 	case "config":
 		showConfig()
 	case "models":
 		showAvailableModels(a)
+	case "model":
+		handleModel(a, parts[1:])
+	case "open":
+		handleOpen(a, parts[1:])
+	case "style":
+		handleStyle(a, parts[1:])
+	case "think":
+		handleThink(s, parts[1:])
+	case "bg":
+		startBackgroundJob(s, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+	case "jobs":
+		handleJobs(s, parts[1:])
+	case "attach":
+		handleAttach(a, parts[1:])
+	case "review":
+		handleReview(a, parts[1:])
+	case "branch":
+		handleBranch(a, strings.TrimSpace(strings.TrimPrefix(input, parts[0])), func(prompt string) bool {
+			answer, err := s.input.ReadLine(prompt)
+			return err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+		})
+	case "whatchanged":
+		handleWhatChanged(a)
+	case "why":
+		handleWhy(a, parts[1:])
+	case "trace":
+		showAuditEntry(a, parts[1:])
+	case "explain":
+		handleExplain(s, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+	case "scope":
+		handleScope(a, parts[1:])
+	case "add-dir":
+		handleAddDir(a, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+	case "pin":
+		handlePin(a, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+	case "unpin":
+		handleUnpin(a, strings.TrimSpace(strings.TrimPrefix(input, parts[0])))
+	case "mcp":
+		showMCPServers(a)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 	}
@@ -137,23 +405,638 @@ func handleSlashCommand(input string, a *agent.Agent) {
 
 func showHelp() {
 	fmt.Println("Available commands:")
-	fmt.Println("  /help     - Show this help")
-	fmt.Println("  /commit   - Create a git commit")
+	fmt.Println("  /help     - Show this help (/help <question> asks about claude-go itself)")
+	fmt.Println("  /commit   - Create a git commit (--amend to amend the last one, --split to split it up)")
+	fmt.Println("  /clear    - Start a new conversation, forgetting the earlier requests")
 	fmt.Println("  /config   - Show current configuration")
 	fmt.Println("  /models   - List available models")
+	fmt.Println("  /model    - Show or switch the model for this session")
+	fmt.Println("  /open     - Open a file in $EDITOR")
+	fmt.Println("  /style    - Show or switch the response style")
+	fmt.Println("  /think    - Control reasoning models (on [budget], off, auto, show, hide)")
+	fmt.Println("  /bg       - Run a task as a background job")
+	fmt.Println("  /jobs     - List, follow, or cancel background jobs")
+	fmt.Println("  /explain  - Explain a pasted error or stack trace")
+	fmt.Println("  /trace    - Show the recorded output behind a footnote")
+	fmt.Println("  /attach   - Attach a file to the next message (list, clear)")
+	fmt.Println("  /review   - Review uncommitted, staged (--staged), or a ref range of changes")
+	fmt.Println("  /branch   - Suggest a branch name for a task and switch to it")
+	fmt.Println("  /why      - Explain why a line exists from its history (/why file:line)")
+	fmt.Println("  /whatchanged - Summarize everything that changed since the session started")
+	fmt.Println("  /scope    - Show or widen the monorepo packages in the context (<package>, all, reset)")
+	fmt.Println("  /add-dir  - Add a directory outside the project to the context, or list those added")
+	fmt.Println("  /pin      - Keep a file in every prompt in full, or list the pinned files")
+	fmt.Println("  /unpin    - Stop keeping a pinned file in every prompt")
+	fmt.Println("  /mcp      - Show the MCP servers and their connection state")
 	fmt.Println("  exit      - Exit the program")
 }
 
+func handleScope(a *agent.Agent, args []string) {
+	ws, scope := a.Workspace()
+	if ws == nil {
+		fmt.Println("Not in a monorepo workspace; the context covers the whole project.")
+		return
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "reset":
+			a.ResetScope()
+		default:
+			if _, err := a.WidenScope(args[0]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+		}
+		_, scope = a.Workspace()
+	}
+
+	fmt.Printf("%s workspace at %s\n", ws.Kind, ws.Root)
+	fmt.Printf("Members: %s\n", strings.Join(ws.Members, ", "))
+	if len(scope) == 0 {
+		fmt.Println("Context: the whole workspace")
+	} else {
+		fmt.Printf("Context: %s\n", strings.Join(scope, ", "))
+	}
+}
+
+func handleAddDir(a *agent.Agent, dir string) {
+	if dir != "" {
+		root, err := a.AddRoot(dir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Added %s to the context as [%s]\n", root.Path, root.Label)
+		return
+	}
+
+	roots := a.Roots()
+	if len(roots) == 0 {
+		fmt.Println("No directories added; the context covers the project only. Usage: /add-dir <path>")
+		return
+	}
+	for _, root := range roots {
+		fmt.Printf("  [%s] %s\n", root.Label, root.Path)
+	}
+}
+
+func handlePin(a *agent.Agent, path string) {
+	if path != "" {
+		relPath, err := a.Pin(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Pinned %s; it is in every prompt until /unpin\n", relPath)
+		return
+	}
+
+	pinned := a.Pinned()
+	if len(pinned) == 0 {
+		fmt.Println("No pinned files. Usage: /pin <file>")
+		return
+	}
+	for _, relPath := range pinned {
+		fmt.Printf("  %s\n", relPath)
+	}
+}
+
+func handleUnpin(a *agent.Agent, path string) {
+	if path == "" {
+		fmt.Println("Usage: /unpin <file>")
+		return
+	}
+	relPath, err := a.Unpin(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Unpinned %s\n", relPath)
+}
+
+func showMCPServers(a *agent.Agent) {
+	servers := a.MCPServers()
+	if len(servers) == 0 {
+		fmt.Println("No MCP servers configured. Add them under mcp_servers in the config or .claude-go/config.json.")
+		return
+	}
+
+	for _, server := range servers {
+		switch server.State {
+		case mcp.StateConnected:
+			fmt.Printf("  %s (%s): connected to %s %s, %d tools\n", server.Name, server.Transport, server.Server.Name, server.Server.Version, len(server.Tools))
+		case mcp.StateDisabled:
+			fmt.Printf("  %s (%s): disabled\n", server.Name, server.Transport)
+		default:
+			fmt.Printf("  %s (%s): %s since %s", server.Name, server.Transport, server.State, server.Since.Format(time.Kitchen))
+			if server.Error != nil {
+				fmt.Printf(" (%v)", server.Error)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func handleWhatChanged(a *agent.Agent) {
+	summary, err := a.WhatChanged(context.Background())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Println(summary)
+	fmt.Println()
+}
+
+func handleHelpQuestion(a *agent.Agent, question string) {
+	answer, err := a.AnswerHelp(context.Background(), question)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+
+	fmt.Println(answer)
+	fmt.Println()
+}
+
 func newCommitCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "commit",
 		Short: "Create an AI-generated git commit",
 		Run: func(cmd *cobra.Command, args []string) {
-			cfg, _ := config.Load()
-			client := llm.NewLMStudioClient(cfg.LMStudio.BaseURL)
-			a := agent.New(client, cfg)
+			a := loadAgent(cmd)
+
+			scanner := bufio.NewScanner(os.Stdin)
+			confirm := func(prompt string) bool {
+				fmt.Print(prompt)
+				return scanner.Scan() && strings.ToLower(scanner.Text()) == "y"
+			}
+
+			if split, _ := cmd.Flags().GetBool("split"); split {
+				handleSplitCommit(a, confirm)
+				return
+			}
+
+			opts := agent.CommitOptions{}
+			opts.Amend, _ = cmd.Flags().GetBool("amend")
+			opts.SkipReview, _ = cmd.Flags().GetBool("skip-review")
+			handleCommit(a, opts, confirm)
+		},
+	}
+
+	cmd.Flags().Bool("amend", false, "Amend the last commit with a regenerated message")
+	cmd.Flags().Bool("split", false, "Split the staged changes into several logical commits")
+	cmd.Flags().Bool("skip-review", false, "Commit without the pre-commit review (git.pre_commit_review)")
+	return cmd
+}
+
+func newExplainCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [error text]",
+		Short: "Explain a stack trace or compiler error read from stdin",
+		Run: func(cmd *cobra.Command, args []string) {
+			trace := strings.Join(args, " ")
+			if trace == "" {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					log.Fatalf("Failed to read stdin: %v", err)
+				}
+				trace = string(data)
+			}
+
+			explanation, err := loadAgent(cmd).ExplainError(context.Background(), trace)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			fmt.Println(explanation)
+		},
+	}
+}
+
+func newReviewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "review [ref..ref]",
+		Short: "Review a git diff and report structured findings",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts := agent.ReviewOptions{}
+			opts.Staged, _ = cmd.Flags().GetBool("staged")
+			if len(args) > 0 {
+				opts.Range = args[0]
+			}
 
-			handleCommit(a)
+			review, err := loadAgent(cmd).ReviewDiff(context.Background(), opts)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			if format, _ := cmd.Flags().GetString("output-format"); format == "json" {
+				output, _ := json.MarshalIndent(review, "", "  ")
+				fmt.Println(string(output))
+				return
+			}
+
+			fmt.Print(review.Format())
+		},
+	}
+
+	cmd.Flags().Bool("staged", false, "Review staged changes")
+	return cmd
+}
+
+func newPRCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pr",
+		Short: "Write a pull request title and body for the current branch",
+		Run: func(cmd *cobra.Command, args []string) {
+			base, _ := cmd.Flags().GetString("base")
+
+			fmt.Fprintln(os.Stderr, "Summarizing the branch...")
+			pr, err := loadAgent(cmd).DescribePullRequest(context.Background(), base)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			if create, _ := cmd.Flags().GetBool("create"); create {
+				draft, _ := cmd.Flags().GetBool("draft")
+				if err := createPullRequest(pr, draft); err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+				return
+			}
+
+			if format, _ := cmd.Flags().GetString("output-format"); format == "json" {
+				output, _ := json.MarshalIndent(pr, "", "  ")
+				fmt.Println(string(output))
+				return
+			}
+
+			fmt.Printf("%s\n\n%s", pr.Title, pr.Body())
+		},
+	}
+
+	cmd.Flags().String("base", "", "Branch the pull request merges into (default: the upstream or the remote's default branch)")
+	cmd.Flags().Bool("create", false, "Open the pull request with gh pr create")
+	cmd.Flags().Bool("draft", false, "Open the pull request as a draft (with --create)")
+	return cmd
+}
+
+// createPullRequest hands the description to the GitHub CLI.
+func createPullRequest(pr *agent.PullRequest, draft bool) error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return fmt.Errorf("gh not found; install the GitHub CLI or run without --create")
+	}
+
+	args := []string{"pr", "create", "--title", pr.Title, "--body-file", "-", "--base", pr.BaseBranch}
+	if draft {
+		args = append(args, "--draft")
+	}
+
+	gh := exec.Command("gh", args...)
+	gh.Stdin = strings.NewReader(pr.Body())
+	gh.Stdout = os.Stdout
+	gh.Stderr = os.Stderr
+	return gh.Run()
+}
+
+func newChangelogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changelog <from..to>",
+		Short: "Write changelog entries for a range of commits and add them to CHANGELOG.md",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			version, _ := cmd.Flags().GetString("version")
+			file, _ := cmd.Flags().GetString("file")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			a := loadAgent(cmd)
+			ctx := agent.WithApprover(context.Background(), func(req agent.ApprovalRequest) bool {
+				for _, p := range req.Paths {
+					fmt.Printf("%s %s\n", permissions.Badge(p.Class), p.Path)
+				}
+				for _, command := range req.Commands {
+					fmt.Printf("$ %s\n", command)
+				}
+				fmt.Print("Approve the edit? [y/N] ")
+				scanner := bufio.NewScanner(os.Stdin)
+				return scanner.Scan() && strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+			})
+
+			changelog, err := a.GenerateChangelog(ctx, args[0], version)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			fmt.Print(changelog.Format())
+			if dryRun {
+				return
+			}
+
+			if err := a.UpdateChangelog(ctx, file, changelog); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			fmt.Printf("\nUpdated %s\n", file)
+		},
+	}
+
+	cmd.Flags().String("version", "", "Version heading for the entries (default: the tag at the end of the range, or Unreleased)")
+	cmd.Flags().String("file", "CHANGELOG.md", "Changelog file to update")
+	cmd.Flags().Bool("dry-run", false, "Print the entries without updating the file")
+	return cmd
+}
+
+func newBranchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "branch <task description>",
+		Short: "Suggest a branch name for a task and offer to create it",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			yes, _ := cmd.Flags().GetBool("yes")
+			handleBranch(loadAgent(cmd), strings.Join(args, " "), func(prompt string) bool {
+				if yes {
+					return true
+				}
+				fmt.Print(prompt)
+				scanner := bufio.NewScanner(os.Stdin)
+				return scanner.Scan() && strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+			})
+		},
+	}
+
+	cmd.Flags().BoolP("yes", "y", false, "Create the branch without asking")
+	return cmd
+}
+
+func newBumpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bump",
+		Short: "Recommend the next semantic version from commits and API changes since the last tag",
+		Run: func(cmd *cobra.Command, args []string) {
+			since, _ := cmd.Flags().GetString("since")
+
+			bump, err := loadAgent(cmd).RecommendVersionBump(context.Background(), since)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			if format, _ := cmd.Flags().GetString("output-format"); format == "json" {
+				output, _ := json.MarshalIndent(bump, "", "  ")
+				fmt.Println(string(output))
+				return
+			}
+
+			fmt.Printf("%d commit(s) since %s\n", bump.Commits, bump.From)
+			for _, group := range []struct {
+				label string
+				names []string
+			}{{"Removed", bump.API.Removed}, {"Changed", bump.API.Changed}, {"Added", bump.API.Added}} {
+				if len(group.names) > 0 {
+					fmt.Printf("%s API: %s\n", group.label, strings.Join(group.names, ", "))
+				}
+			}
+			fmt.Printf("\nRecommended: %s bump, %s -> %s\n\n%s\n\n", bump.Bump, bump.From, bump.To, bump.Reasoning)
+			fmt.Printf("To tag the release:\n  %s\n", bump.TagCommand)
+		},
+	}
+
+	cmd.Flags().String("since", "", "Release tag to compare against (default: the latest tag)")
+	return cmd
+}
+
+func newSessionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "List past interactive sessions",
+		Run: func(cmd *cobra.Command, args []string) {
+			sessions, err := history.List()
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			if len(sessions) == 0 {
+				fmt.Println("No saved sessions")
+				return
+			}
+
+			limit, _ := cmd.Flags().GetInt("limit")
+			for i, s := range sessions {
+				if limit > 0 && i == limit {
+					break
+				}
+				printSessionLine(s)
+			}
+		},
+	}
+	cmd.Flags().Int("limit", 20, "Number of sessions to list (0 for all)")
+
+	search := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Find past sessions by what was discussed",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			limit, _ := cmd.Flags().GetInt("limit")
+			matches, err := loadAgent(cmd).SearchSessions(strings.Join(args, " "), limit)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			if len(matches) == 0 {
+				fmt.Println("No matching sessions")
+				return
+			}
+
+			for _, match := range matches {
+				printSessionLine(match.Session)
+				if match.Snippet != "" {
+					fmt.Printf("    %s\n", match.Snippet)
+				}
+			}
+		},
+	}
+	search.Flags().Int("limit", 10, "Maximum number of sessions to show")
+
+	show := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Print the exchanges of a past session",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			s, err := history.Load(args[0])
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			printSessionLine(s)
+			fmt.Printf("  %s\n", s.Dir)
+			for _, exchange := range s.Exchanges {
+				fmt.Printf("\n[%s] > %s\n\n%s\n", exchange.Time.Format("15:04"), exchange.Input, exchange.Response)
+			}
+		},
+	}
+
+	cmd.AddCommand(search, show)
+	return cmd
+}
+
+func newContextCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "context",
+		Short: "Share the project's context index between checkouts",
+	}
+
+	export := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Write the project's outlines, symbol index and summary to a snapshot file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := loadAgent(cmd).ExportContext(args[0]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			fmt.Printf("Exported the project context to %s\n", args[0])
+		},
+	}
+
+	imp := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Load a snapshot written by `context export` instead of rescanning",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n, err := loadAgent(cmd).ImportContext(args[0])
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			fmt.Printf("Imported %d unchanged file(s); the rest are read as usual\n", n)
+		},
+	}
+
+	cmd.AddCommand(export, imp)
+	return cmd
+}
+
+func printSessionLine(s *history.Session) {
+	title := s.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	fmt.Printf("%s  %s  %s (%d exchange(s))\n", s.ID, s.Updated.Format("2006-01-02 15:04"), title, len(s.Exchanges))
+}
+
+func newImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import project instructions and MCP servers from another assistant",
+		Run: func(cmd *cobra.Command, args []string) {
+			from, _ := cmd.Flags().GetString("from")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			root, err := os.Getwd()
+			if err != nil {
+				log.Fatalf("Failed to get working directory: %v", err)
+			}
+
+			result, err := migrate.Scan(root, from)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			if result.Empty() {
+				fmt.Printf("Nothing to import from %s in %s\n", from, root)
+				return
+			}
+
+			for _, section := range result.Memory {
+				fmt.Printf("Found instructions: %s\n", section.Source)
+			}
+			for name, server := range result.MCPServers {
+				fmt.Printf("Found MCP server: %s (%s)\n", name, server.Transport)
+			}
+			for _, note := range result.Notes {
+				fmt.Printf("Note: %s\n", note)
+			}
+
+			if dryRun {
+				return
+			}
+
+			changes, err := migrate.Apply(root, result)
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			for _, change := range changes {
+				fmt.Printf("  %s\n", change)
+			}
+			fmt.Printf("Imported into %s\n", filepath.Join(root, config.ProjectDirName))
+		},
+	}
+
+	cmd.Flags().String("from", "", "Source assistant ("+strings.Join(migrate.Sources, ", ")+")")
+	cmd.Flags().Bool("dry-run", false, "Show what would be imported without writing files")
+	cmd.MarkFlagRequired("from")
+	return cmd
+}
+
+func newAuditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Scan the project for secrets and injection risks and report vulnerabilities",
+		Run: func(cmd *cobra.Command, args []string) {
+			if scanOnly, _ := cmd.Flags().GetBool("scan-only"); scanOnly {
+				findings, err := tools.ScanSecurity(".", "all")
+				if err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+				for _, f := range findings {
+					fmt.Printf("%s:%d [%s] %s: %s\n", f.File, f.Line, f.Severity, f.Rule, f.Snippet)
+				}
+				fmt.Printf("%d finding(s)\n", len(findings))
+				return
+			}
+
+			a := loadAgent(cmd)
+			attachAuditLog(a)
+
+			report, err := a.SecurityAudit(context.Background(), func(e agent.Event) {
+				if e.Type == "tool_call" {
+					fmt.Fprintf(os.Stderr, "→ %s %s\n", e.Tool, e.Content)
+				}
+			})
+			if err != nil {
+				printPostMortem(os.Stderr, err)
+				log.Fatalf("Error: %v", err)
+			}
+
+			fmt.Println(report)
+		},
+	}
+
+	cmd.Flags().Bool("scan-only", false, "Print the raw pattern scan without LLM analysis")
+	return cmd
+}
+
+func newTestGenCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "testgen <file|package>",
+		Short: "Generate table-driven tests for a Go file or package and run them",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			a := loadAgent(cmd)
+			attachAuditLog(a)
+
+			result, err := a.GenerateTests(context.Background(), args[0], func(e agent.Event) {
+				if e.Type == "tool_call" {
+					fmt.Fprintf(os.Stderr, "→ %s %s\n", e.Tool, e.Content)
+				}
+			})
+			if err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+
+			if !result.Passed {
+				fmt.Println(result.Output)
+				log.Fatalf("Tests in %s still fail after %d attempts; the file was left in place for manual fixes", result.Path, result.Attempts)
+			}
+
+			fmt.Printf("✅ Wrote %s; `%s` passed (attempt %d)\n", result.Path, result.Command, result.Attempts)
 		},
 	}
 }
@@ -176,7 +1059,32 @@ func newChatCommand() *cobra.Command {
 	}
 }
 
-func handleCommit(a *agent.Agent) {
+func handleBranch(a *agent.Agent, description string, confirm func(prompt string) bool) {
+	if description == "" {
+		fmt.Println("Usage: /branch <task description>")
+		return
+	}
+
+	ctx := context.Background()
+	name, err := a.SuggestBranchName(ctx, description)
+	if err != nil {
+		fmt.Printf("Error suggesting a branch name: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Suggested branch: %s\n", name)
+	if !confirm("Create and switch to it? (y/N): ") {
+		return
+	}
+
+	if err := a.CreateBranch(ctx, name); err != nil {
+		fmt.Printf("Error creating branch: %v\n", err)
+		return
+	}
+	fmt.Printf("Switched to new branch %s\n", name)
+}
+
+func handleCommit(a *agent.Agent, opts agent.CommitOptions, confirm func(prompt string) bool) {
 	ctx := context.Background()
 
 	// Get git status
@@ -186,30 +1094,111 @@ func handleCommit(a *agent.Agent) {
 		return
 	}
 
-	if len(status.Changes) == 0 {
+	changes := a.CommitChanges(status)
+	if opts.Amend {
+		// The amended commit keeps what HEAD already changed
+		previous, err := a.LastCommitChanges(ctx)
+		if err != nil {
+			fmt.Printf("Error reading the last commit: %v\n", err)
+			return
+		}
+		changes = append(previous, changes...)
+	}
+
+	if len(changes) == 0 {
+		if len(status.Changes) > 0 {
+			fmt.Println("No staged changes to commit (git.auto_stage is off; stage files with git add)")
+			return
+		}
 		fmt.Println("No changes to commit")
 		return
 	}
 
 	// Generate commit message
-	commitMsg, err := a.GenerateCommitMessage(ctx, status)
+	commitMsg, err := a.GenerateCommitMessage(ctx, &agent.GitStatus{Branch: status.Branch, Changes: changes})
 	if err != nil {
 		fmt.Printf("Error generating commit message: %v\n", err)
 		return
 	}
 
 	fmt.Printf("Generated commit message: %s\n", commitMsg)
-	fmt.Print("Proceed with commit? (y/N): ")
+	if !confirm("Proceed with commit? (y/N): ") {
+		return
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
-		err := a.CreateCommit(ctx, commitMsg)
-		if err != nil {
-			fmt.Printf("Error creating commit: %v\n", err)
+	hash, err := a.CreateCommit(ctx, commitMsg, opts)
+	var blocked *agent.PreCommitError
+	if errors.As(err, &blocked) {
+		if !confirmPreCommitOverride(blocked.Findings, confirm) {
 			return
 		}
-		fmt.Println("Commit created successfully!")
+		opts.SkipReview = true
+		hash, err = a.CreateCommit(ctx, commitMsg, opts)
+	}
+	if err != nil {
+		fmt.Printf("Error creating commit: %v\n", err)
+		return
+	}
+	fmt.Printf("Commit %s created successfully!\n", shortHash(hash))
+}
+
+// confirmPreCommitOverride shows what the pre-commit review found and asks
+// whether to commit anyway.
+func confirmPreCommitOverride(findings []agent.PreCommitFinding, confirm func(prompt string) bool) bool {
+	fmt.Printf("🚫 Pre-commit review found %d problem(s):\n", len(findings))
+	for _, finding := range findings {
+		fmt.Printf("   %s\n", finding)
 	}
+	return confirm("Commit anyway? (y/N): ")
+}
+
+func handleSplitCommit(a *agent.Agent, confirm func(prompt string) bool) {
+	ctx := context.Background()
+
+	fmt.Println("Grouping the staged changes...")
+	split, err := a.ProposeCommitSplit(ctx)
+	if err != nil {
+		fmt.Printf("Error planning commits: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Proposed %d commit(s):\n", len(split.Groups))
+	for i, group := range split.Groups {
+		fmt.Printf("\n%d. %s\n", i+1, strings.ReplaceAll(group.Message, "\n", "\n   "))
+		for _, hunk := range group.Hunks {
+			fmt.Printf("     %s %s\n", hunk.File, hunk.Header)
+		}
+	}
+	fmt.Println()
+
+	if !confirm(fmt.Sprintf("Create these %d commit(s)? (y/N): ", len(split.Groups))) {
+		return
+	}
+
+	// Review the whole change once rather than stopping between commits
+	findings, err := a.PreCommitReview(ctx)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(findings) > 0 && !confirmPreCommitOverride(findings, confirm) {
+		return
+	}
+
+	hashes, err := a.ApplyCommitSplit(ctx, split, agent.CommitOptions{SkipReview: true})
+	for i, hash := range hashes {
+		fmt.Printf("Commit %s created: %s\n", shortHash(hash), strings.SplitN(split.Groups[i].Message, "\n", 2)[0])
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\nThe remaining changes are unstaged in the working tree.\n", err)
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
 }
 
 func showConfig() {
@@ -236,3 +1225,298 @@ func showAvailableModels(a *agent.Agent) {
 		fmt.Printf("  - %s\n", model)
 	}
 }
+
+func handleModel(a *agent.Agent, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Current model: %s\n", a.Model())
+		return
+	}
+
+	a.SetModel(args[0])
+	fmt.Printf("Model set to %s\n", a.Model())
+}
+
+func handleOpen(a *agent.Agent, args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: /open <path>")
+		return
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	a.Opened(args...)
+}
+
+func handleThink(s *session, args []string) {
+	if len(args) == 0 {
+		mode, budget := s.agent.Thinking()
+		fmt.Printf("Thinking: %s", mode)
+		if mode == llm.ThinkingOn && budget > 0 {
+			fmt.Printf(" (budget %d tokens)", budget)
+		}
+		fmt.Printf(", reasoning is %s\n", map[bool]string{true: "shown", false: "collapsed"}[s.showThinking])
+		return
+	}
+
+	switch args[0] {
+	case "show", "hide":
+		s.showThinking = args[0] == "show"
+		fmt.Printf("Reasoning will be %s\n", map[bool]string{true: "shown", false: "collapsed"}[s.showThinking])
+		return
+	}
+
+	_, budget := s.agent.Thinking()
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			fmt.Printf("Invalid budget: %s\n", args[1])
+			return
+		}
+		budget = n
+	}
+
+	if err := s.agent.SetThinking(args[0], budget); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Thinking set to %s\n", args[0])
+}
+
+// printReasoning shows a reasoning model's thinking, collapsed to one line
+// unless the user asked to see it.
+func printReasoning(w io.Writer, reasoning string, show bool) {
+	if show {
+		fmt.Fprintf(w, "💭 %s\n\n", strings.ReplaceAll(reasoning, "\n", "\n   "))
+		return
+	}
+	fmt.Fprintf(w, "💭 Thought for ~%d tokens (/think show to display)\n", len(reasoning)/4)
+}
+
+func handleStyle(a *agent.Agent, args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Current style: %s\n", a.OutputStyle())
+		fmt.Printf("Available styles: %s\n", strings.Join(agent.OutputStyles(), ", "))
+		return
+	}
+
+	if err := a.SetOutputStyle(args[0]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Response style set to %s\n", a.OutputStyle())
+}
+
+// loadConfig loads the config and applies the global flag overrides.
+func loadConfig(cmd *cobra.Command) *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if baseURL, _ := cmd.Flags().GetString("base-url"); baseURL != "" {
+		cfg.LMStudio.BaseURL = baseURL
+	}
+	if model, _ := cmd.Flags().GetString("model"); model != "" {
+		cfg.LMStudio.Model = model
+	}
+	if provider, _ := cmd.Flags().GetString("provider"); provider != "" {
+		cfg.LMStudio.Provider = provider
+	}
+	if script, _ := cmd.Flags().GetString("mock-script"); script != "" {
+		cfg.LMStudio.Provider = "mock"
+		cfg.LMStudio.MockScript = script
+	}
+	if cassette, _ := cmd.Flags().GetString("cassette"); cassette != "" {
+		cfg.LMStudio.Cassette = cassette
+	}
+	if debugLog, _ := cmd.Flags().GetString("mcp-debug"); debugLog != "" {
+		cfg.MCPDebugLog = debugLog
+	}
+
+	return cfg
+}
+
+func newClient(cfg *config.Config) *llm.Client {
+	client, err := llm.NewClient(cfg.LMStudio)
+	if err != nil {
+		log.Fatalf("Failed to set up the model client: %v", err)
+	}
+	return client
+}
+
+// loadAgent builds an agent for one-shot subcommands, honoring the global
+// config overrides.
+func loadAgent(cmd *cobra.Command) *agent.Agent {
+	cfg := loadConfig(cmd)
+	return agent.New(newClient(cfg), cfg)
+}
+
+func handleWhy(a *agent.Agent, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: /why <file>:<line>")
+		return
+	}
+
+	i := strings.LastIndex(args[0], ":")
+	line, err := strconv.Atoi(args[0][i+1:])
+	if i <= 0 || err != nil || line <= 0 {
+		fmt.Println("Usage: /why <file>:<line>")
+		return
+	}
+	path := args[0][:i]
+
+	blame, explanation, err := a.ExplainLine(context.Background(), path, line)
+	if blame != nil {
+		fmt.Printf("%s %s (%s, %s)\n\n", shortHash(blame.Commit), blame.Summary, blame.Author, blame.Date)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Println(explanation)
+}
+
+func handleExplain(s *session, trace string) {
+	if trace == "" {
+		fmt.Println("Paste the error or stack trace, then an empty line:")
+		trace = readMultiline(s.input)
+	}
+
+	explanation, err := s.agent.ExplainError(context.Background(), trace)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Println(explanation)
+}
+
+// readMultiline reads lines until an empty one.
+func readMultiline(input *repl.LineReader) string {
+	var lines []string
+	for {
+		line, err := input.ReadLine("")
+		if err != nil || strings.TrimSpace(line) == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func attachAuditLog(a *agent.Agent) {
+	auditLog, err := audit.Open()
+	if err != nil {
+		log.Printf("Warning: tool executions will not be audited: %v", err)
+		return
+	}
+	a.SetAuditLog(auditLog)
+}
+
+func showAuditEntry(a *agent.Agent, args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: /trace <footnote number>")
+		return
+	}
+
+	id, err := strconv.Atoi(strings.Trim(args[0], "[^]"))
+	if err != nil {
+		fmt.Printf("Invalid footnote: %s\n", args[0])
+		return
+	}
+
+	auditLog := a.AuditLog()
+	if auditLog == nil {
+		fmt.Println("No audit log for this session")
+		return
+	}
+
+	entry, ok := auditLog.Get(id)
+	if !ok {
+		fmt.Printf("No audit entry %d in %s\n", id, auditLog.Path())
+		return
+	}
+
+	fmt.Printf("[%d] %s at %s (%s)\n", entry.ID, entry.Tool, entry.Time.Format(time.RFC3339), entry.Duration.Round(time.Millisecond))
+	fmt.Printf("Arguments: %s\n", entry.Arguments)
+	if entry.Error != "" {
+		fmt.Printf("Error: %s\n", entry.Error)
+	}
+	fmt.Println("Output:")
+	fmt.Println(entry.Output)
+}
+
+func handleReview(a *agent.Agent, args []string) {
+	opts := agent.ReviewOptions{}
+	for _, arg := range args {
+		if arg == "--staged" {
+			opts.Staged = true
+		} else {
+			opts.Range = arg
+		}
+	}
+
+	review, err := a.ReviewDiff(context.Background(), opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Print(review.Format())
+}
+
+func handleAttach(a *agent.Agent, args []string) {
+	if len(args) == 0 {
+		pending := a.PendingAttachments()
+		if len(pending) == 0 {
+			fmt.Println("No pending attachments. Usage: /attach <path> | /attach clear")
+			return
+		}
+		fmt.Println("Attached to next message:")
+		for _, att := range pending {
+			fmt.Printf("  - %s (%s, %s)\n", att.Path, att.Kind, att.Summary)
+		}
+		return
+	}
+
+	if args[0] == "clear" {
+		a.ClearAttachments()
+		fmt.Println("Attachments cleared")
+		return
+	}
+
+	for _, path := range args {
+		att, err := a.Attach(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("Attached %s (%s, %s)\n", att.Path, att.Kind, att.Summary)
+	}
+}
+
+// printPostMortem shows the post-mortem attached to a failed autonomous run.
+func printPostMortem(w io.Writer, err error) {
+	var failure *agent.RunFailure
+	if errors.As(err, &failure) && failure.PostMortem != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, failure.PostMortem)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/N0tT1m/claude-code-go/internal/config"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+// inspectPollInterval is how often `mcp inspect --follow` looks for new
+// frames.
+const inspectPollInterval = 250 * time.Millisecond
+
+// defaultMCPDebugLog is where --mcp-debug logs without a file given.
+func defaultMCPDebugLog() string {
+	dir, err := config.Dir()
+	if err != nil {
+		return "mcp-debug.jsonl"
+	}
+	return filepath.Join(dir, "mcp-debug.jsonl")
+}
+
+func newMCPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Debug the connections to MCP servers",
+	}
+
+	inspect := &cobra.Command{
+		Use:   "inspect [file]",
+		Short: "Show the MCP traffic logged with --mcp-debug",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := defaultMCPDebugLog()
+			if len(args) == 1 {
+				path = args[0]
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				log.Fatalf("Error: %v\nRun with --mcp-debug to log the MCP traffic", err)
+			}
+			defer file.Close()
+
+			follow, _ := cmd.Flags().GetBool("follow")
+			server, _ := cmd.Flags().GetString("server")
+			method, _ := cmd.Flags().GetString("method")
+			compact, _ := cmd.Flags().GetBool("compact")
+
+			// Requests by server, direction and ID, so responses show the
+			// method they answer
+			methods := make(map[string]string)
+			reader := bufio.NewReader(file)
+			var line []byte
+			for {
+				chunk, err := reader.ReadBytes('\n')
+				line = append(line, chunk...)
+				if err == io.EOF {
+					if !follow {
+						return
+					}
+					time.Sleep(inspectPollInterval)
+					continue
+				}
+				if err != nil {
+					log.Fatalf("Error: %v", err)
+				}
+
+				var frame mcp.Frame
+				if json.Unmarshal(line, &frame) == nil {
+					printFrame(frame, methods, server, method, compact)
+				}
+				line = line[:0]
+			}
+		},
+	}
+	inspect.Flags().BoolP("follow", "f", false, "Keep showing frames as they are logged")
+	inspect.Flags().String("server", "", "Only show the frames of this server")
+	inspect.Flags().String("method", "", "Only show the frames of methods containing this")
+	inspect.Flags().Bool("compact", false, "Show each message on one line")
+
+	cmd.AddCommand(inspect)
+	return cmd
+}
+
+// printFrame shows a frame: when, the server, the direction (→ to the
+// server, ← from it), the method or the request it answers with how long
+// that took, and the message pretty-printed.
+func printFrame(frame mcp.Frame, methods map[string]string, server, method string, compact bool) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Error  *mcp.MCPError   `json:"error"`
+	}
+	json.Unmarshal(frame.Message, &msg)
+
+	arrow, requestArrow := "→", "←"
+	if frame.Direction == mcp.FrameReceived {
+		arrow, requestArrow = "←", "→"
+	}
+	name := msg.Method
+	if name != "" && msg.ID != nil {
+		methods[frame.Server+arrow+string(msg.ID)] = name
+	} else if name == "" {
+		key := frame.Server + requestArrow + string(msg.ID)
+		name = methods[key]
+		delete(methods, key)
+	}
+
+	if server != "" && frame.Server != server {
+		return
+	}
+	if method != "" && !strings.Contains(name, method) {
+		return
+	}
+
+	header := fmt.Sprintf("%s %s %s %s", frame.Time.Local().Format("15:04:05.000"), frame.Server, arrow, name)
+	if msg.ID != nil {
+		header += " #" + strings.Trim(string(msg.ID), `"`)
+	}
+	if msg.Method == "" {
+		if msg.Error != nil {
+			header += fmt.Sprintf(" error %d: %s", msg.Error.Code, msg.Error.Message)
+		}
+		if frame.LatencyMS > 0 {
+			header += fmt.Sprintf(" (%.1fms)", frame.LatencyMS)
+		}
+	}
+
+	if compact {
+		fmt.Printf("%s %s\n", header, frame.Message)
+		return
+	}
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, frame.Message, "  ", "  ") != nil {
+		pretty.Reset()
+		pretty.Write(frame.Message)
+	}
+	fmt.Printf("%s\n  %s\n", header, pretty.String())
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/N0tT1m/claude-code-go/internal/agent"
+	"github.com/N0tT1m/claude-code-go/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+// mcpTokenEnv holds the auth token of `serve` unless --token gives one.
+const mcpTokenEnv = "CLAUDE_GO_MCP_TOKEN"
+
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the project's tools, files and prompts to MCP hosts",
+		Long: "Serve the project over MCP until interrupted: its tools, its files as resources, and its\n" +
+			"prompts. The Unix socket needs no token; every network transport requires the auth token\n" +
+			"(--token, $" + mcpTokenEnv + ", or one generated and printed at start) or a client's own.",
+		Args: cobra.NoArgs,
+		Run:  runServe,
+	}
+	cmd.Flags().String("socket", "", "Serve on this Unix socket")
+	cmd.Flags().Int("tcp", 0, "Serve on this TCP port")
+	cmd.Flags().String("tls-cert", "", "Certificate (PEM) to serve the TCP port over TLS with")
+	cmd.Flags().String("tls-key", "", "Key (PEM) of --tls-cert")
+	cmd.Flags().String("http", "", "Serve streamable HTTP at /mcp on this address (host:port or :port)")
+	cmd.Flags().String("sse", "", "Serve HTTP with server-sent events at /sse on this address")
+	cmd.Flags().String("ws", "", "Serve WebSocket at /ws on this address")
+	cmd.Flags().String("metrics", "", "Serve Prometheus metrics at /metrics on this address")
+	cmd.Flags().String("token", "", "Auth token of the network transports (default $"+mcpTokenEnv+")")
+	cmd.Flags().StringArray("client-token", nil, "A client's own token, as name=token (repeatable)")
+	cmd.Flags().StringArray("tool-policy", nil, "Tools a client may use, as name=pattern,pattern; name * covers clients without a token of their own (repeatable)")
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	socketPath, _ := flags.GetString("socket")
+	port, _ := flags.GetInt("tcp")
+	certFile, _ := flags.GetString("tls-cert")
+	keyFile, _ := flags.GetString("tls-key")
+	httpAddr, _ := flags.GetString("http")
+	sseAddr, _ := flags.GetString("sse")
+	wsAddr, _ := flags.GetString("ws")
+	metricsAddr, _ := flags.GetString("metrics")
+
+	network := port != 0 || httpAddr != "" || sseAddr != "" || wsAddr != ""
+	if socketPath == "" && !network {
+		log.Fatal("Error: nothing to serve on; give --socket, --tcp, --http, --sse or --ws")
+	}
+	if (certFile == "") != (keyFile == "") || (certFile != "" && port == 0) {
+		log.Fatal("Error: --tls-cert and --tls-key go together, with --tcp")
+	}
+
+	cfg := loadConfig(cmd)
+	a := agent.NewEnhanced(newClient(cfg), cfg)
+	defer a.Close()
+	if err := a.StartMCPServer(socketPath); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	server := a.MCPServer()
+
+	if err := configureServerAuth(cmd, server, network); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	var err error
+	switch {
+	case port != 0 && certFile != "":
+		err = server.StartTLS(port, certFile, keyFile)
+	case port != 0:
+		err = server.StartTCP(port)
+	}
+	if err == nil && httpAddr != "" {
+		err = server.StartHTTP(httpAddr)
+	}
+	if err == nil && sseAddr != "" {
+		err = server.StartSSE(sseAddr)
+	}
+	if err == nil && wsAddr != "" {
+		err = server.StartWebSocket(wsAddr)
+	}
+	if err == nil && metricsAddr != "" {
+		err = server.StartMetrics(metricsAddr)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintln(os.Stderr, "Serving MCP; press Ctrl+C to stop")
+	<-ctx.Done()
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+}
+
+// configureServerAuth sets the server's tokens and tool policies from the
+// flags. Network transports need a token, so one is generated and printed
+// when they have none.
+func configureServerAuth(cmd *cobra.Command, server *mcp.Server, network bool) error {
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		token = os.Getenv(mcpTokenEnv)
+	}
+
+	clientTokens, _ := cmd.Flags().GetStringArray("client-token")
+	for _, entry := range clientTokens {
+		name, clientToken, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || clientToken == "" {
+			return fmt.Errorf("--client-token %q: want name=token", entry)
+		}
+		server.AddClientToken(name, clientToken)
+	}
+
+	if token == "" && len(clientTokens) == 0 && network {
+		generated, err := mcp.NewAuthToken()
+		if err != nil {
+			return err
+		}
+		token = generated
+		fmt.Fprintf(os.Stderr, "Auth token: %s\n", token)
+	}
+	if token != "" {
+		server.SetAuthToken(token)
+	}
+
+	policies, _ := cmd.Flags().GetStringArray("tool-policy")
+	for _, entry := range policies {
+		name, patterns, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("--tool-policy %q: want name=pattern,pattern", entry)
+		}
+		if err := server.SetToolPolicy(name, strings.Split(patterns, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}